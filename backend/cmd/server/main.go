@@ -12,8 +12,10 @@ import (
 	"vibe-backend/internal/cache"
 	"vibe-backend/internal/config"
 	"vibe-backend/internal/database"
+	"vibe-backend/internal/metrics"
 	"vibe-backend/internal/models"
 	"vibe-backend/internal/router"
+	"vibe-backend/internal/telemetry"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -33,6 +35,25 @@ func main() {
 	log := initLogger(cfg)
 	defer log.Sync()
 
+	// `server migrate ...` manages the database schema instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, log, os.Args[2:]); err != nil {
+			log.Fatal("Migration command failed", zap.Error(err))
+		}
+		return
+	}
+
+	// Initialize tracing
+	shutdownTracing, err := telemetry.Setup(context.Background(), telemetry.Config{
+		Exporter:     cfg.TracingExporter,
+		OTLPEndpoint: cfg.TracingOTLPEndpoint,
+		SampleRatio:  cfg.TracingSampleRatio,
+		Environment:  cfg.Env,
+	}, log)
+	if err != nil {
+		log.Fatal("Failed to set up tracing", zap.Error(err))
+	}
+
 	log.Info("Starting server",
 		zap.String("env", cfg.Env),
 		zap.String("port", cfg.Port),
@@ -52,27 +73,16 @@ func main() {
 		var err error
 		db, err = database.NewPostgres(cfg.DatabaseURL, log)
 		if err == nil {
-			// Auto-migrate models
-			if err := db.DB.AutoMigrate(
-				&models.User{},
-				&models.Pomodoro{},
-				&models.VideoAnalysis{},
-				&models.Chapter{},
-				&models.Transcription{},
-				&models.KeyPoint{},
-				&models.Insight{},
-				&models.Highlight{},
-				&models.ChatMessage{},
-				&models.Translation{},
-				&models.DualSubtitle{},
-			); err != nil {
-				log.Error("Failed to auto-migrate database", zap.Error(err))
-				db.Close()
-				db = nil
-			} else {
-				log.Info("Database migration completed")
-				break
+			// Insight tags use a custom join table model
+			if err := db.DB.SetupJoinTable(&models.Insight{}, "Tags", &models.InsightTag{}); err != nil {
+				log.Fatal("Failed to set up insight tag join table", zap.Error(err))
+			}
+
+			// Refuse to run against an outdated schema, unless asked to migrate it
+			if err := migrateOnStart(cfg, db, log); err != nil {
+				log.Fatal("Database schema is not up to date", zap.Error(err))
 			}
+			break
 		}
 		if i < maxRetries-1 {
 			log.Warn("Failed to connect to database, retrying...",
@@ -155,6 +165,27 @@ func main() {
 		}
 	}()
 
+	// Serve Prometheus metrics on the internal port, if configured
+	var metricsSrv *http.Server
+	if cfg.MetricsPort != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler(cfg.MetricsToken))
+		metricsSrv = &http.Server{
+			Addr:         ":" + cfg.MetricsPort,
+			Handler:      mux,
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+		}
+		go func() {
+			log.Info("Metrics server listening", zap.String("addr", metricsSrv.Addr))
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("Metrics server error", zap.Error(err))
+			}
+		}()
+	} else if cfg.MetricsToken == "" {
+		log.Info("Metrics endpoint disabled, set METRICS_PORT or METRICS_TOKEN to enable it")
+	}
+
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -169,6 +200,14 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Error("Server forced to shutdown", zap.Error(err))
 	}
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(ctx); err != nil {
+			log.Error("Metrics server forced to shutdown", zap.Error(err))
+		}
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error("Failed to flush traces", zap.Error(err))
+	}
 
 	log.Info("Server stopped")
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
@@ -28,6 +29,9 @@ func NewRedis(redisURL string, log *zap.Logger) (*RedisCache, error) {
 	opts.PoolTimeout = 10 * time.Second  // Pool timeout
 
 	client := redis.NewClient(opts)
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		return nil, err
+	}
 
 	// Verify connection with short timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -8,6 +8,7 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"gorm.io/plugin/opentelemetry/tracing"
 )
 
 // PostgresDB wraps the GORM database connection.
@@ -31,6 +32,11 @@ func NewPostgres(databaseURL string, log *zap.Logger) (*PostgresDB, error) {
 		return nil, err
 	}
 
+	// Trace queries; values are left out since they may contain API keys and emails
+	if err := db.Use(tracing.NewPlugin(tracing.WithoutMetrics(), tracing.WithoutQueryVariables())); err != nil {
+		return nil, err
+	}
+
 	// Get underlying SQL DB to configure connection pool
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"vibe-backend/internal/middleware"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/services"
+)
+
+// AccountHandler handles data exports and account deletion.
+type AccountHandler struct {
+	accounts *services.AccountService
+	audit    *services.AuditService
+	log      *zap.Logger
+}
+
+// NewAccountHandler creates a new AccountHandler.
+func NewAccountHandler(accounts *services.AccountService, audit *services.AuditService, log *zap.Logger) *AccountHandler {
+	return &AccountHandler{
+		accounts: accounts,
+		audit:    audit,
+		log:      log,
+	}
+}
+
+// RequestExport handles POST /api/v1/account/exports - start building a ZIP of
+// everything the caller owns
+func (h *AccountHandler) RequestExport(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+
+	export, err := h.accounts.RequestExport(context.WithoutCancel(c.Request.Context()), user, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		h.respondError(c, err, "Failed to request data export")
+		return
+	}
+	entry := auditEntry(c, models.AuditAccountExported)
+	entry.TargetType, entry.TargetID = models.AuditTargetExport, &export.ID
+	h.audit.Record(c.Request.Context(), entry)
+
+	c.JSON(http.StatusAccepted, gin.H{"data": export})
+}
+
+// ListExports handles GET /api/v1/account/exports - list the caller's exports
+func (h *AccountHandler) ListExports(c *gin.Context) {
+	exports, err := h.accounts.ListExports(c.Request.Context(), middleware.MustGetUserID(c))
+	if err != nil {
+		h.respondError(c, err, "Failed to list data exports")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": exports})
+}
+
+// GetExport handles GET /api/v1/account/exports/:id - export status
+func (h *AccountHandler) GetExport(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid export ID.")
+	if !ok {
+		return
+	}
+
+	export, err := h.accounts.GetExport(c.Request.Context(), middleware.MustGetUserID(c), id)
+	if err != nil {
+		h.respondError(c, err, "Failed to get data export")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": export})
+}
+
+// DownloadExport handles GET /api/v1/account/exports/:id/download - the ZIP archive
+func (h *AccountHandler) DownloadExport(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid export ID.")
+	if !ok {
+		return
+	}
+
+	export, err := h.accounts.OpenExport(c.Request.Context(), middleware.MustGetUserID(c), id)
+	if err != nil {
+		h.respondError(c, err, "Failed to download data export")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, h.accounts.ExportFilename(export)))
+	c.Header("Content-Length", strconv.Itoa(len(export.Archive)))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", export.Archive)
+}
+
+// GetDeletion handles GET /api/v1/account/deletion - the scheduled deletion, if any
+func (h *AccountHandler) GetDeletion(c *gin.Context) {
+	deletion, err := h.accounts.GetDeletion(c.Request.Context(), middleware.MustGetUserID(c))
+	if err != nil {
+		h.respondError(c, err, "Failed to get account deletion")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": deletion})
+}
+
+// ScheduleDeletion handles POST /api/v1/account/deletion - delete the caller's account
+// and all its data after the grace period
+func (h *AccountHandler) ScheduleDeletion(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	var req models.DeleteAccountRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	deletion, err := h.accounts.ScheduleDeletion(c.Request.Context(), user, &req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		h.respondError(c, err, "Failed to schedule account deletion")
+		return
+	}
+	entry := auditEntry(c, models.AuditAccountDeletionScheduled)
+	entry.TargetType, entry.TargetID = models.AuditTargetUser, &user.ID
+	entry.Details = services.AuditDetails(map[string]interface{}{"scheduled_for": deletion.ScheduledFor})
+	h.audit.Record(c.Request.Context(), entry)
+
+	c.JSON(http.StatusAccepted, gin.H{"data": deletion})
+}
+
+// CancelDeletion handles DELETE /api/v1/account/deletion - keep the account
+func (h *AccountHandler) CancelDeletion(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+
+	if err := h.accounts.CancelDeletion(c.Request.Context(), user, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		h.respondError(c, err, "Failed to cancel account deletion")
+		return
+	}
+	entry := auditEntry(c, models.AuditAccountDeletionCancelled)
+	entry.TargetType, entry.TargetID = models.AuditTargetUser, &user.ID
+	h.audit.Record(c.Request.Context(), entry)
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondError maps account service errors to HTTP responses.
+func (h *AccountHandler) respondError(c *gin.Context, err error, logMessage string) {
+	requestID := c.GetString("request_id")
+
+	status, code, message := http.StatusInternalServerError, models.ErrInternalServer, "An unexpected error occurred."
+	switch {
+	case errors.Is(err, services.ErrExportInProgress):
+		status, code, message = http.StatusConflict, "EXPORT_IN_PROGRESS", "An export is already being prepared."
+	case errors.Is(err, services.ErrExportNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "Export not found or expired."
+	case errors.Is(err, services.ErrExportNotReady):
+		status, code, message = http.StatusConflict, "EXPORT_NOT_READY", "The export is not ready for download."
+	case errors.Is(err, services.ErrDeletionScheduled):
+		status, code, message = http.StatusConflict, "DELETION_SCHEDULED", "Account deletion is already scheduled."
+	case errors.Is(err, services.ErrNoDeletionScheduled):
+		status, code, message = http.StatusNotFound, "NO_DELETION_SCHEDULED", "No account deletion is scheduled."
+	case errors.Is(err, services.ErrDeletionEmailMismatch):
+		status, code, message = http.StatusBadRequest, "CONFIRMATION_MISMATCH", "The confirmation email does not match your account."
+	case errors.Is(err, services.ErrInvalidPassword):
+		status, code, message = http.StatusUnauthorized, "INVALID_CREDENTIALS", "Invalid password."
+	case errors.Is(err, services.ErrTwoFactorCodeRequired):
+		status, code, message = http.StatusUnauthorized, "2FA_CODE_REQUIRED", "An authentication code is required."
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		status, code, message = http.StatusUnauthorized, "INVALID_2FA_CODE", "Invalid authentication code."
+	}
+
+	if status == http.StatusInternalServerError {
+		h.log.Error(logMessage,
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Code:      code,
+		Message:   message,
+		RequestID: requestID,
+	})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"vibe-backend/internal/middleware"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/services"
+)
+
+// AnkiHandler handles Anki deck exports.
+type AnkiHandler struct {
+	anki  *services.AnkiService
+	audit *services.AuditService
+	log   *zap.Logger
+}
+
+// NewAnkiHandler creates a new AnkiHandler.
+func NewAnkiHandler(anki *services.AnkiService, audit *services.AuditService, log *zap.Logger) *AnkiHandler {
+	return &AnkiHandler{
+		anki:  anki,
+		audit: audit,
+		log:   log,
+	}
+}
+
+// Export handles POST /api/v1/exports/anki - highlights, key points and flashcards of
+// the given insights as an .apkg download
+func (h *AnkiHandler) Export(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+	var req models.AnkiExportRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// Build the whole package before responding so failures still get a JSON error
+	var buf bytes.Buffer
+	if err := h.anki.Export(c.Request.Context(), userID, &req, &buf); err != nil {
+		h.respondError(c, err, "Failed to export anki deck")
+		return
+	}
+	entry := auditEntry(c, models.AuditAnkiExported)
+	entry.TargetType = models.AuditTargetExport
+	entry.Details = services.AuditDetails(map[string]interface{}{
+		"insight_ids": req.InsightIDs,
+		"count":       len(req.InsightIDs),
+	})
+	h.audit.Record(c.Request.Context(), entry)
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, h.anki.ExportFilename(&req)))
+	c.Data(http.StatusOK, "application/apkg", buf.Bytes())
+}
+
+// respondError maps service errors to HTTP responses.
+func (h *AnkiHandler) respondError(c *gin.Context, err error, logMessage string) {
+	requestID := c.GetString("request_id")
+
+	status, code, message := http.StatusInternalServerError, models.ErrInternalServer, "An unexpected error occurred."
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "One or more insights were not found."
+	case errors.Is(err, services.ErrWorkspaceForbidden):
+		status, code, message = http.StatusForbidden, models.ErrForbidden, "You do not have access to one or more of these insights."
+	case errors.Is(err, services.ErrAnkiExportEmpty):
+		status, code, message = http.StatusUnprocessableEntity, "NOTHING_TO_EXPORT", "The selected insights have no highlights, key points or flashcards to export."
+	}
+
+	if status == http.StatusInternalServerError {
+		h.log.Error(logMessage,
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Code:      code,
+		Message:   message,
+		RequestID: requestID,
+	})
+}
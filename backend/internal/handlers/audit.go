@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"vibe-backend/internal/middleware"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+	"vibe-backend/internal/services"
+)
+
+// AuditHandler handles audit log queries.
+type AuditHandler struct {
+	audit *services.AuditService
+	log   *zap.Logger
+}
+
+// NewAuditHandler creates a new AuditHandler.
+func NewAuditHandler(audit *services.AuditService, log *zap.Logger) *AuditHandler {
+	return &AuditHandler{
+		audit: audit,
+		log:   log,
+	}
+}
+
+// ListMine handles GET /api/v1/audit-logs - the caller's actions and those targeting
+// their account. Filters: action (comma-separated), target_type, target_id, from, to.
+func (h *AuditHandler) ListMine(c *gin.Context) {
+	h.list(c, nil)
+}
+
+// ExportMine handles GET /api/v1/audit-logs/export - ListMine as CSV
+func (h *AuditHandler) ExportMine(c *gin.Context) {
+	h.export(c, nil)
+}
+
+// ListWorkspace handles GET /api/v1/workspaces/:id/audit-logs - actions on a
+// workspace's content. Owners only. Same filters as ListMine, plus actor_id.
+func (h *AuditHandler) ListWorkspace(c *gin.Context) {
+	workspaceID, ok := parseUintParam(c, "id", "Invalid workspace ID format.")
+	if !ok {
+		return
+	}
+	h.list(c, &workspaceID)
+}
+
+// ExportWorkspace handles GET /api/v1/workspaces/:id/audit-logs/export - ListWorkspace as CSV
+func (h *AuditHandler) ExportWorkspace(c *gin.Context) {
+	workspaceID, ok := parseUintParam(c, "id", "Invalid workspace ID format.")
+	if !ok {
+		return
+	}
+	h.export(c, &workspaceID)
+}
+
+// list writes a page of the audit log of a workspace, or of the caller if nil.
+func (h *AuditHandler) list(c *gin.Context, workspaceID *uint) {
+	filter, ok := h.scopedFilter(c, workspaceID)
+	if !ok {
+		return
+	}
+	page, err := parsePageRequest(c, 50)
+	if err != nil {
+		h.respondError(c, err, "Failed to list audit log")
+		return
+	}
+
+	result, err := h.audit.List(c.Request.Context(), filter, page)
+	if err != nil {
+		h.respondError(c, err, "Failed to list audit log")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// export streams the audit log of a workspace, or of the caller if nil, as CSV.
+func (h *AuditHandler) export(c *gin.Context, workspaceID *uint) {
+	filter, ok := h.scopedFilter(c, workspaceID)
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("audit-log-%s.csv", time.Now().UTC().Format("20060102"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	// Headers are already sent; a failure can only cut the file short
+	if err := h.audit.WriteCSV(c.Request.Context(), filter, c.Writer); err != nil {
+		h.log.Error("Failed to export audit log",
+			zap.String("request_id", c.GetString("request_id")),
+			zap.Error(err),
+		)
+	}
+}
+
+// scopedFilter parses the filter query parameters and scopes them to what the caller
+// may see, writing an error response on failure.
+func (h *AuditHandler) scopedFilter(c *gin.Context, workspaceID *uint) (models.AuditFilter, bool) {
+	var filter models.AuditFilter
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return filter, false
+	}
+
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		h.respondError(c, err, "Failed to parse audit log filter")
+		return filter, false
+	}
+	if err := h.audit.Scope(c.Request.Context(), user, workspaceID, &filter); err != nil {
+		h.respondError(c, err, "Failed to authorize audit log access")
+		return filter, false
+	}
+	return filter, true
+}
+
+// parseAuditFilter reads action, actor_id, target_type, target_id, from and to from
+// the query string.
+func parseAuditFilter(c *gin.Context) (models.AuditFilter, error) {
+	var filter models.AuditFilter
+
+	for _, action := range strings.Split(c.Query("action"), ",") {
+		if action = strings.TrimSpace(action); action != "" {
+			filter.Actions = append(filter.Actions, models.AuditAction(action))
+		}
+	}
+	filter.TargetType = c.Query("target_type")
+
+	parseID := func(name string) (*uint, error) {
+		value := c.Query(name)
+		if value == "" {
+			return nil, nil
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, errInvalidPageParams
+		}
+		result := uint(id)
+		return &result, nil
+	}
+	var err error
+	if filter.ActorID, err = parseID("actor_id"); err != nil {
+		return filter, err
+	}
+	if filter.TargetID, err = parseID("target_id"); err != nil {
+		return filter, err
+	}
+
+	filter.Created, err = parseDateRange(c)
+	return filter, err
+}
+
+// respondError maps audit service errors to HTTP responses.
+func (h *AuditHandler) respondError(c *gin.Context, err error, logMessage string) {
+	requestID := c.GetString("request_id")
+
+	status, code, message := http.StatusInternalServerError, models.ErrInternalServer, "An unexpected error occurred."
+	switch {
+	case errors.Is(err, errInvalidPageParams), errors.Is(err, repository.ErrInvalidCursor), errors.Is(err, repository.ErrUnsupportedSort):
+		status, code, message = http.StatusBadRequest, models.ErrBadRequest, "Invalid filter or pagination parameters."
+	case errors.Is(err, services.ErrWorkspaceNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "Workspace not found."
+	case errors.Is(err, services.ErrWorkspaceForbidden):
+		status, code, message = http.StatusForbidden, models.ErrForbidden, "Only workspace owners can view its audit log."
+	}
+
+	if status == http.StatusInternalServerError {
+		h.log.Error(logMessage,
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Code:      code,
+		Message:   message,
+		RequestID: requestID,
+	})
+}
+
+// auditEntry starts an audit log entry for an action of the current request, by the
+// signed-in user if any.
+func auditEntry(c *gin.Context, action models.AuditAction) *models.AuditLog {
+	entry := &models.AuditLog{
+		Action:    action,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		RequestID: c.GetString(middleware.RequestIDKey),
+	}
+	if userID, ok := middleware.GetUserID(c); ok {
+		entry.ActorID = &userID
+	}
+	return entry
+}
+
+// recordLogin records a sign-in of a user by method ("password", "two_factor" or the
+// login provider).
+func recordLogin(c *gin.Context, audit *services.AuditService, userID uint, method string) {
+	entry := auditEntry(c, models.AuditLogin)
+	entry.ActorID = &userID
+	entry.TargetType, entry.TargetID = models.AuditTargetUser, &userID
+	entry.Details = services.AuditDetails(map[string]interface{}{"method": method})
+	audit.Record(c.Request.Context(), entry)
+}
+
+// recordInsightAudit records an action on an insight, keeping its title in case the
+// insight is later purged.
+func recordInsightAudit(c *gin.Context, audit *services.AuditService, action models.AuditAction, insight *models.Insight) {
+	entry := auditEntry(c, action)
+	entry.WorkspaceID = insight.WorkspaceID
+	entry.TargetType, entry.TargetID = models.AuditTargetInsight, &insight.ID
+	entry.Details = services.AuditDetails(map[string]interface{}{"title": insight.Title})
+	audit.Record(c.Request.Context(), entry)
+}
@@ -1,33 +1,86 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"vibe-backend/internal/middleware"
 	"vibe-backend/internal/models"
 	"vibe-backend/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // ChatHandler handles chat-related HTTP requests.
 type ChatHandler struct {
 	chatService *services.ChatService
+	workspaces  *services.WorkspaceService
 	log         *zap.Logger
 }
 
 // NewChatHandler creates a new ChatHandler.
-func NewChatHandler(chatService *services.ChatService, log *zap.Logger) *ChatHandler {
+func NewChatHandler(chatService *services.ChatService, workspaces *services.WorkspaceService, log *zap.Logger) *ChatHandler {
 	return &ChatHandler{
 		chatService: chatService,
+		workspaces:  workspaces,
 		log:         log,
 	}
 }
 
+// authorize checks the caller's workspace role for an insight, writing the error
+// response on failure.
+func (h *ChatHandler) authorize(c *gin.Context, insightID uint, need models.WorkspaceRole) bool {
+	requestID := c.GetString("request_id")
+
+	_, _, err := h.workspaces.AuthorizeInsight(c.Request.Context(), middleware.MustGetUserID(c), insightID, need)
+	if err == nil {
+		return true
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Code:      "INSIGHT_NOT_FOUND",
+			Message:   "Insight not found.",
+			RequestID: requestID,
+		})
+	case errors.Is(err, services.ErrWorkspaceForbidden):
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Code:      models.ErrForbidden,
+			Message:   "You do not have permission to access this insight.",
+			RequestID: requestID,
+		})
+	default:
+		h.log.Error("Failed to authorize insight access",
+			zap.Uint("insight_id", insightID),
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      "INTERNAL_SERVER_ERROR",
+			Message:   "Failed to check permissions.",
+			RequestID: requestID,
+		})
+	}
+	return false
+}
+
+// respondBudgetExceeded writes the response for a request refused because a daily
+// LLM budget is spent.
+func (h *ChatHandler) respondBudgetExceeded(c *gin.Context) {
+	c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+		Code:      models.ErrLLMBudgetExceeded,
+		Message:   "Daily AI usage budget exceeded. Please try again tomorrow.",
+		RequestID: c.GetString("request_id"),
+	})
+}
+
 // Chat handles POST /api/v1/insights/:id/chat - streaming chat
 func (h *ChatHandler) Chat(c *gin.Context) {
 	requestID := c.GetString("request_id")
@@ -64,9 +117,18 @@ func (h *ChatHandler) Chat(c *gin.Context) {
 		return
 	}
 
+	if !h.authorize(c, uint(id), models.WorkspaceRoleEditor) {
+		return
+	}
+
 	// Start streaming
-	stream, err := h.chatService.ChatStream(c.Request.Context(), uint(id), req.Message, req.HighlightID)
+	stream, err := h.chatService.ChatStream(c.Request.Context(), uint(id), middleware.MustGetUserID(c), req.Message, req.HighlightID)
 	if err != nil {
+		if errors.Is(err, services.ErrLLMBudgetExceeded) {
+			h.respondBudgetExceeded(c)
+			return
+		}
+
 		// Check if it's a "not found" error
 		if strings.Contains(err.Error(), "not found") {
 			h.log.Error("Insight not found",
@@ -135,6 +197,10 @@ func (h *ChatHandler) GetHistory(c *gin.Context) {
 		return
 	}
 
+	if !h.authorize(c, uint(id), models.WorkspaceRoleViewer) {
+		return
+	}
+
 	history, err := h.chatService.GetChatHistory(c.Request.Context(), uint(id))
 	if err != nil {
 		h.log.Error("Failed to get chat history",
@@ -175,8 +241,17 @@ func (h *ChatHandler) AnalyzeEntities(c *gin.Context) {
 		return
 	}
 
-	result, err := h.chatService.AnalyzeEntities(c.Request.Context(), uint(id))
+	if !h.authorize(c, uint(id), models.WorkspaceRoleEditor) {
+		return
+	}
+
+	result, err := h.chatService.AnalyzeEntities(services.WithUsageUser(c.Request.Context(), middleware.MustGetUserID(c)), uint(id))
 	if err != nil {
+		if errors.Is(err, services.ErrLLMBudgetExceeded) {
+			h.respondBudgetExceeded(c)
+			return
+		}
+
 		errMsg := err.Error()
 		
 		// Check if it's a "insight not found" error (more precise check)
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"vibe-backend/internal/middleware"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/services"
+)
+
+// CommentHandler handles comments on insights, both from workspace members and from
+// readers of share links.
+type CommentHandler struct {
+	comments *services.CommentService
+	log      *zap.Logger
+}
+
+// NewCommentHandler creates a new CommentHandler.
+func NewCommentHandler(comments *services.CommentService, log *zap.Logger) *CommentHandler {
+	return &CommentHandler{
+		comments: comments,
+		log:      log,
+	}
+}
+
+// List handles GET /api/v1/insights/:id/comments
+func (h *CommentHandler) List(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+	insightID, ok := parseUintParam(c, "id", "Invalid insight ID.")
+	if !ok {
+		return
+	}
+
+	comments, err := h.comments.List(c.Request.Context(), userID, insightID)
+	if err != nil {
+		h.respondError(c, err, "Failed to list comments")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": comments})
+}
+
+// Create handles POST /api/v1/insights/:id/comments
+func (h *CommentHandler) Create(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	insightID, ok := parseUintParam(c, "id", "Invalid insight ID.")
+	if !ok {
+		return
+	}
+	var req models.CreateCommentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	comment, err := h.comments.Create(c.Request.Context(), user, insightID, &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to create comment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": comment})
+}
+
+// Moderate handles PATCH /api/v1/insights/:id/comments/:commentId - hide or lock (owner only)
+func (h *CommentHandler) Moderate(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+	insightID, ok := parseUintParam(c, "id", "Invalid insight ID.")
+	if !ok {
+		return
+	}
+	commentID, ok := parseUintParam(c, "commentId", "Invalid comment ID.")
+	if !ok {
+		return
+	}
+	var req models.ModerateCommentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	comment, err := h.comments.Moderate(c.Request.Context(), userID, insightID, commentID, &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to moderate comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": comment})
+}
+
+// Delete handles DELETE /api/v1/insights/:id/comments/:commentId
+func (h *CommentHandler) Delete(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+	insightID, ok := parseUintParam(c, "id", "Invalid insight ID.")
+	if !ok {
+		return
+	}
+	commentID, ok := parseUintParam(c, "commentId", "Invalid comment ID.")
+	if !ok {
+		return
+	}
+
+	if err := h.comments.Delete(c.Request.Context(), userID, insightID, commentID); err != nil {
+		h.respondError(c, err, "Failed to delete comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
+
+// ListShared handles GET /api/v1/shared/:token/comments
+func (h *CommentHandler) ListShared(c *gin.Context) {
+	comments, err := h.comments.ListShared(c.Request.Context(), c.Param("token"), shareAccessToken(c))
+	if err != nil {
+		h.respondError(c, err, "Failed to list shared comments")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": comments})
+}
+
+// CreateShared handles POST /api/v1/shared/:token/comments - signed-in users comment
+// under their account, anonymous readers give a display name
+func (h *CommentHandler) CreateShared(c *gin.Context) {
+	var req models.CreateCommentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	commenter := services.Commenter{
+		Name:      req.AuthorName,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if user, ok := middleware.GetUser(c); ok {
+		commenter.User = user
+	}
+
+	comment, err := h.comments.CreateShared(c.Request.Context(), c.Param("token"), shareAccessToken(c), commenter, &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to create shared comment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": comment})
+}
+
+// respondError maps service errors to HTTP responses.
+func (h *CommentHandler) respondError(c *gin.Context, err error, logMessage string) {
+	requestID := c.GetString("request_id")
+
+	status, code, message := http.StatusInternalServerError, models.ErrInternalServer, "An unexpected error occurred."
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "Insight not found."
+	case errors.Is(err, services.ErrWorkspaceForbidden):
+		status, code, message = http.StatusForbidden, models.ErrForbidden, "You do not have permission to do this."
+	case errors.Is(err, services.ErrCommentNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "Comment not found."
+	case errors.Is(err, services.ErrShareLinkNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "Share link not found or revoked."
+	case errors.Is(err, services.ErrShareLinkExpired):
+		status, code, message = http.StatusGone, "SHARE_EXPIRED", "Share link has expired."
+	case errors.Is(err, services.ErrSharePasswordRequired):
+		status, code, message = http.StatusUnauthorized, "SHARE_LOCKED", "This share link must be unlocked with its password first."
+	case errors.Is(err, services.ErrCommentsDisabled):
+		status, code, message = http.StatusForbidden, "COMMENTS_DISABLED", "Comments are disabled for this share link."
+	case errors.Is(err, services.ErrCommentThreadLocked):
+		status, code, message = http.StatusConflict, "THREAD_LOCKED", "This comment thread is locked."
+	case errors.Is(err, services.ErrCommentNotThread):
+		status, code, message = http.StatusBadRequest, models.ErrBadRequest, "Only the first comment of a thread can be locked."
+	case errors.Is(err, services.ErrCommentEmpty):
+		status, code, message = http.StatusBadRequest, models.ErrBadRequest, "Comment cannot be empty."
+	case errors.Is(err, services.ErrCommentAuthorRequired):
+		status, code, message = http.StatusBadRequest, "AUTHOR_NAME_REQUIRED", "Please enter a display name."
+	case errors.Is(err, services.ErrInvalidCommentAnchor):
+		status, code, message = http.StatusBadRequest, "INVALID_ANCHOR", "Only new threads can be anchored, to a highlight of this insight or a timestamp."
+	}
+
+	if status == http.StatusInternalServerError {
+		h.log.Error(logMessage,
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Code:      code,
+		Message:   message,
+		RequestID: requestID,
+	})
+}
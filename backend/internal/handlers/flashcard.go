@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"vibe-backend/internal/middleware"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+	"vibe-backend/internal/services"
+)
+
+// FlashcardHandler handles spaced-repetition flashcard requests.
+type FlashcardHandler struct {
+	flashcards *services.FlashcardService
+	log        *zap.Logger
+}
+
+// NewFlashcardHandler creates a new FlashcardHandler.
+func NewFlashcardHandler(flashcards *services.FlashcardService, log *zap.Logger) *FlashcardHandler {
+	return &FlashcardHandler{
+		flashcards: flashcards,
+		log:        log,
+	}
+}
+
+// Generate handles POST /api/v1/insights/:id/flashcards/generate - cards for highlights
+// and key points that have none yet
+func (h *FlashcardHandler) Generate(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+	insightID, ok := parseUintParam(c, "id", "Invalid insight ID.")
+	if !ok {
+		return
+	}
+
+	result, err := h.flashcards.Generate(c.Request.Context(), userID, insightID)
+	if err != nil {
+		h.respondError(c, err, "Failed to generate flashcards")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": result})
+}
+
+// List handles GET /api/v1/flashcards?insight_id=&sort=created|due&cursor=&limit=
+func (h *FlashcardHandler) List(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+
+	var insightID *uint
+	if value := c.Query("insight_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Code:      "INVALID_ID",
+				Message:   "Invalid insight ID.",
+				RequestID: c.GetString("request_id"),
+			})
+			return
+		}
+		parsed := uint(id)
+		insightID = &parsed
+	}
+	page, err := parsePageRequest(c, 50)
+	if err != nil {
+		h.respondError(c, err, "Invalid page parameters")
+		return
+	}
+
+	cards, err := h.flashcards.List(c.Request.Context(), userID, insightID, page)
+	if err != nil {
+		h.respondError(c, err, "Failed to list flashcards")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": cards})
+}
+
+// Due handles GET /api/v1/flashcards/due?limit= - cards to review now
+func (h *FlashcardHandler) Due(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	due, err := h.flashcards.Due(c.Request.Context(), userID, limit)
+	if err != nil {
+		h.respondError(c, err, "Failed to list due flashcards")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": due})
+}
+
+// Review handles POST /api/v1/flashcards/:id/review - grade a review (SM-2 quality 0-5)
+func (h *FlashcardHandler) Review(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+	cardID, ok := parseUintParam(c, "id", "Invalid flashcard ID.")
+	if !ok {
+		return
+	}
+	var req models.GradeFlashcardRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	card, err := h.flashcards.Grade(c.Request.Context(), userID, cardID, *req.Grade)
+	if err != nil {
+		h.respondError(c, err, "Failed to grade flashcard")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": card})
+}
+
+// Update handles PATCH /api/v1/flashcards/:id - edit, suspend or resume a card
+func (h *FlashcardHandler) Update(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+	cardID, ok := parseUintParam(c, "id", "Invalid flashcard ID.")
+	if !ok {
+		return
+	}
+	var req models.UpdateFlashcardRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	card, err := h.flashcards.Update(c.Request.Context(), userID, cardID, &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to update flashcard")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": card})
+}
+
+// Delete handles DELETE /api/v1/flashcards/:id
+func (h *FlashcardHandler) Delete(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+	cardID, ok := parseUintParam(c, "id", "Invalid flashcard ID.")
+	if !ok {
+		return
+	}
+
+	if err := h.flashcards.Delete(c.Request.Context(), userID, cardID); err != nil {
+		h.respondError(c, err, "Failed to delete flashcard")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Flashcard deleted"})
+}
+
+// respondError maps service errors to HTTP responses.
+func (h *FlashcardHandler) respondError(c *gin.Context, err error, logMessage string) {
+	requestID := c.GetString("request_id")
+
+	status, code, message := http.StatusInternalServerError, models.ErrInternalServer, "An unexpected error occurred."
+	switch {
+	case errors.Is(err, errInvalidPageParams), errors.Is(err, repository.ErrInvalidCursor), errors.Is(err, repository.ErrUnsupportedSort):
+		status, code, message = http.StatusBadRequest, models.ErrBadRequest, "Invalid pagination parameters."
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "Insight not found."
+	case errors.Is(err, services.ErrWorkspaceForbidden):
+		status, code, message = http.StatusForbidden, models.ErrForbidden, "You do not have access to this insight."
+	case errors.Is(err, services.ErrFlashcardNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "Flashcard not found."
+	case errors.Is(err, services.ErrNoFlashcardSources):
+		status, code, message = http.StatusUnprocessableEntity, "NO_FLASHCARD_SOURCES", "All highlights and key points of this insight already have flashcards."
+	case errors.Is(err, services.ErrLLMBudgetExceeded):
+		status, code, message = http.StatusTooManyRequests, models.ErrLLMBudgetExceeded, "Daily AI usage budget exceeded. Please try again tomorrow."
+	}
+
+	if status == http.StatusInternalServerError {
+		h.log.Error(logMessage,
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Code:      code,
+		Message:   message,
+		RequestID: requestID,
+	})
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"vibe-backend/internal/middleware"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/services"
+)
+
+// loginNonceCookie holds the nonce of a provider login started in this browser.
+const loginNonceCookie = "login_nonce"
+
+// IdentityHandler handles external login providers and linked identities.
+type IdentityHandler struct {
+	identityService *services.IdentityService
+	twoFactor       *services.TwoFactorService
+	audit           *services.AuditService
+	log             *zap.Logger
+}
+
+// NewIdentityHandler creates a new IdentityHandler.
+func NewIdentityHandler(identityService *services.IdentityService, twoFactor *services.TwoFactorService, audit *services.AuditService, log *zap.Logger) *IdentityHandler {
+	return &IdentityHandler{
+		identityService: identityService,
+		twoFactor:       twoFactor,
+		audit:           audit,
+		log:             log,
+	}
+}
+
+// ListProviders handles GET /api/v1/auth/providers - list configured login providers
+func (h *IdentityHandler) ListProviders(c *gin.Context) {
+	providers := h.identityService.Providers()
+
+	response := make([]models.LoginProviderResponse, len(providers))
+	for i, p := range providers {
+		response[i] = models.LoginProviderResponse{
+			Name:        p.Name(),
+			DisplayName: p.DisplayName(),
+			Type:        p.Type(),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// GetLoginURL handles GET /api/v1/auth/sso/:provider/url - start a provider sign-in
+func (h *IdentityHandler) GetLoginURL(c *gin.Context) {
+	h.beginLogin(c, 0)
+}
+
+// GetLinkURL handles POST /api/v1/auth/identities/:provider/link - start linking a provider
+func (h *IdentityHandler) GetLinkURL(c *gin.Context) {
+	h.beginLogin(c, middleware.MustGetUserID(c))
+}
+
+// beginLogin returns the authorization URL for a sign-in or link flow.
+func (h *IdentityHandler) beginLogin(c *gin.Context, linkUserID uint) {
+	requestID := c.GetString("request_id")
+	provider := c.Param("provider")
+
+	result, nonce, err := h.identityService.BeginLogin(c.Request.Context(), provider, linkUserID)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownProvider) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Code:      "UNKNOWN_PROVIDER",
+				Message:   "Login provider is not configured.",
+				RequestID: requestID,
+			})
+			return
+		}
+		h.log.Error("Failed to build provider authorization URL",
+			zap.String("request_id", requestID),
+			zap.String("provider", provider),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{
+			Code:      models.ErrorAuthConfig,
+			Message:   "Login provider is unavailable.",
+			RequestID: requestID,
+		})
+		return
+	}
+
+	setLoginNonceCookie(c, provider, nonce, int(services.LoginStateTTL.Seconds()))
+	c.JSON(http.StatusOK, result)
+}
+
+// Callback handles POST /api/v1/auth/sso/:provider/callback - complete sign-in or linking.
+// The flow must have been started in the same browser; link flows also need the
+// signed-in user who started them.
+func (h *IdentityHandler) Callback(c *gin.Context) {
+	requestID := c.GetString("request_id")
+	provider := c.Param("provider")
+
+	var req models.ProviderCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      "INVALID_REQUEST",
+			Message:   "Invalid request format.",
+			RequestID: requestID,
+		})
+		return
+	}
+
+	nonce, _ := c.Cookie(loginNonceCookie)
+	callerID, _ := middleware.GetUserID(c)
+	// The nonce is single-use whatever the outcome
+	setLoginNonceCookie(c, provider, "", -1)
+
+	result, err := h.identityService.CompleteLogin(c.Request.Context(), provider, req.Code, req.State, nonce, callerID)
+	if err != nil {
+		status, code, message := http.StatusUnauthorized, models.ErrorAuthFailed, "Sign-in with provider failed."
+		switch {
+		case errors.Is(err, services.ErrUnknownProvider):
+			status, code, message = http.StatusNotFound, "UNKNOWN_PROVIDER", "Login provider is not configured."
+		case errors.Is(err, services.ErrInvalidLoginState):
+			status, code, message = http.StatusBadRequest, "INVALID_STATE", "Login state is invalid or expired."
+		case errors.Is(err, services.ErrLinkNotAuthorized):
+			status, code, message = http.StatusForbidden, "LINK_NOT_AUTHORIZED", "Sign in as the account that started linking."
+		case errors.Is(err, services.ErrIdentityLinkedElsewhere):
+			status, code, message = http.StatusConflict, "IDENTITY_LINKED", "This account is already linked to another user."
+		case errors.Is(err, services.ErrEmailInUse):
+			status, code, message = http.StatusConflict, "USER_EXISTS", "An account with this email exists. Sign in and link the provider instead."
+		}
+		h.log.Warn("Provider login failed",
+			zap.String("request_id", requestID),
+			zap.String("provider", provider),
+			zap.Error(err),
+		)
+		c.JSON(status, models.ErrorResponse{
+			Code:      code,
+			Message:   message,
+			RequestID: requestID,
+		})
+		return
+	}
+
+	if result.Linked {
+		entry := auditEntry(c, models.AuditIdentityLinked)
+		entry.ActorID = &result.User.ID
+		entry.TargetType, entry.TargetID = models.AuditTargetUser, &result.User.ID
+		entry.Details = services.AuditDetails(map[string]interface{}{"provider": provider})
+		h.audit.Record(c.Request.Context(), entry)
+	}
+
+	// Provider sign-in does not replace the second factor
+	if result.User.TOTPEnabled {
+		challenge, err := h.twoFactor.IssueChallenge(result.User)
+		if err != nil {
+			h.log.Error("Failed to issue two-factor challenge",
+				zap.String("request_id", requestID),
+				zap.Uint("user_id", result.User.ID),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Code:      "INTERNAL_SERVER_ERROR",
+				Message:   "Failed to log in.",
+				RequestID: requestID,
+			})
+			return
+		}
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	h.log.Info("User logged in via provider",
+		zap.String("request_id", requestID),
+		zap.String("provider", provider),
+		zap.Uint("user_id", result.User.ID),
+		zap.Bool("linked", result.Linked),
+		zap.Bool("created", result.Created),
+	)
+	recordLogin(c, h.audit, result.User.ID, provider)
+
+	c.JSON(http.StatusOK, models.ProviderLoginResponse{
+		User:    result.User.ToResponse(),
+		APIKey:  result.User.APIKey,
+		Linked:  result.Linked,
+		Created: result.Created,
+	})
+}
+
+// setLoginNonceCookie stores the nonce of a provider login in an HttpOnly cookie sent
+// only to that provider's callback. A negative maxAge deletes it.
+func setLoginNonceCookie(c *gin.Context, provider, nonce string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(loginNonceCookie, nonce, maxAge, "/api/v1/auth/sso/"+provider+"/callback", "", secure, true)
+}
+
+// ListIdentities handles GET /api/v1/auth/identities - list linked identities
+func (h *IdentityHandler) ListIdentities(c *gin.Context) {
+	requestID := c.GetString("request_id")
+	userID := middleware.MustGetUserID(c)
+
+	identities, err := h.identityService.ListIdentities(c.Request.Context(), userID)
+	if err != nil {
+		h.log.Error("Failed to list identities",
+			zap.String("request_id", requestID),
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      "INTERNAL_SERVER_ERROR",
+			Message:   "Failed to list identities.",
+			RequestID: requestID,
+		})
+		return
+	}
+
+	response := make([]models.IdentityResponse, len(identities))
+	for i := range identities {
+		response[i] = identities[i].ToResponse()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// Unlink handles DELETE /api/v1/auth/identities/:id - unlink an identity
+func (h *IdentityHandler) Unlink(c *gin.Context) {
+	requestID := c.GetString("request_id")
+	userID := middleware.MustGetUserID(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      "INVALID_ID",
+			Message:   "Invalid identity ID format.",
+			RequestID: requestID,
+		})
+		return
+	}
+
+	if err := h.identityService.Unlink(c.Request.Context(), userID, uint(id)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrIdentityNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Code:      models.ErrNotFound,
+				Message:   "Identity not found.",
+				RequestID: requestID,
+			})
+		case errors.Is(err, services.ErrLastIdentity):
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Code:      "LAST_IDENTITY",
+				Message:   "Cannot unlink the only sign-in method for this account.",
+				RequestID: requestID,
+			})
+		default:
+			h.log.Error("Failed to unlink identity",
+				zap.String("request_id", requestID),
+				zap.Uint("user_id", userID),
+				zap.Uint64("identity_id", id),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Code:      "INTERNAL_SERVER_ERROR",
+				Message:   "Failed to unlink identity.",
+				RequestID: requestID,
+			})
+		}
+		return
+	}
+
+	h.log.Info("Identity unlinked",
+		zap.String("request_id", requestID),
+		zap.Uint("user_id", userID),
+		zap.Uint64("identity_id", id),
+	)
+	entry := auditEntry(c, models.AuditIdentityUnlinked)
+	identityID := uint(id)
+	entry.TargetType, entry.TargetID = models.AuditTargetIdentity, &identityID
+	h.audit.Record(c.Request.Context(), entry)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identity unlinked."})
+}
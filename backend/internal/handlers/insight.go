@@ -2,57 +2,84 @@ package handlers
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"regexp"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
 	"vibe-backend/internal/middleware"
 	"vibe-backend/internal/models"
 	"vibe-backend/internal/repository"
+	"vibe-backend/internal/services"
 )
 
 // InsightProcessor defines the interface for async insight processing.
 type InsightProcessor interface {
 	ProcessInsightAsync(ctx context.Context, insightID uint)
+	CheckBudget(ctx context.Context, userID uint) error
 }
 
 // InsightHandler handles InsightFlow HTTP requests.
 type InsightHandler struct {
-	repo      *repository.InsightRepository
-	processor InsightProcessor
-	log       *zap.Logger
+	repo       *repository.InsightRepository
+	processor  InsightProcessor
+	workspaces *services.WorkspaceService
+	search     *services.SearchService
+	shares     *services.ShareService
+	audit      *services.AuditService
+	log        *zap.Logger
 }
 
 // NewInsightHandler creates a new InsightHandler.
-func NewInsightHandler(repo *repository.InsightRepository, processor InsightProcessor, log *zap.Logger) *InsightHandler {
+func NewInsightHandler(repo *repository.InsightRepository, processor InsightProcessor, workspaces *services.WorkspaceService, search *services.SearchService, shares *services.ShareService, audit *services.AuditService, log *zap.Logger) *InsightHandler {
 	return &InsightHandler{
-		repo:      repo,
-		processor: processor,
-		log:       log,
+		repo:       repo,
+		processor:  processor,
+		workspaces: workspaces,
+		search:     search,
+		shares:     shares,
+		audit:      audit,
+		log:        log,
 	}
 }
 
-// List returns a list of insights grouped by date for the current user.
+// List returns a page of insights for a workspace (the current user's personal
+// workspace unless ?workspace_id= is given), grouped by date when sorted by creation.
+// Paging uses ?cursor= with the previous page's next_cursor; see parsePageRequest.
 // GET /api/v1/insights
 func (h *InsightHandler) List(c *gin.Context) {
-	userID := middleware.MustGetUserID(c)
+	workspace, _, ok := h.resolveWorkspace(c, c.Query("workspace_id"), models.WorkspaceRoleViewer)
+	if !ok {
+		return
+	}
 
-	search := c.Query("search")
-	limitStr := c.DefaultQuery("limit", "50")
-	limit, _ := strconv.Atoi(limitStr)
+	filter, ok := parseInsightFilter(c)
+	if !ok {
+		return
+	}
+	page, err := parsePageRequest(c, 50)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "无效的分页参数",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
 
-	result, err := h.repo.GetByUserIDGroupedByDate(c.Request.Context(), userID, search, limit)
+	result, err := h.repo.GetByWorkspaceGroupedByDate(c.Request.Context(), workspace.ID, filter, page)
 	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) || errors.Is(err, repository.ErrUnsupportedSort) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":      "无效的分页游标或排序方式",
+				"request_id": c.GetString("request_id"),
+			})
+			return
+		}
 		h.log.Error("Failed to get insights", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":      "获取 Insight 列表失败",
@@ -95,17 +122,15 @@ func (h *InsightHandler) Get(c *gin.Context) {
 		return
 	}
 
-	// Verify user ownership
-	if insight.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":      "无权限访问此 Insight",
-			"request_id": c.GetString("request_id"),
-		})
+	// Verify workspace membership
+	role, ok := h.checkInsightRole(c, userID, insight, models.WorkspaceRoleViewer, "无权限访问此 Insight")
+	if !ok {
 		return
 	}
 
 	// Convert to response format
 	response := h.convertToDetailResponse(insight)
+	response.Role = role
 	c.JSON(http.StatusOK, response)
 }
 
@@ -123,6 +148,16 @@ func (h *InsightHandler) Create(c *gin.Context) {
 
 	userID := middleware.MustGetUserID(c)
 
+	// Resolve the target workspace (personal by default); adding content requires editor
+	var workspaceParam string
+	if req.WorkspaceID != nil {
+		workspaceParam = strconv.FormatUint(uint64(*req.WorkspaceID), 10)
+	}
+	workspace, _, ok := h.resolveWorkspace(c, workspaceParam, models.WorkspaceRoleEditor)
+	if !ok {
+		return
+	}
+
 	// Set default target language
 	if req.TargetLang == "" {
 		req.TargetLang = "zh"
@@ -136,12 +171,12 @@ func (h *InsightHandler) Create(c *gin.Context) {
 	var err error
 	
 	if sourceID != "" {
-		existingInsight, err = h.repo.GetBySourceID(c.Request.Context(), sourceID, userID)
+		existingInsight, err = h.repo.GetBySourceID(c.Request.Context(), sourceID, workspace.ID)
 	}
 	
 	// If not found by source_id, try by source_url (for old records without source_id)
 	if existingInsight == nil || err != nil {
-		existingInsight, err = h.repo.GetBySourceURL(c.Request.Context(), req.SourceURL, userID)
+		existingInsight, err = h.repo.GetBySourceURL(c.Request.Context(), req.SourceURL, workspace.ID)
 	}
 	
 	if err == nil && existingInsight != nil {
@@ -184,12 +219,17 @@ func (h *InsightHandler) Create(c *gin.Context) {
 		)
 	}
 
+	if !h.checkLLMBudget(c, userID) {
+		return
+	}
+
 	insight := &models.Insight{
-		UserID:     userID,
-		SourceURL:  req.SourceURL,
-		SourceID:   sourceID,
-		TargetLang: req.TargetLang,
-		Status:     models.InsightStatusPending,
+		UserID:      userID,
+		WorkspaceID: &workspace.ID,
+		SourceURL:   req.SourceURL,
+		SourceID:    sourceID,
+		TargetLang:  req.TargetLang,
+		Status:      models.InsightStatusPending,
 	}
 
 	if err := h.repo.Create(c.Request.Context(), insight); err != nil {
@@ -203,8 +243,8 @@ func (h *InsightHandler) Create(c *gin.Context) {
 
 	// Trigger async processing if processor is available
 	if h.processor != nil {
-		// Use background context for async processing since request context may be cancelled
-		go h.processor.ProcessInsightAsync(context.Background(), insight.ID)
+		// Detach from the request's cancellation, but keep its trace and request ID
+		go h.processor.ProcessInsightAsync(context.WithoutCancel(c.Request.Context()), insight.ID)
 		h.log.Info("Triggered async insight processing", zap.Uint("insight_id", insight.ID))
 	}
 
@@ -217,6 +257,23 @@ func (h *InsightHandler) Create(c *gin.Context) {
 	})
 }
 
+// checkLLMBudget checks that userID has LLM budget left for processing, writing the
+// error response if not.
+func (h *InsightHandler) checkLLMBudget(c *gin.Context, userID uint) bool {
+	if h.processor == nil {
+		return true
+	}
+	if err := h.processor.CheckBudget(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":      "今日 AI 用量已达上限，请明天再试",
+			"code":       models.ErrLLMBudgetExceeded,
+			"request_id": c.GetString("request_id"),
+		})
+		return false
+	}
+	return true
+}
+
 // extractSourceID extracts the source ID from a URL (e.g., YouTube video ID)
 func extractSourceID(sourceURL string) string {
 	// YouTube URL patterns:
@@ -247,6 +304,7 @@ func extractSourceID(sourceURL string) string {
 // Update updates an existing insight.
 // PATCH /api/v1/insights/:id
 func (h *InsightHandler) Update(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
@@ -274,6 +332,10 @@ func (h *InsightHandler) Update(c *gin.Context) {
 		return
 	}
 
+	if _, ok := h.checkInsightRole(c, userID, insight, models.WorkspaceRoleEditor, "无权限修改此 Insight"); !ok {
+		return
+	}
+
 	// Bind update fields
 	var updates struct {
 		Title      *string `json:"title"`
@@ -303,10 +365,14 @@ func (h *InsightHandler) Update(c *gin.Context) {
 		return
 	}
 
+	h.search.Reindex("insight", insight.ID, func() error {
+		return h.search.IndexInsight(c.Request.Context(), insight.ID)
+	})
+
 	c.JSON(http.StatusOK, gin.H{"data": insight})
 }
 
-// Delete soft-deletes an insight.
+// Delete moves an insight to the trash, from where it can be restored until purged.
 // DELETE /api/v1/insights/:id
 func (h *InsightHandler) Delete(c *gin.Context) {
 	userID := middleware.MustGetUserID(c)
@@ -338,8 +404,12 @@ func (h *InsightHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	// Verify user ownership
-	if insight.UserID != userID {
+	// Creators may delete their own insights; workspace owners may delete any
+	role, ok := h.checkInsightRole(c, userID, insight, models.WorkspaceRoleEditor, "无权限删除此 Insight")
+	if !ok {
+		return
+	}
+	if !services.CanModifyAnnotation(role, userID, insight.UserID) {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error":      "无权限删除此 Insight",
 			"request_id": c.GetString("request_id"),
@@ -363,7 +433,9 @@ func (h *InsightHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Insight 已删除"})
+	recordInsightAudit(c, h.audit, models.AuditInsightDeleted, insight)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Insight 已移至回收站"})
 }
 
 // --- Highlight endpoints ---
@@ -392,30 +464,8 @@ func (h *InsightHandler) CreateHighlight(c *gin.Context) {
 
 	userID := middleware.MustGetUserID(c)
 
-	// Verify insight exists and user owns it
-	insight, err := h.repo.GetByID(c.Request.Context(), uint(insightID))
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error":      "Insight 不存在",
-				"request_id": c.GetString("request_id"),
-			})
-			return
-		}
-		h.log.Error("Failed to get insight", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "获取 Insight 失败",
-			"request_id": c.GetString("request_id"),
-		})
-		return
-	}
-
-	// Verify user ownership
-	if insight.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":      "无权限操作此 Insight",
-			"request_id": c.GetString("request_id"),
-		})
+	// Verify insight exists and user may annotate it
+	if _, _, ok := h.authorizeInsight(c, userID, uint(insightID), models.WorkspaceRoleEditor, "无权限操作此 Insight"); !ok {
 		return
 	}
 
@@ -443,6 +493,10 @@ func (h *InsightHandler) CreateHighlight(c *gin.Context) {
 		return
 	}
 
+	h.search.Reindex("highlight", highlight.InsightID, func() error {
+		return h.search.IndexHighlight(c.Request.Context(), highlight)
+	})
+
 	c.JSON(http.StatusCreated, gin.H{"data": highlight})
 }
 
@@ -459,6 +513,10 @@ func (h *InsightHandler) ListHighlights(c *gin.Context) {
 		return
 	}
 
+	if _, _, ok := h.authorizeInsight(c, middleware.MustGetUserID(c), uint(insightID), models.WorkspaceRoleViewer, "无权限访问此 Insight"); !ok {
+		return
+	}
+
 	highlights, err := h.repo.GetHighlightsByInsightID(c.Request.Context(), uint(insightID))
 	if err != nil {
 		h.log.Error("Failed to get highlights", zap.Error(err))
@@ -502,10 +560,13 @@ func (h *InsightHandler) UpdateHighlight(c *gin.Context) {
 		return
 	}
 
-	// Verify user ownership
+	// Authors may edit their own highlights; workspace owners may edit any
 	userID := middleware.MustGetUserID(c)
-	insight, err := h.repo.GetByID(c.Request.Context(), highlight.InsightID)
-	if err != nil || insight.UserID != userID {
+	_, role, ok := h.authorizeInsight(c, userID, highlight.InsightID, models.WorkspaceRoleEditor, "无权限修改此高亮")
+	if !ok {
+		return
+	}
+	if !services.CanModifyAnnotation(role, userID, highlight.UserID) {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error":      "无权限修改此高亮",
 			"request_id": c.GetString("request_id"),
@@ -541,6 +602,10 @@ func (h *InsightHandler) UpdateHighlight(c *gin.Context) {
 		return
 	}
 
+	h.search.Reindex("highlight", highlight.InsightID, func() error {
+		return h.search.IndexHighlight(c.Request.Context(), highlight)
+	})
+
 	c.JSON(http.StatusOK, gin.H{"data": highlight})
 }
 
@@ -576,8 +641,11 @@ func (h *InsightHandler) DeleteHighlight(c *gin.Context) {
 		return
 	}
 
-	insight, err := h.repo.GetByID(c.Request.Context(), highlight.InsightID)
-	if err != nil || insight.UserID != userID {
+	_, role, ok := h.authorizeInsight(c, userID, highlight.InsightID, models.WorkspaceRoleEditor, "无权限删除此高亮")
+	if !ok {
+		return
+	}
+	if !services.CanModifyAnnotation(role, userID, highlight.UserID) {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error":      "无权限删除此高亮",
 			"request_id": c.GetString("request_id"),
@@ -594,12 +662,16 @@ func (h *InsightHandler) DeleteHighlight(c *gin.Context) {
 		return
 	}
 
+	h.search.Reindex("highlight", highlight.InsightID, func() error {
+		return h.search.RemoveHighlight(c.Request.Context(), highlight.ID)
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "高亮已删除"})
 }
 
 // --- Chat endpoints ---
 
-// ListChatMessages returns all chat messages for an insight.
+// ListChatMessages returns a page of chat messages for an insight, newest first.
 // GET /api/v1/insights/:id/chat
 func (h *InsightHandler) ListChatMessages(c *gin.Context) {
 	insightIDStr := c.Param("id")
@@ -612,12 +684,59 @@ func (h *InsightHandler) ListChatMessages(c *gin.Context) {
 		return
 	}
 
+	if _, _, ok := h.authorizeInsight(c, middleware.MustGetUserID(c), uint(insightID), models.WorkspaceRoleViewer, "无权限访问此 Insight"); !ok {
+		return
+	}
+
+	// Offset paging is kept for older clients; new clients page with cursor
+	if c.Query("offset") != "" && c.Query("cursor") == "" {
+		h.listChatMessagesByOffset(c, uint(insightID))
+		return
+	}
+
+	page, err := parsePageRequest(c, 20)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "无效的分页参数",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+
+	messages, total, pageInfo, err := h.repo.ListChatMessagesPage(c.Request.Context(), uint(insightID), page)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) || errors.Is(err, repository.ErrUnsupportedSort) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":      "无效的分页游标或排序方式",
+				"request_id": c.GetString("request_id"),
+			})
+			return
+		}
+		h.log.Error("Failed to get chat messages", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "获取对话历史失败",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        messages,
+		"limit":       pageInfo.Limit,
+		"next_cursor": pageInfo.NextCursor,
+		"has_more":    pageInfo.HasMore,
+		"total":       total,
+	})
+}
+
+// listChatMessagesByOffset serves ListChatMessages with limit/offset paging.
+func (h *InsightHandler) listChatMessagesByOffset(c *gin.Context, insightID uint) {
 	limitStr := c.DefaultQuery("limit", "20")
 	offsetStr := c.DefaultQuery("offset", "0")
 	limit, _ := strconv.Atoi(limitStr)
 	offset, _ := strconv.Atoi(offsetStr)
 
-	messages, total, err := h.repo.GetChatMessagesByInsightIDPaginated(c.Request.Context(), uint(insightID), limit, offset)
+	messages, total, err := h.repo.GetChatMessagesByInsightIDPaginated(c.Request.Context(), insightID, limit, offset)
 	if err != nil {
 		h.log.Error("Failed to get chat messages", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -660,30 +779,8 @@ func (h *InsightHandler) CreateChatMessage(c *gin.Context) {
 
 	userID := middleware.MustGetUserID(c)
 
-	// Verify insight exists and user owns it
-	insight, err := h.repo.GetByID(c.Request.Context(), uint(insightID))
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error":      "Insight 不存在",
-				"request_id": c.GetString("request_id"),
-			})
-			return
-		}
-		h.log.Error("Failed to get insight", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "获取 Insight 失败",
-			"request_id": c.GetString("request_id"),
-		})
-		return
-	}
-
-	// Verify user ownership
-	if insight.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":      "无权限操作此 Insight",
-			"request_id": c.GetString("request_id"),
-		})
+	// Verify insight exists and user may annotate it
+	if _, _, ok := h.authorizeInsight(c, userID, uint(insightID), models.WorkspaceRoleEditor, "无权限操作此 Insight"); !ok {
 		return
 	}
 
@@ -704,6 +801,10 @@ func (h *InsightHandler) CreateChatMessage(c *gin.Context) {
 		return
 	}
 
+	h.search.Reindex("chat", message.InsightID, func() error {
+		return h.search.IndexChatMessage(c.Request.Context(), message)
+	})
+
 	c.JSON(http.StatusCreated, gin.H{
 		"data": models.ChatResponse{
 			ID:      message.ID,
@@ -726,6 +827,11 @@ func (h *InsightHandler) ClearChatHistory(c *gin.Context) {
 		return
 	}
 
+	// Chat history is shared by the workspace, so only owners may clear it
+	if _, _, ok := h.authorizeInsight(c, middleware.MustGetUserID(c), uint(insightID), models.WorkspaceRoleOwner, "无权限清空对话历史"); !ok {
+		return
+	}
+
 	if err := h.repo.DeleteChatMessagesByInsightID(c.Request.Context(), uint(insightID)); err != nil {
 		h.log.Error("Failed to clear chat history", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -735,6 +841,10 @@ func (h *InsightHandler) ClearChatHistory(c *gin.Context) {
 		return
 	}
 
+	h.search.Reindex("chat", uint(insightID), func() error {
+		return h.search.RemoveChatMessages(c.Request.Context(), uint(insightID))
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "对话历史已清空"})
 }
 
@@ -752,20 +862,12 @@ func (h *InsightHandler) Process(c *gin.Context) {
 	}
 
 	// Get the insight
-	insight, err := h.repo.GetByID(c.Request.Context(), uint(id))
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error":      "Insight 不存在",
-				"request_id": c.GetString("request_id"),
-			})
-			return
-		}
-		h.log.Error("Failed to get insight", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "获取 Insight 失败",
-			"request_id": c.GetString("request_id"),
-		})
+	userID := middleware.MustGetUserID(c)
+	insight, _, ok := h.authorizeInsight(c, userID, uint(id), models.WorkspaceRoleEditor, "无权限操作此 Insight")
+	if !ok {
+		return
+	}
+	if !h.checkLLMBudget(c, userID) {
 		return
 	}
 
@@ -783,8 +885,8 @@ func (h *InsightHandler) Process(c *gin.Context) {
 
 	// Trigger async reprocessing if processor is available
 	if h.processor != nil {
-		// Use background context for async processing since request context may be cancelled
-		go h.processor.ProcessInsightAsync(context.Background(), insight.ID)
+		// Detach from the request's cancellation, but keep its trace and request ID
+		go h.processor.ProcessInsightAsync(context.WithoutCancel(c.Request.Context()), insight.ID)
 		h.log.Info("Triggered manual insight reprocessing", zap.Uint("insight_id", insight.ID))
 	}
 
@@ -797,304 +899,153 @@ func (h *InsightHandler) Process(c *gin.Context) {
 	})
 }
 
-// ShareInsight creates or updates a share configuration for an insight.
-// POST /api/v1/insights/:id/share
-func (h *InsightHandler) ShareInsight(c *gin.Context) {
-	insightIDStr := c.Param("id")
-	insightID, err := strconv.ParseUint(insightIDStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":      "无效的 Insight ID",
-			"request_id": c.GetString("request_id"),
-		})
-		return
+// parseInsightFilter reads list filters from the query string:
+// search, tag_id (repeatable; all must match), collection_id (an ID or "none" for
+// unfiled insights), source_type, status and the from/to creation date range.
+// It writes a 400 response on bad input.
+func parseInsightFilter(c *gin.Context) (models.InsightFilter, bool) {
+	filter := models.InsightFilter{
+		Search:     c.Query("search"),
+		SourceType: models.SourceType(c.Query("source_type")),
+		Status:     models.InsightStatus(c.Query("status")),
 	}
 
-	var req models.ShareInsightRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	invalid := func(message string) (models.InsightFilter, bool) {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":      err.Error(),
+			"error":      message,
 			"request_id": c.GetString("request_id"),
 		})
-		return
+		return filter, false
 	}
 
-	userID := middleware.MustGetUserID(c)
+	for _, value := range c.QueryArray("tag_id") {
+		tagID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return invalid("无效的标签 ID")
+		}
+		filter.TagIDs = append(filter.TagIDs, uint(tagID))
+	}
 
-	// Verify insight exists and user owns it
-	insight, err := h.repo.GetByID(c.Request.Context(), uint(insightID))
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error":      "Insight 不存在",
-				"request_id": c.GetString("request_id"),
-			})
-			return
+	switch collection := c.Query("collection_id"); collection {
+	case "":
+	case "none":
+		filter.Unfiled = true
+	default:
+		collectionID, err := strconv.ParseUint(collection, 10, 32)
+		if err != nil {
+			return invalid("无效的合集 ID")
 		}
-		h.log.Error("Failed to get insight", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "获取 Insight 失败",
-			"request_id": c.GetString("request_id"),
-		})
-		return
+		id := uint(collectionID)
+		filter.CollectionID = &id
 	}
 
-	if insight.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":      "无权限分享此 Insight",
+	created, err := parseDateRange(c)
+	if err != nil {
+		return invalid("无效的日期范围")
+	}
+	filter.Created = created
+
+	return filter, true
+}
+
+// resolveWorkspace resolves the workspace_id parameter (empty means the personal
+// workspace) and checks the caller's role, writing the error response on failure.
+func (h *InsightHandler) resolveWorkspace(c *gin.Context, workspaceParam string, need models.WorkspaceRole) (*models.Workspace, models.WorkspaceRole, bool) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":      "用户未登录",
 			"request_id": c.GetString("request_id"),
 		})
-		return
+		return nil, "", false
 	}
 
-	// Generate share token if not exists
-	if insight.ShareToken == nil || *insight.ShareToken == "" {
-		token, err := h.generateShareToken()
+	var workspaceID *uint
+	if workspaceParam != "" {
+		id, err := strconv.ParseUint(workspaceParam, 10, 32)
 		if err != nil {
-			h.log.Error("Failed to generate share token", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":      "生成分享链接失败",
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":      "无效的 Workspace ID",
 				"request_id": c.GetString("request_id"),
 			})
-			return
+			return nil, "", false
 		}
-		insight.ShareToken = &token
+		wid := uint(id)
+		workspaceID = &wid
 	}
 
-	// Hash password if provided
-	if req.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-		if err != nil {
-			h.log.Error("Failed to hash password", zap.Error(err))
+	workspace, role, err := h.workspaces.Resolve(c.Request.Context(), user, workspaceID, need)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrWorkspaceNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":      "Workspace 不存在",
+				"request_id": c.GetString("request_id"),
+			})
+		case errors.Is(err, services.ErrWorkspaceForbidden):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":      "无权限在此 Workspace 中操作",
+				"request_id": c.GetString("request_id"),
+			})
+		default:
+			h.log.Error("Failed to resolve workspace", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":      "密码加密失败",
+				"error":      "获取 Workspace 失败",
 				"request_id": c.GetString("request_id"),
 			})
-			return
 		}
-		insight.SharePassword = string(hashedPassword)
-	} else {
-		insight.SharePassword = ""
-	}
-
-	// Set share configuration
-	shareConfig := models.ShareConfigData{
-		IncludeSummary:    req.IncludeSummary,
-		IncludeKeyPoints:  req.IncludeKeyPoints,
-		IncludeHighlights: req.IncludeHighlights,
-		IncludeChat:       req.IncludeChat,
-	}
-
-	shareConfigJSON, err := json.Marshal(shareConfig)
-	if err != nil {
-		h.log.Error("Failed to marshal share config", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "保存分享配置失败",
-			"request_id": c.GetString("request_id"),
-		})
-		return
-	}
-	insight.ShareConfig = shareConfigJSON
-	insight.IsPublic = req.IsPublic
-	now := time.Now()
-	insight.SharedAt = &now
-
-	if err := h.repo.Update(c.Request.Context(), insight); err != nil {
-		h.log.Error("Failed to update insight share config", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "更新分享配置失败",
-			"request_id": c.GetString("request_id"),
-		})
-		return
+		return nil, "", false
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"data": models.ShareInsightResponse{
-			ShareToken: *insight.ShareToken,
-			ShareURL:   "/api/v1/shared/" + *insight.ShareToken,
-		},
-	})
+	return workspace, role, true
 }
 
-// DeleteShare removes the share configuration for an insight.
-// DELETE /api/v1/insights/:id/share
-func (h *InsightHandler) DeleteShare(c *gin.Context) {
-	insightIDStr := c.Param("id")
-	insightID, err := strconv.ParseUint(insightIDStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":      "无效的 Insight ID",
-			"request_id": c.GetString("request_id"),
-		})
-		return
-	}
-
-	userID := middleware.MustGetUserID(c)
-
-	insight, err := h.repo.GetByID(c.Request.Context(), uint(insightID))
+// authorizeInsight loads an insight and checks the caller's workspace role,
+// writing the error response on failure.
+func (h *InsightHandler) authorizeInsight(c *gin.Context, userID, insightID uint, need models.WorkspaceRole, forbiddenMsg string) (*models.Insight, models.WorkspaceRole, bool) {
+	insight, err := h.repo.GetByID(c.Request.Context(), insightID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":      "Insight 不存在",
 				"request_id": c.GetString("request_id"),
 			})
-			return
+			return nil, "", false
 		}
 		h.log.Error("Failed to get insight", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":      "获取 Insight 失败",
 			"request_id": c.GetString("request_id"),
 		})
-		return
+		return nil, "", false
 	}
 
-	if insight.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":      "无权限操作此 Insight",
-			"request_id": c.GetString("request_id"),
-		})
-		return
-	}
-
-	// Clear share configuration
-	insight.ShareToken = nil
-	insight.SharePassword = ""
-	insight.ShareConfig = nil
-	insight.IsPublic = false
-	insight.SharedAt = nil
-
-	if err := h.repo.Update(c.Request.Context(), insight); err != nil {
-		h.log.Error("Failed to delete share config", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "删除分享配置失败",
-			"request_id": c.GetString("request_id"),
-		})
-		return
+	role, ok := h.checkInsightRole(c, userID, insight, need, forbiddenMsg)
+	if !ok {
+		return nil, "", false
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "分享已取消"})
+	return insight, role, true
 }
 
-// GetShared returns a publicly shared insight.
-// GET /api/v1/shared/:token
-func (h *InsightHandler) GetShared(c *gin.Context) {
-	token := c.Param("token")
-	if token == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":      "无效的分享链接",
+// checkInsightRole checks the caller has at least the needed role for an insight,
+// writing the error response on failure.
+func (h *InsightHandler) checkInsightRole(c *gin.Context, userID uint, insight *models.Insight, need models.WorkspaceRole, forbiddenMsg string) (models.WorkspaceRole, bool) {
+	role, err := h.workspaces.InsightRole(c.Request.Context(), userID, insight)
+	if err != nil && !errors.Is(err, services.ErrWorkspaceForbidden) {
+		h.log.Error("Failed to check workspace role", zap.Error(err), zap.Uint("insight_id", insight.ID))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "获取 Insight 失败",
 			"request_id": c.GetString("request_id"),
 		})
-		return
+		return "", false
 	}
-
-	// Check for password in request
-	password := c.Query("password")
-
-	insight, err := h.repo.GetByShareToken(c.Request.Context(), token)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error":      "分享链接不存在或已过期",
-				"request_id": c.GetString("request_id"),
-			})
-			return
-		}
-		h.log.Error("Failed to get shared insight", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "获取分享内容失败",
+	if err != nil || !role.Allows(need) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":      forbiddenMsg,
 			"request_id": c.GetString("request_id"),
 		})
-		return
+		return "", false
 	}
-
-	// Verify password if required
-	if insight.SharePassword != "" {
-		if password == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":         "此分享需要密码访问",
-				"requires_auth": true,
-				"request_id":    c.GetString("request_id"),
-			})
-			return
-		}
-
-		if err := bcrypt.CompareHashAndPassword([]byte(insight.SharePassword), []byte(password)); err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":      "密码错误",
-				"request_id": c.GetString("request_id"),
-			})
-			return
-		}
-	}
-
-	// Parse share configuration
-	var shareConfig models.ShareConfigData
-	if len(insight.ShareConfig) > 0 {
-		if err := json.Unmarshal(insight.ShareConfig, &shareConfig); err != nil {
-			h.log.Warn("Failed to unmarshal share config, using defaults", zap.Error(err))
-			shareConfig = models.ShareConfigData{
-				IncludeSummary:    true,
-				IncludeKeyPoints:  true,
-				IncludeHighlights: false,
-				IncludeChat:       false,
-			}
-		}
-	} else {
-		// Default config if none set
-		shareConfig = models.ShareConfigData{
-			IncludeSummary:    true,
-			IncludeKeyPoints:  true,
-			IncludeHighlights: false,
-			IncludeChat:       false,
-		}
-	}
-
-	// Build filtered response based on share configuration
-	response := models.SharedInsightResponse{
-		Title:        insight.Title,
-		Author:       insight.Author,
-		ThumbnailURL: insight.ThumbnailURL,
-		SharedBy:     "用户",
-		SharedAt:     insight.SharedAt,
-		SourceType:   insight.SourceType,
-		SourceURL:    insight.SourceURL,
-		Content:      models.SharedContent{},
-	}
-
-	// Apply content filtering based on share config
-	if shareConfig.IncludeSummary {
-		response.Content.Summary = insight.Summary
-	}
-
-	if shareConfig.IncludeKeyPoints {
-		var keyPoints []string
-		if len(insight.KeyPoints) > 0 {
-			if err := json.Unmarshal(insight.KeyPoints, &keyPoints); err != nil {
-				h.log.Warn("Failed to unmarshal key_points", zap.Error(err))
-			} else {
-				response.Content.KeyPoints = keyPoints
-			}
-		}
-	}
-
-	if shareConfig.IncludeHighlights && len(insight.Highlights) > 0 {
-		response.Content.Highlights = insight.Highlights
-	}
-
-	if shareConfig.IncludeChat && len(insight.ChatMessages) > 0 {
-		response.Content.Chat = insight.ChatMessages
-	}
-
-	c.JSON(http.StatusOK, gin.H{"data": response})
-}
-
-// generateShareToken generates a cryptographically secure random token.
-func (h *InsightHandler) generateShareToken() (string, error) {
-	bytes := make([]byte, 32) // 256 bits
-	if _, err := rand.Read(bytes); err != nil {
-		return "", err
-	}
-	return hex.EncodeToString(bytes), nil
+	return role, true
 }
 
 // convertToDetailResponse converts an Insight model to InsightDetailResponse.
@@ -1117,8 +1068,26 @@ func (h *InsightHandler) convertToDetailResponse(insight *models.Insight) *model
 		}
 	}
 
+	tags := insight.Tags
+	if tags == nil {
+		tags = []models.Tag{}
+	}
+
+	chapters := make([]models.ChapterResponse, len(insight.Chapters))
+	for i, chapter := range insight.Chapters {
+		chapters[i] = models.ChapterResponse{
+			Title:     chapter.Title,
+			Timestamp: chapter.Timestamp,
+			Seconds:   chapter.Seconds,
+			Source:    chapter.Source,
+		}
+	}
+
 	return &models.InsightDetailResponse{
 		ID:           insight.ID,
+		WorkspaceID:  insight.WorkspaceID,
+		CollectionID: insight.CollectionID,
+		Tags:         tags,
 		SourceType:   insight.SourceType,
 		SourceURL:    insight.SourceURL,
 		SourceID:     insight.SourceID,
@@ -1132,6 +1101,7 @@ func (h *InsightHandler) convertToDetailResponse(insight *models.Insight) *model
 		RawContent:   insight.RawContent,
 		TransContent: insight.TransContent,
 		Transcripts:  transcripts,
+		Chapters:     chapters,
 		Status:       insight.Status,
 		Highlights:   insight.Highlights,
 		CreatedAt:    insight.CreatedAt,
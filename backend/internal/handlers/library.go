@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"vibe-backend/internal/middleware"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/services"
+)
+
+// LibraryHandler handles tag, collection and bulk organization requests.
+type LibraryHandler struct {
+	library *services.LibraryService
+	log     *zap.Logger
+}
+
+// NewLibraryHandler creates a new LibraryHandler.
+func NewLibraryHandler(library *services.LibraryService, log *zap.Logger) *LibraryHandler {
+	return &LibraryHandler{
+		library: library,
+		log:     log,
+	}
+}
+
+// Sidebar handles GET /api/v1/library/sidebar?workspace_id= - tag counts, collection tree and source type counts
+func (h *LibraryHandler) Sidebar(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	workspaceID, ok := parseWorkspaceQuery(c)
+	if !ok {
+		return
+	}
+
+	sidebar, err := h.library.Sidebar(c.Request.Context(), user, workspaceID)
+	if err != nil {
+		h.respondError(c, err, "Failed to load library sidebar")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": sidebar})
+}
+
+// ListTags handles GET /api/v1/tags?workspace_id=
+func (h *LibraryHandler) ListTags(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	workspaceID, ok := parseWorkspaceQuery(c)
+	if !ok {
+		return
+	}
+
+	tags, err := h.library.ListTags(c.Request.Context(), user, workspaceID)
+	if err != nil {
+		h.respondError(c, err, "Failed to list tags")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": tags})
+}
+
+// CreateTag handles POST /api/v1/tags
+func (h *LibraryHandler) CreateTag(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	var req models.CreateTagRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tag, err := h.library.CreateTag(c.Request.Context(), user, &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to create tag")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": tag})
+}
+
+// UpdateTag handles PATCH /api/v1/tags/:id - rename or recolor a tag
+func (h *LibraryHandler) UpdateTag(c *gin.Context) {
+	tagID, ok := parseUintParam(c, "id", "Invalid tag ID format.")
+	if !ok {
+		return
+	}
+	var req models.UpdateTagRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tag, err := h.library.UpdateTag(c.Request.Context(), middleware.MustGetUserID(c), tagID, &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to update tag")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": tag})
+}
+
+// DeleteTag handles DELETE /api/v1/tags/:id
+func (h *LibraryHandler) DeleteTag(c *gin.Context) {
+	tagID, ok := parseUintParam(c, "id", "Invalid tag ID format.")
+	if !ok {
+		return
+	}
+
+	if err := h.library.DeleteTag(c.Request.Context(), middleware.MustGetUserID(c), tagID); err != nil {
+		h.respondError(c, err, "Failed to delete tag")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted."})
+}
+
+// ListCollections handles GET /api/v1/collections?workspace_id= - the collection tree
+func (h *LibraryHandler) ListCollections(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	workspaceID, ok := parseWorkspaceQuery(c)
+	if !ok {
+		return
+	}
+
+	collections, err := h.library.ListCollections(c.Request.Context(), user, workspaceID)
+	if err != nil {
+		h.respondError(c, err, "Failed to list collections")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": collections})
+}
+
+// CreateCollection handles POST /api/v1/collections
+func (h *LibraryHandler) CreateCollection(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	var req models.CreateCollectionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	collection, err := h.library.CreateCollection(c.Request.Context(), user, &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to create collection")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": collection})
+}
+
+// UpdateCollection handles PATCH /api/v1/collections/:id - rename, move or reorder a collection
+func (h *LibraryHandler) UpdateCollection(c *gin.Context) {
+	collectionID, ok := parseUintParam(c, "id", "Invalid collection ID format.")
+	if !ok {
+		return
+	}
+	var req models.UpdateCollectionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	collection, err := h.library.UpdateCollection(c.Request.Context(), middleware.MustGetUserID(c), collectionID, &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to update collection")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": collection})
+}
+
+// DeleteCollection handles DELETE /api/v1/collections/:id - contents move to the parent collection
+func (h *LibraryHandler) DeleteCollection(c *gin.Context) {
+	collectionID, ok := parseUintParam(c, "id", "Invalid collection ID format.")
+	if !ok {
+		return
+	}
+
+	if err := h.library.DeleteCollection(c.Request.Context(), middleware.MustGetUserID(c), collectionID); err != nil {
+		h.respondError(c, err, "Failed to delete collection")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collection deleted."})
+}
+
+// BulkTag handles POST /api/v1/insights/bulk/tags - add and remove tags on many insights
+func (h *LibraryHandler) BulkTag(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	var req models.BulkTagRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := h.library.BulkTag(c.Request.Context(), user, &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to tag insights")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// BulkMove handles POST /api/v1/insights/bulk/move - move many insights into a collection
+func (h *LibraryHandler) BulkMove(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	var req models.BulkMoveRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := h.library.BulkMove(c.Request.Context(), user, &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to move insights")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// respondError maps library service errors to HTTP responses.
+func (h *LibraryHandler) respondError(c *gin.Context, err error, logMessage string) {
+	requestID := c.GetString("request_id")
+
+	status, code, message := http.StatusInternalServerError, models.ErrInternalServer, "An unexpected error occurred."
+	switch {
+	case errors.Is(err, services.ErrWorkspaceNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "Workspace not found."
+	case errors.Is(err, services.ErrWorkspaceForbidden):
+		status, code, message = http.StatusForbidden, models.ErrForbidden, "You do not have permission to do this in this workspace."
+	case errors.Is(err, services.ErrTagNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "Tag not found."
+	case errors.Is(err, services.ErrTagExists):
+		status, code, message = http.StatusConflict, "TAG_EXISTS", "A tag with this name already exists."
+	case errors.Is(err, services.ErrCollectionNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "Collection not found."
+	case errors.Is(err, services.ErrCollectionCycle):
+		status, code, message = http.StatusBadRequest, "COLLECTION_CYCLE", "A collection cannot be moved into itself or one of its sub-collections."
+	case errors.Is(err, services.ErrMixedWorkspaces):
+		status, code, message = http.StatusBadRequest, "MIXED_WORKSPACES", "All insights must belong to the same workspace."
+	case errors.Is(err, services.ErrBulkInsightNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "One or more insights were not found."
+	}
+
+	if status == http.StatusInternalServerError {
+		h.log.Error(logMessage,
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Code:      code,
+		Message:   message,
+		RequestID: requestID,
+	})
+}
+
+// parseWorkspaceQuery parses the optional workspace_id query parameter (nil means the
+// personal workspace), writing a 400 response on failure.
+func parseWorkspaceQuery(c *gin.Context) (*uint, bool) {
+	value := c.Query("workspace_id")
+	if value == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      "INVALID_ID",
+			Message:   "Invalid workspace ID format.",
+			RequestID: c.GetString("request_id"),
+		})
+		return nil, false
+	}
+	workspaceID := uint(id)
+	return &workspaceID, true
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"vibe-backend/internal/models"
+)
+
+// maxPageLimit caps the page size of cursor-paginated lists.
+const maxPageLimit = 100
+
+// errInvalidPageParams is returned for malformed pagination, sort or date parameters.
+var errInvalidPageParams = errors.New("invalid pagination parameters")
+
+// parsePageRequest reads cursor, limit, sort and order (asc or desc) from the query string.
+// Sorting defaults to newest first; titles default to A-Z.
+func parsePageRequest(c *gin.Context, defaultLimit int) (models.PageRequest, error) {
+	page := models.PageRequest{
+		Cursor: c.Query("cursor"),
+		Limit:  defaultLimit,
+		Sort:   c.DefaultQuery("sort", models.SortCreated),
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return page, errInvalidPageParams
+		}
+		page.Limit = limit
+	}
+	if page.Limit > maxPageLimit {
+		page.Limit = maxPageLimit
+	}
+
+	switch c.Query("order") {
+	case "":
+		page.Desc = page.Sort != models.SortTitle
+	case "desc":
+		page.Desc = true
+	case "asc":
+		page.Desc = false
+	default:
+		return page, errInvalidPageParams
+	}
+
+	return page, nil
+}
+
+// parseDateRange reads the from and to query parameters as RFC 3339 times or
+// YYYY-MM-DD dates. A date in to includes the whole day.
+func parseDateRange(c *gin.Context) (models.DateRange, error) {
+	var dates models.DateRange
+
+	if value := c.Query("from"); value != "" {
+		from, _, err := parseDateParam(value)
+		if err != nil {
+			return dates, errInvalidPageParams
+		}
+		dates.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, dateOnly, err := parseDateParam(value)
+		if err != nil {
+			return dates, errInvalidPageParams
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		dates.To = &to
+	}
+
+	return dates, nil
+}
+
+// parseDateParam parses an RFC 3339 time or a YYYY-MM-DD date (in UTC).
+func parseDateParam(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	return t, true, err
+}
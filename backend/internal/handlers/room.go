@@ -1,36 +1,67 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"vibe-backend/internal/middleware"
 	"vibe-backend/internal/models"
 	"vibe-backend/internal/repository"
+	"vibe-backend/internal/services"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
+)
+
+// Signaling WebSocket limits. SDP offers are a few kilobytes; candidates far less.
+const (
+	signalMaxMessageSize = 64 << 10
+	signalWriteWait      = 10 * time.Second
+	signalPongWait       = 60 * time.Second
+	signalPingPeriod     = signalPongWait * 9 / 10
 )
 
 // RoomHandler handles room-related HTTP requests.
 type RoomHandler struct {
-	repo *repository.RoomRepository
-	log  *zap.Logger
+	rooms     *services.RoomService
+	signaling *services.SignalingHub
+	sfu       *services.SFU
+	upgrader  websocket.Upgrader
+	log       *zap.Logger
 }
 
-// NewRoomHandler creates a new RoomHandler.
-func NewRoomHandler(repo *repository.RoomRepository, log *zap.Logger) *RoomHandler {
+// NewRoomHandler creates a new RoomHandler. sfu may be nil if media forwarding is
+// disabled. Signaling WebSockets are accepted from allowedOrigins, the CORS allowed
+// origins.
+func NewRoomHandler(rooms *services.RoomService, signaling *services.SignalingHub, sfu *services.SFU, allowedOrigins []string, log *zap.Logger) *RoomHandler {
 	return &RoomHandler{
-		repo: repo,
-		log:  log,
+		rooms:     rooms,
+		signaling: signaling,
+		sfu:       sfu,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+			CheckOrigin:     signalingOriginChecker(allowedOrigins),
+		},
+		log: log,
 	}
 }
 
-// JoinRoom handles POST /api/room/join
-// Allows a user to join a video room.
-func (h *RoomHandler) JoinRoom(c *gin.Context) {
-	var req models.JoinRoomRequest
+// CreateRoom handles POST /api/room
+// Creates a video room owned by the signed-in user under a new room ID, which they
+// and the people they share it with then join.
+func (h *RoomHandler) CreateRoom(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	var req models.CreateRoomRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":      err.Error(),
@@ -39,82 +70,51 @@ func (h *RoomHandler) JoinRoom(c *gin.Context) {
 		return
 	}
 
-	// Generate a unique user ID for this participant
-	userID := uuid.New().String()
-
-	// Get or create room
-	room, err := h.repo.GetOrCreateRoom(c.Request.Context(), req.RoomID)
+	response, err := h.rooms.Create(c.Request.Context(), userID, &req)
 	if err != nil {
-		h.log.Error("Failed to get or create room",
-			zap.String("room_id", req.RoomID),
-			zap.Error(err),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "Failed to join room",
-			"request_id": c.GetString("request_id"),
-		})
+		h.respondError(c, err, "Failed to create room")
 		return
 	}
 
-	// Add participant to room
-	_, err = h.repo.AddParticipant(c.Request.Context(), req.RoomID, userID)
-	if err != nil {
-		h.log.Error("Failed to add participant",
-			zap.String("room_id", req.RoomID),
-			zap.String("user_id", userID),
-			zap.Error(err),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "Failed to add participant",
-			"request_id": c.GetString("request_id"),
-		})
-		return
-	}
+	c.JSON(http.StatusCreated, response)
+}
 
-	// Update room status to CONNECTED
-	if err := h.repo.UpdateRoomStatus(c.Request.Context(), req.RoomID, "CONNECTED"); err != nil {
-		h.log.Warn("Failed to update room status",
-			zap.String("room_id", req.RoomID),
-			zap.Error(err),
-		)
+// JoinRoom handles POST /api/room/join
+// Puts the signed-in user in a video room created with CreateRoom, or in its lobby.
+// The response carries the token to connect to the room's signaling with once they
+// are online.
+func (h *RoomHandler) JoinRoom(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
 	}
-
-	// Get all participants
-	participants, err := h.repo.GetParticipants(c.Request.Context(), req.RoomID)
-	if err != nil {
-		h.log.Error("Failed to get participants",
-			zap.String("room_id", req.RoomID),
-			zap.Error(err),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "Failed to get participants",
+	var req models.JoinRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      err.Error(),
 			"request_id": c.GetString("request_id"),
 		})
 		return
 	}
 
-	// Convert to response format
-	participantResponses := make([]models.ParticipantResponse, len(participants))
-	for i, p := range participants {
-		participantResponses[i] = p.ToResponse()
-	}
-
-	response := models.JoinRoomResponse{
-		Status:       room.Status,
-		Participants: participantResponses,
+	response, err := h.rooms.Join(c.Request.Context(), user, &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to join room")
+		return
 	}
 
-	// Include the generated user_id in response for client to use
-	c.JSON(http.StatusOK, gin.H{
-		"status":       response.Status,
-		"participants": response.Participants,
-		"user_id":      userID, // Client needs this to identify themselves
-	})
+	c.JSON(http.StatusOK, response)
 }
 
 // LeaveRoom handles POST /api/room/leave
-// Allows a user to leave a video room.
+// Takes the signed-in user out of a video room.
 func (h *RoomHandler) LeaveRoom(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
 	var req models.LeaveRoomRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -124,47 +124,49 @@ func (h *RoomHandler) LeaveRoom(c *gin.Context) {
 		return
 	}
 
-	// Remove participant from room
-	if err := h.repo.RemoveParticipant(c.Request.Context(), req.RoomID, req.UserID); err != nil {
-		h.log.Error("Failed to remove participant",
-			zap.String("room_id", req.RoomID),
-			zap.String("user_id", req.UserID),
-			zap.Error(err),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "Failed to leave room",
+	if err := h.rooms.Leave(c.Request.Context(), userID, req.RoomID); err != nil {
+		h.respondError(c, err, "Failed to leave room")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.LeaveRoomResponse{Status: "OK"})
+}
+
+// Heartbeat handles POST /api/room/heartbeat
+// Keeps the signed-in user online in a room, or waiting in its lobby. Clients
+// connected to the room's signaling need not call it; the connection keeps them online.
+func (h *RoomHandler) Heartbeat(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	var req models.RoomHeartbeatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      err.Error(),
 			"request_id": c.GetString("request_id"),
 		})
 		return
 	}
 
-	// Check if room is empty
-	participants, err := h.repo.GetParticipants(c.Request.Context(), req.RoomID)
+	status, expiresAt, err := h.rooms.Heartbeat(c.Request.Context(), userID, req.RoomID)
 	if err != nil {
-		h.log.Warn("Failed to check participants after leave",
-			zap.String("room_id", req.RoomID),
-			zap.Error(err),
-		)
-	} else if len(participants) == 0 {
-		// Update room status to DISCONNECTED if no participants
-		if err := h.repo.UpdateRoomStatus(c.Request.Context(), req.RoomID, "DISCONNECTED"); err != nil {
-			h.log.Warn("Failed to update room status to DISCONNECTED",
-				zap.String("room_id", req.RoomID),
-				zap.Error(err),
-			)
-		}
-	}
-
-	response := models.LeaveRoomResponse{
-		Status: "OK",
+		h.respondError(c, err, "Failed to record heartbeat")
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, models.RoomHeartbeatResponse{Status: "OK", ParticipantStatus: status, ExpiresAt: expiresAt})
 }
 
 // GetRoomStatus handles GET /api/room/status
-// Returns the current status of a room and its participants.
+// Returns the current status of a room, and its participants to those in it.
 func (h *RoomHandler) GetRoomStatus(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
 	roomID := c.Query("roomId")
 	if roomID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -174,52 +176,52 @@ func (h *RoomHandler) GetRoomStatus(c *gin.Context) {
 		return
 	}
 
-	// Get room
-	room, err := h.repo.GetOrCreateRoom(c.Request.Context(), roomID)
+	response, err := h.rooms.Status(c.Request.Context(), userID, roomID)
 	if err != nil {
-		h.log.Error("Failed to get room",
-			zap.String("room_id", roomID),
-			zap.Error(err),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "Failed to get room status",
-			"request_id": c.GetString("request_id"),
-		})
+		h.respondError(c, err, "Failed to get room status")
 		return
 	}
 
-	// Get all participants
-	participants, err := h.repo.GetParticipants(c.Request.Context(), roomID)
-	if err != nil {
-		h.log.Error("Failed to get participants",
-			zap.String("room_id", roomID),
-			zap.Error(err),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "Failed to get participants",
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateSettings handles PATCH /api/room/settings
+// Locks the room, sets or removes its password, turns its lobby on or off or changes
+// its participant limit. Owners only.
+func (h *RoomHandler) UpdateSettings(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	var req models.RoomSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      err.Error(),
 			"request_id": c.GetString("request_id"),
 		})
 		return
 	}
 
-	// Convert to response format
-	participantResponses := make([]models.ParticipantResponse, len(participants))
-	for i, p := range participants {
-		participantResponses[i] = p.ToResponse()
-	}
-
-	response := models.RoomStatusResponse{
-		Status:       room.Status,
-		Participants: participantResponses,
+	response, err := h.rooms.UpdateSettings(c.Request.Context(), userID, &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to update room settings")
+		return
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// SendMediaOffer handles POST /api/media/offer
-// Handles WebRTC offer from client.
-func (h *RoomHandler) SendMediaOffer(c *gin.Context) {
-	var req models.MediaOfferRequest
+// CreateInvite handles POST /api/room/invites
+// Creates an invite past the room's password and lobby. Moderators only. The token
+// is only returned here.
+func (h *RoomHandler) CreateInvite(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	var req models.CreateRoomInviteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":      err.Error(),
@@ -228,127 +230,348 @@ func (h *RoomHandler) SendMediaOffer(c *gin.Context) {
 		return
 	}
 
-	// Save the offer
-	signal, err := h.repo.SaveWebRTCOffer(c.Request.Context(), req.RoomID, req.UserID, req.SDP)
+	response, err := h.rooms.CreateInvite(c.Request.Context(), userID, &req)
 	if err != nil {
-		h.log.Error("Failed to save WebRTC offer",
-			zap.String("room_id", req.RoomID),
-			zap.String("user_id", req.UserID),
-			zap.Error(err),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "Failed to save offer",
-			"request_id": c.GetString("request_id"),
-		})
+		h.respondError(c, err, "Failed to create room invite")
 		return
 	}
 
-	// In a real WebRTC implementation, the answer would come from another peer
-	// For this stateless implementation, we generate a simple SDP answer
-	// Note: This is a placeholder. In production, use a proper WebRTC signaling server.
-	sdpAnswer := generateSDPAnswer(req.SDP)
+	c.JSON(http.StatusCreated, response)
+}
 
-	// Save the answer
-	if err := h.repo.SaveWebRTCAnswer(c.Request.Context(), signal.ID, sdpAnswer); err != nil {
-		h.log.Warn("Failed to save WebRTC answer",
-			zap.String("room_id", req.RoomID),
-			zap.String("user_id", req.UserID),
-			zap.Error(err),
-		)
+// ListInvites handles GET /api/room/invites?roomId=
+// Returns the room's invites that can still be used. Moderators only.
+func (h *RoomHandler) ListInvites(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	roomID := c.Query("roomId")
+	if roomID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "roomId query parameter is required",
+			"request_id": c.GetString("request_id"),
+		})
+		return
 	}
 
-	response := models.MediaOfferResponse{
-		SDP: sdpAnswer,
+	invites, err := h.rooms.ListInvites(c.Request.Context(), userID, roomID)
+	if err != nil {
+		h.respondError(c, err, "Failed to list room invites")
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, gin.H{"invites": invites})
 }
 
-// SendICECandidate handles POST /api/media/iceCandidate
-// Handles ICE candidate from client.
-func (h *RoomHandler) SendICECandidate(c *gin.Context) {
-	var req models.ICECandidateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+// RevokeInvite handles DELETE /api/room/invites/:id
+// Revokes an invite. Moderators of its room only.
+func (h *RoomHandler) RevokeInvite(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	inviteID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":      err.Error(),
+			"error":      "Invalid invite ID",
 			"request_id": c.GetString("request_id"),
 		})
 		return
 	}
 
-	// Save the ICE candidate
-	if err := h.repo.SaveICECandidate(c.Request.Context(), req.RoomID, req.UserID, req.Candidate); err != nil {
-		h.log.Error("Failed to save ICE candidate",
-			zap.String("room_id", req.RoomID),
-			zap.String("user_id", req.UserID),
-			zap.Error(err),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "Failed to save ICE candidate",
+	if err := h.rooms.RevokeInvite(c.Request.Context(), userID, uint(inviteID)); err != nil {
+		h.respondError(c, err, "Failed to revoke room invite")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "OK"})
+}
+
+// GetLobby handles GET /api/room/lobby?roomId=
+// Returns who waits in the room's lobby. Moderators only; connected moderators are
+// also sent lobby messages as it changes.
+func (h *RoomHandler) GetLobby(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	roomID := c.Query("roomId")
+	if roomID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "roomId query parameter is required",
 			"request_id": c.GetString("request_id"),
 		})
 		return
 	}
 
-	response := models.ICECandidateResponse{
-		Status: "OK",
+	waiting, err := h.rooms.Lobby(c.Request.Context(), userID, roomID)
+	if err != nil {
+		h.respondError(c, err, "Failed to get room lobby")
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, gin.H{"participants": waiting})
 }
 
-// generateSDPAnswer generates a placeholder SDP answer.
-// Note: This is a simplified placeholder implementation.
-// In production, use a proper WebRTC signaling server (e.g., WebSocket-based)
-// or a selective forwarding unit (SFU) like mediasoup, Janus, or Pion WebRTC.
-func generateSDPAnswer(offer string) string {
-	// This is a placeholder implementation
-	// In a real system, you would:
-	// 1. Parse the SDP offer
-	// 2. Create a peer connection
-	// 3. Set the remote description (offer)
-	// 4. Create an answer
-	// 5. Return the SDP answer
-
-	// For now, return a simple acknowledgment
-	// The client will need to implement proper WebRTC peer connection handling
-	return "v=0\no=- 0 0 IN IP4 127.0.0.1\ns=WebRTC Session\nt=0 0\na=group:BUNDLE 0\n"
-}
+// Moderate handles POST /api/room/moderation/:action
+// Kicks, mutes, unmutes, admits, denies, promotes or demotes a participant, or ends
+// the room for everyone. Moderators only; see services.RoomService.Moderate.
+func (h *RoomHandler) Moderate(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	var req models.RoomModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      err.Error(),
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
 
-// GetICECandidates handles GET /api/media/iceCandidates (optional helper endpoint)
-// Returns ICE candidates for a room/user pair.
-func (h *RoomHandler) GetICECandidates(c *gin.Context) {
-	roomID := c.Query("roomId")
-	userID := c.Query("userId")
+	action := models.RoomModerationAction(c.Param("action"))
+	if err := h.rooms.Moderate(c.Request.Context(), userID, req.RoomID, action, req.UserID); err != nil {
+		h.respondError(c, err, "Failed to moderate room")
+		return
+	}
 
-	if roomID == "" || userID == "" {
+	c.JSON(http.StatusOK, gin.H{"status": "OK"})
+}
+
+// Signal handles GET /api/room/signal?roomId=&token= - upgrades to the room's
+// signaling WebSocket. token is the signal_token returned by JoinRoom, since browsers
+// cannot send an Authorization header with WebSockets; roomId must be the room it was
+// issued for, and lets a load balancer route each room to one instance. Clients send offer, answer and
+// candidate messages addressed to a peer; the server sends the peers already
+// connected and who joins and leaves. In SFU rooms, offers, answers and candidates
+// are exchanged with the "sfu" peer instead (see services.SFU). Moderators' actions
+// arrive as moderation messages; kicked participants are disconnected. While
+// connected, the participant stays online without heartbeats.
+func (h *RoomHandler) Signal(c *gin.Context) {
+	roomID, token := c.Query("roomId"), c.Query("token")
+	if roomID == "" || token == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":      "roomId and userId query parameters are required",
+			"error":      "roomId and token query parameters are required",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+
+	room, participant, err := h.rooms.AuthorizeSignal(c.Request.Context(), roomID, token)
+	if err != nil {
+		h.respondError(c, err, "Failed to connect to room")
+		return
+	}
+	useSFU := room.MediaMode == models.RoomMediaSFU
+	if useSFU && h.sfu == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":      "Media forwarding is disabled on this server",
 			"request_id": c.GetString("request_id"),
 		})
 		return
 	}
 
-	candidates, err := h.repo.GetICECandidates(c.Request.Context(), roomID, userID)
+	// The upgrader writes its own error response
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error":      "No ICE candidates found",
-				"request_id": c.GetString("request_id"),
-			})
+		h.log.Debug("Signaling upgrade failed", zap.String("room_id", room.RoomID), zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	userID := participant.UserID
+	peer := h.signaling.Join(c.Request.Context(), room.RoomID, models.SignalPeerID(userID))
+	defer h.signaling.Leave(context.WithoutCancel(c.Request.Context()), peer)
+	go h.writeSignals(conn, peer)
+
+	if useSFU {
+		if err := h.sfu.Join(peer); err != nil {
+			h.log.Error("Failed to connect participant to SFU",
+				zap.String("room_id", room.RoomID),
+				zap.Uint("user_id", userID),
+				zap.Error(err),
+			)
+			return
+		}
+		defer h.sfu.Leave(peer)
+		h.sfu.SetMuted(peer, participant.Muted)
+	}
+
+	h.readSignals(c.Request.Context(), conn, peer, userID, useSFU)
+}
+
+// readSignals relays the peer's messages, or passes them to the SFU, until the
+// connection fails or the hub disconnects the peer.
+func (h *RoomHandler) readSignals(ctx context.Context, conn *websocket.Conn, peer *services.SignalingPeer, userID uint, useSFU bool) {
+	conn.SetReadLimit(signalMaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(signalPongWait))
+	conn.SetPongHandler(func(string) error {
+		// Pongs keep the participant online; one who left, timed out or was removed is disconnected
+		_, _, err := h.rooms.Heartbeat(ctx, userID, peer.RoomID)
+		if errors.Is(err, services.ErrNotInRoom) {
+			return err
+		}
+		if err != nil {
+			h.log.Warn("Failed to record signaling heartbeat",
+				zap.String("room_id", peer.RoomID),
+				zap.String("user_id", peer.ID),
+				zap.Error(err),
+			)
+		}
+		return conn.SetReadDeadline(time.Now().Add(signalPongWait))
+	})
+
+	for {
+		var msg models.SignalMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				h.log.Debug("Signaling connection closed",
+					zap.String("room_id", peer.RoomID),
+					zap.String("user_id", peer.ID),
+					zap.Error(err),
+				)
+			}
 			return
 		}
-		h.log.Error("Failed to get ICE candidates",
-			zap.String("room_id", roomID),
-			zap.String("user_id", userID),
+
+		var err error
+		if useSFU {
+			if msg.To != models.SignalSFU {
+				err = services.ErrSignalPeerNotFound
+			} else {
+				err = h.sfu.HandleSignal(peer, &msg)
+			}
+		} else {
+			err = h.signaling.Relay(ctx, peer, &msg)
+		}
+		if err != nil {
+			message := "Failed to relay message"
+			switch {
+			case errors.Is(err, services.ErrInvalidSignal):
+				message = "Invalid signaling message"
+			case errors.Is(err, services.ErrSignalPeerNotFound):
+				message = "Peer is not connected"
+			case errors.Is(err, services.ErrSFUTrackMissing):
+				message = "Track not found"
+			default:
+				h.log.Warn("Failed to relay signaling message",
+					zap.String("room_id", peer.RoomID),
+					zap.String("user_id", peer.ID),
+					zap.Error(err),
+				)
+			}
+			peer.Send(&models.SignalMessage{Type: models.SignalError, To: msg.To, Error: message})
+		}
+	}
+}
+
+// respondError maps room service errors to HTTP responses. Unexpected errors are
+// logged and answered with message.
+func (h *RoomHandler) respondError(c *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, repository.ErrRoomFull):
+		status, message = http.StatusConflict, "Room is full"
+	case errors.Is(err, repository.ErrInviteUnusable):
+		status, message = http.StatusBadRequest, "Invite is invalid or expired"
+	case errors.Is(err, services.ErrRoomLimitTooHigh):
+		status, message = http.StatusBadRequest, "maxParticipants exceeds the server maximum"
+	case errors.Is(err, services.ErrRoomNotFound):
+		status, message = http.StatusNotFound, "Room not found"
+	case errors.Is(err, services.ErrNotInRoom):
+		status, message = http.StatusForbidden, "Join the room first"
+	case errors.Is(err, services.ErrInvalidSignalToken):
+		status, message = http.StatusUnauthorized, "Invalid or expired signaling token"
+	case errors.Is(err, services.ErrRoomForbidden):
+		status, message = http.StatusForbidden, "Insufficient room permissions"
+	case errors.Is(err, services.ErrRoomClosed):
+		status, message = http.StatusGone, "Room was closed by a moderator"
+	case errors.Is(err, services.ErrRoomLocked):
+		status, message = http.StatusForbidden, "Room is locked"
+	case errors.Is(err, services.ErrRoomPassword):
+		status, message = http.StatusForbidden, "Wrong or missing room password"
+	case errors.Is(err, services.ErrRemovedFromRoom):
+		status, message = http.StatusForbidden, "You were removed from this room; ask a moderator to readmit you"
+	case errors.Is(err, services.ErrInvalidModeration):
+		status, message = http.StatusBadRequest, "Invalid moderation action"
+	case errors.Is(err, services.ErrParticipantNotFound):
+		status, message = http.StatusNotFound, "Participant not found"
+	case errors.Is(err, services.ErrRoomInviteNotFound):
+		status, message = http.StatusNotFound, "Invite not found"
+	default:
+		h.log.Error(message,
+			zap.String("request_id", c.GetString("request_id")),
 			zap.Error(err),
 		)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":      "Failed to get ICE candidates",
-			"request_id": c.GetString("request_id"),
-		})
-		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": candidates})
+	c.JSON(status, gin.H{
+		"error":      message,
+		"request_id": c.GetString("request_id"),
+	})
+}
+
+// writeSignals writes the peer's queued messages and keeps the connection alive with
+// pings. It closes the connection when the hub disconnects the peer, which also ends
+// readSignals, after writing what was queued before, such as why.
+func (h *RoomHandler) writeSignals(conn *websocket.Conn, peer *services.SignalingPeer) {
+	ticker := time.NewTicker(signalPingPeriod)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case data := <-peer.Messages():
+			_ = conn.SetWriteDeadline(time.Now().Add(signalWriteWait))
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(signalWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-peer.Done():
+		drain:
+			for {
+				select {
+				case data := <-peer.Messages():
+					_ = conn.SetWriteDeadline(time.Now().Add(signalWriteWait))
+					if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+						return
+					}
+				default:
+					break drain
+				}
+			}
+			_ = conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+				time.Now().Add(signalWriteWait))
+			return
+		}
+	}
+}
+
+// signalingOriginChecker allows WebSocket upgrades from the CORS allowed origins.
+// Browsers do not apply CORS to WebSockets, so the origin is checked here.
+func signalingOriginChecker(allowedOrigins []string) func(r *http.Request) bool {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			return func(*http.Request) bool { return true }
+		}
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || allowed[origin]
+	}
 }
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"vibe-backend/internal/middleware"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/services"
+)
+
+// SearchHandler handles full-text library search requests.
+type SearchHandler struct {
+	search *services.SearchService
+	log    *zap.Logger
+}
+
+// NewSearchHandler creates a new SearchHandler.
+func NewSearchHandler(search *services.SearchService, log *zap.Logger) *SearchHandler {
+	return &SearchHandler{
+		search: search,
+		log:    log,
+	}
+}
+
+// Search handles GET /api/v1/search?q=&workspace_id=&limit=&offset=
+// It searches titles, summaries, transcripts, highlights and chat of a workspace's insights.
+func (h *SearchHandler) Search(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	workspaceID, ok := parseWorkspaceQuery(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 50 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	result, err := h.search.Search(c.Request.Context(), user, workspaceID, c.Query("q"), limit, offset)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrEmptySearchQuery):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Code:      "INVALID_QUERY",
+				Message:   "Search query is empty.",
+				RequestID: requestID,
+			})
+		case errors.Is(err, services.ErrWorkspaceNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Code:      models.ErrNotFound,
+				Message:   "Workspace not found.",
+				RequestID: requestID,
+			})
+		default:
+			h.log.Error("Search failed",
+				zap.String("request_id", requestID),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Code:      models.ErrInternalServer,
+				Message:   "An unexpected error occurred.",
+				RequestID: requestID,
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
@@ -0,0 +1,435 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"vibe-backend/internal/middleware"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/services"
+)
+
+// shareAccessCookie is the cookie holding the unlock token of a password-protected link.
+const shareAccessCookie = "share_access"
+
+// ShareInsight creates or updates the insight's legacy share link, or revokes it when
+// is_public is false. It is kept for clients that predate multiple share links; new
+// clients use POST /:id/share-links.
+// POST /api/v1/insights/:id/share
+func (h *InsightHandler) ShareInsight(c *gin.Context) {
+	insightID, ok := h.shareInsightID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ShareInsightRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      err.Error(),
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+
+	userID := middleware.MustGetUserID(c)
+	insight, _, ok := h.authorizeInsight(c, userID, insightID, models.WorkspaceRoleEditor, "无权限分享此 Insight")
+	if !ok {
+		return
+	}
+
+	if !req.IsPublic {
+		linkID, err := h.shares.RevokeLegacy(c.Request.Context(), insight)
+		if err != nil {
+			h.log.Error("Failed to revoke legacy share link", zap.Error(err), zap.Uint("insight_id", insightID))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":      "取消分享失败",
+				"request_id": c.GetString("request_id"),
+			})
+			return
+		}
+		if linkID != nil {
+			h.recordShareAudit(c, models.AuditShareRevoked, insight, linkID)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "分享已取消"})
+		return
+	}
+
+	link, created, err := h.shares.ShareLegacy(c.Request.Context(), userID, insight, &req)
+	if err != nil {
+		h.log.Error("Failed to create share link", zap.Error(err), zap.Uint("insight_id", insightID))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "生成分享链接失败",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+	action := models.AuditShareUpdated
+	if created {
+		action = models.AuditShareCreated
+	}
+	h.recordShareAudit(c, action, insight, &link.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": models.ShareInsightResponse{
+			ShareToken: link.Token,
+			ShareURL:   link.ShareURL,
+			ExpiresAt:  link.ExpiresAt,
+		},
+	})
+}
+
+// DeleteShare revokes every share link of an insight.
+// DELETE /api/v1/insights/:id/share
+func (h *InsightHandler) DeleteShare(c *gin.Context) {
+	insightID, ok := h.shareInsightID(c)
+	if !ok {
+		return
+	}
+
+	userID := middleware.MustGetUserID(c)
+	insight, _, ok := h.authorizeInsight(c, userID, insightID, models.WorkspaceRoleEditor, "无权限操作此 Insight")
+	if !ok {
+		return
+	}
+
+	if err := h.shares.RevokeAll(c.Request.Context(), insightID); err != nil {
+		h.log.Error("Failed to revoke share links", zap.Error(err), zap.Uint("insight_id", insightID))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "删除分享配置失败",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+	h.recordShareAudit(c, models.AuditShareRevoked, insight, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "分享已取消"})
+}
+
+// ListShareLinks returns all share links of an insight, including revoked ones.
+// GET /api/v1/insights/:id/share-links
+func (h *InsightHandler) ListShareLinks(c *gin.Context) {
+	insightID, ok := h.shareInsightID(c)
+	if !ok {
+		return
+	}
+
+	userID := middleware.MustGetUserID(c)
+	if _, _, ok := h.authorizeInsight(c, userID, insightID, models.WorkspaceRoleViewer, "无权限访问此 Insight"); !ok {
+		return
+	}
+
+	links, err := h.shares.List(c.Request.Context(), insightID)
+	if err != nil {
+		h.log.Error("Failed to list share links", zap.Error(err), zap.Uint("insight_id", insightID))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "获取分享链接失败",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": links})
+}
+
+// CreateShareLink creates a share link with its own content selection and limits.
+// POST /api/v1/insights/:id/share-links
+func (h *InsightHandler) CreateShareLink(c *gin.Context) {
+	insightID, ok := h.shareInsightID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      err.Error(),
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "过期时间必须晚于当前时间",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+
+	userID := middleware.MustGetUserID(c)
+	insight, _, ok := h.authorizeInsight(c, userID, insightID, models.WorkspaceRoleEditor, "无权限分享此 Insight")
+	if !ok {
+		return
+	}
+
+	link, err := h.shares.Create(c.Request.Context(), userID, insightID, &req)
+	if err != nil {
+		h.log.Error("Failed to create share link", zap.Error(err), zap.Uint("insight_id", insightID))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "生成分享链接失败",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+	h.recordShareAudit(c, models.AuditShareCreated, insight, &link.ID)
+
+	c.JSON(http.StatusCreated, gin.H{"data": link})
+}
+
+// UpdateShareLink changes a share link's label, content selection, password or limits.
+// PATCH /api/v1/insights/:id/share-links/:linkId
+func (h *InsightHandler) UpdateShareLink(c *gin.Context) {
+	insightID, linkID, ok := h.shareLinkIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      err.Error(),
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ClearExpiry && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "过期时间必须晚于当前时间",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+
+	userID := middleware.MustGetUserID(c)
+	insight, _, ok := h.authorizeInsight(c, userID, insightID, models.WorkspaceRoleEditor, "无权限分享此 Insight")
+	if !ok {
+		return
+	}
+
+	link, err := h.shares.Update(c.Request.Context(), insightID, linkID, &req)
+	if err != nil {
+		h.respondShareLinkError(c, err, "更新分享链接失败")
+		return
+	}
+	h.recordShareAudit(c, models.AuditShareUpdated, insight, &linkID)
+
+	c.JSON(http.StatusOK, gin.H{"data": link})
+}
+
+// RevokeShareLink permanently disables a share link. Revoked links stay listed so
+// their statistics remain available.
+// DELETE /api/v1/insights/:id/share-links/:linkId
+func (h *InsightHandler) RevokeShareLink(c *gin.Context) {
+	insightID, linkID, ok := h.shareLinkIDs(c)
+	if !ok {
+		return
+	}
+
+	userID := middleware.MustGetUserID(c)
+	insight, _, ok := h.authorizeInsight(c, userID, insightID, models.WorkspaceRoleEditor, "无权限操作此 Insight")
+	if !ok {
+		return
+	}
+
+	if err := h.shares.Revoke(c.Request.Context(), insightID, linkID); err != nil {
+		h.respondShareLinkError(c, err, "撤销分享链接失败")
+		return
+	}
+	h.recordShareAudit(c, models.AuditShareRevoked, insight, &linkID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "分享链接已撤销"})
+}
+
+// ShareLinkStats returns the view count, unique visitors, referrers and daily views
+// of a share link.
+// GET /api/v1/insights/:id/share-links/:linkId/stats
+func (h *InsightHandler) ShareLinkStats(c *gin.Context) {
+	insightID, linkID, ok := h.shareLinkIDs(c)
+	if !ok {
+		return
+	}
+
+	userID := middleware.MustGetUserID(c)
+	if _, _, ok := h.authorizeInsight(c, userID, insightID, models.WorkspaceRoleViewer, "无权限访问此 Insight"); !ok {
+		return
+	}
+
+	stats, err := h.shares.Stats(c.Request.Context(), insightID, linkID)
+	if err != nil {
+		h.respondShareLinkError(c, err, "获取分享统计失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": stats})
+}
+
+// GetShared returns a publicly shared insight and counts the view. Password-protected
+// links must first be unlocked with POST /shared/:token/unlock.
+// GET /api/v1/shared/:token
+func (h *InsightHandler) GetShared(c *gin.Context) {
+	token := c.Param("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "无效的分享链接",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+
+	response, err := h.shares.View(c.Request.Context(), token, services.ShareVisit{
+		AccessToken: shareAccessToken(c),
+		Referer:     c.Request.Referer(),
+		IP:          c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+	})
+	if err != nil {
+		h.respondSharedError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// UnlockShared checks the password of a protected share link and sets a short-lived
+// access cookie scoped to the link. The token is also returned for clients that
+// cannot use cookies.
+// POST /api/v1/shared/:token/unlock
+func (h *InsightHandler) UnlockShared(c *gin.Context) {
+	token := c.Param("token")
+
+	var req models.UnlockShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "请输入访问密码",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+
+	result, err := h.shares.Unlock(c.Request.Context(), token, req.Password)
+	if err != nil {
+		h.respondSharedError(c, err)
+		return
+	}
+
+	setShareAccessCookie(c, result.AccessToken, "/api/v1/shared/"+token)
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// shareAccessToken returns the unlock token of a share request, from the X-Share-Access
+// header or the access cookie.
+func shareAccessToken(c *gin.Context) string {
+	if header := c.GetHeader("X-Share-Access"); header != "" {
+		return header
+	}
+	access, _ := c.Cookie(shareAccessCookie)
+	return access
+}
+
+// setShareAccessCookie stores a share unlock token in an HttpOnly cookie scoped to path.
+func setShareAccessCookie(c *gin.Context, accessToken, path string) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(shareAccessCookie, accessToken, int(services.ShareAccessTTL.Seconds()), path, "", secure, true)
+}
+
+// shareInsightID parses the :id path parameter, writing a 400 response on failure.
+func (h *InsightHandler) shareInsightID(c *gin.Context) (uint, bool) {
+	insightID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "无效的 Insight ID",
+			"request_id": c.GetString("request_id"),
+		})
+		return 0, false
+	}
+	return uint(insightID), true
+}
+
+// shareLinkIDs parses the :id and :linkId path parameters, writing a 400 response on failure.
+func (h *InsightHandler) shareLinkIDs(c *gin.Context) (uint, uint, bool) {
+	insightID, ok := h.shareInsightID(c)
+	if !ok {
+		return 0, 0, false
+	}
+	linkID, err := strconv.ParseUint(c.Param("linkId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "无效的分享链接 ID",
+			"request_id": c.GetString("request_id"),
+		})
+		return 0, 0, false
+	}
+	return insightID, uint(linkID), true
+}
+
+// respondShareLinkError writes the response for a failed share link management call.
+func (h *InsightHandler) respondShareLinkError(c *gin.Context, err error, failureMsg string) {
+	if errors.Is(err, services.ErrShareLinkNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":      "分享链接不存在",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+	h.log.Error("Share link operation failed", zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":      failureMsg,
+		"request_id": c.GetString("request_id"),
+	})
+}
+
+// respondSharedError writes the response for a failed public share access.
+func (h *InsightHandler) respondSharedError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrShareLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":      "分享链接不存在或已被撤销",
+			"request_id": c.GetString("request_id"),
+		})
+	case errors.Is(err, services.ErrShareLinkExpired):
+		c.JSON(http.StatusGone, gin.H{
+			"error":      "分享链接已过期",
+			"request_id": c.GetString("request_id"),
+		})
+	case errors.Is(err, services.ErrSharePasswordRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":         "此分享需要密码访问",
+			"requires_auth": true,
+			"request_id":    c.GetString("request_id"),
+		})
+	case errors.Is(err, services.ErrSharePasswordInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":         "密码错误",
+			"requires_auth": true,
+			"request_id":    c.GetString("request_id"),
+		})
+	default:
+		h.log.Error("Failed to get shared insight", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "获取分享内容失败",
+			"request_id": c.GetString("request_id"),
+		})
+	}
+}
+
+// recordShareAudit records a change to an insight's share links; linkID is nil when
+// all of them were revoked.
+func (h *InsightHandler) recordShareAudit(c *gin.Context, action models.AuditAction, insight *models.Insight, linkID *uint) {
+	entry := auditEntry(c, action)
+	entry.WorkspaceID = insight.WorkspaceID
+	details := map[string]interface{}{"insight_id": insight.ID}
+	if linkID != nil {
+		entry.TargetType, entry.TargetID = models.AuditTargetShareLink, linkID
+	} else {
+		entry.TargetType, entry.TargetID = models.AuditTargetInsight, &insight.ID
+		details["all_links"] = true
+	}
+	entry.Details = services.AuditDetails(details)
+	h.audit.Record(c.Request.Context(), entry)
+}
@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/services"
+)
+
+//go:embed templates/share.html
+var shareTemplateFS embed.FS
+
+var shareTemplates = template.Must(template.New("share").Funcs(template.FuncMap{
+	"formatDate": func(t *time.Time) string { return t.Format("2006-01-02") },
+}).ParseFS(shareTemplateFS, "templates/share.html"))
+
+const (
+	// shareDescriptionLength is the maximum length, in characters, of preview descriptions.
+	shareDescriptionLength = 200
+	// Default and minimum size of the oEmbed card iframe.
+	oembedDefaultWidth  = 560
+	oembedDefaultHeight = 160
+	oembedMinWidth      = 240
+	oembedMinHeight     = 120
+	// oembedCacheAge tells consumers how long they may cache an oEmbed response.
+	oembedCacheAge = 3600
+)
+
+// sharePageData is the data of the share page templates.
+type sharePageData struct {
+	Title       string
+	Description string
+	ImageURL    string
+	PageURL     string
+	OEmbedURL   string
+	UnlockURL   string
+	Error       string
+	Insight     *models.SharedInsightResponse
+}
+
+// SharePageHandler serves server-rendered share pages, so pasted links unfurl with a
+// title, summary and thumbnail, and the oEmbed endpoint for embedding share cards.
+type SharePageHandler struct {
+	shares  *services.ShareService
+	baseURL string
+	log     *zap.Logger
+}
+
+// NewSharePageHandler creates a new SharePageHandler. baseURL is the public URL of this
+// server; if empty it is derived from each request.
+func NewSharePageHandler(shares *services.ShareService, baseURL string, log *zap.Logger) *SharePageHandler {
+	return &SharePageHandler{
+		shares:  shares,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		log:     log,
+	}
+}
+
+// Page renders a shared insight as HTML with Open Graph and Twitter card tags.
+// Password-protected links show a password form instead.
+// GET /s/:token
+func (h *SharePageHandler) Page(c *gin.Context) {
+	token := c.Param("token")
+	access, _ := c.Cookie(shareAccessCookie)
+
+	insight, err := h.shares.View(c.Request.Context(), token, services.ShareVisit{
+		AccessToken: access,
+		Referer:     c.Request.Referer(),
+		IP:          c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrSharePasswordRequired) {
+			h.renderLocked(c, http.StatusOK, token, "")
+			return
+		}
+		h.renderUnavailable(c, err)
+		return
+	}
+
+	h.render(c, http.StatusOK, "share_page", h.pageData(c, token, insight))
+}
+
+// Unlock checks the password submitted by the share page form and redirects back to
+// the page with an access cookie set.
+// POST /s/:token/unlock
+func (h *SharePageHandler) Unlock(c *gin.Context) {
+	token := c.Param("token")
+
+	result, err := h.shares.Unlock(c.Request.Context(), token, c.PostForm("password"))
+	if err != nil {
+		if errors.Is(err, services.ErrSharePasswordInvalid) {
+			h.renderLocked(c, http.StatusUnauthorized, token, "密码错误")
+			return
+		}
+		h.renderUnavailable(c, err)
+		return
+	}
+
+	setShareAccessCookie(c, result.AccessToken, "/s/"+token)
+	c.Redirect(http.StatusSeeOther, "/s/"+token)
+}
+
+// Embed renders a compact summary card of a shared insight for use in an iframe. Cards
+// of links with a view limit show only the title and link to the page, so rendering
+// them does not use up views.
+// GET /s/:token/embed
+func (h *SharePageHandler) Embed(c *gin.Context) {
+	token := c.Param("token")
+
+	insight, err := h.shares.View(c.Request.Context(), token, services.ShareVisit{
+		Referer:   c.Request.Referer(),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Embed:     true,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrSharePasswordRequired) {
+			// The unlock cookie is not sent to third-party frames, so link to the page.
+			h.render(c, http.StatusOK, "share_embed", sharePageData{
+				Title:       "此分享需要密码访问",
+				Description: "点击标题输入密码后查看。",
+				PageURL:     h.baseURLFor(c) + "/s/" + token,
+			})
+			return
+		}
+		h.renderUnavailable(c, err)
+		return
+	}
+
+	h.render(c, http.StatusOK, "share_embed", h.pageData(c, token, insight))
+}
+
+// OEmbed is the oEmbed provider endpoint for share links. Only JSON is supported.
+// GET /api/v1/oembed?url=<share page URL>&maxwidth=&maxheight=
+func (h *SharePageHandler) OEmbed(c *gin.Context) {
+	if format := c.DefaultQuery("format", "json"); format != "json" {
+		c.JSON(http.StatusNotImplemented, gin.H{
+			"error":      "仅支持 JSON 格式",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+
+	token, ok := shareTokenFromURL(c.Query("url"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":      "无效的分享链接",
+			"request_id": c.GetString("request_id"),
+		})
+		return
+	}
+
+	width, height := oembedDefaultWidth, oembedDefaultHeight
+	if maxWidth, err := strconv.Atoi(c.Query("maxwidth")); err == nil && maxWidth > 0 {
+		width = max(min(width, maxWidth), oembedMinWidth)
+	}
+	if maxHeight, err := strconv.Atoi(c.Query("maxheight")); err == nil && maxHeight > 0 {
+		height = max(min(height, maxHeight), oembedMinHeight)
+	}
+
+	insight, err := h.shares.Meta(c.Request.Context(), token)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSharePasswordRequired):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":      "此分享需要密码访问",
+				"request_id": c.GetString("request_id"),
+			})
+		case errors.Is(err, services.ErrShareLinkNotFound), errors.Is(err, services.ErrShareLinkExpired):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":      "分享链接不存在或已过期",
+				"request_id": c.GetString("request_id"),
+			})
+		default:
+			h.log.Error("Failed to build oEmbed response", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":      "获取分享内容失败",
+				"request_id": c.GetString("request_id"),
+			})
+		}
+		return
+	}
+
+	baseURL := h.baseURLFor(c)
+	embedURL := baseURL + "/s/" + token + "/embed"
+	c.JSON(http.StatusOK, models.OEmbedResponse{
+		Version:      "1.0",
+		Type:         "rich",
+		ProviderName: "Vibe",
+		ProviderURL:  baseURL,
+		Title:        insight.Title,
+		AuthorName:   insight.SharedBy,
+		HTML: fmt.Sprintf(`<iframe src="%s" width="%d" height="%d" frameborder="0" loading="lazy" style="border:0;max-width:100%%" title="%s"></iframe>`,
+			template.HTMLEscapeString(embedURL), width, height, template.HTMLEscapeString(insight.Title)),
+		Width:    width,
+		Height:   height,
+		CacheAge: oembedCacheAge,
+	})
+}
+
+// pageData builds the template data of a viewable share.
+func (h *SharePageHandler) pageData(c *gin.Context, token string, insight *models.SharedInsightResponse) sharePageData {
+	baseURL := h.baseURLFor(c)
+	pageURL := baseURL + "/s/" + token
+
+	description := insight.Content.Summary
+	if description == "" && len(insight.Content.KeyPoints) > 0 {
+		description = strings.Join(insight.Content.KeyPoints, "；")
+	}
+	if description == "" {
+		description = insight.SharedBy + " 分享了一条 Insight"
+	}
+
+	return sharePageData{
+		Title:       insight.Title,
+		Description: shortenText(strings.Join(strings.Fields(description), " "), shareDescriptionLength),
+		ImageURL:    insight.ThumbnailURL,
+		PageURL:     pageURL,
+		OEmbedURL:   baseURL + "/api/v1/oembed?format=json&url=" + url.QueryEscape(pageURL),
+		Insight:     insight,
+	}
+}
+
+// renderLocked renders the password form of a protected link. Its preview tags reveal
+// nothing about the content.
+func (h *SharePageHandler) renderLocked(c *gin.Context, status int, token, message string) {
+	h.render(c, status, "share_locked", sharePageData{
+		Title:       "此分享需要密码访问",
+		Description: "输入访问密码后查看分享内容。",
+		PageURL:     h.baseURLFor(c) + "/s/" + token,
+		UnlockURL:   "/s/" + token + "/unlock",
+		Error:       message,
+	})
+}
+
+// renderUnavailable renders the page for a missing, revoked or expired link.
+func (h *SharePageHandler) renderUnavailable(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrShareLinkNotFound):
+		h.render(c, http.StatusNotFound, "share_unavailable", sharePageData{
+			Title:       "分享链接不存在",
+			Description: "该分享链接不存在或已被撤销。",
+		})
+	case errors.Is(err, services.ErrShareLinkExpired):
+		h.render(c, http.StatusGone, "share_unavailable", sharePageData{
+			Title:       "分享链接已过期",
+			Description: "该分享链接已过期或已达到访问次数上限。",
+		})
+	default:
+		h.log.Error("Failed to render share page", zap.Error(err))
+		h.render(c, http.StatusInternalServerError, "share_unavailable", sharePageData{
+			Title:       "获取分享内容失败",
+			Description: "请稍后重试。",
+		})
+	}
+}
+
+// render executes a template into a buffer first, so template errors produce a 500
+// instead of a half-written page.
+func (h *SharePageHandler) render(c *gin.Context, status int, name string, data sharePageData) {
+	var buf bytes.Buffer
+	if err := shareTemplates.ExecuteTemplate(&buf, name, data); err != nil {
+		h.log.Error("Failed to execute share template", zap.String("template", name), zap.Error(err))
+		c.String(http.StatusInternalServerError, "内部错误")
+		return
+	}
+	// Pages depend on the unlock cookie and count views, so shared caches must not keep them.
+	c.Header("Cache-Control", "private, no-cache")
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// baseURLFor returns the public base URL of this server.
+func (h *SharePageHandler) baseURLFor(c *gin.Context) string {
+	if h.baseURL != "" {
+		return h.baseURL
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// shareTokenFromURL extracts the token from a share page, embed or API URL.
+func shareTokenFromURL(raw string) (string, bool) {
+	parsed, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return "", false
+	}
+	path := strings.Trim(parsed.Path, "/")
+	for _, prefix := range []string{"s/", "api/v1/shared/"} {
+		if rest, ok := strings.CutPrefix(path, prefix); ok {
+			token := strings.TrimSuffix(rest, "/embed")
+			if token != "" && !strings.Contains(token, "/") {
+				return token, true
+			}
+		}
+	}
+	return "", false
+}
+
+// shortenText cuts s to at most n characters, adding an ellipsis if it was cut.
+func shortenText(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
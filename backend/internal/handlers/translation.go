@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"vibe-backend/internal/middleware"
 	"vibe-backend/internal/models"
 	"vibe-backend/internal/repository"
 	"vibe-backend/internal/services"
@@ -60,8 +62,12 @@ func (h *TranslationHandler) Translate(c *gin.Context) {
 		return
 	}
 
-	// Process translation
-	translation, err := h.translationSvc.ProcessTranslation(c.Request.Context(), &req, h.transcriptSvc)
+	// Process translation, billed to the signed-in user if any
+	ctx := c.Request.Context()
+	if userID, ok := middleware.GetUserID(c); ok {
+		ctx = services.WithUsageUser(ctx, userID)
+	}
+	translation, err := h.translationSvc.ProcessTranslation(ctx, &req, h.transcriptSvc)
 	if err != nil {
 		h.log.Error("Translation processing failed",
 			zap.Error(err),
@@ -78,6 +84,15 @@ func (h *TranslationHandler) Translate(c *gin.Context) {
 			}
 		}
 
+		if errors.Is(err, services.ErrLLMBudgetExceeded) {
+			c.JSON(http.StatusTooManyRequests, models.TranslateResponse{
+				Status:  "error",
+				Code:    models.ErrLLMBudgetExceeded,
+				Message: "今日 AI 用量已达上限，请明天再试",
+			})
+			return
+		}
+
 		c.JSON(http.StatusBadRequest, models.TranslateResponse{
 			Status:  "error",
 			Message: err.Error(),
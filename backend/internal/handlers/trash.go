@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"vibe-backend/internal/middleware"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+	"vibe-backend/internal/services"
+)
+
+// TrashHandler handles the trash of deleted insights.
+type TrashHandler struct {
+	trash *services.TrashService
+	audit *services.AuditService
+	log   *zap.Logger
+}
+
+// NewTrashHandler creates a new TrashHandler.
+func NewTrashHandler(trash *services.TrashService, audit *services.AuditService, log *zap.Logger) *TrashHandler {
+	return &TrashHandler{
+		trash: trash,
+		audit: audit,
+		log:   log,
+	}
+}
+
+// List handles GET /api/v1/trash?workspace_id= - a page of deleted insights, most
+// recently deleted first (?sort=deleted|created|title)
+func (h *TrashHandler) List(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	workspaceID, ok := parseWorkspaceQuery(c)
+	if !ok {
+		return
+	}
+	page, err := parsePageRequest(c, 50)
+	if err != nil {
+		h.respondError(c, err, "Failed to list trash")
+		return
+	}
+	if c.Query("sort") == "" {
+		page.Sort = models.SortDeleted
+	}
+
+	result, err := h.trash.List(c.Request.Context(), user, workspaceID, page)
+	if err != nil {
+		h.respondError(c, err, "Failed to list trash")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// Restore handles POST /api/v1/trash/:id/restore - take an insight out of the trash
+func (h *TrashHandler) Restore(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid insight ID.")
+	if !ok {
+		return
+	}
+
+	insight, err := h.trash.Restore(c.Request.Context(), middleware.MustGetUserID(c), id)
+	if err != nil {
+		h.respondError(c, err, "Failed to restore insight")
+		return
+	}
+	recordInsightAudit(c, h.audit, models.AuditInsightRestored, insight)
+
+	c.Status(http.StatusNoContent)
+}
+
+// Purge handles DELETE /api/v1/trash/:id - delete an insight in the trash for good
+func (h *TrashHandler) Purge(c *gin.Context) {
+	id, ok := parseUintParam(c, "id", "Invalid insight ID.")
+	if !ok {
+		return
+	}
+
+	insight, err := h.trash.Purge(c.Request.Context(), middleware.MustGetUserID(c), id)
+	if err != nil {
+		h.respondError(c, err, "Failed to purge insight")
+		return
+	}
+	recordInsightAudit(c, h.audit, models.AuditInsightPurged, insight)
+
+	c.Status(http.StatusNoContent)
+}
+
+// Empty handles DELETE /api/v1/trash?workspace_id= - delete everything in the trash
+// the caller may delete for good
+func (h *TrashHandler) Empty(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	workspaceID, ok := parseWorkspaceQuery(c)
+	if !ok {
+		return
+	}
+
+	workspace, purged, err := h.trash.Empty(c.Request.Context(), user, workspaceID)
+	if workspace != nil && purged > 0 {
+		entry := auditEntry(c, models.AuditTrashEmptied)
+		entry.WorkspaceID = &workspace.ID
+		entry.TargetType, entry.TargetID = models.AuditTargetWorkspace, &workspace.ID
+		entry.Details = services.AuditDetails(map[string]interface{}{"purged": purged})
+		h.audit.Record(c.Request.Context(), entry)
+	}
+	if err != nil {
+		h.respondError(c, err, "Failed to empty trash")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": models.PurgeResponse{Purged: purged}})
+}
+
+// respondError maps trash service errors to HTTP responses.
+func (h *TrashHandler) respondError(c *gin.Context, err error, logMessage string) {
+	requestID := c.GetString("request_id")
+
+	status, code, message := http.StatusInternalServerError, models.ErrInternalServer, "An unexpected error occurred."
+	switch {
+	case errors.Is(err, errInvalidPageParams), errors.Is(err, repository.ErrInvalidCursor), errors.Is(err, repository.ErrUnsupportedSort):
+		status, code, message = http.StatusBadRequest, models.ErrBadRequest, "Invalid pagination parameters."
+	case errors.Is(err, services.ErrWorkspaceNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "Workspace not found."
+	case errors.Is(err, services.ErrWorkspaceForbidden):
+		status, code, message = http.StatusForbidden, models.ErrForbidden, "You do not have permission to do this in this workspace."
+	case errors.Is(err, services.ErrTrashItemNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "Insight not found in trash."
+	}
+
+	if status == http.StatusInternalServerError {
+		h.log.Error(logMessage,
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Code:      code,
+		Message:   message,
+		RequestID: requestID,
+	})
+}
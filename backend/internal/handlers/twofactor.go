@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"vibe-backend/internal/middleware"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/services"
+)
+
+// TwoFactorHandler handles TOTP two-factor enrollment and management.
+type TwoFactorHandler struct {
+	twoFactor *services.TwoFactorService
+	log       *zap.Logger
+}
+
+// NewTwoFactorHandler creates a new TwoFactorHandler.
+func NewTwoFactorHandler(twoFactor *services.TwoFactorService, log *zap.Logger) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactor: twoFactor,
+		log:       log,
+	}
+}
+
+// Status handles GET /api/v1/auth/2fa - get two-factor status
+func (h *TwoFactorHandler) Status(c *gin.Context) {
+	requestID := c.GetString("request_id")
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		h.respondUnauthorized(c)
+		return
+	}
+
+	status, err := h.twoFactor.Status(c.Request.Context(), user)
+	if err != nil {
+		h.respondError(c, err, "Failed to get two-factor status")
+		return
+	}
+
+	h.log.Debug("Two-factor status retrieved",
+		zap.String("request_id", requestID),
+		zap.Uint("user_id", user.ID),
+	)
+	c.JSON(http.StatusOK, gin.H{"data": status})
+}
+
+// Enroll handles POST /api/v1/auth/2fa/enroll - start enrollment and return the secret
+func (h *TwoFactorHandler) Enroll(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		h.respondUnauthorized(c)
+		return
+	}
+
+	result, err := h.twoFactor.Enroll(c.Request.Context(), user)
+	if err != nil {
+		h.respondError(c, err, "Failed to start two-factor enrollment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// Confirm handles POST /api/v1/auth/2fa/confirm - verify the first code and enable 2FA
+func (h *TwoFactorHandler) Confirm(c *gin.Context) {
+	user, req, ok := h.bindCode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.twoFactor.Confirm(c.Request.Context(), user, req.Code)
+	if err != nil {
+		h.respondError(c, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": models.RecoveryCodesResponse{RecoveryCodes: codes}})
+}
+
+// Disable handles POST /api/v1/auth/2fa/disable - turn off 2FA
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	user, req, ok := h.bindCode(c)
+	if !ok {
+		return
+	}
+
+	if err := h.twoFactor.Disable(c.Request.Context(), user, req.Code); err != nil {
+		h.respondError(c, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled."})
+}
+
+// RegenerateRecoveryCodes handles POST /api/v1/auth/2fa/recovery-codes - replace recovery codes
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user, req, ok := h.bindCode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(c.Request.Context(), user, req.Code)
+	if err != nil {
+		h.respondError(c, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": models.RecoveryCodesResponse{RecoveryCodes: codes}})
+}
+
+// bindCode loads the current user and parses a TwoFactorCodeRequest body.
+func (h *TwoFactorHandler) bindCode(c *gin.Context) (*models.User, *models.TwoFactorCodeRequest, bool) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		h.respondUnauthorized(c)
+		return nil, nil, false
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      "INVALID_REQUEST",
+			Message:   "Invalid request format.",
+			RequestID: c.GetString("request_id"),
+		})
+		return nil, nil, false
+	}
+	return user, &req, true
+}
+
+// respondUnauthorized writes the response for a missing user in context.
+func (h *TwoFactorHandler) respondUnauthorized(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+		Code:      models.ErrUnauthorized,
+		Message:   "User not found.",
+		RequestID: c.GetString("request_id"),
+	})
+}
+
+// respondError maps two-factor service errors to HTTP responses.
+func (h *TwoFactorHandler) respondError(c *gin.Context, err error, logMessage string) {
+	requestID := c.GetString("request_id")
+
+	status, code, message := http.StatusInternalServerError, models.ErrorCode("INTERNAL_SERVER_ERROR"), "An unexpected error occurred."
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		status, code, message = http.StatusBadRequest, "INVALID_2FA_CODE", "Invalid authentication code."
+	case errors.Is(err, services.ErrTwoFactorAlreadyActive):
+		status, code, message = http.StatusConflict, "2FA_ALREADY_ENABLED", "Two-factor authentication is already enabled."
+	case errors.Is(err, services.ErrTwoFactorNotEnabled):
+		status, code, message = http.StatusConflict, "2FA_NOT_ENABLED", "Two-factor authentication is not enabled."
+	case errors.Is(err, services.ErrTwoFactorNotEnrolled):
+		status, code, message = http.StatusConflict, "2FA_NOT_ENROLLED", "Start two-factor enrollment first."
+	}
+
+	if status == http.StatusInternalServerError {
+		h.log.Error(logMessage,
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	} else {
+		h.log.Warn(logMessage,
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Code:      code,
+		Message:   message,
+		RequestID: requestID,
+	})
+}
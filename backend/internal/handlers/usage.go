@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"vibe-backend/internal/middleware"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/services"
+)
+
+// UsageHandler handles LLM usage and budget reports.
+type UsageHandler struct {
+	usage *services.UsageService
+	log   *zap.Logger
+}
+
+// NewUsageHandler creates a new UsageHandler.
+func NewUsageHandler(usage *services.UsageService, log *zap.Logger) *UsageHandler {
+	return &UsageHandler{
+		usage: usage,
+		log:   log,
+	}
+}
+
+// Summary handles GET /api/v1/usage?from=&to= - the caller's daily usage per feature
+// and today's budget. The range defaults to the last 30 days.
+func (h *UsageHandler) Summary(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+	dates, err := parseDateRange(c)
+	if err != nil {
+		h.respondError(c, err, "Invalid date range")
+		return
+	}
+
+	summary, err := h.usage.UserSummary(c.Request.Context(), userID, dates)
+	if err != nil {
+		h.respondError(c, err, "Failed to get usage summary")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": summary})
+}
+
+// ByFeature handles GET /api/v1/usage/features?from=&to= - all users' daily usage per feature
+func (h *UsageHandler) ByFeature(c *gin.Context) {
+	dates, err := parseDateRange(c)
+	if err != nil {
+		h.respondError(c, err, "Invalid date range")
+		return
+	}
+
+	days, err := h.usage.DailyByFeature(c.Request.Context(), dates)
+	if err != nil {
+		h.respondError(c, err, "Failed to get usage by feature")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": days})
+}
+
+// ByUser handles GET /api/v1/usage/users?from=&to= - daily usage per user
+func (h *UsageHandler) ByUser(c *gin.Context) {
+	dates, err := parseDateRange(c)
+	if err != nil {
+		h.respondError(c, err, "Invalid date range")
+		return
+	}
+
+	days, err := h.usage.DailyByUser(c.Request.Context(), dates)
+	if err != nil {
+		h.respondError(c, err, "Failed to get usage by user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": days})
+}
+
+// respondError maps usage errors to HTTP responses.
+func (h *UsageHandler) respondError(c *gin.Context, err error, logMessage string) {
+	requestID := c.GetString("request_id")
+
+	status, code, message := http.StatusInternalServerError, models.ErrInternalServer, "An unexpected error occurred."
+	switch {
+	case errors.Is(err, errInvalidPageParams), errors.Is(err, services.ErrInvalidUsageRange):
+		status, code, message = http.StatusBadRequest, models.ErrBadRequest, "Invalid date range."
+	}
+
+	if status == http.StatusInternalServerError {
+		h.log.Error(logMessage,
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Code:      code,
+		Message:   message,
+		RequestID: requestID,
+	})
+}
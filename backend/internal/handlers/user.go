@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -10,19 +15,35 @@ import (
 	"vibe-backend/internal/middleware"
 	"vibe-backend/internal/models"
 	"vibe-backend/internal/repository"
+	"vibe-backend/internal/services"
 )
 
 // UserHandler handles user-related HTTP requests.
 type UserHandler struct {
-	userRepo *repository.UserRepository
-	log      *zap.Logger
+	userRepo   *repository.UserRepository
+	twoFactor  *services.TwoFactorService
+	loginGuard *services.LoginGuard
+	security   *services.SecurityService
+	audit      *services.AuditService
+	log        *zap.Logger
 }
 
 // NewUserHandler creates a new UserHandler.
-func NewUserHandler(userRepo *repository.UserRepository, log *zap.Logger) *UserHandler {
+func NewUserHandler(
+	userRepo *repository.UserRepository,
+	twoFactor *services.TwoFactorService,
+	loginGuard *services.LoginGuard,
+	security *services.SecurityService,
+	audit *services.AuditService,
+	log *zap.Logger,
+) *UserHandler {
 	return &UserHandler{
-		userRepo: userRepo,
-		log:      log,
+		userRepo:   userRepo,
+		twoFactor:  twoFactor,
+		loginGuard: loginGuard,
+		security:   security,
+		audit:      audit,
+		log:        log,
 	}
 }
 
@@ -103,12 +124,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 
 	// Return user info and API key
 	c.JSON(http.StatusCreated, models.AuthResponse{
-		User: models.UserResponse{
-			ID:        user.ID,
-			Email:     user.Email,
-			Name:      user.Name,
-			CreatedAt: user.CreatedAt,
-		},
+		User:   user.ToResponse(),
 		APIKey: user.APIKey,
 	})
 }
@@ -132,13 +148,31 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	ip := c.ClientIP()
+
+	// Brute-force protection: reject while the account or IP is delayed or locked
+	if wait := h.loginGuard.Check(ctx, email, ip); wait > 0 {
+		h.log.Warn("Login attempt blocked",
+			zap.String("request_id", requestID),
+			zap.String("email", req.Email),
+			zap.String("ip", ip),
+			zap.Duration("retry_after", wait),
+		)
+		h.respondTooManyAttempts(c, wait)
+		return
+	}
+
 	// Get user by email
-	user, err := h.userRepo.GetByEmail(c.Request.Context(), strings.ToLower(strings.TrimSpace(req.Email)))
+	user, err := h.userRepo.GetByEmail(ctx, email)
 	if err != nil {
 		h.log.Warn("User not found or invalid credentials",
 			zap.String("request_id", requestID),
 			zap.String("email", req.Email),
 		)
+		h.loginGuard.RecordFailure(ctx, email, ip)
+		h.recordLoginFailure(c, nil)
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
 			Code:      "INVALID_CREDENTIALS",
 			Message:   "Invalid email or password.",
@@ -153,6 +187,16 @@ func (h *UserHandler) Login(c *gin.Context) {
 			zap.String("request_id", requestID),
 			zap.String("email", req.Email),
 		)
+		failure := h.loginGuard.RecordFailure(ctx, email, ip)
+		h.security.Record(ctx, user, models.SecurityEventLoginFailed, ip, c.Request.UserAgent(), "")
+		h.recordLoginFailure(c, &user.ID)
+		if failure.AccountLocked {
+			h.log.Warn("Account locked after repeated failed logins",
+				zap.String("request_id", requestID),
+				zap.Uint("user_id", user.ID),
+			)
+			h.security.Record(ctx, user, models.SecurityEventAccountLocked, ip, c.Request.UserAgent(), "")
+		}
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
 			Code:      "INVALID_CREDENTIALS",
 			Message:   "Invalid email or password.",
@@ -160,21 +204,165 @@ func (h *UserHandler) Login(c *gin.Context) {
 		})
 		return
 	}
+	h.loginGuard.RecordSuccess(ctx, email)
+
+	// Second factor required: return a short-lived challenge instead of credentials
+	if user.TOTPEnabled {
+		challenge, err := h.twoFactor.IssueChallenge(user)
+		if err != nil {
+			h.log.Error("Failed to issue two-factor challenge",
+				zap.String("request_id", requestID),
+				zap.Uint("user_id", user.ID),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Code:      "INTERNAL_SERVER_ERROR",
+				Message:   "Failed to log in.",
+				RequestID: requestID,
+			})
+			return
+		}
+
+		h.log.Info("Password verified, two-factor challenge issued",
+			zap.String("request_id", requestID),
+			zap.Uint("user_id", user.ID),
+		)
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
 
 	h.log.Info("User logged in successfully",
 		zap.String("request_id", requestID),
 		zap.Uint("user_id", user.ID),
 		zap.String("email", user.Email),
 	)
+	recordLogin(c, h.audit, user.ID, "password")
 
 	// Return user info and API key
 	c.JSON(http.StatusOK, models.AuthResponse{
-		User: models.UserResponse{
-			ID:        user.ID,
-			Email:     user.Email,
-			Name:      user.Name,
-			CreatedAt: user.CreatedAt,
-		},
+		User:   user.ToResponse(),
+		APIKey: user.APIKey,
+	})
+}
+
+// recordLoginFailure records a failed password login, against the account if the
+// email belongs to one.
+func (h *UserHandler) recordLoginFailure(c *gin.Context, userID *uint) {
+	entry := auditEntry(c, models.AuditLoginFailed)
+	if userID != nil {
+		entry.TargetType, entry.TargetID = models.AuditTargetUser, userID
+	}
+	h.audit.Record(c.Request.Context(), entry)
+}
+
+// respondTooManyAttempts writes a 429 response with a Retry-After header.
+func (h *UserHandler) respondTooManyAttempts(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+		Code:      "TOO_MANY_ATTEMPTS",
+		Message:   fmt.Sprintf("Too many failed login attempts. Try again in %d seconds.", seconds),
+		RequestID: c.GetString("request_id"),
+	})
+}
+
+// ListSecurityEvents handles GET /api/v1/auth/security-events?cursor=&limit=&order= -
+// list the user's security log, newest first by default
+func (h *UserHandler) ListSecurityEvents(c *gin.Context) {
+	requestID := c.GetString("request_id")
+	userID := middleware.MustGetUserID(c)
+
+	page, err := parsePageRequest(c, 20)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      models.ErrBadRequest,
+			Message:   "Invalid pagination parameters.",
+			RequestID: requestID,
+		})
+		return
+	}
+
+	result, err := h.security.List(c.Request.Context(), userID, page)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) || errors.Is(err, repository.ErrUnsupportedSort) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Code:      models.ErrBadRequest,
+				Message:   "Invalid pagination parameters.",
+				RequestID: requestID,
+			})
+			return
+		}
+		h.log.Error("Failed to list security events",
+			zap.String("request_id", requestID),
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:      "INTERNAL_SERVER_ERROR",
+			Message:   "Failed to list security events.",
+			RequestID: requestID,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// LoginTwoFactor handles POST /api/v1/auth/login/2fa - exchange a login challenge and
+// TOTP or recovery code for credentials
+func (h *UserHandler) LoginTwoFactor(c *gin.Context) {
+	requestID := c.GetString("request_id")
+
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      "INVALID_REQUEST",
+			Message:   "Invalid request format.",
+			RequestID: requestID,
+		})
+		return
+	}
+
+	user, err := h.twoFactor.CompleteChallenge(c.Request.Context(), req.Challenge, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidChallenge), errors.Is(err, services.ErrTwoFactorNotEnabled):
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Code:      "INVALID_CHALLENGE",
+				Message:   "Login challenge is invalid or expired. Please log in again.",
+				RequestID: requestID,
+			})
+		case errors.Is(err, services.ErrInvalidTwoFactorCode):
+			h.log.Warn("Invalid two-factor code",
+				zap.String("request_id", requestID),
+			)
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Code:      "INVALID_2FA_CODE",
+				Message:   "Invalid authentication code.",
+				RequestID: requestID,
+			})
+		default:
+			h.log.Error("Failed to verify two-factor login",
+				zap.String("request_id", requestID),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Code:      "INTERNAL_SERVER_ERROR",
+				Message:   "Failed to log in.",
+				RequestID: requestID,
+			})
+		}
+		return
+	}
+
+	h.log.Info("User logged in successfully with two-factor",
+		zap.String("request_id", requestID),
+		zap.Uint("user_id", user.ID),
+	)
+	recordLogin(c, h.audit, user.ID, "two_factor")
+
+	c.JSON(http.StatusOK, models.AuthResponse{
+		User:   user.ToResponse(),
 		APIKey: user.APIKey,
 	})
 }
@@ -199,12 +387,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, models.UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		Name:      user.Name,
-		CreatedAt: user.CreatedAt,
-	})
+	c.JSON(http.StatusOK, user.ToResponse())
 }
 
 // RegenerateAPIKey handles POST /api/v1/auth/regenerate-key - regenerate API key
@@ -231,6 +414,12 @@ func (h *UserHandler) RegenerateAPIKey(c *gin.Context) {
 		zap.String("request_id", requestID),
 		zap.Uint("user_id", userID),
 	)
+	if user, ok := middleware.GetUser(c); ok {
+		h.security.Record(c.Request.Context(), user, models.SecurityEventAPIKeyRegenerated, c.ClientIP(), c.Request.UserAgent(), "")
+	}
+	entry := auditEntry(c, models.AuditAPIKeyRegenerated)
+	entry.TargetType, entry.TargetID = models.AuditTargetUser, &userID
+	h.audit.Record(c.Request.Context(), entry)
 
 	c.JSON(http.StatusOK, gin.H{
 		"api_key": apiKey,
@@ -12,18 +12,22 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 
+	"vibe-backend/internal/middleware"
 	"vibe-backend/internal/models"
 	"vibe-backend/internal/repository"
 	"vibe-backend/internal/services"
+	"vibe-backend/internal/telemetry"
 )
 
 // VideoHandler handles video analysis HTTP requests.
 type VideoHandler struct {
 	repo           *repository.VideoRepository
 	youtubeService *services.YouTubeService
+	chapters       *services.ChapterService
 	log            *zap.Logger
 }
 
@@ -36,6 +40,11 @@ func NewVideoHandler(repo *repository.VideoRepository, youtubeService *services.
 	}
 }
 
+// SetChapterService sets the service that detects the chapters of analyzed videos.
+func (h *VideoHandler) SetChapterService(svc *services.ChapterService) {
+	h.chapters = svc
+}
+
 // GetMetadata fetches video metadata and AI analysis directly using Gemini.
 // POST /api/v1/videos/metadata
 // Request body: {"url": "https://youtube.com/watch?v=..."} or {"videoId": "..."}
@@ -76,9 +85,20 @@ func (h *VideoHandler) GetMetadata(c *gin.Context) {
 		return
 	}
 
-	// Get metadata
-	metadata, err := h.youtubeService.GetVideoMetadata(c.Request.Context(), videoURL)
+	// Get metadata, billed to the signed-in user if any
+	ctx := c.Request.Context()
+	if userID, ok := middleware.GetUserID(c); ok {
+		ctx = services.WithUsageUser(ctx, userID)
+	}
+	metadata, err := h.youtubeService.GetVideoMetadata(ctx, videoURL)
 	if err != nil {
+		if errors.Is(err, services.ErrLLMBudgetExceeded) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"code":    models.ErrLLMBudgetExceeded,
+				"message": "今日 AI 用量已达上限，请明天再试",
+			})
+			return
+		}
 		h.log.Error("Failed to get metadata",
 			zap.Error(err),
 			zap.String("video_id", videoID),
@@ -181,9 +201,15 @@ func (h *VideoHandler) AnalyzeVideo(c *gin.Context) {
 		return
 	}
 
-	// Call Gemini directly and save result (even if error, save raw response)
+	// Call Gemini directly and save result (even if error, save raw response).
+	// The job is detached from the request's cancellation, but keeps its trace and request ID.
+	jobCtx := context.WithoutCancel(c.Request.Context())
 	go func() {
-		ctx := context.Background()
+		ctx, span := telemetry.StartJob(jobCtx, "video.analyze",
+			attribute.String("video.id", videoID),
+			attribute.String("job.id", jobID),
+		)
+		defer span.End()
 		response, err := h.youtubeService.CallGeminiDirect(ctx, videoURL)
 
 		// Get analysis record
@@ -196,6 +222,7 @@ func (h *VideoHandler) AnalyzeVideo(c *gin.Context) {
 		// Even if Gemini returns error, save the response as completed
 		// User said: "大模型如果返回错误，也是正确的"
 		if err != nil {
+			span.RecordError(err)
 			h.log.Warn("Gemini returned error, but saving as completed",
 				zap.Error(err),
 				zap.String("video_url", videoURL),
@@ -362,7 +389,7 @@ func (h *VideoHandler) processAnalysis(ctx context.Context, analysisID uint, vid
 		return
 	}
 
-	// Update analysis with results - 只保存字幕，不保存摘要、关键点和章节
+	// Update analysis with results - 只保存字幕和章节，不保存摘要和关键点
 	analysisRecord.Summary = ""
 	analysisRecord.Status = "completed"
 	if err := h.repo.UpdateAnalysis(ctx, analysisRecord); err != nil {
@@ -385,12 +412,57 @@ func (h *VideoHandler) processAnalysis(ctx context.Context, analysisID uint, vid
 		h.log.Error("Failed to save transcriptions", zap.Error(err))
 	}
 
+	h.saveChapters(ctx, analysisID, videoID, result)
+
 	h.log.Info("Video analysis completed",
 		zap.Uint("analysis_id", analysisID),
 		zap.String("video_id", videoID),
 	)
 }
 
+// saveChapters detects and saves the chapters of an analyzed video. Failures are
+// logged; the analysis is still usable without chapters.
+func (h *VideoHandler) saveChapters(ctx context.Context, analysisID uint, videoID string, result *services.AnalysisResult) {
+	if h.chapters == nil {
+		return
+	}
+
+	// Without metadata the chapters come from segmenting the transcript
+	metadata, err := h.youtubeService.GetVideoMetadataFromAPI(ctx, videoID)
+	if err != nil {
+		if metadata, err = h.youtubeService.GetVideoMetadataWithYtDlp(ctx, videoID); err != nil {
+			metadata = nil
+		}
+	}
+	transcripts := make([]models.TranscriptItem, len(result.Transcription))
+	for i, tr := range result.Transcription {
+		transcripts[i] = models.TranscriptItem{
+			Timestamp: tr.Timestamp,
+			Seconds:   tr.Seconds,
+			Text:      tr.Text,
+		}
+	}
+	detected, source := h.chapters.Detect(ctx, metadata, transcripts)
+
+	chapters := make([]models.Chapter, len(detected))
+	for i, chapter := range detected {
+		chapters[i] = models.Chapter{
+			AnalysisID: &analysisID,
+			Title:      chapter.Title,
+			Timestamp:  chapter.Timestamp,
+			Seconds:    chapter.Seconds,
+			Source:     source,
+			OrderIndex: i,
+		}
+	}
+	if err := h.repo.CreateChapters(ctx, chapters); err != nil {
+		h.log.Error("Failed to save chapters",
+			zap.Uint("analysis_id", analysisID),
+			zap.Error(err),
+		)
+	}
+}
+
 // GetResult retrieves the analysis result by job ID.
 // GET /api/v1/videos/result/:jobId
 func (h *VideoHandler) GetResult(c *gin.Context) {
@@ -445,7 +517,21 @@ func (h *VideoHandler) GetResult(c *gin.Context) {
 		h.log.Error("Failed to get transcriptions", zap.Error(err))
 	}
 
-	// Convert to response format - 只返回字幕
+	chapters, err := h.repo.GetChaptersByAnalysisID(c.Request.Context(), analysis.ID)
+	if err != nil {
+		h.log.Error("Failed to get chapters", zap.Error(err))
+	}
+	chaptersResp := make([]models.ChapterResponse, len(chapters))
+	for i, ch := range chapters {
+		chaptersResp[i] = models.ChapterResponse{
+			Title:     ch.Title,
+			Timestamp: ch.Timestamp,
+			Seconds:   ch.Seconds,
+			Source:    ch.Source,
+		}
+	}
+
+	// Convert to response format
 	transcriptionsResp := make([]models.TranscriptionResponse, len(transcriptions))
 	for i, tr := range transcriptions {
 		transcriptionsResp[i] = models.TranscriptionResponse{
@@ -460,19 +546,48 @@ func (h *VideoHandler) GetResult(c *gin.Context) {
 		Status:        "completed",
 		Summary:       "",
 		KeyPoints:     []string{},
-		Chapters:      []models.ChapterResponse{},
+		Chapters:      chaptersResp,
 		Transcription: transcriptionsResp,
 	})
 }
 
-// GetHistory retrieves the user's analysis history.
+// GetHistory retrieves a page of the user's analysis history.
+// Supports cursor, limit, sort (created, duration, title), order, status and from/to.
 // GET /api/v1/history
 func (h *VideoHandler) GetHistory(c *gin.Context) {
 	// TODO: Get user ID from JWT token
 	userID := uint(1)
 
-	analyses, err := h.repo.GetHistoryByUserID(c.Request.Context(), userID, 20)
+	page, err := parsePageRequest(c, 20)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "INVALID_REQUEST",
+			"message": "无效的分页参数",
+		})
+		return
+	}
+	created, err := parseDateRange(c)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "INVALID_REQUEST",
+			"message": "无效的日期范围",
+		})
+		return
+	}
+	filter := models.HistoryFilter{
+		Status:  c.Query("status"),
+		Created: created,
+	}
+
+	analyses, pageInfo, err := h.repo.GetHistoryByUserID(c.Request.Context(), userID, filter, page)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) || errors.Is(err, repository.ErrUnsupportedSort) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    "INVALID_REQUEST",
+				"message": "无效的分页游标或排序方式",
+			})
+			return
+		}
 		h.log.Error("Failed to get history", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    "INTERNAL_ERROR",
@@ -492,7 +607,8 @@ func (h *VideoHandler) GetHistory(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, models.HistoryResponse{
-		Items: items,
+		Items:    items,
+		PageInfo: pageInfo,
 	})
 }
 
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"vibe-backend/internal/middleware"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/services"
+)
+
+// WorkspaceHandler handles workspace, member and invitation requests.
+type WorkspaceHandler struct {
+	workspaces *services.WorkspaceService
+	log        *zap.Logger
+}
+
+// NewWorkspaceHandler creates a new WorkspaceHandler.
+func NewWorkspaceHandler(workspaces *services.WorkspaceService, log *zap.Logger) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaces: workspaces,
+		log:        log,
+	}
+}
+
+// List handles GET /api/v1/workspaces - list the caller's workspaces
+func (h *WorkspaceHandler) List(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+
+	workspaces, err := h.workspaces.List(c.Request.Context(), user)
+	if err != nil {
+		h.respondError(c, err, "Failed to list workspaces")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": workspaces})
+}
+
+// Create handles POST /api/v1/workspaces - create a team workspace
+func (h *WorkspaceHandler) Create(c *gin.Context) {
+	var req models.CreateWorkspaceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	workspace, err := h.workspaces.Create(c.Request.Context(), middleware.MustGetUserID(c), req.Name)
+	if err != nil {
+		h.respondError(c, err, "Failed to create workspace")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": workspace})
+}
+
+// Get handles GET /api/v1/workspaces/:id
+func (h *WorkspaceHandler) Get(c *gin.Context) {
+	workspaceID, ok := parseUintParam(c, "id", "Invalid workspace ID format.")
+	if !ok {
+		return
+	}
+
+	workspace, err := h.workspaces.Get(c.Request.Context(), middleware.MustGetUserID(c), workspaceID)
+	if err != nil {
+		h.respondError(c, err, "Failed to get workspace")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": workspace})
+}
+
+// Update handles PATCH /api/v1/workspaces/:id - rename a workspace
+func (h *WorkspaceHandler) Update(c *gin.Context) {
+	workspaceID, ok := parseUintParam(c, "id", "Invalid workspace ID format.")
+	if !ok {
+		return
+	}
+	var req models.UpdateWorkspaceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	workspace, err := h.workspaces.Rename(c.Request.Context(), middleware.MustGetUserID(c), workspaceID, req.Name)
+	if err != nil {
+		h.respondError(c, err, "Failed to update workspace")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": workspace})
+}
+
+// Delete handles DELETE /api/v1/workspaces/:id - delete an empty team workspace
+func (h *WorkspaceHandler) Delete(c *gin.Context) {
+	workspaceID, ok := parseUintParam(c, "id", "Invalid workspace ID format.")
+	if !ok {
+		return
+	}
+
+	if err := h.workspaces.Delete(c.Request.Context(), middleware.MustGetUserID(c), workspaceID); err != nil {
+		h.respondError(c, err, "Failed to delete workspace")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Workspace deleted."})
+}
+
+// ListMembers handles GET /api/v1/workspaces/:id/members
+func (h *WorkspaceHandler) ListMembers(c *gin.Context) {
+	workspaceID, ok := parseUintParam(c, "id", "Invalid workspace ID format.")
+	if !ok {
+		return
+	}
+
+	members, err := h.workspaces.ListMembers(c.Request.Context(), middleware.MustGetUserID(c), workspaceID)
+	if err != nil {
+		h.respondError(c, err, "Failed to list workspace members")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": members})
+}
+
+// UpdateMember handles PATCH /api/v1/workspaces/:id/members/:userId - change a member's role
+func (h *WorkspaceHandler) UpdateMember(c *gin.Context) {
+	workspaceID, ok := parseUintParam(c, "id", "Invalid workspace ID format.")
+	if !ok {
+		return
+	}
+	memberID, ok := parseUintParam(c, "userId", "Invalid user ID format.")
+	if !ok {
+		return
+	}
+	var req models.UpdateMemberRoleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.workspaces.UpdateMemberRole(c.Request.Context(), middleware.MustGetUserID(c), workspaceID, memberID, req.Role); err != nil {
+		h.respondError(c, err, "Failed to update workspace member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member role updated."})
+}
+
+// RemoveMember handles DELETE /api/v1/workspaces/:id/members/:userId - remove a member or leave
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	workspaceID, ok := parseUintParam(c, "id", "Invalid workspace ID format.")
+	if !ok {
+		return
+	}
+	memberID, ok := parseUintParam(c, "userId", "Invalid user ID format.")
+	if !ok {
+		return
+	}
+
+	if err := h.workspaces.RemoveMember(c.Request.Context(), middleware.MustGetUserID(c), workspaceID, memberID); err != nil {
+		h.respondError(c, err, "Failed to remove workspace member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed."})
+}
+
+// ListInvitations handles GET /api/v1/workspaces/:id/invitations
+func (h *WorkspaceHandler) ListInvitations(c *gin.Context) {
+	workspaceID, ok := parseUintParam(c, "id", "Invalid workspace ID format.")
+	if !ok {
+		return
+	}
+
+	invitations, err := h.workspaces.ListInvitations(c.Request.Context(), middleware.MustGetUserID(c), workspaceID)
+	if err != nil {
+		h.respondError(c, err, "Failed to list invitations")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": invitations})
+}
+
+// CreateInvitation handles POST /api/v1/workspaces/:id/invitations - invite by email
+func (h *WorkspaceHandler) CreateInvitation(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	workspaceID, ok := parseUintParam(c, "id", "Invalid workspace ID format.")
+	if !ok {
+		return
+	}
+	var req models.CreateInvitationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := h.workspaces.Invite(c.Request.Context(), user, workspaceID, req.Email, req.Role)
+	if err != nil {
+		h.respondError(c, err, "Failed to create invitation")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": result})
+}
+
+// RevokeInvitation handles DELETE /api/v1/workspaces/:id/invitations/:invitationId
+func (h *WorkspaceHandler) RevokeInvitation(c *gin.Context) {
+	workspaceID, ok := parseUintParam(c, "id", "Invalid workspace ID format.")
+	if !ok {
+		return
+	}
+	invitationID, ok := parseUintParam(c, "invitationId", "Invalid invitation ID format.")
+	if !ok {
+		return
+	}
+
+	if err := h.workspaces.RevokeInvitation(c.Request.Context(), middleware.MustGetUserID(c), workspaceID, invitationID); err != nil {
+		h.respondError(c, err, "Failed to revoke invitation")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked."})
+}
+
+// AcceptInvitation handles POST /api/v1/workspaces/invitations/accept - join via invitation token
+func (h *WorkspaceHandler) AcceptInvitation(c *gin.Context) {
+	user, ok := middleware.GetUser(c)
+	if !ok {
+		respondNoUser(c)
+		return
+	}
+	var req models.AcceptInvitationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	workspace, err := h.workspaces.AcceptInvitation(c.Request.Context(), user, req.Token)
+	if err != nil {
+		h.respondError(c, err, "Failed to accept invitation")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": workspace})
+}
+
+// respondError maps workspace service errors to HTTP responses.
+func (h *WorkspaceHandler) respondError(c *gin.Context, err error, logMessage string) {
+	requestID := c.GetString("request_id")
+
+	status, code, message := http.StatusInternalServerError, models.ErrInternalServer, "An unexpected error occurred."
+	switch {
+	case errors.Is(err, services.ErrWorkspaceNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "Workspace not found."
+	case errors.Is(err, services.ErrWorkspaceForbidden):
+		status, code, message = http.StatusForbidden, models.ErrForbidden, "You do not have permission to do this in this workspace."
+	case errors.Is(err, services.ErrPersonalWorkspace):
+		status, code, message = http.StatusBadRequest, "PERSONAL_WORKSPACE", "This is not possible in a personal workspace."
+	case errors.Is(err, services.ErrWorkspaceNotEmpty):
+		status, code, message = http.StatusConflict, "WORKSPACE_NOT_EMPTY", "Move or delete the workspace's insights first."
+	case errors.Is(err, services.ErrLastOwner):
+		status, code, message = http.StatusConflict, "LAST_OWNER", "A workspace must keep at least one owner."
+	case errors.Is(err, services.ErrMemberNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "Member not found."
+	case errors.Is(err, services.ErrInvitationNotFound):
+		status, code, message = http.StatusNotFound, models.ErrNotFound, "Invitation not found."
+	case errors.Is(err, services.ErrInvitationInvalid):
+		status, code, message = http.StatusBadRequest, "INVALID_INVITATION", "Invitation is invalid or expired."
+	case errors.Is(err, services.ErrInvitationWrongEmail):
+		status, code, message = http.StatusForbidden, "INVITATION_EMAIL_MISMATCH", "This invitation was sent to a different email address."
+	}
+
+	if status == http.StatusInternalServerError {
+		h.log.Error(logMessage,
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Code:      code,
+		Message:   message,
+		RequestID: requestID,
+	})
+}
+
+// parseUintParam parses a numeric path parameter, writing a 400 response on failure.
+func parseUintParam(c *gin.Context, name, message string) (uint, bool) {
+	value, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      "INVALID_ID",
+			Message:   message,
+			RequestID: c.GetString("request_id"),
+		})
+		return 0, false
+	}
+	return uint(value), true
+}
+
+// bindJSON binds the request body, writing a 400 response on failure.
+func bindJSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:      "INVALID_REQUEST",
+			Message:   "Invalid request format.",
+			RequestID: c.GetString("request_id"),
+		})
+		return false
+	}
+	return true
+}
+
+// respondNoUser writes the response for a missing user in context.
+func respondNoUser(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+		Code:      models.ErrUnauthorized,
+		Message:   "User not found.",
+		RequestID: c.GetString("request_id"),
+	})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -11,10 +12,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"golang.org/x/oauth2"
-	"gorm.io/gorm"
 
 	"vibe-backend/internal/models"
-	"vibe-backend/internal/repository"
 	"vibe-backend/internal/services"
 )
 
@@ -23,21 +22,31 @@ type YouTubeAPIHandler struct {
 	youtubeAPI     *services.YouTubeAPIService
 	youtubeService *services.YouTubeService
 	oauthService   *services.OAuthService
-	userRepo       repository.UserRepository
+	identity       *services.IdentityService
+	twoFactor      *services.TwoFactorService
+	audit          *services.AuditService
 	log            *zap.Logger
 }
 
 // NewYouTubeAPIHandler creates a new YouTubeAPIHandler.
-func NewYouTubeAPIHandler(youtubeAPI *services.YouTubeAPIService, youtubeService *services.YouTubeService, oauthService *services.OAuthService, userRepo *repository.UserRepository, log *zap.Logger) *YouTubeAPIHandler {
+func NewYouTubeAPIHandler(youtubeAPI *services.YouTubeAPIService, youtubeService *services.YouTubeService, oauthService *services.OAuthService, log *zap.Logger) *YouTubeAPIHandler {
 	return &YouTubeAPIHandler{
 		youtubeAPI:     youtubeAPI,
 		youtubeService: youtubeService,
 		oauthService:   oauthService,
-		userRepo:       *userRepo,
 		log:            log,
 	}
 }
 
+// SetSignIn enables signing in through the Google OAuth callback. Sign-in follows
+// the identity linking rules and the second factor of provider logins; without it
+// the callback only returns the Google token.
+func (h *YouTubeAPIHandler) SetSignIn(identity *services.IdentityService, twoFactor *services.TwoFactorService, audit *services.AuditService) {
+	h.identity = identity
+	h.twoFactor = twoFactor
+	h.audit = audit
+}
+
 // GetAuthURL generates Google OAuth authorization URL.
 // GET /api/v1/auth/google/url
 func (h *YouTubeAPIHandler) GetAuthURL(c *gin.Context) {
@@ -107,74 +116,100 @@ func (h *YouTubeAPIHandler) HandleCallback(c *gin.Context) {
 		return
 	}
 
-	// Check if user exists
-	user, err := h.userRepo.GetByEmail(c.Request.Context(), strings.ToLower(userInfo.Email))
-	if err != nil && err != gorm.ErrRecordNotFound {
-		h.log.Error("Failed to query user",
-			zap.String("email", userInfo.Email),
+	// Convert token to JSON for storage (for YouTube API access)
+	tokenJSON, err := h.oauthService.TokenToJSON(token)
+	if err != nil {
+		h.log.Error("Failed to serialize token",
 			zap.Error(err),
 		)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Code:    models.ErrorAuthFailed,
-			Message: "用户查询失败",
+			Message: "授权失败，请重试",
 		})
 		return
 	}
 
-	// If user doesn't exist, create new user
-	if user == nil {
-		user = &models.User{
-			Email:    strings.ToLower(userInfo.Email),
-			Name:     userInfo.Name,
-			Password: "google-oauth-user", // Placeholder password for OAuth users
+	response := models.OAuthCallbackResponse{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenType:    token.TokenType,
+		Expiry:       token.Expiry,
+		TokenJSON:    tokenJSON,
+	}
+	if h.identity == nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	// Sign in as the "google" provider, so the identity is shared with OIDC sign-in
+	// (Google's user ID is its subject) and the same linking rules apply
+	result, err := h.identity.LoginVerified(c.Request.Context(), &models.ExternalIdentity{
+		Provider:      "google",
+		Subject:       userInfo.ID,
+		Email:         strings.ToLower(userInfo.Email),
+		EmailVerified: userInfo.VerifiedEmail,
+		Name:          userInfo.Name,
+		AvatarURL:     userInfo.Picture,
+	})
+	if err != nil {
+		h.log.Warn("Google sign-in failed",
+			zap.String("email", userInfo.Email),
+			zap.Error(err),
+		)
+		if errors.Is(err, services.ErrEmailInUse) {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Code:    "USER_EXISTS",
+				Message: "该邮箱已注册，请先登录后再关联 Google 账号",
+			})
+			return
 		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:    models.ErrorAuthFailed,
+			Message: "登录失败，请重试",
+		})
+		return
+	}
+	user := result.User
+
+	if result.Linked {
+		entry := auditEntry(c, models.AuditIdentityLinked)
+		entry.ActorID = &user.ID
+		entry.TargetType, entry.TargetID = models.AuditTargetUser, &user.ID
+		entry.Details = services.AuditDetails(map[string]interface{}{"provider": "google"})
+		h.audit.Record(c.Request.Context(), entry)
+	}
 
-		if err := h.userRepo.Create(c.Request.Context(), user); err != nil {
-			h.log.Error("Failed to create user",
-				zap.String("email", userInfo.Email),
+	// Google sign-in does not replace the second factor: the client completes it
+	// with the challenge before getting the API key
+	if user.TOTPEnabled {
+		response.TwoFactor, err = h.twoFactor.IssueChallenge(user)
+		if err != nil {
+			h.log.Error("Failed to issue two-factor challenge",
+				zap.Uint("user_id", user.ID),
 				zap.Error(err),
 			)
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 				Code:    models.ErrorAuthFailed,
-				Message: "用户创建失败",
+				Message: "登录失败，请重试",
 			})
 			return
 		}
-
-		h.log.Info("New user created via Google OAuth",
-			zap.Uint("user_id", user.ID),
-			zap.String("email", user.Email),
-		)
-	}
-
-	// Convert token to JSON for storage (for YouTube API access)
-	tokenJSON, err := h.oauthService.TokenToJSON(token)
-	if err != nil {
-		h.log.Error("Failed to serialize token",
-			zap.Error(err),
-		)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Code:    models.ErrorAuthFailed,
-			Message: "授权失败，请重试",
-		})
+		c.JSON(http.StatusOK, response)
 		return
 	}
 
+	h.log.Info("User logged in via Google OAuth",
+		zap.Uint("user_id", user.ID),
+		zap.Bool("linked", result.Linked),
+		zap.Bool("created", result.Created),
+	)
+	recordLogin(c, h.audit, user.ID, "google")
+
 	// Return both system API key and Google OAuth token
-	c.JSON(http.StatusOK, models.OAuthCallbackResponse{
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
-		TokenType:    token.TokenType,
-		Expiry:       token.Expiry,
-		TokenJSON:    tokenJSON,
-		User: &models.UserResponse{
-			ID:        user.ID,
-			Email:     user.Email,
-			Name:      user.Name,
-			CreatedAt: user.CreatedAt,
-		},
-		APIKey: user.APIKey,
-	})
+	userResponse := user.ToResponse()
+	response.User = &userResponse
+	response.APIKey = user.APIKey
+	c.JSON(http.StatusOK, response)
 }
 
 // RefreshToken refreshes an expired OAuth token.
@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "vibe"
+
+// Outcomes of an operation, used as the "outcome" label.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Jobs, used as the "job" label.
+const (
+	JobInsight         = "insight"
+	JobTranslation     = "translation"
+	JobAccountExport   = "account_export"
+	JobAccountDeletion = "account_deletion"
+	JobTrashPurge      = "trash_purge"
+	JobRoomSweep       = "room_sweep"
+)
+
+// registry holds the application's collectors, so /metrics does not expose
+// collectors registered by dependencies on the default registry.
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests by route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	jobsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "jobs",
+		Name:      "total",
+		Help:      "Background jobs finished, by job and outcome.",
+	}, []string{"job", "outcome"})
+
+	jobStageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "jobs",
+		Name:      "stage_duration_seconds",
+		Help:      "Duration of background job pipeline stages. The stage \"total\" is the whole job.",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"job", "stage", "outcome"})
+
+	llmRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of OpenRouter completions by feature and outcome.",
+		Buckets:   []float64{0.25, 0.5, 1, 2.5, 5, 10, 20, 40, 80, 160},
+	}, []string{"feature", "outcome"})
+
+	llmTokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "tokens_total",
+		Help:      "Tokens reported by OpenRouter, by feature, model and type (prompt or completion).",
+	}, []string{"feature", "model", "type"})
+
+	ytdlpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "ytdlp",
+		Name:      "duration_seconds",
+		Help:      "Duration of yt-dlp subprocesses by operation and outcome.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 40, 80},
+	}, []string{"operation", "outcome"})
+
+	subtitleFetchesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ytdlp",
+		Name:      "subtitle_fetches_total",
+		Help:      "Subtitle fetches by the fallback method that succeeded, or \"none\".",
+	}, []string{"method"})
+
+	signalingConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "signaling",
+		Name:      "connections",
+		Help:      "Room signaling WebSockets connected to this instance.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		jobsTotal,
+		jobStageDuration,
+		llmRequestDuration,
+		llmTokensTotal,
+		ytdlpDuration,
+		subtitleFetchesTotal,
+		signalingConnections,
+	)
+}
+
+// Handler returns the handler of the /metrics endpoint. A non-empty token must be
+// sent as "Authorization: Bearer <token>".
+func Handler(token string) http.Handler {
+	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return handler
+	}
+
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// ObserveHTTPRequest records a served HTTP request. route is the route template,
+// e.g. "/api/v1/insights/:id", so IDs do not blow up the label cardinality.
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// ObserveJob records a finished background job that started at start.
+func ObserveJob(job string, start time.Time, outcome string) {
+	jobsTotal.WithLabelValues(job, outcome).Inc()
+	jobStageDuration.WithLabelValues(job, "total", outcome).Observe(time.Since(start).Seconds())
+}
+
+// ObserveJobStage records a pipeline stage of a job that started at start and
+// failed if err is non-nil.
+func ObserveJobStage(job, stage string, start time.Time, err error) {
+	jobStageDuration.WithLabelValues(job, stage, Outcome(err)).Observe(time.Since(start).Seconds())
+}
+
+// ObserveLLMRequest records an OpenRouter completion that started at start and
+// failed if err is non-nil.
+func ObserveLLMRequest(feature string, start time.Time, err error) {
+	llmRequestDuration.WithLabelValues(feature, Outcome(err)).Observe(time.Since(start).Seconds())
+}
+
+// AddLLMTokens records the tokens of an OpenRouter completion.
+func AddLLMTokens(feature, model string, promptTokens, completionTokens int) {
+	llmTokensTotal.WithLabelValues(feature, model, "prompt").Add(float64(promptTokens))
+	llmTokensTotal.WithLabelValues(feature, model, "completion").Add(float64(completionTokens))
+}
+
+// ObserveYtDlp records a yt-dlp subprocess that started at start and failed if
+// err is non-nil.
+func ObserveYtDlp(operation string, start time.Time, err error) {
+	ytdlpDuration.WithLabelValues(operation, Outcome(err)).Observe(time.Since(start).Seconds())
+}
+
+// SubtitleFetched records which subtitle fallback method succeeded, or "none".
+func SubtitleFetched(method string) {
+	subtitleFetchesTotal.WithLabelValues(method).Inc()
+}
+
+// SignalingConnected records a signaling WebSocket joining (+1) or leaving (-1) a room.
+func SignalingConnected(delta int) {
+	signalingConnections.Add(float64(delta))
+}
+
+// Outcome returns the outcome of an operation that failed if err is non-nil.
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
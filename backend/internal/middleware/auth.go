@@ -24,6 +24,12 @@ func Auth(userRepo *repository.UserRepository, log *zap.Logger) gin.HandlerFunc
 	return func(c *gin.Context) {
 		requestID := c.GetString(RequestIDKey)
 
+		// Already authenticated by OptionalAuth earlier in the chain
+		if _, ok := GetUser(c); ok {
+			c.Next()
+			return
+		}
+
 		// Get API key from Authorization header
 		authHeader := c.GetHeader(AuthorizationHeader)
 		if authHeader == "" {
@@ -115,6 +121,31 @@ func OptionalAuth(userRepo *repository.UserRepository, log *zap.Logger) gin.Hand
 	}
 }
 
+// RequireAdmin returns a Gin middleware that only lets through users whose email is
+// in adminEmails, for operator endpoints. It must run after Auth.
+func RequireAdmin(adminEmails []string) gin.HandlerFunc {
+	admins := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			admins[email] = true
+		}
+	}
+
+	return func(c *gin.Context) {
+		user, ok := GetUser(c)
+		if !ok || !admins[strings.ToLower(user.Email)] {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Code:      models.ErrForbidden,
+				Message:   "Administrator access required.",
+				RequestID: c.GetString(RequestIDKey),
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 // GetUserID extracts the user ID from the Gin context.
 func GetUserID(c *gin.Context) (uint, bool) {
 	userID, exists := c.Get(UserIDKey)
@@ -1,129 +1,273 @@
 package middleware
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 	"vibe-backend/internal/models"
 )
 
-// RateLimitConfig holds the configuration for rate limiting.
-type RateLimitConfig struct {
-	// MaxRequests is the maximum number of requests allowed per window.
-	MaxRequests int
-	// Window is the time window for rate limiting.
+// RateLimitPolicy describes a limit shared by the routes it is applied to.
+type RateLimitPolicy struct {
+	// Name identifies the policy; routes using the same name share counters.
+	Name string
+	// Limit is the maximum number of requests allowed per window.
+	Limit int
+	// Window is the sliding time window for rate limiting.
 	Window time.Duration
-	// CleanupInterval is how often to clean up old entries.
-	CleanupInterval time.Duration
 }
 
-// DefaultRateLimitConfig returns the default rate limit configuration.
-func DefaultRateLimitConfig() RateLimitConfig {
-	return RateLimitConfig{
-		MaxRequests:     10,
-		Window:          time.Minute,
-		CleanupInterval: 5 * time.Minute,
+// ShareAccessPolicy returns a policy for share access. It is strict to prevent
+// brute-force attacks on password-protected shares.
+func ShareAccessPolicy(name string) RateLimitPolicy {
+	return RateLimitPolicy{
+		Name:   name,
+		Limit:  5, // 5 requests per minute per client
+		Window: time.Minute,
 	}
 }
 
-// rateLimitEntry tracks request count for a client.
-type rateLimitEntry struct {
-	count     int
-	resetTime time.Time
+// TwoFactorPolicy returns a policy for second-factor code verification.
+func TwoFactorPolicy(name string) RateLimitPolicy {
+	return RateLimitPolicy{
+		Name:   name,
+		Limit:  10, // 10 attempts per 5 minutes per client
+		Window: 5 * time.Minute,
+	}
 }
 
-// rateLimiter implements an in-memory rate limiter.
-type rateLimiter struct {
-	config  RateLimitConfig
-	entries map[string]*rateLimitEntry
-	mu      sync.RWMutex
+// rateLimitResult is the outcome of taking a request from a window.
+type rateLimitResult struct {
+	allowed   bool
+	remaining int
+	// reset is how long until the oldest request in the window expires.
+	reset time.Duration
 }
 
-// newRateLimiter creates a new rate limiter with the given configuration.
-func newRateLimiter(config RateLimitConfig) *rateLimiter {
-	rl := &rateLimiter{
-		config:  config,
-		entries: make(map[string]*rateLimitEntry),
-	}
+// rateLimitStore keeps sliding-window request logs.
+type rateLimitStore interface {
+	// take records a request against key if fewer than limit were made within window.
+	take(ctx context.Context, key string, limit int, window time.Duration) (rateLimitResult, error)
+}
 
-	// Start cleanup goroutine
-	go rl.cleanup()
+// RateLimiter applies sliding-window rate limit policies. Request logs live in Redis
+// when available so limits survive restarts and are shared across replicas;
+// otherwise (or if Redis fails) an in-memory store is used.
+type RateLimiter struct {
+	store    rateLimitStore
+	fallback *memoryRateLimitStore
+	log      *zap.Logger
+}
 
-	return rl
+// NewRateLimiter creates a new RateLimiter. redisClient may be nil.
+func NewRateLimiter(redisClient *redis.Client, log *zap.Logger) *RateLimiter {
+	fallback := newMemoryRateLimitStore(5 * time.Minute)
+	l := &RateLimiter{
+		store:    fallback,
+		fallback: fallback,
+		log:      log,
+	}
+	if redisClient != nil {
+		l.store = &redisRateLimitStore{client: redisClient}
+	}
+	return l
 }
 
-// cleanup removes expired entries periodically.
-func (rl *rateLimiter) cleanup() {
-	ticker := time.NewTicker(rl.config.CleanupInterval)
-	defer ticker.Stop()
+// Limit returns a Gin middleware enforcing policy. Requests are counted per user
+// when Auth or OptionalAuth ran before it, and per client IP otherwise. Every
+// response carries RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers; rejected requests also get Retry-After.
+func (l *RateLimiter) Limit(policy RateLimitPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "ratelimit:" + policy.Name + ":" + rateLimitSubject(c)
+		result := l.take(c.Request.Context(), key, policy)
 
-	for range ticker.C {
-		rl.mu.Lock()
-		now := time.Now()
-		for key, entry := range rl.entries {
-			if now.After(entry.resetTime) {
-				delete(rl.entries, key)
-			}
+		reset := strconv.Itoa(int(math.Ceil(result.reset.Seconds())))
+		c.Header("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.remaining))
+		c.Header("RateLimit-Reset", reset)
+
+		if !result.allowed {
+			c.Header("Retry-After", reset)
+			c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+				Code:      models.ErrRateLimitExceeded,
+				Message:   "请求过于频繁，请稍后再试",
+				RequestID: c.GetString(RequestIDKey),
+			})
+			c.Abort()
+			return
 		}
-		rl.mu.Unlock()
+
+		c.Next()
 	}
 }
 
-// allow checks if a request from the given key should be allowed.
-func (rl *rateLimiter) allow(key string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+// take runs against the primary store, falling back to memory on error.
+func (l *RateLimiter) take(ctx context.Context, key string, policy RateLimitPolicy) rateLimitResult {
+	result, err := l.store.take(ctx, key, policy.Limit, policy.Window)
+	if err == nil || l.store == rateLimitStore(l.fallback) {
+		return result
+	}
+	l.log.Warn("Rate limit store unavailable, using in-memory fallback",
+		zap.String("policy", policy.Name),
+		zap.Error(err),
+	)
+	result, _ = l.fallback.take(ctx, key, policy.Limit, policy.Window)
+	return result
+}
 
-	now := time.Now()
-	entry, exists := rl.entries[key]
+// rateLimitSubject identifies who a request is counted against: the signed-in user
+// (each user has a single API key) or, for anonymous requests, the client IP.
+// Unverified Authorization headers are ignored so random keys cannot be used to
+// get fresh limits.
+func rateLimitSubject(c *gin.Context) string {
+	if userID, ok := GetUserID(c); ok {
+		return "user:" + strconv.FormatUint(uint64(userID), 10)
+	}
+	return "ip:" + c.ClientIP()
+}
 
-	if !exists || now.After(entry.resetTime) {
-		// Create new entry or reset expired entry
-		rl.entries[key] = &rateLimitEntry{
-			count:     1,
-			resetTime: now.Add(rl.config.Window),
+// UnlessAuthenticated applies a middleware only to requests without a signed-in user,
+// e.g. to rate limit anonymous visitors. It must run after Auth or OptionalAuth.
+func UnlessAuthenticated(middleware gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetUserID(c); ok {
+			c.Next()
+			return
 		}
-		return true
+		middleware(c)
 	}
+}
+
+// slidingWindowScript trims a request log to the window and appends the current
+// request if there is room. It returns {allowed, count, milliseconds until reset}.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local reset = window
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, count, reset}
+`)
 
-	if entry.count >= rl.config.MaxRequests {
-		return false
+// redisRateLimitStore keeps request logs in Redis sorted sets.
+type redisRateLimitStore struct {
+	client *redis.Client
+}
+
+func (s *redisRateLimitStore) take(ctx context.Context, key string, limit int, window time.Duration) (rateLimitResult, error) {
+	now := time.Now().UnixMilli()
+	// Members must be unique so concurrent requests in the same millisecond all count
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return rateLimitResult{}, err
 	}
+	member := strconv.FormatInt(now, 10) + "-" + hex.EncodeToString(nonce)
 
-	entry.count++
-	return true
+	values, err := slidingWindowScript.Run(ctx, s.client, []string{key},
+		now, window.Milliseconds(), limit, member).Int64Slice()
+	if err != nil {
+		return rateLimitResult{}, err
+	}
+	return rateLimitResult{
+		allowed:   values[0] == 1,
+		remaining: max(limit-int(values[1]), 0),
+		reset:     time.Duration(values[2]) * time.Millisecond,
+	}, nil
 }
 
-// RateLimit returns a Gin middleware that limits requests per IP.
-func RateLimit(config RateLimitConfig) gin.HandlerFunc {
-	limiter := newRateLimiter(config)
+// memoryRateLimitStore keeps request logs in process memory.
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryRateLimitEntry
+}
 
-	return func(c *gin.Context) {
-		key := c.ClientIP()
+type memoryRateLimitEntry struct {
+	requests  []time.Time
+	expiresAt time.Time
+}
 
-		if !limiter.allow(key) {
-			c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
-				Code:      models.ErrRateLimitExceeded,
-				Message:   "请求过于频繁，请稍后再试",
-				RequestID: c.GetString("request_id"),
-			})
-			c.Abort()
-			return
-		}
+func newMemoryRateLimitStore(cleanupInterval time.Duration) *memoryRateLimitStore {
+	s := &memoryRateLimitStore{entries: make(map[string]*memoryRateLimitEntry)}
+	go s.cleanup(cleanupInterval)
+	return s
+}
 
-		c.Next()
+// cleanup removes expired entries periodically.
+func (s *memoryRateLimitStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		now := time.Now()
+		for key, entry := range s.entries {
+			if now.After(entry.expiresAt) {
+				delete(s.entries, key)
+			}
+		}
+		s.mu.Unlock()
 	}
 }
 
-// ShareAccessRateLimit returns a rate limiter specifically for share access.
-// More strict to prevent brute-force attacks on password-protected shares.
-func ShareAccessRateLimit() gin.HandlerFunc {
-	config := RateLimitConfig{
-		MaxRequests:     5,               // 5 requests per minute per IP
-		Window:          time.Minute,
-		CleanupInterval: 5 * time.Minute,
+func (s *memoryRateLimitStore) take(_ context.Context, key string, limit int, window time.Duration) (rateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryRateLimitEntry{}
+		s.entries[key] = entry
+	}
+
+	// Drop requests that left the window
+	cutoff := now.Add(-window)
+	kept := entry.requests[:0]
+	for _, t := range entry.requests {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	entry.requests = kept
+
+	allowed := len(entry.requests) < limit
+	if allowed {
+		entry.requests = append(entry.requests, now)
+	}
+	entry.expiresAt = now.Add(window)
+
+	reset := window
+	if len(entry.requests) > 0 {
+		reset = entry.requests[0].Add(window).Sub(now)
 	}
-	return RateLimit(config)
+	return rateLimitResult{
+		allowed:   allowed,
+		remaining: max(limit-len(entry.requests), 0),
+		reset:     reset,
+	}, nil
 }
@@ -3,6 +3,9 @@ package middleware
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"vibe-backend/internal/telemetry"
 )
 
 const (
@@ -12,7 +15,9 @@ const (
 	RequestIDKey = "request_id"
 )
 
-// RequestID returns a Gin middleware that generates or extracts a request ID.
+// RequestID returns a Gin middleware that generates or extracts a request ID. The ID
+// is also put in the request's context, so it follows the request into services and
+// background jobs, and on the request's span.
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Try to get request ID from header
@@ -26,6 +31,8 @@ func RequestID() gin.HandlerFunc {
 		// Set request ID in context and response header
 		c.Set(RequestIDKey, requestID)
 		c.Header(RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(telemetry.WithRequestID(c.Request.Context(), requestID))
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("request.id", requestID))
 
 		c.Next()
 	}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// AccountExportStatus is the state of a data export.
+type AccountExportStatus string
+
+const (
+	AccountExportPending   AccountExportStatus = "pending"
+	AccountExportCompleted AccountExportStatus = "completed"
+	AccountExportFailed    AccountExportStatus = "failed"
+)
+
+// AccountExport is a ZIP archive of everything a user owns, built in the background.
+type AccountExport struct {
+	ID           uint                `json:"id" gorm:"primaryKey"`
+	UserID       uint                `json:"-" gorm:"index;not null"`
+	Status       AccountExportStatus `json:"status" gorm:"type:varchar(20);not null;default:'pending'"`
+	Archive      []byte              `json:"-" gorm:"type:bytea"` // Loaded only for downloads
+	SizeBytes    int64               `json:"size_bytes" gorm:"not null;default:0"`
+	ErrorMessage string              `json:"error_message,omitempty" gorm:"type:text"`
+	CompletedAt  *time.Time          `json:"completed_at,omitempty"`
+	ExpiresAt    *time.Time          `json:"expires_at,omitempty" gorm:"index"` // Set once completed
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+// TableName returns the table name for AccountExport model.
+func (AccountExport) TableName() string {
+	return "account_exports"
+}
+
+// AccountDeletionStatus is the state of an account deletion request.
+type AccountDeletionStatus string
+
+const (
+	AccountDeletionScheduled AccountDeletionStatus = "scheduled"
+	AccountDeletionCancelled AccountDeletionStatus = "cancelled"
+	AccountDeletionCompleted AccountDeletionStatus = "completed"
+)
+
+// AccountDeletion is a request to delete an account after a grace period. The row
+// is kept after the user is deleted as the audit record of the deletion.
+type AccountDeletion struct {
+	ID           uint                  `json:"id" gorm:"primaryKey"`
+	UserID       uint                  `json:"-" gorm:"index;not null"`
+	EmailHash    string                `json:"-" gorm:"type:varchar(64);not null"` // SHA-256 hex of the normalized email
+	Status       AccountDeletionStatus `json:"status" gorm:"type:varchar(20);not null;default:'scheduled'"`
+	RequestedAt  time.Time             `json:"requested_at" gorm:"not null"`
+	ScheduledFor time.Time             `json:"scheduled_for" gorm:"not null"`
+	CancelledAt  *time.Time            `json:"cancelled_at,omitempty"`
+	CompletedAt  *time.Time            `json:"completed_at,omitempty"`
+	IPAddress    string                `json:"-" gorm:"type:varchar(64)"`
+	DeletedRows  datatypes.JSON        `json:"-" gorm:"type:jsonb"` // Rows removed per table
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+}
+
+// TableName returns the table name for AccountDeletion model.
+func (AccountDeletion) TableName() string {
+	return "account_deletions"
+}
+
+// DeleteAccountRequest asks for the caller's account to be deleted. The email must be
+// retyped; the password is required unless the account is passwordless, and a TOTP
+// or recovery code if two-factor authentication is enabled.
+type DeleteAccountRequest struct {
+	ConfirmEmail string `json:"confirm_email" binding:"required"`
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+}
+
+// AccountDeletionResponse reports a scheduled account deletion.
+type AccountDeletionResponse struct {
+	Status       AccountDeletionStatus `json:"status"`
+	RequestedAt  time.Time             `json:"requested_at"`
+	ScheduledFor time.Time             `json:"scheduled_for"`
+	// RevokedShareLinks is how many share links stopped working when the deletion
+	// was requested. They stay revoked if the deletion is cancelled.
+	RevokedShareLinks int64 `json:"revoked_share_links,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// AnkiExportRequest selects what goes into an Anki deck export. Nil include flags
+// default to true.
+type AnkiExportRequest struct {
+	InsightIDs        []uint `json:"insight_ids" binding:"required,min=1,max=200"`
+	DeckName          string `json:"deck_name" binding:"max=100"` // Root deck; defaults to "Vibe"
+	IncludeHighlights *bool  `json:"include_highlights"`
+	IncludeKeyPoints  *bool  `json:"include_key_points"`
+	IncludeFlashcards *bool  `json:"include_flashcards"` // The user's generated Q/A cards, with their review state
+	IncludeMedia      *bool  `json:"include_media"`      // Insight thumbnails
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// AuditAction identifies a security- or data-relevant action in the audit log.
+type AuditAction string
+
+const (
+	AuditLogin                    AuditAction = "auth.login"
+	AuditLoginFailed              AuditAction = "auth.login_failed"
+	AuditAPIKeyRegenerated        AuditAction = "auth.api_key_regenerated"
+	AuditIdentityLinked           AuditAction = "identity.linked"
+	AuditIdentityUnlinked         AuditAction = "identity.unlinked"
+	AuditShareCreated             AuditAction = "share.created"
+	AuditShareUpdated             AuditAction = "share.updated"
+	AuditShareRevoked             AuditAction = "share.revoked"
+	AuditInsightDeleted           AuditAction = "insight.deleted"
+	AuditInsightRestored          AuditAction = "insight.restored"
+	AuditInsightPurged            AuditAction = "insight.purged"
+	AuditTrashEmptied             AuditAction = "trash.emptied"
+	AuditAccountExported          AuditAction = "export.account"
+	AuditAnkiExported             AuditAction = "export.anki"
+	AuditAccountDeletionScheduled AuditAction = "account.deletion_scheduled"
+	AuditAccountDeletionCancelled AuditAction = "account.deletion_cancelled"
+)
+
+// Audit target types.
+const (
+	AuditTargetUser      = "user"
+	AuditTargetIdentity  = "identity"
+	AuditTargetInsight   = "insight"
+	AuditTargetShareLink = "share_link"
+	AuditTargetWorkspace = "workspace"
+	AuditTargetExport    = "export"
+)
+
+// AuditLog is an entry in the append-only audit log: who did what to which target,
+// from where. ActorID is nil for anonymous actions such as failed logins.
+type AuditLog struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	ActorID     *uint          `json:"actor_id,omitempty" gorm:"index:idx_audit_logs_actor_created"`
+	WorkspaceID *uint          `json:"workspace_id,omitempty"`
+	Action      AuditAction    `json:"action" gorm:"type:varchar(50);not null"`
+	TargetType  string         `json:"target_type,omitempty" gorm:"type:varchar(50)"`
+	TargetID    *uint          `json:"target_id,omitempty"`
+	Details     datatypes.JSON `json:"details,omitempty" gorm:"type:jsonb"`
+	IPAddress   string         `json:"ip_address" gorm:"type:varchar(64)"`
+	UserAgent   string         `json:"user_agent" gorm:"type:varchar(500)"`
+	RequestID   string         `json:"request_id" gorm:"type:varchar(100)"`
+	CreatedAt   time.Time      `json:"created_at" gorm:"index:idx_audit_logs_actor_created"`
+
+	Actor *UserSummary `json:"actor,omitempty" gorm:"-"` // Filled in by the repository
+}
+
+// TableName returns the table name for AuditLog model.
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// AuditFilter narrows the audit log. Exactly one of UserID and WorkspaceID is set, by
+// the service, to the log the caller may see.
+type AuditFilter struct {
+	UserID      *uint // A user's log: their actions and those targeting their account
+	WorkspaceID *uint // A workspace's log
+	ActorID     *uint
+	Actions     []AuditAction
+	TargetType  string
+	TargetID    *uint
+	Created     DateRange
+}
+
+// AuditLogListResponse is a page of the audit log.
+type AuditLogListResponse struct {
+	Items []AuditLog `json:"items"`
+	PageInfo
+}
@@ -21,6 +21,7 @@ type Suggestion struct {
 // ChatMessageResponse represents a single message in the response.
 type ChatMessageResponse struct {
 	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"` // Author of the question (assistant replies carry the asker)
 	Role      string    `json:"role"`
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
@@ -33,10 +34,19 @@ type ChatHistoryResponse struct {
 
 // ChatStreamEvent represents a streaming chat event.
 type ChatStreamEvent struct {
-	Role      string `json:"role"`
-	Content   string `json:"content"`
-	Done      bool   `json:"done"`
-	MessageID *uint  `json:"message_id,omitempty"`
+	Role      string         `json:"role"`
+	Content   string         `json:"content"`
+	Done      bool           `json:"done"`
+	MessageID *uint          `json:"message_id,omitempty"`
+	Citations []ChatCitation `json:"citations,omitempty"` // Sent with the final event
+}
+
+// ChatCitation is a chapter of the video that an answer cites by timestamp.
+type ChatCitation struct {
+	Timestamp    string `json:"timestamp"` // As cited, e.g. "05:12"
+	Seconds      int    `json:"seconds"`
+	ChapterTitle string `json:"chapter_title"`
+	ChapterStart int    `json:"chapter_start"` // Start of the chapter in seconds
 }
 
 // AnalyzeEntitiesResponse represents the entity analysis response.
@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// Comment is a comment on an insight, left by a workspace member or by a reader of a
+// share link. Threads are one level deep: a root comment (ParentID nil) and its replies.
+// A root comment may be anchored to a highlight or a transcript timestamp.
+type Comment struct {
+	ID          uint  `json:"id" gorm:"primaryKey"`
+	InsightID   uint  `json:"insight_id" gorm:"index;not null"`
+	ParentID    *uint `json:"parent_id,omitempty" gorm:"index"`
+	ShareLinkID *uint `json:"share_link_id,omitempty" gorm:"index"` // Share link the comment was posted through
+
+	// Author: a signed-in user, or an anonymous reader identified by display name
+	UserID      *uint  `json:"user_id,omitempty" gorm:"index"`
+	AuthorName  string `json:"author_name" gorm:"type:varchar(50);not null"`
+	VisitorHash string `json:"-" gorm:"type:varchar(64)"` // Anonymous commenters only
+
+	Body string `json:"body" gorm:"type:text;not null"`
+
+	// Anchor (root comments only)
+	HighlightID *uint    `json:"highlight_id,omitempty" gorm:"index"`
+	Seconds     *float64 `json:"seconds,omitempty"` // Transcript timestamp
+
+	// Moderation
+	Hidden bool `json:"hidden" gorm:"not null;default:false"`
+	Locked bool `json:"locked" gorm:"not null;default:false"` // Root comments only: no new replies
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for Comment model.
+func (Comment) TableName() string {
+	return "comments"
+}
+
+// Request/Response DTOs
+
+// CreateCommentRequest represents the request to post a comment or reply.
+// AuthorName is required for anonymous commenters and ignored for signed-in users.
+type CreateCommentRequest struct {
+	Body        string   `json:"body" binding:"required,max=5000"`
+	AuthorName  string   `json:"author_name" binding:"max=50"`
+	ParentID    *uint    `json:"parent_id"`
+	HighlightID *uint    `json:"highlight_id"`
+	Seconds     *float64 `json:"seconds" binding:"omitempty,min=0"`
+}
+
+// ModerateCommentRequest represents a moderation change. Nil fields are left unchanged.
+type ModerateCommentRequest struct {
+	Hidden *bool `json:"hidden"`
+	Locked *bool `json:"locked"`
+}
+
+// CommentResponse is a comment as shown to readers.
+type CommentResponse struct {
+	ID          uint      `json:"id"`
+	ParentID    *uint     `json:"parent_id,omitempty"`
+	AuthorName  string    `json:"author_name"`
+	UserID      *uint     `json:"user_id,omitempty"`
+	Anonymous   bool      `json:"anonymous"`
+	Body        string    `json:"body"`
+	HighlightID *uint     `json:"highlight_id,omitempty"`
+	Seconds     *float64  `json:"seconds,omitempty"`
+	Hidden      bool      `json:"hidden,omitempty"` // Only moderators see hidden comments
+	Locked      bool      `json:"locked,omitempty"`
+	ViaShare    bool      `json:"via_share"` // Posted through a share link
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CommentThread is a root comment with its replies, oldest first.
+type CommentThread struct {
+	CommentResponse
+	Replies []CommentResponse `json:"replies"`
+}
+
+// CommentListResponse is the comments of an insight.
+type CommentListResponse struct {
+	Threads      []CommentThread `json:"threads"`
+	CanModerate  bool            `json:"can_moderate"`
+	CommentCount int             `json:"comment_count"`
+}
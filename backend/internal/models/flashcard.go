@@ -0,0 +1,108 @@
+package models
+
+import "time"
+
+// FlashcardSource is what a flashcard was generated from.
+type FlashcardSource string
+
+const (
+	FlashcardSourceHighlight FlashcardSource = "highlight"
+	FlashcardSourceKeyPoint  FlashcardSource = "key_point"
+)
+
+// Default SM-2 scheduling state of a new card.
+const (
+	FlashcardInitialEase = 2.5
+	FlashcardMinEase     = 1.3
+)
+
+// Flashcard is a question/answer card for spaced repetition review. Cards belong to the
+// user who generated them and are scheduled with SM-2.
+type Flashcard struct {
+	ID        uint `json:"id" gorm:"primaryKey"`
+	UserID    uint `json:"user_id" gorm:"not null;uniqueIndex:idx_flashcard_source,priority:1;index:idx_flashcard_due,priority:1"`
+	InsightID uint `json:"insight_id" gorm:"not null;index;uniqueIndex:idx_flashcard_source,priority:2"`
+
+	// Source: a highlight, or a key point identified by a hash of its text
+	Source      FlashcardSource `json:"source" gorm:"type:varchar(20);not null"`
+	SourceKey   string          `json:"-" gorm:"type:varchar(64);not null;uniqueIndex:idx_flashcard_source,priority:3"`
+	HighlightID *uint           `json:"highlight_id,omitempty" gorm:"index"`
+	Seconds     *int            `json:"seconds,omitempty"` // Transcript timestamp of the source, if known
+
+	Front string `json:"front" gorm:"type:text;not null"` // Question
+	Back  string `json:"back" gorm:"type:text;not null"`  // Answer
+
+	Suspended bool `json:"suspended" gorm:"not null;default:false"`
+
+	// SM-2 scheduling state
+	EaseFactor     float64    `json:"ease_factor" gorm:"not null;default:2.5"`
+	IntervalDays   int        `json:"interval_days" gorm:"not null;default:0"`
+	Repetitions    int        `json:"repetitions" gorm:"not null;default:0"` // Successful reviews in a row
+	Lapses         int        `json:"lapses" gorm:"not null;default:0"`
+	DueAt          time.Time  `json:"due_at" gorm:"not null;index:idx_flashcard_due,priority:2"`
+	LastReviewedAt *time.Time `json:"last_reviewed_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for Flashcard model.
+func (Flashcard) TableName() string {
+	return "flashcards"
+}
+
+// FlashcardReview records one review of a card.
+type FlashcardReview struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	FlashcardID  uint      `json:"flashcard_id" gorm:"index;not null"`
+	UserID       uint      `json:"user_id" gorm:"index;not null"`
+	Grade        int       `json:"grade" gorm:"not null"` // SM-2 quality, 0-5
+	IntervalDays int       `json:"interval_days"`         // Interval scheduled by this review
+	EaseFactor   float64   `json:"ease_factor"`
+	ReviewedAt   time.Time `json:"reviewed_at" gorm:"index;not null"`
+}
+
+// TableName returns the table name for FlashcardReview model.
+func (FlashcardReview) TableName() string {
+	return "flashcard_reviews"
+}
+
+// Request/Response DTOs
+
+// GradeFlashcardRequest grades a review. Grades follow SM-2: 0-2 mean the answer was
+// not recalled, 3 recalled with difficulty, 4 recalled, 5 recalled easily.
+type GradeFlashcardRequest struct {
+	Grade *int `json:"grade" binding:"required,min=0,max=5"`
+}
+
+// UpdateFlashcardRequest edits a card. Nil fields are left unchanged.
+type UpdateFlashcardRequest struct {
+	Front     *string `json:"front" binding:"omitempty,min=1,max=2000"`
+	Back      *string `json:"back" binding:"omitempty,min=1,max=5000"`
+	Suspended *bool   `json:"suspended"`
+}
+
+// FlashcardResponse is a card with a link back to its source.
+type FlashcardResponse struct {
+	Flashcard
+	InsightTitle string `json:"insight_title"`
+	Link         string `json:"link"` // Insight page, at the source's timestamp or highlight
+}
+
+// GenerateFlashcardsResponse is the result of generating cards for an insight.
+type GenerateFlashcardsResponse struct {
+	Created []FlashcardResponse `json:"created"`
+	Skipped int                 `json:"skipped"` // Sources that already had a card
+}
+
+// FlashcardListResponse is a page of cards.
+type FlashcardListResponse struct {
+	Items []FlashcardResponse `json:"items"`
+	PageInfo
+}
+
+// DueFlashcardsResponse is the cards due for review now.
+type DueFlashcardsResponse struct {
+	Cards    []FlashcardResponse `json:"cards"`
+	DueCount int64               `json:"due_count"` // All due cards, not just this batch
+}
@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// UserIdentity links an external login provider account to a User.
+// A user may have several identities (Google, GitHub, a company OIDC issuer, ...).
+type UserIdentity struct {
+	ID            uint   `json:"id" gorm:"primaryKey"`
+	UserID        uint   `json:"user_id" gorm:"index;not null"`
+	Provider      string `json:"provider" gorm:"type:varchar(50);not null;uniqueIndex:idx_identity_provider_subject"`
+	Subject       string `json:"subject" gorm:"type:varchar(255);not null;uniqueIndex:idx_identity_provider_subject"` // Stable account ID at the provider
+	Email         string `json:"email" gorm:"type:varchar(255);index"`
+	EmailVerified bool   `json:"email_verified" gorm:"default:false"`
+	Name          string `json:"name" gorm:"type:varchar(255)"`
+	AvatarURL     string `json:"avatar_url,omitempty" gorm:"type:varchar(1000)"`
+
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for UserIdentity model.
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// ExternalIdentity is the normalized user profile returned by a login provider.
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	AvatarURL     string
+}
+
+// Request/Response DTOs
+
+// LoginProviderResponse describes a configured login provider.
+type LoginProviderResponse struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"` // "oauth2" or "oidc"
+}
+
+// ProviderAuthURLResponse represents the authorization URL for a login provider.
+type ProviderAuthURLResponse struct {
+	URL   string `json:"url"`
+	State string `json:"state"`
+}
+
+// ProviderCallbackRequest represents the callback payload from a login provider.
+type ProviderCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// ProviderLoginResponse represents the response after a provider login or link.
+type ProviderLoginResponse struct {
+	User    UserResponse `json:"user"`
+	APIKey  string       `json:"api_key,omitempty"`
+	Linked  bool         `json:"linked"`  // true if an identity was linked to an existing account
+	Created bool         `json:"created"` // true if a new user account was created
+}
+
+// IdentityResponse represents a linked identity in API responses.
+type IdentityResponse struct {
+	ID          uint       `json:"id"`
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email"`
+	Name        string     `json:"name"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ToResponse converts a UserIdentity to IdentityResponse.
+func (i *UserIdentity) ToResponse() IdentityResponse {
+	return IdentityResponse{
+		ID:          i.ID,
+		Provider:    i.Provider,
+		Email:       i.Email,
+		Name:        i.Name,
+		LastLoginAt: i.LastLoginAt,
+		CreatedAt:   i.CreatedAt,
+	}
+}
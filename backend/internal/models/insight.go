@@ -28,8 +28,10 @@ const (
 
 // Insight represents a media content analysis record (video, tweet, podcast, etc.).
 type Insight struct {
-	ID     uint `json:"id" gorm:"primaryKey"`
-	UserID uint `json:"user_id" gorm:"index;not null"`
+	ID           uint  `json:"id" gorm:"primaryKey"`
+	UserID       uint  `json:"user_id" gorm:"index;not null"`        // Creator
+	WorkspaceID  *uint `json:"workspace_id,omitempty" gorm:"index"`  // Owning workspace; nil for legacy personal insights
+	CollectionID *uint `json:"collection_id,omitempty" gorm:"index"` // Containing collection; nil if unfiled
 
 	// Source information
 	SourceType SourceType `json:"source_type" gorm:"type:varchar(20);not null"`
@@ -59,7 +61,7 @@ type Insight struct {
 	Status       InsightStatus `json:"status" gorm:"type:varchar(20);default:'pending'"`
 	ErrorMessage string        `json:"error_message,omitempty" gorm:"type:text"`
 
-	// Sharing
+	// Legacy single share link, migrated to share_links on startup (see ShareLink)
 	ShareToken    *string    `json:"share_token,omitempty" gorm:"type:varchar(64);uniqueIndex"`
 	SharePassword string     `json:"-" gorm:"type:varchar(255)"`             // bcrypt hash, never exposed in JSON
 	ShareConfig   datatypes.JSON `json:"share_config,omitempty" gorm:"type:jsonb"` // What to include in share
@@ -67,7 +69,9 @@ type Insight struct {
 	SharedAt      *time.Time `json:"shared_at,omitempty"`
 
 	// Associations
+	Tags         []Tag         `json:"tags,omitempty" gorm:"many2many:insight_tags;"`
 	Highlights   []Highlight   `json:"highlights,omitempty" gorm:"foreignKey:InsightID"`
+	Chapters     []Chapter     `json:"chapters,omitempty" gorm:"foreignKey:InsightID"`
 	ChatMessages []ChatMessage `json:"chat_messages,omitempty" gorm:"foreignKey:InsightID"`
 
 	CreatedAt time.Time      `json:"created_at"`
@@ -86,6 +90,7 @@ type ShareConfigData struct {
 	IncludeKeyPoints  bool `json:"include_key_points"`
 	IncludeHighlights bool `json:"include_highlights"`
 	IncludeChat       bool `json:"include_chat"`
+	AllowComments     bool `json:"allow_comments"` // Readers may comment through the link
 }
 
 // TranscriptItem represents a single transcript segment with timestamp.
@@ -108,6 +113,8 @@ type Highlight struct {
 	Color       string `json:"color" gorm:"type:varchar(20);default:'yellow'"`    // Highlight color
 	Note        string `json:"note,omitempty" gorm:"type:text"`                   // User's note on the highlight
 
+	Author *UserSummary `json:"author,omitempty" gorm:"-"` // Filled in by the repository
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -129,6 +136,8 @@ type ChatMessage struct {
 	// Optional: link to a specific highlight
 	HighlightID *uint `json:"highlight_id,omitempty" gorm:"index"`
 
+	Author *UserSummary `json:"author,omitempty" gorm:"-"` // Filled in by the repository
+
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -141,8 +150,9 @@ func (ChatMessage) TableName() string {
 
 // CreateInsightRequest represents the request to create a new insight.
 type CreateInsightRequest struct {
-	SourceURL  string `json:"source_url" binding:"required,url"`
-	TargetLang string `json:"target_lang" binding:"omitempty,min=2,max=10"`
+	SourceURL   string `json:"source_url" binding:"required,url"`
+	TargetLang  string `json:"target_lang" binding:"omitempty,min=2,max=10"`
+	WorkspaceID *uint  `json:"workspace_id" binding:"omitempty"` // Defaults to the personal workspace
 }
 
 // CreateInsightResponse represents the response after creating an insight.
@@ -155,6 +165,9 @@ type CreateInsightResponse struct {
 // InsightListItem represents a single insight in list view.
 type InsightListItem struct {
 	ID           uint       `json:"id"`
+	WorkspaceID  *uint      `json:"workspace_id,omitempty"`
+	CollectionID *uint      `json:"collection_id,omitempty"`
+	Tags         []Tag      `json:"tags"`
 	SourceType   SourceType `json:"source_type"`
 	Title        string     `json:"title"`
 	Author       string     `json:"author"`
@@ -164,32 +177,41 @@ type InsightListItem struct {
 }
 
 // InsightListResponse represents the grouped insight list response.
+// Items is the page in sort order; the date buckets split the same page by creation
+// day and are only filled when sorting by creation time.
 type InsightListResponse struct {
+	Items     []InsightListItem `json:"items"`
 	Today     []InsightListItem `json:"today"`
 	Yesterday []InsightListItem `json:"yesterday"`
 	Previous  []InsightListItem `json:"previous"`
-	Total     int               `json:"total"`
+	Total     int64             `json:"total"`
+	PageInfo
 }
 
 // InsightDetailResponse represents the full insight detail response.
 type InsightDetailResponse struct {
-	ID           uint             `json:"id"`
-	SourceType   SourceType       `json:"source_type"`
-	SourceURL    string           `json:"source_url"`
-	SourceID     string           `json:"source_id"`
-	Title        string           `json:"title"`
-	Author       string           `json:"author"`
-	ThumbnailURL string           `json:"thumbnail_url"`
-	Duration     int              `json:"duration"`
-	PublishedAt  *time.Time       `json:"published_at,omitempty"`
-	Summary      string           `json:"summary"`
-	KeyPoints    []string         `json:"key_points"`
-	RawContent   string           `json:"raw_content,omitempty"`
-	TransContent string           `json:"trans_content,omitempty"`
-	Transcripts  []TranscriptItem `json:"transcripts,omitempty"`
-	Status       InsightStatus    `json:"status"`
-	Highlights   []Highlight      `json:"highlights,omitempty"`
-	CreatedAt    time.Time        `json:"created_at"`
+	ID           uint              `json:"id"`
+	WorkspaceID  *uint             `json:"workspace_id,omitempty"`
+	CollectionID *uint             `json:"collection_id,omitempty"`
+	Tags         []Tag             `json:"tags"`
+	Role         WorkspaceRole     `json:"role"` // Caller's role in the owning workspace
+	SourceType   SourceType        `json:"source_type"`
+	SourceURL    string            `json:"source_url"`
+	SourceID     string            `json:"source_id"`
+	Title        string            `json:"title"`
+	Author       string            `json:"author"`
+	ThumbnailURL string            `json:"thumbnail_url"`
+	Duration     int               `json:"duration"`
+	PublishedAt  *time.Time        `json:"published_at,omitempty"`
+	Summary      string            `json:"summary"`
+	KeyPoints    []string          `json:"key_points"`
+	RawContent   string            `json:"raw_content,omitempty"`
+	TransContent string            `json:"trans_content,omitempty"`
+	Transcripts  []TranscriptItem  `json:"transcripts,omitempty"`
+	Chapters     []ChapterResponse `json:"chapters"`
+	Status       InsightStatus     `json:"status"`
+	Highlights   []Highlight       `json:"highlights,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
 }
 
 // CreateHighlightRequest represents the request to create a highlight.
@@ -233,14 +255,16 @@ type ShareInsightResponse struct {
 
 // SharedInsightResponse represents the public view of a shared insight.
 type SharedInsightResponse struct {
-	Title        string           `json:"title"`
-	Author       string           `json:"author"`
-	ThumbnailURL string           `json:"thumbnail_url"`
-	SharedBy     string           `json:"shared_by"`
-	SharedAt     *time.Time       `json:"shared_at"`
-	SourceType   SourceType       `json:"source_type"`
-	SourceURL    string           `json:"source_url"`
-	Content      SharedContent    `json:"content"`
+	Title         string        `json:"title"`
+	Author        string        `json:"author"`
+	ThumbnailURL  string        `json:"thumbnail_url"`
+	SharedBy      string        `json:"shared_by"`
+	SharedAt      *time.Time    `json:"shared_at"`
+	ExpiresAt     *time.Time    `json:"expires_at,omitempty"`
+	AllowComments bool          `json:"allow_comments"`
+	SourceType    SourceType    `json:"source_type"`
+	SourceURL     string        `json:"source_url"`
+	Content       SharedContent `json:"content"`
 }
 
 // SharedContent represents the content included in a shared insight.
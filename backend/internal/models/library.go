@@ -0,0 +1,138 @@
+package models
+
+import "time"
+
+// Tag is a user-defined label for insights, scoped to a workspace.
+type Tag struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint   `json:"workspace_id" gorm:"not null;uniqueIndex:idx_tags_workspace_name"`
+	Name        string `json:"name" gorm:"type:varchar(50);not null;uniqueIndex:idx_tags_workspace_name"`
+	Color       string `json:"color,omitempty" gorm:"type:varchar(20)"`
+	CreatedBy   uint   `json:"created_by" gorm:"not null"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for Tag model.
+func (Tag) TableName() string {
+	return "tags"
+}
+
+// InsightTag is the join table between insights and tags.
+type InsightTag struct {
+	InsightID uint      `gorm:"primaryKey"`
+	TagID     uint      `gorm:"primaryKey;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName returns the table name for InsightTag model.
+func (InsightTag) TableName() string {
+	return "insight_tags"
+}
+
+// Collection is a folder of insights. Collections nest via ParentID and are
+// ordered among their siblings by Position.
+type Collection struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint   `json:"workspace_id" gorm:"index;not null"`
+	ParentID    *uint  `json:"parent_id,omitempty" gorm:"index"`
+	Name        string `json:"name" gorm:"type:varchar(255);not null"`
+	Position    int    `json:"position" gorm:"not null;default:0"`
+	CreatedBy   uint   `json:"created_by" gorm:"not null"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for Collection model.
+func (Collection) TableName() string {
+	return "collections"
+}
+
+// InsightFilter narrows an insight list.
+type InsightFilter struct {
+	Search       string
+	TagIDs       []uint // Insights must carry all of these tags
+	CollectionID *uint  // Exact collection
+	Unfiled      bool   // Only insights without a collection
+	SourceType   SourceType
+	Status       InsightStatus
+	Created      DateRange
+}
+
+// Request/Response DTOs
+
+// CreateTagRequest represents the request to create a tag.
+type CreateTagRequest struct {
+	WorkspaceID *uint  `json:"workspace_id"`
+	Name        string `json:"name" binding:"required,min=1,max=50"`
+	Color       string `json:"color" binding:"omitempty,max=20"`
+}
+
+// UpdateTagRequest represents the request to update a tag.
+type UpdateTagRequest struct {
+	Name  *string `json:"name" binding:"omitempty,min=1,max=50"`
+	Color *string `json:"color" binding:"omitempty,max=20"`
+}
+
+// TagWithCount is a tag with the number of insights carrying it.
+type TagWithCount struct {
+	Tag
+	Count int64 `json:"count"`
+}
+
+// CreateCollectionRequest represents the request to create a collection.
+type CreateCollectionRequest struct {
+	WorkspaceID *uint  `json:"workspace_id"`
+	ParentID    *uint  `json:"parent_id"`
+	Name        string `json:"name" binding:"required,min=1,max=255"`
+	Position    *int   `json:"position"`
+}
+
+// UpdateCollectionRequest represents the request to rename, move or reorder a collection.
+// Set MoveToRoot to move a collection to the top level.
+type UpdateCollectionRequest struct {
+	Name       *string `json:"name" binding:"omitempty,min=1,max=255"`
+	ParentID   *uint   `json:"parent_id"`
+	MoveToRoot bool    `json:"move_to_root"`
+	Position   *int    `json:"position"`
+}
+
+// CollectionNode is a collection in the sidebar tree.
+type CollectionNode struct {
+	ID       uint             `json:"id"`
+	ParentID *uint            `json:"parent_id,omitempty"`
+	Name     string           `json:"name"`
+	Position int              `json:"position"`
+	Count    int64            `json:"count"` // Insights directly in this collection
+	Children []CollectionNode `json:"children"`
+}
+
+// LibrarySidebarResponse is the sidebar summary of a workspace library.
+type LibrarySidebarResponse struct {
+	WorkspaceID uint                 `json:"workspace_id"`
+	Total       int64                `json:"total"`
+	Unfiled     int64                `json:"unfiled"`
+	Tags        []TagWithCount       `json:"tags"`
+	Collections []CollectionNode     `json:"collections"`
+	SourceTypes map[SourceType]int64 `json:"source_types"`
+}
+
+// BulkTagRequest adds and/or removes tags on many insights.
+type BulkTagRequest struct {
+	InsightIDs   []uint `json:"insight_ids" binding:"required,min=1,max=500"`
+	AddTagIDs    []uint `json:"add_tag_ids"`
+	RemoveTagIDs []uint `json:"remove_tag_ids"`
+}
+
+// BulkMoveRequest moves many insights into a collection (nil collection_id unfiles them).
+type BulkMoveRequest struct {
+	InsightIDs   []uint `json:"insight_ids" binding:"required,min=1,max=500"`
+	CollectionID *uint  `json:"collection_id"`
+}
+
+// BulkResult reports how many insights a bulk operation touched.
+type BulkResult struct {
+	Updated int `json:"updated"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Sort keys accepted by cursor-paginated lists. Not every list supports every key.
+const (
+	SortCreated   = "created"
+	SortPublished = "published"
+	SortDuration  = "duration"
+	SortTitle     = "title"
+	SortDue       = "due"
+	SortDeleted   = "deleted"
+)
+
+// PageRequest asks for one page of a keyset-paginated list.
+// Cursor is the opaque next_cursor of the previous page; it pins Sort and Desc.
+type PageRequest struct {
+	Cursor string
+	Limit  int
+	Sort   string
+	Desc   bool
+}
+
+// PageInfo describes where a page ends.
+type PageInfo struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	Limit      int    `json:"limit"`
+}
+
+// DateRange restricts a list to items created in [From, To). Either end may be nil.
+type DateRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// HistoryFilter narrows the video analysis history.
+type HistoryFilter struct {
+	Status  string // Defaults to "completed"
+	Created DateRange
+}
@@ -1,20 +1,80 @@
 package models
 
 import (
+	"strconv"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// RoomMediaMode is how media flows between the participants of a room.
+type RoomMediaMode string
+
+const (
+	RoomMediaMesh RoomMediaMode = "mesh" // Peer-to-peer connections between every pair of participants
+	RoomMediaSFU  RoomMediaMode = "sfu"  // Each participant connects to the server, which forwards tracks
+)
+
+// RoomStatus is where a room is in its lifecycle. The server moves rooms between
+// statuses as participants join, leave and time out.
+type RoomStatus string
+
+const (
+	RoomIdle         RoomStatus = "IDLE"         // Created, nobody has joined yet
+	RoomConnecting   RoomStatus = "CONNECTING"   // One participant, waiting for others
+	RoomConnected    RoomStatus = "CONNECTED"    // Two or more participants
+	RoomDisconnected RoomStatus = "DISCONNECTED" // Everyone left; joining starts over
+	RoomClosed       RoomStatus = "CLOSED"       // Ended by a moderator; only the owner can open it again
+)
+
+// roomTransitions lists the statuses each status may move to.
+var roomTransitions = map[RoomStatus][]RoomStatus{
+	RoomIdle:         {RoomConnecting, RoomClosed},
+	RoomConnecting:   {RoomConnected, RoomDisconnected, RoomClosed},
+	RoomConnected:    {RoomConnecting, RoomDisconnected, RoomClosed},
+	RoomDisconnected: {RoomConnecting, RoomClosed},
+	RoomClosed:       {RoomConnecting},
+}
+
+// CanTransitionTo reports whether a room in status s may move to next.
+func (s RoomStatus) CanTransitionTo(next RoomStatus) bool {
+	for _, allowed := range roomTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the status of a room in status s once online participants are in it.
+func (s RoomStatus) Next(online int64) RoomStatus {
+	switch {
+	case online >= 2:
+		return RoomConnected
+	case online == 1:
+		return RoomConnecting
+	case s == RoomIdle, s == RoomClosed:
+		return s
+	default:
+		return RoomDisconnected
+	}
+}
+
 // Room represents a video conference room.
 type Room struct {
-	ID           uint           `json:"id" gorm:"primaryKey"`
-	RoomID       string         `json:"room_id" gorm:"uniqueIndex;type:varchar(255);not null"`
-	Status       string         `json:"status" gorm:"type:varchar(50);default:'IDLE'"` // IDLE, CONNECTING, CONNECTED, ERROR, DISCONNECTED
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
-	Participants []Participant  `json:"participants" gorm:"foreignKey:RoomID;references:RoomID"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	RoomID          string         `json:"room_id" gorm:"uniqueIndex;type:varchar(255);not null"`
+	Status          RoomStatus     `json:"status" gorm:"type:varchar(50);default:'IDLE'"`
+	MediaMode       RoomMediaMode  `json:"media_mode" gorm:"type:varchar(10);not null;default:'mesh'"` // Fixed when the room is created
+	MaxParticipants int            `json:"max_participants" gorm:"not null;default:0"`                 // 0 for the server default
+	OwnerID         *uint          `json:"owner_id,omitempty"`                                         // The creator; rooms created before ownership go to their next joiner
+	Locked          bool           `json:"locked" gorm:"not null;default:false"`                       // Nobody new may join
+	LobbyEnabled    bool           `json:"lobby_enabled" gorm:"not null;default:false"`                // Newcomers wait until a moderator admits them
+	PasswordHash    string         `json:"-" gorm:"type:varchar(255);not null;default:''"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+	Participants    []Participant  `json:"participants" gorm:"foreignKey:RoomID;references:RoomID"`
 }
 
 // TableName returns the table name for Room model.
@@ -22,17 +82,73 @@ func (Room) TableName() string {
 	return "rooms"
 }
 
-// Participant represents a user in a video room.
+// IsOwner reports whether a user owns the room.
+func (r *Room) IsOwner(userID uint) bool {
+	return r.OwnerID != nil && *r.OwnerID == userID
+}
+
+// Capacity returns how many participants may be online in the room at once.
+func (r *Room) Capacity(defaultMax int) int {
+	if r.MaxParticipants > 0 {
+		return r.MaxParticipants
+	}
+	return defaultMax
+}
+
+// ParticipantStatus is whether a participant is currently in a room.
+type ParticipantStatus string
+
+const (
+	ParticipantOnline  ParticipantStatus = "ONLINE"
+	ParticipantWaiting ParticipantStatus = "WAITING" // In the lobby until a moderator admits them
+	ParticipantOffline ParticipantStatus = "OFFLINE" // Left, or stopped sending heartbeats
+	ParticipantRemoved ParticipantStatus = "REMOVED" // Kicked or turned away; kept out until a moderator readmits them
+)
+
+// RoomRole is a participant's role in a room.
+type RoomRole string
+
+const (
+	RoomRoleOwner     RoomRole = "owner"
+	RoomRoleModerator RoomRole = "moderator" // Appointed by the owner
+	RoomRoleMember    RoomRole = "member"
+)
+
+// Rank orders roles by privilege; unknown roles rank lowest.
+func (r RoomRole) Rank() int {
+	switch r {
+	case RoomRoleOwner:
+		return 3
+	case RoomRoleModerator:
+		return 2
+	case RoomRoleMember:
+		return 1
+	}
+	return 0
+}
+
+// Allows reports whether the role grants at least the privileges of need.
+func (r RoomRole) Allows(need RoomRole) bool {
+	return r.Rank() >= need.Rank()
+}
+
+// Participant represents a user in a video room. There is one row per user and room,
+// reused when they join again.
 type Participant struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	RoomID    string         `json:"room_id" gorm:"index;type:varchar(255);not null"`
-	UserID    string         `json:"user_id" gorm:"type:varchar(255);not null"` // Client-generated unique user ID
-	Status    string         `json:"status" gorm:"type:varchar(50);default:'ONLINE'"` // ONLINE, OFFLINE
-	JoinedAt  time.Time      `json:"joined_at"`
-	LeftAt    *time.Time     `json:"left_at,omitempty"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID         uint              `json:"id" gorm:"primaryKey"`
+	RoomID     string            `json:"room_id" gorm:"index;type:varchar(255);not null"`
+	UserID     uint              `json:"user_id" gorm:"not null"`
+	Status     ParticipantStatus `json:"status" gorm:"type:varchar(50);default:'ONLINE'"`
+	Role       RoomRole          `json:"role" gorm:"type:varchar(20);not null;default:'member'"`
+	Muted      bool              `json:"muted" gorm:"not null;default:false"` // By a moderator, until a moderator unmutes them
+	JoinedAt   time.Time         `json:"joined_at"`
+	LastSeenAt time.Time         `json:"last_seen_at"` // Last heartbeat or signaling pong
+	LeftAt     *time.Time        `json:"left_at,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt    `json:"-" gorm:"index"`
+
+	User *User `json:"-" gorm:"foreignKey:UserID"`
 }
 
 // TableName returns the table name for Participant model.
@@ -40,89 +156,140 @@ func (Participant) TableName() string {
 	return "participants"
 }
 
-// WebRTCSignal stores WebRTC signaling data (SDP offers/answers and ICE candidates).
-// Note: In production, this should be handled via WebSocket for real-time communication.
-// This implementation uses database storage as a stateless alternative.
-type WebRTCSignal struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	RoomID      string         `json:"room_id" gorm:"index;type:varchar(255);not null"`
-	UserID      string         `json:"user_id" gorm:"type:varchar(255);not null"`
-	SignalType  string         `json:"signal_type" gorm:"type:varchar(50);not null"` // OFFER, ANSWER, ICE_CANDIDATE
-	SDPOffer    string         `json:"sdp_offer,omitempty" gorm:"type:text"`
-	SDPAnswer   string         `json:"sdp_answer,omitempty" gorm:"type:text"`
-	ICECandidate string        `json:"ice_candidate,omitempty" gorm:"type:text"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+// RoomInvite lets its holder into a room without the password or the lobby, until it
+// expires, is used up or is revoked.
+type RoomInvite struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	RoomID    string     `json:"room_id" gorm:"index;type:varchar(255);not null"`
+	TokenHash string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"` // SHA-256 of the invite token
+	CreatedBy uint       `json:"created_by" gorm:"not null"`
+	MaxUses   int        `json:"max_uses" gorm:"not null;default:0"` // 0 for unlimited
+	Uses      int        `json:"uses" gorm:"not null;default:0"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
 }
 
-// TableName returns the table name for WebRTCSignal model.
-func (WebRTCSignal) TableName() string {
-	return "webrtc_signals"
+// TableName returns the table name for RoomInvite model.
+func (RoomInvite) TableName() string {
+	return "room_invites"
+}
+
+// CreateRoomRequest represents the request body for creating a room.
+type CreateRoomRequest struct {
+	MaxParticipants int `json:"maxParticipants" binding:"omitempty,min=2"` // 0 for the server default
+}
+
+// CreateRoomResponse represents the response for creating a room.
+type CreateRoomResponse struct {
+	RoomStatusResponse
+	RoomID string `json:"room_id"` // Generated by the server; join it with /api/room/join
 }
 
 // JoinRoomRequest represents the request body for joining a room.
 type JoinRoomRequest struct {
-	RoomID string `json:"roomId" binding:"required"`
+	RoomID   string `json:"roomId" binding:"required,max=255"`
+	Password string `json:"password"`
+	Invite   string `json:"invite"` // Skips the password and the lobby
 }
 
 // JoinRoomResponse represents the response for joining a room.
 type JoinRoomResponse struct {
-	Status       string                `json:"status"`
-	Participants []ParticipantResponse `json:"participants"`
+	RoomStatusResponse
+	UserID            string            `json:"user_id"` // The caller's ID in signaling messages
+	Role              RoomRole          `json:"role"`
+	ParticipantStatus ParticipantStatus `json:"participant_status"`     // WAITING in the lobby: heartbeat, and join again once admitted
+	SignalToken       string            `json:"signal_token,omitempty"` // Short-lived, for /api/room/signal; not while waiting
+	HeartbeatInterval int               `json:"heartbeat_interval"`     // Seconds between heartbeats when not connected to signaling
 }
 
 // LeaveRoomRequest represents the request body for leaving a room.
 type LeaveRoomRequest struct {
 	RoomID string `json:"roomId" binding:"required"`
-	UserID string `json:"userId" binding:"required"`
 }
 
-// LeaveRoomResponse represents the response for leaving a room.
-type LeaveRoomResponse struct {
-	Status string `json:"status"`
+// RoomHeartbeatRequest represents the request body for a presence heartbeat.
+type RoomHeartbeatRequest struct {
+	RoomID string `json:"roomId" binding:"required"`
 }
 
-// RoomStatusResponse represents the response for room status.
-type RoomStatusResponse struct {
-	Status       string                `json:"status"`
-	Participants []ParticipantResponse `json:"participants"`
+// RoomHeartbeatResponse represents the response for a presence heartbeat.
+type RoomHeartbeatResponse struct {
+	Status            string            `json:"status"`
+	ParticipantStatus ParticipantStatus `json:"participant_status"` // ONLINE once admitted from the lobby
+	ExpiresAt         time.Time         `json:"expires_at"`         // When the participant goes offline without another heartbeat
 }
 
-// ParticipantResponse represents a participant in the response.
-type ParticipantResponse struct {
-	ID     string `json:"id"`
-	Status string `json:"status"`
+// RoomSettingsRequest represents the request body for changing a room's settings.
+// Omitted settings are left unchanged; an empty password removes it.
+type RoomSettingsRequest struct {
+	RoomID          string  `json:"roomId" binding:"required"`
+	Locked          *bool   `json:"locked"`
+	LobbyEnabled    *bool   `json:"lobbyEnabled"`
+	Password        *string `json:"password" binding:"omitempty,max=72"`
+	MaxParticipants *int    `json:"maxParticipants" binding:"omitempty,min=2"`
 }
 
-// MediaOfferRequest represents the request body for WebRTC offer.
-type MediaOfferRequest struct {
-	RoomID string `json:"roomId" binding:"required"`
-	UserID string `json:"userId" binding:"required"`
-	SDP    string `json:"sdp" binding:"required"`
+// CreateRoomInviteRequest represents the request body for creating a room invite.
+type CreateRoomInviteRequest struct {
+	RoomID    string `json:"roomId" binding:"required"`
+	ExpiresIn int    `json:"expiresIn" binding:"omitempty,min=60"` // Seconds; defaults to a day
+	MaxUses   int    `json:"maxUses" binding:"omitempty,min=1"`    // Unlimited if omitted
 }
 
-// MediaOfferResponse represents the response for WebRTC offer.
-type MediaOfferResponse struct {
-	SDP string `json:"sdp"`
+// RoomInviteResponse represents a room invite in the response. The token is only
+// returned when the invite is created.
+type RoomInviteResponse struct {
+	RoomInvite
+	Token string `json:"token,omitempty"`
 }
 
-// ICECandidateRequest represents the request body for ICE candidate.
-type ICECandidateRequest struct {
-	RoomID    string `json:"roomId" binding:"required"`
-	UserID    string `json:"userId" binding:"required"`
-	Candidate string `json:"candidate" binding:"required"`
+// RoomModerationRequest represents the request body for a moderation action.
+type RoomModerationRequest struct {
+	RoomID string `json:"roomId" binding:"required"`
+	UserID string `json:"userId"` // The participant acted on, as in signaling messages; not for "end"
 }
 
-// ICECandidateResponse represents the response for ICE candidate.
-type ICECandidateResponse struct {
+// LeaveRoomResponse represents the response for leaving a room.
+type LeaveRoomResponse struct {
 	Status string `json:"status"`
 }
 
+// RoomStatusResponse represents the response for room status.
+type RoomStatusResponse struct {
+	Status            RoomStatus            `json:"status"`
+	MediaMode         RoomMediaMode         `json:"media_mode"` // "sfu": exchange offers with the server, not peers
+	MaxParticipants   int                   `json:"max_participants"`
+	Locked            bool                  `json:"locked"`
+	LobbyEnabled      bool                  `json:"lobby_enabled"`
+	PasswordProtected bool                  `json:"password_protected"`
+	Participants      []ParticipantResponse `json:"participants,omitempty"` // Online participants; only for those in the room
+}
+
+// ParticipantResponse represents a participant in the response.
+type ParticipantResponse struct {
+	ID     string            `json:"id"` // As in signaling messages
+	Name   string            `json:"name"`
+	Status ParticipantStatus `json:"status"`
+	Role   RoomRole          `json:"role"`
+	Muted  bool              `json:"muted"`
+}
+
 // ToResponse converts a Participant to ParticipantResponse.
 func (p *Participant) ToResponse() ParticipantResponse {
-	return ParticipantResponse{
-		ID:     p.UserID,
+	response := ParticipantResponse{
+		ID:     SignalPeerID(p.UserID),
 		Status: p.Status,
+		Role:   p.Role,
+		Muted:  p.Muted,
 	}
+	if p.User != nil {
+		response.Name = p.User.Name
+	}
+	return response
+}
+
+// SignalPeerID returns the ID of a user in a room's signaling messages.
+func SignalPeerID(userID uint) string {
+	return strconv.FormatUint(uint64(userID), 10)
 }
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// SearchKind identifies which part of an insight a search entry was built from.
+type SearchKind string
+
+const (
+	SearchKindTitle       SearchKind = "title"
+	SearchKindSummary     SearchKind = "summary"
+	SearchKindContent     SearchKind = "content"     // RawContent, when there are no transcripts
+	SearchKindTranslation SearchKind = "translation" // TransContent, when there are no transcripts
+	SearchKindTranscript  SearchKind = "transcript"  // One transcript segment (original and translated text)
+	SearchKindHighlight   SearchKind = "highlight"
+	SearchKindChat        SearchKind = "chat"
+)
+
+// SearchEntry is one indexed chunk of insight text. Document holds the tsvector of the
+// pre-tokenized text (CJK runs are split into bigrams before Postgres sees them).
+type SearchEntry struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	InsightID uint       `json:"insight_id" gorm:"index;not null"`
+	Kind      SearchKind `json:"kind" gorm:"type:varchar(20);not null;index"`
+	RefID     *uint      `json:"ref_id,omitempty" gorm:"index"` // Highlight or chat message ID
+	Seconds   *int       `json:"seconds,omitempty"`             // Transcript position, if known
+	Timestamp string     `json:"timestamp,omitempty" gorm:"type:varchar(20)"`
+	Text      string     `json:"text" gorm:"type:text;not null"`
+	Document  string     `json:"-" gorm:"type:tsvector;index:idx_search_entries_document,type:gin"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName returns the table name for SearchEntry model.
+func (SearchEntry) TableName() string {
+	return "search_entries"
+}
+
+// Response DTOs
+
+// SearchMatch is one matching passage of an insight.
+type SearchMatch struct {
+	Kind      SearchKind `json:"kind"`
+	RefID     *uint      `json:"ref_id,omitempty"`
+	Snippet   string     `json:"snippet"` // HTML-escaped text; matches wrapped in <mark></mark>
+	Seconds   *int       `json:"seconds,omitempty"`
+	Timestamp string     `json:"timestamp,omitempty"`
+	Link      string     `json:"link"` // App path to the match, with ?t= for transcript positions
+	Rank      float64    `json:"rank"`
+}
+
+// SearchResult is an insight matching a search, with its best passages.
+type SearchResult struct {
+	Insight InsightListItem `json:"insight"`
+	Rank    float64         `json:"rank"`
+	Matches []SearchMatch   `json:"matches"`
+}
+
+// SearchResponse is the paginated response of a library search.
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+	Limit   int            `json:"limit"`
+	Offset  int            `json:"offset"`
+	Total   int64          `json:"total"`
+}
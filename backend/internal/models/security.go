@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// SecurityEventType identifies the kind of security-relevant account activity.
+type SecurityEventType string
+
+const (
+	SecurityEventLoginFailed       SecurityEventType = "login_failed"
+	SecurityEventAccountLocked     SecurityEventType = "account_locked"
+	SecurityEventAPIKeyRegenerated SecurityEventType = "api_key_regenerated"
+	SecurityEventDataExported      SecurityEventType = "data_export_requested"
+	SecurityEventDeletionScheduled SecurityEventType = "account_deletion_scheduled"
+	SecurityEventDeletionCancelled SecurityEventType = "account_deletion_cancelled"
+)
+
+// SecurityEvent is an entry in a user's security log.
+type SecurityEvent struct {
+	ID        uint              `json:"id" gorm:"primaryKey"`
+	UserID    uint              `json:"-" gorm:"index:idx_security_events_user_created;not null"`
+	Type      SecurityEventType `json:"type" gorm:"type:varchar(50);not null"`
+	IPAddress string            `json:"ip_address" gorm:"type:varchar(64)"`
+	UserAgent string            `json:"user_agent" gorm:"type:varchar(500)"`
+	Details   string            `json:"details,omitempty" gorm:"type:text"`
+	CreatedAt time.Time         `json:"created_at" gorm:"index:idx_security_events_user_created"`
+}
+
+// TableName returns the table name for SecurityEvent model.
+func (SecurityEvent) TableName() string {
+	return "security_events"
+}
+
+// SecurityEventListResponse is a page of a user's security log.
+type SecurityEventListResponse struct {
+	Items []SecurityEvent `json:"items"`
+	PageInfo
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// ShareLinkStatus is the derived state of a share link.
+type ShareLinkStatus string
+
+const (
+	ShareLinkActive    ShareLinkStatus = "active"
+	ShareLinkExpired   ShareLinkStatus = "expired"
+	ShareLinkExhausted ShareLinkStatus = "exhausted" // Max views reached
+	ShareLinkRevoked   ShareLinkStatus = "revoked"
+)
+
+// ShareLink is a public link to an insight. An insight can have many links, each with
+// its own content selection, password, expiry and view limit.
+type ShareLink struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	InsightID    uint           `json:"insight_id" gorm:"index;not null"`
+	CreatedBy    uint           `json:"created_by" gorm:"not null"`
+	Token        string         `json:"token" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Label        string         `json:"label" gorm:"type:varchar(100)"`
+	Config       datatypes.JSON `json:"-" gorm:"type:jsonb"` // ShareConfigData
+	PasswordHash string         `json:"-" gorm:"type:varchar(255)"`
+	ExpiresAt    *time.Time     `json:"expires_at,omitempty"`
+	MaxViews     *int           `json:"max_views,omitempty"` // nil means unlimited
+	ViewCount    int            `json:"view_count" gorm:"not null;default:0"`
+	LastViewedAt *time.Time     `json:"last_viewed_at,omitempty"`
+	RevokedAt    *time.Time     `json:"revoked_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for ShareLink model.
+func (ShareLink) TableName() string {
+	return "share_links"
+}
+
+// Status returns the link's state at the given time.
+func (l *ShareLink) Status(now time.Time) ShareLinkStatus {
+	switch {
+	case l.RevokedAt != nil:
+		return ShareLinkRevoked
+	case l.ExpiresAt != nil && !now.Before(*l.ExpiresAt):
+		return ShareLinkExpired
+	case l.MaxViews != nil && l.ViewCount >= *l.MaxViews:
+		return ShareLinkExhausted
+	default:
+		return ShareLinkActive
+	}
+}
+
+// ShareLinkView records one view of a share link, for analytics.
+type ShareLinkView struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ShareLinkID uint      `json:"share_link_id" gorm:"index;not null"`
+	RefererHost string    `json:"referer_host" gorm:"type:varchar(255);index"` // Empty for direct visits
+	Referer     string    `json:"referer,omitempty" gorm:"type:varchar(2000)"`
+	VisitorHash string    `json:"-" gorm:"type:varchar(64)"` // Salted hash of IP and user agent; no raw IPs are stored
+	ViewedAt    time.Time `json:"viewed_at" gorm:"index;not null"`
+}
+
+// TableName returns the table name for ShareLinkView model.
+func (ShareLinkView) TableName() string {
+	return "share_link_views"
+}
+
+// Request/Response DTOs
+
+// CreateShareLinkRequest represents the request to create a share link.
+type CreateShareLinkRequest struct {
+	Label             string     `json:"label" binding:"omitempty,max=100"`
+	IncludeSummary    bool       `json:"include_summary"`
+	IncludeKeyPoints  bool       `json:"include_key_points"`
+	IncludeHighlights bool       `json:"include_highlights"`
+	IncludeChat       bool       `json:"include_chat"`
+	AllowComments     bool       `json:"allow_comments"`
+	Password          string     `json:"password,omitempty" binding:"omitempty,max=72"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	MaxViews          *int       `json:"max_views,omitempty" binding:"omitempty,min=1"`
+}
+
+// UpdateShareLinkRequest represents the request to change a share link. Nil fields are
+// left unchanged; set ClearPassword, ClearExpiry or ClearMaxViews to remove a limit.
+type UpdateShareLinkRequest struct {
+	Label             *string    `json:"label" binding:"omitempty,max=100"`
+	IncludeSummary    *bool      `json:"include_summary"`
+	IncludeKeyPoints  *bool      `json:"include_key_points"`
+	IncludeHighlights *bool      `json:"include_highlights"`
+	IncludeChat       *bool      `json:"include_chat"`
+	AllowComments     *bool      `json:"allow_comments"`
+	Password          *string    `json:"password" binding:"omitempty,min=1,max=72"`
+	ClearPassword     bool       `json:"clear_password"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	ClearExpiry       bool       `json:"clear_expiry"`
+	MaxViews          *int       `json:"max_views" binding:"omitempty,min=1"`
+	ClearMaxViews     bool       `json:"clear_max_views"`
+}
+
+// ShareLinkResponse is a share link as shown to workspace members.
+type ShareLinkResponse struct {
+	ID           uint            `json:"id"`
+	InsightID    uint            `json:"insight_id"`
+	Token        string          `json:"token"`
+	ShareURL     string          `json:"share_url"`
+	PageURL      string          `json:"page_url"` // Server-rendered page with link previews
+	Label        string          `json:"label"`
+	Config       ShareConfigData `json:"config"`
+	HasPassword  bool            `json:"has_password"`
+	ExpiresAt    *time.Time      `json:"expires_at,omitempty"`
+	MaxViews     *int            `json:"max_views,omitempty"`
+	ViewCount    int             `json:"view_count"`
+	LastViewedAt *time.Time      `json:"last_viewed_at,omitempty"`
+	RevokedAt    *time.Time      `json:"revoked_at,omitempty"`
+	Status       ShareLinkStatus `json:"status"`
+	CreatedBy    uint            `json:"created_by"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// UnlockShareRequest carries the password of a protected share link.
+type UnlockShareRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// UnlockShareResponse is returned after unlocking a share link. The access token is
+// also set as a cookie; clients without cookies can send it as X-Share-Access.
+type UnlockShareResponse struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// ReferrerCount is the number of views from one referring host.
+type ReferrerCount struct {
+	Host  string `json:"host"` // Empty for direct visits
+	Count int64  `json:"count"`
+}
+
+// DailyViewCount is the number of views on one day (UTC).
+type DailyViewCount struct {
+	Date  string `json:"date"` // YYYY-MM-DD
+	Count int64  `json:"count"`
+}
+
+// ShareLinkStats is the view analytics of a share link.
+type ShareLinkStats struct {
+	ShareLinkID    uint             `json:"share_link_id"`
+	Views          int64            `json:"views"`
+	UniqueVisitors int64            `json:"unique_visitors"`
+	LastViewedAt   *time.Time       `json:"last_viewed_at,omitempty"`
+	Referrers      []ReferrerCount  `json:"referrers"`
+	Daily          []DailyViewCount `json:"daily"` // Last 30 days
+}
+
+// OEmbedResponse is an oEmbed "rich" response for a share link (https://oembed.com).
+type OEmbedResponse struct {
+	Version      string `json:"version"`
+	Type         string `json:"type"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name,omitempty"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	CacheAge     int    `json:"cache_age,omitempty"` // Seconds
+}
@@ -0,0 +1,84 @@
+package models
+
+import "encoding/json"
+
+// SignalType is the type of a message on a room's signaling WebSocket.
+type SignalType string
+
+const (
+	// Sent by clients and relayed to the participant named in To
+	SignalOffer     SignalType = "offer"
+	SignalAnswer    SignalType = "answer"
+	SignalCandidate SignalType = "candidate"
+
+	// Sent by the server
+	SignalPeers      SignalType = "peers"       // Participants already connected, sent on connect
+	SignalPeerJoined SignalType = "peer-joined" // From connected
+	SignalPeerLeft   SignalType = "peer-left"   // From disconnected
+	SignalError      SignalType = "error"       // A message could not be relayed
+	SignalModeration SignalType = "moderation"  // A moderator acted on the room; payload is a RoomModerationEvent
+	SignalLobby      SignalType = "lobby"       // To moderators: who waits in the lobby; payload is []ParticipantResponse
+
+	// Sent by clients to the SFU to pick the simulcast layer of a track they receive
+	SignalLayer SignalType = "layer"
+)
+
+// SignalSFU is the participant ID that messages for the SFU are addressed to (and sent
+// from) in rooms whose media mode is "sfu".
+const SignalSFU = "sfu"
+
+// SFUTransport names one of a participant's two connections to the SFU.
+type SFUTransport string
+
+const (
+	SFUPublish   SFUTransport = "publish"   // Client offers and sends its tracks
+	SFUSubscribe SFUTransport = "subscribe" // Server offers and sends the other participants' tracks
+)
+
+// SFUSignalPayload is the payload of offer, answer and candidate messages exchanged
+// with the SFU.
+type SFUSignalPayload struct {
+	Transport SFUTransport    `json:"transport"`
+	SDP       string          `json:"sdp,omitempty"`       // Offers and answers
+	Candidate json.RawMessage `json:"candidate,omitempty"` // RTCIceCandidateInit
+}
+
+// SFULayerRequest is the payload of a layer message. Tracks are identified by the
+// publishing participant (the stream ID) and the track ID.
+type SFULayerRequest struct {
+	Publisher string `json:"publisher"`
+	TrackID   string `json:"track_id"`
+	RID       string `json:"rid"`
+}
+
+// RoomModerationAction is something a moderator does to a participant or the room.
+type RoomModerationAction string
+
+const (
+	RoomModerationKick    RoomModerationAction = "kick"    // Disconnects the participant and keeps them out, invite or not
+	RoomModerationMute    RoomModerationAction = "mute"    // Their audio is no longer forwarded (SFU) or should be muted (mesh)
+	RoomModerationUnmute  RoomModerationAction = "unmute"  // Lifts a mute
+	RoomModerationAdmit   RoomModerationAction = "admit"   // Lets them in from the lobby, or lifts a kick
+	RoomModerationDeny    RoomModerationAction = "deny"    // Turns them away from the lobby
+	RoomModerationPromote RoomModerationAction = "promote" // Makes them a moderator; owner only
+	RoomModerationDemote  RoomModerationAction = "demote"  // Owner only
+	RoomModerationEnd     RoomModerationAction = "end"     // Disconnects everyone and closes the room
+)
+
+// RoomModerationEvent is the payload of a moderation message.
+type RoomModerationEvent struct {
+	Action RoomModerationAction `json:"action"`
+	Target string               `json:"target,omitempty"` // Participant acted on; empty for "end"
+	By     string               `json:"by"`
+}
+
+// SignalMessage is a message on a room's signaling WebSocket. Payload is relayed as
+// is: the session description for offers and answers, the ICE candidate for candidates.
+type SignalMessage struct {
+	Type    SignalType      `json:"type"`
+	From    string          `json:"from,omitempty"` // Set by the server, never trusted from clients
+	To      string          `json:"to,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Peers   []string        `json:"peers,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
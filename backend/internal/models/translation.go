@@ -80,6 +80,7 @@ type DualSubtitleResponse struct {
 // TranslateResponse represents the translation API response.
 type TranslateResponse struct {
 	Status         string                  `json:"status"`
+	Code           ErrorCode               `json:"code,omitempty"`
 	Message        string                  `json:"message,omitempty"`
 	TranslatedText *string                 `json:"translated_text,omitempty"`
 	DualSubtitles  []DualSubtitleResponse  `json:"dual_subtitles,omitempty"`
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// TrashItem is an insight in the trash.
+type TrashItem struct {
+	InsightListItem
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"` // When it is deleted for good
+}
+
+// TrashListResponse is a page of a workspace's trash.
+type TrashListResponse struct {
+	Items         []TrashItem `json:"items"`
+	Total         int64       `json:"total"`
+	RetentionDays int         `json:"retention_days"`
+	PageInfo
+}
+
+// PurgeResponse reports how many insights were deleted for good.
+type PurgeResponse struct {
+	Purged int64 `json:"purged"`
+}
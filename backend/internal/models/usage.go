@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// ErrLLMBudgetExceeded is returned instead of calling the LLM once a daily budget is spent.
+const ErrLLMBudgetExceeded ErrorCode = "LLM_BUDGET_EXCEEDED"
+
+// LLMFeature is the product feature an LLM call was made for.
+type LLMFeature string
+
+const (
+	LLMFeatureChat           LLMFeature = "chat"
+	LLMFeatureEntityAnalysis LLMFeature = "entity_analysis"
+	LLMFeatureTranslation    LLMFeature = "translation"
+	LLMFeatureVideoMetadata  LLMFeature = "video_metadata"
+	LLMFeatureVideoAnalysis  LLMFeature = "video_analysis"
+	LLMFeatureFlashcards     LLMFeature = "flashcards"
+	LLMFeatureChapters       LLMFeature = "chapters"
+)
+
+// LLMUsage is one entry of the LLM usage ledger: the tokens of one OpenRouter
+// completion and its cost according to the configured price table.
+type LLMUsage struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	UserID           *uint      `json:"user_id,omitempty" gorm:"index:idx_llm_usage_user_day,priority:1"` // Nil for anonymous requests
+	Feature          LLMFeature `json:"feature" gorm:"type:varchar(40);not null;index:idx_llm_usage_feature_day,priority:1"`
+	Model            string     `json:"model" gorm:"type:varchar(200);not null"`
+	PromptTokens     int        `json:"prompt_tokens" gorm:"not null;default:0"`
+	CompletionTokens int        `json:"completion_tokens" gorm:"not null;default:0"`
+	CostUSD          float64    `json:"cost_usd" gorm:"type:numeric(14,8);not null;default:0"`
+	CreatedAt        time.Time  `json:"created_at" gorm:"index;index:idx_llm_usage_user_day,priority:2;index:idx_llm_usage_feature_day,priority:2"`
+}
+
+// TableName returns the table name for LLMUsage model.
+func (LLMUsage) TableName() string {
+	return "llm_usage"
+}
+
+// Request/Response DTOs
+
+// UsageDailyAggregate is the usage of one day, per feature or per user.
+type UsageDailyAggregate struct {
+	Date             string     `json:"date"` // YYYY-MM-DD (UTC)
+	Feature          LLMFeature `json:"feature,omitempty"`
+	UserID           *uint      `json:"user_id,omitempty"`
+	Requests         int64      `json:"requests"`
+	PromptTokens     int64      `json:"prompt_tokens"`
+	CompletionTokens int64      `json:"completion_tokens"`
+	CostUSD          float64    `json:"cost_usd"`
+}
+
+// UsageBudgetResponse is the state of a daily budget. LimitUSD is 0 for no limit.
+type UsageBudgetResponse struct {
+	LimitUSD     float64 `json:"limit_usd"`
+	SpentUSD     float64 `json:"spent_usd"`
+	RemainingUSD float64 `json:"remaining_usd"`
+	Exceeded     bool    `json:"exceeded"`
+}
+
+// UsageSummaryResponse is the usage of a user over a range of days.
+type UsageSummaryResponse struct {
+	From  string                `json:"from"`
+	To    string                `json:"to"`
+	Days  []UsageDailyAggregate `json:"days"`
+	Today UsageBudgetResponse   `json:"today"`
+}
@@ -14,6 +14,15 @@ type User struct {
 	Name     string `json:"name" gorm:"type:varchar(255)"`
 	APIKey   string `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"` // API key for authentication
 
+	// Passwordless is true for accounts created through an external login provider.
+	// Such accounts have a random password and must keep at least one linked identity.
+	Passwordless bool `json:"-" gorm:"default:false"`
+
+	// Two-factor authentication (TOTP)
+	TOTPSecret   string `json:"-" gorm:"type:varchar(64)"` // base32 secret, set during enrollment
+	TOTPEnabled  bool   `json:"-" gorm:"default:false"`    // true once enrollment is confirmed
+	TOTPLastStep int64  `json:"-" gorm:"default:0"`        // last accepted time step, prevents code replay
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
@@ -26,10 +35,11 @@ func (User) TableName() string {
 
 // UserResponse represents the user data returned in API responses.
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	ID               uint      `json:"id"`
+	Email            string    `json:"email"`
+	Name             string    `json:"name"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	CreatedAt        time.Time `json:"created_at"`
 }
 
 // RegisterRequest represents the user registration request.
@@ -50,3 +60,63 @@ type AuthResponse struct {
 	User   UserResponse `json:"user"`
 	APIKey string       `json:"api_key"`
 }
+
+// TwoFactorChallengeResponse is returned by login when a second factor is required.
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	Challenge         string `json:"challenge"`
+	ExpiresIn         int    `json:"expires_in"` // seconds
+}
+
+// TwoFactorLoginRequest exchanges a login challenge and a TOTP or recovery code for credentials.
+type TwoFactorLoginRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+}
+
+// TwoFactorCodeRequest carries a TOTP or recovery code.
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorEnrollResponse is returned when starting TOTP enrollment.
+type TwoFactorEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesResponse returns freshly generated recovery codes (shown once).
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorStatusResponse reports the current 2FA state of the user.
+type TwoFactorStatusResponse struct {
+	Enabled                bool  `json:"enabled"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+// RecoveryCode is a hashed single-use 2FA recovery code.
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	CodeHash  string     `json:"-" gorm:"type:varchar(64);not null"` // SHA-256 hex of the normalized code
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName returns the table name for RecoveryCode model.
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// ToResponse converts a User to UserResponse.
+func (u *User) ToResponse() UserResponse {
+	return UserResponse{
+		ID:               u.ID,
+		Email:            u.Email,
+		Name:             u.Name,
+		TwoFactorEnabled: u.TOTPEnabled,
+		CreatedAt:        u.CreatedAt,
+	}
+}
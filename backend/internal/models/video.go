@@ -30,15 +30,26 @@ func (VideoAnalysis) TableName() string {
 	return "video_analyses"
 }
 
-// Chapter represents a video chapter/section.
+// ChapterSource is where a chapter list came from.
+type ChapterSource string
+
+const (
+	ChapterSourceYouTube     ChapterSource = "youtube"     // Chapters reported by yt-dlp
+	ChapterSourceDescription ChapterSource = "description" // Timestamps listed in the video description
+	ChapterSourceAI          ChapterSource = "ai"          // LLM topic segmentation of the transcript
+)
+
+// Chapter represents a video chapter/section, of a video analysis or an insight.
 type Chapter struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	AnalysisID  uint      `json:"analysis_id" gorm:"index;not null"`
-	Title       string    `json:"title" gorm:"type:varchar(500)"`
-	Timestamp   string    `json:"timestamp" gorm:"type:varchar(20)"` // e.g., "05:12"
-	Seconds     int       `json:"seconds"`                           // time in seconds
-	OrderIndex  int       `json:"order_index"`                       // for maintaining order
-	CreatedAt   time.Time `json:"created_at"`
+	ID         uint          `json:"id" gorm:"primaryKey"`
+	AnalysisID *uint         `json:"analysis_id,omitempty" gorm:"index"`
+	InsightID  *uint         `json:"insight_id,omitempty" gorm:"index"`
+	Title      string        `json:"title" gorm:"type:varchar(500)"`
+	Timestamp  string        `json:"timestamp" gorm:"type:varchar(20)"` // e.g., "05:12"
+	Seconds    int           `json:"seconds"`                           // start time in seconds
+	Source     ChapterSource `json:"source,omitempty" gorm:"type:varchar(20)"`
+	OrderIndex int           `json:"order_index"` // for maintaining order
+	CreatedAt  time.Time     `json:"created_at"`
 }
 
 // TableName returns the table name for Chapter model.
@@ -121,9 +132,10 @@ type AnalyzeResponse struct {
 
 // ChapterResponse represents a chapter in the API response.
 type ChapterResponse struct {
-	Title     string `json:"title"`
-	Timestamp string `json:"timestamp"`
-	Seconds   int    `json:"seconds"`
+	Title     string        `json:"title"`
+	Timestamp string        `json:"timestamp"`
+	Seconds   int           `json:"seconds"`
+	Source    ChapterSource `json:"source,omitempty"`
 }
 
 // TranscriptionResponse represents a transcription segment in the API response.
@@ -154,6 +166,7 @@ type HistoryItem struct {
 // HistoryResponse represents the list of history items.
 type HistoryResponse struct {
 	Items []HistoryItem `json:"items"`
+	PageInfo
 }
 
 // ExportRequest represents the request to export analysis results.
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WorkspaceRole is a member's role in a workspace.
+type WorkspaceRole string
+
+const (
+	WorkspaceRoleOwner  WorkspaceRole = "owner"
+	WorkspaceRoleEditor WorkspaceRole = "editor"
+	WorkspaceRoleViewer WorkspaceRole = "viewer"
+)
+
+// Rank orders roles by privilege; unknown roles rank lowest.
+func (r WorkspaceRole) Rank() int {
+	switch r {
+	case WorkspaceRoleOwner:
+		return 3
+	case WorkspaceRoleEditor:
+		return 2
+	case WorkspaceRoleViewer:
+		return 1
+	}
+	return 0
+}
+
+// Allows reports whether r grants at least the privileges of need.
+func (r WorkspaceRole) Allows(need WorkspaceRole) bool {
+	return r.Rank() >= need.Rank() && r.Rank() > 0
+}
+
+// Workspace is a shared insight library. Every user has one personal workspace,
+// which is the default library for their insights.
+type Workspace struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Name     string `json:"name" gorm:"type:varchar(255);not null"`
+	OwnerID  uint   `json:"owner_id" gorm:"index;not null;uniqueIndex:idx_workspaces_personal_owner,where:personal = true"`
+	Personal bool   `json:"personal" gorm:"default:false"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName returns the table name for Workspace model.
+func (Workspace) TableName() string {
+	return "workspaces"
+}
+
+// WorkspaceMember grants a user a role in a workspace.
+type WorkspaceMember struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint          `json:"workspace_id" gorm:"not null;uniqueIndex:idx_workspace_member"`
+	UserID      uint          `json:"user_id" gorm:"not null;uniqueIndex:idx_workspace_member;index"`
+	Role        WorkspaceRole `json:"role" gorm:"type:varchar(20);not null"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for WorkspaceMember model.
+func (WorkspaceMember) TableName() string {
+	return "workspace_members"
+}
+
+// WorkspaceInvitation is a pending invitation to join a workspace.
+type WorkspaceInvitation struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint          `json:"workspace_id" gorm:"index;not null"`
+	Email       string        `json:"email" gorm:"type:varchar(255);not null"`
+	Role        WorkspaceRole `json:"role" gorm:"type:varchar(20);not null"`
+	TokenHash   string        `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"` // SHA-256 of the invitation token
+	InvitedBy   uint          `json:"invited_by" gorm:"not null"`
+
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName returns the table name for WorkspaceInvitation model.
+func (WorkspaceInvitation) TableName() string {
+	return "workspace_invitations"
+}
+
+// UserSummary is the public part of a user shown to teammates (e.g. annotation authors).
+type UserSummary struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// Request/Response DTOs
+
+// CreateWorkspaceRequest represents the request to create a workspace.
+type CreateWorkspaceRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=255"`
+}
+
+// UpdateWorkspaceRequest represents the request to rename a workspace.
+type UpdateWorkspaceRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=255"`
+}
+
+// WorkspaceResponse represents a workspace with the caller's role.
+type WorkspaceResponse struct {
+	ID        uint          `json:"id"`
+	Name      string        `json:"name"`
+	OwnerID   uint          `json:"owner_id"`
+	Personal  bool          `json:"personal"`
+	Role      WorkspaceRole `json:"role"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// WorkspaceMemberResponse represents a workspace member.
+type WorkspaceMemberResponse struct {
+	UserID   uint          `json:"user_id"`
+	Name     string        `json:"name"`
+	Email    string        `json:"email"`
+	Role     WorkspaceRole `json:"role"`
+	JoinedAt time.Time     `json:"joined_at"`
+}
+
+// UpdateMemberRoleRequest represents the request to change a member's role.
+type UpdateMemberRoleRequest struct {
+	Role WorkspaceRole `json:"role" binding:"required,oneof=owner editor viewer"`
+}
+
+// CreateInvitationRequest represents the request to invite someone to a workspace.
+type CreateInvitationRequest struct {
+	Email string        `json:"email" binding:"required,email"`
+	Role  WorkspaceRole `json:"role" binding:"required,oneof=owner editor viewer"`
+}
+
+// CreateInvitationResponse includes the invitation token, which is only returned once.
+type CreateInvitationResponse struct {
+	Invitation WorkspaceInvitation `json:"invitation"`
+	Token      string              `json:"token"`
+	AcceptURL  string              `json:"accept_url"`
+}
+
+// AcceptInvitationRequest represents the request to accept an invitation.
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
@@ -128,13 +128,14 @@ type OAuthCallbackRequest struct {
 
 // OAuthCallbackResponse represents the OAuth callback response.
 type OAuthCallbackResponse struct {
-	AccessToken  string        `json:"accessToken"`
-	RefreshToken string        `json:"refreshToken"`
-	TokenType    string        `json:"tokenType"`
-	Expiry       time.Time     `json:"expiry"`
-	TokenJSON    string        `json:"tokenJSON"`
-	User         *UserResponse `json:"user,omitempty"`  // System user info
-	APIKey       string        `json:"apiKey,omitempty"` // System API key for authentication
+	AccessToken  string                      `json:"accessToken"`
+	RefreshToken string                      `json:"refreshToken"`
+	TokenType    string                      `json:"tokenType"`
+	Expiry       time.Time                   `json:"expiry"`
+	TokenJSON    string                      `json:"tokenJSON"`
+	User         *UserResponse               `json:"user,omitempty"`      // System user info
+	APIKey       string                      `json:"apiKey,omitempty"`    // System API key for authentication
+	TwoFactor    *TwoFactorChallengeResponse `json:"twoFactor,omitempty"` // Set instead of User and APIKey when 2FA is enabled
 }
 
 const (
@@ -0,0 +1,463 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"vibe-backend/internal/models"
+)
+
+// AccountRepository handles data exports and account deletions, which span the
+// tables of every other repository.
+type AccountRepository struct {
+	db *gorm.DB
+}
+
+// NewAccountRepository creates a new AccountRepository.
+func NewAccountRepository(db *gorm.DB) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// --- Exports ---
+
+// CreateExport creates a new export record.
+func (r *AccountRepository) CreateExport(ctx context.Context, export *models.AccountExport) error {
+	return r.db.WithContext(ctx).Create(export).Error
+}
+
+// HasPendingExport reports whether the user has an export that is still being built.
+func (r *AccountRepository) HasPendingExport(ctx context.Context, userID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.AccountExport{}).
+		Where("user_id = ? AND status = ?", userID, models.AccountExportPending).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetExport returns one of a user's exports without the archive.
+func (r *AccountRepository) GetExport(ctx context.Context, userID, id uint) (*models.AccountExport, error) {
+	var export models.AccountExport
+	err := r.db.WithContext(ctx).
+		Omit("archive").
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&export).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// GetExportArchive returns one of a user's exports with the archive.
+func (r *AccountRepository) GetExportArchive(ctx context.Context, userID, id uint) (*models.AccountExport, error) {
+	var export models.AccountExport
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&export).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// ListExports returns a user's exports without archives, newest first.
+func (r *AccountRepository) ListExports(ctx context.Context, userID uint) ([]models.AccountExport, error) {
+	var exports []models.AccountExport
+	err := r.db.WithContext(ctx).
+		Omit("archive").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&exports).Error
+	return exports, err
+}
+
+// CompleteExport stores the archive of a pending export.
+func (r *AccountRepository) CompleteExport(ctx context.Context, id uint, archive []byte, expiresAt time.Time) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Model(&models.AccountExport{}).
+		Where("id = ? AND status = ?", id, models.AccountExportPending).
+		Updates(map[string]interface{}{
+			"status":       models.AccountExportCompleted,
+			"archive":      archive,
+			"size_bytes":   len(archive),
+			"completed_at": now,
+			"expires_at":   expiresAt,
+			"updated_at":   now,
+		}).Error
+}
+
+// FailExport marks a pending export as failed.
+func (r *AccountRepository) FailExport(ctx context.Context, id uint, message string) error {
+	return r.db.WithContext(ctx).Model(&models.AccountExport{}).
+		Where("id = ? AND status = ?", id, models.AccountExportPending).
+		Updates(map[string]interface{}{
+			"status":        models.AccountExportFailed,
+			"error_message": message,
+			"updated_at":    time.Now(),
+		}).Error
+}
+
+// FailStaleExports fails exports still pending since before, e.g. because the
+// server building them was restarted.
+func (r *AccountRepository) FailStaleExports(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.AccountExport{}).
+		Where("status = ? AND created_at < ?", models.AccountExportPending, before).
+		Updates(map[string]interface{}{
+			"status":        models.AccountExportFailed,
+			"error_message": "export was interrupted",
+			"updated_at":    time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteExpiredExports deletes exports whose download period has ended.
+func (r *AccountRepository) DeleteExpiredExports(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at < ?", now).
+		Delete(&models.AccountExport{})
+	return result.RowsAffected, result.Error
+}
+
+// ExportInsights returns the next batch of a user's insights after afterID, with
+// highlights, tags, chapters and chat messages.
+func (r *AccountRepository) ExportInsights(ctx context.Context, userID, afterID uint, limit int) ([]models.Insight, error) {
+	var insights []models.Insight
+	err := r.db.WithContext(ctx).
+		Preload("Highlights", func(db *gorm.DB) *gorm.DB {
+			return db.Order("start_offset ASC")
+		}).
+		Preload("Tags", func(db *gorm.DB) *gorm.DB {
+			return db.Order("tags.name ASC")
+		}).
+		Preload("Chapters", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_index ASC")
+		}).
+		Preload("ChatMessages", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC, id ASC")
+		}).
+		Where("user_id = ? AND id > ?", userID, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&insights).Error
+	return insights, err
+}
+
+// ListOwned loads every row of dest's table whose column equals userID, in ID order.
+// column is one of the user reference columns, never user input.
+func (r *AccountRepository) ListOwned(ctx context.Context, dest interface{}, column string, userID uint) error {
+	return r.db.WithContext(ctx).
+		Where(column+" = ?", userID).
+		Order("id ASC").
+		Find(dest).Error
+}
+
+// --- Deletions ---
+
+// CreateDeletion creates a deletion request.
+func (r *AccountRepository) CreateDeletion(ctx context.Context, deletion *models.AccountDeletion) error {
+	return r.db.WithContext(ctx).Create(deletion).Error
+}
+
+// GetScheduledDeletion returns the user's scheduled deletion.
+func (r *AccountRepository) GetScheduledDeletion(ctx context.Context, userID uint) (*models.AccountDeletion, error) {
+	var deletion models.AccountDeletion
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status = ?", userID, models.AccountDeletionScheduled).
+		First(&deletion).Error
+	if err != nil {
+		return nil, err
+	}
+	return &deletion, nil
+}
+
+// CancelDeletion cancels the user's scheduled deletion. It returns false if none
+// was scheduled, or it already started.
+func (r *AccountRepository) CancelDeletion(ctx context.Context, userID uint) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&models.AccountDeletion{}).
+		Where("user_id = ? AND status = ?", userID, models.AccountDeletionScheduled).
+		Updates(map[string]interface{}{
+			"status":       models.AccountDeletionCancelled,
+			"cancelled_at": now,
+			"updated_at":   now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DueDeletionIDs returns scheduled deletions whose grace period ended before now.
+func (r *AccountRepository) DueDeletionIDs(ctx context.Context, now time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.AccountDeletion{}).
+		Where("status = ? AND scheduled_for <= ?", models.AccountDeletionScheduled, now).
+		Order("scheduled_for ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// RevokeShares revokes every active share link created by the user or pointing to
+// one of their insights, and unpublishes legacy shares. It returns the number of
+// share links revoked.
+func (r *AccountRepository) RevokeShares(ctx context.Context, userID uint) (int64, error) {
+	var revoked int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ShareLink{}).
+			Where("revoked_at IS NULL AND (created_by = ? OR insight_id IN (?))", userID,
+				tx.Unscoped().Model(&models.Insight{}).Select("id").Where("user_id = ?", userID)).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		revoked = result.RowsAffected
+
+		return tx.Unscoped().Model(&models.Insight{}).
+			Where("user_id = ? AND (share_token IS NOT NULL OR is_public = ?)", userID, true).
+			Updates(map[string]interface{}{
+				"share_token":    nil,
+				"share_password": "",
+				"is_public":      false,
+			}).Error
+	})
+	return revoked, err
+}
+
+// ExecuteDeletion hard-deletes the user of a scheduled deletion and everything they
+// own, in one transaction, and marks the deletion completed with the number of rows
+// removed per table. It returns nil if the deletion is no longer scheduled; the row
+// lock keeps two servers from running the same deletion.
+//
+// Workspaces the user owns are handed to the longest-standing remaining member,
+// preferring owners and editors, and deleted if no one else is left. Insights in
+// deleted workspaces go with them, whoever created them. LLM usage is kept for cost
+// accounting but no longer attributed to the user.
+func (r *AccountRepository) ExecuteDeletion(ctx context.Context, id uint) (*models.AccountDeletion, error) {
+	var deletion *models.AccountDeletion
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked models.AccountDeletion
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND status = ?", id, models.AccountDeletionScheduled).
+			First(&locked).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		counts, err := deleteUserData(tx, locked.UserID)
+		if err != nil {
+			return err
+		}
+		deletedRows, err := json.Marshal(counts)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		locked.Status = models.AccountDeletionCompleted
+		locked.CompletedAt = &now
+		locked.DeletedRows = deletedRows
+		if err := tx.Save(&locked).Error; err != nil {
+			return err
+		}
+		deletion = &locked
+		return nil
+	})
+	return deletion, err
+}
+
+// deleteUserData removes a user and their data within tx and returns the rows
+// affected per table.
+func deleteUserData(tx *gorm.DB, userID uint) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	run := func(key string, result *gorm.DB) error {
+		if result.Error != nil {
+			return result.Error
+		}
+		counts[key] += result.RowsAffected
+		return nil
+	}
+
+	var user models.User
+	if err := tx.Unscoped().First(&user, userID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Hand over or delete owned workspaces
+	var owned []models.Workspace
+	if err := tx.Unscoped().Where("owner_id = ?", userID).Find(&owned).Error; err != nil {
+		return nil, err
+	}
+	var doomed []uint
+	for _, workspace := range owned {
+		var successor models.WorkspaceMember
+		err := tx.Where("workspace_id = ? AND user_id <> ?", workspace.ID, userID).
+			Order("CASE role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END, created_at ASC, id ASC").
+			First(&successor).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || workspace.Personal || workspace.DeletedAt.Valid {
+			doomed = append(doomed, workspace.ID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Model(&successor).Update("role", models.WorkspaceRoleOwner).Error; err != nil {
+			return nil, err
+		}
+		if err := run("workspaces_transferred", tx.Model(&models.Workspace{}).
+			Where("id = ?", workspace.ID).Update("owner_id", successor.UserID)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Subqueries are rebuilt for each use; the tables they read are deleted last
+	insights := func() *gorm.DB {
+		return tx.Unscoped().Model(&models.Insight{}).Select("id").Where("user_id = ? OR workspace_id IN ?", userID, doomed)
+	}
+	analyses := func() *gorm.DB {
+		return tx.Unscoped().Model(&models.VideoAnalysis{}).Select("id").Where("user_id = ?", userID)
+	}
+	highlights := func() *gorm.DB {
+		return tx.Model(&models.Highlight{}).Select("id").Where("user_id = ?", userID)
+	}
+	shareLinks := func() *gorm.DB {
+		return tx.Model(&models.ShareLink{}).Select("id").Where("created_by = ? OR insight_id IN (?)", userID, insights())
+	}
+	flashcards := func() *gorm.DB {
+		return tx.Model(&models.Flashcard{}).Select("id").Where("user_id = ? OR insight_id IN (?)", userID, insights())
+	}
+
+	steps := []struct {
+		key   string
+		query func() *gorm.DB
+	}{
+		// Comments and flashcards of other users made from the user's highlights stay, without the link
+		{"comments_unanchored", func() *gorm.DB {
+			return tx.Model(&models.Comment{}).Where("highlight_id IN (?)", highlights()).Update("highlight_id", nil)
+		}},
+		{"flashcards_unanchored", func() *gorm.DB {
+			return tx.Model(&models.Flashcard{}).Where("highlight_id IN (?) AND user_id <> ?", highlights(), userID).Update("highlight_id", nil)
+		}},
+		{"share_link_views", func() *gorm.DB {
+			return tx.Where("share_link_id IN (?)", shareLinks()).Delete(&models.ShareLinkView{})
+		}},
+		{"share_links", func() *gorm.DB {
+			return tx.Where("created_by = ? OR insight_id IN (?)", userID, insights()).Delete(&models.ShareLink{})
+		}},
+		// Replies to the user's comments go with them, as when a thread is deleted
+		{"comments", func() *gorm.DB {
+			return tx.Where("parent_id IN (?)", tx.Model(&models.Comment{}).Select("id").Where("user_id = ?", userID)).Delete(&models.Comment{})
+		}},
+		{"comments", func() *gorm.DB {
+			return tx.Where("user_id = ? OR insight_id IN (?)", userID, insights()).Delete(&models.Comment{})
+		}},
+		{"search_entries", func() *gorm.DB {
+			return tx.Where("insight_id IN (?) OR (kind = ? AND ref_id IN (?)) OR (kind = ? AND ref_id IN (?))",
+				insights(),
+				models.SearchKindHighlight, highlights(),
+				models.SearchKindChat, tx.Model(&models.ChatMessage{}).Select("id").Where("user_id = ?", userID),
+			).Delete(&models.SearchEntry{})
+		}},
+		{"flashcard_reviews", func() *gorm.DB {
+			return tx.Where("user_id = ? OR flashcard_id IN (?)", userID, flashcards()).Delete(&models.FlashcardReview{})
+		}},
+		{"flashcards", func() *gorm.DB {
+			return tx.Where("user_id = ? OR insight_id IN (?)", userID, insights()).Delete(&models.Flashcard{})
+		}},
+		{"chat_messages", func() *gorm.DB {
+			return tx.Where("user_id = ? OR insight_id IN (?)", userID, insights()).Delete(&models.ChatMessage{})
+		}},
+		{"highlights", func() *gorm.DB {
+			return tx.Where("user_id = ? OR insight_id IN (?)", userID, insights()).Delete(&models.Highlight{})
+		}},
+		{"chapters", func() *gorm.DB {
+			return tx.Where("insight_id IN (?) OR analysis_id IN (?)", insights(), analyses()).Delete(&models.Chapter{})
+		}},
+		{"transcriptions", func() *gorm.DB {
+			return tx.Where("analysis_id IN (?)", analyses()).Delete(&models.Transcription{})
+		}},
+		{"key_points", func() *gorm.DB {
+			return tx.Where("analysis_id IN (?)", analyses()).Delete(&models.KeyPoint{})
+		}},
+		{"insight_tags", func() *gorm.DB {
+			return tx.Where("insight_id IN (?) OR tag_id IN (?)", insights(),
+				tx.Model(&models.Tag{}).Select("id").Where("workspace_id IN ?", doomed)).Delete(&models.InsightTag{})
+		}},
+		{"insights", func() *gorm.DB {
+			return tx.Unscoped().Where("user_id = ? OR workspace_id IN ?", userID, doomed).Delete(&models.Insight{})
+		}},
+		{"video_analyses", func() *gorm.DB {
+			return tx.Unscoped().Where("user_id = ?", userID).Delete(&models.VideoAnalysis{})
+		}},
+		{"room_participants", func() *gorm.DB {
+			return tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Participant{})
+		}},
+		{"room_invites", func() *gorm.DB {
+			return tx.Where("created_by = ?", userID).Delete(&models.RoomInvite{})
+		}},
+		// Rooms the user owns go to whoever joins them next
+		{"rooms_unowned", func() *gorm.DB {
+			return tx.Model(&models.Room{}).Where("owner_id = ?", userID).Update("owner_id", nil)
+		}},
+		{"pomodoros", func() *gorm.DB {
+			return tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Pomodoro{})
+		}},
+		{"tags", func() *gorm.DB {
+			return tx.Where("workspace_id IN ?", doomed).Delete(&models.Tag{})
+		}},
+		{"collections", func() *gorm.DB {
+			return tx.Where("workspace_id IN ?", doomed).Delete(&models.Collection{})
+		}},
+		// Tags and collections the user created in workspaces that remain now belong to their owners
+		{"tags_reassigned", func() *gorm.DB {
+			return tx.Exec(`UPDATE tags SET created_by = workspaces.owner_id FROM workspaces
+				WHERE tags.workspace_id = workspaces.id AND tags.created_by = ?`, userID)
+		}},
+		{"collections_reassigned", func() *gorm.DB {
+			return tx.Exec(`UPDATE collections SET created_by = workspaces.owner_id FROM workspaces
+				WHERE collections.workspace_id = workspaces.id AND collections.created_by = ?`, userID)
+		}},
+		{"workspace_invitations", func() *gorm.DB {
+			return tx.Where("workspace_id IN ? OR invited_by = ? OR email = ?", doomed, userID, user.Email).Delete(&models.WorkspaceInvitation{})
+		}},
+		{"workspace_members", func() *gorm.DB {
+			return tx.Where("workspace_id IN ? OR user_id = ?", doomed, userID).Delete(&models.WorkspaceMember{})
+		}},
+		{"workspaces", func() *gorm.DB {
+			return tx.Unscoped().Where("id IN ?", doomed).Delete(&models.Workspace{})
+		}},
+		{"user_identities", func() *gorm.DB {
+			return tx.Where("user_id = ?", userID).Delete(&models.UserIdentity{})
+		}},
+		{"recovery_codes", func() *gorm.DB {
+			return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{})
+		}},
+		{"security_events", func() *gorm.DB {
+			return tx.Where("user_id = ?", userID).Delete(&models.SecurityEvent{})
+		}},
+		{"account_exports", func() *gorm.DB {
+			return tx.Where("user_id = ?", userID).Delete(&models.AccountExport{})
+		}},
+		{"llm_usage_anonymized", func() *gorm.DB {
+			return tx.Model(&models.LLMUsage{}).Where("user_id = ?", userID).Update("user_id", nil)
+		}},
+		// The audit log is append-only; only the client details of its entries may be erased
+		{"audit_logs_anonymized", func() *gorm.DB {
+			return tx.Model(&models.AuditLog{}).
+				Where("actor_id = ? OR (target_type = ? AND target_id = ?)", userID, models.AuditTargetUser, userID).
+				Updates(map[string]interface{}{"ip_address": nil, "user_agent": nil})
+		}},
+		{"users", func() *gorm.DB {
+			return tx.Unscoped().Where("id = ?", userID).Delete(&models.User{})
+		}},
+	}
+	for _, step := range steps {
+		if err := run(step.key, step.query()); err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
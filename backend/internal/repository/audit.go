@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"vibe-backend/internal/models"
+)
+
+// auditSortColumns are the sort keys of the audit log.
+var auditSortColumns = map[string]sortColumn{
+	models.SortCreated: {expr: "created_at", kind: cursorTime},
+}
+
+// AuditRepository handles database operations for the audit log. The table is
+// append-only: entries are never updated or deleted.
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new AuditRepository.
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Create inserts an audit log entry.
+func (r *AuditRepository) Create(ctx context.Context, entry *models.AuditLog) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// ListPage returns a page of the audit log entries matching filter, with their actors.
+func (r *AuditRepository) ListPage(ctx context.Context, filter models.AuditFilter, page models.PageRequest) ([]models.AuditLog, models.PageInfo, error) {
+	query := applyAuditFilter(r.db.WithContext(ctx).Model(&models.AuditLog{}), filter)
+
+	query, page, err := paginate(query, auditSortColumns, "id", page)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	var entries []models.AuditLog
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	count, info := pageInfo(len(entries), page, func(i int) (string, uint) {
+		return formatCursorTime(entries[i].CreatedAt), entries[i].ID
+	})
+	entries = entries[:count]
+	if err := r.attachActors(ctx, entries); err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	return entries, info, nil
+}
+
+// applyAuditFilter adds the conditions of filter to an audit log query.
+func applyAuditFilter(query *gorm.DB, filter models.AuditFilter) *gorm.DB {
+	if filter.UserID != nil {
+		query = query.Where("actor_id = ? OR (target_type = ? AND target_id = ?)", *filter.UserID, models.AuditTargetUser, *filter.UserID)
+	}
+	if filter.WorkspaceID != nil {
+		query = query.Where("workspace_id = ?", *filter.WorkspaceID)
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if len(filter.Actions) > 0 {
+		query = query.Where("action IN ?", filter.Actions)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != nil {
+		query = query.Where("target_id = ?", *filter.TargetID)
+	}
+	return applyDateRange(query, "created_at", filter.Created)
+}
+
+// attachActors fills in the Actor of each entry.
+func (r *AuditRepository) attachActors(ctx context.Context, entries []models.AuditLog) error {
+	ids := make([]uint, 0, len(entries))
+	for _, e := range entries {
+		if e.ActorID != nil {
+			ids = append(ids, *e.ActorID)
+		}
+	}
+	actors, err := userSummaries(r.db.WithContext(ctx), ids)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		if entries[i].ActorID == nil {
+			continue
+		}
+		if actor, ok := actors[*entries[i].ActorID]; ok {
+			entries[i].Actor = &actor
+		}
+	}
+	return nil
+}
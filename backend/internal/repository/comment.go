@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"vibe-backend/internal/models"
+)
+
+// maxCommentsPerInsight bounds how many comments are loaded for one insight.
+const maxCommentsPerInsight = 2000
+
+// CommentRepository handles database operations for comments.
+type CommentRepository struct {
+	db *gorm.DB
+}
+
+// NewCommentRepository creates a new CommentRepository.
+func NewCommentRepository(db *gorm.DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+// Create creates a new comment.
+func (r *CommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	return r.db.WithContext(ctx).Create(comment).Error
+}
+
+// GetByID returns a comment by ID.
+func (r *CommentRepository) GetByID(ctx context.Context, id uint) (*models.Comment, error) {
+	var comment models.Comment
+	if err := r.db.WithContext(ctx).First(&comment, id).Error; err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// ListByInsight returns the comments of an insight, oldest first. Hidden comments
+// are only included when includeHidden is set.
+func (r *CommentRepository) ListByInsight(ctx context.Context, insightID uint, includeHidden bool) ([]models.Comment, error) {
+	query := r.db.WithContext(ctx).Where("insight_id = ?", insightID)
+	if !includeHidden {
+		query = query.Where("hidden = ?", false)
+	}
+	var comments []models.Comment
+	err := query.
+		Order("created_at ASC").
+		Order("id ASC").
+		Limit(maxCommentsPerInsight).
+		Find(&comments).Error
+	return comments, err
+}
+
+// UpdateModeration saves the hidden and locked flags of a comment.
+func (r *CommentRepository) UpdateModeration(ctx context.Context, comment *models.Comment) error {
+	return r.db.WithContext(ctx).Model(comment).
+		Select("hidden", "locked", "updated_at").
+		Updates(comment).Error
+}
+
+// DeleteThread deletes a comment and its replies.
+func (r *CommentRepository) DeleteThread(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).
+		Where("id = ? OR parent_id = ?", id, id).
+		Delete(&models.Comment{}).Error
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"vibe-backend/internal/models"
+)
+
+// flashcardSortColumns are the sort keys of the card list.
+var flashcardSortColumns = map[string]sortColumn{
+	models.SortCreated: {expr: "created_at", kind: cursorTime},
+	models.SortDue:     {expr: "due_at", kind: cursorTime},
+}
+
+// FlashcardRepository handles database operations for flashcards and their reviews.
+type FlashcardRepository struct {
+	db *gorm.DB
+}
+
+// NewFlashcardRepository creates a new FlashcardRepository.
+func NewFlashcardRepository(db *gorm.DB) *FlashcardRepository {
+	return &FlashcardRepository{db: db}
+}
+
+// CreateBatch creates cards, skipping any whose source already has a card.
+// Only created cards get an ID.
+func (r *FlashcardRepository) CreateBatch(ctx context.Context, cards []models.Flashcard) error {
+	if len(cards) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&cards).Error
+}
+
+// SourceKeys returns the source keys of a user's cards for an insight.
+func (r *FlashcardRepository) SourceKeys(ctx context.Context, userID, insightID uint) (map[string]bool, error) {
+	var keys []string
+	err := r.db.WithContext(ctx).Model(&models.Flashcard{}).
+		Where("user_id = ? AND insight_id = ?", userID, insightID).
+		Pluck("source_key", &keys).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		result[key] = true
+	}
+	return result, nil
+}
+
+// GetByID returns a card by ID.
+func (r *FlashcardRepository) GetByID(ctx context.Context, id uint) (*models.Flashcard, error) {
+	var card models.Flashcard
+	if err := r.db.WithContext(ctx).First(&card, id).Error; err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// Update saves a card.
+func (r *FlashcardRepository) Update(ctx context.Context, card *models.Flashcard) error {
+	return r.db.WithContext(ctx).Save(card).Error
+}
+
+// Delete deletes a card and its review history.
+func (r *FlashcardRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("flashcard_id = ?", id).Delete(&models.FlashcardReview{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Flashcard{}, id).Error
+	})
+}
+
+// RecordReview saves a card's new schedule together with the review.
+func (r *FlashcardRepository) RecordReview(ctx context.Context, card *models.Flashcard, review *models.FlashcardReview) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(card).Error; err != nil {
+			return err
+		}
+		return tx.Create(review).Error
+	})
+}
+
+// ListPage returns a page of a user's cards, optionally only those of one insight.
+func (r *FlashcardRepository) ListPage(ctx context.Context, userID uint, insightID *uint, page models.PageRequest) ([]models.Flashcard, models.PageInfo, error) {
+	query := r.db.WithContext(ctx).Model(&models.Flashcard{}).Where("user_id = ?", userID)
+	query = withoutTrashedInsights(query)
+	if insightID != nil {
+		query = query.Where("insight_id = ?", *insightID)
+	}
+
+	query, page, err := paginate(query, flashcardSortColumns, "id", page)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	var cards []models.Flashcard
+	if err := query.Find(&cards).Error; err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	count, info := pageInfo(len(cards), page, func(i int) (string, uint) {
+		if page.Sort == models.SortDue {
+			return formatCursorTime(cards[i].DueAt), cards[i].ID
+		}
+		return formatCursorTime(cards[i].CreatedAt), cards[i].ID
+	})
+	return cards[:count], info, nil
+}
+
+// ListDue returns a user's unsuspended cards due at now, most overdue first, and the
+// total number of due cards.
+func (r *FlashcardRepository) ListDue(ctx context.Context, userID uint, now time.Time, limit int) ([]models.Flashcard, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.Flashcard{}).
+		Where("user_id = ? AND suspended = ? AND due_at <= ?", userID, false, now)
+	query = withoutTrashedInsights(query)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var cards []models.Flashcard
+	err := query.Order("due_at ASC").Order("id ASC").Limit(limit).Find(&cards).Error
+	return cards, total, err
+}
+
+// ListByInsights returns all of a user's cards for the given insights.
+func (r *FlashcardRepository) ListByInsights(ctx context.Context, userID uint, insightIDs []uint) ([]models.Flashcard, error) {
+	var cards []models.Flashcard
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND insight_id IN ?", userID, insightIDs).
+		Order("insight_id ASC, id ASC").
+		Find(&cards).Error
+	return cards, err
+}
+
+// InsightTitles returns the titles of the given insights.
+func (r *FlashcardRepository) InsightTitles(ctx context.Context, insightIDs []uint) (map[uint]string, error) {
+	titles := make(map[uint]string, len(insightIDs))
+	if len(insightIDs) == 0 {
+		return titles, nil
+	}
+	var rows []struct {
+		ID    uint
+		Title string
+	}
+	err := r.db.WithContext(ctx).Model(&models.Insight{}).
+		Select("id, title").
+		Where("id IN ?", insightIDs).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		titles[row.ID] = row.Title
+	}
+	return titles, nil
+}
+
+// withoutTrashedInsights leaves out cards of insights in the trash; they come back
+// when the insight is restored.
+func withoutTrashedInsights(query *gorm.DB) *gorm.DB {
+	return query.Where("NOT EXISTS (SELECT 1 FROM insights i WHERE i.id = flashcards.insight_id AND i.deleted_at IS NOT NULL)")
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"vibe-backend/internal/models"
+)
+
+// IdentityRepository handles database operations for linked login identities.
+type IdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewIdentityRepository creates a new IdentityRepository.
+func NewIdentityRepository(db *gorm.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// Create creates a new identity record.
+func (r *IdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+// GetByID returns an identity by ID.
+func (r *IdentityRepository) GetByID(ctx context.Context, id uint) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.WithContext(ctx).First(&identity, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// GetByProviderSubject returns the identity for a provider account.
+func (r *IdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.WithContext(ctx).
+		Where("provider = ? AND subject = ?", provider, subject).
+		First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// GetByUserID returns all identities linked to a user.
+func (r *IdentityRepository) GetByUserID(ctx context.Context, userID uint) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&identities).Error
+	return identities, err
+}
+
+// CountByUserID returns the number of identities linked to a user.
+func (r *IdentityRepository) CountByUserID(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.UserIdentity{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// TouchLogin refreshes the profile fields and last login time of an identity.
+func (r *IdentityRepository) TouchLogin(ctx context.Context, id uint, ext *models.ExternalIdentity) error {
+	return r.db.WithContext(ctx).
+		Model(&models.UserIdentity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"email":          ext.Email,
+			"email_verified": ext.EmailVerified,
+			"name":           ext.Name,
+			"avatar_url":     ext.AvatarURL,
+			"last_login_at":  time.Now().UTC(),
+		}).Error
+}
+
+// Delete deletes an identity record.
+func (r *IdentityRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.UserIdentity{}, id).Error
+}
@@ -2,9 +2,11 @@ package repository
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"vibe-backend/internal/models"
 )
 
@@ -33,17 +35,26 @@ func (r *InsightRepository) GetByID(ctx context.Context, id uint) (*models.Insig
 	return &insight, nil
 }
 
-// GetByIDWithRelations returns an insight by ID with highlights preloaded.
+// GetByIDWithRelations returns an insight by ID with highlights and their authors preloaded.
 func (r *InsightRepository) GetByIDWithRelations(ctx context.Context, id uint) (*models.Insight, error) {
 	var insight models.Insight
 	err := r.db.WithContext(ctx).
 		Preload("Highlights", func(db *gorm.DB) *gorm.DB {
 			return db.Order("start_offset ASC")
 		}).
+		Preload("Tags", func(db *gorm.DB) *gorm.DB {
+			return db.Order("tags.name ASC")
+		}).
+		Preload("Chapters", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_index ASC")
+		}).
 		First(&insight, id).Error
 	if err != nil {
 		return nil, err
 	}
+	if err := r.attachHighlightAuthors(ctx, insight.Highlights); err != nil {
+		return nil, err
+	}
 	return &insight, nil
 }
 
@@ -73,8 +84,17 @@ func (r *InsightRepository) GetByUserID(ctx context.Context, userID uint, status
 	return insights, total, err
 }
 
-// GetByUserIDGroupedByDate returns insights grouped by today, yesterday, and previous.
-func (r *InsightRepository) GetByUserIDGroupedByDate(ctx context.Context, userID uint, search string, limit int) (*models.InsightListResponse, error) {
+// insightSortColumns are the sort keys of insight lists.
+var insightSortColumns = map[string]sortColumn{
+	models.SortCreated:   {expr: "created_at", kind: cursorTime},
+	models.SortPublished: {expr: "COALESCE(published_at, created_at)", kind: cursorTime},
+	models.SortDuration:  {expr: "duration", kind: cursorInt},
+	models.SortTitle:     {expr: "COALESCE(title, '')", kind: cursorString},
+}
+
+// GetByWorkspaceGroupedByDate returns one page of a workspace's insights matching filter.
+// When sorted by creation time the page is also grouped into today, yesterday, and previous.
+func (r *InsightRepository) GetByWorkspaceGroupedByDate(ctx context.Context, workspaceID uint, filter models.InsightFilter, page models.PageRequest) (*models.InsightListResponse, error) {
 	var insights []models.Insight
 
 	// Get current time boundaries
@@ -83,41 +103,50 @@ func (r *InsightRepository) GetByUserIDGroupedByDate(ctx context.Context, userID
 	yesterdayStart := todayStart.AddDate(0, 0, -1)
 
 	query := r.db.WithContext(ctx).
-		Where("user_id = ?", userID).
-		Order("created_at DESC")
+		Model(&models.Insight{}).
+		Where("workspace_id = ?", workspaceID)
+	query = applyInsightFilter(query, filter)
 
-	// Apply search filter if provided
-	if search != "" {
-		query = query.Where("title ILIKE ?", "%"+search+"%")
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
 	}
 
-	if limit > 0 {
-		query = query.Limit(limit)
+	query, page, err := paginate(query, insightSortColumns, "id", page)
+	if err != nil {
+		return nil, err
 	}
-
-	if err := query.Find(&insights).Error; err != nil {
+	err = query.
+		Preload("Tags", func(db *gorm.DB) *gorm.DB {
+			return db.Order("tags.name ASC")
+		}).
+		Find(&insights).Error
+	if err != nil {
 		return nil, err
 	}
 
-	// Group by date
+	count, info := pageInfo(len(insights), page, func(i int) (string, uint) {
+		return insightCursorValue(&insights[i], page.Sort), insights[i].ID
+	})
+	insights = insights[:count]
+
 	response := &models.InsightListResponse{
+		Items:     make([]models.InsightListItem, 0, len(insights)),
 		Today:     make([]models.InsightListItem, 0),
 		Yesterday: make([]models.InsightListItem, 0),
 		Previous:  make([]models.InsightListItem, 0),
-		Total:     len(insights),
+		Total:     total,
+		PageInfo:  info,
 	}
 
 	for _, insight := range insights {
-		item := models.InsightListItem{
-			ID:           insight.ID,
-			SourceType:   insight.SourceType,
-			Title:        insight.Title,
-			Author:       insight.Author,
-			ThumbnailURL: insight.ThumbnailURL,
-			Status:       insight.Status,
-			CreatedAt:    insight.CreatedAt,
-		}
+		item := toInsightListItem(&insight)
+		response.Items = append(response.Items, item)
 
+		// Date buckets only make sense in creation order
+		if page.Sort != models.SortCreated {
+			continue
+		}
 		if insight.CreatedAt.After(todayStart) || insight.CreatedAt.Equal(todayStart) {
 			response.Today = append(response.Today, item)
 		} else if insight.CreatedAt.After(yesterdayStart) || insight.CreatedAt.Equal(yesterdayStart) {
@@ -130,11 +159,98 @@ func (r *InsightRepository) GetByUserIDGroupedByDate(ctx context.Context, userID
 	return response, nil
 }
 
-// GetBySourceID returns an insight by source ID and user ID.
-func (r *InsightRepository) GetBySourceID(ctx context.Context, sourceID string, userID uint) (*models.Insight, error) {
+// insightCursorValue returns an insight's value for a sort key, formatted for a cursor.
+func insightCursorValue(insight *models.Insight, sort string) string {
+	switch sort {
+	case models.SortPublished:
+		if insight.PublishedAt != nil {
+			return formatCursorTime(*insight.PublishedAt)
+		}
+		return formatCursorTime(insight.CreatedAt)
+	case models.SortDuration:
+		return strconv.Itoa(insight.Duration)
+	case models.SortTitle:
+		return insight.Title
+	default:
+		return formatCursorTime(insight.CreatedAt)
+	}
+}
+
+// applyInsightFilter adds the conditions of filter to an insight query.
+func applyInsightFilter(query *gorm.DB, filter models.InsightFilter) *gorm.DB {
+	if filter.Search != "" {
+		query = query.Where("title ILIKE ?", "%"+filter.Search+"%")
+	}
+	if len(filter.TagIDs) > 0 {
+		query = query.Where(
+			"id IN (SELECT insight_id FROM insight_tags WHERE tag_id IN ? GROUP BY insight_id HAVING COUNT(DISTINCT tag_id) = ?)",
+			filter.TagIDs, len(filter.TagIDs),
+		)
+	}
+	if filter.Unfiled {
+		query = query.Where("collection_id IS NULL")
+	} else if filter.CollectionID != nil {
+		query = query.Where("collection_id = ?", *filter.CollectionID)
+	}
+	if filter.SourceType != "" {
+		query = query.Where("source_type = ?", filter.SourceType)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	return applyDateRange(query, "created_at", filter.Created)
+}
+
+// GetListItemsByIDs returns list items for the given insights, keyed by ID.
+// Missing and deleted insights are left out.
+func (r *InsightRepository) GetListItemsByIDs(ctx context.Context, ids []uint) (map[uint]models.InsightListItem, error) {
+	items := make(map[uint]models.InsightListItem, len(ids))
+	if len(ids) == 0 {
+		return items, nil
+	}
+
+	var insights []models.Insight
+	err := r.db.WithContext(ctx).
+		Where("id IN ?", ids).
+		Preload("Tags", func(db *gorm.DB) *gorm.DB {
+			return db.Order("tags.name ASC")
+		}).
+		Find(&insights).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range insights {
+		items[insights[i].ID] = toInsightListItem(&insights[i])
+	}
+	return items, nil
+}
+
+// toInsightListItem converts an insight to its list representation.
+func toInsightListItem(insight *models.Insight) models.InsightListItem {
+	item := models.InsightListItem{
+		ID:           insight.ID,
+		WorkspaceID:  insight.WorkspaceID,
+		CollectionID: insight.CollectionID,
+		Tags:         insight.Tags,
+		SourceType:   insight.SourceType,
+		Title:        insight.Title,
+		Author:       insight.Author,
+		ThumbnailURL: insight.ThumbnailURL,
+		Status:       insight.Status,
+		CreatedAt:    insight.CreatedAt,
+	}
+	if item.Tags == nil {
+		item.Tags = make([]models.Tag, 0)
+	}
+	return item
+}
+
+// GetBySourceID returns an insight by source ID within a workspace.
+func (r *InsightRepository) GetBySourceID(ctx context.Context, sourceID string, workspaceID uint) (*models.Insight, error) {
 	var insight models.Insight
 	err := r.db.WithContext(ctx).
-		Where("source_id = ? AND user_id = ?", sourceID, userID).
+		Where("source_id = ? AND workspace_id = ?", sourceID, workspaceID).
 		Order("created_at DESC").
 		First(&insight).Error
 	if err != nil {
@@ -143,11 +259,11 @@ func (r *InsightRepository) GetBySourceID(ctx context.Context, sourceID string,
 	return &insight, nil
 }
 
-// GetBySourceURL returns an insight by source URL and user ID.
-func (r *InsightRepository) GetBySourceURL(ctx context.Context, sourceURL string, userID uint) (*models.Insight, error) {
+// GetBySourceURL returns an insight by source URL within a workspace.
+func (r *InsightRepository) GetBySourceURL(ctx context.Context, sourceURL string, workspaceID uint) (*models.Insight, error) {
 	var insight models.Insight
 	err := r.db.WithContext(ctx).
-		Where("source_url = ? AND user_id = ?", sourceURL, userID).
+		Where("source_url = ? AND workspace_id = ?", sourceURL, workspaceID).
 		Order("created_at DESC").
 		First(&insight).Error
 	if err != nil {
@@ -156,24 +272,56 @@ func (r *InsightRepository) GetBySourceURL(ctx context.Context, sourceURL string
 	return &insight, nil
 }
 
-// GetByShareToken returns an insight by share token.
-func (r *InsightRepository) GetByShareToken(ctx context.Context, token string) (*models.Insight, error) {
+// GetForShare returns an insight with its highlights for a public share page.
+func (r *InsightRepository) GetForShare(ctx context.Context, id uint) (*models.Insight, error) {
 	var insight models.Insight
 	err := r.db.WithContext(ctx).
-		Where("share_token = ? AND is_public = ?", token, true).
 		Preload("Highlights", func(db *gorm.DB) *gorm.DB {
 			return db.Order("start_offset ASC")
 		}).
-		First(&insight).Error
+		First(&insight, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &insight, nil
+}
+
+// GetShareMeta returns the columns of an insight that share previews show: its
+// title, source, thumbnail, summary and key points, without highlights.
+func (r *InsightRepository) GetShareMeta(ctx context.Context, id uint) (*models.Insight, error) {
+	var insight models.Insight
+	err := r.db.WithContext(ctx).
+		Select("id", "source_type", "source_url", "title", "author", "thumbnail_url", "summary", "key_points").
+		First(&insight, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &insight, nil
 }
 
-// Update updates an insight record.
+// GetForExport returns insights with their highlights and tags for a deck export.
+func (r *InsightRepository) GetForExport(ctx context.Context, ids []uint) ([]models.Insight, error) {
+	var insights []models.Insight
+	err := r.db.WithContext(ctx).
+		Preload("Highlights", func(db *gorm.DB) *gorm.DB {
+			return db.Order("start_offset ASC")
+		}).
+		Preload("Tags", func(db *gorm.DB) *gorm.DB {
+			return db.Order("tags.name ASC")
+		}).
+		Preload("Chapters", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_index ASC")
+		}).
+		Where("id IN ?", ids).
+		Order("id ASC").
+		Find(&insights).Error
+	return insights, err
+}
+
+// Update updates an insight record. An insight moved to the trash in the meantime is
+// left alone; a plain Save would re-insert it.
 func (r *InsightRepository) Update(ctx context.Context, insight *models.Insight) error {
-	return r.db.WithContext(ctx).Save(insight).Error
+	return r.db.WithContext(ctx).Select("*").Save(insight).Error
 }
 
 // UpdateStatus updates only the status and error message of an insight.
@@ -188,36 +336,232 @@ func (r *InsightRepository) UpdateStatus(ctx context.Context, id uint, status mo
 	return r.db.WithContext(ctx).Model(&models.Insight{}).Where("id = ?", id).Updates(updates).Error
 }
 
-// Delete soft-deletes an insight and all related records.
+// Delete moves an insight to the trash. Its highlights, chat, comments, chapters,
+// flashcards and share links are kept so a restored insight comes back intact; they
+// are removed when the insight is purged.
 func (r *InsightRepository) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// Delete related records first (hard delete since they don't have DeletedAt)
-		if err := tx.Where("insight_id = ?", id).Delete(&models.Highlight{}).Error; err != nil {
-			return err
+	result := r.db.WithContext(ctx).Delete(&models.Insight{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// --- Trash ---
+
+// trashSortColumns are the sort keys of the trash.
+var trashSortColumns = map[string]sortColumn{
+	models.SortDeleted: {expr: "deleted_at", kind: cursorTime},
+	models.SortCreated: {expr: "created_at", kind: cursorTime},
+	models.SortTitle:   {expr: "COALESCE(title, '')", kind: cursorString},
+}
+
+// GetTrashed returns an insight in the trash.
+func (r *InsightRepository) GetTrashed(ctx context.Context, id uint) (*models.Insight, error) {
+	var insight models.Insight
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL").
+		First(&insight, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &insight, nil
+}
+
+// ListTrashPage returns a page of a workspace's trash, only insights created by
+// createdBy if set, and the total number of matching insights.
+func (r *InsightRepository) ListTrashPage(ctx context.Context, workspaceID uint, createdBy *uint, page models.PageRequest) ([]models.TrashItem, int64, models.PageInfo, error) {
+	query := r.db.WithContext(ctx).Unscoped().Model(&models.Insight{}).
+		Where("workspace_id = ? AND deleted_at IS NOT NULL", workspaceID)
+	if createdBy != nil {
+		query = query.Where("user_id = ?", *createdBy)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, models.PageInfo{}, err
+	}
+
+	query, page, err := paginate(query, trashSortColumns, "id", page)
+	if err != nil {
+		return nil, 0, models.PageInfo{}, err
+	}
+	var insights []models.Insight
+	err = query.
+		Preload("Tags", func(db *gorm.DB) *gorm.DB {
+			return db.Order("tags.name ASC")
+		}).
+		Find(&insights).Error
+	if err != nil {
+		return nil, 0, models.PageInfo{}, err
+	}
+
+	count, info := pageInfo(len(insights), page, func(i int) (string, uint) {
+		switch page.Sort {
+		case models.SortDeleted:
+			return formatCursorTime(insights[i].DeletedAt.Time), insights[i].ID
+		case models.SortTitle:
+			return insights[i].Title, insights[i].ID
+		default:
+			return formatCursorTime(insights[i].CreatedAt), insights[i].ID
+		}
+	})
+
+	items := make([]models.TrashItem, count)
+	for i := range items {
+		items[i] = models.TrashItem{
+			InsightListItem: toInsightListItem(&insights[i]),
+			DeletedAt:       insights[i].DeletedAt.Time,
 		}
-		if err := tx.Where("insight_id = ?", id).Delete(&models.ChatMessage{}).Error; err != nil {
+	}
+	return items, total, info, nil
+}
+
+// Restore takes an insight out of the trash. It is unfiled if its collection was
+// deleted meanwhile, and marked failed if it was trashed while being processed,
+// since the processing result was discarded.
+func (r *InsightRepository) Restore(ctx context.Context, id uint, interruptedMsg string) error {
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.Insight{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{
+			"deleted_at":    nil,
+			"collection_id": gorm.Expr("CASE WHEN EXISTS (SELECT 1 FROM collections c WHERE c.id = insights.collection_id) THEN collection_id END"),
+			"error_message": gorm.Expr("CASE WHEN status IN ? THEN ? ELSE error_message END", []models.InsightStatus{models.InsightStatusPending, models.InsightStatusProcessing}, interruptedMsg),
+			"status":        gorm.Expr("CASE WHEN status IN ? THEN ? ELSE status END", []models.InsightStatus{models.InsightStatusPending, models.InsightStatusProcessing}, models.InsightStatusFailed),
+			"updated_at":    time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// TrashIDs returns the insights in a workspace's trash, only those created by
+// createdBy if set.
+func (r *InsightRepository) TrashIDs(ctx context.Context, workspaceID uint, createdBy *uint) ([]uint, error) {
+	query := r.db.WithContext(ctx).Unscoped().Model(&models.Insight{}).
+		Where("workspace_id = ? AND deleted_at IS NOT NULL", workspaceID)
+	if createdBy != nil {
+		query = query.Where("user_id = ?", *createdBy)
+	}
+	var ids []uint
+	err := query.Order("id ASC").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ExpiredTrashIDs returns up to limit insights that were trashed before the given time.
+func (r *InsightRepository) ExpiredTrashIDs(ctx context.Context, before time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Insight{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Order("deleted_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// Purge permanently deletes trashed insights with everything attached to them.
+// Insights among ids that are not in the trash (e.g. restored meanwhile) are skipped.
+// It returns the number of insights deleted.
+func (r *InsightRepository) Purge(ctx context.Context, ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	var purged int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the rows so a concurrent restore either wins or waits
+		var trashed []uint
+		if err := tx.Unscoped().Model(&models.Insight{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ? AND deleted_at IS NOT NULL", ids).
+			Pluck("id", &trashed).Error; err != nil {
 			return err
 		}
-		// Soft delete the insight
-		return tx.Delete(&models.Insight{}, id).Error
+		if len(trashed) == 0 {
+			return nil
+		}
+
+		steps := []func() *gorm.DB{
+			func() *gorm.DB {
+				return tx.Where("share_link_id IN (?)", tx.Model(&models.ShareLink{}).Select("id").Where("insight_id IN ?", trashed)).
+					Delete(&models.ShareLinkView{})
+			},
+			func() *gorm.DB { return tx.Where("insight_id IN ?", trashed).Delete(&models.ShareLink{}) },
+			func() *gorm.DB { return tx.Where("insight_id IN ?", trashed).Delete(&models.Comment{}) },
+			func() *gorm.DB { return tx.Where("insight_id IN ?", trashed).Delete(&models.SearchEntry{}) },
+			func() *gorm.DB {
+				return tx.Where("flashcard_id IN (?)", tx.Model(&models.Flashcard{}).Select("id").Where("insight_id IN ?", trashed)).
+					Delete(&models.FlashcardReview{})
+			},
+			func() *gorm.DB { return tx.Where("insight_id IN ?", trashed).Delete(&models.Flashcard{}) },
+			func() *gorm.DB { return tx.Where("insight_id IN ?", trashed).Delete(&models.ChatMessage{}) },
+			func() *gorm.DB { return tx.Where("insight_id IN ?", trashed).Delete(&models.Highlight{}) },
+			func() *gorm.DB { return tx.Where("insight_id IN ?", trashed).Delete(&models.Chapter{}) },
+			func() *gorm.DB { return tx.Where("insight_id IN ?", trashed).Delete(&models.InsightTag{}) },
+		}
+		for _, step := range steps {
+			if err := step().Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Unscoped().Where("id IN ?", trashed).Delete(&models.Insight{})
+		purged = result.RowsAffected
+		return result.Error
 	})
+	return purged, err
 }
 
 // --- Highlight operations ---
 
+// ReplaceChapters replaces the chapters of an insight.
+func (r *InsightRepository) ReplaceChapters(ctx context.Context, insightID uint, chapters []models.Chapter) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("insight_id = ?", insightID).Delete(&models.Chapter{}).Error; err != nil {
+			return err
+		}
+		if len(chapters) == 0 {
+			return nil
+		}
+		return tx.Create(&chapters).Error
+	})
+}
+
+// GetChapters returns the chapters of an insight in order.
+func (r *InsightRepository) GetChapters(ctx context.Context, insightID uint) ([]models.Chapter, error) {
+	var chapters []models.Chapter
+	err := r.db.WithContext(ctx).
+		Where("insight_id = ?", insightID).
+		Order("order_index ASC").
+		Find(&chapters).Error
+	return chapters, err
+}
+
 // CreateHighlight creates a new highlight record.
 func (r *InsightRepository) CreateHighlight(ctx context.Context, highlight *models.Highlight) error {
 	return r.db.WithContext(ctx).Create(highlight).Error
 }
 
-// GetHighlightsByInsightID returns all highlights for an insight.
+// GetHighlightsByInsightID returns all highlights for an insight with their authors.
 func (r *InsightRepository) GetHighlightsByInsightID(ctx context.Context, insightID uint) ([]models.Highlight, error) {
 	var highlights []models.Highlight
 	err := r.db.WithContext(ctx).
 		Where("insight_id = ?", insightID).
 		Order("start_offset ASC").
 		Find(&highlights).Error
-	return highlights, err
+	if err != nil {
+		return nil, err
+	}
+	if err := r.attachHighlightAuthors(ctx, highlights); err != nil {
+		return nil, err
+	}
+	return highlights, nil
 }
 
 // GetHighlightByID returns a highlight by ID.
@@ -237,7 +581,16 @@ func (r *InsightRepository) UpdateHighlight(ctx context.Context, highlight *mode
 
 // DeleteHighlight deletes a highlight record.
 func (r *InsightRepository) DeleteHighlight(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&models.Highlight{}, id).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Comments and flashcards made from the highlight stay, without the link
+		if err := tx.Model(&models.Comment{}).Where("highlight_id = ?", id).Update("highlight_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Flashcard{}).Where("highlight_id = ?", id).Update("highlight_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Highlight{}, id).Error
+	})
 }
 
 // --- ChatMessage operations ---
@@ -273,11 +626,90 @@ func (r *InsightRepository) GetChatMessagesByInsightIDPaginated(ctx context.Cont
 		Limit(limit).
 		Offset(offset).
 		Find(&messages).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := r.attachChatAuthors(ctx, messages); err != nil {
+		return nil, 0, err
+	}
+
+	return messages, total, nil
+}
+
+// chatSortColumns are the sort keys of chat message lists.
+var chatSortColumns = map[string]sortColumn{
+	models.SortCreated: {expr: "created_at", kind: cursorTime},
+}
+
+// ListChatMessagesPage returns one keyset-paginated page of an insight's chat messages
+// and the total number of messages.
+func (r *InsightRepository) ListChatMessagesPage(ctx context.Context, insightID uint, page models.PageRequest) ([]models.ChatMessage, int64, models.PageInfo, error) {
+	var messages []models.ChatMessage
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.ChatMessage{}).Where("insight_id = ?", insightID)
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, models.PageInfo{}, err
+	}
 
-	return messages, total, err
+	query, page, err := paginate(query, chatSortColumns, "id", page)
+	if err != nil {
+		return nil, 0, models.PageInfo{}, err
+	}
+	if err := query.Find(&messages).Error; err != nil {
+		return nil, 0, models.PageInfo{}, err
+	}
+
+	count, info := pageInfo(len(messages), page, func(i int) (string, uint) {
+		return formatCursorTime(messages[i].CreatedAt), messages[i].ID
+	})
+	messages = messages[:count]
+	if err := r.attachChatAuthors(ctx, messages); err != nil {
+		return nil, 0, models.PageInfo{}, err
+	}
+
+	return messages, total, info, nil
 }
 
 // DeleteChatMessagesByInsightID deletes all chat messages for an insight.
 func (r *InsightRepository) DeleteChatMessagesByInsightID(ctx context.Context, insightID uint) error {
 	return r.db.WithContext(ctx).Where("insight_id = ?", insightID).Delete(&models.ChatMessage{}).Error
 }
+
+// --- Author helpers ---
+
+// attachHighlightAuthors fills in the Author of each highlight.
+func (r *InsightRepository) attachHighlightAuthors(ctx context.Context, highlights []models.Highlight) error {
+	ids := make([]uint, 0, len(highlights))
+	for _, h := range highlights {
+		ids = append(ids, h.UserID)
+	}
+	authors, err := userSummaries(r.db.WithContext(ctx), ids)
+	if err != nil {
+		return err
+	}
+	for i := range highlights {
+		if author, ok := authors[highlights[i].UserID]; ok {
+			highlights[i].Author = &author
+		}
+	}
+	return nil
+}
+
+// attachChatAuthors fills in the Author of each chat message.
+func (r *InsightRepository) attachChatAuthors(ctx context.Context, messages []models.ChatMessage) error {
+	ids := make([]uint, 0, len(messages))
+	for _, m := range messages {
+		ids = append(ids, m.UserID)
+	}
+	authors, err := userSummaries(r.db.WithContext(ctx), ids)
+	if err != nil {
+		return err
+	}
+	for i := range messages {
+		if author, ok := authors[messages[i].UserID]; ok {
+			messages[i].Author = &author
+		}
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"vibe-backend/internal/models"
+)
+
+// InsightRef is the minimal insight data needed for bulk permission checks.
+type InsightRef struct {
+	ID          uint
+	WorkspaceID *uint
+	UserID      uint
+}
+
+// LibraryRepository handles database operations for tags and collections.
+type LibraryRepository struct {
+	db *gorm.DB
+}
+
+// NewLibraryRepository creates a new LibraryRepository.
+func NewLibraryRepository(db *gorm.DB) *LibraryRepository {
+	return &LibraryRepository{db: db}
+}
+
+// --- Tag operations ---
+
+// CreateTag creates a new tag.
+func (r *LibraryRepository) CreateTag(ctx context.Context, tag *models.Tag) error {
+	return r.db.WithContext(ctx).Create(tag).Error
+}
+
+// GetTag returns a tag by ID.
+func (r *LibraryRepository) GetTag(ctx context.Context, id uint) (*models.Tag, error) {
+	var tag models.Tag
+	if err := r.db.WithContext(ctx).First(&tag, id).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetTagByName returns a workspace's tag by name, case-insensitively.
+func (r *LibraryRepository) GetTagByName(ctx context.Context, workspaceID uint, name string) (*models.Tag, error) {
+	var tag models.Tag
+	err := r.db.WithContext(ctx).
+		Where("workspace_id = ? AND LOWER(name) = LOWER(?)", workspaceID, name).
+		First(&tag).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// UpdateTag saves a tag.
+func (r *LibraryRepository) UpdateTag(ctx context.Context, tag *models.Tag) error {
+	return r.db.WithContext(ctx).Save(tag).Error
+}
+
+// DeleteTag removes a tag from all insights and deletes it.
+func (r *LibraryRepository) DeleteTag(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", id).Delete(&models.InsightTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Tag{}, id).Error
+	})
+}
+
+// ListTagsWithCounts returns a workspace's tags with the number of (non-deleted) insights per tag.
+func (r *LibraryRepository) ListTagsWithCounts(ctx context.Context, workspaceID uint) ([]models.TagWithCount, error) {
+	tags := make([]models.TagWithCount, 0)
+	err := r.db.WithContext(ctx).
+		Table("tags").
+		Select("tags.*, COUNT(insights.id) AS count").
+		Joins("LEFT JOIN insight_tags ON insight_tags.tag_id = tags.id").
+		Joins("LEFT JOIN insights ON insights.id = insight_tags.insight_id AND insights.deleted_at IS NULL").
+		Where("tags.workspace_id = ?", workspaceID).
+		Group("tags.id").
+		Order("tags.name ASC").
+		Scan(&tags).Error
+	return tags, err
+}
+
+// CountTagsInWorkspace returns how many of the given tags belong to the workspace.
+func (r *LibraryRepository) CountTagsInWorkspace(ctx context.Context, workspaceID uint, tagIDs []uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Tag{}).
+		Where("workspace_id = ? AND id IN ?", workspaceID, tagIDs).
+		Count(&count).Error
+	return count, err
+}
+
+// --- Collection operations ---
+
+// CreateCollection creates a new collection.
+func (r *LibraryRepository) CreateCollection(ctx context.Context, collection *models.Collection) error {
+	return r.db.WithContext(ctx).Create(collection).Error
+}
+
+// GetCollection returns a collection by ID.
+func (r *LibraryRepository) GetCollection(ctx context.Context, id uint) (*models.Collection, error) {
+	var collection models.Collection
+	if err := r.db.WithContext(ctx).First(&collection, id).Error; err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// UpdateCollection saves a collection.
+func (r *LibraryRepository) UpdateCollection(ctx context.Context, collection *models.Collection) error {
+	return r.db.WithContext(ctx).Save(collection).Error
+}
+
+// ListCollections returns all collections of a workspace in sibling order.
+func (r *LibraryRepository) ListCollections(ctx context.Context, workspaceID uint) ([]models.Collection, error) {
+	var collections []models.Collection
+	err := r.db.WithContext(ctx).
+		Where("workspace_id = ?", workspaceID).
+		Order("position ASC, id ASC").
+		Find(&collections).Error
+	return collections, err
+}
+
+// NextCollectionPosition returns the position after the last sibling under parentID.
+func (r *LibraryRepository) NextCollectionPosition(ctx context.Context, workspaceID uint, parentID *uint) (int, error) {
+	var max *int
+	query := r.db.WithContext(ctx).Model(&models.Collection{}).Where("workspace_id = ?", workspaceID)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	if err := query.Select("MAX(position)").Scan(&max).Error; err != nil {
+		return 0, err
+	}
+	if max == nil {
+		return 0, nil
+	}
+	return *max + 1, nil
+}
+
+// DeleteCollection deletes a collection. Its children and insights move up to its parent.
+func (r *LibraryRepository) DeleteCollection(ctx context.Context, collection *models.Collection) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Collection{}).
+			Where("parent_id = ?", collection.ID).
+			Update("parent_id", collection.ParentID).Error; err != nil {
+			return err
+		}
+		// Including trashed insights, so they are not restored into a deleted collection
+		if err := tx.Unscoped().Model(&models.Insight{}).
+			Where("collection_id = ?", collection.ID).
+			Update("collection_id", collection.ParentID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Collection{}, collection.ID).Error
+	})
+}
+
+// --- Library statistics ---
+
+// CollectionCounts returns the number of insights directly in each collection of a workspace.
+func (r *LibraryRepository) CollectionCounts(ctx context.Context, workspaceID uint) (map[uint]int64, error) {
+	var rows []struct {
+		CollectionID uint
+		Count        int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.Insight{}).
+		Select("collection_id, COUNT(*) AS count").
+		Where("workspace_id = ? AND collection_id IS NOT NULL", workspaceID).
+		Group("collection_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CollectionID] = row.Count
+	}
+	return counts, nil
+}
+
+// SourceTypeCounts returns the number of insights per source type in a workspace.
+func (r *LibraryRepository) SourceTypeCounts(ctx context.Context, workspaceID uint) (map[models.SourceType]int64, error) {
+	var rows []struct {
+		SourceType models.SourceType
+		Count      int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.Insight{}).
+		Select("source_type, COUNT(*) AS count").
+		Where("workspace_id = ?", workspaceID).
+		Group("source_type").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[models.SourceType]int64, len(rows))
+	for _, row := range rows {
+		counts[row.SourceType] = row.Count
+	}
+	return counts, nil
+}
+
+// CountUnfiled returns the number of insights in a workspace without a collection.
+func (r *LibraryRepository) CountUnfiled(ctx context.Context, workspaceID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Insight{}).
+		Where("workspace_id = ? AND collection_id IS NULL", workspaceID).
+		Count(&count).Error
+	return count, err
+}
+
+// --- Bulk insight operations ---
+
+// GetInsightRefs returns workspace and creator info for the given insights.
+func (r *LibraryRepository) GetInsightRefs(ctx context.Context, insightIDs []uint) ([]InsightRef, error) {
+	var refs []InsightRef
+	err := r.db.WithContext(ctx).Model(&models.Insight{}).
+		Select("id, workspace_id, user_id").
+		Where("id IN ?", insightIDs).
+		Scan(&refs).Error
+	return refs, err
+}
+
+// AddTags attaches tags to insights, ignoring pairs that already exist.
+func (r *LibraryRepository) AddTags(ctx context.Context, insightIDs, tagIDs []uint) error {
+	rows := make([]models.InsightTag, 0, len(insightIDs)*len(tagIDs))
+	for _, insightID := range insightIDs {
+		for _, tagID := range tagIDs {
+			rows = append(rows, models.InsightTag{InsightID: insightID, TagID: tagID})
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(rows, 500).Error
+}
+
+// RemoveTags detaches tags from insights.
+func (r *LibraryRepository) RemoveTags(ctx context.Context, insightIDs, tagIDs []uint) error {
+	if len(insightIDs) == 0 || len(tagIDs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Where("insight_id IN ? AND tag_id IN ?", insightIDs, tagIDs).
+		Delete(&models.InsightTag{}).Error
+}
+
+// MoveInsights sets the collection of the given insights (nil unfiles them).
+func (r *LibraryRepository) MoveInsights(ctx context.Context, insightIDs []uint, collectionID *uint) error {
+	return r.db.WithContext(ctx).Model(&models.Insight{}).
+		Where("id IN ?", insightIDs).
+		Update("collection_id", collectionID).Error
+}
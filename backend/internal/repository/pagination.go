@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"vibe-backend/internal/models"
+)
+
+// Pagination errors.
+var (
+	ErrInvalidCursor   = errors.New("invalid pagination cursor")
+	ErrUnsupportedSort = errors.New("unsupported sort")
+)
+
+// cursorKind is the type of a sort column's value, used to decode cursor values.
+type cursorKind int
+
+const (
+	cursorTime cursorKind = iota
+	cursorInt
+	cursorString
+)
+
+// sortColumn is the SQL expression behind a public sort key.
+type sortColumn struct {
+	expr string
+	kind cursorKind
+}
+
+// pageCursor is the decoded form of an opaque cursor: the sort it belongs to and the
+// sort value and ID of the last row of the previous page.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// encodeCursor returns the opaque string form of a cursor.
+func encodeCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses an opaque cursor.
+func decodeCursor(value string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// paginate applies keyset pagination to query: it orders by the sort column and the ID
+// column, skips past the cursor and fetches one extra row to detect further pages.
+// A cursor fixes the sort, so page.Sort and page.Desc are replaced by the cursor's.
+// It returns the resolved page request.
+func paginate(query *gorm.DB, columns map[string]sortColumn, idColumn string, page models.PageRequest) (*gorm.DB, models.PageRequest, error) {
+	if page.Cursor != "" {
+		cursor, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return nil, page, err
+		}
+		page.Sort, page.Desc = cursor.Sort, cursor.Desc
+
+		column, ok := columns[page.Sort]
+		if !ok {
+			return nil, page, ErrInvalidCursor
+		}
+		value, err := parseCursorValue(column.kind, cursor.Value)
+		if err != nil {
+			return nil, page, err
+		}
+
+		op := ">"
+		if page.Desc {
+			op = "<"
+		}
+		query = query.Where(fmt.Sprintf("(%s, %s) %s (?, ?)", column.expr, idColumn, op), value, cursor.ID)
+	}
+
+	column, ok := columns[page.Sort]
+	if !ok {
+		return nil, page, ErrUnsupportedSort
+	}
+	direction := "ASC"
+	if page.Desc {
+		direction = "DESC"
+	}
+	query = query.
+		Order(fmt.Sprintf("%s %s", column.expr, direction)).
+		Order(fmt.Sprintf("%s %s", idColumn, direction)).
+		Limit(page.Limit + 1)
+
+	return query, page, nil
+}
+
+// pageInfo trims the extra row fetched by paginate and builds the next cursor from the
+// last remaining row. cursorOf returns a row's sort value (already formatted) and ID.
+func pageInfo(count int, page models.PageRequest, cursorOf func(i int) (string, uint)) (int, models.PageInfo) {
+	info := models.PageInfo{Limit: page.Limit}
+	if count <= page.Limit {
+		return count, info
+	}
+
+	count = page.Limit
+	value, id := cursorOf(count - 1)
+	info.HasMore = true
+	info.NextCursor = encodeCursor(pageCursor{Sort: page.Sort, Desc: page.Desc, Value: value, ID: id})
+	return count, info
+}
+
+// parseCursorValue converts a cursor value to the type of its sort column.
+func parseCursorValue(kind cursorKind, value string) (interface{}, error) {
+	switch kind {
+	case cursorTime:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return t, nil
+	case cursorInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return n, nil
+	default:
+		return value, nil
+	}
+}
+
+// formatCursorTime formats a time as a cursor value.
+func formatCursorTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// applyDateRange restricts column to the range.
+func applyDateRange(query *gorm.DB, column string, dates models.DateRange) *gorm.DB {
+	if dates.From != nil {
+		query = query.Where(column+" >= ?", *dates.From)
+	}
+	if dates.To != nil {
+		query = query.Where(column+" < ?", *dates.To)
+	}
+	return query
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	_ "modernc.org/sqlite"
+	"vibe-backend/internal/models"
+)
+
+// pageRow is a table to paginate, with ties in both sort columns.
+type pageRow struct {
+	ID    uint
+	Title string
+	Views int
+}
+
+var pageColumns = map[string]sortColumn{
+	models.SortTitle: {expr: "title", kind: cursorString},
+	"views":          {expr: "views", kind: cursorInt},
+}
+
+// newPageDB returns an in-memory SQLite database holding the pageRow rows.
+func newPageDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	conn, err := sql.Open("sqlite", fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(sqlite.Dialector{DriverName: "sqlite", Conn: conn}, &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open gorm: %v", err)
+	}
+	if err := db.AutoMigrate(&pageRow{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	rows := []pageRow{
+		{ID: 1, Title: "b", Views: 10},
+		{ID: 2, Title: "a", Views: 20},
+		{ID: 3, Title: "b", Views: 20},
+		{ID: 4, Title: "c", Views: 10},
+		{ID: 5, Title: "a", Views: 10},
+	}
+	if err := db.Create(&rows).Error; err != nil {
+		t.Fatalf("create rows: %v", err)
+	}
+	return db
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursors := []pageCursor{
+		{Sort: models.SortCreated, Value: "2024-05-01T10:00:00.123456789Z", ID: 7},
+		{Sort: models.SortTitle, Desc: true, Value: "机器学习 & more", ID: 1},
+		{Sort: models.SortDuration, Value: "-3", ID: 42},
+		{},
+	}
+	for _, cursor := range cursors {
+		encoded := encodeCursor(cursor)
+		decoded, err := decodeCursor(encoded)
+		if err != nil {
+			t.Fatalf("decode %q: %v", encoded, err)
+		}
+		if *decoded != cursor {
+			t.Errorf("round trip = %+v, want %+v", *decoded, cursor)
+		}
+	}
+
+	invalid := []string{
+		"not base64!",
+		base64.StdEncoding.EncodeToString([]byte(`{"s":"title"}`)), // Padded, not URL encoding
+		base64.RawURLEncoding.EncodeToString([]byte("not json")),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"id":"seven"}`)),
+	}
+	for _, value := range invalid {
+		if _, err := decodeCursor(value); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decode %q: err = %v, want %v", value, err, ErrInvalidCursor)
+		}
+	}
+}
+
+func TestParseCursorValue(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 123456789, time.UTC)
+	tests := []struct {
+		name    string
+		kind    cursorKind
+		value   string
+		want    interface{}
+		wantErr error
+	}{
+		{name: "time", kind: cursorTime, value: formatCursorTime(created), want: created},
+		{name: "time in another zone", kind: cursorTime, value: "2024-05-01T12:00:00.123456789+02:00", want: created},
+		{name: "bad time", kind: cursorTime, value: "yesterday", wantErr: ErrInvalidCursor},
+		{name: "int", kind: cursorInt, value: "125", want: int64(125)},
+		{name: "bad int", kind: cursorInt, value: "1.5", wantErr: ErrInvalidCursor},
+		{name: "string", kind: cursorString, value: "Go 入门", want: "Go 入门"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCursorValue(tt.kind, tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if want, ok := tt.want.(time.Time); ok {
+				if gotTime, _ := got.(time.Time); !gotTime.Equal(want) {
+					t.Errorf("value = %v, want %v", got, want)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("value = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	db := newPageDB(t)
+
+	tests := []struct {
+		name string
+		sort string
+		desc bool
+		want []uint
+	}{
+		{name: "string ascending", sort: models.SortTitle, want: []uint{2, 5, 1, 3, 4}},
+		{name: "string descending", sort: models.SortTitle, desc: true, want: []uint{4, 3, 1, 5, 2}},
+		{name: "int ascending", sort: "views", want: []uint{1, 4, 5, 2, 3}},
+		{name: "int descending", sort: "views", desc: true, want: []uint{3, 2, 5, 4, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Pages of two split the ties in both columns, so the ID must break them
+			page := models.PageRequest{Limit: 2, Sort: tt.sort, Desc: tt.desc}
+			var got []uint
+			for pages := 0; ; pages++ {
+				if pages > len(tt.want) {
+					t.Fatalf("more pages than rows; got %v so far", got)
+				}
+				query, resolved, err := paginate(db.Model(&pageRow{}), pageColumns, "id", page)
+				if err != nil {
+					t.Fatalf("paginate: %v", err)
+				}
+				var rows []pageRow
+				if err := query.Find(&rows).Error; err != nil {
+					t.Fatalf("find: %v", err)
+				}
+				count, info := pageInfo(len(rows), resolved, func(i int) (string, uint) {
+					if resolved.Sort == "views" {
+						return strconv.Itoa(rows[i].Views), rows[i].ID
+					}
+					return rows[i].Title, rows[i].ID
+				})
+				for _, row := range rows[:count] {
+					got = append(got, row.ID)
+				}
+				if !info.HasMore {
+					if info.NextCursor != "" {
+						t.Errorf("last page has cursor %q", info.NextCursor)
+					}
+					break
+				}
+				// The cursor pins the sort, whatever the next request asks for
+				page = models.PageRequest{Cursor: info.NextCursor, Limit: 2, Sort: models.SortCreated}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginateErrors(t *testing.T) {
+	db := newPageDB(t)
+
+	tests := []struct {
+		name    string
+		page    models.PageRequest
+		wantErr error
+	}{
+		{name: "unsupported sort", page: models.PageRequest{Limit: 2, Sort: models.SortCreated}, wantErr: ErrUnsupportedSort},
+		{name: "malformed cursor", page: models.PageRequest{Limit: 2, Cursor: "%%%"}, wantErr: ErrInvalidCursor},
+		{name: "cursor for another list", page: models.PageRequest{Limit: 2, Cursor: encodeCursor(pageCursor{Sort: models.SortDue, Value: "x", ID: 1})}, wantErr: ErrInvalidCursor},
+		{name: "cursor value of the wrong type", page: models.PageRequest{Limit: 2, Cursor: encodeCursor(pageCursor{Sort: "views", Value: "many", ID: 1})}, wantErr: ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := paginate(db.Model(&pageRow{}), pageColumns, "id", tt.page); !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
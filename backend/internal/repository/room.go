@@ -2,12 +2,28 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"vibe-backend/internal/models"
 )
 
+var (
+	// ErrRoomFull is returned when a room already has as many online participants as it may.
+	ErrRoomFull = errors.New("room is full")
+	// ErrInviteUnusable is returned when a room invite does not exist, is for another
+	// room, or has expired, been used up or been revoked.
+	ErrInviteUnusable = errors.New("room invite is invalid or expired")
+)
+
+// RoomAdmission decides, under the room lock, whether a user joining a room is let in:
+// online, or waiting in the lobby. participant is nil on their first join; invited
+// reports whether they used a valid invite.
+type RoomAdmission func(room *models.Room, participant *models.Participant, invited bool) (models.ParticipantStatus, error)
+
 // RoomRepository handles database operations for rooms and participants.
 type RoomRepository struct {
 	db *gorm.DB
@@ -18,151 +34,437 @@ func NewRoomRepository(db *gorm.DB) *RoomRepository {
 	return &RoomRepository{db: db}
 }
 
-// GetOrCreateRoom gets an existing room or creates a new one.
-func (r *RoomRepository) GetOrCreateRoom(ctx context.Context, roomID string) (*models.Room, error) {
+// CreateRoom creates a new room.
+func (r *RoomRepository) CreateRoom(ctx context.Context, room *models.Room) error {
+	return r.db.WithContext(ctx).Create(room).Error
+}
+
+// GetRoom returns a room.
+func (r *RoomRepository) GetRoom(ctx context.Context, roomID string) (*models.Room, error) {
 	var room models.Room
-	err := r.db.WithContext(ctx).
-		Preload("Participants", "status = ?", "ONLINE").
-		Where("room_id = ?", roomID).
-		First(&room).Error
+	if err := r.db.WithContext(ctx).Where("room_id = ?", roomID).First(&room).Error; err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
 
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// Create new room
-			room = models.Room{
-				RoomID: roomID,
-				Status: "IDLE",
+// Join lets a user into a room as admit decides, or refreshes their presence if they
+// are already online, and moves the room to its next status. A room without an owner
+// (created before rooms had one, or whose owner deleted their account) goes to the
+// user. inviteHash, if set, is the hash of an invite to consume. The room row is
+// locked so concurrent joins cannot exceed its capacity (defaultCapacity unless the
+// room sets its own). It returns ErrRoomFull if the room has no space left, and
+// ErrInviteUnusable if the invite cannot be used.
+func (r *RoomRepository) Join(ctx context.Context, roomID string, userID uint, inviteHash string, defaultCapacity int, admit RoomAdmission) (*models.Room, *models.Participant, error) {
+	var room models.Room
+	var participant models.Participant
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("room_id = ?", roomID).
+			First(&room).Error; err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		err := tx.Where("room_id = ? AND user_id = ?", roomID, userID).First(&participant).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		found := err == nil
+
+		if found && participant.Status == models.ParticipantOnline {
+			participant.LastSeenAt = now
+			return tx.Model(&participant).Update("last_seen_at", now).Error
+		}
+
+		invited := false
+		if inviteHash != "" {
+			result := tx.Model(&models.RoomInvite{}).
+				Where("token_hash = ? AND room_id = ? AND revoked_at IS NULL AND expires_at > ?", inviteHash, roomID, now).
+				Where("max_uses = 0 OR uses < max_uses").
+				Update("uses", gorm.Expr("uses + 1"))
+			if result.Error != nil {
+				return result.Error
 			}
-			if err := r.db.WithContext(ctx).Create(&room).Error; err != nil {
-				return nil, err
+			if result.RowsAffected == 0 {
+				return ErrInviteUnusable
 			}
-			return &room, nil
+			invited = true
+		}
+		if room.OwnerID == nil {
+			if err := tx.Model(&room).Update("owner_id", userID).Error; err != nil {
+				return err
+			}
+			room.OwnerID = &userID
 		}
-		return nil, err
-	}
 
-	return &room, nil
-}
+		var existing *models.Participant
+		if found {
+			existing = &participant
+		}
+		status, err := admit(&room, existing, invited)
+		if err != nil {
+			return err
+		}
+		if found && participant.Status == models.ParticipantWaiting && status == models.ParticipantWaiting {
+			participant.LastSeenAt = now
+			return tx.Model(&participant).Update("last_seen_at", now).Error
+		}
+		if status == models.ParticipantOnline {
+			online, err := countOnline(tx, roomID)
+			if err != nil {
+				return err
+			}
+			if online >= int64(room.Capacity(defaultCapacity)) {
+				return ErrRoomFull
+			}
+		}
 
-// UpdateRoomStatus updates the room status.
-func (r *RoomRepository) UpdateRoomStatus(ctx context.Context, roomID string, status string) error {
-	return r.db.WithContext(ctx).
-		Model(&models.Room{}).
-		Where("room_id = ?", roomID).
-		Update("status", status).Error
+		role := models.RoomRoleMember
+		if room.IsOwner(userID) {
+			role = models.RoomRoleOwner
+		} else if found && participant.Role == models.RoomRoleModerator {
+			role = models.RoomRoleModerator
+		}
+		if found {
+			err = tx.Model(&participant).Updates(map[string]interface{}{
+				"status":       status,
+				"role":         role,
+				"joined_at":    now,
+				"last_seen_at": now,
+				"left_at":      nil,
+			}).Error
+		} else {
+			participant = models.Participant{
+				RoomID:     roomID,
+				UserID:     userID,
+				Status:     status,
+				Role:       role,
+				JoinedAt:   now,
+				LastSeenAt: now,
+			}
+			err = tx.Create(&participant).Error
+		}
+		if err != nil {
+			return err
+		}
+		return syncRoomStatus(tx, &room)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &room, &participant, nil
 }
 
-// AddParticipant adds a participant to a room.
-func (r *RoomRepository) AddParticipant(ctx context.Context, roomID, userID string) (*models.Participant, error) {
-	// Check if participant already exists
-	var existing models.Participant
-	err := r.db.WithContext(ctx).
-		Where("room_id = ? AND user_id = ? AND status = ?", roomID, userID, "ONLINE").
-		First(&existing).Error
+// Leave marks a participant who is online or waiting in the lobby offline and moves
+// the room to its next status. It returns the status they left, or "" if they were
+// not in the room.
+func (r *RoomRepository) Leave(ctx context.Context, roomID string, userID uint) (models.ParticipantStatus, error) {
+	var left models.ParticipantStatus
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var room models.Room
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("room_id = ?", roomID).
+			First(&room).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
 
-	if err == nil {
-		// Participant already exists and is online
-		return &existing, nil
-	}
+		var participant models.Participant
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("room_id = ? AND user_id = ? AND status IN ?", roomID, userID, presentStatuses).
+			First(&participant).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&participant).Updates(map[string]interface{}{
+			"status":  models.ParticipantOffline,
+			"left_at": time.Now().UTC(),
+		}).Error; err != nil {
+			return err
+		}
+		left = participant.Status
+		return syncRoomStatus(tx, &room)
+	})
+	return left, err
+}
 
-	if err != gorm.ErrRecordNotFound {
-		return nil, err
+// Touch records that a participant online or waiting in the lobby is still there and
+// returns their status, or "" if they are neither.
+func (r *RoomRepository) Touch(ctx context.Context, roomID string, userID uint) (models.ParticipantStatus, error) {
+	var participant models.Participant
+	result := r.db.WithContext(ctx).
+		Model(&participant).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "status"}}}).
+		Where("room_id = ? AND user_id = ? AND status IN ?", roomID, userID, presentStatuses).
+		Update("last_seen_at", time.Now().UTC())
+	if result.Error != nil || result.RowsAffected == 0 {
+		return "", result.Error
 	}
+	return participant.Status, nil
+}
 
-	// Create new participant
-	participant := models.Participant{
-		RoomID:   roomID,
-		UserID:   userID,
-		Status:   "ONLINE",
-		JoinedAt: time.Now().UTC(),
-	}
+// StaleRoomIDs returns the rooms with participants online or waiting in the lobby not
+// seen since before.
+func (r *RoomRepository) StaleRoomIDs(ctx context.Context, before time.Time) ([]string, error) {
+	var roomIDs []string
+	err := r.db.WithContext(ctx).
+		Model(&models.Participant{}).
+		Distinct("room_id").
+		Where("status IN ? AND last_seen_at < ?", presentStatuses, before).
+		Pluck("room_id", &roomIDs).Error
+	return roomIDs, err
+}
 
-	if err := r.db.WithContext(ctx).Create(&participant).Error; err != nil {
-		return nil, err
-	}
+// ExpireParticipants marks the participants of a room, online or waiting in the lobby,
+// not seen since before offline and moves the room to its next status. It returns the participants expired.
+func (r *RoomRepository) ExpireParticipants(ctx context.Context, roomID string, before time.Time) ([]models.Participant, error) {
+	var expired []models.Participant
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var room models.Room
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("room_id = ?", roomID).
+			First(&room).Error; err != nil {
+			return err
+		}
 
-	return &participant, nil
+		// Checked again under the lock: a heartbeat may have arrived meanwhile
+		if err := tx.Model(&expired).
+			Clauses(clause.Returning{}).
+			Where("room_id = ? AND status IN ? AND last_seen_at < ?", roomID, presentStatuses, before).
+			Updates(map[string]interface{}{
+				"status":  models.ParticipantOffline,
+				"left_at": time.Now().UTC(),
+			}).Error; err != nil {
+			return err
+		}
+		if len(expired) == 0 {
+			return nil
+		}
+		return syncRoomStatus(tx, &room)
+	})
+	return expired, err
 }
 
-// RemoveParticipant marks a participant as offline.
-func (r *RoomRepository) RemoveParticipant(ctx context.Context, roomID, userID string) error {
-	now := time.Now().UTC()
-	return r.db.WithContext(ctx).
-		Model(&models.Participant{}).
+// GetParticipant returns a user's participant record in a room, whatever its status.
+func (r *RoomRepository) GetParticipant(ctx context.Context, roomID string, userID uint) (*models.Participant, error) {
+	var participant models.Participant
+	err := r.db.WithContext(ctx).
 		Where("room_id = ? AND user_id = ?", roomID, userID).
-		Updates(map[string]interface{}{
-			"status":  "OFFLINE",
-			"left_at": now,
-		}).Error
+		First(&participant).Error
+	if err != nil {
+		return nil, err
+	}
+	return &participant, nil
 }
 
-// GetParticipants returns all online participants in a room.
-func (r *RoomRepository) GetParticipants(ctx context.Context, roomID string) ([]models.Participant, error) {
+// GetParticipants returns the participants of a room with the given status, with
+// their users, in the order they joined.
+func (r *RoomRepository) GetParticipants(ctx context.Context, roomID string, status models.ParticipantStatus) ([]models.Participant, error) {
 	var participants []models.Participant
 	err := r.db.WithContext(ctx).
-		Where("room_id = ? AND status = ?", roomID, "ONLINE").
+		Preload("User").
+		Where("room_id = ? AND status = ?", roomID, status).
+		Order("joined_at ASC, id ASC").
 		Find(&participants).Error
 	return participants, err
 }
 
-// SaveWebRTCOffer saves a WebRTC offer.
-func (r *RoomRepository) SaveWebRTCOffer(ctx context.Context, roomID, userID, sdp string) (*models.WebRTCSignal, error) {
-	signal := models.WebRTCSignal{
-		RoomID:     roomID,
-		UserID:     userID,
-		SignalType: "OFFER",
-		SDPOffer:   sdp,
-	}
+// SetParticipantStatus moves a participant from one status to another and the room
+// to its next status. Participants moved online count against the room's capacity
+// (defaultCapacity unless the room sets its own); removed ones lose their role and
+// mute. It returns gorm.ErrRecordNotFound if the participant is not in status from,
+// and ErrRoomFull if the room has no space left.
+func (r *RoomRepository) SetParticipantStatus(ctx context.Context, roomID string, userID uint, from, to models.ParticipantStatus, defaultCapacity int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var room models.Room
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("room_id = ?", roomID).
+			First(&room).Error; err != nil {
+			return err
+		}
 
-	if err := r.db.WithContext(ctx).Create(&signal).Error; err != nil {
-		return nil, err
-	}
+		if to == models.ParticipantOnline {
+			online, err := countOnline(tx, roomID)
+			if err != nil {
+				return err
+			}
+			if online >= int64(room.Capacity(defaultCapacity)) {
+				return ErrRoomFull
+			}
+		}
 
-	return &signal, nil
+		now := time.Now().UTC()
+		updates := map[string]interface{}{"status": to}
+		switch to {
+		case models.ParticipantOnline:
+			updates["joined_at"] = now
+			updates["last_seen_at"] = now
+		case models.ParticipantRemoved:
+			updates["left_at"] = now
+			updates["role"] = models.RoomRoleMember
+			updates["muted"] = false
+		default:
+			updates["left_at"] = now
+		}
+		result := tx.Model(&models.Participant{}).
+			Where("room_id = ? AND user_id = ? AND status = ?", roomID, userID, from).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return syncRoomStatus(tx, &room)
+	})
 }
 
-// GetLatestWebRTCOffer gets the latest WebRTC offer for a room/user.
-func (r *RoomRepository) GetLatestWebRTCOffer(ctx context.Context, roomID, userID string) (*models.WebRTCSignal, error) {
-	var signal models.WebRTCSignal
-	err := r.db.WithContext(ctx).
-		Where("room_id = ? AND user_id = ? AND signal_type = ?", roomID, userID, "OFFER").
-		Order("created_at DESC").
-		First(&signal).Error
+// SetParticipantMuted mutes or unmutes an online participant. It returns
+// gorm.ErrRecordNotFound if they are not online in the room.
+func (r *RoomRepository) SetParticipantMuted(ctx context.Context, roomID string, userID uint, muted bool) error {
+	return r.updateOnlineParticipant(ctx, roomID, userID, "muted", muted)
+}
 
-	if err != nil {
-		return nil, err
+// SetParticipantRole changes the role of an online participant. It returns
+// gorm.ErrRecordNotFound if they are not online in the room.
+func (r *RoomRepository) SetParticipantRole(ctx context.Context, roomID string, userID uint, role models.RoomRole) error {
+	return r.updateOnlineParticipant(ctx, roomID, userID, "role", role)
+}
+
+func (r *RoomRepository) updateOnlineParticipant(ctx context.Context, roomID string, userID uint, column string, value interface{}) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.Participant{}).
+		Where("room_id = ? AND user_id = ? AND status = ?", roomID, userID, models.ParticipantOnline).
+		Update(column, value)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
 	}
+	return nil
+}
 
-	return &signal, nil
+// CloseRoom marks everyone online or waiting in the lobby offline and closes the room.
+// It returns the participants who were online.
+func (r *RoomRepository) CloseRoom(ctx context.Context, roomID string) ([]models.Participant, error) {
+	var online []models.Participant
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var room models.Room
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("room_id = ?", roomID).
+			First(&room).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("room_id = ? AND status = ?", roomID, models.ParticipantOnline).
+			Find(&online).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Participant{}).
+			Where("room_id = ? AND status IN ?", roomID, presentStatuses).
+			Updates(map[string]interface{}{
+				"status":  models.ParticipantOffline,
+				"left_at": time.Now().UTC(),
+			}).Error; err != nil {
+			return err
+		}
+		if room.Status == models.RoomClosed {
+			return nil
+		}
+		if !room.Status.CanTransitionTo(models.RoomClosed) {
+			return fmt.Errorf("room %s cannot move from %s to %s", room.RoomID, room.Status, models.RoomClosed)
+		}
+		return tx.Model(&room).Update("status", models.RoomClosed).Error
+	})
+	return online, err
 }
 
-// SaveWebRTCAnswer saves a WebRTC answer.
-func (r *RoomRepository) SaveWebRTCAnswer(ctx context.Context, signalID uint, sdpAnswer string) error {
+// UpdateSettings changes the given columns of a room.
+func (r *RoomRepository) UpdateSettings(ctx context.Context, roomID string, updates map[string]interface{}) error {
 	return r.db.WithContext(ctx).
-		Model(&models.WebRTCSignal{}).
-		Where("id = ?", signalID).
-		Update("sdp_answer", sdpAnswer).Error
-}
-
-// SaveICECandidate saves an ICE candidate.
-func (r *RoomRepository) SaveICECandidate(ctx context.Context, roomID, userID, candidate string) error {
-	signal := models.WebRTCSignal{
-		RoomID:       roomID,
-		UserID:       userID,
-		SignalType:   "ICE_CANDIDATE",
-		ICECandidate: candidate,
-	}
+		Model(&models.Room{}).
+		Where("room_id = ?", roomID).
+		Updates(updates).Error
+}
+
+// --- Invite operations ---
 
-	return r.db.WithContext(ctx).Create(&signal).Error
+// CreateInvite creates a new room invite.
+func (r *RoomRepository) CreateInvite(ctx context.Context, invite *models.RoomInvite) error {
+	return r.db.WithContext(ctx).Create(invite).Error
+}
+
+// GetInvite returns a room invite by ID.
+func (r *RoomRepository) GetInvite(ctx context.Context, id uint) (*models.RoomInvite, error) {
+	var invite models.RoomInvite
+	if err := r.db.WithContext(ctx).First(&invite, id).Error; err != nil {
+		return nil, err
+	}
+	return &invite, nil
 }
 
-// GetICECandidates gets all ICE candidates for a room/user.
-func (r *RoomRepository) GetICECandidates(ctx context.Context, roomID, userID string) ([]models.WebRTCSignal, error) {
-	var signals []models.WebRTCSignal
+// ListActiveInvites returns a room's invites that can still be used, newest first.
+func (r *RoomRepository) ListActiveInvites(ctx context.Context, roomID string) ([]models.RoomInvite, error) {
+	var invites []models.RoomInvite
 	err := r.db.WithContext(ctx).
-		Where("room_id = ? AND user_id = ? AND signal_type = ?", roomID, userID, "ICE_CANDIDATE").
-		Order("created_at ASC").
-		Find(&signals).Error
+		Where("room_id = ? AND revoked_at IS NULL AND expires_at > ?", roomID, time.Now().UTC()).
+		Where("max_uses = 0 OR uses < max_uses").
+		Order("created_at DESC, id DESC").
+		Find(&invites).Error
+	return invites, err
+}
+
+// RevokeInvite revokes a room invite. It returns gorm.ErrRecordNotFound if the invite
+// was already revoked.
+func (r *RoomRepository) RevokeInvite(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.RoomInvite{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now().UTC())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// presentStatuses are the statuses of participants who are in a room, including its lobby.
+var presentStatuses = []models.ParticipantStatus{models.ParticipantOnline, models.ParticipantWaiting}
 
-	return signals, err
+func countOnline(tx *gorm.DB, roomID string) (int64, error) {
+	var online int64
+	err := tx.Model(&models.Participant{}).
+		Where("room_id = ? AND status = ?", roomID, models.ParticipantOnline).
+		Count(&online).Error
+	return online, err
+}
+
+// syncRoomStatus moves a locked room to the status matching its online participants.
+func syncRoomStatus(tx *gorm.DB, room *models.Room) error {
+	online, err := countOnline(tx, room.RoomID)
+	if err != nil {
+		return err
+	}
+	next := room.Status.Next(online)
+	if next == room.Status {
+		return nil
+	}
+	if !room.Status.CanTransitionTo(next) {
+		return fmt.Errorf("room %s cannot move from %s to %s", room.RoomID, room.Status, next)
+	}
+	if err := tx.Model(room).Update("status", next).Error; err != nil {
+		return err
+	}
+	room.Status = next
+	return nil
 }
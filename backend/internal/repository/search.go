@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"vibe-backend/internal/models"
+)
+
+// SearchDocument is a search entry to index together with its pre-tokenized terms
+// and tsvector weight ("A" to "D").
+type SearchDocument struct {
+	Entry  models.SearchEntry
+	Terms  string
+	Weight string
+}
+
+// SearchHit is a ranked insight in a search.
+type SearchHit struct {
+	InsightID uint
+	Rank      float64
+}
+
+// SearchMatchRow is a ranked search entry.
+type SearchMatchRow struct {
+	models.SearchEntry
+	Rank float64
+}
+
+// SearchRepository handles the full-text search index.
+type SearchRepository struct {
+	db *gorm.DB
+}
+
+// NewSearchRepository creates a new SearchRepository.
+func NewSearchRepository(db *gorm.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+// Replace deletes an insight's entries of the given kinds (only those for refID, if set)
+// and indexes docs in their place.
+func (r *SearchRepository) Replace(ctx context.Context, insightID uint, kinds []models.SearchKind, refID *uint, docs []SearchDocument) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("insight_id = ? AND kind IN ?", insightID, kinds)
+		if refID != nil {
+			query = query.Where("ref_id = ?", *refID)
+		}
+		if err := query.Delete(&models.SearchEntry{}).Error; err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		rows := make([]map[string]interface{}, 0, len(docs))
+		for _, doc := range docs {
+			rows = append(rows, map[string]interface{}{
+				"insight_id": doc.Entry.InsightID,
+				"kind":       doc.Entry.Kind,
+				"ref_id":     doc.Entry.RefID,
+				"seconds":    doc.Entry.Seconds,
+				"timestamp":  doc.Entry.Timestamp,
+				"text":       doc.Entry.Text,
+				"document": clause.Expr{
+					SQL:  "setweight(to_tsvector('simple', ?), ?::\"char\")",
+					Vars: []interface{}{doc.Terms, doc.Weight},
+				},
+				"created_at": gorm.Expr("NOW()"),
+			})
+		}
+		for start := 0; start < len(rows); start += 200 {
+			end := start + 200
+			if end > len(rows) {
+				end = len(rows)
+			}
+			if err := tx.Model(&models.SearchEntry{}).Create(rows[start:end]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteByRef deletes the entries of one highlight or chat message.
+func (r *SearchRepository) DeleteByRef(ctx context.Context, kind models.SearchKind, refID uint) error {
+	return r.db.WithContext(ctx).
+		Where("kind = ? AND ref_id = ?", kind, refID).
+		Delete(&models.SearchEntry{}).Error
+}
+
+// DeleteByInsight deletes an insight's entries of the given kinds, or all of them if none are given.
+func (r *SearchRepository) DeleteByInsight(ctx context.Context, insightID uint, kinds ...models.SearchKind) error {
+	query := r.db.WithContext(ctx).Where("insight_id = ?", insightID)
+	if len(kinds) > 0 {
+		query = query.Where("kind IN ?", kinds)
+	}
+	return query.Delete(&models.SearchEntry{}).Error
+}
+
+// Search ranks a workspace's insights against a tsquery. An insight scores its best
+// entry's rank plus a small boost per additional matching entry.
+func (r *SearchRepository) Search(ctx context.Context, workspaceID uint, tsquery string, limit, offset int) ([]SearchHit, int64, error) {
+	base := r.db.WithContext(ctx).
+		Table("search_entries AS e").
+		Joins("JOIN insights AS i ON i.id = e.insight_id AND i.deleted_at IS NULL").
+		Where("i.workspace_id = ?", workspaceID).
+		Where("e.document @@ to_tsquery('simple', ?)", tsquery)
+
+	var total int64
+	if err := base.Session(&gorm.Session{}).Distinct("e.insight_id").Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]SearchHit, 0)
+	err := base.Session(&gorm.Session{}).
+		Select("e.insight_id, MAX(ts_rank(e.document, to_tsquery('simple', ?))) + 0.01 * LEAST(COUNT(*) - 1, 10) AS rank", tsquery).
+		Group("e.insight_id").
+		Order("rank DESC, e.insight_id DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return hits, total, nil
+}
+
+// TopMatches returns up to perInsight best-ranked matching entries of each insight.
+func (r *SearchRepository) TopMatches(ctx context.Context, insightIDs []uint, tsquery string, perInsight int) ([]SearchMatchRow, error) {
+	rows := make([]SearchMatchRow, 0)
+	if len(insightIDs) == 0 {
+		return rows, nil
+	}
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT * FROM (
+			SELECT e.*, ts_rank(e.document, q.query) AS rank,
+				ROW_NUMBER() OVER (PARTITION BY e.insight_id ORDER BY ts_rank(e.document, q.query) DESC, e.id) AS row_num
+			FROM search_entries e, to_tsquery('simple', ?) AS q(query)
+			WHERE e.insight_id IN ? AND e.document @@ q.query
+		) ranked
+		WHERE row_num <= ?
+		ORDER BY insight_id, rank DESC`,
+		tsquery, insightIDs, perInsight,
+	).Scan(&rows).Error
+	return rows, err
+}
+
+// InsightIDsWithoutEntries returns up to limit insights (after afterID) that have no search entries yet.
+func (r *SearchRepository) InsightIDsWithoutEntries(ctx context.Context, afterID uint, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.Insight{}).
+		Where("id > ?", afterID).
+		Where("NOT EXISTS (SELECT 1 FROM search_entries e WHERE e.insight_id = insights.id)").
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"vibe-backend/internal/models"
+)
+
+// securityEventSortColumns are the sort keys of the security log.
+var securityEventSortColumns = map[string]sortColumn{
+	models.SortCreated: {expr: "created_at", kind: cursorTime},
+}
+
+// SecurityEventRepository handles database operations for security events.
+type SecurityEventRepository struct {
+	db *gorm.DB
+}
+
+// NewSecurityEventRepository creates a new SecurityEventRepository.
+func NewSecurityEventRepository(db *gorm.DB) *SecurityEventRepository {
+	return &SecurityEventRepository{db: db}
+}
+
+// Create inserts a new security event.
+func (r *SecurityEventRepository) Create(ctx context.Context, event *models.SecurityEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// ListPage returns a page of a user's security events.
+func (r *SecurityEventRepository) ListPage(ctx context.Context, userID uint, page models.PageRequest) ([]models.SecurityEvent, models.PageInfo, error) {
+	query := r.db.WithContext(ctx).Model(&models.SecurityEvent{}).Where("user_id = ?", userID)
+
+	query, page, err := paginate(query, securityEventSortColumns, "id", page)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	var events []models.SecurityEvent
+	if err := query.Find(&events).Error; err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	count, info := pageInfo(len(events), page, func(i int) (string, uint) {
+		return formatCursorTime(events[i].CreatedAt), events[i].ID
+	})
+	return events[:count], info, nil
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"vibe-backend/internal/models"
+)
+
+// ShareRepository handles database operations for share links and their views.
+type ShareRepository struct {
+	db *gorm.DB
+}
+
+// NewShareRepository creates a new ShareRepository.
+func NewShareRepository(db *gorm.DB) *ShareRepository {
+	return &ShareRepository{db: db}
+}
+
+// Create creates a new share link.
+func (r *ShareRepository) Create(ctx context.Context, link *models.ShareLink) error {
+	return r.db.WithContext(ctx).Create(link).Error
+}
+
+// GetByID returns a share link by ID.
+func (r *ShareRepository) GetByID(ctx context.Context, id uint) (*models.ShareLink, error) {
+	var link models.ShareLink
+	if err := r.db.WithContext(ctx).First(&link, id).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// GetByToken returns a share link by token.
+func (r *ShareRepository) GetByToken(ctx context.Context, token string) (*models.ShareLink, error) {
+	var link models.ShareLink
+	if err := r.db.WithContext(ctx).Where("token = ?", token).First(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// ListByInsight returns all share links of an insight, newest first.
+func (r *ShareRepository) ListByInsight(ctx context.Context, insightID uint) ([]models.ShareLink, error) {
+	var links []models.ShareLink
+	err := r.db.WithContext(ctx).
+		Where("insight_id = ?", insightID).
+		Order("created_at DESC").
+		Find(&links).Error
+	return links, err
+}
+
+// Update saves a share link.
+func (r *ShareRepository) Update(ctx context.Context, link *models.ShareLink) error {
+	return r.db.WithContext(ctx).Save(link).Error
+}
+
+// RevokeAllForInsight revokes every active share link of an insight and clears its
+// legacy share link.
+func (r *ShareRepository) RevokeAllForInsight(ctx context.Context, insightID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ShareLink{}).
+			Where("insight_id = ? AND revoked_at IS NULL", insightID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return setLegacyLink(tx, insightID, nil)
+	})
+}
+
+// SetLegacyLink points the insight's legacy share token at a share link, or clears it
+// when token is nil.
+func (r *ShareRepository) SetLegacyLink(ctx context.Context, insightID uint, token *string) error {
+	return setLegacyLink(r.db.WithContext(ctx), insightID, token)
+}
+
+// setLegacyLink updates the legacy share columns of an insight.
+func setLegacyLink(db *gorm.DB, insightID uint, token *string) error {
+	updates := map[string]interface{}{
+		"share_token":    token,
+		"share_password": "",
+		"is_public":      token != nil,
+	}
+	if token != nil {
+		updates["shared_at"] = time.Now()
+	}
+	return db.Model(&models.Insight{}).Where("id = ?", insightID).Updates(updates).Error
+}
+
+// RecordView counts a view of a link and stores it for analytics. The count is only
+// incremented while the link is under its view limit; ok is false if the limit was
+// already reached.
+func (r *ShareRepository) RecordView(ctx context.Context, view *models.ShareLinkView) (bool, error) {
+	counted := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ShareLink{}).
+			Where("id = ? AND (max_views IS NULL OR view_count < max_views)", view.ShareLinkID).
+			Updates(map[string]interface{}{
+				"view_count":     gorm.Expr("view_count + 1"),
+				"last_viewed_at": view.ViewedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		counted = true
+		return tx.Create(view).Error
+	})
+	return counted, err
+}
+
+// Stats returns the view analytics of a link; daily counts cover views since `since`.
+func (r *ShareRepository) Stats(ctx context.Context, linkID uint, since time.Time) (*models.ShareLinkStats, error) {
+	db := r.db.WithContext(ctx)
+	stats := &models.ShareLinkStats{
+		ShareLinkID: linkID,
+		Referrers:   make([]models.ReferrerCount, 0),
+		Daily:       make([]models.DailyViewCount, 0),
+	}
+
+	var totals struct {
+		Views          int64
+		UniqueVisitors int64
+		LastViewedAt   *time.Time
+	}
+	err := db.Model(&models.ShareLinkView{}).
+		Select("COUNT(*) AS views, COUNT(DISTINCT visitor_hash) AS unique_visitors, MAX(viewed_at) AS last_viewed_at").
+		Where("share_link_id = ?", linkID).
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	stats.Views, stats.UniqueVisitors, stats.LastViewedAt = totals.Views, totals.UniqueVisitors, totals.LastViewedAt
+
+	err = db.Model(&models.ShareLinkView{}).
+		Select("referer_host AS host, COUNT(*) AS count").
+		Where("share_link_id = ?", linkID).
+		Group("referer_host").
+		Order("count DESC").
+		Limit(20).
+		Scan(&stats.Referrers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Model(&models.ShareLinkView{}).
+		Select("TO_CHAR(viewed_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS date, COUNT(*) AS count").
+		Where("share_link_id = ? AND viewed_at >= ?", linkID, since).
+		Group("1").
+		Order("1 ASC").
+		Scan(&stats.Daily).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// GetCreatorName returns the display name of a link's creator, or "" if unknown.
+func (r *ShareRepository) GetCreatorName(ctx context.Context, userID uint) (string, error) {
+	summaries, err := userSummaries(r.db.WithContext(ctx), []uint{userID})
+	if err != nil {
+		return "", err
+	}
+	return summaries[userID].Name, nil
+}
+
+// MigrateLegacyShares copies the single share token stored on insights into share
+// links, keeping the token so existing URLs keep working. It is safe to run repeatedly.
+func (r *ShareRepository) MigrateLegacyShares(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`
+		INSERT INTO share_links (insight_id, created_by, token, label, config, password_hash, view_count, created_at, updated_at)
+		SELECT i.id, i.user_id, i.share_token, '', i.share_config, COALESCE(i.share_password, ''), 0,
+			COALESCE(i.shared_at, NOW()), NOW()
+		FROM insights i
+		WHERE i.share_token IS NOT NULL AND i.share_token <> '' AND i.is_public AND i.deleted_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM share_links s WHERE s.token = i.share_token)`)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"vibe-backend/internal/models"
+)
+
+// usageDailySelect aggregates ledger entries per UTC day.
+const usageDailySelect = `to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS date,
+	COUNT(*) AS requests,
+	COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens,
+	COALESCE(SUM(completion_tokens), 0) AS completion_tokens,
+	COALESCE(SUM(cost_usd), 0) AS cost_usd`
+
+// UsageRepository handles database operations for the LLM usage ledger.
+type UsageRepository struct {
+	db *gorm.DB
+}
+
+// NewUsageRepository creates a new UsageRepository.
+func NewUsageRepository(db *gorm.DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// Create appends an entry to the ledger.
+func (r *UsageRepository) Create(ctx context.Context, entry *models.LLMUsage) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// CostSince returns the cost of the entries created since a time. A non-nil userID
+// restricts it to one user, a non-empty feature to one feature.
+func (r *UsageRepository) CostSince(ctx context.Context, since time.Time, userID *uint, feature models.LLMFeature) (float64, error) {
+	query := r.db.WithContext(ctx).Model(&models.LLMUsage{}).Where("created_at >= ?", since)
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+	if feature != "" {
+		query = query.Where("feature = ?", feature)
+	}
+
+	var cost float64
+	err := query.Select("COALESCE(SUM(cost_usd), 0)").Scan(&cost).Error
+	return cost, err
+}
+
+// DailyByFeature aggregates the entries created in [from, to) per day and feature.
+// A non-nil userID restricts it to one user.
+func (r *UsageRepository) DailyByFeature(ctx context.Context, from, to time.Time, userID *uint) ([]models.UsageDailyAggregate, error) {
+	query := r.db.WithContext(ctx).Model(&models.LLMUsage{}).
+		Where("created_at >= ? AND created_at < ?", from, to)
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	var days []models.UsageDailyAggregate
+	err := query.
+		Select(usageDailySelect + ", feature").
+		Group("date, feature").
+		Order("date, feature").
+		Scan(&days).Error
+	return days, err
+}
+
+// DailyByUser aggregates the entries created in [from, to) per day and user.
+// Anonymous usage is reported with a nil user.
+func (r *UsageRepository) DailyByUser(ctx context.Context, from, to time.Time) ([]models.UsageDailyAggregate, error) {
+	var days []models.UsageDailyAggregate
+	err := r.db.WithContext(ctx).Model(&models.LLMUsage{}).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Select(usageDailySelect + ", user_id").
+		Group("date, user_id").
+		Order("date, cost_usd DESC").
+		Scan(&days).Error
+	return days, err
+}
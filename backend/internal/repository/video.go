@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"strconv"
 
 	"gorm.io/gorm"
 	"vibe-backend/internal/models"
@@ -60,19 +61,45 @@ func (r *VideoRepository) UpdateAnalysis(ctx context.Context, analysis *models.V
 	return r.db.WithContext(ctx).Save(analysis).Error
 }
 
-// GetHistoryByUserID returns all video analyses for a user.
-func (r *VideoRepository) GetHistoryByUserID(ctx context.Context, userID uint, limit int) ([]models.VideoAnalysis, error) {
+// historySortColumns are the sort keys of the video analysis history.
+var historySortColumns = map[string]sortColumn{
+	models.SortCreated:  {expr: "created_at", kind: cursorTime},
+	models.SortDuration: {expr: "duration", kind: cursorInt},
+	models.SortTitle:    {expr: "COALESCE(title, '')", kind: cursorString},
+}
+
+// GetHistoryByUserID returns one keyset-paginated page of a user's analysis history.
+func (r *VideoRepository) GetHistoryByUserID(ctx context.Context, userID uint, filter models.HistoryFilter, page models.PageRequest) ([]models.VideoAnalysis, models.PageInfo, error) {
 	var analyses []models.VideoAnalysis
+
+	status := filter.Status
+	if status == "" {
+		status = "completed"
+	}
 	query := r.db.WithContext(ctx).
-		Where("user_id = ? AND status = ?", userID, "completed").
-		Order("created_at DESC")
+		Where("user_id = ? AND status = ?", userID, status)
+	query = applyDateRange(query, "created_at", filter.Created)
 
-	if limit > 0 {
-		query = query.Limit(limit)
+	query, page, err := paginate(query, historySortColumns, "id", page)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+	if err := query.Find(&analyses).Error; err != nil {
+		return nil, models.PageInfo{}, err
 	}
 
-	err := query.Find(&analyses).Error
-	return analyses, err
+	count, info := pageInfo(len(analyses), page, func(i int) (string, uint) {
+		a := &analyses[i]
+		switch page.Sort {
+		case models.SortDuration:
+			return strconv.Itoa(a.Duration), a.ID
+		case models.SortTitle:
+			return a.Title, a.ID
+		default:
+			return formatCursorTime(a.CreatedAt), a.ID
+		}
+	})
+	return analyses[:count], info, nil
 }
 
 // CreateChapters creates multiple chapter records.
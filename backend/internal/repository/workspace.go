@@ -0,0 +1,268 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"vibe-backend/internal/models"
+)
+
+// WorkspaceWithRole is a workspace joined with the caller's membership role.
+type WorkspaceWithRole struct {
+	models.Workspace
+	Role models.WorkspaceRole
+}
+
+// WorkspaceRepository handles database operations for workspaces, members and invitations.
+type WorkspaceRepository struct {
+	db *gorm.DB
+}
+
+// NewWorkspaceRepository creates a new WorkspaceRepository.
+func NewWorkspaceRepository(db *gorm.DB) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db}
+}
+
+// CreateWithOwner creates a workspace and makes its owner a member.
+func (r *WorkspaceRepository) CreateWithOwner(ctx context.Context, workspace *models.Workspace) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(workspace).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.WorkspaceMember{
+			WorkspaceID: workspace.ID,
+			UserID:      workspace.OwnerID,
+			Role:        models.WorkspaceRoleOwner,
+		}).Error
+	})
+}
+
+// GetByID returns a workspace by ID.
+func (r *WorkspaceRepository) GetByID(ctx context.Context, id uint) (*models.Workspace, error) {
+	var workspace models.Workspace
+	if err := r.db.WithContext(ctx).First(&workspace, id).Error; err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// GetPersonal returns the personal workspace of a user.
+func (r *WorkspaceRepository) GetPersonal(ctx context.Context, userID uint) (*models.Workspace, error) {
+	var workspace models.Workspace
+	err := r.db.WithContext(ctx).
+		Where("owner_id = ? AND personal = ?", userID, true).
+		First(&workspace).Error
+	if err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// ListForUser returns the workspaces a user belongs to, personal workspace first.
+func (r *WorkspaceRepository) ListForUser(ctx context.Context, userID uint) ([]WorkspaceWithRole, error) {
+	var rows []WorkspaceWithRole
+	err := r.db.WithContext(ctx).
+		Table("workspaces").
+		Select("workspaces.*, workspace_members.role AS role").
+		Joins("JOIN workspace_members ON workspace_members.workspace_id = workspaces.id").
+		Where("workspace_members.user_id = ? AND workspaces.deleted_at IS NULL", userID).
+		Order("workspaces.personal DESC, workspaces.name ASC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// Update saves a workspace.
+func (r *WorkspaceRepository) Update(ctx context.Context, workspace *models.Workspace) error {
+	return r.db.WithContext(ctx).Save(workspace).Error
+}
+
+// Delete removes memberships and invitations and soft-deletes the workspace.
+func (r *WorkspaceRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("workspace_id = ?", id).Delete(&models.WorkspaceMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workspace_id = ?", id).Delete(&models.WorkspaceInvitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Workspace{}, id).Error
+	})
+}
+
+// CountInsights returns the number of insights owned by a workspace.
+func (r *WorkspaceRepository) CountInsights(ctx context.Context, workspaceID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Insight{}).Where("workspace_id = ?", workspaceID).Count(&count).Error
+	return count, err
+}
+
+// AssignLegacyInsights moves a user's insights without a workspace into the given workspace.
+func (r *WorkspaceRepository) AssignLegacyInsights(ctx context.Context, userID, workspaceID uint) error {
+	return r.db.WithContext(ctx).Model(&models.Insight{}).
+		Where("user_id = ? AND workspace_id IS NULL", userID).
+		Update("workspace_id", workspaceID).Error
+}
+
+// --- Member operations ---
+
+// GetMember returns a user's membership in a workspace.
+func (r *WorkspaceRepository) GetMember(ctx context.Context, workspaceID, userID uint) (*models.WorkspaceMember, error) {
+	var member models.WorkspaceMember
+	err := r.db.WithContext(ctx).
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// ListMembers returns the members of a workspace with their user details.
+func (r *WorkspaceRepository) ListMembers(ctx context.Context, workspaceID uint) ([]models.WorkspaceMemberResponse, error) {
+	var members []models.WorkspaceMemberResponse
+	err := r.db.WithContext(ctx).
+		Table("workspace_members").
+		Select("workspace_members.user_id, users.name, users.email, workspace_members.role, workspace_members.created_at AS joined_at").
+		Joins("JOIN users ON users.id = workspace_members.user_id").
+		Where("workspace_members.workspace_id = ?", workspaceID).
+		Order("workspace_members.created_at ASC").
+		Scan(&members).Error
+	return members, err
+}
+
+// UpdateMemberRole changes a member's role.
+func (r *WorkspaceRepository) UpdateMemberRole(ctx context.Context, workspaceID, userID uint, role models.WorkspaceRole) error {
+	result := r.db.WithContext(ctx).Model(&models.WorkspaceMember{}).
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RemoveMember removes a user from a workspace.
+func (r *WorkspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID uint) error {
+	result := r.db.WithContext(ctx).
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		Delete(&models.WorkspaceMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CountOwners returns the number of owners of a workspace.
+func (r *WorkspaceRepository) CountOwners(ctx context.Context, workspaceID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.WorkspaceMember{}).
+		Where("workspace_id = ? AND role = ?", workspaceID, models.WorkspaceRoleOwner).
+		Count(&count).Error
+	return count, err
+}
+
+// --- Invitation operations ---
+
+// CreateInvitation creates a new invitation.
+func (r *WorkspaceRepository) CreateInvitation(ctx context.Context, invitation *models.WorkspaceInvitation) error {
+	return r.db.WithContext(ctx).Create(invitation).Error
+}
+
+// GetInvitationByTokenHash returns an invitation by the hash of its token.
+func (r *WorkspaceRepository) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*models.WorkspaceInvitation, error) {
+	var invitation models.WorkspaceInvitation
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&invitation).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// ListPendingInvitations returns unaccepted, unexpired invitations for a workspace.
+func (r *WorkspaceRepository) ListPendingInvitations(ctx context.Context, workspaceID uint) ([]models.WorkspaceInvitation, error) {
+	var invitations []models.WorkspaceInvitation
+	err := r.db.WithContext(ctx).
+		Where("workspace_id = ? AND accepted_at IS NULL AND expires_at > ?", workspaceID, time.Now()).
+		Order("created_at DESC").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+// DeleteInvitation revokes an invitation of a workspace.
+func (r *WorkspaceRepository) DeleteInvitation(ctx context.Context, workspaceID, invitationID uint) error {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND workspace_id = ?", invitationID, workspaceID).
+		Delete(&models.WorkspaceInvitation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation marks an invitation accepted and adds the user as a member.
+// An existing membership keeps the higher of its current and the invited role.
+func (r *WorkspaceRepository) AcceptInvitation(ctx context.Context, invitation *models.WorkspaceInvitation, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.WorkspaceInvitation{}).
+			Where("id = ? AND accepted_at IS NULL", invitation.ID).
+			Update("accepted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var existing models.WorkspaceMember
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("workspace_id = ? AND user_id = ?", invitation.WorkspaceID, userID).
+			First(&existing).Error
+		if err == nil {
+			if invitation.Role.Rank() > existing.Role.Rank() {
+				return tx.Model(&existing).Update("role", invitation.Role).Error
+			}
+			return nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		return tx.Create(&models.WorkspaceMember{
+			WorkspaceID: invitation.WorkspaceID,
+			UserID:      userID,
+			Role:        invitation.Role,
+		}).Error
+	})
+}
+
+// GetUserSummaries returns id/name pairs for the given users.
+func (r *WorkspaceRepository) GetUserSummaries(ctx context.Context, userIDs []uint) (map[uint]models.UserSummary, error) {
+	return userSummaries(r.db.WithContext(ctx), userIDs)
+}
+
+// userSummaries loads id/name pairs for the given users.
+func userSummaries(db *gorm.DB, userIDs []uint) (map[uint]models.UserSummary, error) {
+	result := make(map[uint]models.UserSummary, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+	var rows []models.UserSummary
+	if err := db.Table("users").Select("id, name").Where("id IN ?", userIDs).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.ID] = row
+	}
+	return result, nil
+}
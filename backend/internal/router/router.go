@@ -1,15 +1,21 @@
 package router
 
 import (
+	"context"
+
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.uber.org/zap"
 	"vibe-backend/internal/cache"
 	"vibe-backend/internal/config"
 	"vibe-backend/internal/database"
 	"vibe-backend/internal/handlers"
+	"vibe-backend/internal/metrics"
 	"vibe-backend/internal/middleware"
 	"vibe-backend/internal/repository"
 	"vibe-backend/internal/services"
+	"vibe-backend/internal/telemetry"
 )
 
 // New creates and configures a new Gin router.
@@ -22,8 +28,17 @@ func New(cfg *config.Config, db *database.PostgresDB, cache *cache.RedisCache, l
 	// Create router
 	r := gin.New()
 
-	// Global middleware
+	// Global middleware; tracing comes first so the request ID lands on the span
+	r.Use(otelgin.Middleware(telemetry.ServiceName, otelgin.WithGinFilter(func(c *gin.Context) bool {
+		// Probes and scrapes would drown out real traffic
+		switch c.FullPath() {
+		case "/health", "/ready", "/metrics":
+			return false
+		}
+		return true
+	})))
 	r.Use(middleware.RequestID())
+	r.Use(middleware.Metrics())
 	r.Use(middleware.Logger(log))
 	r.Use(middleware.Recovery(log))
 	r.Use(middleware.CORS(cfg.AllowedOrigins))
@@ -35,6 +50,12 @@ func New(cfg *config.Config, db *database.PostgresDB, cache *cache.RedisCache, l
 	r.GET("/health", healthHandler.Health)
 	r.GET("/ready", healthHandler.Ready)
 
+	// Prometheus metrics are only served on the public port when protected by a token;
+	// with METRICS_PORT they are served on the internal port instead
+	if cfg.MetricsPort == "" && cfg.MetricsToken != "" {
+		r.GET("/metrics", gin.WrapH(metrics.Handler(cfg.MetricsToken)))
+	}
+
 	// Only register other routes if database is available
 	if db == nil {
 		log.Warn("Database not available, only health check endpoints are registered")
@@ -47,17 +68,52 @@ func New(cfg *config.Config, db *database.PostgresDB, cache *cache.RedisCache, l
 	parserService := services.NewParserService(log)
 	parseHandler := handlers.NewParseHandler(parserService, log)
 
+	// Shared Redis client for cross-replica state; nil without Redis
+	var redisClient *redis.Client
+	if cache != nil {
+		redisClient = cache.Client()
+	}
+
 	// Room handlers (video conference)
 	roomRepo := repository.NewRoomRepository(db.DB)
-	roomHandler := handlers.NewRoomHandler(roomRepo, log)
+	signalingHub := services.NewSignalingHub(redisClient, log)
+	var sfu *services.SFU
+	if cfg.RoomSFUEnabled {
+		var err error
+		sfu, err = services.NewSFU(services.SFUConfig{
+			ICEServers: cfg.WebRTCICEServers,
+			PublicIPs:  cfg.WebRTCPublicIPs,
+			UDPPortMin: cfg.WebRTCUDPPortMin,
+			UDPPortMax: cfg.WebRTCUDPPortMax,
+		}, log)
+		if err != nil {
+			log.Fatal("Failed to initialize SFU", zap.Error(err))
+		}
+		// Moderators' mutes stop the SFU forwarding the participant's audio
+		signalingHub.SetMuteHandler(sfu.SetMuted)
+	}
+	go signalingHub.Run(context.Background())
 
 	// Analysis handlers
 	analysisRepo := repository.NewAnalysisRepository(db.DB)
 	analysisHandler := handlers.NewAnalysisHandler(analysisRepo, log)
 
+	// LLM usage metering and daily budgets
+	llmPrices, err := services.ParseModelPrices(cfg.LLMPrices)
+	if err != nil {
+		log.Warn("Ignoring invalid LLM_PRICES entries", zap.Error(err))
+	}
+	usageService := services.NewUsageService(repository.NewUsageRepository(db.DB), llmPrices, services.UsageBudgets{
+		UserDaily:    cfg.LLMUserDailyBudget,
+		TotalDaily:   cfg.LLMTotalDailyBudget,
+		FeatureDaily: cfg.LLMFeatureDailyBudgets,
+	}, log)
+	usageHandler := handlers.NewUsageHandler(usageService, log)
+
 	// YouTube video analysis handlers
 	videoRepo := repository.NewVideoRepository(db.DB)
 	youtubeService := services.NewYouTubeService(cfg.OpenRouterAPIKey, cfg.GeminiModel, log)
+	youtubeService.SetUsageService(usageService)
 	videoHandler := handlers.NewVideoHandler(videoRepo, youtubeService, log)
 
 	// Transcript service (yt-dlp based subtitle extraction)
@@ -66,29 +122,128 @@ func New(cfg *config.Config, db *database.PostgresDB, cache *cache.RedisCache, l
 	// Translation service and handlers
 	translationRepo := repository.NewTranslationRepository(db.DB)
 	translationService := services.NewTranslationService(cfg.OpenRouterAPIKey, cfg.GeminiModel, log)
+	translationService.SetUsageService(usageService)
 	translationHandler := handlers.NewTranslationHandler(translationRepo, translationService, transcriptService, log)
 
+	// Signed short-lived tokens (OAuth state, 2FA challenges etc.)
+	signer, ephemeral, err := services.NewSigner(cfg.SessionSecret)
+	if err != nil {
+		log.Fatal("Failed to initialize token signer", zap.Error(err))
+	}
+	if ephemeral {
+		log.Warn("SESSION_SECRET not set, using a random per-process secret (tokens will not survive restarts)")
+	}
+
+	// Room presence and capacity; signaling tokens are signed with the signer
+	roomService := services.NewRoomService(roomRepo, signalingHub, signer, services.RoomConfig{
+		SFUEnabled:      sfu != nil,
+		MaxParticipants: cfg.RoomMaxParticipants,
+		PresenceTTL:     cfg.RoomPresenceTTL,
+	}, log)
+	go roomService.Run(context.Background())
+	roomHandler := handlers.NewRoomHandler(roomService, signalingHub, sfu, cfg.AllowedOrigins, log)
+
 	// User authentication handlers
 	userRepo := repository.NewUserRepository(db.DB)
-	userHandler := handlers.NewUserHandler(userRepo, log)
+	twoFactorService := services.NewTwoFactorService(userRepo, signer, cfg.TOTPIssuer, log)
+	var mailer services.Mailer
+	if cfg.SMTPHost != "" {
+		mailer = services.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	}
+	securityService := services.NewSecurityService(repository.NewSecurityEventRepository(db.DB), mailer, log)
+	loginGuard := services.NewLoginGuard(services.DefaultLoginGuardConfig(), redisClient, log)
+	rateLimiter := middleware.NewRateLimiter(redisClient, log)
+	apiRateLimit := rateLimiter.Limit(middleware.RateLimitPolicy{Name: "api", Limit: cfg.RateLimitAPIRequests, Window: cfg.RateLimitAPIWindow})
+	llmRateLimit := rateLimiter.Limit(middleware.RateLimitPolicy{Name: "llm", Limit: cfg.RateLimitLLMRequests, Window: cfg.RateLimitLLMWindow})
+	shareUnlockRateLimit := rateLimiter.Limit(middleware.ShareAccessPolicy("share-unlock"))
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService, log)
+
+	// External login providers (Google, GitHub, generic OIDC)
+	loginProviders := services.NewLoginProviderRegistry()
+	if cfg.GoogleClientID != "" && cfg.GoogleClientSecret != "" {
+		googleRedirectURL := cfg.GoogleLoginRedirectURL
+		if googleRedirectURL == "" {
+			googleRedirectURL = cfg.GoogleRedirectURL
+		}
+		loginProviders.Register(services.NewOIDCProvider("google", "Google", "https://accounts.google.com",
+			cfg.GoogleClientID, cfg.GoogleClientSecret, googleRedirectURL, nil, nil))
+	}
+	if cfg.GitHubClientID != "" && cfg.GitHubClientSecret != "" {
+		loginProviders.Register(services.NewGitHubProvider(cfg.GitHubClientID, cfg.GitHubClientSecret, cfg.GitHubRedirectURL))
+	}
+	if cfg.OIDCIssuerURL != "" && cfg.OIDCClientID != "" {
+		loginProviders.Register(services.NewOIDCProvider(cfg.OIDCProviderName, cfg.OIDCDisplayName, cfg.OIDCIssuerURL,
+			cfg.OIDCClientID, cfg.OIDCClientSecret, cfg.OIDCRedirectURL, cfg.OIDCScopes, nil))
+	}
+	identityRepo := repository.NewIdentityRepository(db.DB)
+	identityService := services.NewIdentityService(loginProviders, signer, userRepo, identityRepo, log)
 
 	// InsightFlow handlers
 	insightRepo := repository.NewInsightRepository(db.DB)
 	insightProcessor := services.NewInsightProcessor(insightRepo, youtubeService, log)
 	insightProcessor.SetTranslationService(translationService) // Inject translation service
-	insightHandler := handlers.NewInsightHandler(insightRepo, insightProcessor, log)
+	chapterService := services.NewChapterService(cfg.OpenRouterAPIKey, cfg.GeminiModel, log)
+	chapterService.SetUsageService(usageService)
+	videoHandler.SetChapterService(chapterService)
+	insightProcessor.SetChapterService(chapterService)
+	insightProcessor.SetUsageService(usageService)
+	workspaceRepo := repository.NewWorkspaceRepository(db.DB)
+	workspaceService := services.NewWorkspaceService(workspaceRepo, insightRepo, mailer, cfg.AppBaseURL, log)
+	workspaceHandler := handlers.NewWorkspaceHandler(workspaceService, log)
+
+	// Audit log of sign-ins and sensitive changes; the auth handlers need it too
+	auditService := services.NewAuditService(repository.NewAuditRepository(db.DB), workspaceService, log)
+	auditHandler := handlers.NewAuditHandler(auditService, log)
+	userHandler := handlers.NewUserHandler(userRepo, twoFactorService, loginGuard, securityService, auditService, log)
+	identityHandler := handlers.NewIdentityHandler(identityService, twoFactorService, auditService, log)
+
+	searchService := services.NewSearchService(repository.NewSearchRepository(db.DB), insightRepo, workspaceService, log)
+	insightProcessor.SetSearchService(searchService)
+	searchHandler := handlers.NewSearchHandler(searchService, log)
+	go searchService.Backfill(context.Background())
+	shareService := services.NewShareService(repository.NewShareRepository(db.DB), insightRepo, signer, log)
+	go shareService.MigrateLegacy(context.Background())
+	insightHandler := handlers.NewInsightHandler(insightRepo, insightProcessor, workspaceService, searchService, shareService, auditService, log)
+	sharePageHandler := handlers.NewSharePageHandler(shareService, cfg.PublicBaseURL, log)
+	commentService := services.NewCommentService(repository.NewCommentRepository(db.DB), insightRepo, userRepo, shareService, workspaceService, mailer, cfg.AppBaseURL, log)
+	commentHandler := handlers.NewCommentHandler(commentService, log)
+	flashcardRepo := repository.NewFlashcardRepository(db.DB)
+	flashcardService := services.NewFlashcardService(flashcardRepo, insightRepo, workspaceService, cfg.OpenRouterAPIKey, cfg.GeminiModel, log)
+	flashcardService.SetUsageService(usageService)
+	flashcardHandler := handlers.NewFlashcardHandler(flashcardService, log)
+	libraryRepo := repository.NewLibraryRepository(db.DB)
+	libraryService := services.NewLibraryService(libraryRepo, workspaceService, log)
+	libraryHandler := handlers.NewLibraryHandler(libraryService, log)
+	ankiHandler := handlers.NewAnkiHandler(services.NewAnkiService(insightRepo, libraryRepo, flashcardRepo, workspaceService, log), auditService, log)
+	trashService := services.NewTrashService(insightRepo, workspaceService, cfg.InsightTrashRetention, log)
+	go trashService.Run(context.Background())
+	trashHandler := handlers.NewTrashHandler(trashService, auditService, log)
+
+	// Data export and account deletion
+	accountService := services.NewAccountService(repository.NewAccountRepository(db.DB), userRepo, workspaceRepo,
+		twoFactorService, securityService, cfg.AccountDeletionGracePeriod, cfg.AccountExportTTL, log)
+	go accountService.Run(context.Background())
+	accountHandler := handlers.NewAccountHandler(accountService, auditService, log)
 
 	// YouTube Data API v3 handlers (OAuth + API endpoints)
 	oauthService := services.NewOAuthService(cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.GoogleRedirectURL, log)
 	youtubeAPIService := services.NewYouTubeAPIService(cfg.YouTubeAPIKey, cache, oauthService, log)
-	youtubeAPIHandler := handlers.NewYouTubeAPIHandler(youtubeAPIService, youtubeService, oauthService, userRepo, log)
+	youtubeAPIHandler := handlers.NewYouTubeAPIHandler(youtubeAPIService, youtubeService, oauthService, log)
+	youtubeAPIHandler.SetSignIn(identityService, twoFactorService, auditService)
 
 	transcriptHandler := handlers.NewTranscriptHandler(transcriptService, log)
 
 	// Chat handlers
 	chatRepo := repository.NewChatRepository(db.DB)
 	chatService := services.NewChatService(chatRepo, videoRepo, insightRepo, cfg.OpenRouterAPIKey, cfg.GeminiModel, log)
-	chatHandler := handlers.NewChatHandler(chatService, log)
+	chatService.SetSearchService(searchService)
+	chatService.SetUsageService(usageService)
+	chatHandler := handlers.NewChatHandler(chatService, workspaceService, log)
+
+	// Server-rendered share pages (public, unfurled by chat apps)
+	r.GET("/s/:token", sharePageHandler.Page)
+	r.GET("/s/:token/embed", sharePageHandler.Embed)
+	r.POST("/s/:token/unlock", shareUnlockRateLimit, sharePageHandler.Unlock)
 
 	// API routes
 	api := r.Group("/api")
@@ -98,6 +253,13 @@ func New(cfg *config.Config, db *database.PostgresDB, cache *cache.RedisCache, l
 		{
 			auth.POST("/register", userHandler.Register)
 			auth.POST("/login", userHandler.Login)
+			auth.POST("/login/2fa", rateLimiter.Limit(middleware.TwoFactorPolicy("2fa-login")), userHandler.LoginTwoFactor)
+
+			// External login providers
+			auth.GET("/providers", identityHandler.ListProviders)
+			auth.GET("/sso/:provider/url", identityHandler.GetLoginURL)
+			// Optional auth: link flows are completed by the user who started them
+			auth.POST("/sso/:provider/callback", middleware.OptionalAuth(userRepo, log), identityHandler.Callback)
 			
 			// Protected auth routes
 			authProtected := auth.Group("")
@@ -105,6 +267,23 @@ func New(cfg *config.Config, db *database.PostgresDB, cache *cache.RedisCache, l
 			{
 				authProtected.GET("/profile", userHandler.GetProfile)
 				authProtected.POST("/regenerate-key", userHandler.RegenerateAPIKey)
+				authProtected.GET("/security-events", userHandler.ListSecurityEvents)
+
+				// Linked identities
+				authProtected.GET("/identities", identityHandler.ListIdentities)
+				authProtected.POST("/identities/:provider/link", identityHandler.GetLinkURL)
+				authProtected.DELETE("/identities/:id", identityHandler.Unlink)
+
+				// Two-factor authentication
+				twoFactor := authProtected.Group("/2fa")
+				twoFactor.Use(rateLimiter.Limit(middleware.TwoFactorPolicy("2fa")))
+				{
+					twoFactor.GET("", twoFactorHandler.Status)
+					twoFactor.POST("/enroll", twoFactorHandler.Enroll)
+					twoFactor.POST("/confirm", twoFactorHandler.Confirm)
+					twoFactor.POST("/disable", twoFactorHandler.Disable)
+					twoFactor.POST("/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+				}
 			}
 		}
 
@@ -114,17 +293,24 @@ func New(cfg *config.Config, db *database.PostgresDB, cache *cache.RedisCache, l
 		// Room routes (video conference)
 		room := api.Group("/room")
 		{
-			room.POST("/join", roomHandler.JoinRoom)
-			room.POST("/leave", roomHandler.LeaveRoom)
-			room.GET("/status", roomHandler.GetRoomStatus)
-		}
+			// Authenticated by the token from /join; browsers cannot set headers on WebSockets
+			room.GET("/signal", roomHandler.Signal) // WebRTC signaling WebSocket
 
-		// Media routes (WebRTC signaling)
-		media := api.Group("/media")
-		{
-			media.POST("/offer", roomHandler.SendMediaOffer)
-			media.POST("/iceCandidate", roomHandler.SendICECandidate)
-			media.GET("/iceCandidates", roomHandler.GetICECandidates) // Optional helper endpoint
+			roomProtected := room.Group("")
+			roomProtected.Use(middleware.Auth(userRepo, log))
+			{
+				roomProtected.POST("", roomHandler.CreateRoom)
+				roomProtected.POST("/join", roomHandler.JoinRoom)
+				roomProtected.POST("/leave", roomHandler.LeaveRoom)
+				roomProtected.POST("/heartbeat", roomHandler.Heartbeat)
+				roomProtected.GET("/status", roomHandler.GetRoomStatus)
+				roomProtected.GET("/lobby", roomHandler.GetLobby)
+				roomProtected.PATCH("/settings", roomHandler.UpdateSettings)
+				roomProtected.POST("/invites", roomHandler.CreateInvite)
+				roomProtected.GET("/invites", roomHandler.ListInvites)
+				roomProtected.DELETE("/invites/:id", roomHandler.RevokeInvite)
+				roomProtected.POST("/moderation/:action", roomHandler.Moderate)
+			}
 		}
 
 		// Analysis routes
@@ -149,13 +335,15 @@ func New(cfg *config.Config, db *database.PostgresDB, cache *cache.RedisCache, l
 		}
 
 		// YouTube video analysis routes (API v1)
+		// Authenticated requests are limited per user, anonymous ones per IP
 		v1 := api.Group("/v1")
+		v1.Use(middleware.OptionalAuth(userRepo, log), apiRateLimit)
 		{
 			// Video routes
 			videos := v1.Group("/videos")
 			{
-				videos.POST("/metadata", videoHandler.GetMetadata)
-				videos.POST("/analyze", videoHandler.AnalyzeVideo)
+				videos.POST("/metadata", llmRateLimit, videoHandler.GetMetadata)
+				videos.POST("/analyze", llmRateLimit, videoHandler.AnalyzeVideo)
 				videos.GET("/result/:jobId", videoHandler.GetResult)
 				videos.POST("/export", videoHandler.ExportVideo)
 				videos.DELETE("/:id", videoHandler.DeleteAnalysis)
@@ -189,23 +377,136 @@ func New(cfg *config.Config, db *database.PostgresDB, cache *cache.RedisCache, l
 			v1.POST("/transcript", transcriptHandler.GetTranscript)
 
 			// Translation routes
-			v1.POST("/translate", translationHandler.Translate)
+			v1.POST("/translate", llmRateLimit, translationHandler.Translate)
 			v1.GET("/translate/:id", translationHandler.GetTranslation)
 
+			// Workspace routes (shared insight libraries)
+			workspaces := v1.Group("/workspaces")
+			workspaces.Use(middleware.Auth(userRepo, log))
+			{
+				workspaces.GET("", workspaceHandler.List)
+				workspaces.POST("", workspaceHandler.Create)
+				workspaces.POST("/invitations/accept", workspaceHandler.AcceptInvitation)
+				workspaces.GET("/:id", workspaceHandler.Get)
+				workspaces.PATCH("/:id", workspaceHandler.Update)
+				workspaces.DELETE("/:id", workspaceHandler.Delete)
+
+				// Members
+				workspaces.GET("/:id/members", workspaceHandler.ListMembers)
+				workspaces.PATCH("/:id/members/:userId", workspaceHandler.UpdateMember)
+				workspaces.DELETE("/:id/members/:userId", workspaceHandler.RemoveMember)
+
+				// Invitations
+				workspaces.GET("/:id/invitations", workspaceHandler.ListInvitations)
+				workspaces.POST("/:id/invitations", workspaceHandler.CreateInvitation)
+				workspaces.DELETE("/:id/invitations/:invitationId", workspaceHandler.RevokeInvitation)
+
+				// Audit log (owners only)
+				workspaces.GET("/:id/audit-logs", auditHandler.ListWorkspace)
+				workspaces.GET("/:id/audit-logs/export", auditHandler.ExportWorkspace)
+			}
+
+			// Full-text search across a workspace's library
+			v1.GET("/search", middleware.Auth(userRepo, log), searchHandler.Search)
+
+			// Library organization routes (tags and collections)
+			v1.GET("/library/sidebar", middleware.Auth(userRepo, log), libraryHandler.Sidebar)
+
+			// Spaced-repetition review
+			flashcards := v1.Group("/flashcards")
+			flashcards.Use(middleware.Auth(userRepo, log))
+			{
+				flashcards.GET("", flashcardHandler.List)
+				flashcards.GET("/due", flashcardHandler.Due)
+				flashcards.POST("/:id/review", flashcardHandler.Review)
+				flashcards.PATCH("/:id", flashcardHandler.Update)
+				flashcards.DELETE("/:id", flashcardHandler.Delete)
+			}
+
+			// Anki deck export
+			v1.POST("/exports/anki", middleware.Auth(userRepo, log), ankiHandler.Export)
+
+			// The caller's own audit log
+			auditLogs := v1.Group("/audit-logs")
+			auditLogs.Use(middleware.Auth(userRepo, log))
+			{
+				auditLogs.GET("", auditHandler.ListMine)
+				auditLogs.GET("/export", auditHandler.ExportMine)
+			}
+
+			// Data export and account deletion (GDPR)
+			account := v1.Group("/account")
+			account.Use(middleware.Auth(userRepo, log))
+			{
+				account.GET("/exports", accountHandler.ListExports)
+				account.POST("/exports", accountHandler.RequestExport)
+				account.GET("/exports/:id", accountHandler.GetExport)
+				account.GET("/exports/:id/download", accountHandler.DownloadExport)
+				account.GET("/deletion", accountHandler.GetDeletion)
+				account.POST("/deletion", rateLimiter.Limit(middleware.TwoFactorPolicy("account-deletion")), accountHandler.ScheduleDeletion)
+				account.DELETE("/deletion", accountHandler.CancelDeletion)
+			}
+
+			// LLM usage and budgets; usage of all users is limited to admins
+			usage := v1.Group("/usage")
+			usage.Use(middleware.Auth(userRepo, log))
+			{
+				usage.GET("", usageHandler.Summary)
+				usage.GET("/features", middleware.RequireAdmin(cfg.AdminEmails), usageHandler.ByFeature)
+				usage.GET("/users", middleware.RequireAdmin(cfg.AdminEmails), usageHandler.ByUser)
+			}
+
+			tags := v1.Group("/tags")
+			tags.Use(middleware.Auth(userRepo, log))
+			{
+				tags.GET("", libraryHandler.ListTags)
+				tags.POST("", libraryHandler.CreateTag)
+				tags.PATCH("/:id", libraryHandler.UpdateTag)
+				tags.DELETE("/:id", libraryHandler.DeleteTag)
+			}
+
+			collections := v1.Group("/collections")
+			collections.Use(middleware.Auth(userRepo, log))
+			{
+				collections.GET("", libraryHandler.ListCollections)
+				collections.POST("", libraryHandler.CreateCollection)
+				collections.PATCH("/:id", libraryHandler.UpdateCollection)
+				collections.DELETE("/:id", libraryHandler.DeleteCollection)
+			}
+
+			// Deleted insights, restorable until purged
+			trash := v1.Group("/trash")
+			trash.Use(middleware.Auth(userRepo, log))
+			{
+				trash.GET("", trashHandler.List)
+				trash.DELETE("", trashHandler.Empty)
+				trash.POST("/:id/restore", trashHandler.Restore)
+				trash.DELETE("/:id", trashHandler.Purge)
+			}
+
 			// InsightFlow routes (protected by authentication)
 			insights := v1.Group("/insights")
 			insights.Use(middleware.Auth(userRepo, log))
 			{
 				insights.GET("", insightHandler.List)
-				insights.POST("", insightHandler.Create)
+				insights.POST("", llmRateLimit, insightHandler.Create)
 				insights.GET("/:id", insightHandler.Get)
 				insights.PATCH("/:id", insightHandler.Update)
 				insights.DELETE("/:id", insightHandler.Delete)
-				insights.POST("/:id/process", insightHandler.Process)
+				insights.POST("/:id/process", llmRateLimit, insightHandler.Process)
+
+				// Bulk organization
+				insights.POST("/bulk/tags", libraryHandler.BulkTag)
+				insights.POST("/bulk/move", libraryHandler.BulkMove)
 
 				// Share routes
 				insights.POST("/:id/share", insightHandler.ShareInsight)
 				insights.DELETE("/:id/share", insightHandler.DeleteShare)
+				insights.GET("/:id/share-links", insightHandler.ListShareLinks)
+				insights.POST("/:id/share-links", insightHandler.CreateShareLink)
+				insights.PATCH("/:id/share-links/:linkId", insightHandler.UpdateShareLink)
+				insights.DELETE("/:id/share-links/:linkId", insightHandler.RevokeShareLink)
+				insights.GET("/:id/share-links/:linkId/stats", insightHandler.ShareLinkStats)
 
 				// Highlight routes
 				insights.GET("/:id/highlights", insightHandler.ListHighlights)
@@ -213,17 +514,36 @@ func New(cfg *config.Config, db *database.PostgresDB, cache *cache.RedisCache, l
 				insights.PATCH("/:id/highlights/:highlightId", insightHandler.UpdateHighlight)
 				insights.DELETE("/:id/highlights/:highlightId", insightHandler.DeleteHighlight)
 
+				// Comment routes (moderation is limited to the insight owner)
+				insights.GET("/:id/comments", commentHandler.List)
+				insights.POST("/:id/comments", commentHandler.Create)
+				insights.PATCH("/:id/comments/:commentId", commentHandler.Moderate)
+				insights.DELETE("/:id/comments/:commentId", commentHandler.Delete)
+
+				// Flashcard generation
+				insights.POST("/:id/flashcards/generate", llmRateLimit, flashcardHandler.Generate)
+
 				// Chat routes (InsightHandler)
 				insights.GET("/:id/chat", insightHandler.ListChatMessages)
-				insights.POST("/:id/chat", insightHandler.CreateChatMessage)
+				insights.POST("/:id/chat", llmRateLimit, insightHandler.CreateChatMessage)
 				insights.DELETE("/:id/chat", insightHandler.ClearChatHistory)
 
 				// Entity analysis route (ChatHandler)
-				insights.POST("/:id/analyze-entities", chatHandler.AnalyzeEntities)
+				insights.POST("/:id/analyze-entities", llmRateLimit, chatHandler.AnalyzeEntities)
 			}
 
 			// Shared insight (public access, with rate limiting to prevent brute-force)
-			v1.GET("/shared/:token", middleware.ShareAccessRateLimit(), insightHandler.GetShared)
+			v1.GET("/shared/:token", rateLimiter.Limit(middleware.ShareAccessPolicy("share-view")), insightHandler.GetShared)
+			v1.POST("/shared/:token/unlock", shareUnlockRateLimit, insightHandler.UnlockShared)
+			v1.GET("/oembed", sharePageHandler.OEmbed)
+
+			// Comments through share links; anonymous commenters are rate limited
+			sharedComments := v1.Group("/shared/:token/comments")
+			sharedComments.Use(middleware.OptionalAuth(userRepo, log))
+			{
+				sharedComments.GET("", commentHandler.ListShared)
+				sharedComments.POST("", middleware.UnlessAuthenticated(rateLimiter.Limit(middleware.ShareAccessPolicy("share-comment"))), commentHandler.CreateShared)
+			}
 		}
 	}
 
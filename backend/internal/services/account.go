@@ -0,0 +1,623 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"vibe-backend/internal/metrics"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+	"vibe-backend/internal/telemetry"
+)
+
+const (
+	// accountSweepInterval is how often due deletions and expired exports are processed.
+	accountSweepInterval = 15 * time.Minute
+	// accountExportStaleAfter fails exports that have been pending this long.
+	accountExportStaleAfter = time.Hour
+	// accountExportBatchSize is how many insights are loaded at a time for an export.
+	accountExportBatchSize = 50
+)
+
+var (
+	ErrExportInProgress      = errors.New("a data export is already being prepared")
+	ErrExportNotFound        = errors.New("export not found")
+	ErrExportNotReady        = errors.New("export is not ready")
+	ErrDeletionScheduled     = errors.New("account deletion is already scheduled")
+	ErrNoDeletionScheduled   = errors.New("no account deletion is scheduled")
+	ErrDeletionEmailMismatch = errors.New("confirmation email does not match the account")
+	ErrInvalidPassword       = errors.New("invalid password")
+	ErrTwoFactorCodeRequired = errors.New("two-factor code required")
+)
+
+// AccountService exports a user's data and deletes accounts after a grace period.
+type AccountService struct {
+	repo          *repository.AccountRepository
+	userRepo      *repository.UserRepository
+	workspaceRepo *repository.WorkspaceRepository
+	twoFactor     *TwoFactorService
+	security      *SecurityService
+	gracePeriod   time.Duration
+	exportTTL     time.Duration
+	log           *zap.Logger
+}
+
+// NewAccountService creates a new AccountService. Deletions run gracePeriod after
+// they are requested; exports can be downloaded for exportTTL.
+func NewAccountService(
+	repo *repository.AccountRepository,
+	userRepo *repository.UserRepository,
+	workspaceRepo *repository.WorkspaceRepository,
+	twoFactor *TwoFactorService,
+	security *SecurityService,
+	gracePeriod, exportTTL time.Duration,
+	log *zap.Logger,
+) *AccountService {
+	return &AccountService{
+		repo:          repo,
+		userRepo:      userRepo,
+		workspaceRepo: workspaceRepo,
+		twoFactor:     twoFactor,
+		security:      security,
+		gracePeriod:   gracePeriod,
+		exportTTL:     exportTTL,
+		log:           log,
+	}
+}
+
+// --- Exports ---
+
+// RequestExport starts building an export of everything the user owns. ctx should
+// be detached from the request's cancellation; the export outlives the request.
+func (s *AccountService) RequestExport(ctx context.Context, user *models.User, ip, userAgent string) (*models.AccountExport, error) {
+	pending, err := s.repo.HasPendingExport(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, ErrExportInProgress
+	}
+
+	export := &models.AccountExport{UserID: user.ID, Status: models.AccountExportPending}
+	if err := s.repo.CreateExport(ctx, export); err != nil {
+		return nil, err
+	}
+	s.security.Record(ctx, user, models.SecurityEventDataExported, ip, userAgent, "")
+
+	go s.buildExport(ctx, export.ID, user.ID)
+	return export, nil
+}
+
+// ListExports returns the user's exports, newest first.
+func (s *AccountService) ListExports(ctx context.Context, userID uint) ([]models.AccountExport, error) {
+	return s.repo.ListExports(ctx, userID)
+}
+
+// GetExport returns one of the user's exports.
+func (s *AccountService) GetExport(ctx context.Context, userID, id uint) (*models.AccountExport, error) {
+	export, err := s.repo.GetExport(ctx, userID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExportNotFound
+	}
+	return export, err
+}
+
+// OpenExport returns a completed export with its archive.
+func (s *AccountService) OpenExport(ctx context.Context, userID, id uint) (*models.AccountExport, error) {
+	export, err := s.repo.GetExportArchive(ctx, userID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if export.Status != models.AccountExportCompleted {
+		return nil, ErrExportNotReady
+	}
+	if export.ExpiresAt != nil && time.Now().After(*export.ExpiresAt) {
+		return nil, ErrExportNotFound
+	}
+	return export, nil
+}
+
+// ExportFilename returns the download name of an export.
+func (s *AccountService) ExportFilename(export *models.AccountExport) string {
+	return fmt.Sprintf("vibe-export-%s.zip", export.CreatedAt.Format("20060102"))
+}
+
+// buildExport builds and stores the archive of a pending export.
+func (s *AccountService) buildExport(ctx context.Context, exportID, userID uint) {
+	ctx, span := telemetry.StartJob(ctx, "account.export", attribute.Int("export.id", int(exportID)))
+	start := time.Now()
+	var err error
+	defer func() {
+		metrics.ObserveJob(metrics.JobAccountExport, start, metrics.Outcome(err))
+		telemetry.End(span, err)
+	}()
+
+	var buf bytes.Buffer
+	if err = s.writeArchive(ctx, userID, &buf); err != nil {
+		s.log.Error("Failed to build data export",
+			zap.Uint("export_id", exportID),
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		if failErr := s.repo.FailExport(ctx, exportID, "failed to build export"); failErr != nil {
+			s.log.Error("Failed to mark data export failed", zap.Uint("export_id", exportID), zap.Error(failErr))
+		}
+		return
+	}
+
+	if err = s.repo.CompleteExport(ctx, exportID, buf.Bytes(), time.Now().Add(s.exportTTL)); err != nil {
+		s.log.Error("Failed to store data export", zap.Uint("export_id", exportID), zap.Error(err))
+		return
+	}
+	s.log.Info("Data export completed",
+		zap.Uint("export_id", exportID),
+		zap.Uint("user_id", userID),
+		zap.Int("size_bytes", buf.Len()),
+		zap.Duration("duration", time.Since(start)),
+	)
+}
+
+// accountExportProfile is account.json of an export.
+type accountExportProfile struct {
+	User             models.UserResponse      `json:"user"`
+	Passwordless     bool                     `json:"passwordless"`
+	LinkedIdentities []models.UserIdentity    `json:"linked_identities"`
+	Workspaces       []accountExportWorkspace `json:"workspaces"`
+	ExportedAt       time.Time                `json:"exported_at"`
+}
+
+// accountExportWorkspace is a workspace the user belongs to.
+type accountExportWorkspace struct {
+	ID       uint                 `json:"id"`
+	Name     string               `json:"name"`
+	Personal bool                 `json:"personal"`
+	Role     models.WorkspaceRole `json:"role"`
+}
+
+// accountExportReadme describes the layout of an export.
+const accountExportReadme = `# Vibe 数据导出
+
+本压缩包包含你账户中的全部数据：
+
+- account.json：账户资料、已关联的登录方式和所属工作区
+- insights/：你创建的每条内容，JSON 为完整数据，Markdown 便于阅读（含摘要、核心观点、章节、高亮、对话和转录）
+- highlights.json、chat_messages.json、comments.json：你在任何内容上创建的高亮、对话和评论
+- flashcards.json、flashcard_reviews.json：闪卡及复习记录
+- video_analyses.json、pomodoros.json：视频分析和番茄钟记录
+- share_links.json：你创建的分享链接
+- security_events.json、llm_usage.json：账户安全日志和 AI 用量记录
+- audit_log.json：你执行的操作的审计日志（登录、分享、删除、导出等）
+`
+
+// writeArchive writes the ZIP of everything the user owns to w.
+func (s *AccountService) writeArchive(ctx context.Context, userID uint, w io.Writer) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+
+	zw := zip.NewWriter(w)
+	if err := writeZipFile(zw, "README.md", []byte(accountExportReadme)); err != nil {
+		return err
+	}
+
+	profile := accountExportProfile{
+		User:         user.ToResponse(),
+		Passwordless: user.Passwordless,
+		ExportedAt:   time.Now().UTC(),
+	}
+	if err := s.repo.ListOwned(ctx, &profile.LinkedIdentities, "user_id", userID); err != nil {
+		return err
+	}
+	workspaces, err := s.workspaceRepo.ListForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, workspace := range workspaces {
+		profile.Workspaces = append(profile.Workspaces, accountExportWorkspace{
+			ID:       workspace.ID,
+			Name:     workspace.Name,
+			Personal: workspace.Personal,
+			Role:     workspace.Role,
+		})
+	}
+	if err := writeZipJSON(zw, "account.json", profile); err != nil {
+		return err
+	}
+
+	var afterID uint
+	for {
+		insights, err := s.repo.ExportInsights(ctx, userID, afterID, accountExportBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(insights) == 0 {
+			break
+		}
+		for i := range insights {
+			insight := &insights[i]
+			base := "insights/" + exportFileBase(insight.ID, insight.Title)
+			if err := writeZipJSON(zw, base+".json", insight); err != nil {
+				return err
+			}
+			if err := writeZipFile(zw, base+".md", []byte(insightMarkdown(insight))); err != nil {
+				return err
+			}
+			afterID = insight.ID
+		}
+	}
+
+	var (
+		highlights       []models.Highlight
+		chatMessages     []models.ChatMessage
+		comments         []models.Comment
+		flashcards       []models.Flashcard
+		flashcardReviews []models.FlashcardReview
+		videoAnalyses    []models.VideoAnalysis
+		pomodoros        []models.Pomodoro
+		shareLinks       []models.ShareLink
+		securityEvents   []models.SecurityEvent
+		llmUsage         []models.LLMUsage
+		auditLog         []models.AuditLog
+	)
+	tables := []struct {
+		name   string
+		dest   interface{}
+		column string
+	}{
+		{"highlights.json", &highlights, "user_id"},
+		{"chat_messages.json", &chatMessages, "user_id"},
+		{"comments.json", &comments, "user_id"},
+		{"flashcards.json", &flashcards, "user_id"},
+		{"flashcard_reviews.json", &flashcardReviews, "user_id"},
+		{"video_analyses.json", &videoAnalyses, "user_id"},
+		{"pomodoros.json", &pomodoros, "user_id"},
+		{"share_links.json", &shareLinks, "created_by"},
+		{"security_events.json", &securityEvents, "user_id"},
+		{"llm_usage.json", &llmUsage, "user_id"},
+		{"audit_log.json", &auditLog, "actor_id"},
+	}
+	for _, table := range tables {
+		if err := s.repo.ListOwned(ctx, table.dest, table.column, userID); err != nil {
+			return fmt.Errorf("failed to load %s: %w", table.name, err)
+		}
+		if err := writeZipJSON(zw, table.name, table.dest); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// insightMarkdown renders an insight for reading.
+func insightMarkdown(insight *models.Insight) string {
+	var md strings.Builder
+	title := insight.Title
+	if title == "" {
+		title = insight.SourceURL
+	}
+	fmt.Fprintf(&md, "# %s\n\n", title)
+	fmt.Fprintf(&md, "**来源**: %s\n\n", insight.SourceURL)
+	if insight.Author != "" {
+		fmt.Fprintf(&md, "**作者**: %s\n\n", insight.Author)
+	}
+	fmt.Fprintf(&md, "**创建时间**: %s\n\n", insight.CreatedAt.Format("2006-01-02 15:04:05"))
+	if len(insight.Tags) > 0 {
+		names := make([]string, len(insight.Tags))
+		for i, tag := range insight.Tags {
+			names[i] = tag.Name
+		}
+		fmt.Fprintf(&md, "**标签**: %s\n\n", strings.Join(names, ", "))
+	}
+	md.WriteString("---\n\n")
+
+	if insight.Summary != "" {
+		fmt.Fprintf(&md, "## 摘要\n\n%s\n\n", insight.Summary)
+	}
+
+	var keyPoints []string
+	if len(insight.KeyPoints) > 0 {
+		_ = json.Unmarshal(insight.KeyPoints, &keyPoints)
+	}
+	if len(keyPoints) > 0 {
+		md.WriteString("## 核心观点\n\n")
+		for i, point := range keyPoints {
+			fmt.Fprintf(&md, "%d. %s\n", i+1, point)
+		}
+		md.WriteString("\n")
+	}
+
+	if len(insight.Chapters) > 0 {
+		md.WriteString("## 章节\n\n")
+		for _, chapter := range insight.Chapters {
+			fmt.Fprintf(&md, "- [%s] %s\n", chapter.Timestamp, chapter.Title)
+		}
+		md.WriteString("\n")
+	}
+
+	if len(insight.Highlights) > 0 {
+		md.WriteString("## 高亮\n\n")
+		for _, highlight := range insight.Highlights {
+			fmt.Fprintf(&md, "> %s\n\n", strings.ReplaceAll(highlight.Text, "\n", "\n> "))
+			if highlight.Note != "" {
+				fmt.Fprintf(&md, "%s\n\n", highlight.Note)
+			}
+		}
+	}
+
+	if len(insight.ChatMessages) > 0 {
+		md.WriteString("## 对话\n\n")
+		for _, message := range insight.ChatMessages {
+			speaker := "我"
+			if message.Role == "assistant" {
+				speaker = "AI"
+			}
+			fmt.Fprintf(&md, "**%s**: %s\n\n", speaker, message.Content)
+		}
+	}
+
+	var transcripts []models.TranscriptItem
+	if len(insight.Transcripts) > 0 {
+		_ = json.Unmarshal(insight.Transcripts, &transcripts)
+	}
+	switch {
+	case len(transcripts) > 0:
+		md.WriteString("## 完整转录\n\n")
+		for _, item := range transcripts {
+			fmt.Fprintf(&md, "**[%s]** %s\n\n", item.Timestamp, item.Text)
+			if item.TranslatedText != "" {
+				fmt.Fprintf(&md, "%s\n\n", item.TranslatedText)
+			}
+		}
+	case insight.RawContent != "":
+		fmt.Fprintf(&md, "## 原文\n\n%s\n\n", insight.RawContent)
+		if insight.TransContent != "" {
+			fmt.Fprintf(&md, "## 译文\n\n%s\n\n", insight.TransContent)
+		}
+	}
+
+	return md.String()
+}
+
+// exportFileBase returns a file name for an insight: its ID and a slug of the title.
+func exportFileBase(id uint, title string) string {
+	var slug strings.Builder
+	runes := 0
+	for _, r := range strings.ToLower(title) {
+		if runes >= 60 {
+			break
+		}
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+			slug.WriteRune(r)
+			runes++
+		case slug.Len() > 0 && !strings.HasSuffix(slug.String(), "-"):
+			slug.WriteByte('-')
+			runes++
+		}
+	}
+	base := strings.Trim(slug.String(), "-")
+	if base == "" {
+		return fmt.Sprintf("%d", id)
+	}
+	return fmt.Sprintf("%d-%s", id, base)
+}
+
+// writeZipJSON adds v to an archive as indented JSON.
+func writeZipJSON(zw *zip.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	return writeZipFile(zw, name, data)
+}
+
+// writeZipFile adds a file to an archive.
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// --- Deletion ---
+
+// ScheduleDeletion schedules the user's account for deletion after the grace period
+// and revokes their share links right away, so shared content stops being public.
+func (s *AccountService) ScheduleDeletion(ctx context.Context, user *models.User, req *models.DeleteAccountRequest, ip, userAgent string) (*models.AccountDeletionResponse, error) {
+	if !strings.EqualFold(strings.TrimSpace(req.ConfirmEmail), user.Email) {
+		return nil, ErrDeletionEmailMismatch
+	}
+	if !user.Passwordless && !s.userRepo.VerifyPassword(user, req.Password) {
+		return nil, ErrInvalidPassword
+	}
+	if user.TOTPEnabled {
+		if req.Code == "" {
+			return nil, ErrTwoFactorCodeRequired
+		}
+		if err := s.twoFactor.VerifyCode(ctx, user, req.Code); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.repo.GetScheduledDeletion(ctx, user.ID); err == nil {
+		return nil, ErrDeletionScheduled
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	now := time.Now()
+	deletion := &models.AccountDeletion{
+		UserID:       user.ID,
+		EmailHash:    hashEmail(user.Email),
+		Status:       models.AccountDeletionScheduled,
+		RequestedAt:  now,
+		ScheduledFor: now.Add(s.gracePeriod),
+		IPAddress:    ip,
+	}
+	if err := s.repo.CreateDeletion(ctx, deletion); err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.repo.RevokeShares(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke share links: %w", err)
+	}
+
+	s.security.Record(ctx, user, models.SecurityEventDeletionScheduled, ip, userAgent,
+		"scheduled for "+deletion.ScheduledFor.UTC().Format(time.RFC3339))
+	s.log.Info("Account deletion scheduled",
+		zap.Uint("user_id", user.ID),
+		zap.Time("scheduled_for", deletion.ScheduledFor),
+		zap.Int64("revoked_share_links", revoked),
+	)
+
+	if s.gracePeriod <= 0 {
+		go s.runDeletion(context.WithoutCancel(ctx), deletion.ID)
+	}
+
+	response := deletionResponse(deletion)
+	response.RevokedShareLinks = revoked
+	return response, nil
+}
+
+// GetDeletion returns the user's scheduled deletion.
+func (s *AccountService) GetDeletion(ctx context.Context, userID uint) (*models.AccountDeletionResponse, error) {
+	deletion, err := s.repo.GetScheduledDeletion(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoDeletionScheduled
+	}
+	if err != nil {
+		return nil, err
+	}
+	return deletionResponse(deletion), nil
+}
+
+// CancelDeletion cancels the user's scheduled deletion. Revoked share links stay revoked.
+func (s *AccountService) CancelDeletion(ctx context.Context, user *models.User, ip, userAgent string) error {
+	cancelled, err := s.repo.CancelDeletion(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return ErrNoDeletionScheduled
+	}
+
+	s.security.Record(ctx, user, models.SecurityEventDeletionCancelled, ip, userAgent, "")
+	s.log.Info("Account deletion cancelled", zap.Uint("user_id", user.ID))
+	return nil
+}
+
+// Run processes due deletions and expired exports until ctx is done.
+func (s *AccountService) Run(ctx context.Context) {
+	ticker := time.NewTicker(accountSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sweep(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweep runs due deletions, fails interrupted exports and removes expired ones.
+func (s *AccountService) sweep(ctx context.Context) {
+	for {
+		ids, err := s.repo.DueDeletionIDs(ctx, time.Now(), 10)
+		if err != nil {
+			s.log.Error("Failed to list due account deletions", zap.Error(err))
+			break
+		}
+		if len(ids) == 0 {
+			break
+		}
+		failed := false
+		for _, id := range ids {
+			if !s.runDeletion(ctx, id) {
+				failed = true
+			}
+		}
+		// Failed deletions stay due; retry them on the next sweep rather than spinning
+		if failed {
+			break
+		}
+	}
+
+	if stale, err := s.repo.FailStaleExports(ctx, time.Now().Add(-accountExportStaleAfter)); err != nil {
+		s.log.Error("Failed to fail stale data exports", zap.Error(err))
+	} else if stale > 0 {
+		s.log.Warn("Failed interrupted data exports", zap.Int64("count", stale))
+	}
+	if expired, err := s.repo.DeleteExpiredExports(ctx, time.Now()); err != nil {
+		s.log.Error("Failed to delete expired data exports", zap.Error(err))
+	} else if expired > 0 {
+		s.log.Info("Deleted expired data exports", zap.Int64("count", expired))
+	}
+}
+
+// runDeletion hard-deletes the account of a due deletion. It returns false on failure.
+func (s *AccountService) runDeletion(ctx context.Context, deletionID uint) bool {
+	ctx, span := telemetry.Tracer().Start(ctx, "account.delete")
+	start := time.Now()
+	deletion, err := s.repo.ExecuteDeletion(ctx, deletionID)
+	telemetry.End(span, err)
+	if err != nil {
+		metrics.ObserveJob(metrics.JobAccountDeletion, start, metrics.OutcomeFailure)
+		s.log.Error("Failed to delete account", zap.Uint("deletion_id", deletionID), zap.Error(err))
+		return false
+	}
+	if deletion == nil {
+		// Cancelled, or run by another server
+		return true
+	}
+
+	metrics.ObserveJob(metrics.JobAccountDeletion, start, metrics.OutcomeSuccess)
+	s.log.Info("Account deleted",
+		zap.Uint("deletion_id", deletion.ID),
+		zap.Uint("user_id", deletion.UserID),
+		zap.ByteString("deleted_rows", deletion.DeletedRows),
+		zap.Duration("duration", time.Since(start)),
+	)
+	return true
+}
+
+// deletionResponse converts a deletion to its API response.
+func deletionResponse(deletion *models.AccountDeletion) *models.AccountDeletionResponse {
+	return &models.AccountDeletionResponse{
+		Status:       deletion.Status,
+		RequestedAt:  deletion.RequestedAt,
+		ScheduledFor: deletion.ScheduledFor,
+	}
+}
+
+// hashEmail returns the SHA-256 hex of a normalized email, which identifies a deleted
+// account in the audit record without keeping the address.
+func hashEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,448 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+	"vibe-backend/internal/telemetry"
+)
+
+const (
+	// defaultAnkiDeck is the root deck of an export unless the user names one.
+	defaultAnkiDeck = "Vibe"
+	// ankiContextRunes is how much surrounding content a highlight cloze shows on
+	// each side, at most.
+	ankiContextRunes = 200
+	// ankiMediaTimeout bounds fetching all thumbnails of one export.
+	ankiMediaTimeout = 30 * time.Second
+	// maxAnkiMediaBytes bounds the size of one thumbnail.
+	maxAnkiMediaBytes = 2 << 20
+)
+
+// ErrAnkiExportEmpty is returned when the selected insights have nothing to export.
+var ErrAnkiExportEmpty = errors.New("nothing to export")
+
+// Note types of exported decks. The IDs are fixed so that re-imports reuse them.
+var (
+	ankiBasicModel = &ankiModel{
+		id:     1718000000101,
+		name:   "Vibe Basic",
+		fields: []string{"Front", "Back", "Source", "Link"},
+		qfmt:   "{{Front}}",
+		afmt:   `{{FrontSide}}<hr id="answer">{{Back}}<div class="source">{{Source}} {{Link}}</div>`,
+	}
+	ankiClozeModel = &ankiModel{
+		id:     1718000000102,
+		name:   "Vibe Cloze",
+		cloze:  true,
+		fields: []string{"Text", "Extra", "Source", "Link"},
+		qfmt:   "{{cloze:Text}}",
+		afmt:   `{{cloze:Text}}{{#Extra}}<div class="extra">{{Extra}}</div>{{/Extra}}<div class="source">{{Source}} {{Link}}</div>`,
+	}
+)
+
+// AnkiService exports highlights, key points and flashcards as Anki decks.
+type AnkiService struct {
+	insightRepo   *repository.InsightRepository
+	libraryRepo   *repository.LibraryRepository
+	flashcardRepo *repository.FlashcardRepository
+	workspaces    *WorkspaceService
+	httpClient    *http.Client
+	log           *zap.Logger
+}
+
+// NewAnkiService creates a new AnkiService.
+func NewAnkiService(insightRepo *repository.InsightRepository, libraryRepo *repository.LibraryRepository, flashcardRepo *repository.FlashcardRepository, workspaces *WorkspaceService, log *zap.Logger) *AnkiService {
+	return &AnkiService{
+		insightRepo:   insightRepo,
+		libraryRepo:   libraryRepo,
+		flashcardRepo: flashcardRepo,
+		workspaces:    workspaces,
+		httpClient: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: telemetry.NewTransport(),
+		},
+		log: log,
+	}
+}
+
+// ankiSource is what the notes of one insight share.
+type ankiSource struct {
+	insight *models.Insight
+	deck    string
+	tags    []string
+	html    string // Title, with the thumbnail if exported
+	videoID string // Empty for non-YouTube sources
+}
+
+// Export writes an .apkg package with the selected content of the given insights,
+// which the user must be able to view. Each insight's notes go to a deck under the
+// root deck named after its collection path, or else its first tag.
+func (s *AnkiService) Export(ctx context.Context, userID uint, req *models.AnkiExportRequest, w io.Writer) error {
+	ids := uniqueIDs(req.InsightIDs)
+	insights, err := s.insightRepo.GetForExport(ctx, ids)
+	if err != nil {
+		return err
+	}
+	if len(insights) != len(ids) {
+		return gorm.ErrRecordNotFound
+	}
+	for i := range insights {
+		role, err := s.workspaces.InsightRole(ctx, userID, &insights[i])
+		if err != nil {
+			return err
+		}
+		if !role.Allows(models.WorkspaceRoleViewer) {
+			return ErrWorkspaceForbidden
+		}
+	}
+
+	root := ankiDeckPart(req.DeckName)
+	if root == "" {
+		root = defaultAnkiDeck
+	}
+	collectionPaths, err := s.collectionPaths(ctx, insights)
+	if err != nil {
+		return err
+	}
+
+	var cards map[uint][]models.Flashcard
+	if includeOption(req.IncludeFlashcards) {
+		list, err := s.flashcardRepo.ListByInsights(ctx, userID, ids)
+		if err != nil {
+			return err
+		}
+		cards = make(map[uint][]models.Flashcard)
+		for _, card := range list {
+			cards[card.InsightID] = append(cards[card.InsightID], card)
+		}
+	}
+
+	builder := newAPKGBuilder(time.Now(), ankiBasicModel, ankiClozeModel)
+	mediaCtx, cancel := context.WithTimeout(ctx, ankiMediaTimeout)
+	defer cancel()
+
+	for i := range insights {
+		insight := &insights[i]
+		source := &ankiSource{
+			insight: insight,
+			deck:    root,
+			html:    html.EscapeString(insight.Title),
+		}
+		if path := collectionPaths[insight.ID]; path != "" {
+			source.deck = root + "::" + path
+		} else if len(insight.Tags) > 0 {
+			source.deck = root + "::" + ankiDeckPart(insight.Tags[0].Name)
+		}
+		for _, tag := range insight.Tags {
+			source.tags = append(source.tags, ankiTagName(tag.Name))
+		}
+		if insight.SourceType == models.SourceTypeYouTube {
+			source.videoID, _ = ExtractVideoID(insight.SourceURL)
+		}
+		if includeOption(req.IncludeMedia) {
+			if name, data, ok := s.fetchThumbnail(mediaCtx, insight); ok {
+				builder.addMedia(name, data)
+				source.html = fmt.Sprintf(`<img src="%s">%s`, html.EscapeString(name), source.html)
+			}
+		}
+
+		if includeOption(req.IncludeHighlights) {
+			addHighlightNotes(builder, source)
+		}
+		if includeOption(req.IncludeKeyPoints) {
+			addKeyPointNote(builder, source)
+		}
+		for _, card := range cards[insight.ID] {
+			addFlashcardNote(builder, source, &card)
+		}
+	}
+
+	if len(builder.notes) == 0 {
+		return ErrAnkiExportEmpty
+	}
+	return builder.write(ctx, w)
+}
+
+// ExportFilename returns the download name for an export request.
+func (s *AnkiService) ExportFilename(req *models.AnkiExportRequest) string {
+	return ankiFilename(req.DeckName, time.Now())
+}
+
+// collectionPaths returns each filed insight's collection path, "::" separated.
+func (s *AnkiService) collectionPaths(ctx context.Context, insights []models.Insight) (map[uint]string, error) {
+	byID := make(map[uint]models.Collection)
+	loaded := make(map[uint]bool)
+	for _, insight := range insights {
+		if insight.CollectionID == nil || insight.WorkspaceID == nil || loaded[*insight.WorkspaceID] {
+			continue
+		}
+		loaded[*insight.WorkspaceID] = true
+		collections, err := s.libraryRepo.ListCollections(ctx, *insight.WorkspaceID)
+		if err != nil {
+			return nil, err
+		}
+		for _, collection := range collections {
+			byID[collection.ID] = collection
+		}
+	}
+
+	paths := make(map[uint]string)
+	for _, insight := range insights {
+		if insight.CollectionID == nil {
+			continue
+		}
+		var parts []string
+		id := insight.CollectionID
+		// Bounded walk up the tree in case of a cycle
+		for depth := 0; id != nil && depth < 32; depth++ {
+			collection, ok := byID[*id]
+			if !ok {
+				break
+			}
+			parts = append([]string{ankiDeckPart(collection.Name)}, parts...)
+			id = collection.ParentID
+		}
+		paths[insight.ID] = strings.Join(parts, "::")
+	}
+	return paths, nil
+}
+
+// fetchThumbnail downloads an insight's thumbnail. Failures are logged and skipped; a
+// deck without pictures is better than no deck.
+func (s *AnkiService) fetchThumbnail(ctx context.Context, insight *models.Insight) (string, []byte, bool) {
+	url := insight.ThumbnailURL
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return "", nil, false
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", nil, false
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		s.log.Debug("Failed to fetch thumbnail for anki export", zap.Uint("insight_id", insight.ID), zap.Error(err))
+		return "", nil, false
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(mediaType, "image/") {
+		return "", nil, false
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAnkiMediaBytes+1))
+	if err != nil || len(data) > maxAnkiMediaBytes {
+		return "", nil, false
+	}
+
+	ext := ".jpg"
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		ext = exts[len(exts)-1]
+	}
+	return fmt.Sprintf("vibe-insight-%d%s", insight.ID, ext), data, true
+}
+
+// addHighlightNotes adds a cloze note per highlight: the highlight clozed within its
+// surrounding content, with the highlight's note as extra.
+func addHighlightNotes(builder *apkgBuilder, source *ankiSource) {
+	transcripts := parseTranscripts(source.insight)
+	for _, highlight := range source.insight.Highlights {
+		text := strings.TrimSpace(highlight.Text)
+		if text == "" {
+			continue
+		}
+		var seconds *int
+		if item := findTranscriptItem(transcripts, text); item != nil {
+			seconds = &item.Seconds
+		}
+		before, after := highlightContext(source.insight, text)
+
+		builder.addNote(ankiNote{
+			guid:  ankiGUID(fmt.Sprintf("highlight:%d", highlight.ID)),
+			model: ankiClozeModel,
+			deck:  source.deck,
+			fields: []string{
+				ankiText(before) + "{{c1::" + ankiText(text) + "}}" + ankiText(after),
+				ankiText(highlight.Note),
+				source.sourceAt(seconds),
+				source.link(seconds),
+			},
+			tags: append([]string{"vibe::highlight"}, source.tags...),
+		})
+	}
+}
+
+// addKeyPointNote adds one cloze note per insight listing its key points, each
+// clozed separately.
+func addKeyPointNote(builder *apkgBuilder, source *ankiSource) {
+	var keyPoints []string
+	if len(source.insight.KeyPoints) > 0 {
+		_ = json.Unmarshal(source.insight.KeyPoints, &keyPoints)
+	}
+
+	var list strings.Builder
+	count := 0
+	for _, point := range keyPoints {
+		point = strings.TrimSpace(point)
+		if point == "" {
+			continue
+		}
+		count++
+		fmt.Fprintf(&list, "<li>{{c%d::%s}}</li>", count, ankiText(point))
+	}
+	if count == 0 {
+		return
+	}
+
+	builder.addNote(ankiNote{
+		guid:  ankiGUID(fmt.Sprintf("key_points:%d", source.insight.ID)),
+		model: ankiClozeModel,
+		deck:  source.deck,
+		fields: []string{
+			fmt.Sprintf("Key points of <b>%s</b>:<ol>%s</ol>", html.EscapeString(source.insight.Title), list.String()),
+			"",
+			source.html,
+			source.link(nil),
+		},
+		tags: append([]string{"vibe::key_point"}, source.tags...),
+	})
+}
+
+// addFlashcardNote adds a basic note for a generated card, carrying over its review
+// state so reviews continue in Anki where they left off.
+func addFlashcardNote(builder *apkgBuilder, source *ankiSource, card *models.Flashcard) {
+	exported := ankiCard{ord: 0, suspended: card.Suspended}
+	if card.LastReviewedAt != nil {
+		exported.review = &ankiReview{
+			intervalDays: card.IntervalDays,
+			ease:         card.EaseFactor,
+			reps:         card.Repetitions,
+			lapses:       card.Lapses,
+			due:          card.DueAt,
+		}
+	}
+
+	builder.addNote(ankiNote{
+		guid:  ankiGUID(fmt.Sprintf("flashcard:%d", card.ID)),
+		model: ankiBasicModel,
+		deck:  source.deck,
+		fields: []string{
+			ankiText(card.Front),
+			ankiText(card.Back),
+			source.sourceAt(card.Seconds),
+			source.link(card.Seconds),
+		},
+		tags:  append([]string{"vibe::qa"}, source.tags...),
+		cards: []ankiCard{exported},
+	})
+}
+
+// sourceAt returns the Source field: the title, followed by the chapter at seconds if
+// known.
+func (s *ankiSource) sourceAt(seconds *int) string {
+	if seconds == nil {
+		return s.html
+	}
+	chapter := chapterAt(s.insight.Chapters, *seconds)
+	if chapter == nil {
+		return s.html
+	}
+	return fmt.Sprintf("%s › %s", s.html, html.EscapeString(chapter.Title))
+}
+
+// link returns the Link field: a YouTube deep link at seconds for videos, otherwise
+// the source URL.
+func (s *ankiSource) link(seconds *int) string {
+	url, label := s.insight.SourceURL, "Open source"
+	if s.videoID != "" {
+		url = "https://www.youtube.com/watch?v=" + s.videoID
+		label = "Watch on YouTube"
+		if seconds != nil {
+			url += fmt.Sprintf("&t=%ds", *seconds)
+			label = fmt.Sprintf("Watch at %d:%02d", *seconds/60, *seconds%60)
+		}
+	}
+	if url == "" {
+		return ""
+	}
+	return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(url), label)
+}
+
+// highlightContext returns the content before and after a highlight, cut at sentence
+// boundaries where possible. Both are empty if the highlight is not found verbatim.
+func highlightContext(insight *models.Insight, text string) (string, string) {
+	for _, content := range []string{insight.RawContent, insight.TransContent} {
+		index := strings.Index(content, text)
+		if index < 0 {
+			continue
+		}
+		before := []rune(content[:index])
+		after := []rune(content[index+len(text):])
+
+		start := len(before) - ankiContextRunes
+		if start < 0 {
+			start = 0
+		}
+		for i := len(before) - 1; i >= start; i-- {
+			if isSentenceEnd(before[i]) {
+				start = i + 1
+				break
+			}
+		}
+		end := len(after)
+		if end > ankiContextRunes {
+			end = ankiContextRunes
+		}
+		for i := 0; i < end; i++ {
+			if isSentenceEnd(after[i]) {
+				end = i + 1
+				break
+			}
+		}
+		return strings.TrimLeft(string(before[start:]), " \n"), strings.TrimRight(string(after[:end]), " \n")
+	}
+	return "", ""
+}
+
+func isSentenceEnd(r rune) bool {
+	switch r {
+	case '.', '!', '?', '\n', '。', '！', '？':
+		return true
+	}
+	return false
+}
+
+// includeOption reads an include flag that defaults to true.
+func includeOption(value *bool) bool {
+	return value == nil || *value
+}
+
+// ankiFilename returns the download name of an export.
+func ankiFilename(deckName string, now time.Time) string {
+	var name strings.Builder
+	for _, r := range strings.ToLower(deckName) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			name.WriteRune(r)
+		case name.Len() > 0 && !strings.HasSuffix(name.String(), "-"):
+			name.WriteByte('-')
+		}
+	}
+	base := strings.Trim(name.String(), "-")
+	if base == "" {
+		base = "vibe"
+	}
+	return fmt.Sprintf("%s-%s.apkg", base, now.Format("20060102"))
+}
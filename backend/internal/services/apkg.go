@@ -0,0 +1,456 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // SQLite driver for the Anki collection
+)
+
+// Anki package (.apkg) writer. A package is a zip holding collection.anki2, an Anki
+// schema 11 SQLite collection, a "media" JSON index mapping the numbered media files
+// of the zip to their names, and the media files themselves.
+
+// ankiSchema creates the tables and indexes of a schema 11 collection.
+const ankiSchema = `
+CREATE TABLE col (
+	id integer primary key, crt integer not null, mod integer not null, scm integer not null,
+	ver integer not null, dty integer not null, usn integer not null, ls integer not null,
+	conf text not null, models text not null, decks text not null, dconf text not null,
+	tags text not null
+);
+CREATE TABLE notes (
+	id integer primary key, guid text not null, mid integer not null, mod integer not null,
+	usn integer not null, tags text not null, flds text not null, sfld integer not null,
+	csum integer not null, flags integer not null, data text not null
+);
+CREATE TABLE cards (
+	id integer primary key, nid integer not null, did integer not null, ord integer not null,
+	mod integer not null, usn integer not null, type integer not null, queue integer not null,
+	due integer not null, ivl integer not null, factor integer not null, reps integer not null,
+	lapses integer not null, left integer not null, odue integer not null, odid integer not null,
+	flags integer not null, data text not null
+);
+CREATE TABLE revlog (
+	id integer primary key, cid integer not null, usn integer not null, ease integer not null,
+	ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null,
+	type integer not null
+);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
+
+// ankiDefaultDeckID is the "Default" deck every collection has.
+const ankiDefaultDeckID = 1
+
+// ankiCardCSS styles both note types.
+const ankiCardCSS = `.card { font-family: arial; font-size: 20px; text-align: left; color: black; background-color: white; }
+.cloze { font-weight: bold; color: blue; }
+.extra { margin-top: 12px; color: #555; }
+.source { margin-top: 16px; font-size: 14px; color: #888; }
+.source img { display: block; max-width: 240px; margin-bottom: 6px; }`
+
+var ankiClozePattern = regexp.MustCompile(`\{\{c(\d+)::`)
+var ankiTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// ankiModel is a note type.
+type ankiModel struct {
+	id     int64
+	name   string
+	cloze  bool
+	fields []string
+	qfmt   string // Question template
+	afmt   string // Answer template
+}
+
+// ankiReview is the review state of a card that has been studied.
+type ankiReview struct {
+	intervalDays int
+	ease         float64
+	reps         int
+	lapses       int
+	due          time.Time
+}
+
+// ankiCard is one card of a note. Cloze notes get a card per cloze number.
+type ankiCard struct {
+	ord       int
+	review    *ankiReview // Nil for a new card
+	suspended bool
+}
+
+// ankiNote is a note with its fields as HTML, in the order of its model's fields.
+type ankiNote struct {
+	guid   string // Stable across exports, so re-importing updates instead of duplicating
+	model  *ankiModel
+	deck   string // Deck name, "::" separating levels
+	fields []string
+	tags   []string
+	cards  []ankiCard // Derived from the cloze numbers for cloze notes if empty
+}
+
+// ankiMedia is a media file referenced by name from note fields.
+type ankiMedia struct {
+	name string
+	data []byte
+}
+
+// apkgBuilder collects notes and media and writes them as a package.
+type apkgBuilder struct {
+	now    time.Time
+	models []*ankiModel
+	notes  []ankiNote
+	media  []ankiMedia
+}
+
+func newAPKGBuilder(now time.Time, noteTypes ...*ankiModel) *apkgBuilder {
+	return &apkgBuilder{now: now, models: noteTypes}
+}
+
+func (b *apkgBuilder) addNote(note ankiNote) {
+	b.notes = append(b.notes, note)
+}
+
+func (b *apkgBuilder) addMedia(name string, data []byte) {
+	b.media = append(b.media, ankiMedia{name: name, data: data})
+}
+
+// write writes the package to w. The collection is built in a temporary file since
+// SQLite needs one.
+func (b *apkgBuilder) write(ctx context.Context, w io.Writer) error {
+	file, err := os.CreateTemp("", "vibe-anki-*.anki2")
+	if err != nil {
+		return err
+	}
+	path := file.Name()
+	file.Close()
+	defer os.Remove(path)
+
+	if err := b.writeCollection(ctx, path); err != nil {
+		return fmt.Errorf("failed to write anki collection: %w", err)
+	}
+	collection, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	entry, err := archive.Create("collection.anki2")
+	if err != nil {
+		return err
+	}
+	if _, err := entry.Write(collection); err != nil {
+		return err
+	}
+
+	index := make(map[string]string, len(b.media))
+	for i, media := range b.media {
+		index[strconv.Itoa(i)] = media.name
+		entry, err := archive.Create(strconv.Itoa(i))
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(media.data); err != nil {
+			return err
+		}
+	}
+	entry, err = archive.Create("media")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(entry).Encode(index); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func (b *apkgBuilder) writeCollection(ctx context.Context, path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, ankiSchema); err != nil {
+		return err
+	}
+
+	// Review due dates are days since the collection was created; Anki shifts them to
+	// the importing collection's day count on import
+	year, month, day := b.now.UTC().Date()
+	created := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	mod := b.now.Unix()
+
+	deckIDs := b.deckIDs()
+	colConfig, modelsJSON, decksJSON, dconfJSON, err := b.collectionJSON(deckIDs, mod)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO col (id, crt, mod, scm, ver, dty, usn, ls, conf, models, decks, dconf, tags)
+		 VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		created.Unix(), b.now.UnixMilli(), b.now.UnixMilli(), colConfig, modelsJSON, decksJSON, dconfJSON)
+	if err != nil {
+		return err
+	}
+
+	noteStmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO notes (id, guid, mid, mod, usn, tags, flds, sfld, csum, flags, data)
+		 VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`)
+	if err != nil {
+		return err
+	}
+	defer noteStmt.Close()
+	cardStmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO cards (id, nid, did, ord, mod, usn, type, queue, due, ivl, factor, reps, lapses, left, odue, odid, flags, data)
+		 VALUES (?, ?, ?, ?, ?, -1, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, 0, '')`)
+	if err != nil {
+		return err
+	}
+	defer cardStmt.Close()
+
+	// Note and card IDs are millisecond timestamps in Anki; count up from now
+	nextID := b.now.UnixMilli()
+	newPosition := 0
+	for i := range b.notes {
+		note := &b.notes[i]
+		nextID++
+		noteID := nextID
+		sortField := ankiStripHTML(note.fields[0])
+		_, err := noteStmt.ExecContext(ctx,
+			noteID, note.guid, note.model.id, mod, ankiTags(note.tags),
+			strings.Join(note.fields, "\x1f"), sortField, ankiChecksum(sortField))
+		if err != nil {
+			return err
+		}
+
+		cards := note.cards
+		if len(cards) == 0 {
+			cards = defaultAnkiCards(note)
+		}
+		for _, card := range cards {
+			nextID++
+			cardType, queue, due, ivl, factor, reps, lapses := 0, 0, 0, 0, 0, 0, 0
+			if card.review != nil {
+				cardType, queue = 2, 2
+				due = int(card.review.due.Sub(created).Hours() / 24)
+				ivl = card.review.intervalDays
+				if ivl < 1 {
+					ivl = 1
+				}
+				factor = int(card.review.ease*1000 + 0.5)
+				reps, lapses = card.review.reps, card.review.lapses
+			} else {
+				newPosition++
+				due = newPosition
+			}
+			if card.suspended {
+				queue = -1
+			}
+			_, err := cardStmt.ExecContext(ctx,
+				nextID, noteID, deckIDs[note.deck], card.ord, mod,
+				cardType, queue, due, ivl, factor, reps, lapses)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// deckIDs assigns every deck, and every parent of a nested deck, a stable ID.
+func (b *apkgBuilder) deckIDs() map[string]int64 {
+	ids := make(map[string]int64)
+	for _, note := range b.notes {
+		parts := strings.Split(note.deck, "::")
+		for i := range parts {
+			name := strings.Join(parts[:i+1], "::")
+			if _, ok := ids[name]; ok {
+				continue
+			}
+			hash := fnv.New64a()
+			hash.Write([]byte(name))
+			id := int64(hash.Sum64() & (1<<52 - 1))
+			if id <= ankiDefaultDeckID {
+				id += ankiDefaultDeckID + 1
+			}
+			ids[name] = id
+		}
+	}
+	return ids
+}
+
+// collectionJSON returns the configuration, note types, decks and deck options of
+// the col row.
+func (b *apkgBuilder) collectionJSON(deckIDs map[string]int64, mod int64) (conf, noteTypes, decks, dconf string, err error) {
+	deck := func(id int64, name string) map[string]any {
+		return map[string]any{
+			"id": id, "name": name, "desc": "", "conf": 1, "dyn": 0, "collapsed": false,
+			"extendNew": 10, "extendRev": 50, "mod": mod, "usn": -1,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+		}
+	}
+	deckMap := map[string]any{strconv.Itoa(ankiDefaultDeckID): deck(ankiDefaultDeckID, "Default")}
+	var firstDeck int64 = ankiDefaultDeckID
+	names := make([]string, 0, len(deckIDs))
+	for name := range deckIDs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		deckMap[strconv.FormatInt(deckIDs[name], 10)] = deck(deckIDs[name], name)
+		if i == 0 {
+			firstDeck = deckIDs[name]
+		}
+	}
+
+	modelMap := make(map[string]any, len(b.models))
+	for _, model := range b.models {
+		fields := make([]map[string]any, len(model.fields))
+		for i, name := range model.fields {
+			fields[i] = map[string]any{
+				"name": name, "ord": i, "font": "Arial", "size": 20,
+				"rtl": false, "sticky": false, "media": []string{},
+			}
+		}
+		templateName, modelType := "Card 1", 0
+		if model.cloze {
+			templateName, modelType = "Cloze", 1
+		}
+		modelMap[strconv.FormatInt(model.id, 10)] = map[string]any{
+			"id": model.id, "name": model.name, "type": modelType, "mod": mod, "usn": -1,
+			"sortf": 0, "did": firstDeck, "flds": fields, "css": ankiCardCSS,
+			"tmpls": []map[string]any{{
+				"name": templateName, "ord": 0, "qfmt": model.qfmt, "afmt": model.afmt,
+				"bqfmt": "", "bafmt": "", "did": nil,
+			}},
+			"req":       [][]any{{0, "any", []int{0}}},
+			"tags":      []string{},
+			"vers":      []any{},
+			"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+			"latexPost": "\\end{document}",
+		}
+	}
+
+	dconfMap := map[string]any{"1": map[string]any{
+		"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true, "timer": 0, "replayq": true,
+		"new": map[string]any{
+			"bury": true, "delays": []int{1, 10}, "initialFactor": 2500, "ints": []int{1, 4, 7},
+			"order": 1, "perDay": 20, "separate": true,
+		},
+		"lapse": map[string]any{"delays": []int{10}, "leechAction": 0, "leechFails": 8, "minInt": 1, "mult": 0},
+		"rev": map[string]any{
+			"bury": true, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1, "maxIvl": 36500, "minSpace": 1, "perDay": 100,
+		},
+	}}
+
+	var currentModel int64
+	if len(b.models) > 0 {
+		currentModel = b.models[0].id
+	}
+	confMap := map[string]any{
+		"activeDecks": []int64{firstDeck}, "curDeck": firstDeck, "curModel": strconv.FormatInt(currentModel, 10),
+		"addToCur": true, "collapseTime": 1200, "dueCounts": true, "estTimes": true, "newBury": true,
+		"newSpread": 0, "nextPos": 1, "sortBackwards": false, "sortType": "noteFld", "timeLim": 0,
+	}
+
+	encoded := make([]string, 4)
+	for i, value := range []any{confMap, modelMap, deckMap, dconfMap} {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", "", "", "", err
+		}
+		encoded[i] = string(data)
+	}
+	return encoded[0], encoded[1], encoded[2], encoded[3], nil
+}
+
+// defaultAnkiCards returns one new card per cloze number of a cloze note, or a single
+// new card for other notes.
+func defaultAnkiCards(note *ankiNote) []ankiCard {
+	if !note.model.cloze {
+		return []ankiCard{{ord: 0}}
+	}
+	seen := make(map[int]bool)
+	var cards []ankiCard
+	for _, match := range ankiClozePattern.FindAllStringSubmatch(note.fields[0], -1) {
+		number, err := strconv.Atoi(match[1])
+		if err != nil || number < 1 || seen[number] {
+			continue
+		}
+		seen[number] = true
+		cards = append(cards, ankiCard{ord: number - 1})
+	}
+	sort.Slice(cards, func(i, j int) bool { return cards[i].ord < cards[j].ord })
+	return cards
+}
+
+// ankiText escapes plain text for a note field, keeping line breaks.
+func ankiText(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+// ankiStripHTML returns the text of a field as Anki computes its sort field.
+func ankiStripHTML(field string) string {
+	return strings.TrimSpace(html.UnescapeString(ankiTagPattern.ReplaceAllString(field, "")))
+}
+
+// ankiChecksum is the duplicate-detection checksum of a note: the first 8 hex digits
+// of the SHA-1 of its sort field.
+func ankiChecksum(sortField string) int64 {
+	sum := sha1.Sum([]byte(sortField))
+	value, _ := strconv.ParseInt(hex.EncodeToString(sum[:4]), 16, 64)
+	return value
+}
+
+// ankiGUID returns a stable note GUID for a source key.
+func ankiGUID(key string) string {
+	sum := sha1.Sum([]byte("vibe:" + key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ankiTags formats note tags as Anki stores them: space separated with surrounding
+// spaces. Tags cannot contain spaces.
+func ankiTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return " " + strings.Join(tags, " ") + " "
+}
+
+// ankiTagName turns a tag name into an Anki tag.
+func ankiTagName(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
+
+// ankiDeckPart makes a name usable as one level of a deck name.
+func ankiDeckPart(name string) string {
+	return strings.TrimSpace(strings.ReplaceAll(name, "::", ":"))
+}
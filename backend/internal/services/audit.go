@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+	"vibe-backend/internal/telemetry"
+)
+
+const (
+	// auditExportBatchSize is how many entries are loaded at a time for a CSV export.
+	auditExportBatchSize = 1000
+	// auditExportMaxRows caps the size of a CSV export; narrow the filter for more.
+	auditExportMaxRows = 100000
+)
+
+// auditCSVHeader is the header row of audit log CSV exports.
+var auditCSVHeader = []string{
+	"id", "created_at", "action", "actor_id", "actor_name", "workspace_id",
+	"target_type", "target_id", "ip_address", "user_agent", "request_id", "details",
+}
+
+// AuditService records security- and data-relevant actions in the append-only
+// audit log and lets users and workspace owners query it.
+type AuditService struct {
+	repo       *repository.AuditRepository
+	workspaces *WorkspaceService
+	log        *zap.Logger
+}
+
+// NewAuditService creates a new AuditService.
+func NewAuditService(repo *repository.AuditRepository, workspaces *WorkspaceService, log *zap.Logger) *AuditService {
+	return &AuditService{
+		repo:       repo,
+		workspaces: workspaces,
+		log:        log,
+	}
+}
+
+// Record stores an audit log entry. The request ID is taken from ctx if not set.
+// Failures are logged rather than returned so callers never fail a request over auditing.
+func (s *AuditService) Record(ctx context.Context, entry *models.AuditLog) {
+	if entry.RequestID == "" {
+		entry.RequestID = telemetry.RequestID(ctx)
+	}
+	entry.UserAgent = truncate(entry.UserAgent, 500)
+	entry.CreatedAt = time.Now()
+
+	if err := s.repo.Create(ctx, entry); err != nil {
+		s.log.Error("Failed to record audit log entry",
+			zap.String("action", string(entry.Action)),
+			zap.String("request_id", entry.RequestID),
+			zap.Error(err),
+		)
+	}
+}
+
+// AuditDetails encodes the details of an audit log entry.
+func AuditDetails(details map[string]interface{}) []byte {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// Scope restricts filter to the log the user may see: with a workspace, that
+// workspace's log (owners only); otherwise the user's own.
+func (s *AuditService) Scope(ctx context.Context, user *models.User, workspaceID *uint, filter *models.AuditFilter) error {
+	if workspaceID == nil {
+		filter.UserID, filter.WorkspaceID, filter.ActorID = &user.ID, nil, nil
+		return nil
+	}
+	workspace, _, err := s.workspaces.Resolve(ctx, user, workspaceID, models.WorkspaceRoleOwner)
+	if err != nil {
+		return err
+	}
+	filter.UserID, filter.WorkspaceID = nil, &workspace.ID
+	return nil
+}
+
+// List returns a page of the entries matching a scoped filter, newest first.
+func (s *AuditService) List(ctx context.Context, filter models.AuditFilter, page models.PageRequest) (*models.AuditLogListResponse, error) {
+	entries, info, err := s.repo.ListPage(ctx, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = make([]models.AuditLog, 0)
+	}
+	return &models.AuditLogListResponse{Items: entries, PageInfo: info}, nil
+}
+
+// WriteCSV writes the entries matching a scoped filter to w as CSV, newest first,
+// up to auditExportMaxRows.
+func (s *AuditService) WriteCSV(ctx context.Context, filter models.AuditFilter, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(auditCSVHeader); err != nil {
+		return err
+	}
+
+	page := models.PageRequest{Limit: auditExportBatchSize, Sort: models.SortCreated, Desc: true}
+	for written := 0; written < auditExportMaxRows; {
+		entries, info, err := s.repo.ListPage(ctx, filter, page)
+		if err != nil {
+			return err
+		}
+		for i := range entries {
+			if err := cw.Write(auditCSVRecord(&entries[i])); err != nil {
+				return err
+			}
+		}
+		written += len(entries)
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+		if !info.HasMore {
+			break
+		}
+		page.Cursor = info.NextCursor
+	}
+	return nil
+}
+
+// auditCSVRecord converts an entry to a CSV row.
+func auditCSVRecord(entry *models.AuditLog) []string {
+	optional := func(id *uint) string {
+		if id == nil {
+			return ""
+		}
+		return strconv.FormatUint(uint64(*id), 10)
+	}
+	actorName := ""
+	if entry.Actor != nil {
+		actorName = entry.Actor.Name
+	}
+	return []string{
+		strconv.FormatUint(uint64(entry.ID), 10),
+		entry.CreatedAt.UTC().Format(time.RFC3339),
+		string(entry.Action),
+		optional(entry.ActorID),
+		csvSafe(actorName),
+		optional(entry.WorkspaceID),
+		entry.TargetType,
+		optional(entry.TargetID),
+		entry.IPAddress,
+		csvSafe(entry.UserAgent),
+		csvSafe(entry.RequestID),
+		csvSafe(string(entry.Details)),
+	}
+}
+
+// csvSafe keeps user-controlled values from being evaluated as formulas when the
+// CSV is opened in a spreadsheet.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"vibe-backend/internal/models"
+)
+
+const (
+	// minChapters and minChapterSeconds follow YouTube's rules for description
+	// chapters: at least three, starting at 0:00, each at least ten seconds long.
+	minChapters       = 3
+	minChapterSeconds = 10
+	// minSegmentDuration is the shortest video worth segmenting with the LLM.
+	minSegmentDuration = 3 * 60
+	// maxSegmentLines bounds the transcript sent for segmentation; longer transcripts
+	// are merged into coarser blocks.
+	maxSegmentLines = 400
+	// maxSegmentLineRunes bounds the text of one transcript block.
+	maxSegmentLineRunes = 200
+	// maxAIChapters bounds the chapters of one video.
+	maxAIChapters = 30
+)
+
+// descriptionChapterPattern matches a description line with a leading timestamp,
+// e.g. "00:00 Intro", "1:02:03 - Q&A" or "(12:30) Wrap-up".
+var descriptionChapterPattern = regexp.MustCompile(`^[\s\-*•▶►]*[(\[]?((?:\d{1,2}:)?\d{1,2}:\d{2})[)\]]?\s*[-–—:|.)]*\s*(.+?)\s*$`)
+
+// ChapterService detects the chapters of a video.
+type ChapterService struct {
+	llm *openRouterClient
+	log *zap.Logger
+}
+
+// NewChapterService creates a new ChapterService.
+func NewChapterService(apiKey, model string, log *zap.Logger) *ChapterService {
+	return &ChapterService{
+		llm: newOpenRouterClient(apiKey, model, "Vibe Chapters", log),
+		log: log,
+	}
+}
+
+// SetUsageService sets the service that meters and budgets LLM calls.
+func (s *ChapterService) SetUsageService(svc *UsageService) {
+	s.llm.usage = svc
+}
+
+// Detect returns a video's chapters: those reported with its metadata (yt-dlp), else
+// those listed in its description, else an LLM topic segmentation of the transcript.
+// metadata may be nil. It returns nil if no chapters could be found.
+func (s *ChapterService) Detect(ctx context.Context, metadata *VideoMetadata, transcripts []models.TranscriptItem) ([]ChapterData, models.ChapterSource) {
+	duration := 0
+	if metadata != nil {
+		duration = metadata.Duration
+		if chapters := normalizeChapters(metadata.Chapters, duration); len(chapters) > 0 {
+			return chapters, models.ChapterSourceYouTube
+		}
+		if chapters := parseDescriptionChapters(metadata.Description, duration); len(chapters) > 0 {
+			return chapters, models.ChapterSourceDescription
+		}
+	}
+	if duration == 0 && len(transcripts) > 0 {
+		duration = transcripts[len(transcripts)-1].Seconds
+	}
+	if duration < minSegmentDuration || len(transcripts) < minChapters {
+		return nil, ""
+	}
+
+	chapters, err := s.segment(ctx, transcripts, duration)
+	if err != nil {
+		s.log.Warn("Failed to segment transcript into chapters", zap.Error(err))
+		return nil, ""
+	}
+	return chapters, models.ChapterSourceAI
+}
+
+// parseDescriptionChapters parses the chapter list of a video description.
+func parseDescriptionChapters(description string, duration int) []ChapterData {
+	var chapters []ChapterData
+	for _, line := range strings.Split(description, "\n") {
+		matches := descriptionChapterPattern.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+		seconds, ok := parseChapterTimestamp(matches[1])
+		if !ok {
+			continue
+		}
+		chapters = append(chapters, ChapterData{Title: matches[2], Seconds: seconds})
+	}
+	// Unlike yt-dlp chapters, a timestamp list that does not start at 0:00 is not a
+	// chapter list (e.g. "best moments" links)
+	if len(chapters) == 0 || chapters[0].Seconds != 0 {
+		return nil
+	}
+	return normalizeChapters(chapters, duration)
+}
+
+// normalizeChapters sorts chapters, drops those past the end of the video or too
+// close to the previous one, and fills in timestamps. It returns nil if fewer than
+// minChapters remain.
+func normalizeChapters(chapters []ChapterData, duration int) []ChapterData {
+	sorted := make([]ChapterData, 0, len(chapters))
+	for _, chapter := range chapters {
+		chapter.Title = strings.TrimSpace(chapter.Title)
+		if chapter.Title == "" || chapter.Seconds < 0 || (duration > 0 && chapter.Seconds >= duration) {
+			continue
+		}
+		sorted = append(sorted, chapter)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Seconds < sorted[j].Seconds })
+
+	result := make([]ChapterData, 0, len(sorted))
+	for _, chapter := range sorted {
+		if len(result) > 0 && chapter.Seconds-result[len(result)-1].Seconds < minChapterSeconds {
+			continue
+		}
+		chapter.Title = truncate(chapter.Title, 500)
+		chapter.Timestamp = formatChapterTimestamp(chapter.Seconds)
+		result = append(result, chapter)
+	}
+	if len(result) < minChapters {
+		return nil
+	}
+	result[0].Seconds, result[0].Timestamp = 0, formatChapterTimestamp(0)
+	return result
+}
+
+// segment asks the LLM to split the transcript into topical chapters. Chapter starts
+// are snapped to transcript segment starts.
+func (s *ChapterService) segment(ctx context.Context, transcripts []models.TranscriptItem, duration int) ([]ChapterData, error) {
+	// Merge segments into blocks so long transcripts fit the prompt
+	blockSeconds := duration / maxSegmentLines
+	var lines strings.Builder
+	for i := 0; i < len(transcripts); {
+		start := transcripts[i].Seconds
+		var text strings.Builder
+		for ; i < len(transcripts) && (text.Len() == 0 || transcripts[i].Seconds-start < blockSeconds); i++ {
+			text.WriteString(transcripts[i].Text)
+			text.WriteByte(' ')
+		}
+		line := []rune(strings.Join(strings.Fields(text.String()), " "))
+		if len(line) > maxSegmentLineRunes {
+			line = line[:maxSegmentLineRunes]
+		}
+		fmt.Fprintf(&lines, "[%d] %s\n", start, string(line))
+	}
+
+	prompt := fmt.Sprintf(`你是一名视频编辑。下面是一段时长 %s 的视频字幕，每行以该段开始的秒数开头。请按话题把视频划分为章节。
+
+要求：
+- 章节数量与内容匹配，通常每 3 到 10 分钟一个章节，最多 %d 个
+- 第一个章节从 0 秒开始
+- 每个章节的开始秒数必须是下面某一行开头的秒数
+- 标题简洁，不超过 10 个词，使用与字幕相同的语言
+
+字幕：
+%s
+请以JSON格式返回：
+{"chapters": [{"start_seconds": 0, "title": "章节标题"}]}
+
+只返回JSON，不要其他文字。`, formatChapterTimestamp(duration), maxAIChapters, lines.String())
+
+	response, err := s.llm.complete(ctx, models.LLMFeatureChapters, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call AI service: %w", err)
+	}
+
+	var result struct {
+		Chapters []struct {
+			StartSeconds int    `json:"start_seconds"`
+			Title        string `json:"title"`
+		} `json:"chapters"`
+	}
+	if err := json.Unmarshal([]byte(stripJSONCodeFence(response)), &result); err != nil {
+		s.log.Error("Failed to parse chapter response",
+			zap.Error(err),
+			zap.String("raw_response", response),
+		)
+		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+
+	chapters := make([]ChapterData, 0, len(result.Chapters))
+	for _, chapter := range result.Chapters {
+		chapters = append(chapters, ChapterData{
+			Title:   chapter.Title,
+			Seconds: snapToTranscript(transcripts, chapter.StartSeconds),
+		})
+		if len(chapters) == maxAIChapters {
+			break
+		}
+	}
+	chapters = normalizeChapters(chapters, duration)
+	if chapters == nil {
+		return nil, fmt.Errorf("AI returned fewer than %d usable chapters", minChapters)
+	}
+	return chapters, nil
+}
+
+// snapToTranscript returns the start of the last transcript segment at or before
+// seconds.
+func snapToTranscript(transcripts []models.TranscriptItem, seconds int) int {
+	i := sort.Search(len(transcripts), func(i int) bool { return transcripts[i].Seconds > seconds })
+	if i == 0 {
+		return 0
+	}
+	return transcripts[i-1].Seconds
+}
+
+// chapterAt returns the chapter playing at seconds, or nil before the first one.
+func chapterAt(chapters []models.Chapter, seconds int) *models.Chapter {
+	var current *models.Chapter
+	for i := range chapters {
+		if chapters[i].Seconds > seconds {
+			break
+		}
+		current = &chapters[i]
+	}
+	return current
+}
+
+// parseChapterTimestamp parses "M:SS", "MM:SS" or "H:MM:SS".
+func parseChapterTimestamp(timestamp string) (int, bool) {
+	parts := strings.Split(timestamp, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	seconds := 0
+	for i, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil || (i > 0 && value >= 60) {
+			return 0, false
+		}
+		seconds = seconds*60 + value
+	}
+	return seconds, true
+}
+
+// formatChapterTimestamp formats seconds as "MM:SS", or "H:MM:SS" past an hour.
+func formatChapterTimestamp(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return SecondsToTimestamp(seconds)
+}
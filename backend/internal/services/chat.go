@@ -7,12 +7,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
 	"go.uber.org/zap"
+	"vibe-backend/internal/metrics"
 	"vibe-backend/internal/models"
 	"vibe-backend/internal/repository"
+	"vibe-backend/internal/telemetry"
 )
 
 // ChatService handles AI chat operations.
@@ -23,6 +26,9 @@ type ChatService struct {
 	openRouterAPIKey string
 	chatModel        string
 	httpClient       *http.Client
+	searchService    *SearchService
+	usage            *UsageService
+	llm              *openRouterClient // Non-streaming calls
 	log              *zap.Logger
 }
 
@@ -61,14 +67,42 @@ func NewChatService(
 		openRouterAPIKey: apiKey,
 		chatModel:        chatModel,
 		httpClient: &http.Client{
-			Timeout: 120 * time.Second,
+			Timeout:   120 * time.Second,
+			Transport: telemetry.NewTransport(),
 		},
+		llm: newOpenRouterClient(apiKey, chatModel, "VIBE Engineering Playbook", log),
 		log: log,
 	}
 }
 
-// ChatStream sends a message and returns a channel for streaming responses.
-func (s *ChatService) ChatStream(ctx context.Context, insightID uint, message string, highlightID *uint) (<-chan models.ChatStreamEvent, error) {
+// SetSearchService sets the search service used to index chat messages.
+func (s *ChatService) SetSearchService(svc *SearchService) {
+	s.searchService = svc
+}
+
+// SetUsageService sets the service that meters and budgets LLM calls.
+func (s *ChatService) SetUsageService(svc *UsageService) {
+	s.usage = svc
+	s.llm.usage = svc
+}
+
+// indexMessage adds a saved chat message to the search index.
+func (s *ChatService) indexMessage(ctx context.Context, message *models.ChatMessage) {
+	if s.searchService == nil {
+		return
+	}
+	s.searchService.Reindex("chat", message.InsightID, func() error {
+		return s.searchService.IndexChatMessage(ctx, message)
+	})
+}
+
+// ChatStream sends a message on behalf of userID and returns a channel for streaming responses.
+func (s *ChatService) ChatStream(ctx context.Context, insightID, userID uint, message string, highlightID *uint) (<-chan models.ChatStreamEvent, error) {
+	ctx = WithUsageUser(ctx, userID)
+	if err := s.usage.Check(ctx, models.LLMFeatureChat); err != nil {
+		return nil, err
+	}
+
 	// Get the insight for context
 	insight, err := s.insightRepo.GetByID(ctx, insightID)
 	if err != nil {
@@ -85,17 +119,24 @@ func (s *ChatService) ChatStream(ctx context.Context, insightID uint, message st
 	// Save user message
 	userMessage := &models.ChatMessage{
 		InsightID:   insightID,
-		UserID:      0, // TODO: Get from context/auth
+		UserID:      userID,
 		Role:        "user",
 		Content:     message,
 		HighlightID: highlightID,
 	}
 	if err := s.chatRepo.CreateMessage(ctx, userMessage); err != nil {
 		s.log.Error("Failed to save user message", zap.Error(err))
+	} else {
+		s.indexMessage(ctx, userMessage)
+	}
+
+	chapters, err := s.insightRepo.GetChapters(ctx, insightID)
+	if err != nil {
+		s.log.Warn("Failed to get insight chapters", zap.Error(err))
 	}
 
 	// Build system prompt with context
-	systemPrompt := s.buildSystemPrompt(insight)
+	systemPrompt := s.buildSystemPrompt(insight, chapters)
 
 	// Build messages array
 	messages := s.buildMessages(systemPrompt, history, message)
@@ -106,7 +147,7 @@ func (s *ChatService) ChatStream(ctx context.Context, insightID uint, message st
 	// Start streaming in goroutine
 	go func() {
 		defer close(responseChan)
-		s.streamFromOpenRouter(ctx, messages, insightID, responseChan)
+		s.streamFromOpenRouter(ctx, messages, insightID, userID, chapters, responseChan)
 	}()
 
 	return responseChan, nil
@@ -126,6 +167,7 @@ func (s *ChatService) GetChatHistory(ctx context.Context, analysisID uint) (*mod
 	for i, msg := range messages {
 		response.Messages[i] = models.ChatMessageResponse{
 			ID:        msg.ID,
+			UserID:    msg.UserID,
 			Role:      msg.Role,
 			Content:   msg.Content,
 			CreatedAt: msg.CreatedAt,
@@ -181,7 +223,7 @@ func (s *ChatService) AnalyzeEntities(ctx context.Context, insightID uint) (*mod
 		zap.String("model", s.chatModel),
 	)
 
-	response, err := s.callOpenRouter(ctx, prompt)
+	response, err := s.llm.complete(ctx, models.LLMFeatureEntityAnalysis, prompt)
 	if err != nil {
 		s.log.Error("Failed to analyze entities",
 			zap.Uint("insight_id", insightID),
@@ -232,9 +274,10 @@ func (s *ChatService) AnalyzeEntities(ctx context.Context, insightID uint) (*mod
 	return &result, nil
 }
 
-// buildSystemPrompt creates the system prompt with insight context.
-func (s *ChatService) buildSystemPrompt(insight *models.Insight) string {
-	return fmt.Sprintf(`你是一个智能阅读助手。用户正在阅读以下内容：
+// buildSystemPrompt creates the system prompt with insight context. With chapters, the
+// model is asked to cite them by timestamp so answers can link into the video.
+func (s *ChatService) buildSystemPrompt(insight *models.Insight, chapters []models.Chapter) string {
+	prompt := fmt.Sprintf(`你是一个智能阅读助手。用户正在阅读以下内容：
 
 标题: %s
 作者: %s
@@ -245,6 +288,46 @@ func (s *ChatService) buildSystemPrompt(insight *models.Insight) string {
 2. 如果内容中没有相关信息，可以结合你的知识回答，但需说明
 3. 保持回答简洁、有洞察力
 4. 支持 Markdown 格式`, insight.Title, insight.Author, insight.Summary)
+
+	if len(chapters) == 0 {
+		return prompt
+	}
+	var outline strings.Builder
+	for _, chapter := range chapters {
+		fmt.Fprintf(&outline, "[%s] %s\n", chapter.Timestamp, chapter.Title)
+	}
+	return prompt + fmt.Sprintf(`
+5. 引用视频内容时，在相关句子后用方括号标注所属章节的开始时间，例如 [%s]
+
+视频章节：
+%s`, chapters[0].Timestamp, outline.String())
+}
+
+// chatCitationPattern matches a cited timestamp such as [05:12] or [1:02:03].
+var chatCitationPattern = regexp.MustCompile(`\[((?:\d{1,2}:)?\d{1,2}:\d{2})\]`)
+
+// chatCitations returns the chapters cited in an answer, in order of first citation.
+func chatCitations(content string, chapters []models.Chapter) []models.ChatCitation {
+	var citations []models.ChatCitation
+	seen := make(map[int]bool)
+	for _, match := range chatCitationPattern.FindAllStringSubmatch(content, -1) {
+		seconds, ok := parseChapterTimestamp(match[1])
+		if !ok || seen[seconds] {
+			continue
+		}
+		chapter := chapterAt(chapters, seconds)
+		if chapter == nil {
+			continue
+		}
+		seen[seconds] = true
+		citations = append(citations, models.ChatCitation{
+			Timestamp:    match[1],
+			Seconds:      seconds,
+			ChapterTitle: chapter.Title,
+			ChapterStart: chapter.Seconds,
+		})
+	}
+	return citations
 }
 
 // buildMessages constructs the messages array for the API call.
@@ -279,15 +362,19 @@ func (s *ChatService) buildMessages(systemPrompt string, history []models.ChatMe
 }
 
 // streamFromOpenRouter handles the SSE streaming from OpenRouter.
-func (s *ChatService) streamFromOpenRouter(ctx context.Context, messages []map[string]string, insightID uint, responseChan chan<- models.ChatStreamEvent) {
-	const openRouterURL = "https://openrouter.ai/api/v1/chat/completions"
-
+func (s *ChatService) streamFromOpenRouter(ctx context.Context, messages []map[string]string, insightID, userID uint, chapters []models.Chapter, responseChan chan<- models.ChatStreamEvent) {
 	requestBody := map[string]interface{}{
 		"model":    s.chatModel,
 		"messages": messages,
 		"stream":   true,
+		"usage":    map[string]bool{"include": true}, // Token counts arrive with the last chunk
 	}
 
+	// err is the outcome of the stream in metrics
+	start := time.Now()
+	var err error
+	defer func() { metrics.ObserveLLMRequest(string(models.LLMFeatureChat), start, err) }()
+
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
 		s.log.Error("Failed to marshal request", zap.Error(err))
@@ -338,6 +425,7 @@ func (s *ChatService) streamFromOpenRouter(ctx context.Context, messages []map[s
 				zap.Uint("insight_id", insightID),
 			)
 		}
+		err = fmt.Errorf("OpenRouter API returned status %d", resp.StatusCode)
 		
 		responseChan <- models.ChatStreamEvent{Done: true}
 		return
@@ -345,6 +433,8 @@ func (s *ChatService) streamFromOpenRouter(ctx context.Context, messages []map[s
 
 	// Read SSE stream
 	var fullContent strings.Builder
+	var servedModel string
+	var usage *openRouterUsage
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -371,11 +461,16 @@ func (s *ChatService) streamFromOpenRouter(ctx context.Context, messages []map[s
 					} `json:"delta"`
 					FinishReason *string `json:"finish_reason"`
 				} `json:"choices"`
+				Model string           `json:"model"`
+				Usage *openRouterUsage `json:"usage"`
 			}
 
 			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 				continue
 			}
+			if chunk.Usage != nil {
+				servedModel, usage = chunk.Model, chunk.Usage
+			}
 
 			if len(chunk.Choices) > 0 {
 				content := chunk.Choices[0].Delta.Content
@@ -390,17 +485,24 @@ func (s *ChatService) streamFromOpenRouter(ctx context.Context, messages []map[s
 			}
 		}
 	}
+	if err = scanner.Err(); err != nil {
+		s.log.Warn("OpenRouter stream interrupted", zap.Uint("insight_id", insightID), zap.Error(err))
+	}
+
+	s.usage.Record(ctx, models.LLMFeatureChat, s.chatModel, servedModel, usage)
 
 	// Save assistant message
 	if fullContent.Len() > 0 {
 		assistantMessage := &models.ChatMessage{
 			InsightID: insightID,
-			UserID:    0, // TODO: Get from context/auth
+			UserID:    userID, // The member who asked
 			Role:      "assistant",
 			Content:   fullContent.String(),
 		}
 		if err := s.chatRepo.CreateMessage(ctx, assistantMessage); err != nil {
 			s.log.Error("Failed to save assistant message", zap.Error(err))
+		} else {
+			s.indexMessage(ctx, assistantMessage)
 		}
 
 		// Send final event with message ID
@@ -409,97 +511,13 @@ func (s *ChatService) streamFromOpenRouter(ctx context.Context, messages []map[s
 			Content:   "",
 			Done:      true,
 			MessageID: &assistantMessage.ID,
+			Citations: chatCitations(fullContent.String(), chapters),
 		}
 	} else {
 		responseChan <- models.ChatStreamEvent{Done: true}
 	}
 }
 
-// callOpenRouter makes a non-streaming call to OpenRouter.
-func (s *ChatService) callOpenRouter(ctx context.Context, prompt string) (string, error) {
-	const openRouterURL = "https://openrouter.ai/api/v1/chat/completions"
-
-	requestBody := map[string]interface{}{
-		"model": s.chatModel,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
-	}
-
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", openRouterURL, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.openRouterAPIKey)
-	req.Header.Set("HTTP-Referer", "https://github.com/your-repo/vibe-engineering-playbook")
-	req.Header.Set("X-Title", "VIBE Engineering Playbook")
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to call OpenRouter API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check HTTP status code
-	if resp.StatusCode != http.StatusOK {
-		var errorBody bytes.Buffer
-		errorBody.ReadFrom(resp.Body)
-		errorBodyStr := errorBody.String()
-		
-		// Special handling for 401 authentication errors
-		if resp.StatusCode == http.StatusUnauthorized {
-			s.log.Error("❌ OpenRouter API 认证失败 - API密钥无效",
-				zap.Int("status_code", resp.StatusCode),
-				zap.String("response_body", errorBodyStr),
-				zap.String("api_key_prefix", s.maskAPIKey()),
-				zap.String("error_type", "AUTHENTICATION_FAILED"),
-				zap.String("解决方案", "请检查 OPENROUTER_API_KEY 环境变量，访问 https://openrouter.ai/ 获取有效密钥"),
-			)
-			return "", fmt.Errorf("OpenRouter API 认证失败（401）: %s - 请检查 OPENROUTER_API_KEY 是否有效", errorBodyStr)
-		}
-		
-		s.log.Error("OpenRouter API returned error",
-			zap.Int("status_code", resp.StatusCode),
-			zap.String("response_body", errorBodyStr),
-		)
-		return "", fmt.Errorf("OpenRouter API error: status %d, body: %s", resp.StatusCode, errorBodyStr)
-	}
-
-	var response struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-		Error *struct {
-			Message string `json:"message"`
-			Code    string `json:"code"`
-		} `json:"error"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	// Check for API-level errors
-	if response.Error != nil {
-		return "", fmt.Errorf("OpenRouter API error: %s (code: %s)", response.Error.Message, response.Error.Code)
-	}
-
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no response from API")
-	}
-
-	return response.Choices[0].Message.Content, nil
-}
-
 // cleanJSONResponse removes markdown code blocks from JSON response.
 func (s *ChatService) cleanJSONResponse(response string) string {
 	cleaned := strings.TrimSpace(response)
@@ -537,11 +555,5 @@ func (s *ChatService) cleanJSONResponse(response string) string {
 
 // maskAPIKey returns a masked version of the API key for logging (shows only first 10 chars).
 func (s *ChatService) maskAPIKey() string {
-	if s.openRouterAPIKey == "" {
-		return "<未设置>"
-	}
-	if len(s.openRouterAPIKey) <= 10 {
-		return "***"
-	}
-	return s.openRouterAPIKey[:10] + "..." + s.openRouterAPIKey[len(s.openRouterAPIKey)-4:]
+	return maskOpenRouterKey(s.openRouterAPIKey)
 }
@@ -0,0 +1,361 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+)
+
+// Comment service errors.
+var (
+	ErrCommentNotFound       = errors.New("comment not found")
+	ErrCommentsDisabled      = errors.New("comments are disabled for this share link")
+	ErrCommentThreadLocked   = errors.New("comment thread is locked")
+	ErrCommentNotThread      = errors.New("only thread root comments can be locked")
+	ErrCommentEmpty          = errors.New("comment is empty")
+	ErrCommentAuthorRequired = errors.New("anonymous comments need a display name")
+	ErrInvalidCommentAnchor  = errors.New("invalid comment anchor")
+)
+
+// Commenter is the author of a new comment: a signed-in user, or an anonymous share
+// reader identified by display name.
+type Commenter struct {
+	User      *models.User // nil for anonymous readers
+	Name      string       // Display name of anonymous readers
+	IP        string
+	UserAgent string
+}
+
+// CommentService manages threaded comments on insights, posted by workspace members or
+// through share links, and their moderation by the insight owner.
+type CommentService struct {
+	repo        *repository.CommentRepository
+	insightRepo *repository.InsightRepository
+	userRepo    *repository.UserRepository
+	shares      *ShareService
+	workspaces  *WorkspaceService
+	mailer      Mailer // optional
+	baseURL     string
+	log         *zap.Logger
+}
+
+// NewCommentService creates a new CommentService. mailer may be nil, in which case no
+// comment notifications are sent.
+func NewCommentService(repo *repository.CommentRepository, insightRepo *repository.InsightRepository, userRepo *repository.UserRepository, shares *ShareService, workspaces *WorkspaceService, mailer Mailer, baseURL string, log *zap.Logger) *CommentService {
+	return &CommentService{
+		repo:        repo,
+		insightRepo: insightRepo,
+		userRepo:    userRepo,
+		shares:      shares,
+		workspaces:  workspaces,
+		mailer:      mailer,
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		log:         log,
+	}
+}
+
+// --- Workspace members ---
+
+// List returns the comment threads of an insight. Moderators also see hidden comments.
+func (s *CommentService) List(ctx context.Context, userID, insightID uint) (*models.CommentListResponse, error) {
+	insight, role, err := s.workspaces.AuthorizeInsight(ctx, userID, insightID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.list(ctx, insight.ID, canModerate(userID, insight, role))
+}
+
+// Create posts a comment as a workspace member.
+func (s *CommentService) Create(ctx context.Context, user *models.User, insightID uint, req *models.CreateCommentRequest) (*models.CommentResponse, error) {
+	insight, _, err := s.workspaces.AuthorizeInsight(ctx, user.ID, insightID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.create(ctx, insight, nil, Commenter{User: user}, req)
+}
+
+// Moderate hides or unhides a comment, or locks or unlocks a thread. Insight owners only.
+func (s *CommentService) Moderate(ctx context.Context, userID, insightID, commentID uint, req *models.ModerateCommentRequest) (*models.CommentResponse, error) {
+	insight, role, err := s.workspaces.AuthorizeInsight(ctx, userID, insightID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	if !canModerate(userID, insight, role) {
+		return nil, ErrWorkspaceForbidden
+	}
+
+	comment, err := s.load(ctx, insight.ID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Locked != nil && comment.ParentID != nil {
+		return nil, ErrCommentNotThread
+	}
+	if req.Hidden != nil {
+		comment.Hidden = *req.Hidden
+	}
+	if req.Locked != nil {
+		comment.Locked = *req.Locked
+	}
+	if err := s.repo.UpdateModeration(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Comment moderated",
+		zap.Uint("comment_id", comment.ID),
+		zap.Uint("moderator_id", userID),
+		zap.Bool("hidden", comment.Hidden),
+		zap.Bool("locked", comment.Locked),
+	)
+	response := toCommentResponse(comment, true)
+	return &response, nil
+}
+
+// Delete deletes a comment with its replies. Insight owners may delete any comment;
+// members may delete their own.
+func (s *CommentService) Delete(ctx context.Context, userID, insightID, commentID uint) error {
+	insight, role, err := s.workspaces.AuthorizeInsight(ctx, userID, insightID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return err
+	}
+	comment, err := s.load(ctx, insight.ID, commentID)
+	if err != nil {
+		return err
+	}
+	isAuthor := comment.UserID != nil && *comment.UserID == userID
+	if !isAuthor && !canModerate(userID, insight, role) {
+		return ErrWorkspaceForbidden
+	}
+	return s.repo.DeleteThread(ctx, comment.ID)
+}
+
+// --- Share link readers ---
+
+// ListShared returns the visible comment threads of a shared insight.
+func (s *CommentService) ListShared(ctx context.Context, token, accessToken string) (*models.CommentListResponse, error) {
+	link, err := s.commentableLink(ctx, token, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.list(ctx, link.InsightID, false)
+}
+
+// CreateShared posts a comment through a share link.
+func (s *CommentService) CreateShared(ctx context.Context, token, accessToken string, commenter Commenter, req *models.CreateCommentRequest) (*models.CommentResponse, error) {
+	link, err := s.commentableLink(ctx, token, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	insight, err := s.insightRepo.GetByID(ctx, link.InsightID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShareLinkNotFound
+		}
+		return nil, err
+	}
+	return s.create(ctx, insight, link, commenter, req)
+}
+
+// commentableLink returns a viewable share link that allows comments.
+func (s *CommentService) commentableLink(ctx context.Context, token, accessToken string) (*models.ShareLink, error) {
+	link, err := s.shares.Access(ctx, token, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if !s.shares.Config(link).AllowComments {
+		return nil, ErrCommentsDisabled
+	}
+	return link, nil
+}
+
+// --- Helpers ---
+
+// list builds the comment threads of an insight.
+func (s *CommentService) list(ctx context.Context, insightID uint, moderator bool) (*models.CommentListResponse, error) {
+	comments, err := s.repo.ListByInsight(ctx, insightID, moderator)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.CommentListResponse{
+		Threads:     make([]models.CommentThread, 0),
+		CanModerate: moderator,
+	}
+	threadIndex := make(map[uint]int)
+	for i := range comments {
+		comment := &comments[i]
+		if comment.ParentID == nil {
+			threadIndex[comment.ID] = len(response.Threads)
+			response.Threads = append(response.Threads, models.CommentThread{
+				CommentResponse: toCommentResponse(comment, moderator),
+				Replies:         make([]models.CommentResponse, 0),
+			})
+			response.CommentCount++
+			continue
+		}
+		// Replies to a hidden thread are hidden with it
+		if index, ok := threadIndex[*comment.ParentID]; ok {
+			response.Threads[index].Replies = append(response.Threads[index].Replies, toCommentResponse(comment, moderator))
+			response.CommentCount++
+		}
+	}
+	return response, nil
+}
+
+// create validates and stores a new comment, then notifies the insight owner.
+func (s *CommentService) create(ctx context.Context, insight *models.Insight, link *models.ShareLink, commenter Commenter, req *models.CreateCommentRequest) (*models.CommentResponse, error) {
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		return nil, ErrCommentEmpty
+	}
+
+	comment := &models.Comment{
+		InsightID: insight.ID,
+		Body:      body,
+	}
+	if link != nil {
+		comment.ShareLinkID = &link.ID
+	}
+
+	if commenter.User != nil {
+		comment.UserID = &commenter.User.ID
+		comment.AuthorName = truncate(commenter.User.Name, 50)
+		if comment.AuthorName == "" {
+			comment.AuthorName = "用户"
+		}
+	} else {
+		comment.AuthorName = truncate(strings.TrimSpace(commenter.Name), 50)
+		if comment.AuthorName == "" {
+			return nil, ErrCommentAuthorRequired
+		}
+		var linkID uint
+		if link != nil {
+			linkID = link.ID
+		}
+		comment.VisitorHash = s.shares.visitorHash(linkID, commenter.IP, commenter.UserAgent)
+	}
+
+	if req.ParentID != nil {
+		if req.HighlightID != nil || req.Seconds != nil {
+			return nil, ErrInvalidCommentAnchor
+		}
+		parent, err := s.load(ctx, insight.ID, *req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		root := parent
+		if parent.ParentID != nil {
+			if root, err = s.load(ctx, insight.ID, *parent.ParentID); err != nil {
+				return nil, err
+			}
+		}
+		if root.Hidden {
+			return nil, ErrCommentNotFound
+		}
+		if root.Locked {
+			return nil, ErrCommentThreadLocked
+		}
+		comment.ParentID = &root.ID
+	} else {
+		if req.HighlightID != nil {
+			highlight, err := s.insightRepo.GetHighlightByID(ctx, *req.HighlightID)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, ErrInvalidCommentAnchor
+				}
+				return nil, err
+			}
+			if highlight.InsightID != insight.ID {
+				return nil, ErrInvalidCommentAnchor
+			}
+			comment.HighlightID = &highlight.ID
+		}
+		comment.Seconds = req.Seconds
+	}
+
+	if err := s.repo.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	s.notifyOwner(insight, comment)
+	response := toCommentResponse(comment, link == nil)
+	return &response, nil
+}
+
+// notifyOwner emails the insight owner about a new comment by someone else.
+func (s *CommentService) notifyOwner(insight *models.Insight, comment *models.Comment) {
+	if s.mailer == nil || (comment.UserID != nil && *comment.UserID == insight.UserID) {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), securityNotifyTimeout)
+		defer cancel()
+
+		owner, err := s.userRepo.GetByID(ctx, insight.UserID)
+		if err != nil || owner.Email == "" {
+			return
+		}
+
+		action := "commented on"
+		if comment.ParentID != nil {
+			action = "replied to a comment on"
+		}
+		subject := fmt.Sprintf("%s %s \"%s\"", comment.AuthorName, action, truncate(insight.Title, 80))
+		body := fmt.Sprintf("%s %s your insight \"%s\":\n\n%s\n\nView and moderate comments:\n%s/insights/%d#comments",
+			comment.AuthorName, action, insight.Title, truncate(comment.Body, 1000), s.baseURL, insight.ID)
+		if err := s.mailer.Send(ctx, owner.Email, subject, body); err != nil {
+			s.log.Warn("Failed to send comment notification", zap.Uint("comment_id", comment.ID), zap.Error(err))
+		}
+	}()
+}
+
+// load returns a comment of the given insight.
+func (s *CommentService) load(ctx context.Context, insightID, commentID uint) (*models.Comment, error) {
+	comment, err := s.repo.GetByID(ctx, commentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	if comment.InsightID != insightID {
+		return nil, ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+// canModerate reports whether a user moderates an insight's comments: its owner or an
+// owner of its workspace.
+func canModerate(userID uint, insight *models.Insight, role models.WorkspaceRole) bool {
+	return insight.UserID == userID || role.Allows(models.WorkspaceRoleOwner)
+}
+
+// toCommentResponse converts a comment to its API representation. Commenters' user IDs
+// are internal: they are only included in the moderation view and in a workspace
+// member's own new comment, never in what share link readers see.
+func toCommentResponse(comment *models.Comment, withUserID bool) models.CommentResponse {
+	response := models.CommentResponse{
+		ID:          comment.ID,
+		ParentID:    comment.ParentID,
+		AuthorName:  comment.AuthorName,
+		Anonymous:   comment.UserID == nil,
+		Body:        comment.Body,
+		HighlightID: comment.HighlightID,
+		Seconds:     comment.Seconds,
+		Hidden:      comment.Hidden,
+		Locked:      comment.Locked,
+		ViaShare:    comment.ShareLinkID != nil,
+		CreatedAt:   comment.CreatedAt,
+	}
+	if withUserID {
+		response.UserID = comment.UserID
+	}
+	return response
+}
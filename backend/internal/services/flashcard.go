@@ -0,0 +1,436 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+)
+
+const (
+	// flashcardBatchSize is how many sources are turned into cards per LLM call.
+	flashcardBatchSize = 20
+	// maxFlashcardSources bounds how many new cards one generation creates.
+	maxFlashcardSources = 100
+	// maxDueFlashcards bounds the due-card batch size.
+	maxDueFlashcards = 100
+)
+
+// Flashcard service errors.
+var (
+	ErrFlashcardNotFound  = errors.New("flashcard not found")
+	ErrNoFlashcardSources = errors.New("insight has no highlights or key points without cards")
+)
+
+// flashcardSource is a highlight or key point to generate a card from.
+type flashcardSource struct {
+	source      models.FlashcardSource
+	key         string
+	highlightID *uint
+	seconds     *int
+	text        string
+	note        string
+}
+
+// generatedCard is one card in the LLM response.
+type generatedCard struct {
+	Index    int    `json:"index"`
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+// FlashcardService generates spaced-repetition cards from highlights and key points
+// and schedules their reviews with SM-2.
+type FlashcardService struct {
+	repo        *repository.FlashcardRepository
+	insightRepo *repository.InsightRepository
+	workspaces  *WorkspaceService
+	llm         *openRouterClient
+	log         *zap.Logger
+}
+
+// NewFlashcardService creates a new FlashcardService.
+func NewFlashcardService(repo *repository.FlashcardRepository, insightRepo *repository.InsightRepository, workspaces *WorkspaceService, apiKey, model string, log *zap.Logger) *FlashcardService {
+	return &FlashcardService{
+		repo:        repo,
+		insightRepo: insightRepo,
+		workspaces:  workspaces,
+		llm:         newOpenRouterClient(apiKey, model, "Vibe Flashcards", log),
+		log:         log,
+	}
+}
+
+// SetUsageService sets the service that meters and budgets LLM calls.
+func (s *FlashcardService) SetUsageService(svc *UsageService) {
+	s.llm.usage = svc
+}
+
+// --- Generation ---
+
+// Generate creates cards for the highlights and key points of an insight that do not
+// have one of the user's cards yet.
+func (s *FlashcardService) Generate(ctx context.Context, userID, insightID uint) (*models.GenerateFlashcardsResponse, error) {
+	insight, _, err := s.workspaces.AuthorizeInsight(ctx, userID, insightID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	ctx = WithUsageUser(ctx, userID)
+	highlights, err := s.insightRepo.GetHighlightsByInsightID(ctx, insight.ID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.repo.SourceKeys(ctx, userID, insight.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	all := flashcardSources(insight, highlights)
+	sources := make([]flashcardSource, 0, len(all))
+	for _, source := range all {
+		if !existing[source.key] {
+			sources = append(sources, source)
+		}
+	}
+	skipped := len(all) - len(sources)
+	if len(sources) == 0 {
+		return nil, ErrNoFlashcardSources
+	}
+	if len(sources) > maxFlashcardSources {
+		sources = sources[:maxFlashcardSources]
+	}
+
+	now := time.Now()
+	cards := make([]models.Flashcard, 0, len(sources))
+	for start := 0; start < len(sources); start += flashcardBatchSize {
+		batch := sources[start:min(start+flashcardBatchSize, len(sources))]
+		generated, err := s.generateBatch(ctx, insight, batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, card := range generated {
+			if card.Index < 1 || card.Index > len(batch) {
+				continue
+			}
+			question, answer := strings.TrimSpace(card.Question), strings.TrimSpace(card.Answer)
+			if question == "" || answer == "" {
+				continue
+			}
+			source := batch[card.Index-1]
+			cards = append(cards, models.Flashcard{
+				UserID:      userID,
+				InsightID:   insight.ID,
+				Source:      source.source,
+				SourceKey:   source.key,
+				HighlightID: source.highlightID,
+				Seconds:     source.seconds,
+				Front:       question,
+				Back:        answer,
+				EaseFactor:  models.FlashcardInitialEase,
+				DueAt:       now,
+			})
+		}
+	}
+
+	if err := s.repo.CreateBatch(ctx, cards); err != nil {
+		return nil, err
+	}
+
+	response := &models.GenerateFlashcardsResponse{
+		Created: make([]models.FlashcardResponse, 0, len(cards)),
+		Skipped: skipped,
+	}
+	for i := range cards {
+		if cards[i].ID == 0 { // Created concurrently by another request
+			response.Skipped++
+			continue
+		}
+		response.Created = append(response.Created, toFlashcardResponse(&cards[i], insight.Title))
+	}
+
+	s.log.Info("Generated flashcards",
+		zap.Uint("user_id", userID),
+		zap.Uint("insight_id", insight.ID),
+		zap.Int("created", len(response.Created)),
+		zap.Int("skipped", response.Skipped),
+	)
+	return response, nil
+}
+
+// generateBatch asks the LLM for one card per source.
+func (s *FlashcardService) generateBatch(ctx context.Context, insight *models.Insight, batch []flashcardSource) ([]generatedCard, error) {
+	var items strings.Builder
+	for i, source := range batch {
+		fmt.Fprintf(&items, "%d. %s\n", i+1, source.text)
+		if source.note != "" {
+			fmt.Fprintf(&items, "   笔记: %s\n", source.note)
+		}
+	}
+
+	prompt := fmt.Sprintf(`你是一名学习卡片编写助手。根据下面来自《%s》的摘录，为每条摘录编写一张问答卡片，用于间隔重复复习。
+
+要求：
+- 问题应考察摘录中的核心知识点，不看答案时可以回答，不要照抄原文作为问题
+- 答案简洁准确，不超过三句话
+- 如果摘录附有笔记，结合笔记确定考察重点
+- 使用与摘录相同的语言
+
+摘录：
+%s
+请以JSON格式返回，index 为摘录编号：
+{"cards": [{"index": 1, "question": "问题", "answer": "答案"}]}
+
+只返回JSON，不要其他文字。`, insight.Title, items.String())
+
+	response, err := s.llm.complete(ctx, models.LLMFeatureFlashcards, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call AI service: %w", err)
+	}
+
+	var result struct {
+		Cards []generatedCard `json:"cards"`
+	}
+	if err := json.Unmarshal([]byte(stripJSONCodeFence(response)), &result); err != nil {
+		s.log.Error("Failed to parse flashcard response",
+			zap.Uint("insight_id", insight.ID),
+			zap.Error(err),
+			zap.String("raw_response", response),
+		)
+		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+	return result.Cards, nil
+}
+
+// flashcardSources lists the highlights (with their notes) and key points of an insight.
+func flashcardSources(insight *models.Insight, highlights []models.Highlight) []flashcardSource {
+	transcripts := parseTranscripts(insight)
+	sources := make([]flashcardSource, 0, len(highlights))
+
+	for i := range highlights {
+		highlight := &highlights[i]
+		text := strings.TrimSpace(highlight.Text)
+		if text == "" {
+			continue
+		}
+		source := flashcardSource{
+			source:      models.FlashcardSourceHighlight,
+			key:         fmt.Sprintf("highlight:%d", highlight.ID),
+			highlightID: &highlight.ID,
+			text:        text,
+			note:        strings.TrimSpace(highlight.Note),
+		}
+		if item := findTranscriptItem(transcripts, text); item != nil {
+			seconds := item.Seconds
+			source.seconds = &seconds
+		}
+		sources = append(sources, source)
+	}
+
+	var keyPoints []string
+	if len(insight.KeyPoints) > 0 {
+		_ = json.Unmarshal(insight.KeyPoints, &keyPoints)
+	}
+	for _, point := range keyPoints {
+		point = strings.TrimSpace(point)
+		if point == "" {
+			continue
+		}
+		// Key points have no ID; their text identifies them across reprocessing
+		sum := sha256.Sum256([]byte(point))
+		sources = append(sources, flashcardSource{
+			source: models.FlashcardSourceKeyPoint,
+			key:    "key_point:" + hex.EncodeToString(sum[:16]),
+			text:   point,
+		})
+	}
+	return sources
+}
+
+// --- Review ---
+
+// Due returns the user's cards due for review, most overdue first.
+func (s *FlashcardService) Due(ctx context.Context, userID uint, limit int) (*models.DueFlashcardsResponse, error) {
+	if limit <= 0 || limit > maxDueFlashcards {
+		limit = maxDueFlashcards
+	}
+	cards, total, err := s.repo.ListDue(ctx, userID, time.Now(), limit)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := s.toResponses(ctx, cards)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DueFlashcardsResponse{Cards: responses, DueCount: total}, nil
+}
+
+// Grade records a review of a card and schedules the next one.
+func (s *FlashcardService) Grade(ctx context.Context, userID, cardID uint, grade int) (*models.FlashcardResponse, error) {
+	card, err := s.load(ctx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	scheduleSM2(card, grade, now)
+	review := &models.FlashcardReview{
+		FlashcardID:  card.ID,
+		UserID:       userID,
+		Grade:        grade,
+		IntervalDays: card.IntervalDays,
+		EaseFactor:   card.EaseFactor,
+		ReviewedAt:   now,
+	}
+	if err := s.repo.RecordReview(ctx, card, review); err != nil {
+		return nil, err
+	}
+	return s.toResponse(ctx, card)
+}
+
+// scheduleSM2 applies an SM-2 review with the given quality (0-5) to a card.
+func scheduleSM2(card *models.Flashcard, grade int, now time.Time) {
+	if grade >= 3 {
+		switch card.Repetitions {
+		case 0:
+			card.IntervalDays = 1
+		case 1:
+			card.IntervalDays = 6
+		default:
+			card.IntervalDays = int(math.Round(float64(card.IntervalDays) * card.EaseFactor))
+		}
+		card.Repetitions++
+	} else {
+		card.Repetitions = 0
+		card.IntervalDays = 1
+		card.Lapses++
+	}
+
+	q := float64(5 - grade)
+	card.EaseFactor += 0.1 - q*(0.08+q*0.02)
+	if card.EaseFactor < models.FlashcardMinEase {
+		card.EaseFactor = models.FlashcardMinEase
+	}
+
+	card.LastReviewedAt = &now
+	card.DueAt = now.AddDate(0, 0, card.IntervalDays)
+}
+
+// --- Management ---
+
+// List returns a page of the user's cards, optionally only those of one insight.
+func (s *FlashcardService) List(ctx context.Context, userID uint, insightID *uint, page models.PageRequest) (*models.FlashcardListResponse, error) {
+	cards, info, err := s.repo.ListPage(ctx, userID, insightID, page)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := s.toResponses(ctx, cards)
+	if err != nil {
+		return nil, err
+	}
+	return &models.FlashcardListResponse{Items: responses, PageInfo: info}, nil
+}
+
+// Update edits a card's text or suspends or resumes it.
+func (s *FlashcardService) Update(ctx context.Context, userID, cardID uint, req *models.UpdateFlashcardRequest) (*models.FlashcardResponse, error) {
+	card, err := s.load(ctx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Front != nil {
+		card.Front = strings.TrimSpace(*req.Front)
+	}
+	if req.Back != nil {
+		card.Back = strings.TrimSpace(*req.Back)
+	}
+	if req.Suspended != nil {
+		card.Suspended = *req.Suspended
+	}
+	if err := s.repo.Update(ctx, card); err != nil {
+		return nil, err
+	}
+	return s.toResponse(ctx, card)
+}
+
+// Delete deletes a card and its review history.
+func (s *FlashcardService) Delete(ctx context.Context, userID, cardID uint) error {
+	card, err := s.load(ctx, userID, cardID)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, card.ID)
+}
+
+// --- Helpers ---
+
+// load returns one of the user's cards.
+func (s *FlashcardService) load(ctx context.Context, userID, cardID uint) (*models.Flashcard, error) {
+	card, err := s.repo.GetByID(ctx, cardID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFlashcardNotFound
+		}
+		return nil, err
+	}
+	if card.UserID != userID {
+		return nil, ErrFlashcardNotFound
+	}
+	return card, nil
+}
+
+// toResponse converts a card to its API representation.
+func (s *FlashcardService) toResponse(ctx context.Context, card *models.Flashcard) (*models.FlashcardResponse, error) {
+	titles, err := s.repo.InsightTitles(ctx, []uint{card.InsightID})
+	if err != nil {
+		return nil, err
+	}
+	response := toFlashcardResponse(card, titles[card.InsightID])
+	return &response, nil
+}
+
+// toResponses converts cards to their API representation.
+func (s *FlashcardService) toResponses(ctx context.Context, cards []models.Flashcard) ([]models.FlashcardResponse, error) {
+	insightIDs := make([]uint, 0, len(cards))
+	for _, card := range cards {
+		insightIDs = append(insightIDs, card.InsightID)
+	}
+	titles, err := s.repo.InsightTitles(ctx, uniqueIDs(insightIDs))
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]models.FlashcardResponse, 0, len(cards))
+	for i := range cards {
+		responses = append(responses, toFlashcardResponse(&cards[i], titles[cards[i].InsightID]))
+	}
+	return responses, nil
+}
+
+// toFlashcardResponse adds the insight title and a link to the card's source.
+func toFlashcardResponse(card *models.Flashcard, insightTitle string) models.FlashcardResponse {
+	kind := models.SearchKindContent
+	if card.HighlightID != nil {
+		kind = models.SearchKindHighlight
+	}
+	return models.FlashcardResponse{
+		Flashcard:    *card,
+		InsightTitle: insightTitle,
+		Link:         searchMatchLink(card.InsightID, kind, card.HighlightID, card.Seconds),
+	}
+}
+
+// stripJSONCodeFence removes a markdown code fence around a JSON response.
+func stripJSONCodeFence(response string) string {
+	cleaned := strings.TrimSpace(response)
+	cleaned = strings.TrimPrefix(cleaned, "```json")
+	cleaned = strings.TrimPrefix(cleaned, "```")
+	cleaned = strings.TrimSuffix(cleaned, "```")
+	return strings.TrimSpace(cleaned)
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"vibe-backend/internal/models"
+)
+
+func TestScheduleSM2(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	newCard := models.Flashcard{EaseFactor: models.FlashcardInitialEase}
+
+	tests := []struct {
+		name  string
+		card  models.Flashcard
+		grade int
+		want  models.Flashcard // Only the scheduling state is compared
+	}{
+		{
+			name:  "first review",
+			card:  newCard,
+			grade: 4,
+			want:  models.Flashcard{EaseFactor: 2.5, IntervalDays: 1, Repetitions: 1},
+		},
+		{
+			name:  "first review at the pass mark",
+			card:  newCard,
+			grade: 3,
+			want:  models.Flashcard{EaseFactor: 2.36, IntervalDays: 1, Repetitions: 1},
+		},
+		{
+			name:  "second review",
+			card:  models.Flashcard{EaseFactor: 2.5, IntervalDays: 1, Repetitions: 1},
+			grade: 5,
+			want:  models.Flashcard{EaseFactor: 2.6, IntervalDays: 6, Repetitions: 2},
+		},
+		{
+			name:  "later reviews multiply by the ease",
+			card:  models.Flashcard{EaseFactor: 2.5, IntervalDays: 6, Repetitions: 2},
+			grade: 4,
+			want:  models.Flashcard{EaseFactor: 2.5, IntervalDays: 15, Repetitions: 3},
+		},
+		{
+			name:  "ease changes after the interval",
+			card:  models.Flashcard{EaseFactor: 2.6, IntervalDays: 15, Repetitions: 3},
+			grade: 3,
+			want:  models.Flashcard{EaseFactor: 2.46, IntervalDays: 39, Repetitions: 4},
+		},
+		{
+			name:  "interval is rounded and ease floored",
+			card:  models.Flashcard{EaseFactor: 1.3, IntervalDays: 6, Repetitions: 2},
+			grade: 3,
+			want:  models.Flashcard{EaseFactor: 1.3, IntervalDays: 8, Repetitions: 3},
+		},
+		{
+			name:  "lapse restarts the card",
+			card:  models.Flashcard{EaseFactor: 2.5, IntervalDays: 39, Repetitions: 4, Lapses: 1},
+			grade: 2,
+			want:  models.Flashcard{EaseFactor: 2.18, IntervalDays: 1, Repetitions: 0, Lapses: 2},
+		},
+		{
+			name:  "blackout keeps the minimum ease",
+			card:  models.Flashcard{EaseFactor: 1.5, IntervalDays: 8, Repetitions: 3},
+			grade: 0,
+			want:  models.Flashcard{EaseFactor: 1.3, IntervalDays: 1, Repetitions: 0, Lapses: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := tt.card
+			scheduleSM2(&card, tt.grade, now)
+
+			if math.Abs(card.EaseFactor-tt.want.EaseFactor) > 1e-9 {
+				t.Errorf("ease = %v, want %v", card.EaseFactor, tt.want.EaseFactor)
+			}
+			if card.IntervalDays != tt.want.IntervalDays || card.Repetitions != tt.want.Repetitions || card.Lapses != tt.want.Lapses {
+				t.Errorf("interval, repetitions, lapses = %d, %d, %d; want %d, %d, %d",
+					card.IntervalDays, card.Repetitions, card.Lapses,
+					tt.want.IntervalDays, tt.want.Repetitions, tt.want.Lapses)
+			}
+			if card.LastReviewedAt == nil || !card.LastReviewedAt.Equal(now) {
+				t.Errorf("last reviewed = %v, want %v", card.LastReviewedAt, now)
+			}
+			if want := now.AddDate(0, 0, tt.want.IntervalDays); !card.DueAt.Equal(want) {
+				t.Errorf("due = %v, want %v", card.DueAt, want)
+			}
+		})
+	}
+}
+
+func TestScheduleSM2Sequence(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	card := models.Flashcard{EaseFactor: models.FlashcardInitialEase}
+
+	// Perfect answers, then a lapse and a recovery
+	steps := []struct {
+		grade    int
+		interval int
+	}{
+		{5, 1}, {5, 6}, {5, 16}, {1, 1}, {4, 1}, {4, 6},
+	}
+	for i, step := range steps {
+		scheduleSM2(&card, step.grade, now)
+		if card.IntervalDays != step.interval {
+			t.Fatalf("review %d (grade %d): interval = %d, want %d", i+1, step.grade, card.IntervalDays, step.interval)
+		}
+		now = card.DueAt
+	}
+	if card.Lapses != 1 || card.Repetitions != 2 {
+		t.Errorf("lapses, repetitions = %d, %d; want 1, 2", card.Lapses, card.Repetitions)
+	}
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+)
+
+// LoginStateTTL is how long a provider authorization round-trip may take.
+const LoginStateTTL = 10 * time.Minute
+
+// loginStatePurpose binds signed state tokens to the provider login flow.
+const loginStatePurpose = "provider-login"
+
+// Identity service errors.
+var (
+	ErrUnknownProvider         = errors.New("unknown login provider")
+	ErrInvalidLoginState       = errors.New("invalid or expired login state")
+	ErrLinkNotAuthorized       = errors.New("link flow must be completed by the user who started it")
+	ErrIdentityLinkedElsewhere = errors.New("identity is already linked to another account")
+	ErrEmailInUse              = errors.New("an account with this email already exists")
+	ErrIdentityNotFound        = errors.New("identity not found")
+	ErrLastIdentity            = errors.New("cannot unlink the only sign-in method")
+)
+
+// loginState is the payload carried in the OAuth state parameter.
+type loginState struct {
+	Provider   string `json:"provider"`
+	Nonce      string `json:"nonce"`
+	LinkUserID uint   `json:"link_user_id,omitempty"`
+}
+
+// LoginResult is the outcome of a completed provider login.
+type LoginResult struct {
+	User    *models.User
+	Linked  bool
+	Created bool
+}
+
+// IdentityService implements provider sign-in and account linking.
+//
+// Linking rules on callback:
+//  1. An identity already known for (provider, subject) signs in its user.
+//  2. A link flow started by a signed-in user attaches the identity to that user.
+//  3. A verified email matching an existing user attaches the identity to that user.
+//  4. Otherwise a new passwordless user is created. Unverified emails that collide
+//     with an existing account are rejected instead of being merged.
+type IdentityService struct {
+	registry     *LoginProviderRegistry
+	signer       *Signer
+	userRepo     *repository.UserRepository
+	identityRepo *repository.IdentityRepository
+	log          *zap.Logger
+}
+
+// NewIdentityService creates a new IdentityService.
+func NewIdentityService(
+	registry *LoginProviderRegistry,
+	signer *Signer,
+	userRepo *repository.UserRepository,
+	identityRepo *repository.IdentityRepository,
+	log *zap.Logger,
+) *IdentityService {
+	return &IdentityService{
+		registry:     registry,
+		signer:       signer,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		log:          log,
+	}
+}
+
+// Providers returns the configured login providers.
+func (s *IdentityService) Providers() []LoginProvider {
+	return s.registry.List()
+}
+
+// BeginLogin returns the authorization URL and signed state for a provider, and the
+// nonce the state carries. The caller keeps the nonce in the browser that started the
+// flow (see CompleteLogin). A non-zero linkUserID starts a link flow for that user
+// instead of a sign-in.
+func (s *IdentityService) BeginLogin(ctx context.Context, providerName string, linkUserID uint) (*models.ProviderAuthURLResponse, string, error) {
+	provider, ok := s.registry.Get(providerName)
+	if !ok {
+		return nil, "", ErrUnknownProvider
+	}
+
+	nonce, err := randomToken(16)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	state, err := s.signer.Sign(loginStatePurpose, loginState{
+		Provider:   providerName,
+		Nonce:      nonce,
+		LinkUserID: linkUserID,
+	}, LoginStateTTL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	url, err := provider.AuthCodeURL(ctx, state, nonce)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to build authorization URL: %w", err)
+	}
+
+	return &models.ProviderAuthURLResponse{URL: url, State: state}, nonce, nil
+}
+
+// CompleteLogin exchanges the authorization code and resolves the user per the linking rules.
+// browserNonce is the nonce BeginLogin returned, as kept by the browser completing the
+// flow; it must match the state's, so a state started elsewhere cannot be completed in
+// another browser. Link flows must also be completed by the signed-in user who started
+// them (callerID, 0 if signed out).
+func (s *IdentityService) CompleteLogin(ctx context.Context, providerName, code, state, browserNonce string, callerID uint) (*LoginResult, error) {
+	var st loginState
+	if err := s.signer.Verify(loginStatePurpose, state, &st); err != nil || st.Provider != providerName {
+		return nil, ErrInvalidLoginState
+	}
+	if browserNonce == "" || subtle.ConstantTimeCompare([]byte(st.Nonce), []byte(browserNonce)) != 1 {
+		return nil, ErrInvalidLoginState
+	}
+	if st.LinkUserID != 0 && st.LinkUserID != callerID {
+		return nil, ErrLinkNotAuthorized
+	}
+
+	provider, ok := s.registry.Get(providerName)
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	ext, err := provider.Exchange(ctx, code, st.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	return s.login(ctx, ext, st.LinkUserID)
+}
+
+// LoginVerified signs in with an identity the caller has already verified with its
+// provider, applying the same linking rules as CompleteLogin except link flows. It
+// backs the legacy Google OAuth callback, which also grants YouTube access.
+func (s *IdentityService) LoginVerified(ctx context.Context, ext *models.ExternalIdentity) (*LoginResult, error) {
+	return s.login(ctx, ext, 0)
+}
+
+// login applies the linking rules to an identity returned by a provider. A non-zero
+// linkUserID links it to that user (rule 2).
+func (s *IdentityService) login(ctx context.Context, ext *models.ExternalIdentity, linkUserID uint) (*LoginResult, error) {
+	// Rule 1: known identity
+	existing, err := s.identityRepo.GetByProviderSubject(ctx, ext.Provider, ext.Subject)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if existing != nil {
+		if linkUserID != 0 && existing.UserID != linkUserID {
+			return nil, ErrIdentityLinkedElsewhere
+		}
+		if err := s.identityRepo.TouchLogin(ctx, existing.ID, ext); err != nil {
+			s.log.Warn("Failed to update identity login time", zap.Uint("identity_id", existing.ID), zap.Error(err))
+		}
+		user, err := s.userRepo.GetByID(ctx, existing.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{User: user}, nil
+	}
+
+	// Rule 2: explicit link by a signed-in user
+	if linkUserID != 0 {
+		user, err := s.userRepo.GetByID(ctx, linkUserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.linkIdentity(ctx, user.ID, ext); err != nil {
+			return nil, err
+		}
+		return &LoginResult{User: user, Linked: true}, nil
+	}
+
+	// Rule 3: verified email matches an existing account
+	if ext.Email != "" {
+		user, err := s.userRepo.GetByEmail(ctx, ext.Email)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if user != nil {
+			if !ext.EmailVerified {
+				return nil, ErrEmailInUse
+			}
+			if err := s.linkIdentity(ctx, user.ID, ext); err != nil {
+				return nil, err
+			}
+			s.log.Info("Linked provider identity by verified email",
+				zap.String("provider", ext.Provider),
+				zap.Uint("user_id", user.ID),
+			)
+			return &LoginResult{User: user, Linked: true}, nil
+		}
+	}
+
+	// Rule 4: new account
+	user, err := s.createUser(ctx, ext)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.linkIdentity(ctx, user.ID, ext); err != nil {
+		return nil, err
+	}
+	s.log.Info("New user created via login provider",
+		zap.String("provider", ext.Provider),
+		zap.Uint("user_id", user.ID),
+	)
+	return &LoginResult{User: user, Created: true}, nil
+}
+
+// ListIdentities returns the identities linked to a user.
+func (s *IdentityService) ListIdentities(ctx context.Context, userID uint) ([]models.UserIdentity, error) {
+	return s.identityRepo.GetByUserID(ctx, userID)
+}
+
+// Unlink removes an identity from a user, keeping at least one way to sign in.
+func (s *IdentityService) Unlink(ctx context.Context, userID, identityID uint) error {
+	identity, err := s.identityRepo.GetByID(ctx, identityID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrIdentityNotFound
+		}
+		return err
+	}
+	if identity.UserID != userID {
+		return ErrIdentityNotFound
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Passwordless {
+		count, err := s.identityRepo.CountByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if count <= 1 {
+			return ErrLastIdentity
+		}
+	}
+
+	return s.identityRepo.Delete(ctx, identityID)
+}
+
+// linkIdentity stores a new identity for a user.
+func (s *IdentityService) linkIdentity(ctx context.Context, userID uint, ext *models.ExternalIdentity) error {
+	now := time.Now().UTC()
+	return s.identityRepo.Create(ctx, &models.UserIdentity{
+		UserID:        userID,
+		Provider:      ext.Provider,
+		Subject:       ext.Subject,
+		Email:         ext.Email,
+		EmailVerified: ext.EmailVerified,
+		Name:          ext.Name,
+		AvatarURL:     ext.AvatarURL,
+		LastLoginAt:   &now,
+	})
+}
+
+// createUser creates a passwordless user for a provider identity.
+func (s *IdentityService) createUser(ctx context.Context, ext *models.ExternalIdentity) (*models.User, error) {
+	password, err := randomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate password: %w", err)
+	}
+
+	email := ext.Email
+	if email == "" || !ext.EmailVerified {
+		// Unverified addresses are not claimed, so they cannot later be matched by rule 3.
+		// Some providers (e.g. GitHub with a private email) return no address at all.
+		email = fmt.Sprintf("%s+%s@users.noreply.invalid", ext.Provider, ext.Subject)
+	}
+	name := strings.TrimSpace(ext.Name)
+	if name == "" {
+		name = strings.Split(email, "@")[0]
+	}
+
+	user := &models.User{
+		Email:        email,
+		Name:         name,
+		Password:     password, // Will be hashed in repository; never used for login
+		Passwordless: true,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -5,11 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	"vibe-backend/internal/metrics"
 	"vibe-backend/internal/models"
 	"vibe-backend/internal/repository"
+	"vibe-backend/internal/telemetry"
 )
 
 // InsightProcessor handles async processing of insights.
@@ -17,6 +23,9 @@ type InsightProcessor struct {
 	repo               *repository.InsightRepository
 	youtubeService     *YouTubeService
 	translationService *TranslationService
+	searchService      *SearchService
+	chapterService     *ChapterService
+	usage              *UsageService
 	log                *zap.Logger
 }
 
@@ -39,10 +48,41 @@ func (p *InsightProcessor) SetTranslationService(svc *TranslationService) {
 	p.translationService = svc
 }
 
+// SetSearchService sets the search service used to index processed insights.
+func (p *InsightProcessor) SetSearchService(svc *SearchService) {
+	p.searchService = svc
+}
+
+// SetChapterService sets the service that detects chapters of processed videos.
+func (p *InsightProcessor) SetChapterService(svc *ChapterService) {
+	p.chapterService = svc
+}
+
+// SetUsageService sets the service that budgets LLM calls. The services used for
+// processing meter their own calls.
+func (p *InsightProcessor) SetUsageService(svc *UsageService) {
+	p.usage = svc
+}
+
+// CheckBudget returns ErrLLMBudgetExceeded if userID has spent their daily LLM
+// budget, so processing is not started only to fail halfway.
+func (p *InsightProcessor) CheckBudget(ctx context.Context, userID uint) error {
+	return p.usage.Check(WithUsageUser(ctx, userID), "")
+}
+
 // ProcessInsightAsync starts async processing of an insight.
-// This should be called in a goroutine.
+// This should be called in a goroutine, with a context detached from the request's
+// cancellation; the job's trace is linked to the request's.
 func (p *InsightProcessor) ProcessInsightAsync(ctx context.Context, insightID uint) {
-	p.log.Info("Starting async insight processing", zap.Uint("insight_id", insightID))
+	p.log.Info("Starting async insight processing",
+		zap.Uint("insight_id", insightID),
+		zap.String("request_id", telemetry.RequestID(ctx)),
+	)
+	ctx, span := telemetry.StartJob(ctx, "insight.process", attribute.Int("insight.id", int(insightID)))
+	defer span.End()
+	start := time.Now()
+	outcome := metrics.OutcomeFailure
+	defer func() { metrics.ObserveJob(metrics.JobInsight, start, outcome) }()
 
 	// Get the insight
 	insight, err := p.repo.GetByID(ctx, insightID)
@@ -53,6 +93,8 @@ func (p *InsightProcessor) ProcessInsightAsync(ctx context.Context, insightID ui
 		)
 		return
 	}
+	// LLM calls made while processing are billed to the insight's owner
+	ctx = WithUsageUser(ctx, insight.UserID)
 
 	// Update status to processing
 	if err := p.repo.UpdateStatus(ctx, insightID, models.InsightStatusProcessing, ""); err != nil {
@@ -74,7 +116,9 @@ func (p *InsightProcessor) ProcessInsightAsync(ctx context.Context, insightID ui
 
 	switch sourceType {
 	case models.SourceTypeYouTube:
-		p.processYouTubeInsight(ctx, insight)
+		if p.processYouTubeInsight(ctx, insight) {
+			outcome = metrics.OutcomeSuccess
+		}
 	default:
 		p.handleProcessingError(ctx, insightID, fmt.Sprintf("暂不支持的来源类型: %s", sourceType))
 	}
@@ -104,8 +148,8 @@ func (p *InsightProcessor) detectSourceType(sourceURL string) (models.SourceType
 	return "", fmt.Errorf("无法从 URL 识别来源类型: %s", sourceURL)
 }
 
-// processYouTubeInsight processes a YouTube video insight.
-func (p *InsightProcessor) processYouTubeInsight(ctx context.Context, insight *models.Insight) {
+// processYouTubeInsight processes a YouTube video insight and reports whether it succeeded.
+func (p *InsightProcessor) processYouTubeInsight(ctx context.Context, insight *models.Insight) bool {
 	p.log.Info("Processing YouTube insight",
 		zap.Uint("insight_id", insight.ID),
 		zap.String("source_url", insight.SourceURL),
@@ -115,13 +159,14 @@ func (p *InsightProcessor) processYouTubeInsight(ctx context.Context, insight *m
 	videoID, err := p.youtubeService.ExtractVideoID(insight.SourceURL)
 	if err != nil {
 		p.handleProcessingError(ctx, insight.ID, fmt.Sprintf("无效的 YouTube URL: %v", err))
-		return
+		return false
 	}
 
 	insight.SourceID = videoID
 
 	// Fetch video metadata with multiple fallback methods
 	var metadata *VideoMetadata
+	stageStart := time.Now()
 	
 	// Method 1: Try YouTube Data API v3 (fastest if configured)
 	metadata, err = p.youtubeService.GetVideoMetadataFromAPI(ctx, videoID)
@@ -142,12 +187,15 @@ func (p *InsightProcessor) processYouTubeInsight(ctx context.Context, insight *m
 			// Method 3: Try Gemini/OpenRouter (last resort, requires valid API key)
 			metadata, err = p.youtubeService.GetVideoMetadata(ctx, insight.SourceURL)
 			if err != nil {
+				metrics.ObserveJobStage(metrics.JobInsight, "metadata", stageStart, err)
 				p.handleProcessingError(ctx, insight.ID, fmt.Sprintf("无法获取视频元数据: 所有方法都失败了。YouTube API: 未配置或失败, yt-dlp: %v, OpenRouter API: %v", err, err))
-				return
+				return false
 			}
 		}
 	}
 
+	metrics.ObserveJobStage(metrics.JobInsight, "metadata", stageStart, nil)
+
 	// Update insight with metadata
 	insight.Title = metadata.Title
 	insight.Author = metadata.Author
@@ -155,7 +203,9 @@ func (p *InsightProcessor) processYouTubeInsight(ctx context.Context, insight *m
 	insight.Duration = metadata.Duration
 
 	// Fetch structured transcripts
+	stageStart = time.Now()
 	transcriptResponse, err := p.youtubeService.FetchYouTubeTranscriptStructured(ctx, videoID)
+	metrics.ObserveJobStage(metrics.JobInsight, "transcript", stageStart, err)
 	if err != nil {
 		p.log.Warn("Failed to fetch structured transcripts",
 			zap.String("video_id", videoID),
@@ -164,7 +214,9 @@ func (p *InsightProcessor) processYouTubeInsight(ctx context.Context, insight *m
 		// Transcripts are optional, continue processing
 	} else {
 		// Convert transcripts to the format expected by Insight model
-		transcripts, err := p.convertTranscriptsToInsightFormat(transcriptResponse, insight.TargetLang)
+		stageStart = time.Now()
+		transcripts, err := p.convertTranscriptsToInsightFormat(ctx, transcriptResponse, insight.TargetLang)
+		metrics.ObserveJobStage(metrics.JobInsight, "translation", stageStart, err)
 		if err != nil {
 			p.log.Warn("Failed to convert transcripts",
 				zap.String("video_id", videoID),
@@ -181,13 +233,22 @@ func (p *InsightProcessor) processYouTubeInsight(ctx context.Context, insight *m
 	// Update insight with all collected data
 	insight.Status = models.InsightStatusCompleted
 
-	if err := p.repo.Update(ctx, insight); err != nil {
+	stageStart = time.Now()
+	err = p.repo.Update(ctx, insight)
+	metrics.ObserveJobStage(metrics.JobInsight, "save", stageStart, err)
+	if err != nil {
 		p.log.Error("Failed to update insight after processing",
 			zap.Uint("insight_id", insight.ID),
 			zap.Error(err),
 		)
 		p.handleProcessingError(ctx, insight.ID, fmt.Sprintf("保存处理结果失败: %v", err))
-		return
+		return false
+	}
+
+	if p.searchService != nil {
+		p.searchService.Reindex("insight", insight.ID, func() error {
+			return p.searchService.IndexInsight(ctx, insight.ID)
+		})
 	}
 
 	p.log.Info("Successfully processed YouTube insight",
@@ -195,11 +256,52 @@ func (p *InsightProcessor) processYouTubeInsight(ctx context.Context, insight *m
 		zap.String("title", insight.Title),
 		zap.Int("duration", insight.Duration),
 	)
+
+	// Chapters come after the insight is available, since segmentation may call the LLM
+	p.detectChapters(ctx, insight, metadata)
+	return true
+}
+
+// detectChapters detects and saves the chapters of a processed video. Failures are
+// logged; an insight without chapters is still usable.
+func (p *InsightProcessor) detectChapters(ctx context.Context, insight *models.Insight, metadata *VideoMetadata) {
+	if p.chapterService == nil {
+		return
+	}
+
+	start := time.Now()
+	detected, source := p.chapterService.Detect(ctx, metadata, parseTranscripts(insight))
+	chapters := make([]models.Chapter, len(detected))
+	for i, chapter := range detected {
+		chapters[i] = models.Chapter{
+			InsightID:  &insight.ID,
+			Title:      chapter.Title,
+			Timestamp:  chapter.Timestamp,
+			Seconds:    chapter.Seconds,
+			Source:     source,
+			OrderIndex: i,
+		}
+	}
+	err := p.repo.ReplaceChapters(ctx, insight.ID, chapters)
+	metrics.ObserveJobStage(metrics.JobInsight, "chapters", start, err)
+	if err != nil {
+		p.log.Error("Failed to save insight chapters",
+			zap.Uint("insight_id", insight.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	p.log.Info("Detected insight chapters",
+		zap.Uint("insight_id", insight.ID),
+		zap.Int("count", len(chapters)),
+		zap.String("source", string(source)),
+	)
 }
 
 // convertTranscriptsToInsightFormat converts YouTube transcripts to the Insight model format.
 // It also translates the transcripts to the target language if translation service is available.
-func (p *InsightProcessor) convertTranscriptsToInsightFormat(response *models.YouTubeTranscriptResponse, targetLang string) ([]byte, error) {
+func (p *InsightProcessor) convertTranscriptsToInsightFormat(ctx context.Context, response *models.YouTubeTranscriptResponse, targetLang string) ([]byte, error) {
 	// Convert to TranscriptItem array format expected by the Insight model
 	var transcriptItems []models.TranscriptItem
 
@@ -260,7 +362,7 @@ func (p *InsightProcessor) convertTranscriptsToInsightFormat(response *models.Yo
 		// Detect source language from first segment
 		var sourceLang string
 		if len(texts) > 0 {
-			detected, err := p.translationService.DetectLanguage(context.Background(), texts[0])
+			detected, err := p.translationService.DetectLanguage(ctx, texts[0])
 			if err != nil {
 				p.log.Warn("Failed to detect source language, skipping translation",
 					zap.Error(err),
@@ -276,7 +378,7 @@ func (p *InsightProcessor) convertTranscriptsToInsightFormat(response *models.Yo
 		// Only attempt translation if we detected the source language
 		if sourceLang != "" && sourceLang != targetLang {
 			// Batch translate
-			translations, err := p.translationService.TranslateBatch(context.Background(), texts, sourceLang, targetLang)
+			translations, err := p.translationService.TranslateBatch(ctx, texts, sourceLang, targetLang)
 			if err != nil {
 				p.log.Warn("⚠️  翻译失败，字幕仍包含原文",
 					zap.Error(err),
@@ -340,6 +442,7 @@ func (p *InsightProcessor) extractRawContentFromTranscripts(response *models.You
 
 // handleProcessingError updates the insight status to failed with an error message.
 func (p *InsightProcessor) handleProcessingError(ctx context.Context, insightID uint, errorMsg string) {
+	trace.SpanFromContext(ctx).SetStatus(codes.Error, errorMsg)
 	p.log.Error("Insight processing failed",
 		zap.Uint("insight_id", insightID),
 		zap.String("error", errorMsg),
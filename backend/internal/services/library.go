@@ -0,0 +1,492 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+)
+
+// Library service errors.
+var (
+	ErrTagNotFound         = errors.New("tag not found")
+	ErrTagExists           = errors.New("tag with this name already exists")
+	ErrCollectionNotFound  = errors.New("collection not found")
+	ErrCollectionCycle     = errors.New("collection cannot be moved into itself")
+	ErrMixedWorkspaces     = errors.New("insights belong to different workspaces")
+	ErrBulkInsightNotFound = errors.New("one or more insights not found")
+)
+
+// LibraryService organizes a workspace's insights with tags and nested collections.
+// Any member can read the library; editing tags, collections and assignments needs
+// the editor role.
+type LibraryService struct {
+	repo       *repository.LibraryRepository
+	workspaces *WorkspaceService
+	log        *zap.Logger
+}
+
+// NewLibraryService creates a new LibraryService.
+func NewLibraryService(repo *repository.LibraryRepository, workspaces *WorkspaceService, log *zap.Logger) *LibraryService {
+	return &LibraryService{
+		repo:       repo,
+		workspaces: workspaces,
+		log:        log,
+	}
+}
+
+// Sidebar returns tag counts, the collection tree and per-source-type counts for a workspace.
+func (s *LibraryService) Sidebar(ctx context.Context, user *models.User, workspaceID *uint) (*models.LibrarySidebarResponse, error) {
+	workspace, _, err := s.workspaces.Resolve(ctx, user, workspaceID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := s.repo.ListTagsWithCounts(ctx, workspace.ID)
+	if err != nil {
+		return nil, err
+	}
+	collections, err := s.collectionTree(ctx, workspace.ID)
+	if err != nil {
+		return nil, err
+	}
+	sourceTypes, err := s.repo.SourceTypeCounts(ctx, workspace.ID)
+	if err != nil {
+		return nil, err
+	}
+	unfiled, err := s.repo.CountUnfiled(ctx, workspace.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, count := range sourceTypes {
+		total += count
+	}
+
+	return &models.LibrarySidebarResponse{
+		WorkspaceID: workspace.ID,
+		Total:       total,
+		Unfiled:     unfiled,
+		Tags:        tags,
+		Collections: collections,
+		SourceTypes: sourceTypes,
+	}, nil
+}
+
+// --- Tags ---
+
+// ListTags returns a workspace's tags with insight counts.
+func (s *LibraryService) ListTags(ctx context.Context, user *models.User, workspaceID *uint) ([]models.TagWithCount, error) {
+	workspace, _, err := s.workspaces.Resolve(ctx, user, workspaceID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListTagsWithCounts(ctx, workspace.ID)
+}
+
+// CreateTag creates a tag in a workspace. Tag names are unique per workspace, ignoring case.
+func (s *LibraryService) CreateTag(ctx context.Context, user *models.User, req *models.CreateTagRequest) (*models.Tag, error) {
+	workspace, _, err := s.workspaces.Resolve(ctx, user, req.WorkspaceID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if err := s.ensureTagNameFree(ctx, workspace.ID, name, 0); err != nil {
+		return nil, err
+	}
+
+	tag := &models.Tag{
+		WorkspaceID: workspace.ID,
+		Name:        name,
+		Color:       req.Color,
+		CreatedBy:   user.ID,
+	}
+	if err := s.repo.CreateTag(ctx, tag); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// UpdateTag renames or recolors a tag.
+func (s *LibraryService) UpdateTag(ctx context.Context, userID, tagID uint, req *models.UpdateTagRequest) (*models.Tag, error) {
+	tag, err := s.loadTag(ctx, userID, tagID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if err := s.ensureTagNameFree(ctx, tag.WorkspaceID, name, tag.ID); err != nil {
+			return nil, err
+		}
+		tag.Name = name
+	}
+	if req.Color != nil {
+		tag.Color = *req.Color
+	}
+
+	if err := s.repo.UpdateTag(ctx, tag); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// DeleteTag deletes a tag and removes it from all insights.
+func (s *LibraryService) DeleteTag(ctx context.Context, userID, tagID uint) error {
+	tag, err := s.loadTag(ctx, userID, tagID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteTag(ctx, tag.ID)
+}
+
+// --- Collections ---
+
+// ListCollections returns a workspace's collections as a tree.
+func (s *LibraryService) ListCollections(ctx context.Context, user *models.User, workspaceID *uint) ([]models.CollectionNode, error) {
+	workspace, _, err := s.workspaces.Resolve(ctx, user, workspaceID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.collectionTree(ctx, workspace.ID)
+}
+
+// CreateCollection creates a collection, appended after its siblings unless a position is given.
+func (s *LibraryService) CreateCollection(ctx context.Context, user *models.User, req *models.CreateCollectionRequest) (*models.Collection, error) {
+	workspace, _, err := s.workspaces.Resolve(ctx, user, req.WorkspaceID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ParentID != nil {
+		parent, err := s.repo.GetCollection(ctx, *req.ParentID)
+		if err != nil || parent.WorkspaceID != workspace.ID {
+			return nil, notFoundOr(err, ErrCollectionNotFound)
+		}
+	}
+
+	collection := &models.Collection{
+		WorkspaceID: workspace.ID,
+		ParentID:    req.ParentID,
+		Name:        strings.TrimSpace(req.Name),
+		CreatedBy:   user.ID,
+	}
+	if req.Position != nil {
+		collection.Position = *req.Position
+	} else {
+		position, err := s.repo.NextCollectionPosition(ctx, workspace.ID, req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		collection.Position = position
+	}
+
+	if err := s.repo.CreateCollection(ctx, collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// UpdateCollection renames, re-parents or reorders a collection.
+func (s *LibraryService) UpdateCollection(ctx context.Context, userID, collectionID uint, req *models.UpdateCollectionRequest) (*models.Collection, error) {
+	collection, err := s.loadCollection(ctx, userID, collectionID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		collection.Name = strings.TrimSpace(*req.Name)
+	}
+
+	moved := false
+	switch {
+	case req.MoveToRoot:
+		moved = collection.ParentID != nil
+		collection.ParentID = nil
+	case req.ParentID != nil:
+		if err := s.checkReparent(ctx, collection, *req.ParentID); err != nil {
+			return nil, err
+		}
+		moved = collection.ParentID == nil || *collection.ParentID != *req.ParentID
+		collection.ParentID = req.ParentID
+	}
+
+	if req.Position != nil {
+		collection.Position = *req.Position
+	} else if moved {
+		position, err := s.repo.NextCollectionPosition(ctx, collection.WorkspaceID, collection.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		collection.Position = position
+	}
+
+	if err := s.repo.UpdateCollection(ctx, collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// DeleteCollection deletes a collection. Sub-collections and insights move to its parent.
+func (s *LibraryService) DeleteCollection(ctx context.Context, userID, collectionID uint) error {
+	collection, err := s.loadCollection(ctx, userID, collectionID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteCollection(ctx, collection)
+}
+
+// --- Bulk operations ---
+
+// BulkTag adds and removes tags on many insights of one workspace.
+func (s *LibraryService) BulkTag(ctx context.Context, user *models.User, req *models.BulkTagRequest) (*models.BulkResult, error) {
+	insightIDs := uniqueIDs(req.InsightIDs)
+	workspaceID, err := s.bulkWorkspace(ctx, user, insightIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	addIDs := uniqueIDs(req.AddTagIDs)
+	removeIDs := uniqueIDs(req.RemoveTagIDs)
+	if err := s.ensureTagsInWorkspace(ctx, workspaceID, append(append([]uint{}, addIDs...), removeIDs...)); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.AddTags(ctx, insightIDs, addIDs); err != nil {
+		return nil, err
+	}
+	if err := s.repo.RemoveTags(ctx, insightIDs, removeIDs); err != nil {
+		return nil, err
+	}
+
+	return &models.BulkResult{Updated: len(insightIDs)}, nil
+}
+
+// BulkMove moves many insights of one workspace into a collection, or unfiles them
+// when collectionID is nil.
+func (s *LibraryService) BulkMove(ctx context.Context, user *models.User, req *models.BulkMoveRequest) (*models.BulkResult, error) {
+	insightIDs := uniqueIDs(req.InsightIDs)
+	workspaceID, err := s.bulkWorkspace(ctx, user, insightIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.CollectionID != nil {
+		collection, err := s.repo.GetCollection(ctx, *req.CollectionID)
+		if err != nil || collection.WorkspaceID != workspaceID {
+			return nil, notFoundOr(err, ErrCollectionNotFound)
+		}
+	}
+
+	if err := s.repo.MoveInsights(ctx, insightIDs, req.CollectionID); err != nil {
+		return nil, err
+	}
+	return &models.BulkResult{Updated: len(insightIDs)}, nil
+}
+
+// bulkWorkspace checks that all insights exist, share one workspace and that the user
+// is an editor there. It returns the workspace ID.
+func (s *LibraryService) bulkWorkspace(ctx context.Context, user *models.User, insightIDs []uint) (uint, error) {
+	// Makes sure legacy insights have been moved into the personal workspace
+	if _, err := s.workspaces.EnsurePersonal(ctx, user); err != nil {
+		return 0, err
+	}
+
+	refs, err := s.repo.GetInsightRefs(ctx, insightIDs)
+	if err != nil {
+		return 0, err
+	}
+	if len(refs) != len(insightIDs) {
+		return 0, ErrBulkInsightNotFound
+	}
+
+	var workspaceID uint
+	for i, ref := range refs {
+		if ref.WorkspaceID == nil {
+			return 0, ErrWorkspaceForbidden
+		}
+		if i > 0 && *ref.WorkspaceID != workspaceID {
+			return 0, ErrMixedWorkspaces
+		}
+		workspaceID = *ref.WorkspaceID
+	}
+
+	role, err := s.workspaces.Role(ctx, workspaceID, user.ID)
+	if err != nil {
+		if errors.Is(err, ErrWorkspaceNotFound) {
+			return 0, ErrBulkInsightNotFound
+		}
+		return 0, err
+	}
+	if !role.Allows(models.WorkspaceRoleEditor) {
+		return 0, ErrWorkspaceForbidden
+	}
+	return workspaceID, nil
+}
+
+// --- Helpers ---
+
+// loadTag returns a tag after checking the user's role in its workspace.
+// Tags in workspaces the user cannot see are reported as not found.
+func (s *LibraryService) loadTag(ctx context.Context, userID, tagID uint, need models.WorkspaceRole) (*models.Tag, error) {
+	tag, err := s.repo.GetTag(ctx, tagID)
+	if err != nil {
+		return nil, notFoundOr(err, ErrTagNotFound)
+	}
+	if err := s.checkRole(ctx, userID, tag.WorkspaceID, need, ErrTagNotFound); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// loadCollection returns a collection after checking the user's role in its workspace.
+func (s *LibraryService) loadCollection(ctx context.Context, userID, collectionID uint, need models.WorkspaceRole) (*models.Collection, error) {
+	collection, err := s.repo.GetCollection(ctx, collectionID)
+	if err != nil {
+		return nil, notFoundOr(err, ErrCollectionNotFound)
+	}
+	if err := s.checkRole(ctx, userID, collection.WorkspaceID, need, ErrCollectionNotFound); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// checkRole checks the user's workspace role, mapping non-membership to notFound.
+func (s *LibraryService) checkRole(ctx context.Context, userID, workspaceID uint, need models.WorkspaceRole, notFound error) error {
+	role, err := s.workspaces.Role(ctx, workspaceID, userID)
+	if err != nil {
+		if errors.Is(err, ErrWorkspaceNotFound) {
+			return notFound
+		}
+		return err
+	}
+	if !role.Allows(need) {
+		return ErrWorkspaceForbidden
+	}
+	return nil
+}
+
+// checkReparent verifies that parentID is in the same workspace and not the collection
+// itself or one of its descendants.
+func (s *LibraryService) checkReparent(ctx context.Context, collection *models.Collection, parentID uint) error {
+	collections, err := s.repo.ListCollections(ctx, collection.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	parents := make(map[uint]*uint, len(collections))
+	for _, c := range collections {
+		parents[c.ID] = c.ParentID
+	}
+	if _, ok := parents[parentID]; !ok {
+		return ErrCollectionNotFound
+	}
+
+	// Walk up from the new parent; reaching the collection means a cycle
+	for id := &parentID; id != nil; id = parents[*id] {
+		if *id == collection.ID {
+			return ErrCollectionCycle
+		}
+	}
+	return nil
+}
+
+// ensureTagNameFree returns ErrTagExists if another tag in the workspace has the name.
+func (s *LibraryService) ensureTagNameFree(ctx context.Context, workspaceID uint, name string, exceptID uint) error {
+	existing, err := s.repo.GetTagByName(ctx, workspaceID, name)
+	if err == nil && existing.ID != exceptID {
+		return ErrTagExists
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+// ensureTagsInWorkspace returns ErrTagNotFound unless every tag belongs to the workspace.
+func (s *LibraryService) ensureTagsInWorkspace(ctx context.Context, workspaceID uint, tagIDs []uint) error {
+	tagIDs = uniqueIDs(tagIDs)
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	count, err := s.repo.CountTagsInWorkspace(ctx, workspaceID, tagIDs)
+	if err != nil {
+		return err
+	}
+	if count != int64(len(tagIDs)) {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+// collectionTree builds the nested collection tree of a workspace with insight counts.
+func (s *LibraryService) collectionTree(ctx context.Context, workspaceID uint) ([]models.CollectionNode, error) {
+	collections, err := s.repo.ListCollections(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.repo.CollectionCounts(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]models.Collection)
+	known := make(map[uint]bool, len(collections))
+	for _, c := range collections {
+		known[c.ID] = true
+	}
+	var roots []models.Collection
+	for _, c := range collections {
+		// Collections whose parent is gone are shown at the top level
+		if c.ParentID == nil || !known[*c.ParentID] {
+			roots = append(roots, c)
+			continue
+		}
+		children[*c.ParentID] = append(children[*c.ParentID], c)
+	}
+
+	var build func(list []models.Collection) []models.CollectionNode
+	build = func(list []models.Collection) []models.CollectionNode {
+		nodes := make([]models.CollectionNode, 0, len(list))
+		for _, c := range list {
+			nodes = append(nodes, models.CollectionNode{
+				ID:       c.ID,
+				ParentID: c.ParentID,
+				Name:     c.Name,
+				Position: c.Position,
+				Count:    counts[c.ID],
+				Children: build(children[c.ID]),
+			})
+		}
+		return nodes
+	}
+	return build(roots), nil
+}
+
+// notFoundOr maps gorm.ErrRecordNotFound (or a nil error from a failed ownership check)
+// to notFound and passes other errors through.
+func notFoundOr(err, notFound error) error {
+	if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound
+	}
+	return err
+}
+
+// uniqueIDs returns ids without duplicates, preserving order.
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
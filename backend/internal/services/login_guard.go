@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// LoginGuardConfig holds the thresholds for login brute-force protection.
+type LoginGuardConfig struct {
+	// FreeAttempts is the number of failures allowed before delays start.
+	FreeAttempts int
+	// MaxAccountFailures locks an account after this many failures within Window.
+	MaxAccountFailures int
+	// MaxIPFailures locks a client IP after this many failures within Window.
+	MaxIPFailures int
+	// Window is how long failures are remembered.
+	Window time.Duration
+	// LockoutDuration is how long a lockout lasts.
+	LockoutDuration time.Duration
+	// MaxDelay caps the progressive delay between attempts.
+	MaxDelay time.Duration
+}
+
+// DefaultLoginGuardConfig returns the default login protection thresholds.
+func DefaultLoginGuardConfig() LoginGuardConfig {
+	return LoginGuardConfig{
+		FreeAttempts:       3,
+		MaxAccountFailures: 10,
+		MaxIPFailures:      50,
+		Window:             15 * time.Minute,
+		LockoutDuration:    15 * time.Minute,
+		MaxDelay:           30 * time.Second,
+	}
+}
+
+// LoginFailure describes the state after a failed login was recorded.
+type LoginFailure struct {
+	// AccountLocked is true when this failure triggered an account lockout.
+	AccountLocked bool
+	// RetryAfter is how long the client must wait before the next attempt.
+	RetryAfter time.Duration
+}
+
+// attemptStore keeps failure counters and temporary blocks.
+type attemptStore interface {
+	// incr increments a counter, starting its window on first use.
+	incr(ctx context.Context, key string, window time.Duration) (int64, error)
+	// block blocks a key for d.
+	block(ctx context.Context, key string, d time.Duration) error
+	// blockedFor returns the remaining block time for a key, or 0.
+	blockedFor(ctx context.Context, key string) (time.Duration, error)
+	// reset removes keys.
+	reset(ctx context.Context, keys ...string) error
+}
+
+// LoginGuard tracks failed logins per account and per IP and applies progressive
+// delays and temporary lockouts. Counters live in Redis when available so they are
+// shared across replicas; otherwise (or if Redis fails) an in-memory store is used.
+type LoginGuard struct {
+	config   LoginGuardConfig
+	store    attemptStore
+	fallback *memoryAttemptStore
+	log      *zap.Logger
+}
+
+// NewLoginGuard creates a new LoginGuard. redisClient may be nil.
+func NewLoginGuard(config LoginGuardConfig, redisClient *redis.Client, log *zap.Logger) *LoginGuard {
+	fallback := newMemoryAttemptStore(config.Window)
+	g := &LoginGuard{
+		config:   config,
+		store:    fallback,
+		fallback: fallback,
+		log:      log,
+	}
+	if redisClient != nil {
+		g.store = &redisAttemptStore{client: redisClient}
+	}
+	return g
+}
+
+// Check returns how long the caller must wait before attempting to log in, or 0.
+func (g *LoginGuard) Check(ctx context.Context, email, ip string) time.Duration {
+	var wait time.Duration
+	for _, key := range []string{g.blockKey("account", accountKey(email)), g.blockKey("ip", ip)} {
+		var d time.Duration
+		g.withStore(func(s attemptStore) (err error) {
+			d, err = s.blockedFor(ctx, key)
+			return err
+		})
+		if d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// RecordFailure counts a failed login and applies delays or lockouts.
+func (g *LoginGuard) RecordFailure(ctx context.Context, email, ip string) LoginFailure {
+	var result LoginFailure
+
+	account := accountKey(email)
+	accountFailures := g.increment(ctx, g.failKey("account", account))
+	accountDelay := g.delayFor(accountFailures)
+	if accountFailures >= int64(g.config.MaxAccountFailures) {
+		accountDelay = g.config.LockoutDuration
+		result.AccountLocked = accountFailures == int64(g.config.MaxAccountFailures)
+	}
+	g.applyBlock(ctx, g.blockKey("account", account), accountDelay)
+
+	ipFailures := g.increment(ctx, g.failKey("ip", ip))
+	var ipDelay time.Duration
+	if ipFailures >= int64(g.config.MaxIPFailures) {
+		ipDelay = g.config.LockoutDuration
+	}
+	g.applyBlock(ctx, g.blockKey("ip", ip), ipDelay)
+
+	result.RetryAfter = accountDelay
+	if ipDelay > result.RetryAfter {
+		result.RetryAfter = ipDelay
+	}
+	return result
+}
+
+// RecordSuccess clears the account's failure history after a successful login.
+// The IP counter is left alone so one valid account cannot be used to reset it.
+func (g *LoginGuard) RecordSuccess(ctx context.Context, email string) {
+	account := accountKey(email)
+	g.withStore(func(s attemptStore) error {
+		return s.reset(ctx, g.failKey("account", account), g.blockKey("account", account))
+	})
+}
+
+// delayFor returns the progressive delay after n failures: 1s, 2s, 4s ... up to MaxDelay.
+func (g *LoginGuard) delayFor(n int64) time.Duration {
+	over := n - int64(g.config.FreeAttempts)
+	if over <= 0 {
+		return 0
+	}
+	if over > 16 {
+		return g.config.MaxDelay
+	}
+	delay := time.Second << (over - 1)
+	if delay > g.config.MaxDelay {
+		delay = g.config.MaxDelay
+	}
+	return delay
+}
+
+func (g *LoginGuard) increment(ctx context.Context, key string) int64 {
+	var n int64
+	g.withStore(func(s attemptStore) (err error) {
+		n, err = s.incr(ctx, key, g.config.Window)
+		return err
+	})
+	return n
+}
+
+func (g *LoginGuard) applyBlock(ctx context.Context, key string, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	g.withStore(func(s attemptStore) error {
+		return s.block(ctx, key, d)
+	})
+}
+
+// withStore runs fn against the primary store, falling back to memory on error.
+func (g *LoginGuard) withStore(fn func(attemptStore) error) {
+	err := fn(g.store)
+	if err == nil || g.store == attemptStore(g.fallback) {
+		return
+	}
+	g.log.Warn("Login guard store unavailable, using in-memory fallback", zap.Error(err))
+	_ = fn(g.fallback)
+}
+
+func (g *LoginGuard) failKey(scope, id string) string {
+	return "login:fail:" + scope + ":" + id
+}
+
+func (g *LoginGuard) blockKey(scope, id string) string {
+	return "login:block:" + scope + ":" + id
+}
+
+// accountKey hashes the normalized email so addresses are not stored in plain text.
+func accountKey(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:16])
+}
+
+// redisAttemptStore keeps counters in Redis.
+type redisAttemptStore struct {
+	client *redis.Client
+}
+
+func (s *redisAttemptStore) incr(ctx context.Context, key string, window time.Duration) (int64, error) {
+	n, err := s.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 {
+		if err := s.client.Expire(ctx, key, window).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+func (s *redisAttemptStore) block(ctx context.Context, key string, d time.Duration) error {
+	return s.client.Set(ctx, key, 1, d).Err()
+}
+
+func (s *redisAttemptStore) blockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := s.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (s *redisAttemptStore) reset(ctx context.Context, keys ...string) error {
+	return s.client.Del(ctx, keys...).Err()
+}
+
+// memoryAttemptStore keeps counters in process memory.
+type memoryAttemptStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryAttemptEntry
+}
+
+type memoryAttemptEntry struct {
+	count     int64
+	expiresAt time.Time
+}
+
+func newMemoryAttemptStore(cleanupInterval time.Duration) *memoryAttemptStore {
+	s := &memoryAttemptStore{entries: make(map[string]*memoryAttemptEntry)}
+	go s.cleanup(cleanupInterval)
+	return s
+}
+
+// cleanup removes expired entries periodically.
+func (s *memoryAttemptStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		now := time.Now()
+		for key, entry := range s.entries {
+			if now.After(entry.expiresAt) {
+				delete(s.entries, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *memoryAttemptStore) incr(_ context.Context, key string, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := s.entries[key]
+	if !ok || now.After(entry.expiresAt) {
+		entry = &memoryAttemptEntry{expiresAt: now.Add(window)}
+		s.entries[key] = entry
+	}
+	entry.count++
+	return entry.count, nil
+}
+
+func (s *memoryAttemptStore) block(_ context.Context, key string, d time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = &memoryAttemptEntry{count: 1, expiresAt: time.Now().Add(d)}
+	return nil
+}
+
+func (s *memoryAttemptStore) blockedFor(_ context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return 0, nil
+	}
+	remaining := time.Until(entry.expiresAt)
+	if remaining <= 0 {
+		delete(s.entries, key)
+		return 0, nil
+	}
+	return remaining, nil
+}
+
+func (s *memoryAttemptStore) reset(_ context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
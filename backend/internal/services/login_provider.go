@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+	"vibe-backend/internal/models"
+)
+
+// LoginProvider is an external identity provider that users can sign in with.
+type LoginProvider interface {
+	// Name is the URL-safe identifier used in routes and stored on identities.
+	Name() string
+	// DisplayName is a human readable name for login buttons.
+	DisplayName() string
+	// Type is "oauth2" or "oidc".
+	Type() string
+	// AuthCodeURL returns the URL the user is redirected to for authorization.
+	AuthCodeURL(ctx context.Context, state, nonce string) (string, error)
+	// Exchange trades an authorization code for the user's normalized identity.
+	Exchange(ctx context.Context, code, nonce string) (*models.ExternalIdentity, error)
+}
+
+// LoginProviderRegistry holds the login providers configured for this server.
+type LoginProviderRegistry struct {
+	mu        sync.RWMutex
+	providers map[string]LoginProvider
+	order     []string
+}
+
+// NewLoginProviderRegistry creates an empty LoginProviderRegistry.
+func NewLoginProviderRegistry() *LoginProviderRegistry {
+	return &LoginProviderRegistry{
+		providers: make(map[string]LoginProvider),
+	}
+}
+
+// Register adds a provider to the registry, replacing any provider with the same name.
+func (r *LoginProviderRegistry) Register(p LoginProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.providers[p.Name()]; !exists {
+		r.order = append(r.order, p.Name())
+	}
+	r.providers[p.Name()] = p
+}
+
+// Get returns the provider with the given name.
+func (r *LoginProviderRegistry) Get(name string) (LoginProvider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// List returns all registered providers in registration order.
+func (r *LoginProviderRegistry) List() []LoginProvider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]LoginProvider, 0, len(r.order))
+	for _, name := range r.order {
+		list = append(list, r.providers[name])
+	}
+	return list
+}
+
+// GitHubProvider implements LoginProvider for GitHub OAuth apps.
+// GitHub is not an OIDC issuer, so the profile is read from the REST API.
+type GitHubProvider struct {
+	config     *oauth2.Config
+	apiBaseURL string
+	httpClient *http.Client
+}
+
+// NewGitHubProvider creates a new GitHubProvider.
+func NewGitHubProvider(clientID, clientSecret, redirectURL string) *GitHubProvider {
+	return &GitHubProvider{
+		config: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"read:user", "user:email"},
+			Endpoint:     github.Endpoint,
+		},
+		apiBaseURL: "https://api.github.com",
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Name returns the provider name.
+func (p *GitHubProvider) Name() string { return "github" }
+
+// DisplayName returns the provider display name.
+func (p *GitHubProvider) DisplayName() string { return "GitHub" }
+
+// Type returns the provider type.
+func (p *GitHubProvider) Type() string { return "oauth2" }
+
+// AuthCodeURL returns the GitHub authorization URL.
+func (p *GitHubProvider) AuthCodeURL(ctx context.Context, state, nonce string) (string, error) {
+	return p.config.AuthCodeURL(state), nil
+}
+
+// Exchange exchanges the code and fetches the GitHub user profile and primary email.
+func (p *GitHubProvider) Exchange(ctx context.Context, code, nonce string) (*models.ExternalIdentity, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.httpClient)
+	token, err := p.config.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+	client := p.config.Client(ctx, token)
+
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := p.getJSON(client, "/user", &user); err != nil {
+		return nil, err
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.getJSON(client, "/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &models.ExternalIdentity{
+		Provider:  p.Name(),
+		Subject:   strconv.FormatInt(user.ID, 10),
+		Name:      user.Name,
+		AvatarURL: user.AvatarURL,
+	}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+	for _, e := range emails {
+		if e.Primary {
+			identity.Email = strings.ToLower(e.Email)
+			identity.EmailVerified = e.Verified
+			break
+		}
+	}
+
+	return identity, nil
+}
+
+// getJSON performs a GET against the GitHub API and decodes the JSON response.
+func (p *GitHubProvider) getJSON(client *http.Client, path string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, p.apiBaseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call GitHub API %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("GitHub API %s: status %d, body: %s", path, resp.StatusCode, string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode GitHub API %s response: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,360 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+)
+
+const testOIDCClientID = "test-client"
+
+// testIssuer is a local OpenID Connect issuer: discovery, JWKS and a token endpoint
+// that returns an ID token with the claims registered for each authorization code.
+type testIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu     sync.Mutex
+	tokens map[string]string // Authorization code -> ID token
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	issuer := &testIssuer{key: key, kid: "test-key", tokens: make(map[string]string)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, map[string]string{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/authorize",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"jwks_uri":               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": issuer.kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mu.Lock()
+		idToken, ok := issuer.tokens[r.FormValue("code")]
+		issuer.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			writeTestJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		writeTestJSON(w, map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+// provider returns an OIDCProvider configured for the issuer.
+func (i *testIssuer) provider() *OIDCProvider {
+	return NewOIDCProvider("test", "Test", i.server.URL, testOIDCClientID, "secret", "http://localhost/callback", nil, i.server.Client())
+}
+
+// claims returns valid ID token claims for a subject, which tests adjust.
+func (i *testIssuer) claims(subject, email string, emailVerified interface{}, nonce string) map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":            i.server.URL,
+		"sub":            subject,
+		"aud":            testOIDCClientID,
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          nonce,
+		"email":          email,
+		"email_verified": emailVerified,
+		"name":           "Test User",
+	}
+}
+
+// issue registers an ID token with the given claims, signed by key, under code.
+func (i *testIssuer) issue(t *testing.T, code string, claims map[string]interface{}, key *rsa.PrivateKey) {
+	t.Helper()
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.tokens[code] = signTestJWT(t, key, i.kid, claims)
+}
+
+func signTestJWT(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeTestJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestOIDCProviderExchange(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider := issuer.provider()
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	if !strings.HasPrefix(authURL, issuer.server.URL+"/authorize?") || !strings.Contains(authURL, "nonce=nonce-1") {
+		t.Fatalf("AuthCodeURL = %q, want the discovered endpoint with the nonce", authURL)
+	}
+
+	// Some issuers send email_verified as a string
+	issuer.issue(t, "code", issuer.claims("alice", "Alice@Example.com", "true", "nonce-1"), issuer.key)
+	ext, err := provider.Exchange(ctx, "code", "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := models.ExternalIdentity{Provider: "test", Subject: "alice", Email: "alice@example.com", EmailVerified: true, Name: "Test User"}
+	if *ext != want {
+		t.Fatalf("Exchange = %+v, want %+v", *ext, want)
+	}
+}
+
+func TestOIDCProviderRejectsInvalidIDTokens(t *testing.T) {
+	issuer := newTestIssuer(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		adjust func(claims map[string]interface{})
+		key    *rsa.PrivateKey
+		nonce  string
+		want   string
+	}{
+		{name: "bad signature", key: otherKey, want: "invalid id_token signature"},
+		{name: "wrong audience", adjust: func(c map[string]interface{}) { c["aud"] = "someone-else" }, want: "audience"},
+		{name: "wrong issuer", adjust: func(c map[string]interface{}) { c["iss"] = "https://evil.example" }, want: "issuer"},
+		{name: "expired", adjust: func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, want: "expired"},
+		{name: "nonce mismatch", nonce: "other-nonce", want: "nonce"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := issuer.claims("alice", "alice@example.com", true, "nonce-1")
+			if tt.adjust != nil {
+				tt.adjust(claims)
+			}
+			key := issuer.key
+			if tt.key != nil {
+				key = tt.key
+			}
+			nonce := "nonce-1"
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			issuer.issue(t, tt.name, claims, key)
+
+			_, err := issuer.provider().Exchange(context.Background(), tt.name, nonce)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Exchange error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// identityTest wires an IdentityService to a test issuer and an in-memory database.
+type identityTest struct {
+	t        *testing.T
+	issuer   *testIssuer
+	service  *IdentityService
+	users    *repository.UserRepository
+	identity *repository.IdentityRepository
+	codes    int
+}
+
+func newIdentityTest(t *testing.T) *identityTest {
+	issuer := newTestIssuer(t)
+	registry := NewLoginProviderRegistry()
+	registry.Register(issuer.provider())
+	signer, _, err := NewSigner("identity-test-secret")
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+
+	db := newTestDB(t, &models.User{}, &models.UserIdentity{})
+	users := repository.NewUserRepository(db)
+	identities := repository.NewIdentityRepository(db)
+	return &identityTest{
+		t:        t,
+		issuer:   issuer,
+		service:  NewIdentityService(registry, signer, users, identities, zap.NewNop()),
+		users:    users,
+		identity: identities,
+	}
+}
+
+// login runs a provider flow for the subject: started by linkUserID (0 to sign in),
+// completed by callerID in a browser holding browserNonce ("" for the flow's own).
+func (it *identityTest) login(subject, email string, verified bool, linkUserID, callerID uint, browserNonce string) (*LoginResult, error) {
+	it.t.Helper()
+	ctx := context.Background()
+
+	start, nonce, err := it.service.BeginLogin(ctx, "test", linkUserID)
+	if err != nil {
+		it.t.Fatalf("BeginLogin: %v", err)
+	}
+	it.codes++
+	code := "code-" + strconv.Itoa(it.codes)
+	it.issuer.issue(it.t, code, it.issuer.claims(subject, email, verified, nonce), it.issuer.key)
+
+	if browserNonce == "" {
+		browserNonce = nonce
+	}
+	return it.service.CompleteLogin(ctx, "test", code, start.State, browserNonce, callerID)
+}
+
+func (it *identityTest) createUser(email string) *models.User {
+	it.t.Helper()
+	user := &models.User{Email: email, Name: email, Password: "password"}
+	if err := it.users.Create(context.Background(), user); err != nil {
+		it.t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+func TestIdentityServiceLinkingRules(t *testing.T) {
+	t.Run("new account and known identity", func(t *testing.T) {
+		it := newIdentityTest(t)
+		first, err := it.login("alice", "alice@example.com", true, 0, 0, "")
+		if err != nil || !first.Created {
+			t.Fatalf("first login = %+v, %v; want a created account", first, err)
+		}
+		again, err := it.login("alice", "alice@example.com", true, 0, 0, "")
+		if err != nil || again.Created || again.Linked || again.User.ID != first.User.ID {
+			t.Fatalf("second login = %+v, %v; want the same user signed in", again, err)
+		}
+	})
+
+	t.Run("verified email links to the existing account", func(t *testing.T) {
+		it := newIdentityTest(t)
+		existing := it.createUser("bob@example.com")
+		result, err := it.login("bob", "bob@example.com", true, 0, 0, "")
+		if err != nil || !result.Linked || result.User.ID != existing.ID {
+			t.Fatalf("login = %+v, %v; want linked to user %d", result, err, existing.ID)
+		}
+	})
+
+	t.Run("unverified email is not merged", func(t *testing.T) {
+		it := newIdentityTest(t)
+		it.createUser("carol@example.com")
+		if _, err := it.login("carol", "carol@example.com", false, 0, 0, ""); !errors.Is(err, ErrEmailInUse) {
+			t.Fatalf("login error = %v, want ErrEmailInUse", err)
+		}
+		if _, err := it.identity.GetByProviderSubject(context.Background(), "test", "carol"); err == nil {
+			t.Fatal("identity was linked despite the unverified email")
+		}
+	})
+
+	t.Run("identity linked elsewhere", func(t *testing.T) {
+		it := newIdentityTest(t)
+		owner, err := it.login("dave", "dave@example.com", true, 0, 0, "")
+		if err != nil {
+			t.Fatalf("first login: %v", err)
+		}
+		other := it.createUser("erin@example.com")
+		if _, err := it.login("dave", "dave@example.com", true, other.ID, other.ID, ""); !errors.Is(err, ErrIdentityLinkedElsewhere) {
+			t.Fatalf("link error = %v, want ErrIdentityLinkedElsewhere", err)
+		}
+		identity, err := it.identity.GetByProviderSubject(context.Background(), "test", "dave")
+		if err != nil || identity.UserID != owner.User.ID {
+			t.Fatalf("identity = %+v, %v; want still linked to user %d", identity, err, owner.User.ID)
+		}
+	})
+
+	t.Run("verified login follows the same rules", func(t *testing.T) {
+		it := newIdentityTest(t)
+		existing := it.createUser("grace@example.com")
+		ctx := context.Background()
+		if _, err := it.service.LoginVerified(ctx, &models.ExternalIdentity{
+			Provider: "google", Subject: "grace-unverified", Email: "grace@example.com",
+		}); !errors.Is(err, ErrEmailInUse) {
+			t.Fatalf("unverified login error = %v, want ErrEmailInUse", err)
+		}
+		result, err := it.service.LoginVerified(ctx, &models.ExternalIdentity{
+			Provider: "google", Subject: "grace", Email: "grace@example.com", EmailVerified: true,
+		})
+		if err != nil || !result.Linked || result.User.ID != existing.ID {
+			t.Fatalf("verified login = %+v, %v; want linked to user %d", result, err, existing.ID)
+		}
+	})
+
+	t.Run("link flow attaches to the signed-in user", func(t *testing.T) {
+		it := newIdentityTest(t)
+		user := it.createUser("frank@example.com")
+		result, err := it.login("frank-at-idp", "someone-else@example.com", false, user.ID, user.ID, "")
+		if err != nil || !result.Linked || result.User.ID != user.ID {
+			t.Fatalf("link = %+v, %v; want linked to user %d", result, err, user.ID)
+		}
+	})
+
+	t.Run("link flow completed by another caller", func(t *testing.T) {
+		it := newIdentityTest(t)
+		attacker := it.createUser("mallory@example.com")
+		for _, callerID := range []uint{0, attacker.ID + 1} {
+			if _, err := it.login("victim", "victim@example.com", true, attacker.ID, callerID, ""); !errors.Is(err, ErrLinkNotAuthorized) {
+				t.Fatalf("link completed by %d: error = %v, want ErrLinkNotAuthorized", callerID, err)
+			}
+		}
+	})
+
+	t.Run("state completed in another browser", func(t *testing.T) {
+		it := newIdentityTest(t)
+		if _, err := it.login("grace", "grace@example.com", true, 0, 0, "another-browser"); !errors.Is(err, ErrInvalidLoginState) {
+			t.Fatalf("login error = %v, want ErrInvalidLoginState", err)
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"vibe-backend/internal/metrics"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/telemetry"
+)
+
+// openRouterURL is the OpenRouter chat completions endpoint.
+const openRouterURL = "https://openrouter.ai/api/v1/chat/completions"
+
+// openRouterClient makes non-streaming OpenRouter completions for a service. Each call
+// is budgeted, metered and billed to the LLM feature it is made for.
+type openRouterClient struct {
+	apiKey     string
+	model      string
+	title      string // Sent as X-Title, shown in the OpenRouter dashboard
+	httpClient *http.Client
+	usage      *UsageService
+	log        *zap.Logger
+}
+
+// newOpenRouterClient creates a new openRouterClient.
+func newOpenRouterClient(apiKey, model, title string, log *zap.Logger) *openRouterClient {
+	return &openRouterClient{
+		apiKey: apiKey,
+		model:  model,
+		title:  title,
+		httpClient: &http.Client{
+			Timeout:   120 * time.Second,
+			Transport: telemetry.NewTransport(),
+		},
+		log: log,
+	}
+}
+
+// complete sends a single user prompt and returns the model's reply.
+func (c *openRouterClient) complete(ctx context.Context, feature models.LLMFeature, prompt string) (_ string, err error) {
+	if c.apiKey == "" {
+		return "", fmt.Errorf("OpenRouter API key not configured")
+	}
+	if err := c.usage.Check(ctx, feature); err != nil {
+		return "", err
+	}
+	start := time.Now()
+	defer func() { metrics.ObserveLLMRequest(string(feature), start, err) }()
+
+	jsonBody, err := json.Marshal(map[string]interface{}{
+		"model": c.model,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", openRouterURL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("HTTP-Referer", "https://vibe-engineering-playbook-l8kw.vercel.app")
+	req.Header.Set("X-Title", c.title)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call OpenRouter API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		c.log.Error("❌ OpenRouter API 认证失败 - API密钥无效",
+			zap.Int("status_code", resp.StatusCode),
+			zap.String("response_body", string(body)),
+			zap.String("api_key_prefix", maskOpenRouterKey(c.apiKey)),
+			zap.String("feature", string(feature)),
+			zap.String("error_type", "AUTHENTICATION_FAILED"),
+			zap.String("解决方案", "请检查 OPENROUTER_API_KEY 环境变量，访问 https://openrouter.ai/ 获取有效密钥"),
+		)
+		return "", fmt.Errorf("OpenRouter API 认证失败（401）: %s - 请检查 OPENROUTER_API_KEY 是否有效", string(body))
+	}
+	if resp.StatusCode != http.StatusOK {
+		c.log.Error("OpenRouter API error",
+			zap.Int("status", resp.StatusCode),
+			zap.String("feature", string(feature)),
+			zap.String("body", string(body)),
+		)
+		return "", fmt.Errorf("OpenRouter API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Error *struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		} `json:"error"`
+		Model string           `json:"model"`
+		Usage *openRouterUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	c.usage.Record(ctx, feature, c.model, result.Model, result.Usage)
+	if result.Error != nil {
+		return "", fmt.Errorf("OpenRouter API error: %s (code: %s)", result.Error.Message, result.Error.Code)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("no choices returned from OpenRouter API")
+	}
+	return result.Choices[0].Message.Content, nil
+}
+
+// maskOpenRouterKey returns an API key with all but its ends hidden, for logs.
+func maskOpenRouterKey(key string) string {
+	if key == "" {
+		return "<未设置>"
+	}
+	if len(key) <= 10 {
+		return "***"
+	}
+	return key[:10] + "..." + key[len(key)-4:]
+}
@@ -0,0 +1,622 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"vibe-backend/internal/metrics"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+	"vibe-backend/internal/telemetry"
+)
+
+const (
+	// roomSignalPurpose is the signer purpose of signaling tokens.
+	roomSignalPurpose = "room-signal"
+	// roomSignalTokenTTL is how long after joining a signaling token can be used to connect.
+	roomSignalTokenTTL = 5 * time.Minute
+	// roomSweepInterval is how often participants who went quiet are marked offline.
+	roomSweepInterval = 15 * time.Second
+	// roomInviteTTL is how long room invites last unless created with another expiry.
+	roomInviteTTL = 24 * time.Hour
+)
+
+var (
+	ErrRoomNotFound        = errors.New("room not found")
+	ErrNotInRoom           = errors.New("not a participant of the room")
+	ErrRoomLimitTooHigh    = errors.New("participant limit exceeds the server maximum")
+	ErrInvalidSignalToken  = errors.New("invalid or expired signaling token")
+	ErrRoomForbidden       = errors.New("insufficient room permissions")
+	ErrRoomClosed          = errors.New("room was closed by a moderator")
+	ErrRoomLocked          = errors.New("room is locked")
+	ErrRoomPassword        = errors.New("wrong or missing room password")
+	ErrRemovedFromRoom     = errors.New("removed from the room")
+	ErrInvalidModeration   = errors.New("invalid moderation action")
+	ErrParticipantNotFound = errors.New("participant not found")
+	ErrRoomInviteNotFound  = errors.New("room invite not found")
+)
+
+// RoomConfig configures video rooms.
+type RoomConfig struct {
+	SFUEnabled      bool          // Rooms created from now on forward media through the SFU
+	MaxParticipants int           // Default and upper bound of a room's participant limit
+	PresenceTTL     time.Duration // How long participants stay online without a heartbeat
+}
+
+// RoomService manages who is in which video room. Participants are signed-in users
+// who stay online as long as they send heartbeats or keep their signaling connection
+// open; the sweeper marks those who went quiet offline. Rooms move through their
+// lifecycle (see models.RoomStatus) as participants come and go.
+//
+// The user who creates a room owns it and may lock it, protect it with a password,
+// put newcomers in a lobby and appoint moderators. The owner and moderators invite,
+// admit, mute and kick participants, and may end the room for everyone; the hub
+// carries their actions out on the signaling connections.
+type RoomService struct {
+	repo   *repository.RoomRepository
+	hub    *SignalingHub
+	signer *Signer
+	config RoomConfig
+	log    *zap.Logger
+}
+
+// NewRoomService creates a new RoomService.
+func NewRoomService(repo *repository.RoomRepository, hub *SignalingHub, signer *Signer, config RoomConfig, log *zap.Logger) *RoomService {
+	return &RoomService{
+		repo:   repo,
+		hub:    hub,
+		signer: signer,
+		config: config,
+		log:    log,
+	}
+}
+
+// roomSignalGrant is the payload of a signaling token.
+type roomSignalGrant struct {
+	RoomID string `json:"room_id"`
+	UserID uint   `json:"user_id"`
+}
+
+// Create creates a room owned by the user, under an unguessable ID, so nobody can
+// claim a room ID others are about to use. Its creator joins it like anyone else.
+func (s *RoomService) Create(ctx context.Context, userID uint, req *models.CreateRoomRequest) (*models.CreateRoomResponse, error) {
+	if req.MaxParticipants > s.config.MaxParticipants {
+		return nil, ErrRoomLimitTooHigh
+	}
+	roomID, err := randomToken(9)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate room ID: %w", err)
+	}
+	room := &models.Room{
+		RoomID:          roomID,
+		Status:          models.RoomIdle,
+		MediaMode:       models.RoomMediaMesh,
+		MaxParticipants: req.MaxParticipants,
+		OwnerID:         &userID,
+	}
+	if s.config.SFUEnabled {
+		room.MediaMode = models.RoomMediaSFU
+	}
+	if err := s.repo.CreateRoom(ctx, room); err != nil {
+		return nil, err
+	}
+
+	status, err := s.status(ctx, room, false)
+	if err != nil {
+		return nil, err
+	}
+	s.log.Info("Room created",
+		zap.String("room_id", room.RoomID),
+		zap.Uint("owner_id", userID),
+		zap.String("media_mode", string(room.MediaMode)),
+	)
+	return &models.CreateRoomResponse{RoomStatusResponse: *status, RoomID: room.RoomID}, nil
+}
+
+// Join puts a user in a room created with Create; it returns ErrRoomNotFound for
+// any other room ID. Depending on the room's settings they need its password or an
+// invite, and wait in the lobby until a moderator admits them; waiting participants
+// get no signaling token, and join again once their heartbeat reports them online.
+// It returns repository.ErrRoomFull if the room has no space left.
+func (s *RoomService) Join(ctx context.Context, user *models.User, req *models.JoinRoomRequest) (*models.JoinRoomResponse, error) {
+	room, err := s.room(ctx, req.RoomID)
+	if err != nil {
+		return nil, err
+	}
+
+	// bcrypt is slow, so the password is checked before taking the room lock; it
+	// only counts if the password has not changed since
+	checkedHash := room.PasswordHash
+	passwordOK := checkedHash != "" && req.Password != "" &&
+		bcrypt.CompareHashAndPassword([]byte(checkedHash), []byte(req.Password)) == nil
+	var inviteHash string
+	if req.Invite != "" {
+		inviteHash = hashInvitationToken(req.Invite)
+	}
+
+	room, participant, err := s.repo.Join(ctx, req.RoomID, user.ID, inviteHash, s.config.MaxParticipants,
+		func(room *models.Room, participant *models.Participant, invited bool) (models.ParticipantStatus, error) {
+			return admit(room, participant, user.ID, invited, passwordOK && room.PasswordHash == checkedHash)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	// Whoever waits in the lobby is not in the room yet, so does not see who is
+	status, err := s.status(ctx, room, participant.Status == models.ParticipantOnline)
+	if err != nil {
+		return nil, err
+	}
+	response := &models.JoinRoomResponse{
+		RoomStatusResponse: *status,
+		UserID:             models.SignalPeerID(user.ID),
+		Role:               participant.Role,
+		ParticipantStatus:  participant.Status,
+		HeartbeatInterval:  int((s.config.PresenceTTL / 3).Seconds()),
+	}
+	if participant.Status == models.ParticipantWaiting {
+		s.log.Info("Participant waiting in room lobby", zap.String("room_id", room.RoomID), zap.Uint("user_id", user.ID))
+		s.notifyLobby(ctx, room.RoomID)
+		return response, nil
+	}
+
+	response.SignalToken, err = s.signer.Sign(roomSignalPurpose, roomSignalGrant{RoomID: room.RoomID, UserID: user.ID}, roomSignalTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	s.log.Info("Participant joined room",
+		zap.String("room_id", room.RoomID),
+		zap.Uint("user_id", user.ID),
+		zap.String("role", string(participant.Role)),
+		zap.String("status", string(room.Status)),
+	)
+	return response, nil
+}
+
+// admit decides how a user joining a room is let in. The owner and moderators always
+// get in, the owner even once the room is closed. Others are refused by a closed or
+// locked room, or if they were removed from it, invite or not, until a moderator
+// readmits them. An invite lets them in directly, otherwise they need the room's
+// password if it has one and wait in the lobby if it is enabled.
+func admit(room *models.Room, participant *models.Participant, userID uint, invited, passwordOK bool) (models.ParticipantStatus, error) {
+	if room.IsOwner(userID) {
+		return models.ParticipantOnline, nil
+	}
+	switch {
+	case room.Status == models.RoomClosed:
+		return "", ErrRoomClosed
+	case participant != nil && participant.Role == models.RoomRoleModerator:
+		return models.ParticipantOnline, nil
+	case room.Locked:
+		return "", ErrRoomLocked
+	case participant != nil && participant.Status == models.ParticipantRemoved:
+		return "", ErrRemovedFromRoom
+	case invited:
+		return models.ParticipantOnline, nil
+	case room.PasswordHash != "" && !passwordOK:
+		return "", ErrRoomPassword
+	case room.LobbyEnabled:
+		return models.ParticipantWaiting, nil
+	}
+	return models.ParticipantOnline, nil
+}
+
+// Leave takes a user out of a room or its lobby.
+func (s *RoomService) Leave(ctx context.Context, userID uint, roomID string) error {
+	left, err := s.repo.Leave(ctx, roomID, userID)
+	if err != nil {
+		return err
+	}
+	if left == models.ParticipantWaiting {
+		s.notifyLobby(ctx, roomID)
+	}
+	s.log.Info("Participant left room", zap.String("room_id", roomID), zap.Uint("user_id", userID))
+	return nil
+}
+
+// Heartbeat keeps a participant online, or waiting in the lobby, and returns their
+// status and when they go offline without another heartbeat. It returns ErrNotInRoom
+// if they are neither, e.g. after timing out or being removed; they have to join again.
+func (s *RoomService) Heartbeat(ctx context.Context, userID uint, roomID string) (models.ParticipantStatus, time.Time, error) {
+	status, err := s.repo.Touch(ctx, roomID, userID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if status == "" {
+		return "", time.Time{}, ErrNotInRoom
+	}
+	return status, time.Now().Add(s.config.PresenceTTL), nil
+}
+
+// Status returns a room's status. Its online participants are only listed to the
+// owner and to participants online in the room; others get the public fields.
+func (s *RoomService) Status(ctx context.Context, userID uint, roomID string) (*models.RoomStatusResponse, error) {
+	room, _, err := s.authorize(ctx, userID, roomID, models.RoomRoleMember)
+	if errors.Is(err, ErrRoomForbidden) {
+		if room, err = s.room(ctx, roomID); err != nil {
+			return nil, err
+		}
+		return s.status(ctx, room, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.status(ctx, room, true)
+}
+
+// AuthorizeSignal checks a signaling token for a room and returns the room and the
+// participant the token was issued to, who must still be online in the room. The room
+// ID travels next to the token so proxies can route a room's signaling to one
+// instance; a token granted for another room is rejected.
+func (s *RoomService) AuthorizeSignal(ctx context.Context, roomID, token string) (*models.Room, *models.Participant, error) {
+	var grant roomSignalGrant
+	if err := s.signer.Verify(roomSignalPurpose, token, &grant); err != nil || grant.RoomID != roomID {
+		return nil, nil, ErrInvalidSignalToken
+	}
+	participant, err := s.repo.GetParticipant(ctx, grant.RoomID, grant.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && participant.Status != models.ParticipantOnline) {
+		return nil, nil, ErrNotInRoom
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	room, err := s.repo.GetRoom(ctx, grant.RoomID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return room, participant, nil
+}
+
+// UpdateSettings changes a room's settings. Owners only.
+func (s *RoomService) UpdateSettings(ctx context.Context, userID uint, req *models.RoomSettingsRequest) (*models.RoomStatusResponse, error) {
+	room, _, err := s.authorize(ctx, userID, req.RoomID, models.RoomRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Locked != nil {
+		updates["locked"] = *req.Locked
+	}
+	if req.LobbyEnabled != nil {
+		updates["lobby_enabled"] = *req.LobbyEnabled
+	}
+	if req.Password != nil {
+		hash := ""
+		if *req.Password != "" {
+			raw, err := bcrypt.GenerateFromPassword([]byte(*req.Password), bcrypt.DefaultCost)
+			if err != nil {
+				return nil, fmt.Errorf("failed to hash room password: %w", err)
+			}
+			hash = string(raw)
+		}
+		updates["password_hash"] = hash
+	}
+	if req.MaxParticipants != nil {
+		if *req.MaxParticipants > s.config.MaxParticipants {
+			return nil, ErrRoomLimitTooHigh
+		}
+		updates["max_participants"] = *req.MaxParticipants
+	}
+	if len(updates) > 0 {
+		if err := s.repo.UpdateSettings(ctx, room.RoomID, updates); err != nil {
+			return nil, err
+		}
+		s.log.Info("Room settings updated", zap.String("room_id", room.RoomID), zap.Uint("user_id", userID))
+	}
+	return s.status(ctx, room, true)
+}
+
+// CreateInvite creates an invite that lets its holders into a room past its password
+// and lobby. Moderators only. The token is only returned now.
+func (s *RoomService) CreateInvite(ctx context.Context, userID uint, req *models.CreateRoomInviteRequest) (*models.RoomInviteResponse, error) {
+	room, _, err := s.authorize(ctx, userID, req.RoomID, models.RoomRoleModerator)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := roomInviteTTL
+	if req.ExpiresIn > 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invite token: %w", err)
+	}
+	invite := &models.RoomInvite{
+		RoomID:    room.RoomID,
+		TokenHash: hashInvitationToken(token),
+		CreatedBy: userID,
+		MaxUses:   req.MaxUses,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.repo.CreateInvite(ctx, invite); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Room invite created", zap.String("room_id", room.RoomID), zap.Uint("created_by", userID))
+	return &models.RoomInviteResponse{RoomInvite: *invite, Token: token}, nil
+}
+
+// ListInvites returns a room's invites that can still be used. Moderators only.
+func (s *RoomService) ListInvites(ctx context.Context, userID uint, roomID string) ([]models.RoomInvite, error) {
+	if _, _, err := s.authorize(ctx, userID, roomID, models.RoomRoleModerator); err != nil {
+		return nil, err
+	}
+	return s.repo.ListActiveInvites(ctx, roomID)
+}
+
+// RevokeInvite revokes a room invite. Moderators of its room only.
+func (s *RoomService) RevokeInvite(ctx context.Context, userID, inviteID uint) error {
+	invite, err := s.repo.GetInvite(ctx, inviteID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRoomInviteNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if _, _, err := s.authorize(ctx, userID, invite.RoomID, models.RoomRoleModerator); err != nil {
+		return err
+	}
+	if err := s.repo.RevokeInvite(ctx, invite.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRoomInviteNotFound
+		}
+		return err
+	}
+	s.log.Info("Room invite revoked", zap.String("room_id", invite.RoomID), zap.Uint("invite_id", invite.ID))
+	return nil
+}
+
+// Lobby returns who waits in a room's lobby. Moderators only.
+func (s *RoomService) Lobby(ctx context.Context, userID uint, roomID string) ([]models.ParticipantResponse, error) {
+	if _, _, err := s.authorize(ctx, userID, roomID, models.RoomRoleModerator); err != nil {
+		return nil, err
+	}
+	return s.participants(ctx, roomID, models.ParticipantWaiting)
+}
+
+// Moderate carries out a moderator's action on a participant, named by their ID in
+// signaling messages, or on the whole room. Moderators may act on members; only the
+// owner may act on moderators, promote and demote. Nobody acts on the owner. In mesh
+// rooms media does not pass through the server, so mutes are only announced and
+// clients are expected to honor them.
+func (s *RoomService) Moderate(ctx context.Context, userID uint, roomID string, action models.RoomModerationAction, target string) error {
+	room, role, err := s.authorize(ctx, userID, roomID, models.RoomRoleModerator)
+	if err != nil {
+		return err
+	}
+	event := &models.RoomModerationEvent{Action: action, By: models.SignalPeerID(userID)}
+	if action == models.RoomModerationEnd {
+		return s.end(ctx, room, event)
+	}
+
+	targetID, err := strconv.ParseUint(target, 10, 32)
+	if err != nil || uint(targetID) == userID {
+		return ErrInvalidModeration
+	}
+	if room.IsOwner(uint(targetID)) {
+		return ErrRoomForbidden
+	}
+	participant, err := s.repo.GetParticipant(ctx, roomID, uint(targetID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrParticipantNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if participant.Role == models.RoomRoleModerator && role != models.RoomRoleOwner {
+		return ErrRoomForbidden
+	}
+	event.Target = models.SignalPeerID(participant.UserID)
+
+	switch action {
+	case models.RoomModerationKick:
+		err = s.repo.SetParticipantStatus(ctx, roomID, participant.UserID, models.ParticipantOnline, models.ParticipantRemoved, s.config.MaxParticipants)
+	case models.RoomModerationMute, models.RoomModerationUnmute:
+		err = s.repo.SetParticipantMuted(ctx, roomID, participant.UserID, action == models.RoomModerationMute)
+	case models.RoomModerationAdmit:
+		if participant.Status == models.ParticipantRemoved {
+			// Lifts the removal; they join again like anyone else
+			err = s.repo.SetParticipantStatus(ctx, roomID, participant.UserID, models.ParticipantRemoved, models.ParticipantOffline, s.config.MaxParticipants)
+			break
+		}
+		err = s.repo.SetParticipantStatus(ctx, roomID, participant.UserID, models.ParticipantWaiting, models.ParticipantOnline, s.config.MaxParticipants)
+	case models.RoomModerationDeny:
+		err = s.repo.SetParticipantStatus(ctx, roomID, participant.UserID, models.ParticipantWaiting, models.ParticipantRemoved, s.config.MaxParticipants)
+	case models.RoomModerationPromote, models.RoomModerationDemote:
+		if role != models.RoomRoleOwner {
+			return ErrRoomForbidden
+		}
+		newRole := models.RoomRoleModerator
+		if action == models.RoomModerationDemote {
+			newRole = models.RoomRoleMember
+		}
+		err = s.repo.SetParticipantRole(ctx, roomID, participant.UserID, newRole)
+	default:
+		return ErrInvalidModeration
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrParticipantNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	// Lobby decisions only concern moderators; the admitted learn it from their heartbeat
+	if action == models.RoomModerationAdmit || action == models.RoomModerationDeny {
+		s.notifyLobby(ctx, roomID)
+	} else {
+		s.hub.Moderate(ctx, roomID, event)
+	}
+	s.log.Info("Room moderation",
+		zap.String("room_id", roomID),
+		zap.String("action", string(action)),
+		zap.Uint("by", userID),
+		zap.Uint("target", participant.UserID),
+	)
+	return nil
+}
+
+// end closes a room and disconnects everyone in it.
+func (s *RoomService) end(ctx context.Context, room *models.Room, event *models.RoomModerationEvent) error {
+	if _, err := s.repo.CloseRoom(ctx, room.RoomID); err != nil {
+		return err
+	}
+	s.hub.Moderate(ctx, room.RoomID, event)
+	s.log.Info("Room ended", zap.String("room_id", room.RoomID), zap.String("by", event.By))
+	return nil
+}
+
+// authorize returns a room and the user's role in it after checking the role allows
+// need. The owner's role holds whether or not they are in the room; others must be
+// online.
+func (s *RoomService) authorize(ctx context.Context, userID uint, roomID string, need models.RoomRole) (*models.Room, models.RoomRole, error) {
+	room, err := s.room(ctx, roomID)
+	if err != nil {
+		return nil, "", err
+	}
+	role := models.RoomRoleOwner
+	if !room.IsOwner(userID) {
+		participant, err := s.repo.GetParticipant(ctx, roomID, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && participant.Status != models.ParticipantOnline) {
+			return nil, "", ErrRoomForbidden
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		role = participant.Role
+	}
+	if !role.Allows(need) {
+		return nil, "", ErrRoomForbidden
+	}
+	return room, role, nil
+}
+
+// notifyLobby sends who waits in a room's lobby to its moderators connected to
+// signaling. Failures are logged, not returned.
+func (s *RoomService) notifyLobby(ctx context.Context, roomID string) {
+	waiting, err := s.participants(ctx, roomID, models.ParticipantWaiting)
+	if err != nil {
+		s.log.Warn("Failed to load room lobby", zap.String("room_id", roomID), zap.Error(err))
+		return
+	}
+	online, err := s.repo.GetParticipants(ctx, roomID, models.ParticipantOnline)
+	if err != nil {
+		s.log.Warn("Failed to load room moderators", zap.String("room_id", roomID), zap.Error(err))
+		return
+	}
+	payload, err := json.Marshal(waiting)
+	if err != nil {
+		return
+	}
+	msg := &models.SignalMessage{Type: models.SignalLobby, Payload: payload}
+	for _, participant := range online {
+		if participant.Role.Allows(models.RoomRoleModerator) {
+			s.hub.Notify(ctx, roomID, models.SignalPeerID(participant.UserID), msg)
+		}
+	}
+}
+
+// room returns a room, or ErrRoomNotFound.
+func (s *RoomService) room(ctx context.Context, roomID string) (*models.Room, error) {
+	room, err := s.repo.GetRoom(ctx, roomID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoomNotFound
+	}
+	return room, err
+}
+
+// participants returns the participants of a room with a status, as responses.
+func (s *RoomService) participants(ctx context.Context, roomID string, status models.ParticipantStatus) ([]models.ParticipantResponse, error) {
+	participants, err := s.repo.GetParticipants(ctx, roomID, status)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]models.ParticipantResponse, len(participants))
+	for i := range participants {
+		responses[i] = participants[i].ToResponse()
+	}
+	return responses, nil
+}
+
+// status builds the status response of a room, with its online participants if
+// roster is set.
+func (s *RoomService) status(ctx context.Context, room *models.Room, roster bool) (*models.RoomStatusResponse, error) {
+	var responses []models.ParticipantResponse
+	if roster {
+		var err error
+		if responses, err = s.participants(ctx, room.RoomID, models.ParticipantOnline); err != nil {
+			return nil, err
+		}
+	}
+	return &models.RoomStatusResponse{
+		Status:            room.Status,
+		MediaMode:         room.MediaMode,
+		MaxParticipants:   room.Capacity(s.config.MaxParticipants),
+		Locked:            room.Locked,
+		LobbyEnabled:      room.LobbyEnabled,
+		PasswordProtected: room.PasswordHash != "",
+		Participants:      responses,
+	}, nil
+}
+
+// Run marks participants who stopped sending heartbeats offline until ctx is cancelled.
+func (s *RoomService) Run(ctx context.Context) {
+	ticker := time.NewTicker(roomSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sweep(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweep marks participants not seen for the presence TTL offline, room by room.
+func (s *RoomService) sweep(ctx context.Context) {
+	ctx, span := telemetry.StartJob(ctx, "room.sweep")
+	start := time.Now()
+	var err error
+	defer func() {
+		metrics.ObserveJob(metrics.JobRoomSweep, start, metrics.Outcome(err))
+		telemetry.End(span, err)
+	}()
+
+	before := time.Now().Add(-s.config.PresenceTTL)
+	var roomIDs []string
+	roomIDs, err = s.repo.StaleRoomIDs(ctx, before)
+	if err != nil {
+		s.log.Error("Failed to list rooms with stale participants", zap.Error(err))
+		return
+	}
+	for _, roomID := range roomIDs {
+		expired, expireErr := s.repo.ExpireParticipants(ctx, roomID, before)
+		if expireErr != nil {
+			err = expireErr
+			s.log.Error("Failed to expire stale participants", zap.String("room_id", roomID), zap.Error(err))
+			continue
+		}
+		// Some of them may have been waiting in the lobby
+		if len(expired) > 0 {
+			s.notifyLobby(ctx, roomID)
+		}
+		for _, participant := range expired {
+			s.log.Info("Participant timed out of room",
+				zap.String("room_id", roomID),
+				zap.Uint("user_id", participant.UserID),
+				zap.Time("last_seen_at", participant.LastSeenAt),
+			)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+)
+
+func TestAdmit(t *testing.T) {
+	const ownerID, userID = 1, 2
+	owner := uint(ownerID)
+	member := &models.Participant{UserID: userID, Status: models.ParticipantOffline, Role: models.RoomRoleMember}
+	moderator := &models.Participant{UserID: userID, Status: models.ParticipantOffline, Role: models.RoomRoleModerator}
+	removed := &models.Participant{UserID: userID, Status: models.ParticipantRemoved, Role: models.RoomRoleMember}
+
+	tests := []struct {
+		name        string
+		room        models.Room
+		participant *models.Participant
+		userID      uint
+		invited     bool
+		passwordOK  bool
+		want        models.ParticipantStatus
+		wantErr     error
+	}{
+		{name: "open room", participant: nil, userID: userID, want: models.ParticipantOnline},
+		{name: "returning member", participant: member, userID: userID, want: models.ParticipantOnline},
+		{name: "owner of closed room", room: models.Room{Status: models.RoomClosed}, userID: ownerID, want: models.ParticipantOnline},
+		{name: "closed room", room: models.Room{Status: models.RoomClosed}, userID: userID, invited: true, wantErr: ErrRoomClosed},
+		{name: "moderator of locked room", room: models.Room{Locked: true}, participant: moderator, userID: userID, want: models.ParticipantOnline},
+		{name: "locked room", room: models.Room{Locked: true}, userID: userID, invited: true, wantErr: ErrRoomLocked},
+		{name: "removed", participant: removed, userID: userID, wantErr: ErrRemovedFromRoom},
+		{name: "removed with invite", participant: removed, userID: userID, invited: true, wantErr: ErrRemovedFromRoom},
+		{name: "wrong password", room: models.Room{PasswordHash: "hash"}, userID: userID, wantErr: ErrRoomPassword},
+		{name: "password", room: models.Room{PasswordHash: "hash", LobbyEnabled: true}, userID: userID, passwordOK: true, want: models.ParticipantWaiting},
+		{name: "invite skips password and lobby", room: models.Room{PasswordHash: "hash", LobbyEnabled: true}, userID: userID, invited: true, want: models.ParticipantOnline},
+		{name: "lobby", room: models.Room{LobbyEnabled: true}, userID: userID, want: models.ParticipantWaiting},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room := tt.room
+			room.OwnerID = &owner
+			got, err := admit(&room, tt.participant, tt.userID, tt.invited, tt.passwordOK)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("status = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// roomTest is a RoomService on a fresh database, with a room owner and a guest.
+type roomTest struct {
+	db    *gorm.DB
+	rooms *RoomService
+	owner *models.User
+	guest *models.User
+}
+
+func newRoomTest(t *testing.T) *roomTest {
+	t.Helper()
+
+	db := newTestDB(t, &models.User{}, &models.Room{}, &models.Participant{}, &models.RoomInvite{})
+	signer, _, err := NewSigner("test-secret")
+	if err != nil {
+		t.Fatalf("new signer: %v", err)
+	}
+	rt := &roomTest{
+		db: db,
+		rooms: NewRoomService(repository.NewRoomRepository(db), NewSignalingHub(nil, zap.NewNop()), signer,
+			RoomConfig{MaxParticipants: 8, PresenceTTL: time.Minute}, zap.NewNop()),
+		owner: &models.User{Email: "owner@example.com", APIKey: "owner-key"},
+		guest: &models.User{Email: "guest@example.com", APIKey: "guest-key"},
+	}
+	for _, u := range []*models.User{rt.owner, rt.guest} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	return rt
+}
+
+func TestRoomCreateThenJoin(t *testing.T) {
+	ctx := context.Background()
+	rt := newRoomTest(t)
+
+	// Joining does not create rooms, so nobody can claim an ID others will use
+	_, err := rt.rooms.Join(ctx, rt.guest, &models.JoinRoomRequest{RoomID: "team-standup"})
+	if !errors.Is(err, ErrRoomNotFound) {
+		t.Fatalf("join unknown room: err = %v, want %v", err, ErrRoomNotFound)
+	}
+	var count int64
+	if err := rt.db.Model(&models.Room{}).Count(&count).Error; err != nil || count != 0 {
+		t.Fatalf("rooms after joining unknown room = %d, %v; want 0", count, err)
+	}
+
+	if _, err := rt.rooms.Create(ctx, rt.owner.ID, &models.CreateRoomRequest{MaxParticipants: 9}); !errors.Is(err, ErrRoomLimitTooHigh) {
+		t.Fatalf("create over limit: err = %v, want %v", err, ErrRoomLimitTooHigh)
+	}
+	created, err := rt.rooms.Create(ctx, rt.owner.ID, &models.CreateRoomRequest{MaxParticipants: 4})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.RoomID == "" || created.MaxParticipants != 4 || created.Status != models.RoomIdle {
+		t.Fatalf("created = %+v; want a new idle room for 4", created)
+	}
+
+	// The guest joining first does not take the room from its creator
+	guest, err := rt.rooms.Join(ctx, rt.guest, &models.JoinRoomRequest{RoomID: created.RoomID})
+	if err != nil || guest.Role != models.RoomRoleMember {
+		t.Fatalf("guest join = %+v, %v; want member", guest, err)
+	}
+	owner, err := rt.rooms.Join(ctx, rt.owner, &models.JoinRoomRequest{RoomID: created.RoomID})
+	if err != nil || owner.Role != models.RoomRoleOwner {
+		t.Fatalf("owner join = %+v, %v; want owner", owner, err)
+	}
+}
+
+func TestRoomKickThenRejoinWithInvite(t *testing.T) {
+	ctx := context.Background()
+	rt := newRoomTest(t)
+	rooms, owner, guest := rt.rooms, rt.owner, rt.guest
+
+	created, err := rooms.Create(ctx, owner.ID, &models.CreateRoomRequest{})
+	if err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+	roomID := created.RoomID
+	join := func(user *models.User, invite string) (*models.JoinRoomResponse, error) {
+		return rooms.Join(ctx, user, &models.JoinRoomRequest{RoomID: roomID, Invite: invite})
+	}
+	if _, err := join(owner, ""); err != nil {
+		t.Fatalf("owner join: %v", err)
+	}
+	invite, err := rooms.CreateInvite(ctx, owner.ID, &models.CreateRoomInviteRequest{RoomID: roomID})
+	if err != nil {
+		t.Fatalf("create invite: %v", err)
+	}
+	if resp, err := join(guest, invite.Token); err != nil || resp.ParticipantStatus != models.ParticipantOnline {
+		t.Fatalf("guest join = %+v, %v; want online", resp, err)
+	}
+
+	guestID := models.SignalPeerID(guest.ID)
+	if err := rooms.Moderate(ctx, owner.ID, roomID, models.RoomModerationKick, guestID); err != nil {
+		t.Fatalf("kick: %v", err)
+	}
+	// The multi-use invite that let them in does not bring them back
+	if _, err := join(guest, invite.Token); !errors.Is(err, ErrRemovedFromRoom) {
+		t.Fatalf("rejoin with invite after kick: err = %v, want %v", err, ErrRemovedFromRoom)
+	}
+	if _, err := join(guest, ""); !errors.Is(err, ErrRemovedFromRoom) {
+		t.Fatalf("rejoin after kick: err = %v, want %v", err, ErrRemovedFromRoom)
+	}
+
+	// Until a moderator readmits them
+	if err := rooms.Moderate(ctx, owner.ID, roomID, models.RoomModerationAdmit, guestID); err != nil {
+		t.Fatalf("readmit: %v", err)
+	}
+	if resp, err := join(guest, ""); err != nil || resp.ParticipantStatus != models.ParticipantOnline {
+		t.Fatalf("rejoin after readmit = %+v, %v; want online", resp, err)
+	}
+}
@@ -0,0 +1,560 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+)
+
+const (
+	// searchChunkRunes is the target size of indexed text chunks, so snippets stay local.
+	searchChunkRunes = 800
+	// searchSnippetRunes is the context kept on each side of the first match in a snippet.
+	searchSnippetRunes = 60
+	// searchMatchesPerInsight is how many passages are returned per matching insight.
+	searchMatchesPerInsight = 3
+	// searchMaxQueryTerms caps the number of terms in a query.
+	searchMaxQueryTerms = 32
+)
+
+// ErrEmptySearchQuery is returned when a query has no searchable terms.
+var ErrEmptySearchQuery = errors.New("search query has no searchable terms")
+
+// contentSearchKinds are the entry kinds built from the insight record itself.
+var contentSearchKinds = []models.SearchKind{
+	models.SearchKindTitle,
+	models.SearchKindSummary,
+	models.SearchKindContent,
+	models.SearchKindTranslation,
+	models.SearchKindTranscript,
+}
+
+// SearchService maintains and queries the full-text index of insights, highlights and chat.
+//
+// Postgres has no built-in Chinese or Japanese parser, so text is tokenized here before
+// indexing: runs of CJK characters become overlapping bigrams ("机器学习" -> "机器 器学 学习"),
+// other words are lowercased, and the result is indexed with the 'simple' configuration.
+// Queries are tokenized the same way and all terms must match.
+type SearchService struct {
+	repo        *repository.SearchRepository
+	insightRepo *repository.InsightRepository
+	workspaces  *WorkspaceService
+	log         *zap.Logger
+}
+
+// NewSearchService creates a new SearchService.
+func NewSearchService(
+	repo *repository.SearchRepository,
+	insightRepo *repository.InsightRepository,
+	workspaces *WorkspaceService,
+	log *zap.Logger,
+) *SearchService {
+	return &SearchService{
+		repo:        repo,
+		insightRepo: insightRepo,
+		workspaces:  workspaces,
+		log:         log,
+	}
+}
+
+// Search runs a ranked full-text search over a workspace's library.
+func (s *SearchService) Search(ctx context.Context, user *models.User, workspaceID *uint, query string, limit, offset int) (*models.SearchResponse, error) {
+	workspace, _, err := s.workspaces.Resolve(ctx, user, workspaceID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	tsquery, needles := buildSearchQuery(query)
+	if tsquery == "" {
+		return nil, ErrEmptySearchQuery
+	}
+
+	hits, total, err := s.repo.Search(ctx, workspace.ID, tsquery, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.InsightID)
+	}
+	items, err := s.insightRepo.GetListItemsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.TopMatches(ctx, ids, tsquery, searchMatchesPerInsight)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make(map[uint][]models.SearchMatch, len(ids))
+	for _, row := range rows {
+		matches[row.InsightID] = append(matches[row.InsightID], models.SearchMatch{
+			Kind:      row.Kind,
+			RefID:     row.RefID,
+			Snippet:   buildSnippet(row.Text, needles),
+			Seconds:   row.Seconds,
+			Timestamp: row.Timestamp,
+			Link:      searchMatchLink(row.InsightID, row.Kind, row.RefID, row.Seconds),
+			Rank:      row.Rank,
+		})
+	}
+
+	results := make([]models.SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		item, ok := items[hit.InsightID]
+		if !ok {
+			continue
+		}
+		insightMatches := matches[hit.InsightID]
+		if insightMatches == nil {
+			insightMatches = []models.SearchMatch{}
+		}
+		results = append(results, models.SearchResult{
+			Insight: item,
+			Rank:    hit.Rank,
+			Matches: insightMatches,
+		})
+	}
+
+	return &models.SearchResponse{
+		Query:   query,
+		Results: results,
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+	}, nil
+}
+
+// --- Indexing ---
+
+// IndexInsight re-indexes the title, summary and content of an insight.
+func (s *SearchService) IndexInsight(ctx context.Context, insightID uint) error {
+	insight, err := s.insightRepo.GetByID(ctx, insightID)
+	if err != nil {
+		return err
+	}
+
+	var docs []repository.SearchDocument
+	add := func(kind models.SearchKind, weight, text string, seconds *int, timestamp string) {
+		if doc, ok := newSearchDocument(insight.ID, kind, nil, weight, text, seconds, timestamp); ok {
+			docs = append(docs, doc)
+		}
+	}
+
+	add(models.SearchKindTitle, "A", insight.Title, nil, "")
+
+	summary := insight.Summary
+	var keyPoints []string
+	if len(insight.KeyPoints) > 0 && json.Unmarshal(insight.KeyPoints, &keyPoints) == nil && len(keyPoints) > 0 {
+		summary = strings.TrimSpace(summary + "\n" + strings.Join(keyPoints, "\n"))
+	}
+	for _, chunk := range chunkText(summary, searchChunkRunes) {
+		add(models.SearchKindSummary, "B", chunk, nil, "")
+	}
+
+	// Transcript segments carry timestamps; only fall back to the raw text without them
+	if transcripts := parseTranscripts(insight); len(transcripts) > 0 {
+		for _, item := range transcripts {
+			seconds := item.Seconds
+			text := strings.TrimSpace(item.Text + "\n" + item.TranslatedText)
+			add(models.SearchKindTranscript, "C", text, &seconds, item.Timestamp)
+		}
+	} else {
+		for _, chunk := range chunkText(insight.RawContent, searchChunkRunes) {
+			add(models.SearchKindContent, "C", chunk, nil, "")
+		}
+		for _, chunk := range chunkText(insight.TransContent, searchChunkRunes) {
+			add(models.SearchKindTranslation, "C", chunk, nil, "")
+		}
+	}
+
+	return s.repo.Replace(ctx, insight.ID, contentSearchKinds, nil, docs)
+}
+
+// IndexHighlight indexes a highlight's text and note, linked to the transcript
+// segment it was taken from when that can be found.
+func (s *SearchService) IndexHighlight(ctx context.Context, highlight *models.Highlight) error {
+	var seconds *int
+	var timestamp string
+	if insight, err := s.insightRepo.GetByID(ctx, highlight.InsightID); err == nil {
+		if item := findTranscriptItem(parseTranscripts(insight), highlight.Text); item != nil {
+			value := item.Seconds
+			seconds, timestamp = &value, item.Timestamp
+		}
+	}
+
+	var docs []repository.SearchDocument
+	text := strings.TrimSpace(highlight.Text + "\n" + highlight.Note)
+	if doc, ok := newSearchDocument(highlight.InsightID, models.SearchKindHighlight, &highlight.ID, "B", text, seconds, timestamp); ok {
+		docs = append(docs, doc)
+	}
+	return s.repo.Replace(ctx, highlight.InsightID, []models.SearchKind{models.SearchKindHighlight}, &highlight.ID, docs)
+}
+
+// RemoveHighlight removes a highlight from the index.
+func (s *SearchService) RemoveHighlight(ctx context.Context, highlightID uint) error {
+	return s.repo.DeleteByRef(ctx, models.SearchKindHighlight, highlightID)
+}
+
+// IndexChatMessage indexes a chat message.
+func (s *SearchService) IndexChatMessage(ctx context.Context, message *models.ChatMessage) error {
+	var docs []repository.SearchDocument
+	for _, chunk := range chunkText(message.Content, searchChunkRunes) {
+		if doc, ok := newSearchDocument(message.InsightID, models.SearchKindChat, &message.ID, "D", chunk, nil, ""); ok {
+			docs = append(docs, doc)
+		}
+	}
+	return s.repo.Replace(ctx, message.InsightID, []models.SearchKind{models.SearchKindChat}, &message.ID, docs)
+}
+
+// RemoveChatMessages removes all chat messages of an insight from the index.
+func (s *SearchService) RemoveChatMessages(ctx context.Context, insightID uint) error {
+	return s.repo.DeleteByInsight(ctx, insightID, models.SearchKindChat)
+}
+
+// RemoveInsight removes an insight from the index.
+func (s *SearchService) RemoveInsight(ctx context.Context, insightID uint) error {
+	return s.repo.DeleteByInsight(ctx, insightID)
+}
+
+// RebuildInsight re-indexes an insight with all its highlights and chat messages.
+func (s *SearchService) RebuildInsight(ctx context.Context, insightID uint) error {
+	if err := s.IndexInsight(ctx, insightID); err != nil {
+		return err
+	}
+
+	highlights, err := s.insightRepo.GetHighlightsByInsightID(ctx, insightID)
+	if err != nil {
+		return err
+	}
+	for i := range highlights {
+		if err := s.IndexHighlight(ctx, &highlights[i]); err != nil {
+			return err
+		}
+	}
+
+	messages, err := s.insightRepo.GetChatMessagesByInsightID(ctx, insightID)
+	if err != nil {
+		return err
+	}
+	for i := range messages {
+		if err := s.IndexChatMessage(ctx, &messages[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Backfill indexes insights that have no search entries yet, e.g. those created
+// before search existed. It is meant to run once in the background at startup.
+func (s *SearchService) Backfill(ctx context.Context) {
+	var afterID uint
+	indexed := 0
+	for {
+		ids, err := s.repo.InsightIDsWithoutEntries(ctx, afterID, 50)
+		if err != nil {
+			s.log.Error("Search backfill failed", zap.Error(err))
+			return
+		}
+		if len(ids) == 0 {
+			break
+		}
+		for _, id := range ids {
+			if err := s.RebuildInsight(ctx, id); err != nil {
+				s.log.Warn("Failed to index insight", zap.Uint("insight_id", id), zap.Error(err))
+			} else {
+				indexed++
+			}
+			afterID = id
+		}
+	}
+	if indexed > 0 {
+		s.log.Info("Search backfill completed", zap.Int("insights", indexed))
+	}
+}
+
+// Reindex runs fn and logs failures instead of returning them; index updates should
+// never fail the request that triggered them.
+func (s *SearchService) Reindex(what string, insightID uint, fn func() error) {
+	if err := fn(); err != nil {
+		s.log.Warn("Failed to update search index",
+			zap.String("what", what),
+			zap.Uint("insight_id", insightID),
+			zap.Error(err),
+		)
+	}
+}
+
+// --- Tokenization ---
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || r == 'ー'
+}
+
+// searchTerms splits text into index terms: lowercased words, and overlapping bigrams
+// for CJK runs. For documents (forQuery false) the last character of every CJK run is
+// also emitted as a unigram so single-character queries can prefix-match any character.
+func searchTerms(text string, forQuery bool) []string {
+	var terms []string
+	var word, cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			terms = append(terms, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		switch {
+		case len(cjk) == 1:
+			terms = append(terms, string(cjk))
+		case len(cjk) > 1:
+			for i := 0; i+1 < len(cjk); i++ {
+				terms = append(terms, string(cjk[i:i+2]))
+			}
+			if !forQuery {
+				terms = append(terms, string(cjk[len(cjk)-1]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		r = unicode.ToLower(r)
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return terms
+}
+
+// buildSearchQuery turns user input into a to_tsquery expression where all terms must
+// match, plus the lowercased query words used to mark snippets. Single CJK characters
+// and the last word (for search-as-you-type) are prefix matches.
+func buildSearchQuery(query string) (string, [][]rune) {
+	var needles [][]rune
+	var parts []string
+	words := strings.Fields(query)
+	for i, word := range words {
+		terms := searchTerms(word, true)
+		if len(terms) == 0 {
+			continue
+		}
+		needles = append(needles, []rune(strings.ToLower(word)))
+		for j, term := range terms {
+			runes := []rune(term)
+			last := i == len(words)-1 && j == len(terms)-1
+			if (len(runes) == 1 && isCJK(runes[0])) || (last && !isCJK(runes[0])) {
+				term += ":*"
+			}
+			parts = append(parts, term)
+			if len(parts) == searchMaxQueryTerms {
+				return strings.Join(parts, " & "), needles
+			}
+		}
+	}
+	return strings.Join(parts, " & "), needles
+}
+
+// newSearchDocument builds a search document; ok is false if text has nothing to index.
+func newSearchDocument(insightID uint, kind models.SearchKind, refID *uint, weight, text string, seconds *int, timestamp string) (repository.SearchDocument, bool) {
+	text = strings.TrimSpace(text)
+	terms := searchTerms(text, false)
+	if len(terms) == 0 {
+		return repository.SearchDocument{}, false
+	}
+	return repository.SearchDocument{
+		Entry: models.SearchEntry{
+			InsightID: insightID,
+			Kind:      kind,
+			RefID:     refID,
+			Seconds:   seconds,
+			Timestamp: timestamp,
+			Text:      text,
+		},
+		Terms:  strings.Join(terms, " "),
+		Weight: weight,
+	}, true
+}
+
+// chunkText splits text into paragraphs merged up to about size runes each.
+// Paragraphs longer than size are cut.
+func chunkText(text string, size int) []string {
+	var chunks []string
+	var current []rune
+	flush := func() {
+		if chunk := strings.TrimSpace(string(current)); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current = current[:0]
+	}
+
+	for _, paragraph := range strings.Split(text, "\n") {
+		runes := []rune(strings.TrimSpace(paragraph))
+		if len(runes) == 0 {
+			continue
+		}
+		if len(current) > 0 && len(current)+len(runes) > size {
+			flush()
+		}
+		for len(runes) > size {
+			current = append(current, runes[:size]...)
+			flush()
+			runes = runes[size:]
+		}
+		if len(current) > 0 {
+			current = append(current, '\n')
+		}
+		current = append(current, runes...)
+	}
+	flush()
+	return chunks
+}
+
+// parseTranscripts returns an insight's transcript segments, or nil.
+func parseTranscripts(insight *models.Insight) []models.TranscriptItem {
+	if len(insight.Transcripts) == 0 {
+		return nil
+	}
+	var items []models.TranscriptItem
+	if err := json.Unmarshal(insight.Transcripts, &items); err != nil {
+		return nil
+	}
+	return items
+}
+
+// findTranscriptItem returns the segment whose text contains the start of text.
+func findTranscriptItem(items []models.TranscriptItem, text string) *models.TranscriptItem {
+	probe := []rune(strings.TrimSpace(text))
+	if len(probe) == 0 {
+		return nil
+	}
+	if len(probe) > 20 {
+		probe = probe[:20]
+	}
+	needle := string(probe)
+	for i := range items {
+		if strings.Contains(items[i].Text, needle) || strings.Contains(items[i].TranslatedText, needle) {
+			return &items[i]
+		}
+	}
+	return nil
+}
+
+// buildSnippet cuts a window of text around the first match of any needle and wraps
+// every match in <mark>. The text is HTML-escaped. If no needle occurs verbatim (CJK
+// bigrams can match out of order), the snippet is the start of the text.
+func buildSnippet(text string, needles [][]rune) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// Mark every occurrence of every needle
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, needle := range needles {
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if runesEqual(lower[i:i+len(needle)], needle) {
+				for j := i; j < i+len(needle); j++ {
+					marked[j] = true
+				}
+				if first == -1 || i < first {
+					first = i
+				}
+			}
+		}
+	}
+
+	start, end := 0, len(runes)
+	if first > searchSnippetRunes {
+		start = first - searchSnippetRunes
+	}
+	if first == -1 {
+		first = 0
+	}
+	if limit := first + searchSnippetRunes*2; limit < end {
+		end = limit
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	inMark := false
+	for i := start; i < end; i++ {
+		if marked[i] != inMark {
+			if marked[i] {
+				b.WriteString("<mark>")
+			} else {
+				b.WriteString("</mark>")
+			}
+			inMark = marked[i]
+		}
+		b.WriteString(html.EscapeString(string(runes[i])))
+	}
+	if inMark {
+		b.WriteString("</mark>")
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return strings.ReplaceAll(b.String(), "\n", " ")
+}
+
+// runesEqual reports whether a and b are equal.
+func runesEqual(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// searchMatchLink returns the app path of a match: transcript positions link with ?t=,
+// highlights and chat messages by ID.
+func searchMatchLink(insightID uint, kind models.SearchKind, refID *uint, seconds *int) string {
+	params := make([]string, 0, 2)
+	if seconds != nil {
+		params = append(params, fmt.Sprintf("t=%d", *seconds))
+	}
+	if refID != nil {
+		switch kind {
+		case models.SearchKindHighlight:
+			params = append(params, fmt.Sprintf("highlight=%d", *refID))
+		case models.SearchKindChat:
+			params = append(params, fmt.Sprintf("message=%d", *refID))
+		}
+	}
+
+	link := fmt.Sprintf("/insights/%d", insightID)
+	if len(params) > 0 {
+		link += "?" + strings.Join(params, "&")
+	}
+	return link
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		document []string
+		query    []string
+	}{
+		{name: "empty", text: "", document: nil, query: nil},
+		{name: "punctuation only", text: "!? -- …", document: nil, query: nil},
+		{name: "words are lowercased", text: "Hello, World 42", document: []string{"hello", "world", "42"}, query: []string{"hello", "world", "42"}},
+		{name: "non-ASCII letters", text: "Ärger-Straße", document: []string{"ärger", "straße"}, query: []string{"ärger", "straße"}},
+		{name: "Chinese bigrams", text: "机器学习", document: []string{"机器", "器学", "学习", "习"}, query: []string{"机器", "器学", "学习"}},
+		{name: "single Chinese character", text: "学", document: []string{"学"}, query: []string{"学"}},
+		{name: "Latin then Chinese", text: "GPT模型训练", document: []string{"gpt", "模型", "型训", "训练", "练"}, query: []string{"gpt", "模型", "型训", "训练"}},
+		{name: "digits split CJK runs", text: "第1章", document: []string{"第", "1", "章"}, query: []string{"第", "1", "章"}},
+		{name: "Chinese punctuation splits runs", text: "你好，世界", document: []string{"你好", "好", "世界", "界"}, query: []string{"你好", "世界"}},
+		{name: "katakana with long vowel mark", text: "データ", document: []string{"デー", "ータ", "タ"}, query: []string{"デー", "ータ"}},
+		{name: "Korean and English", text: "한국어 text", document: []string{"한국", "국어", "어", "text"}, query: []string{"한국", "국어", "text"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchTerms(tt.text, false); !reflect.DeepEqual(got, tt.document) {
+				t.Errorf("document terms = %q, want %q", got, tt.document)
+			}
+			if got := searchTerms(tt.text, true); !reflect.DeepEqual(got, tt.query) {
+				t.Errorf("query terms = %q, want %q", got, tt.query)
+			}
+		})
+	}
+}
+
+func TestBuildSearchQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		needles []string
+	}{
+		{name: "empty", query: "  ", want: "", needles: nil},
+		{name: "last word is a prefix", query: "Go lang", want: "go & lang:*", needles: []string{"go", "lang"}},
+		{name: "CJK bigrams are exact", query: "机器学习", want: "机器 & 器学 & 学习", needles: []string{"机器学习"}},
+		{name: "single CJK character is a prefix", query: "机器 学", want: "机器 & 学:*", needles: []string{"机器", "学"}},
+		{name: "mixed", query: "hello 世界", want: "hello & 世界", needles: []string{"hello", "世界"}},
+		{name: "words without terms are skipped", query: "rust -- 2024", want: "rust & 2024:*", needles: []string{"rust", "2024"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, needles := buildSearchQuery(tt.query)
+			if got != tt.want {
+				t.Errorf("query = %q, want %q", got, tt.want)
+			}
+			var gotNeedles []string
+			for _, n := range needles {
+				gotNeedles = append(gotNeedles, string(n))
+			}
+			if !reflect.DeepEqual(gotNeedles, tt.needles) {
+				t.Errorf("needles = %q, want %q", gotNeedles, tt.needles)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+)
+
+// securityNotifyTimeout bounds how long a notification email may take.
+const securityNotifyTimeout = 30 * time.Second
+
+// SecurityService records security events and notifies users about important ones.
+type SecurityService struct {
+	repo   *repository.SecurityEventRepository
+	mailer Mailer
+	log    *zap.Logger
+}
+
+// NewSecurityService creates a new SecurityService. mailer may be nil to disable notifications.
+func NewSecurityService(repo *repository.SecurityEventRepository, mailer Mailer, log *zap.Logger) *SecurityService {
+	return &SecurityService{
+		repo:   repo,
+		mailer: mailer,
+		log:    log,
+	}
+}
+
+// Record stores a security event for a user and sends a notification if warranted.
+// Failures are logged rather than returned so callers never fail a request over auditing.
+func (s *SecurityService) Record(ctx context.Context, user *models.User, eventType models.SecurityEventType, ip, userAgent, details string) {
+	event := &models.SecurityEvent{
+		UserID:    user.ID,
+		Type:      eventType,
+		IPAddress: ip,
+		UserAgent: truncate(userAgent, 500),
+		Details:   details,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.Create(ctx, event); err != nil {
+		s.log.Error("Failed to record security event",
+			zap.Uint("user_id", user.ID),
+			zap.String("type", string(eventType)),
+			zap.Error(err),
+		)
+	}
+
+	subject, body, ok := securityNotification(eventType, ip, event.CreatedAt)
+	if !ok || s.mailer == nil || strings.HasSuffix(user.Email, ".invalid") {
+		return
+	}
+	go func(to string) {
+		ctx, cancel := context.WithTimeout(context.Background(), securityNotifyTimeout)
+		defer cancel()
+		if err := s.mailer.Send(ctx, to, subject, body); err != nil {
+			s.log.Warn("Failed to send security notification",
+				zap.Uint("user_id", user.ID),
+				zap.String("type", string(eventType)),
+				zap.Error(err),
+			)
+		}
+	}(user.Email)
+}
+
+// List returns a page of a user's security events.
+func (s *SecurityService) List(ctx context.Context, userID uint, page models.PageRequest) (*models.SecurityEventListResponse, error) {
+	events, info, err := s.repo.ListPage(ctx, userID, page)
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = make([]models.SecurityEvent, 0)
+	}
+	return &models.SecurityEventListResponse{Items: events, PageInfo: info}, nil
+}
+
+// securityNotification returns the email for event types users are notified about.
+// Individual failed logins are only logged; the lockout they lead to is notified.
+func securityNotification(eventType models.SecurityEventType, ip string, at time.Time) (string, string, bool) {
+	when := at.UTC().Format(time.RFC1123)
+	switch eventType {
+	case models.SecurityEventAccountLocked:
+		return "Your account was temporarily locked",
+			fmt.Sprintf("We temporarily locked sign-in to your account after repeated failed login attempts.\n\nTime: %s\nIP address: %s\n\nIf this wasn't you, consider changing your password.", when, ip),
+			true
+	case models.SecurityEventAPIKeyRegenerated:
+		return "Your API key was regenerated",
+			fmt.Sprintf("The API key for your account was regenerated. Previously issued keys no longer work.\n\nTime: %s\nIP address: %s\n\nIf this wasn't you, secure your account immediately.", when, ip),
+			true
+	case models.SecurityEventDataExported:
+		return "A copy of your data was requested",
+			fmt.Sprintf("An export of all data in your account was requested. It can be downloaded from your account settings once it is ready.\n\nTime: %s\nIP address: %s\n\nIf this wasn't you, regenerate your API key immediately.", when, ip),
+			true
+	case models.SecurityEventDeletionScheduled:
+		return "Your account is scheduled for deletion",
+			fmt.Sprintf("Your account and all its data are scheduled to be permanently deleted. Your share links were revoked. You can cancel the deletion from your account settings until it happens.\n\nTime: %s\nIP address: %s\n\nIf this wasn't you, cancel the deletion and secure your account immediately.", when, ip),
+			true
+	case models.SecurityEventDeletionCancelled:
+		return "Your account deletion was cancelled",
+			fmt.Sprintf("The scheduled deletion of your account was cancelled. Share links revoked when the deletion was requested stay revoked.\n\nTime: %s\nIP address: %s", when, ip),
+			true
+	}
+	return "", "", false
+}
+
+// truncate limits s to n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,918 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v4"
+	"go.uber.org/zap"
+	"vibe-backend/internal/models"
+)
+
+var (
+	ErrSFUNotJoined    = errors.New("participant is not connected to the SFU")
+	ErrSFUTrackMissing = errors.New("track not found")
+)
+
+// SFUConfig configures the ICE transport of the SFU's peer connections.
+type SFUConfig struct {
+	ICEServers      []string // STUN/TURN URLs
+	PublicIPs       []string // Advertised host addresses when behind a 1:1 NAT
+	UDPPortMin      uint16   // Ephemeral UDP port range; both 0 for any port
+	UDPPortMax      uint16
+	IncludeLoopback bool // Gather loopback candidates, for in-process clients on one host
+}
+
+// SFU is a selective forwarding unit for rooms in "sfu" media mode. Each participant
+// has two peer connections with the server: a publish connection, offered by the
+// client, that carries its tracks (optionally simulcast), and a subscribe connection,
+// offered by the server, that carries one forwarded track per track published by the
+// others and is renegotiated as participants come and go. Signaling goes through the
+// room's SignalingHub connection, addressed to models.SignalSFU.
+//
+// The SFU lives in process memory, so all participants of a room must signal through
+// the same instance.
+type SFU struct {
+	api    *webrtc.API
+	config webrtc.Configuration
+	log    *zap.Logger
+
+	mu    sync.Mutex
+	rooms map[string]*sfuRoom
+}
+
+// NewSFU creates a new SFU.
+func NewSFU(cfg SFUConfig, log *zap.Logger) (*SFU, error) {
+	media := &webrtc.MediaEngine{}
+	if err := registerSFUCodecs(media); err != nil {
+		return nil, err
+	}
+	registry := &interceptor.Registry{}
+	if err := webrtc.RegisterDefaultInterceptors(media, registry); err != nil {
+		return nil, err
+	}
+
+	settings := webrtc.SettingEngine{}
+	if cfg.UDPPortMin != 0 || cfg.UDPPortMax != 0 {
+		if err := settings.SetEphemeralUDPPortRange(cfg.UDPPortMin, cfg.UDPPortMax); err != nil {
+			return nil, fmt.Errorf("invalid UDP port range: %w", err)
+		}
+	}
+	if len(cfg.PublicIPs) > 0 {
+		settings.SetNAT1To1IPs(cfg.PublicIPs, webrtc.ICECandidateTypeHost)
+	}
+	settings.SetIncludeLoopbackCandidate(cfg.IncludeLoopback)
+
+	var iceServers []webrtc.ICEServer
+	if len(cfg.ICEServers) > 0 {
+		iceServers = []webrtc.ICEServer{{URLs: cfg.ICEServers}}
+	}
+
+	return &SFU{
+		api: webrtc.NewAPI(
+			webrtc.WithMediaEngine(media),
+			webrtc.WithInterceptorRegistry(registry),
+			webrtc.WithSettingEngine(settings),
+		),
+		config: webrtc.Configuration{ICEServers: iceServers},
+		log:    log,
+		rooms:  make(map[string]*sfuRoom),
+	}, nil
+}
+
+// registerSFUCodecs registers the codecs the SFU forwards: Opus, and VP8 and H.264,
+// whose keyframes it can recognize to switch simulcast layers cleanly.
+func registerSFUCodecs(media *webrtc.MediaEngine) error {
+	videoFeedback := []webrtc.RTCPFeedback{
+		{Type: "goog-remb"},
+		{Type: "ccm", Parameter: "fir"},
+		{Type: "nack"},
+		{Type: "nack", Parameter: "pli"},
+	}
+	audio := []webrtc.RTPCodecParameters{
+		{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2, SDPFmtpLine: "minptime=10;useinbandfec=1"}, PayloadType: 111},
+	}
+	video := []webrtc.RTPCodecParameters{
+		{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000, RTCPFeedback: videoFeedback}, PayloadType: 96},
+		{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000,
+			SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f", RTCPFeedback: videoFeedback}, PayloadType: 102},
+		{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000,
+			SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42001f", RTCPFeedback: videoFeedback}, PayloadType: 106},
+	}
+	for _, codec := range audio {
+		if err := media.RegisterCodec(codec, webrtc.RTPCodecTypeAudio); err != nil {
+			return err
+		}
+	}
+	for _, codec := range video {
+		if err := media.RegisterCodec(codec, webrtc.RTPCodecTypeVideo); err != nil {
+			return err
+		}
+	}
+
+	// Simulcast layers are told apart by their RTP stream ID
+	for _, uri := range []string{sdp.SDESMidURI, sdp.SDESRTPStreamIDURI, sdp.SDESRepairRTPStreamIDURI} {
+		if err := media.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{URI: uri}, webrtc.RTPCodecTypeVideo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Join connects a signaling peer to the SFU of its room. The server offers the
+// subscribe connection whenever there are tracks to receive; the client offers the
+// publish connection when it has tracks to send. A participant joining again replaces
+// its previous connections.
+func (s *SFU) Join(peer *SignalingPeer) error {
+	p := &sfuParticipant{
+		id:                peer.ID,
+		signal:            peer,
+		downTracks:        make(map[string]*sfuDownTrack),
+		pendingCandidates: make(map[models.SFUTransport][]webrtc.ICECandidateInit),
+		log:               s.log.With(zap.String("room_id", peer.RoomID), zap.String("user_id", peer.ID)),
+	}
+	var err error
+	if p.publisher, err = s.api.NewPeerConnection(s.config); err != nil {
+		return err
+	}
+	if p.subscriber, err = s.api.NewPeerConnection(s.config); err != nil {
+		_ = p.publisher.Close()
+		return err
+	}
+	p.watch(p.publisher, models.SFUPublish)
+	p.watch(p.subscriber, models.SFUSubscribe)
+
+	// Registered under both locks so Leave cannot drop the room meanwhile
+	s.mu.Lock()
+	room, ok := s.rooms[peer.RoomID]
+	if !ok {
+		room = &sfuRoom{
+			participants: make(map[string]*sfuParticipant),
+			tracks:       make(map[string]*sfuTrack),
+		}
+		s.rooms[peer.RoomID] = room
+	}
+	p.room = room
+	room.mu.Lock()
+	previous := room.participants[p.id]
+	room.participants[p.id] = p
+	var subscribed []*sfuTrack
+	for _, track := range room.tracks {
+		if track.publisher != previous {
+			subscribed = append(subscribed, track)
+		}
+	}
+	room.mu.Unlock()
+	s.mu.Unlock()
+
+	p.publisher.OnTrack(func(remote *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		room.publish(p, remote)
+	})
+
+	if previous != nil {
+		room.removeParticipant(previous)
+	}
+	for _, track := range subscribed {
+		if err := p.subscribe(track); err != nil {
+			p.log.Warn("Failed to subscribe to track", zap.String("track", track.key), zap.Error(err))
+		}
+	}
+	if len(p.downTrackList()) > 0 {
+		go p.negotiate()
+	}
+	return nil
+}
+
+// Leave disconnects a signaling peer from the SFU, stops forwarding its tracks and
+// renegotiates with the rest of the room.
+func (s *SFU) Leave(peer *SignalingPeer) {
+	s.mu.Lock()
+	room := s.rooms[peer.RoomID]
+	s.mu.Unlock()
+	if room == nil {
+		return
+	}
+
+	room.mu.Lock()
+	p := room.participants[peer.ID]
+	if p == nil || p.signal != peer {
+		room.mu.Unlock()
+		return
+	}
+	delete(room.participants, peer.ID)
+	room.mu.Unlock()
+
+	room.removeParticipant(p)
+
+	s.mu.Lock()
+	room.mu.Lock()
+	if len(room.participants) == 0 && s.rooms[peer.RoomID] == room {
+		delete(s.rooms, peer.RoomID)
+	}
+	room.mu.Unlock()
+	s.mu.Unlock()
+}
+
+// HandleSignal processes a message a peer addressed to the SFU: an offer for its
+// publish connection, an answer for its subscribe connection, an ICE candidate for
+// either, or a simulcast layer choice.
+func (s *SFU) HandleSignal(peer *SignalingPeer, msg *models.SignalMessage) error {
+	p := s.participant(peer)
+	if p == nil {
+		return ErrSFUNotJoined
+	}
+
+	if msg.Type == models.SignalLayer {
+		var req models.SFULayerRequest
+		if err := json.Unmarshal(msg.Payload, &req); err != nil || req.Publisher == "" || req.TrackID == "" {
+			return ErrInvalidSignal
+		}
+		return p.selectLayer(req)
+	}
+
+	var payload models.SFUSignalPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return ErrInvalidSignal
+	}
+	switch {
+	case msg.Type == models.SignalOffer && payload.Transport == models.SFUPublish:
+		return p.answerPublish(payload.SDP)
+	case msg.Type == models.SignalAnswer && payload.Transport == models.SFUSubscribe:
+		return p.acceptSubscribeAnswer(payload.SDP)
+	case msg.Type == models.SignalCandidate && (payload.Transport == models.SFUPublish || payload.Transport == models.SFUSubscribe):
+		var candidate webrtc.ICECandidateInit
+		if err := json.Unmarshal(payload.Candidate, &candidate); err != nil {
+			return ErrInvalidSignal
+		}
+		return p.addCandidate(payload.Transport, candidate)
+	}
+	return ErrInvalidSignal
+}
+
+// SetMuted stops or resumes forwarding the audio a peer publishes, when a moderator
+// mutes or unmutes them. Peers not connected to the SFU are skipped.
+func (s *SFU) SetMuted(peer *SignalingPeer, muted bool) {
+	if p := s.participant(peer); p != nil {
+		p.muted.Store(muted)
+	}
+}
+
+func (s *SFU) participant(peer *SignalingPeer) *sfuParticipant {
+	s.mu.Lock()
+	room := s.rooms[peer.RoomID]
+	s.mu.Unlock()
+	if room == nil {
+		return nil
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	p := room.participants[peer.ID]
+	if p == nil || p.signal != peer {
+		return nil
+	}
+	return p
+}
+
+// sfuRoom holds the participants of a room and the tracks they publish.
+type sfuRoom struct {
+	mu           sync.Mutex
+	participants map[string]*sfuParticipant
+	tracks       map[string]*sfuTrack // By sfuTrack.key
+}
+
+// publish starts forwarding a track (or one simulcast layer of it) received from a
+// participant to everyone else in the room.
+func (r *sfuRoom) publish(p *sfuParticipant, remote *webrtc.TrackRemote) {
+	key := p.id + "/" + remote.ID()
+
+	r.mu.Lock()
+	if r.participants[p.id] != p {
+		r.mu.Unlock()
+		return
+	}
+	track, existed := r.tracks[key]
+	if !existed {
+		track = &sfuTrack{
+			key:       key,
+			id:        remote.ID(),
+			publisher: p,
+			kind:      remote.Kind(),
+			codec:     remote.Codec().RTPCodecCapability,
+			layers:    make(map[string]*webrtc.TrackRemote),
+			downs:     make(map[*sfuDownTrack]struct{}),
+		}
+		r.tracks[key] = track
+	}
+	var subscribers []*sfuParticipant
+	if !existed {
+		for _, other := range r.participants {
+			if other != p {
+				subscribers = append(subscribers, other)
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	track.addLayer(remote)
+	p.log.Debug("Forwarding track",
+		zap.String("track", key),
+		zap.String("rid", remote.RID()),
+		zap.String("codec", remote.Codec().MimeType),
+	)
+
+	for _, subscriber := range subscribers {
+		if err := subscriber.subscribe(track); err != nil {
+			subscriber.log.Warn("Failed to subscribe to track", zap.String("track", key), zap.Error(err))
+			continue
+		}
+		go subscriber.negotiate()
+	}
+
+	track.forward(remote)
+
+	// The layer ended: the publisher left or stopped sending it
+	if track.removeLayer(remote.RID()) {
+		r.removeTrack(track)
+	}
+}
+
+// removeTrack stops forwarding a track and renegotiates with its subscribers.
+func (r *sfuRoom) removeTrack(track *sfuTrack) {
+	r.mu.Lock()
+	if r.tracks[track.key] == track {
+		delete(r.tracks, track.key)
+	}
+	r.mu.Unlock()
+
+	for _, down := range track.detachAll() {
+		if down.subscriber.unsubscribe(down) {
+			go down.subscriber.negotiate()
+		}
+	}
+}
+
+// removeParticipant closes a participant's connections and removes its tracks from
+// everyone else.
+func (r *sfuRoom) removeParticipant(p *sfuParticipant) {
+	p.close()
+
+	r.mu.Lock()
+	var published []*sfuTrack
+	for _, track := range r.tracks {
+		if track.publisher == p {
+			published = append(published, track)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, track := range published {
+		r.removeTrack(track)
+	}
+	for _, down := range p.downTrackList() {
+		down.track.detach(down)
+	}
+}
+
+// sfuParticipant is a participant's pair of connections with the SFU.
+type sfuParticipant struct {
+	id         string
+	room       *sfuRoom
+	signal     *SignalingPeer
+	publisher  *webrtc.PeerConnection // Offered by the client
+	subscriber *webrtc.PeerConnection // Offered by the server
+	muted      atomic.Bool            // Audio is not forwarded
+	log        *zap.Logger
+
+	mu                sync.Mutex
+	downTracks        map[string]*sfuDownTrack // By sfuTrack.key
+	pendingCandidates map[models.SFUTransport][]webrtc.ICECandidateInit
+	negotiating       bool // A subscribe offer awaits its answer
+	renegotiate       bool // Tracks changed meanwhile; offer again after the answer
+	closed            bool
+}
+
+// watch sends the server's ICE candidates of a connection to the client.
+func (p *sfuParticipant) watch(pc *webrtc.PeerConnection, transport models.SFUTransport) {
+	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate == nil {
+			return
+		}
+		init, err := json.Marshal(candidate.ToJSON())
+		if err != nil {
+			return
+		}
+		p.send(models.SignalCandidate, &models.SFUSignalPayload{Transport: transport, Candidate: init})
+	})
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateFailed {
+			p.log.Warn("SFU connection failed", zap.String("transport", string(transport)))
+		}
+	})
+}
+
+func (p *sfuParticipant) send(signalType models.SignalType, payload *models.SFUSignalPayload) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	p.signal.Send(&models.SignalMessage{Type: signalType, From: models.SignalSFU, To: p.id, Payload: data})
+}
+
+// answerPublish answers the client's offer for its publish connection.
+func (p *sfuParticipant) answerPublish(sdp string) error {
+	if sdp == "" {
+		return ErrInvalidSignal
+	}
+	if err := p.publisher.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: sdp}); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignal, err)
+	}
+	p.flushCandidates(models.SFUPublish, p.publisher)
+
+	answer, err := p.publisher.CreateAnswer(nil)
+	if err != nil {
+		return err
+	}
+	if err := p.publisher.SetLocalDescription(answer); err != nil {
+		return err
+	}
+	p.send(models.SignalAnswer, &models.SFUSignalPayload{Transport: models.SFUPublish, SDP: answer.SDP})
+	return nil
+}
+
+// negotiate offers the subscribe connection's current tracks to the client. While an
+// offer is outstanding, changes are batched into one more offer after the answer.
+func (p *sfuParticipant) negotiate() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	if p.negotiating {
+		p.renegotiate = true
+		p.mu.Unlock()
+		return
+	}
+	p.negotiating = true
+	p.mu.Unlock()
+
+	offer, err := p.subscriber.CreateOffer(nil)
+	if err == nil {
+		err = p.subscriber.SetLocalDescription(offer)
+	}
+	if err != nil {
+		p.mu.Lock()
+		p.negotiating = false
+		closed := p.closed
+		p.mu.Unlock()
+		if !closed {
+			p.log.Warn("Failed to offer subscribe connection", zap.Error(err))
+		}
+		return
+	}
+	p.send(models.SignalOffer, &models.SFUSignalPayload{Transport: models.SFUSubscribe, SDP: offer.SDP})
+}
+
+// acceptSubscribeAnswer applies the client's answer to the last subscribe offer.
+func (p *sfuParticipant) acceptSubscribeAnswer(sdp string) error {
+	if sdp == "" || p.subscriber.SignalingState() != webrtc.SignalingStateHaveLocalOffer {
+		return ErrInvalidSignal
+	}
+	if err := p.subscriber.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: sdp}); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignal, err)
+	}
+	p.flushCandidates(models.SFUSubscribe, p.subscriber)
+
+	p.mu.Lock()
+	p.negotiating = false
+	again := p.renegotiate
+	p.renegotiate = false
+	p.mu.Unlock()
+
+	if again {
+		go p.negotiate()
+	}
+	return nil
+}
+
+// addCandidate adds a client ICE candidate, holding it until the connection has a
+// remote description.
+func (p *sfuParticipant) addCandidate(transport models.SFUTransport, candidate webrtc.ICECandidateInit) error {
+	pc := p.publisher
+	if transport == models.SFUSubscribe {
+		pc = p.subscriber
+	}
+
+	p.mu.Lock()
+	if pc.RemoteDescription() == nil {
+		p.pendingCandidates[transport] = append(p.pendingCandidates[transport], candidate)
+		p.mu.Unlock()
+		return nil
+	}
+	p.mu.Unlock()
+
+	if err := pc.AddICECandidate(candidate); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignal, err)
+	}
+	return nil
+}
+
+func (p *sfuParticipant) flushCandidates(transport models.SFUTransport, pc *webrtc.PeerConnection) {
+	p.mu.Lock()
+	pending := p.pendingCandidates[transport]
+	delete(p.pendingCandidates, transport)
+	p.mu.Unlock()
+
+	for _, candidate := range pending {
+		if err := pc.AddICECandidate(candidate); err != nil {
+			p.log.Debug("Dropping ICE candidate", zap.Error(err))
+		}
+	}
+}
+
+// subscribe adds a forwarded copy of a track to the subscribe connection. The caller
+// renegotiates.
+func (p *sfuParticipant) subscribe(track *sfuTrack) error {
+	local, err := webrtc.NewTrackLocalStaticRTP(track.codec, track.id, track.publisher.id)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrSFUNotJoined
+	}
+	if _, ok := p.downTracks[track.key]; ok {
+		p.mu.Unlock()
+		return nil
+	}
+	sender, err := p.subscriber.AddTrack(local)
+	if err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	down := &sfuDownTrack{
+		track:      track,
+		subscriber: p,
+		local:      local,
+		sender:     sender,
+		clockRate:  track.codec.ClockRate,
+	}
+	p.downTracks[track.key] = down
+	p.mu.Unlock()
+
+	track.attach(down)
+	go down.readRTCP()
+	return nil
+}
+
+// unsubscribe removes a forwarded track from the subscribe connection and reports
+// whether renegotiation is needed.
+func (p *sfuParticipant) unsubscribe(down *sfuDownTrack) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.downTracks[down.track.key] != down {
+		return false
+	}
+	delete(p.downTracks, down.track.key)
+	if p.closed {
+		return false
+	}
+	if err := p.subscriber.RemoveTrack(down.sender); err != nil {
+		p.log.Debug("Failed to remove forwarded track", zap.Error(err))
+	}
+	return true
+}
+
+func (p *sfuParticipant) downTrackList() []*sfuDownTrack {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	downs := make([]*sfuDownTrack, 0, len(p.downTracks))
+	for _, down := range p.downTracks {
+		downs = append(downs, down)
+	}
+	return downs
+}
+
+// selectLayer switches a received track to another simulcast layer.
+func (p *sfuParticipant) selectLayer(req models.SFULayerRequest) error {
+	p.mu.Lock()
+	down := p.downTracks[req.Publisher+"/"+req.TrackID]
+	p.mu.Unlock()
+	if down == nil {
+		return ErrSFUTrackMissing
+	}
+	if !down.track.hasLayer(req.RID) {
+		return ErrInvalidSignal
+	}
+
+	down.mu.Lock()
+	down.target = req.RID
+	down.mu.Unlock()
+	down.track.requestKeyframe(req.RID)
+	return nil
+}
+
+func (p *sfuParticipant) close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	if err := p.publisher.Close(); err != nil {
+		p.log.Debug("Failed to close publish connection", zap.Error(err))
+	}
+	if err := p.subscriber.Close(); err != nil {
+		p.log.Debug("Failed to close subscribe connection", zap.Error(err))
+	}
+}
+
+// sfuTrack is a track published by a participant, with one remote track per
+// simulcast layer ("" without simulcast). Subscribers get the first layer that
+// arrived until they pick another one.
+type sfuTrack struct {
+	key       string // Publisher ID and track ID
+	id        string
+	publisher *sfuParticipant
+	kind      webrtc.RTPCodecType
+	codec     webrtc.RTPCodecCapability
+
+	mu     sync.RWMutex
+	layers map[string]*webrtc.TrackRemote // By RID
+	order  []string                       // RIDs in order of arrival
+	downs  map[*sfuDownTrack]struct{}
+}
+
+func (t *sfuTrack) addLayer(remote *webrtc.TrackRemote) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.layers[remote.RID()]; !ok {
+		t.order = append(t.order, remote.RID())
+	}
+	t.layers[remote.RID()] = remote
+	// Subscribers waiting for a first layer start with this one
+	for down := range t.downs {
+		down.mu.Lock()
+		if t.layers[down.target] == nil {
+			down.target = remote.RID()
+		}
+		down.mu.Unlock()
+	}
+}
+
+// removeLayer forgets an ended layer, moving its subscribers to the first remaining
+// one, and reports whether none are left.
+func (t *sfuTrack) removeLayer(rid string) bool {
+	t.mu.Lock()
+	delete(t.layers, rid)
+	for i, r := range t.order {
+		if r == rid {
+			t.order = append(t.order[:i], t.order[i+1:]...)
+			break
+		}
+	}
+	if len(t.order) == 0 {
+		t.mu.Unlock()
+		return true
+	}
+	fallback := t.order[0]
+	moved := false
+	for down := range t.downs {
+		down.mu.Lock()
+		if down.target == rid {
+			down.target = fallback
+			moved = true
+		}
+		down.mu.Unlock()
+	}
+	t.mu.Unlock()
+
+	if moved {
+		t.requestKeyframe(fallback)
+	}
+	return false
+}
+
+func (t *sfuTrack) hasLayer(rid string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	_, ok := t.layers[rid]
+	return ok
+}
+
+// attach starts forwarding to a subscriber, on the first layer that arrived.
+func (t *sfuTrack) attach(down *sfuDownTrack) {
+	t.mu.Lock()
+	var target string
+	if len(t.order) > 0 {
+		target = t.order[0]
+	}
+	down.mu.Lock()
+	down.target = target
+	down.mu.Unlock()
+	t.downs[down] = struct{}{}
+	t.mu.Unlock()
+
+	t.requestKeyframe(target)
+}
+
+func (t *sfuTrack) detach(down *sfuDownTrack) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.downs, down)
+}
+
+func (t *sfuTrack) detachAll() []*sfuDownTrack {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	downs := make([]*sfuDownTrack, 0, len(t.downs))
+	for down := range t.downs {
+		downs = append(downs, down)
+	}
+	t.downs = make(map[*sfuDownTrack]struct{})
+	return downs
+}
+
+// forward copies packets of one layer to the subscribers until the layer ends.
+func (t *sfuTrack) forward(remote *webrtc.TrackRemote) {
+	rid := remote.RID()
+	for {
+		packet, _, err := remote.ReadRTP()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.publisher.log.Debug("Stopped reading track", zap.String("track", t.key), zap.Error(err))
+			}
+			return
+		}
+		if t.kind == webrtc.RTPCodecTypeAudio && t.publisher.muted.Load() {
+			continue
+		}
+
+		keyframe := t.kind == webrtc.RTPCodecTypeAudio || isKeyframe(t.codec.MimeType, packet.Payload)
+		t.mu.RLock()
+		for down := range t.downs {
+			down.write(rid, packet, keyframe)
+		}
+		t.mu.RUnlock()
+	}
+}
+
+// requestKeyframe asks the publisher for a keyframe on a layer.
+func (t *sfuTrack) requestKeyframe(rid string) {
+	if t.kind != webrtc.RTPCodecTypeVideo {
+		return
+	}
+	t.mu.RLock()
+	remote := t.layers[rid]
+	t.mu.RUnlock()
+	if remote == nil {
+		return
+	}
+	if err := t.publisher.publisher.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(remote.SSRC())}}); err != nil {
+		t.publisher.log.Debug("Failed to request keyframe", zap.Error(err))
+	}
+}
+
+// sfuDownTrack forwards one layer of a track to one subscriber. Layers have their own
+// sequence numbers and timestamps, so packets are rewritten to continue the stream
+// seamlessly, and switching waits for a keyframe of the new layer.
+type sfuDownTrack struct {
+	track      *sfuTrack
+	subscriber *sfuParticipant
+	local      *webrtc.TrackLocalStaticRTP
+	sender     *webrtc.RTPSender
+	clockRate  uint32
+
+	mu        sync.Mutex
+	target    string // Requested layer
+	current   string // Layer being forwarded
+	started   bool
+	seqOffset uint16
+	tsOffset  uint32
+	lastSeq   uint16
+	lastTS    uint32
+	lastWrite time.Time
+}
+
+func (d *sfuDownTrack) write(rid string, packet *rtp.Packet, keyframe bool) {
+	d.mu.Lock()
+	if !d.started || rid != d.current {
+		if rid != d.target || !keyframe {
+			d.mu.Unlock()
+			return
+		}
+		if d.started {
+			// Continue from the last packet sent, advanced by the time since
+			elapsed := uint32(time.Since(d.lastWrite).Seconds() * float64(d.clockRate))
+			if elapsed == 0 {
+				elapsed = 1
+			}
+			d.seqOffset = d.lastSeq + 1 - packet.SequenceNumber
+			d.tsOffset = d.lastTS + elapsed - packet.Timestamp
+		} else {
+			d.seqOffset, d.tsOffset = 0, 0
+		}
+		d.current = rid
+		d.started = true
+	}
+
+	out := rtp.Packet{Header: packet.Header, Payload: packet.Payload}
+	out.SequenceNumber = packet.SequenceNumber + d.seqOffset
+	out.Timestamp = packet.Timestamp + d.tsOffset
+	// Header extension IDs were negotiated with the publisher, not the subscriber
+	out.Extension = false
+	out.Extensions = nil
+	d.lastSeq, d.lastTS, d.lastWrite = out.SequenceNumber, out.Timestamp, time.Now()
+	d.mu.Unlock()
+
+	if err := d.local.WriteRTP(&out); err != nil && !errors.Is(err, io.ErrClosedPipe) {
+		d.subscriber.log.Debug("Failed to forward packet", zap.String("track", d.track.key), zap.Error(err))
+	}
+}
+
+// readRTCP passes the subscriber's keyframe requests on to the publisher.
+func (d *sfuDownTrack) readRTCP() {
+	for {
+		packets, _, err := d.sender.ReadRTCP()
+		if err != nil {
+			return
+		}
+		for _, packet := range packets {
+			switch packet.(type) {
+			case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+				d.mu.Lock()
+				rid := d.current
+				if !d.started {
+					rid = d.target
+				}
+				d.mu.Unlock()
+				d.track.requestKeyframe(rid)
+			}
+		}
+	}
+}
+
+// isKeyframe reports whether an RTP payload starts a keyframe.
+func isKeyframe(mimeType string, payload []byte) bool {
+	switch mimeType {
+	case webrtc.MimeTypeVP8:
+		var vp8 codecs.VP8Packet
+		if _, err := vp8.Unmarshal(payload); err != nil || len(vp8.Payload) == 0 {
+			return false
+		}
+		// Start of partition 0 with the P bit of the frame header clear
+		return vp8.S == 1 && vp8.PID == 0 && vp8.Payload[0]&0x01 == 0
+	case webrtc.MimeTypeH264:
+		return isH264Keyframe(payload)
+	}
+	return true
+}
+
+// isH264Keyframe reports whether an H.264 payload carries an SPS or IDR slice,
+// directly, aggregated (STAP-A) or as the first fragment (FU-A).
+func isH264Keyframe(payload []byte) bool {
+	if len(payload) < 1 {
+		return false
+	}
+	isKey := func(nalType byte) bool { return nalType == 5 || nalType == 7 }
+
+	switch nalType := payload[0] & 0x1F; nalType {
+	case 24: // STAP-A
+		for i := 1; i+2 < len(payload); {
+			size := int(payload[i])<<8 | int(payload[i+1])
+			i += 2
+			if i >= len(payload) {
+				break
+			}
+			if isKey(payload[i] & 0x1F) {
+				return true
+			}
+			i += size
+		}
+		return false
+	case 28: // FU-A
+		return len(payload) > 1 && payload[1]&0x80 != 0 && isKey(payload[1]&0x1F)
+	default:
+		return isKey(nalType)
+	}
+}
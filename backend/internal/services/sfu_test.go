@@ -0,0 +1,434 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v4"
+	"go.uber.org/zap"
+	"vibe-backend/internal/models"
+)
+
+const (
+	testSFURoom    = "room"
+	testSFUTimeout = 15 * time.Second
+)
+
+// testSFUClient is an in-process participant of an SFU room: a publish and a
+// subscribe connection signaled through a SignalingPeer, as the browser does over
+// the room's WebSocket.
+type testSFUClient struct {
+	t    *testing.T
+	sfu  *SFU
+	peer *SignalingPeer
+	pub  *webrtc.PeerConnection
+	sub  *webrtc.PeerConnection
+
+	tracks chan *webrtc.TrackRemote
+	offers chan string // Subscribe offers, once answered
+	wg     sync.WaitGroup
+}
+
+func newTestSFU(t *testing.T) *SFU {
+	t.Helper()
+
+	sfu, err := NewSFU(SFUConfig{IncludeLoopback: true}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("new SFU: %v", err)
+	}
+	return sfu
+}
+
+// newTestSFUAPI creates a client WebRTC API with the codecs and header extensions
+// the SFU negotiates.
+func newTestSFUAPI(t *testing.T) *webrtc.API {
+	t.Helper()
+
+	media := &webrtc.MediaEngine{}
+	if err := registerSFUCodecs(media); err != nil {
+		t.Fatalf("register codecs: %v", err)
+	}
+	registry := &interceptor.Registry{}
+	if err := webrtc.RegisterDefaultInterceptors(media, registry); err != nil {
+		t.Fatalf("register interceptors: %v", err)
+	}
+	settings := webrtc.SettingEngine{}
+	settings.SetIncludeLoopbackCandidate(true)
+	return webrtc.NewAPI(
+		webrtc.WithMediaEngine(media),
+		webrtc.WithInterceptorRegistry(registry),
+		webrtc.WithSettingEngine(settings),
+	)
+}
+
+// joinTestSFU connects a new client to the SFU.
+func joinTestSFU(t *testing.T, sfu *SFU, api *webrtc.API, id string) *testSFUClient {
+	t.Helper()
+
+	c := &testSFUClient{
+		t:      t,
+		sfu:    sfu,
+		peer:   &SignalingPeer{RoomID: testSFURoom, ID: id, send: make(chan []byte, signalingSendBuffer), done: make(chan struct{})},
+		tracks: make(chan *webrtc.TrackRemote, 4),
+		offers: make(chan string, 16),
+	}
+	var err error
+	if c.pub, err = api.NewPeerConnection(webrtc.Configuration{}); err != nil {
+		t.Fatalf("new publish connection: %v", err)
+	}
+	if c.sub, err = api.NewPeerConnection(webrtc.Configuration{}); err != nil {
+		t.Fatalf("new subscribe connection: %v", err)
+	}
+	c.trickle(c.pub, models.SFUPublish)
+	c.trickle(c.sub, models.SFUSubscribe)
+	c.sub.OnTrack(func(remote *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		c.tracks <- remote
+	})
+
+	if err := sfu.Join(c.peer); err != nil {
+		t.Fatalf("join %s: %v", id, err)
+	}
+	c.wg.Add(1)
+	go c.run()
+
+	t.Cleanup(func() {
+		sfu.Leave(c.peer)
+		c.peer.close()
+		c.wg.Wait()
+		_ = c.pub.Close()
+		_ = c.sub.Close()
+	})
+	return c
+}
+
+// trickle sends the client's ICE candidates of a connection to the SFU. They are
+// dropped once the client has left.
+func (c *testSFUClient) trickle(pc *webrtc.PeerConnection, transport models.SFUTransport) {
+	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate == nil {
+			return
+		}
+		init, err := json.Marshal(candidate.ToJSON())
+		if err != nil {
+			return
+		}
+		_ = c.signal(models.SignalCandidate, &models.SFUSignalPayload{Transport: transport, Candidate: init})
+	})
+}
+
+func (c *testSFUClient) signal(signalType models.SignalType, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return c.sfu.HandleSignal(c.peer, &models.SignalMessage{Type: signalType, To: models.SignalSFU, Payload: data})
+}
+
+// run handles the SFU's messages until the client leaves.
+func (c *testSFUClient) run() {
+	defer c.wg.Done()
+
+	pending := make(map[models.SFUTransport][]webrtc.ICECandidateInit)
+	for {
+		select {
+		case <-c.peer.Done():
+			return
+		case data := <-c.peer.Messages():
+			if err := c.handle(data, pending); err != nil {
+				c.t.Errorf("%s: %v", c.peer.ID, err)
+				return
+			}
+		}
+	}
+}
+
+func (c *testSFUClient) handle(data []byte, pending map[models.SFUTransport][]webrtc.ICECandidateInit) error {
+	var msg models.SignalMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	if msg.From != models.SignalSFU || msg.To != c.peer.ID {
+		return errors.New("unexpected message " + string(data))
+	}
+	var payload models.SFUSignalPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return err
+	}
+	pc := c.pub
+	if payload.Transport == models.SFUSubscribe {
+		pc = c.sub
+	}
+
+	switch msg.Type {
+	case models.SignalCandidate:
+		var candidate webrtc.ICECandidateInit
+		if err := json.Unmarshal(payload.Candidate, &candidate); err != nil {
+			return err
+		}
+		if pc.RemoteDescription() == nil {
+			pending[payload.Transport] = append(pending[payload.Transport], candidate)
+			return nil
+		}
+		return pc.AddICECandidate(candidate)
+	case models.SignalAnswer:
+		if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: payload.SDP}); err != nil {
+			return err
+		}
+	case models.SignalOffer:
+		if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: payload.SDP}); err != nil {
+			return err
+		}
+		answer, err := pc.CreateAnswer(nil)
+		if err != nil {
+			return err
+		}
+		if err := pc.SetLocalDescription(answer); err != nil {
+			return err
+		}
+		if err := c.signal(models.SignalAnswer, &models.SFUSignalPayload{Transport: models.SFUSubscribe, SDP: answer.SDP}); err != nil {
+			return err
+		}
+		c.offers <- payload.SDP
+	default:
+		return errors.New("unexpected message type " + string(msg.Type))
+	}
+
+	for _, candidate := range pending[payload.Transport] {
+		if err := pc.AddICECandidate(candidate); err != nil {
+			return err
+		}
+	}
+	delete(pending, payload.Transport)
+	return nil
+}
+
+// publishSimulcast offers a VP8 track "video" with layers "h" and "l" and keeps
+// sending keyframes on it, each tagged with the byte of its layer. Layer "l" is only
+// sent once sendLow is set.
+func (c *testSFUClient) publishSimulcast(sendLow *atomic.Bool) {
+	t := c.t
+	t.Helper()
+
+	codec := webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000}
+	high, err := webrtc.NewTrackLocalStaticRTP(codec, "video", c.peer.ID, webrtc.WithRTPStreamID("h"))
+	if err != nil {
+		t.Fatalf("new track: %v", err)
+	}
+	low, err := webrtc.NewTrackLocalStaticRTP(codec, "video", c.peer.ID, webrtc.WithRTPStreamID("l"))
+	if err != nil {
+		t.Fatalf("new track: %v", err)
+	}
+	transceiver, err := c.pub.AddTransceiverFromTrack(high, webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionSendonly})
+	if err != nil {
+		t.Fatalf("add transceiver: %v", err)
+	}
+	if err := transceiver.Sender().AddEncoding(low); err != nil {
+		t.Fatalf("add encoding: %v", err)
+	}
+
+	offer, err := c.pub.CreateOffer(nil)
+	if err != nil {
+		t.Fatalf("create offer: %v", err)
+	}
+	if err := c.pub.SetLocalDescription(offer); err != nil {
+		t.Fatalf("set local description: %v", err)
+	}
+	if err := c.signal(models.SignalOffer, &models.SFUSignalPayload{Transport: models.SFUPublish, SDP: offer.SDP}); err != nil {
+		t.Fatalf("publish offer: %v", err)
+	}
+
+	// The SFU tells layers apart by these header extensions
+	var midID, ridID uint8
+	for _, extension := range transceiver.Sender().GetParameters().HeaderExtensions {
+		switch extension.URI {
+		case sdp.SDESMidURI:
+			midID = uint8(extension.ID)
+		case sdp.SDESRTPStreamIDURI:
+			ridID = uint8(extension.ID)
+		}
+	}
+	if midID == 0 || ridID == 0 {
+		t.Fatal("simulcast header extensions not negotiated")
+	}
+	mid := transceiver.Mid()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for seq := uint16(0); ; seq++ {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			for _, track := range []*webrtc.TrackLocalStaticRTP{high, low} {
+				if track == low && !sendLow.Load() {
+					continue
+				}
+				packet := &rtp.Packet{
+					Header: rtp.Header{Version: 2, SequenceNumber: seq, Timestamp: uint32(seq) * 900, Marker: true},
+					// VP8 descriptor with S set, then a keyframe header (P bit clear)
+					Payload: []byte{0x10, 0x00, track.RID()[0]},
+				}
+				_ = packet.Header.SetExtension(midID, []byte(mid))
+				_ = packet.Header.SetExtension(ridID, []byte(track.RID()))
+				_ = track.WriteRTP(packet)
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		close(stop)
+		wg.Wait()
+	})
+}
+
+// receive waits for a forwarded track and returns the layer bytes of its packets.
+func (c *testSFUClient) receive(streamID, trackID string) <-chan byte {
+	t := c.t
+	t.Helper()
+
+	var remote *webrtc.TrackRemote
+	select {
+	case remote = <-c.tracks:
+	case <-time.After(testSFUTimeout):
+		t.Fatalf("%s: no track received", c.peer.ID)
+	}
+	if remote.StreamID() != streamID || remote.ID() != trackID {
+		t.Fatalf("%s: got track %s/%s, want %s/%s", c.peer.ID, remote.StreamID(), remote.ID(), streamID, trackID)
+	}
+
+	layers := make(chan byte, 256)
+	go func() {
+		for {
+			packet, _, err := remote.ReadRTP()
+			if err != nil {
+				return
+			}
+			if len(packet.Payload) == 0 {
+				continue
+			}
+			select {
+			case layers <- packet.Payload[len(packet.Payload)-1]:
+			default:
+			}
+		}
+	}()
+	return layers
+}
+
+// waitLayer waits until a packet of the given layer is received.
+func waitLayer(t *testing.T, layers <-chan byte, layer byte) {
+	t.Helper()
+
+	deadline := time.After(testSFUTimeout)
+	for {
+		select {
+		case got := <-layers:
+			if got == layer {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("no packet of layer %q received", layer)
+		}
+	}
+}
+
+// waitOffer waits for a subscribe offer matching a condition.
+func (c *testSFUClient) waitOffer(match func(sdp string) bool) {
+	t := c.t
+	t.Helper()
+
+	deadline := time.After(testSFUTimeout)
+	for {
+		select {
+		case offer := <-c.offers:
+			if match(offer) {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("%s: no matching subscribe offer", c.peer.ID)
+		}
+	}
+}
+
+func TestSFULoopback(t *testing.T) {
+	if testing.Short() {
+		t.Skip("opens WebRTC connections")
+	}
+	sfu := newTestSFU(t)
+	api := newTestSFUAPI(t)
+
+	alice := joinTestSFU(t, sfu, api, "alice")
+	bob := joinTestSFU(t, sfu, api, "bob")
+	carol := joinTestSFU(t, sfu, api, "carol")
+
+	var sendLow atomic.Bool
+	alice.publishSimulcast(&sendLow)
+
+	// Both subscribers get the layer that arrived first
+	bobLayers := bob.receive("alice", "video")
+	carolLayers := carol.receive("alice", "video")
+	waitLayer(t, bobLayers, 'h')
+	waitLayer(t, carolLayers, 'h')
+
+	layer := func(c *testSFUClient, trackID, rid string) error {
+		return c.signal(models.SignalLayer, &models.SFULayerRequest{Publisher: "alice", TrackID: trackID, RID: rid})
+	}
+	if err := layer(bob, "screen", "l"); !errors.Is(err, ErrSFUTrackMissing) {
+		t.Fatalf("layer of unknown track: got %v, want ErrSFUTrackMissing", err)
+	}
+	if err := layer(bob, "video", "l"); !errors.Is(err, ErrInvalidSignal) {
+		t.Fatalf("layer not received yet: got %v, want ErrInvalidSignal", err)
+	}
+
+	// Bob switches to the low layer once it arrives; Carol stays on the high one
+	sendLow.Store(true)
+	deadline := time.Now().Add(testSFUTimeout)
+	for {
+		err := layer(bob, "video", "l")
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrInvalidSignal) || time.Now().After(deadline) {
+			t.Fatalf("select layer: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	waitLayer(t, bobLayers, 'l')
+	for end := time.After(300 * time.Millisecond); ; {
+		select {
+		case got := <-carolLayers:
+			if got != 'h' {
+				t.Fatalf("carol received layer %q, want h", got)
+			}
+			continue
+		case <-end:
+		}
+		break
+	}
+
+	// Joining later offers the published track right away
+	dave := joinTestSFU(t, sfu, api, "dave")
+	waitLayer(t, dave.receive("alice", "video"), 'h')
+
+	// The publisher leaving renegotiates the track away from everyone
+	sfu.Leave(alice.peer)
+	for _, c := range []*testSFUClient{bob, carol, dave} {
+		c.waitOffer(func(offer string) bool { return !strings.Contains(offer, "msid:alice ") })
+		if p := sfu.participant(c.peer); p == nil || len(p.downTrackList()) != 0 {
+			t.Fatalf("%s still subscribed after the publisher left", c.peer.ID)
+		}
+	}
+}
@@ -0,0 +1,638 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+)
+
+const (
+	// shareAccessPurpose is the signer purpose of share unlock tokens.
+	shareAccessPurpose = "share_access"
+	// ShareAccessTTL is how long an unlocked password-protected link stays unlocked.
+	ShareAccessTTL = time.Hour
+	// shareStatsDays is how many days of daily view counts are reported.
+	shareStatsDays = 30
+)
+
+// Share service errors.
+var (
+	ErrShareLinkNotFound     = errors.New("share link not found")
+	ErrShareLinkExpired      = errors.New("share link expired or view limit reached")
+	ErrSharePasswordRequired = errors.New("share link requires a password")
+	ErrSharePasswordInvalid  = errors.New("wrong share link password")
+)
+
+// defaultShareConfig is used for links without a stored content selection.
+var defaultShareConfig = models.ShareConfigData{
+	IncludeSummary:   true,
+	IncludeKeyPoints: true,
+}
+
+// shareAccess is the payload of a share unlock token. PasswordTag ties the token to the
+// password it was issued for, so changing the password locks the link again.
+type shareAccess struct {
+	LinkID      uint   `json:"l"`
+	PasswordTag string `json:"t"`
+}
+
+// ShareService manages public share links: creation, limits, password unlock,
+// the public view and view analytics. Callers check workspace permissions.
+type ShareService struct {
+	repo        *repository.ShareRepository
+	insightRepo *repository.InsightRepository
+	signer      *Signer
+	log         *zap.Logger
+}
+
+// NewShareService creates a new ShareService.
+func NewShareService(repo *repository.ShareRepository, insightRepo *repository.InsightRepository, signer *Signer, log *zap.Logger) *ShareService {
+	return &ShareService{
+		repo:        repo,
+		insightRepo: insightRepo,
+		signer:      signer,
+		log:         log,
+	}
+}
+
+// --- Link management ---
+
+// Create creates a new share link for an insight.
+func (s *ShareService) Create(ctx context.Context, userID, insightID uint, req *models.CreateShareLinkRequest) (*models.ShareLinkResponse, error) {
+	token, err := randomHexToken(32)
+	if err != nil {
+		return nil, err
+	}
+	config, err := json.Marshal(models.ShareConfigData{
+		IncludeSummary:    req.IncludeSummary,
+		IncludeKeyPoints:  req.IncludeKeyPoints,
+		IncludeHighlights: req.IncludeHighlights,
+		IncludeChat:       req.IncludeChat,
+		AllowComments:     req.AllowComments,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	link := &models.ShareLink{
+		InsightID: insightID,
+		CreatedBy: userID,
+		Token:     token,
+		Label:     strings.TrimSpace(req.Label),
+		Config:    config,
+		ExpiresAt: req.ExpiresAt,
+		MaxViews:  req.MaxViews,
+	}
+	if req.Password != "" {
+		if link.PasswordHash, err = hashSharePassword(req.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Create(ctx, link); err != nil {
+		return nil, err
+	}
+	return s.toResponse(link), nil
+}
+
+// List returns all share links of an insight.
+func (s *ShareService) List(ctx context.Context, insightID uint) ([]models.ShareLinkResponse, error) {
+	links, err := s.repo.ListByInsight(ctx, insightID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]models.ShareLinkResponse, 0, len(links))
+	for i := range links {
+		responses = append(responses, *s.toResponse(&links[i]))
+	}
+	return responses, nil
+}
+
+// Update changes a share link's label, content selection, password or limits.
+func (s *ShareService) Update(ctx context.Context, insightID, linkID uint, req *models.UpdateShareLinkRequest) (*models.ShareLinkResponse, error) {
+	link, err := s.load(ctx, insightID, linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Label != nil {
+		link.Label = strings.TrimSpace(*req.Label)
+	}
+
+	config := s.config(link)
+	setBool := func(dst *bool, src *bool) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	setBool(&config.IncludeSummary, req.IncludeSummary)
+	setBool(&config.IncludeKeyPoints, req.IncludeKeyPoints)
+	setBool(&config.IncludeHighlights, req.IncludeHighlights)
+	setBool(&config.IncludeChat, req.IncludeChat)
+	setBool(&config.AllowComments, req.AllowComments)
+	if link.Config, err = json.Marshal(config); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case req.ClearPassword:
+		link.PasswordHash = ""
+	case req.Password != nil:
+		if link.PasswordHash, err = hashSharePassword(*req.Password); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case req.ClearExpiry:
+		link.ExpiresAt = nil
+	case req.ExpiresAt != nil:
+		link.ExpiresAt = req.ExpiresAt
+	}
+	switch {
+	case req.ClearMaxViews:
+		link.MaxViews = nil
+	case req.MaxViews != nil:
+		link.MaxViews = req.MaxViews
+	}
+
+	if err := s.repo.Update(ctx, link); err != nil {
+		return nil, err
+	}
+	return s.toResponse(link), nil
+}
+
+// Revoke permanently disables a share link.
+func (s *ShareService) Revoke(ctx context.Context, insightID, linkID uint) error {
+	link, err := s.load(ctx, insightID, linkID)
+	if err != nil {
+		return err
+	}
+	if link.RevokedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	link.RevokedAt = &now
+	return s.repo.Update(ctx, link)
+}
+
+// RevokeAll disables every share link of an insight.
+func (s *ShareService) RevokeAll(ctx context.Context, insightID uint) error {
+	return s.repo.RevokeAllForInsight(ctx, insightID)
+}
+
+// Stats returns the view analytics of a share link.
+func (s *ShareService) Stats(ctx context.Context, insightID, linkID uint) (*models.ShareLinkStats, error) {
+	link, err := s.load(ctx, insightID, linkID)
+	if err != nil {
+		return nil, err
+	}
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -(shareStatsDays - 1))
+	return s.repo.Stats(ctx, link.ID, since)
+}
+
+// ShareLegacy creates or updates the single share link managed by the legacy share
+// endpoint. The link is the one the insight's share token points at; while it is active
+// its content selection and password are updated in place so the URL stays the same,
+// otherwise a new link replaces it. created reports whether a new link was made.
+func (s *ShareService) ShareLegacy(ctx context.Context, userID uint, insight *models.Insight, req *models.ShareInsightRequest) (link *models.ShareLinkResponse, created bool, err error) {
+	current, err := s.legacyLink(ctx, insight)
+	if err != nil {
+		return nil, false, err
+	}
+	if current != nil && current.Status(time.Now()) == models.ShareLinkActive {
+		config := s.config(current)
+		config.IncludeSummary = req.IncludeSummary
+		config.IncludeKeyPoints = req.IncludeKeyPoints
+		config.IncludeHighlights = req.IncludeHighlights
+		config.IncludeChat = req.IncludeChat
+		if current.Config, err = json.Marshal(config); err != nil {
+			return nil, false, err
+		}
+		current.PasswordHash = ""
+		if req.Password != "" {
+			if current.PasswordHash, err = hashSharePassword(req.Password); err != nil {
+				return nil, false, err
+			}
+		}
+		if err := s.repo.Update(ctx, current); err != nil {
+			return nil, false, err
+		}
+		return s.toResponse(current), false, nil
+	}
+
+	link, err = s.Create(ctx, userID, insight.ID, &models.CreateShareLinkRequest{
+		IncludeSummary:    req.IncludeSummary,
+		IncludeKeyPoints:  req.IncludeKeyPoints,
+		IncludeHighlights: req.IncludeHighlights,
+		IncludeChat:       req.IncludeChat,
+		Password:          req.Password,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.repo.SetLegacyLink(ctx, insight.ID, &link.Token); err != nil {
+		return nil, false, err
+	}
+	return link, true, nil
+}
+
+// RevokeLegacy revokes the insight's legacy share link, if any, and clears it. Links
+// created through the share link API are left alone. It returns the revoked link's ID,
+// or nil if there was none.
+func (s *ShareService) RevokeLegacy(ctx context.Context, insight *models.Insight) (*uint, error) {
+	current, err := s.legacyLink(ctx, insight)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetLegacyLink(ctx, insight.ID, nil); err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, nil
+	}
+	if current.RevokedAt == nil {
+		now := time.Now()
+		current.RevokedAt = &now
+		if err := s.repo.Update(ctx, current); err != nil {
+			return nil, err
+		}
+	}
+	return &current.ID, nil
+}
+
+// MigrateLegacy moves single share tokens stored on insights into share links.
+func (s *ShareService) MigrateLegacy(ctx context.Context) {
+	migrated, err := s.repo.MigrateLegacyShares(ctx)
+	if err != nil {
+		s.log.Error("Failed to migrate legacy share links", zap.Error(err))
+		return
+	}
+	if migrated > 0 {
+		s.log.Info("Migrated legacy share links", zap.Int64("links", migrated))
+	}
+}
+
+// --- Public access ---
+
+// Unlock checks the password of a protected link and returns an access token for it.
+func (s *ShareService) Unlock(ctx context.Context, token, password string) (*models.UnlockShareResponse, error) {
+	link, err := s.activeLink(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if link.PasswordHash != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)); err != nil {
+			return nil, ErrSharePasswordInvalid
+		}
+	}
+
+	access, err := s.signer.Sign(shareAccessPurpose, shareAccess{
+		LinkID:      link.ID,
+		PasswordTag: passwordTag(link.PasswordHash),
+	}, ShareAccessTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &models.UnlockShareResponse{
+		AccessToken: access,
+		ExpiresAt:   time.Now().Add(ShareAccessTTL),
+	}, nil
+}
+
+// ShareVisit describes the request viewing a share link.
+type ShareVisit struct {
+	AccessToken string // From the unlock cookie or X-Share-Access header
+	Referer     string
+	IP          string
+	UserAgent   string
+	Embed       bool // Rendered as an embedded card on another site
+}
+
+// Access returns a viewable link: it exists, is not revoked or expired, and if it has a
+// password, accessToken is a valid unlock token for it.
+func (s *ShareService) Access(ctx context.Context, token, accessToken string) (*models.ShareLink, error) {
+	link, err := s.activeLink(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if link.PasswordHash == "" {
+		return link, nil
+	}
+
+	if accessToken == "" {
+		return nil, ErrSharePasswordRequired
+	}
+	var access shareAccess
+	if err := s.signer.Verify(shareAccessPurpose, accessToken, &access); err != nil ||
+		access.LinkID != link.ID || access.PasswordTag != passwordTag(link.PasswordHash) {
+		return nil, ErrSharePasswordRequired
+	}
+	return link, nil
+}
+
+// Config returns a link's content selection.
+func (s *ShareService) Config(link *models.ShareLink) models.ShareConfigData {
+	return s.config(link)
+}
+
+// View returns the shared content of a link and counts the view. Link unfurlers
+// and embedded cards get its preview instead (see meta).
+func (s *ShareService) View(ctx context.Context, token string, visit ShareVisit) (*models.SharedInsightResponse, error) {
+	link, err := s.Access(ctx, token, visit.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// The content of a link with a view limit is only served to visits that count
+	// against it. Link unfurlers and embedded cards get the title and thumbnail instead,
+	// so they neither use up views nor read the content for free.
+	bot := isLinkPreviewBot(visit.UserAgent)
+	if link.MaxViews != nil && (bot || visit.Embed) {
+		return s.meta(ctx, link)
+	}
+
+	// Link unfurlers fetch the page to build previews; they do not count as views.
+	if !bot {
+		view := &models.ShareLinkView{
+			ShareLinkID: link.ID,
+			RefererHost: refererHost(visit.Referer),
+			Referer:     truncate(visit.Referer, 2000),
+			VisitorHash: s.visitorHash(link.ID, visit.IP, visit.UserAgent),
+			ViewedAt:    time.Now(),
+		}
+		counted, err := s.repo.RecordView(ctx, view)
+		if err != nil {
+			return nil, err
+		}
+		if !counted {
+			return nil, ErrShareLinkExpired
+		}
+	}
+
+	// Previews and cards show no more than the title and summary
+	if bot || visit.Embed {
+		return s.meta(ctx, link)
+	}
+
+	insight, err := s.insightRepo.GetForShare(ctx, link.InsightID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShareLinkNotFound
+		}
+		return nil, err
+	}
+	return s.sharedContent(ctx, link, insight)
+}
+
+// Meta returns what previews of a link show, without counting a view: see meta.
+// Protected links return ErrSharePasswordRequired, so previews never expose their
+// content.
+func (s *ShareService) Meta(ctx context.Context, token string) (*models.SharedInsightResponse, error) {
+	link, err := s.activeLink(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if link.PasswordHash != "" {
+		return nil, ErrSharePasswordRequired
+	}
+	return s.meta(ctx, link)
+}
+
+// activeLink returns a link that exists, is not revoked and has not expired.
+func (s *ShareService) activeLink(ctx context.Context, token string) (*models.ShareLink, error) {
+	link, err := s.repo.GetByToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShareLinkNotFound
+		}
+		return nil, err
+	}
+	switch link.Status(time.Now()) {
+	case models.ShareLinkRevoked:
+		return nil, ErrShareLinkNotFound
+	case models.ShareLinkExpired, models.ShareLinkExhausted:
+		return nil, ErrShareLinkExpired
+	}
+	return link, nil
+}
+
+// meta builds the preview of a shared insight: its title, thumbnail and who shared
+// it, plus its summary and key points if the link shares them and has no view limit.
+// It loads neither highlights nor chat.
+func (s *ShareService) meta(ctx context.Context, link *models.ShareLink) (*models.SharedInsightResponse, error) {
+	insight, err := s.insightRepo.GetShareMeta(ctx, link.InsightID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShareLinkNotFound
+		}
+		return nil, err
+	}
+	response := s.sharedMeta(ctx, link, insight)
+	if link.MaxViews == nil {
+		s.sharedSummary(response, s.config(link), insight)
+	}
+	return response, nil
+}
+
+// sharedMeta builds the public view of an insight without any of its content.
+func (s *ShareService) sharedMeta(ctx context.Context, link *models.ShareLink, insight *models.Insight) *models.SharedInsightResponse {
+	sharedBy, err := s.repo.GetCreatorName(ctx, link.CreatedBy)
+	if err != nil || sharedBy == "" {
+		sharedBy = "用户"
+	}
+	sharedAt := link.CreatedAt
+
+	return &models.SharedInsightResponse{
+		Title:        insight.Title,
+		Author:       insight.Author,
+		ThumbnailURL: insight.ThumbnailURL,
+		SharedBy:     sharedBy,
+		SharedAt:     &sharedAt,
+		ExpiresAt:    link.ExpiresAt,
+		SourceType:   insight.SourceType,
+		SourceURL:    insight.SourceURL,
+		Content:      models.SharedContent{},
+	}
+}
+
+// sharedContent builds the public view of an insight filtered by the link's config.
+func (s *ShareService) sharedContent(ctx context.Context, link *models.ShareLink, insight *models.Insight) (*models.SharedInsightResponse, error) {
+	config := s.config(link)
+	response := s.sharedMeta(ctx, link, insight)
+	response.AllowComments = config.AllowComments
+
+	s.sharedSummary(response, config, insight)
+	if config.IncludeHighlights && len(insight.Highlights) > 0 {
+		response.Content.Highlights = insight.Highlights
+	}
+	if config.IncludeChat {
+		messages, err := s.insightRepo.GetChatMessagesByInsightID(ctx, insight.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(messages) > 0 {
+			response.Content.Chat = messages
+		}
+	}
+
+	return response, nil
+}
+
+// sharedSummary adds the summary and key points the link's config shares.
+func (s *ShareService) sharedSummary(response *models.SharedInsightResponse, config models.ShareConfigData, insight *models.Insight) {
+	if config.IncludeSummary {
+		response.Content.Summary = insight.Summary
+	}
+	if config.IncludeKeyPoints && len(insight.KeyPoints) > 0 {
+		var keyPoints []string
+		if err := json.Unmarshal(insight.KeyPoints, &keyPoints); err != nil {
+			s.log.Warn("Failed to unmarshal key_points", zap.Error(err))
+		} else {
+			response.Content.KeyPoints = keyPoints
+		}
+	}
+}
+
+// --- Helpers ---
+
+// load returns a link of the given insight.
+func (s *ShareService) load(ctx context.Context, insightID, linkID uint) (*models.ShareLink, error) {
+	link, err := s.repo.GetByID(ctx, linkID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShareLinkNotFound
+		}
+		return nil, err
+	}
+	if link.InsightID != insightID {
+		return nil, ErrShareLinkNotFound
+	}
+	return link, nil
+}
+
+// legacyLink returns the link the insight's legacy share token points at, or nil.
+func (s *ShareService) legacyLink(ctx context.Context, insight *models.Insight) (*models.ShareLink, error) {
+	if insight.ShareToken == nil || *insight.ShareToken == "" {
+		return nil, nil
+	}
+	link, err := s.repo.GetByToken(ctx, *insight.ShareToken)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if link.InsightID != insight.ID {
+		return nil, nil
+	}
+	return link, nil
+}
+
+// config returns a link's content selection, falling back to the default.
+func (s *ShareService) config(link *models.ShareLink) models.ShareConfigData {
+	if len(link.Config) == 0 {
+		return defaultShareConfig
+	}
+	var config models.ShareConfigData
+	if err := json.Unmarshal(link.Config, &config); err != nil {
+		s.log.Warn("Failed to unmarshal share config, using defaults", zap.Uint("share_link_id", link.ID), zap.Error(err))
+		return defaultShareConfig
+	}
+	return config
+}
+
+// toResponse converts a link to its API representation.
+func (s *ShareService) toResponse(link *models.ShareLink) *models.ShareLinkResponse {
+	return &models.ShareLinkResponse{
+		ID:           link.ID,
+		InsightID:    link.InsightID,
+		Token:        link.Token,
+		ShareURL:     "/api/v1/shared/" + link.Token,
+		PageURL:      "/s/" + link.Token,
+		Label:        link.Label,
+		Config:       s.config(link),
+		HasPassword:  link.PasswordHash != "",
+		ExpiresAt:    link.ExpiresAt,
+		MaxViews:     link.MaxViews,
+		ViewCount:    link.ViewCount,
+		LastViewedAt: link.LastViewedAt,
+		RevokedAt:    link.RevokedAt,
+		Status:       link.Status(time.Now()),
+		CreatedBy:    link.CreatedBy,
+		CreatedAt:    link.CreatedAt,
+	}
+}
+
+// visitorHash identifies a visitor for unique-visitor counts without storing their IP.
+func (s *ShareService) visitorHash(linkID uint, ip, userAgent string) string {
+	sum := sha256.Sum256([]byte(s.signer.mac("share_visitor|" + ip + "|" + userAgent)))
+	return hex.EncodeToString(sum[:])
+}
+
+// linkPreviewBots are user agent fragments of crawlers that unfurl pasted links.
+var linkPreviewBots = []string{
+	"slackbot", "twitterbot", "facebookexternalhit", "linkedinbot", "discordbot",
+	"telegrambot", "whatsapp", "skypeuripreview", "embedly", "iframely",
+}
+
+// isLinkPreviewBot reports whether a user agent belongs to a link preview crawler.
+func isLinkPreviewBot(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, bot := range linkPreviewBots {
+		if strings.Contains(userAgent, bot) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashSharePassword hashes a share link password.
+func hashSharePassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// passwordTag returns a short fingerprint of a password hash.
+func passwordTag(hash string) string {
+	if hash == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(hash))
+	return hex.EncodeToString(sum[:8])
+}
+
+// refererHost returns the host of a Referer header, or "" for direct visits.
+func refererHost(referer string) string {
+	if referer == "" {
+		return ""
+	}
+	parsed, err := url.Parse(referer)
+	if err != nil {
+		return ""
+	}
+	return truncate(strings.ToLower(parsed.Hostname()), 255)
+}
+
+// randomHexToken returns n random bytes, hex encoded.
+func randomHexToken(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,433 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"vibe-backend/internal/metrics"
+	"vibe-backend/internal/models"
+)
+
+const (
+	// signalingChannel is the Redis pub/sub channel shared by all instances.
+	signalingChannel = "signaling:messages"
+	// signalingPresenceTTL bounds how long presence of an instance that died without
+	// cleaning up lingers in Redis.
+	signalingPresenceTTL = 24 * time.Hour
+	// signalingSendBuffer is how many messages may queue for a peer before it is
+	// disconnected as too slow.
+	signalingSendBuffer = 64
+)
+
+var (
+	ErrInvalidSignal      = errors.New("invalid signaling message")
+	ErrSignalPeerNotFound = errors.New("peer is not connected to the room")
+)
+
+// signalingLeaveScript removes a peer's presence only if it is still registered by
+// the given instance, so a stale connection cannot remove a peer that reconnected
+// to another instance.
+var signalingLeaveScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], ARGV[1]) == ARGV[2] then
+	return redis.call('HDEL', KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// SignalingHub relays WebRTC signaling messages (offers, answers and ICE candidates)
+// between the participants of a room and tells them who joins and leaves. Nothing is
+// stored in Postgres. With Redis, presence is kept in a hash per room and messages
+// for peers on other instances go through pub/sub; otherwise only peers connected to
+// the same instance see each other.
+type SignalingHub struct {
+	redis      *redis.Client
+	instanceID string
+	onMute     func(peer *SignalingPeer, muted bool)
+	log        *zap.Logger
+
+	mu    sync.RWMutex
+	rooms map[string]map[string]*SignalingPeer // Room ID -> participant ID -> peer
+}
+
+// NewSignalingHub creates a new SignalingHub. redisClient may be nil.
+func NewSignalingHub(redisClient *redis.Client, log *zap.Logger) *SignalingHub {
+	return &SignalingHub{
+		redis:      redisClient,
+		instanceID: uuid.New().String(),
+		log:        log,
+		rooms:      make(map[string]map[string]*SignalingPeer),
+	}
+}
+
+// SetMuteHandler sets a function called with the local peer of a participant a
+// moderator mutes or unmutes, e.g. to stop forwarding their audio. Optional.
+func (h *SignalingHub) SetMuteHandler(onMute func(peer *SignalingPeer, muted bool)) {
+	h.onMute = onMute
+}
+
+// SignalingPeer is a participant's connection to the hub. The connection writes the
+// messages from Messages until Done is closed, which happens when the peer leaves,
+// reconnects elsewhere or cannot keep up.
+type SignalingPeer struct {
+	RoomID string
+	ID     string
+
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Messages returns the encoded messages to write to the peer.
+func (p *SignalingPeer) Messages() <-chan []byte {
+	return p.send
+}
+
+// Done is closed when the peer's connection should be closed.
+func (p *SignalingPeer) Done() <-chan struct{} {
+	return p.done
+}
+
+// Send queues a message for the peer.
+func (p *SignalingPeer) Send(msg *models.SignalMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	p.enqueue(data)
+}
+
+// enqueue queues an encoded message, disconnecting the peer if its queue is full so a
+// stalled client cannot hold up the room.
+func (p *SignalingPeer) enqueue(data []byte) {
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+	select {
+	case p.send <- data:
+	default:
+		p.close()
+	}
+}
+
+func (p *SignalingPeer) close() {
+	p.closeOnce.Do(func() { close(p.done) })
+}
+
+// signalEnvelope carries a message between instances.
+type signalEnvelope struct {
+	Instance string          `json:"instance"`
+	RoomID   string          `json:"room_id"`
+	To       string          `json:"to,omitempty"`     // Single recipient; everyone in the room if empty
+	Except   string          `json:"except,omitempty"` // Not delivered to this peer
+	Message  json.RawMessage `json:"message"`
+
+	Moderation *models.RoomModerationEvent `json:"moderation,omitempty"` // Carried out by each instance after delivery
+}
+
+// Join connects a participant to a room's signaling. The peer is sent the participants
+// already connected, and they are told it joined. A participant connecting again
+// replaces its previous connection.
+func (h *SignalingHub) Join(ctx context.Context, roomID, participantID string) *SignalingPeer {
+	peer := &SignalingPeer{
+		RoomID: roomID,
+		ID:     participantID,
+		send:   make(chan []byte, signalingSendBuffer),
+		done:   make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	room, ok := h.rooms[roomID]
+	if !ok {
+		room = make(map[string]*SignalingPeer)
+		h.rooms[roomID] = room
+	}
+	previous := room[participantID]
+	room[participantID] = peer
+	local := make([]string, 0, len(room))
+	for id := range room {
+		if id != participantID {
+			local = append(local, id)
+		}
+	}
+	h.mu.Unlock()
+
+	if previous != nil {
+		previous.close()
+	} else {
+		metrics.SignalingConnected(1)
+	}
+
+	peers := h.peers(ctx, roomID, participantID, local)
+	if h.redis != nil {
+		key := signalingPresenceKey(roomID)
+		_, err := h.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, key, participantID, h.instanceID)
+			pipe.Expire(ctx, key, signalingPresenceTTL)
+			return nil
+		})
+		if err != nil {
+			h.log.Warn("Failed to record signaling presence", zap.String("room_id", roomID), zap.Error(err))
+		}
+	}
+
+	peer.Send(&models.SignalMessage{Type: models.SignalPeers, Peers: peers})
+	h.broadcast(ctx, roomID, participantID, &models.SignalMessage{Type: models.SignalPeerJoined, From: participantID})
+	return peer
+}
+
+// Leave disconnects a peer and tells the rest of the room, unless the participant has
+// reconnected in the meantime.
+func (h *SignalingHub) Leave(ctx context.Context, peer *SignalingPeer) {
+	peer.close()
+
+	h.mu.Lock()
+	room := h.rooms[peer.RoomID]
+	if room[peer.ID] != peer {
+		h.mu.Unlock()
+		return
+	}
+	delete(room, peer.ID)
+	if len(room) == 0 {
+		delete(h.rooms, peer.RoomID)
+	}
+	h.mu.Unlock()
+	metrics.SignalingConnected(-1)
+
+	if h.redis != nil {
+		removed, err := signalingLeaveScript.Run(ctx, h.redis, []string{signalingPresenceKey(peer.RoomID)}, peer.ID, h.instanceID).Int()
+		if err != nil {
+			h.log.Warn("Failed to remove signaling presence", zap.String("room_id", peer.RoomID), zap.Error(err))
+		} else if removed == 0 {
+			return
+		}
+	}
+
+	h.broadcast(ctx, peer.RoomID, peer.ID, &models.SignalMessage{Type: models.SignalPeerLeft, From: peer.ID})
+}
+
+// Relay forwards an offer, answer or ICE candidate from a peer to the participant
+// named in msg.To.
+func (h *SignalingHub) Relay(ctx context.Context, from *SignalingPeer, msg *models.SignalMessage) error {
+	switch msg.Type {
+	case models.SignalOffer, models.SignalAnswer, models.SignalCandidate:
+	default:
+		return ErrInvalidSignal
+	}
+	if msg.To == "" || msg.To == from.ID || len(msg.Payload) == 0 {
+		return ErrInvalidSignal
+	}
+
+	data, err := json.Marshal(&models.SignalMessage{
+		Type:    msg.Type,
+		From:    from.ID,
+		To:      msg.To,
+		Payload: msg.Payload,
+	})
+	if err != nil {
+		return err
+	}
+	if h.deliver(from.RoomID, msg.To, "", data) {
+		return nil
+	}
+	if h.redis == nil {
+		return ErrSignalPeerNotFound
+	}
+
+	connected, err := h.redis.HExists(ctx, signalingPresenceKey(from.RoomID), msg.To).Result()
+	if err != nil {
+		return err
+	}
+	if !connected {
+		return ErrSignalPeerNotFound
+	}
+	return h.publish(ctx, &signalEnvelope{RoomID: from.RoomID, To: msg.To, Message: data})
+}
+
+// Notify sends a server message to one participant of a room, on whichever instance
+// they are connected to. Participants not connected are skipped.
+func (h *SignalingHub) Notify(ctx context.Context, roomID, participantID string, msg *models.SignalMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	if h.deliver(roomID, participantID, "", data) || h.redis == nil {
+		return
+	}
+	if err := h.publish(ctx, &signalEnvelope{RoomID: roomID, To: participantID, Message: data}); err != nil {
+		h.log.Warn("Failed to publish signaling message", zap.String("room_id", roomID), zap.Error(err))
+	}
+}
+
+// Moderate tells everyone in a room about a moderator's action and carries it out on
+// every instance: kicked participants, or everyone when the room ends, are
+// disconnected once told, and mutes are passed to the mute handler.
+func (h *SignalingHub) Moderate(ctx context.Context, roomID string, event *models.RoomModerationEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(&models.SignalMessage{Type: models.SignalModeration, From: event.By, Payload: payload})
+	if err != nil {
+		return
+	}
+	h.moderate(roomID, event, data)
+	if h.redis != nil {
+		if err := h.publish(ctx, &signalEnvelope{RoomID: roomID, Message: data, Moderation: event}); err != nil {
+			h.log.Warn("Failed to publish moderation", zap.String("room_id", roomID), zap.Error(err))
+		}
+	}
+}
+
+// moderate delivers a moderation message to the peers connected to this instance and
+// carries out the action on them.
+func (h *SignalingHub) moderate(roomID string, event *models.RoomModerationEvent, data []byte) {
+	h.deliver(roomID, "", "", data)
+
+	h.mu.RLock()
+	room := h.rooms[roomID]
+	target := room[event.Target]
+	var all []*SignalingPeer
+	if event.Action == models.RoomModerationEnd {
+		all = make([]*SignalingPeer, 0, len(room))
+		for _, peer := range room {
+			all = append(all, peer)
+		}
+	}
+	h.mu.RUnlock()
+
+	switch event.Action {
+	case models.RoomModerationKick:
+		if target != nil {
+			target.close()
+		}
+	case models.RoomModerationEnd:
+		for _, peer := range all {
+			peer.close()
+		}
+	case models.RoomModerationMute, models.RoomModerationUnmute:
+		if target != nil && h.onMute != nil {
+			h.onMute(target, event.Action == models.RoomModerationMute)
+		}
+	}
+}
+
+// Run delivers messages published by other instances to the peers connected to this
+// one until ctx is cancelled. Without Redis it returns immediately.
+func (h *SignalingHub) Run(ctx context.Context) {
+	if h.redis == nil {
+		return
+	}
+
+	sub := h.redis.Subscribe(ctx, signalingChannel)
+	defer sub.Close()
+
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-messages:
+			if !ok {
+				return
+			}
+			var env signalEnvelope
+			if err := json.Unmarshal([]byte(m.Payload), &env); err != nil {
+				h.log.Warn("Dropping malformed signaling message", zap.Error(err))
+				continue
+			}
+			if env.Instance == h.instanceID {
+				continue
+			}
+			if env.Moderation != nil {
+				h.moderate(env.RoomID, env.Moderation, env.Message)
+				continue
+			}
+			h.deliver(env.RoomID, env.To, env.Except, env.Message)
+		}
+	}
+}
+
+// broadcast sends a message to everyone in a room except one peer, on all instances.
+func (h *SignalingHub) broadcast(ctx context.Context, roomID, except string, msg *models.SignalMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	h.deliver(roomID, "", except, data)
+	if h.redis != nil {
+		if err := h.publish(ctx, &signalEnvelope{RoomID: roomID, Except: except, Message: data}); err != nil {
+			h.log.Warn("Failed to publish signaling message", zap.String("room_id", roomID), zap.Error(err))
+		}
+	}
+}
+
+// deliver queues a message for the peers of a room connected to this instance: only
+// to if set, otherwise everyone but except. It reports whether anyone received it.
+func (h *SignalingHub) deliver(roomID, to, except string, data []byte) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	room := h.rooms[roomID]
+	if to != "" {
+		peer, ok := room[to]
+		if ok {
+			peer.enqueue(data)
+		}
+		return ok
+	}
+	delivered := false
+	for id, peer := range room {
+		if id != except {
+			peer.enqueue(data)
+			delivered = true
+		}
+	}
+	return delivered
+}
+
+func (h *SignalingHub) publish(ctx context.Context, env *signalEnvelope) error {
+	env.Instance = h.instanceID
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return h.redis.Publish(ctx, signalingChannel, data).Err()
+}
+
+// peers returns the participants connected to a room other than self, on any instance.
+func (h *SignalingHub) peers(ctx context.Context, roomID, self string, local []string) []string {
+	seen := make(map[string]bool, len(local))
+	for _, id := range local {
+		seen[id] = true
+	}
+	if h.redis != nil {
+		remote, err := h.redis.HKeys(ctx, signalingPresenceKey(roomID)).Result()
+		if err != nil {
+			h.log.Warn("Failed to load signaling presence", zap.String("room_id", roomID), zap.Error(err))
+		}
+		for _, id := range remote {
+			seen[id] = true
+		}
+	}
+	delete(seen, self)
+
+	peers := make([]string, 0, len(seen))
+	for id := range seen {
+		peers = append(peers, id)
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+func signalingPresenceKey(roomID string) string {
+	return "signaling:room:" + roomID
+}
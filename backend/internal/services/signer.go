@@ -0,0 +1,105 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignedToken is returned when a signed token is malformed, tampered with or expired.
+var ErrInvalidSignedToken = errors.New("invalid or expired token")
+
+// Signer issues and verifies short-lived HMAC-signed tokens carrying a JSON payload.
+// Tokens are bound to a purpose so a token issued for one flow cannot be replayed in another.
+type Signer struct {
+	secret []byte
+}
+
+// signedEnvelope is the payload wrapper inside a signed token.
+type signedEnvelope struct {
+	Purpose   string          `json:"p"`
+	ExpiresAt int64           `json:"e"`
+	Data      json.RawMessage `json:"d"`
+}
+
+// NewSigner creates a new Signer. If secret is empty a random per-process secret is used,
+// which means tokens do not survive restarts and are not shared across replicas.
+func NewSigner(secret string) (*Signer, bool, error) {
+	if secret != "" {
+		return &Signer{secret: []byte(secret)}, false, nil
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, false, fmt.Errorf("failed to generate signing secret: %w", err)
+	}
+	return &Signer{secret: random}, true, nil
+}
+
+// Sign encodes data into a token valid for ttl.
+func (s *Signer) Sign(purpose string, data interface{}, ttl time.Duration) (string, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal token data: %w", err)
+	}
+	payload, err := json.Marshal(signedEnvelope{
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+		Data:      raw,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal token: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.mac(encoded), nil
+}
+
+// Verify checks the token signature, purpose and expiry, and decodes its data into out.
+func (s *Signer) Verify(purpose, token string, out interface{}) error {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.mac(encoded))) {
+		return ErrInvalidSignedToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidSignedToken
+	}
+	var envelope signedEnvelope
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return ErrInvalidSignedToken
+	}
+	if envelope.Purpose != purpose || time.Now().Unix() > envelope.ExpiresAt {
+		return ErrInvalidSignedToken
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(envelope.Data, out); err != nil {
+			return ErrInvalidSignedToken
+		}
+	}
+	return nil
+}
+
+// mac returns the base64url HMAC-SHA256 of the encoded payload.
+func (s *Signer) mac(encoded string) string {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// randomToken returns a random URL-safe token with n bytes of entropy.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
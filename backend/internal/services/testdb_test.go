@@ -0,0 +1,37 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB returns an in-memory SQLite database with the given models migrated. It
+// goes through the pure-Go driver the Anki importer already links, so tests need no
+// cgo and no Postgres; row locks are ignored.
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	conn, err := sql.Open("sqlite", fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	// One connection, so every query sees the same in-memory database
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(sqlite.Dialector{DriverName: "sqlite", Conn: conn}, &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open gorm: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
@@ -8,8 +8,13 @@ import (
 	"os/exec"
 	"regexp"
 	"strings"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"vibe-backend/internal/metrics"
+	"vibe-backend/internal/telemetry"
 )
 
 // TranscriptService handles YouTube transcript extraction.
@@ -90,6 +95,7 @@ func (s *TranscriptService) GetTranscript(ctx context.Context, input string) (*T
 			zap.String("video_id", videoID),
 			zap.Int("segments", len(segments)),
 		)
+		metrics.SubtitleFetched("method1")
 		return &TranscriptResponse{
 			VideoID:     videoID,
 			Title:       metadata.Title,
@@ -110,6 +116,7 @@ func (s *TranscriptService) GetTranscript(ctx context.Context, input string) (*T
 			zap.String("video_id", videoID),
 			zap.Int("segments", len(segments)),
 		)
+		metrics.SubtitleFetched("method2")
 		return &TranscriptResponse{
 			VideoID:     videoID,
 			Title:       metadata.Title,
@@ -130,6 +137,7 @@ func (s *TranscriptService) GetTranscript(ctx context.Context, input string) (*T
 			zap.String("video_id", videoID),
 			zap.Int("segments", len(segments)),
 		)
+		metrics.SubtitleFetched("method3")
 		return &TranscriptResponse{
 			VideoID:     videoID,
 			Title:       metadata.Title,
@@ -150,6 +158,7 @@ func (s *TranscriptService) GetTranscript(ctx context.Context, input string) (*T
 			zap.String("video_id", videoID),
 			zap.Int("segments", len(segments)),
 		)
+		metrics.SubtitleFetched("method4")
 		return &TranscriptResponse{
 			VideoID:     videoID,
 			Title:       metadata.Title,
@@ -163,9 +172,23 @@ func (s *TranscriptService) GetTranscript(ctx context.Context, input string) (*T
 	s.log.Error("All subtitle extraction methods failed",
 		zap.String("video_id", videoID),
 	)
+	metrics.SubtitleFetched("none")
 	return nil, fmt.Errorf("无法获取该视频的字幕。可能原因：1) 视频没有字幕 2) 字幕功能被禁用 3) 视频不可访问")
 }
 
+// observeYtDlp traces a yt-dlp run of operation. The returned function ends the span
+// and records the run's duration in metrics.
+func observeYtDlp(ctx context.Context, operation string, cmd *exec.Cmd) func(err error) {
+	start := time.Now()
+	_, span := telemetry.Tracer().Start(ctx, "yt-dlp "+operation,
+		trace.WithAttributes(attribute.StringSlice("process.command_args", cmd.Args)),
+	)
+	return func(err error) {
+		metrics.ObserveYtDlp(operation, start, err)
+		telemetry.End(span, err)
+	}
+}
+
 // getVideoMetadata fetches video metadata using yt-dlp.
 func (s *TranscriptService) getVideoMetadata(ctx context.Context, videoID string) (struct {
 	Title    string `json:"title"`
@@ -187,7 +210,9 @@ func (s *TranscriptService) getVideoMetadata(ctx context.Context, videoID string
 		fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID),
 	)
 
+	done := observeYtDlp(ctx, "metadata", cmd)
 	output, err := cmd.Output()
+	done(err)
 	if err != nil {
 		return metadata, fmt.Errorf("yt-dlp metadata extraction failed: %w", err)
 	}
@@ -214,7 +239,10 @@ func (s *TranscriptService) getSubtitlesMethod1(ctx context.Context, videoID str
 		fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID),
 	)
 
-	if err := cmd.Run(); err != nil {
+	done := observeYtDlp(ctx, "subtitles_method1", cmd)
+	err := cmd.Run()
+	done(err)
+	if err != nil {
 		return nil, fmt.Errorf("failed to download json3 subtitle: %w", err)
 	}
 
@@ -293,7 +321,10 @@ func (s *TranscriptService) getSubtitlesMethod2(ctx context.Context, videoID str
 
 		// Capture stderr to check for errors
 		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
+		done := observeYtDlp(ctx, "subtitles_method2", cmd)
+		err := cmd.Run()
+		done(err)
+		if err != nil {
 			s.log.Debug("Failed to download subtitle for language",
 				zap.String("lang", lang),
 				zap.Error(err),
@@ -338,7 +369,9 @@ func (s *TranscriptService) getSubtitlesMethod3(ctx context.Context, videoID str
 		fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID),
 	)
 
+	done := observeYtDlp(ctx, "list_subtitles", listCmd)
 	listOutput, err := listCmd.Output()
+	done(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list subtitles: %w", err)
 	}
@@ -364,7 +397,10 @@ func (s *TranscriptService) getSubtitlesMethod3(ctx context.Context, videoID str
 			fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID),
 		)
 
-		if err := cmd.Run(); err != nil {
+		done := observeYtDlp(ctx, "subtitles_method3", cmd)
+		err := cmd.Run()
+		done(err)
+		if err != nil {
 			continue
 		}
 
@@ -395,7 +431,10 @@ func (s *TranscriptService) getSubtitlesMethod4(ctx context.Context, videoID str
 			fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID),
 		)
 
-		if err := cmd.Run(); err != nil {
+		done := observeYtDlp(ctx, "subtitles_method4", cmd)
+		err := cmd.Run()
+		done(err)
+		if err != nil {
 			continue
 		}
 
@@ -8,8 +8,11 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
+	"vibe-backend/internal/metrics"
 	"vibe-backend/internal/models"
+	"vibe-backend/internal/telemetry"
 
 	"go.uber.org/zap"
 )
@@ -18,6 +21,7 @@ import (
 type TranslationService struct {
 	apiKey string
 	model  string
+	usage  *UsageService
 	log    *zap.Logger
 }
 
@@ -45,6 +49,11 @@ func NewTranslationService(apiKey, model string, log *zap.Logger) *TranslationSe
 	}
 }
 
+// SetUsageService sets the service that meters and budgets LLM calls.
+func (s *TranslationService) SetUsageService(svc *UsageService) {
+	s.usage = svc
+}
+
 // DetectLanguage detects the language of the input text.
 func (s *TranslationService) DetectLanguage(ctx context.Context, text string) (string, error) {
 	// Use OpenRouter API to detect language
@@ -221,10 +230,15 @@ func (s *TranslationService) parseNumberedTranslations(result string, expectedCo
 }
 
 // callOpenRouter makes a request to the OpenRouter API.
-func (s *TranslationService) callOpenRouter(ctx context.Context, request map[string]interface{}) (string, error) {
+func (s *TranslationService) callOpenRouter(ctx context.Context, request map[string]interface{}) (_ string, err error) {
 	if s.apiKey == "" {
 		return "", fmt.Errorf("OpenRouter API key not configured")
 	}
+	if err := s.usage.Check(ctx, models.LLMFeatureTranslation); err != nil {
+		return "", err
+	}
+	start := time.Now()
+	defer func() { metrics.ObserveLLMRequest(string(models.LLMFeatureTranslation), start, err) }()
 
 	jsonData, err := json.Marshal(request)
 	if err != nil {
@@ -241,7 +255,7 @@ func (s *TranslationService) callOpenRouter(ctx context.Context, request map[str
 	req.Header.Set("HTTP-Referer", "https://vibe-engineering-playbook-l8kw.vercel.app")
 	req.Header.Set("X-Title", "Vibe Translation Service")
 
-	client := &http.Client{}
+	client := &http.Client{Transport: telemetry.NewTransport()}
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to send request: %w", err)
@@ -281,11 +295,14 @@ func (s *TranslationService) callOpenRouter(ctx context.Context, request map[str
 		Error *struct {
 			Message string `json:"message"`
 		} `json:"error"`
+		Model string           `json:"model"`
+		Usage *openRouterUsage `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
 		return "", fmt.Errorf("failed to parse response: %w", err)
 	}
+	s.usage.Record(ctx, models.LLMFeatureTranslation, s.model, result.Model, result.Usage)
 
 	if result.Error != nil {
 		return "", fmt.Errorf("OpenRouter API error: %s", result.Error.Message)
@@ -327,7 +344,10 @@ func (s *TranslationService) getLanguageName(code string) string {
 }
 
 // ProcessTranslation processes a translation request and returns the result.
-func (s *TranslationService) ProcessTranslation(ctx context.Context, req *models.TranslateRequest, transcriptService *TranscriptService) (*models.Translation, error) {
+func (s *TranslationService) ProcessTranslation(ctx context.Context, req *models.TranslateRequest, transcriptService *TranscriptService) (_ *models.Translation, err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveJob(metrics.JobTranslation, start, metrics.Outcome(err)) }()
+
 	translation := &models.Translation{
 		TargetLanguage: req.TargetLanguage,
 		EnableDualSubs: req.EnableDualSubs,
@@ -349,7 +369,9 @@ func (s *TranslationService) ProcessTranslation(ctx context.Context, req *models
 		translation.VideoID = videoID
 
 		// Get transcript
+		stageStart := time.Now()
 		transcript, err := transcriptService.GetTranscript(ctx, videoID)
+		metrics.ObserveJobStage(metrics.JobTranslation, "transcript", stageStart, err)
 		if err != nil {
 			translation.Status = "failed"
 			translation.ErrorMessage = err.Error()
@@ -379,7 +401,9 @@ func (s *TranslationService) ProcessTranslation(ctx context.Context, req *models
 	if req.SourceLanguage != "" {
 		sourceLang = req.SourceLanguage
 	} else {
+		stageStart := time.Now()
 		detectedLang, err := s.DetectLanguage(ctx, sourceText)
+		metrics.ObserveJobStage(metrics.JobTranslation, "language_detection", stageStart, err)
 		if err != nil {
 			s.log.Warn("Failed to detect language, proceeding without source language",
 				zap.Error(err),
@@ -391,7 +415,9 @@ func (s *TranslationService) ProcessTranslation(ctx context.Context, req *models
 	translation.SourceLanguage = sourceLang
 
 	// Step 3: Translate text
+	stageStart := time.Now()
 	translated, err := s.TranslateText(ctx, sourceText, sourceLang, req.TargetLanguage)
+	metrics.ObserveJobStage(metrics.JobTranslation, "translation", stageStart, err)
 	if err != nil {
 		translation.Status = "failed"
 		translation.ErrorMessage = err.Error()
@@ -417,7 +443,9 @@ func (s *TranslationService) processWithDualSubtitles(ctx context.Context, trans
 	if req.SourceLanguage != "" {
 		sourceLang = req.SourceLanguage
 	} else if len(texts) > 0 {
+		stageStart := time.Now()
 		detectedLang, err := s.DetectLanguage(ctx, texts[0])
+		metrics.ObserveJobStage(metrics.JobTranslation, "language_detection", stageStart, err)
 		if err != nil {
 			s.log.Warn("Failed to detect language",
 				zap.Error(err),
@@ -429,7 +457,9 @@ func (s *TranslationService) processWithDualSubtitles(ctx context.Context, trans
 	translation.SourceLanguage = sourceLang
 
 	// Translate all segments
+	stageStart := time.Now()
 	translations, err := s.TranslateBatch(ctx, texts, sourceLang, req.TargetLanguage)
+	metrics.ObserveJobStage(metrics.JobTranslation, "translation", stageStart, err)
 	if err != nil {
 		translation.Status = "failed"
 		translation.ErrorMessage = err.Error()
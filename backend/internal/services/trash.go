@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"vibe-backend/internal/metrics"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+	"vibe-backend/internal/telemetry"
+)
+
+const (
+	// trashPurgeInterval is how often insights past the retention period are purged.
+	trashPurgeInterval = time.Hour
+	// trashPurgeBatchSize is how many insights are purged per transaction.
+	trashPurgeBatchSize = 50
+	// trashInterruptedMessage replaces the error of an insight trashed while it was processed.
+	trashInterruptedMessage = "处理在移入回收站时中断，请重新处理"
+)
+
+var ErrTrashItemNotFound = errors.New("insight not found in trash")
+
+// TrashService lists, restores and purges deleted insights. Deleted insights stay in
+// the trash for the retention period and are then purged with everything attached.
+type TrashService struct {
+	insightRepo *repository.InsightRepository
+	workspaces  *WorkspaceService
+	retention   time.Duration
+	log         *zap.Logger
+}
+
+// NewTrashService creates a new TrashService.
+func NewTrashService(insightRepo *repository.InsightRepository, workspaces *WorkspaceService, retention time.Duration, log *zap.Logger) *TrashService {
+	return &TrashService{
+		insightRepo: insightRepo,
+		workspaces:  workspaces,
+		retention:   retention,
+		log:         log,
+	}
+}
+
+// List returns a page of a workspace's trash. Owners see every deleted insight,
+// editors only those they created.
+func (s *TrashService) List(ctx context.Context, user *models.User, workspaceID *uint, page models.PageRequest) (*models.TrashListResponse, error) {
+	workspace, role, err := s.workspaces.Resolve(ctx, user, workspaceID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	items, total, info, err := s.insightRepo.ListTrashPage(ctx, workspace.ID, trashCreatorFilter(role, user.ID), page)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].PurgeAt = items[i].DeletedAt.Add(s.retention)
+	}
+	return &models.TrashListResponse{
+		Items:         items,
+		Total:         total,
+		RetentionDays: int(s.retention / (24 * time.Hour)),
+		PageInfo:      info,
+	}, nil
+}
+
+// Restore takes an insight out of the trash, with its highlights, chat, comments,
+// chapters, flashcards and share links. Same permissions as deleting it. Returns the
+// insight as it was in the trash.
+func (s *TrashService) Restore(ctx context.Context, userID, insightID uint) (*models.Insight, error) {
+	insight, err := s.authorize(ctx, userID, insightID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.insightRepo.Restore(ctx, insightID, trashInterruptedMessage); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTrashItemNotFound
+		}
+		return nil, err
+	}
+	s.log.Info("Insight restored from trash", zap.Uint("user_id", userID), zap.Uint("insight_id", insightID))
+	return insight, nil
+}
+
+// Purge permanently deletes an insight in the trash and returns it as it was.
+func (s *TrashService) Purge(ctx context.Context, userID, insightID uint) (*models.Insight, error) {
+	insight, err := s.authorize(ctx, userID, insightID)
+	if err != nil {
+		return nil, err
+	}
+	purged, err := s.insightRepo.Purge(ctx, []uint{insightID})
+	if err != nil {
+		return nil, err
+	}
+	if purged == 0 {
+		return nil, ErrTrashItemNotFound
+	}
+	s.log.Info("Insight purged from trash", zap.Uint("user_id", userID), zap.Uint("insight_id", insightID))
+	return insight, nil
+}
+
+// Empty permanently deletes every insight in a workspace's trash the user may delete.
+// Returns the workspace and the number of insights purged.
+func (s *TrashService) Empty(ctx context.Context, user *models.User, workspaceID *uint) (*models.Workspace, int64, error) {
+	workspace, role, err := s.workspaces.Resolve(ctx, user, workspaceID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids, err := s.insightRepo.TrashIDs(ctx, workspace.ID, trashCreatorFilter(role, user.ID))
+	if err != nil {
+		return nil, 0, err
+	}
+	purged, err := s.purgeBatches(ctx, ids)
+	if err != nil {
+		return workspace, purged, err
+	}
+	s.log.Info("Trash emptied",
+		zap.Uint("user_id", user.ID),
+		zap.Uint("workspace_id", workspace.ID),
+		zap.Int64("purged", purged),
+	)
+	return workspace, purged, nil
+}
+
+// Run purges insights past the retention period until ctx is cancelled.
+func (s *TrashService) Run(ctx context.Context) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		s.purgeExpired(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpired purges every insight that has been in the trash longer than the retention period.
+func (s *TrashService) purgeExpired(ctx context.Context) {
+	ctx, span := telemetry.StartJob(ctx, "trash.purge")
+	start := time.Now()
+	var (
+		purged int64
+		err    error
+	)
+	defer func() {
+		metrics.ObserveJob(metrics.JobTrashPurge, start, metrics.Outcome(err))
+		telemetry.End(span, err)
+	}()
+
+	before := time.Now().Add(-s.retention)
+	for {
+		var ids []uint
+		ids, err = s.insightRepo.ExpiredTrashIDs(ctx, before, trashPurgeBatchSize)
+		if err != nil {
+			s.log.Error("Failed to list expired trash", zap.Error(err))
+			return
+		}
+		if len(ids) == 0 {
+			break
+		}
+		var n int64
+		n, err = s.insightRepo.Purge(ctx, ids)
+		if err != nil {
+			s.log.Error("Failed to purge expired trash", zap.Error(err))
+			return
+		}
+		purged += n
+		// Everything listed was restored meanwhile; the next sweep picks up the rest
+		if n == 0 {
+			break
+		}
+	}
+	if purged > 0 {
+		s.log.Info("Purged expired trash", zap.Int64("insights", purged))
+	}
+}
+
+// purgeBatches purges ids a batch at a time and returns the number purged.
+func (s *TrashService) purgeBatches(ctx context.Context, ids []uint) (int64, error) {
+	var purged int64
+	for len(ids) > 0 {
+		batch := ids[:min(len(ids), trashPurgeBatchSize)]
+		ids = ids[len(batch):]
+		n, err := s.insightRepo.Purge(ctx, batch)
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+	}
+	return purged, nil
+}
+
+// authorize loads a trashed insight and checks the user may restore or purge it:
+// its creator (editor or above) or a workspace owner.
+func (s *TrashService) authorize(ctx context.Context, userID, insightID uint) (*models.Insight, error) {
+	insight, err := s.insightRepo.GetTrashed(ctx, insightID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTrashItemNotFound
+		}
+		return nil, err
+	}
+	role, err := s.workspaces.InsightRole(ctx, userID, insight)
+	if err != nil {
+		return nil, err
+	}
+	if !CanModifyAnnotation(role, userID, insight.UserID) {
+		return nil, ErrWorkspaceForbidden
+	}
+	return insight, nil
+}
+
+// trashCreatorFilter returns the creator a user's view of the trash is limited to:
+// nobody for owners, themselves otherwise.
+func trashCreatorFilter(role models.WorkspaceRole, userID uint) *uint {
+	if role.Allows(models.WorkspaceRoleOwner) {
+		return nil
+	}
+	return &userID
+}
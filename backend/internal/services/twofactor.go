@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+)
+
+const (
+	// totpPeriod is the RFC 6238 time step.
+	totpPeriod = 30
+	// totpDigits is the number of digits in a TOTP code.
+	totpDigits = 6
+	// totpSkew is how many steps before/after the current one are accepted.
+	totpSkew = 1
+	// recoveryCodeCount is the number of recovery codes issued at once.
+	recoveryCodeCount = 10
+	// loginChallengeTTL is how long a password-verified login may wait for the second factor.
+	loginChallengeTTL = 5 * time.Minute
+	// loginChallengePurpose binds signed challenge tokens to the 2FA login flow.
+	loginChallengePurpose = "2fa-login"
+)
+
+// Two-factor service errors.
+var (
+	ErrTwoFactorNotEnrolled   = errors.New("two-factor enrollment has not been started")
+	ErrTwoFactorAlreadyActive = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled    = errors.New("two-factor authentication is not enabled")
+	ErrInvalidTwoFactorCode   = errors.New("invalid two-factor code")
+	ErrInvalidChallenge       = errors.New("invalid or expired login challenge")
+)
+
+// base32NoPad is the encoding used for TOTP secrets in otpauth URIs.
+var base32NoPad = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// loginChallenge is the payload of a signed 2FA login challenge.
+type loginChallenge struct {
+	UserID uint `json:"uid"`
+}
+
+// TwoFactorService implements TOTP enrollment, verification and recovery codes.
+type TwoFactorService struct {
+	userRepo *repository.UserRepository
+	signer   *Signer
+	issuer   string
+	log      *zap.Logger
+}
+
+// NewTwoFactorService creates a new TwoFactorService. issuer is shown in authenticator apps.
+func NewTwoFactorService(userRepo *repository.UserRepository, signer *Signer, issuer string, log *zap.Logger) *TwoFactorService {
+	return &TwoFactorService{
+		userRepo: userRepo,
+		signer:   signer,
+		issuer:   issuer,
+		log:      log,
+	}
+}
+
+// Enroll generates a new pending secret and returns it with its otpauth URI.
+func (s *TwoFactorService) Enroll(ctx context.Context, user *models.User) (*models.TwoFactorEnrollResponse, error) {
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyActive
+	}
+
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+	secret := base32NoPad.EncodeToString(raw)
+
+	if err := s.userRepo.SetTOTPSecret(ctx, user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: s.otpauthURI(user.Email, secret),
+	}, nil
+}
+
+// Confirm verifies the first code from the authenticator, enables 2FA and issues recovery codes.
+func (s *TwoFactorService) Confirm(ctx context.Context, user *models.User, code string) ([]string, error) {
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyActive
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := validateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.EnableTOTP(ctx, user.ID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Two-factor authentication enabled", zap.Uint("user_id", user.ID))
+	return codes, nil
+}
+
+// Disable turns off 2FA after verifying a current TOTP or recovery code.
+func (s *TwoFactorService) Disable(ctx context.Context, user *models.User, code string) error {
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if err := s.VerifyCode(ctx, user, code); err != nil {
+		return err
+	}
+	if err := s.userRepo.DisableTOTP(ctx, user.ID); err != nil {
+		return err
+	}
+
+	s.log.Info("Two-factor authentication disabled", zap.Uint("user_id", user.ID))
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after verifying a TOTP code.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, user *models.User, code string) ([]string, error) {
+	if !user.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.verifyTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Status returns the 2FA state for a user.
+func (s *TwoFactorService) Status(ctx context.Context, user *models.User) (*models.TwoFactorStatusResponse, error) {
+	remaining, err := s.userRepo.CountUnusedRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorStatusResponse{
+		Enabled:                user.TOTPEnabled,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// IssueChallenge returns a signed challenge for a user whose password was verified.
+func (s *TwoFactorService) IssueChallenge(user *models.User) (*models.TwoFactorChallengeResponse, error) {
+	challenge, err := s.signer.Sign(loginChallengePurpose, loginChallenge{UserID: user.ID}, loginChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		Challenge:         challenge,
+		ExpiresIn:         int(loginChallengeTTL.Seconds()),
+	}, nil
+}
+
+// CompleteChallenge verifies a challenge and second factor and returns the user.
+func (s *TwoFactorService) CompleteChallenge(ctx context.Context, challenge, code string) (*models.User, error) {
+	var payload loginChallenge
+	if err := s.signer.Verify(loginChallengePurpose, challenge, &payload); err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	user, err := s.userRepo.GetByID(ctx, payload.UserID)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.VerifyCode(ctx, user, code); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// VerifyCode accepts either a TOTP code or an unused recovery code.
+func (s *TwoFactorService) VerifyCode(ctx context.Context, user *models.User, code string) error {
+	normalized := normalizeCode(code)
+	if len(normalized) == totpDigits && isDigits(normalized) {
+		return s.verifyTOTP(ctx, user, normalized)
+	}
+
+	used, err := s.userRepo.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(normalized))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+
+	s.log.Info("Recovery code used", zap.Uint("user_id", user.ID))
+	return nil
+}
+
+// verifyTOTP validates a TOTP code and records its time step to prevent replay.
+func (s *TwoFactorService) verifyTOTP(ctx context.Context, user *models.User, code string) error {
+	step, ok := validateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	fresh, err := s.userRepo.AdvanceTOTPStep(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// otpauthURI builds the Key URI understood by authenticator apps.
+func (s *TwoFactorService) otpauthURI(account, secret string) string {
+	label := url.PathEscape(s.issuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", s.issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// validateTOTP checks code against the steps around now and returns the matching step.
+func validateTOTP(secret, code string, now time.Time) (int64, bool) {
+	key, err := base32NoPad.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+	code = normalizeCode(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for i := -totpSkew; i <= totpSkew; i++ {
+		step := current + int64(i)
+		if hmac.Equal([]byte(totpCode(key, step)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode computes the RFC 4226 HOTP value for a counter.
+func totpCode(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// generateRecoveryCodes returns plaintext codes (xxxxx-xxxxx) and their hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		encoded := strings.ToLower(base32NoPad.EncodeToString(raw))[:10]
+		codes[i] = encoded[:5] + "-" + encoded[5:]
+		hashes[i] = hashRecoveryCode(normalizeCode(codes[i]))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes a normalized recovery code. Codes carry 50 bits of
+// entropy, so a fast hash is sufficient.
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeCode strips separators and whitespace and lowercases a code.
+func normalizeCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// isDigits reports whether s consists only of ASCII digits.
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
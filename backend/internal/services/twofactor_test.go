@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+)
+
+// testTOTPSecret is the RFC 6238 SHA-1 test key "12345678901234567890" in base32.
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	key, err := base32NoPad.DecodeString(testTOTPSecret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	// RFC 6238 appendix B, truncated to six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	key, _ := base32NoPad.DecodeString(testTOTPSecret)
+	now := time.Unix(1111111109, 0)
+	current := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", secret: testTOTPSecret, code: "081804", wantStep: current, wantOK: true},
+		{name: "previous step", secret: testTOTPSecret, code: totpCode(key, current-1), wantStep: current - 1, wantOK: true},
+		{name: "next step", secret: testTOTPSecret, code: totpCode(key, current+1), wantStep: current + 1, wantOK: true},
+		{name: "two steps old", secret: testTOTPSecret, code: totpCode(key, current-2)},
+		{name: "two steps ahead", secret: testTOTPSecret, code: totpCode(key, current+2)},
+		{name: "lowercase secret", secret: "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code: "081804", wantStep: current, wantOK: true},
+		{name: "code with separators", secret: testTOTPSecret, code: " 081-804 ", wantStep: current, wantOK: true},
+		{name: "wrong code", secret: testTOTPSecret, code: "123456"},
+		{name: "too short", secret: testTOTPSecret, code: "81804"},
+		{name: "too long", secret: testTOTPSecret, code: "0818040"},
+		{name: "invalid secret", secret: "not base32!", code: "081804"},
+		{name: "no secret", secret: "", code: "081804"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := validateTOTP(tt.secret, tt.code, now)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("validateTOTP = %d, %v; want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestAdvanceTOTPStep(t *testing.T) {
+	ctx := context.Background()
+	users := repository.NewUserRepository(newTestDB(t, &models.User{}))
+	user := &models.User{Email: "alice@example.com", Name: "alice", Password: "password"}
+	if err := users.Create(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// Each step is accepted once, and never after a later one
+	steps := []struct {
+		step int64
+		want bool
+	}{
+		{100, true},
+		{100, false},
+		{99, false},
+		{101, true},
+		{103, true},
+		{102, false},
+	}
+	for _, s := range steps {
+		fresh, err := users.AdvanceTOTPStep(ctx, user.ID, s.step)
+		if err != nil {
+			t.Fatalf("advance to %d: %v", s.step, err)
+		}
+		if fresh != s.want {
+			t.Errorf("advance to %d = %v, want %v", s.step, fresh, s.want)
+		}
+	}
+}
+
+func TestTwoFactorVerifyCode(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t, &models.User{}, &models.RecoveryCode{})
+	users := repository.NewUserRepository(db)
+	signer, _, err := NewSigner("test-secret")
+	if err != nil {
+		t.Fatalf("new signer: %v", err)
+	}
+	twoFactor := NewTwoFactorService(users, signer, "Vibe", zap.NewNop())
+
+	user := &models.User{Email: "alice@example.com", Name: "alice", Password: "password", TOTPSecret: testTOTPSecret, TOTPEnabled: true}
+	if err := users.Create(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("generate recovery codes: %v", err)
+	}
+	if err := users.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		t.Fatalf("store recovery codes: %v", err)
+	}
+
+	key, _ := base32NoPad.DecodeString(testTOTPSecret)
+	current := time.Now().Unix() / totpPeriod
+	steps := []struct {
+		name    string
+		code    string
+		wantErr error
+	}{
+		{name: "current code", code: totpCode(key, current)},
+		{name: "replayed code", code: totpCode(key, current), wantErr: ErrInvalidTwoFactorCode},
+		{name: "earlier code still in the window", code: totpCode(key, current-1), wantErr: ErrInvalidTwoFactorCode},
+		{name: "next code", code: totpCode(key, current+1)},
+		{name: "recovery code", code: codes[0]},
+		{name: "reused recovery code", code: codes[0], wantErr: ErrInvalidTwoFactorCode},
+		{name: "recovery code without separator, uppercase", code: "  " + strings.ToUpper(codes[1][:5]+codes[1][6:])},
+		{name: "unknown recovery code", code: "aaaaa-bbbbb", wantErr: ErrInvalidTwoFactorCode},
+	}
+	// Steps run in order; each depends on what the previous ones used up
+	for _, s := range steps {
+		if err := twoFactor.VerifyCode(ctx, user, s.code); !errors.Is(err, s.wantErr) {
+			t.Errorf("%s: err = %v, want %v", s.name, err, s.wantErr)
+		}
+	}
+}
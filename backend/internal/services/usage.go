@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"vibe-backend/internal/metrics"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+)
+
+// maxUsageDays bounds the range of a usage report.
+const maxUsageDays = 366
+
+// ErrLLMBudgetExceeded is returned instead of calling the LLM once a daily budget is spent.
+var ErrLLMBudgetExceeded = errors.New("daily LLM budget exceeded")
+
+// ErrInvalidUsageRange is returned for usage reports with an empty or too long range.
+var ErrInvalidUsageRange = errors.New("invalid usage date range")
+
+// ModelPrice is the price of a model in USD per million tokens.
+type ModelPrice struct {
+	Prompt     float64
+	Completion float64
+}
+
+// DefaultModelPrices returns the prices of the models this app is configured with
+// by default. LLM_PRICES adds to or overrides them.
+func DefaultModelPrices() map[string]ModelPrice {
+	return map[string]ModelPrice{
+		"google/gemini-3-flash-preview": {Prompt: 0.50, Completion: 3.00},
+		"google/gemini-2.5-flash":       {Prompt: 0.30, Completion: 2.50},
+		"anthropic/claude-3-5-sonnet":   {Prompt: 3.00, Completion: 15.00},
+	}
+}
+
+// ParseModelPrices parses a price table of "prompt/completion" values (USD per
+// million tokens) keyed by model, on top of the defaults. Invalid entries are
+// skipped and reported in the returned error.
+func ParseModelPrices(raw map[string]string) (map[string]ModelPrice, error) {
+	prices := DefaultModelPrices()
+	var invalid []string
+	for model, value := range raw {
+		prompt, completion, ok := strings.Cut(value, "/")
+		p, err1 := strconv.ParseFloat(strings.TrimSpace(prompt), 64)
+		c, err2 := strconv.ParseFloat(strings.TrimSpace(completion), 64)
+		if !ok || err1 != nil || err2 != nil || p < 0 || c < 0 {
+			invalid = append(invalid, model)
+			continue
+		}
+		prices[strings.TrimSpace(model)] = ModelPrice{Prompt: p, Completion: c}
+	}
+	if len(invalid) > 0 {
+		return prices, fmt.Errorf("invalid prices for models: %s", strings.Join(invalid, ", "))
+	}
+	return prices, nil
+}
+
+// UsageBudgets are daily spending limits in USD. A zero limit means no limit.
+type UsageBudgets struct {
+	// UserDaily limits each user's spend per day.
+	UserDaily float64
+	// TotalDaily limits the spend of all users together per day.
+	TotalDaily float64
+	// FeatureDaily limits the spend of all users per feature per day.
+	FeatureDaily map[string]float64
+}
+
+// openRouterUsage is the token usage reported with an OpenRouter completion.
+type openRouterUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// usageUserKey is the context key of the user LLM calls are billed to.
+type usageUserKey struct{}
+
+// WithUsageUser returns a context whose LLM calls are billed to userID.
+func WithUsageUser(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, usageUserKey{}, userID)
+}
+
+// usageUser returns the user LLM calls made with ctx are billed to, if any.
+func usageUser(ctx context.Context) *uint {
+	if userID, ok := ctx.Value(usageUserKey{}).(uint); ok {
+		return &userID
+	}
+	return nil
+}
+
+// UsageService records the tokens and cost of every OpenRouter completion in a
+// ledger and enforces daily budgets. A nil *UsageService only reports tokens to
+// metrics and allows every call, so services work without metering.
+type UsageService struct {
+	repo    *repository.UsageRepository
+	prices  map[string]ModelPrice
+	budgets UsageBudgets
+	log     *zap.Logger
+}
+
+// NewUsageService creates a new UsageService.
+func NewUsageService(repo *repository.UsageRepository, prices map[string]ModelPrice, budgets UsageBudgets, log *zap.Logger) *UsageService {
+	return &UsageService{
+		repo:    repo,
+		prices:  prices,
+		budgets: budgets,
+		log:     log,
+	}
+}
+
+// Check returns ErrLLMBudgetExceeded if the user billed with ctx, all users, or the
+// feature have spent their daily budget. An empty feature checks only the user and
+// total budgets. Ledger errors are logged and do not block the call.
+func (s *UsageService) Check(ctx context.Context, feature models.LLMFeature) error {
+	if s == nil {
+		return nil
+	}
+	today := usageDay(time.Now())
+
+	check := func(limit float64, userID *uint, feature models.LLMFeature, scope string) error {
+		if limit <= 0 {
+			return nil
+		}
+		spent, err := s.repo.CostSince(ctx, today, userID, feature)
+		if err != nil {
+			s.log.Warn("Failed to check LLM budget", zap.String("scope", scope), zap.Error(err))
+			return nil
+		}
+		if spent >= limit {
+			return fmt.Errorf("%w: %s budget of $%.2f spent", ErrLLMBudgetExceeded, scope, limit)
+		}
+		return nil
+	}
+
+	if userID := usageUser(ctx); userID != nil {
+		if err := check(s.budgets.UserDaily, userID, "", "user"); err != nil {
+			return err
+		}
+	}
+	if err := check(s.budgets.TotalDaily, nil, "", "total"); err != nil {
+		return err
+	}
+	if feature != "" {
+		return check(s.budgets.FeatureDaily[string(feature)], nil, feature, string(feature))
+	}
+	return nil
+}
+
+// Record adds a completion to the ledger, billed to the user of ctx. model is the
+// requested model and servedModel the one OpenRouter reports, which may be a dated
+// version or a fallback; it is priced if the price table lists it. Failures are logged.
+func (s *UsageService) Record(ctx context.Context, feature models.LLMFeature, model, servedModel string, usage *openRouterUsage) {
+	if usage == nil {
+		return
+	}
+	recorded := model
+	if servedModel != "" {
+		recorded = servedModel
+	}
+	metrics.AddLLMTokens(string(feature), recorded, usage.PromptTokens, usage.CompletionTokens)
+	if s == nil {
+		return
+	}
+
+	price, ok := s.prices[servedModel]
+	if !ok {
+		price, ok = s.prices[model]
+	}
+	if !ok {
+		s.log.Warn("No price configured for LLM model, recording zero cost", zap.String("model", model))
+	}
+
+	entry := &models.LLMUsage{
+		UserID:           usageUser(ctx),
+		Feature:          feature,
+		Model:            recorded,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CostUSD:          (float64(usage.PromptTokens)*price.Prompt + float64(usage.CompletionTokens)*price.Completion) / 1e6,
+	}
+	// The completion is paid for even if the request that caused it was cancelled
+	if err := s.repo.Create(context.WithoutCancel(ctx), entry); err != nil {
+		s.log.Error("Failed to record LLM usage",
+			zap.String("feature", string(feature)),
+			zap.String("model", recorded),
+			zap.Error(err),
+		)
+	}
+}
+
+// UserSummary returns a user's usage per day and feature in [from, to), and the
+// state of the user's budget today.
+func (s *UsageService) UserSummary(ctx context.Context, userID uint, dates models.DateRange) (*models.UsageSummaryResponse, error) {
+	from, to, err := usageRange(dates)
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.repo.DailyByFeature(ctx, from, to, &userID)
+	if err != nil {
+		return nil, err
+	}
+	spent, err := s.repo.CostSince(ctx, usageDay(time.Now()), &userID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	today := models.UsageBudgetResponse{
+		LimitUSD: s.budgets.UserDaily,
+		SpentUSD: spent,
+	}
+	if s.budgets.UserDaily > 0 {
+		today.RemainingUSD = max(s.budgets.UserDaily-spent, 0)
+		today.Exceeded = spent >= s.budgets.UserDaily
+	}
+
+	return &models.UsageSummaryResponse{
+		From:  from.Format(time.DateOnly),
+		To:    to.AddDate(0, 0, -1).Format(time.DateOnly),
+		Days:  nonNilDays(days),
+		Today: today,
+	}, nil
+}
+
+// DailyByFeature returns all users' usage per day and feature in [from, to).
+func (s *UsageService) DailyByFeature(ctx context.Context, dates models.DateRange) ([]models.UsageDailyAggregate, error) {
+	from, to, err := usageRange(dates)
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.repo.DailyByFeature(ctx, from, to, nil)
+	return nonNilDays(days), err
+}
+
+// DailyByUser returns the usage per day and user in [from, to).
+func (s *UsageService) DailyByUser(ctx context.Context, dates models.DateRange) ([]models.UsageDailyAggregate, error) {
+	from, to, err := usageRange(dates)
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.repo.DailyByUser(ctx, from, to)
+	return nonNilDays(days), err
+}
+
+// usageRange resolves a report range to whole UTC days, defaulting to the last 30
+// days including today.
+func usageRange(dates models.DateRange) (time.Time, time.Time, error) {
+	to := usageDay(time.Now()).AddDate(0, 0, 1)
+	if dates.To != nil {
+		to = usageDay(dates.To.Add(-time.Nanosecond)).AddDate(0, 0, 1)
+	}
+	from := to.AddDate(0, 0, -30)
+	if dates.From != nil {
+		from = usageDay(*dates.From)
+	}
+	if !from.Before(to) || to.Sub(from) > maxUsageDays*24*time.Hour {
+		return time.Time{}, time.Time{}, ErrInvalidUsageRange
+	}
+	return from, to, nil
+}
+
+// usageDay returns the start of t's day in UTC, the day budgets reset.
+func usageDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+func nonNilDays(days []models.UsageDailyAggregate) []models.UsageDailyAggregate {
+	if days == nil {
+		return []models.UsageDailyAggregate{}
+	}
+	return days
+}
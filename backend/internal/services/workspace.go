@@ -0,0 +1,515 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"vibe-backend/internal/models"
+	"vibe-backend/internal/repository"
+)
+
+// invitationTTL is how long a workspace invitation stays valid.
+const invitationTTL = 7 * 24 * time.Hour
+
+// Workspace service errors.
+var (
+	ErrWorkspaceNotFound    = errors.New("workspace not found")
+	ErrWorkspaceForbidden   = errors.New("insufficient workspace permissions")
+	ErrPersonalWorkspace    = errors.New("operation not allowed on a personal workspace")
+	ErrWorkspaceNotEmpty    = errors.New("workspace still contains insights")
+	ErrLastOwner            = errors.New("workspace must keep at least one owner")
+	ErrMemberNotFound       = errors.New("workspace member not found")
+	ErrInvitationNotFound   = errors.New("invitation not found")
+	ErrInvitationInvalid    = errors.New("invitation is invalid or expired")
+	ErrInvitationWrongEmail = errors.New("invitation was sent to a different email address")
+)
+
+// WorkspaceService implements workspaces, memberships, invitations and permission checks.
+//
+// Roles:
+//   - viewer: read insights, highlights and chat
+//   - editor: viewer + add insights, highlights and chat; edit their own annotations
+//   - owner:  editor + manage members and invitations, edit or delete anything
+type WorkspaceService struct {
+	repo        *repository.WorkspaceRepository
+	insightRepo *repository.InsightRepository
+	mailer      Mailer
+	baseURL     string
+	log         *zap.Logger
+}
+
+// NewWorkspaceService creates a new WorkspaceService. mailer may be nil; baseURL is used
+// to build invitation links.
+func NewWorkspaceService(
+	repo *repository.WorkspaceRepository,
+	insightRepo *repository.InsightRepository,
+	mailer Mailer,
+	baseURL string,
+	log *zap.Logger,
+) *WorkspaceService {
+	return &WorkspaceService{
+		repo:        repo,
+		insightRepo: insightRepo,
+		mailer:      mailer,
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		log:         log,
+	}
+}
+
+// EnsurePersonal returns the user's personal workspace, creating it on first use.
+// Insights created before workspaces existed are moved into it.
+func (s *WorkspaceService) EnsurePersonal(ctx context.Context, user *models.User) (*models.Workspace, error) {
+	workspace, err := s.repo.GetPersonal(ctx, user.ID)
+	if err == nil {
+		return workspace, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	workspace = &models.Workspace{
+		Name:     personalWorkspaceName(user),
+		OwnerID:  user.ID,
+		Personal: true,
+	}
+	if err := s.repo.CreateWithOwner(ctx, workspace); err != nil {
+		// A concurrent request may have created it first
+		if existing, getErr := s.repo.GetPersonal(ctx, user.ID); getErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	if err := s.repo.AssignLegacyInsights(ctx, user.ID, workspace.ID); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Personal workspace created", zap.Uint("user_id", user.ID), zap.Uint("workspace_id", workspace.ID))
+	return workspace, nil
+}
+
+// Resolve returns the workspace to use for a request: the given one if the user has
+// at least the needed role, or the personal workspace when id is nil.
+func (s *WorkspaceService) Resolve(ctx context.Context, user *models.User, id *uint, need models.WorkspaceRole) (*models.Workspace, models.WorkspaceRole, error) {
+	if id == nil {
+		workspace, err := s.EnsurePersonal(ctx, user)
+		if err != nil {
+			return nil, "", err
+		}
+		return workspace, models.WorkspaceRoleOwner, nil
+	}
+
+	workspace, err := s.repo.GetByID(ctx, *id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrWorkspaceNotFound
+		}
+		return nil, "", err
+	}
+	role, err := s.Role(ctx, workspace.ID, user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !role.Allows(need) {
+		return nil, "", ErrWorkspaceForbidden
+	}
+	return workspace, role, nil
+}
+
+// Role returns the user's role in a workspace. Non-members get ErrWorkspaceNotFound so
+// workspace IDs cannot be probed.
+func (s *WorkspaceService) Role(ctx context.Context, workspaceID, userID uint) (models.WorkspaceRole, error) {
+	member, err := s.repo.GetMember(ctx, workspaceID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrWorkspaceNotFound
+		}
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// InsightRole returns the user's role for an insight, via its workspace.
+// Legacy insights without a workspace are only visible to their creator.
+func (s *WorkspaceService) InsightRole(ctx context.Context, userID uint, insight *models.Insight) (models.WorkspaceRole, error) {
+	if insight.WorkspaceID == nil {
+		if insight.UserID == userID {
+			return models.WorkspaceRoleOwner, nil
+		}
+		return "", ErrWorkspaceForbidden
+	}
+	role, err := s.Role(ctx, *insight.WorkspaceID, userID)
+	if errors.Is(err, ErrWorkspaceNotFound) {
+		return "", ErrWorkspaceForbidden
+	}
+	return role, err
+}
+
+// AuthorizeInsight loads an insight and checks that the user has at least the needed role.
+// It returns gorm.ErrRecordNotFound if the insight does not exist.
+func (s *WorkspaceService) AuthorizeInsight(ctx context.Context, userID, insightID uint, need models.WorkspaceRole) (*models.Insight, models.WorkspaceRole, error) {
+	insight, err := s.insightRepo.GetByID(ctx, insightID)
+	if err != nil {
+		return nil, "", err
+	}
+	role, err := s.InsightRole(ctx, userID, insight)
+	if err != nil {
+		return nil, "", err
+	}
+	if !role.Allows(need) {
+		return nil, "", ErrWorkspaceForbidden
+	}
+	return insight, role, nil
+}
+
+// CanModifyAnnotation reports whether a user may edit or delete an annotation
+// (highlight, chat message) authored by authorID: its author, or a workspace owner.
+func CanModifyAnnotation(role models.WorkspaceRole, userID, authorID uint) bool {
+	if role.Allows(models.WorkspaceRoleOwner) {
+		return true
+	}
+	return authorID == userID && role.Allows(models.WorkspaceRoleEditor)
+}
+
+// --- Workspace management ---
+
+// List returns the user's workspaces, making sure the personal one exists.
+func (s *WorkspaceService) List(ctx context.Context, user *models.User) ([]models.WorkspaceResponse, error) {
+	if _, err := s.EnsurePersonal(ctx, user); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.ListForUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]models.WorkspaceResponse, len(rows))
+	for i, row := range rows {
+		response[i] = toWorkspaceResponse(&row.Workspace, row.Role)
+	}
+	return response, nil
+}
+
+// Get returns a workspace the user belongs to.
+func (s *WorkspaceService) Get(ctx context.Context, userID, workspaceID uint) (*models.WorkspaceResponse, error) {
+	workspace, role, err := s.load(ctx, userID, workspaceID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	response := toWorkspaceResponse(workspace, role)
+	return &response, nil
+}
+
+// Create creates a team workspace owned by the user.
+func (s *WorkspaceService) Create(ctx context.Context, userID uint, name string) (*models.WorkspaceResponse, error) {
+	workspace := &models.Workspace{
+		Name:    strings.TrimSpace(name),
+		OwnerID: userID,
+	}
+	if err := s.repo.CreateWithOwner(ctx, workspace); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Workspace created", zap.Uint("user_id", userID), zap.Uint("workspace_id", workspace.ID))
+	response := toWorkspaceResponse(workspace, models.WorkspaceRoleOwner)
+	return &response, nil
+}
+
+// Rename changes a workspace's name. Owners only.
+func (s *WorkspaceService) Rename(ctx context.Context, userID, workspaceID uint, name string) (*models.WorkspaceResponse, error) {
+	workspace, role, err := s.load(ctx, userID, workspaceID, models.WorkspaceRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	workspace.Name = strings.TrimSpace(name)
+	if err := s.repo.Update(ctx, workspace); err != nil {
+		return nil, err
+	}
+	response := toWorkspaceResponse(workspace, role)
+	return &response, nil
+}
+
+// Delete deletes an empty team workspace, purging its trash. Owners only.
+func (s *WorkspaceService) Delete(ctx context.Context, userID, workspaceID uint) error {
+	workspace, _, err := s.load(ctx, userID, workspaceID, models.WorkspaceRoleOwner)
+	if err != nil {
+		return err
+	}
+	if workspace.Personal {
+		return ErrPersonalWorkspace
+	}
+	count, err := s.repo.CountInsights(ctx, workspace.ID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrWorkspaceNotEmpty
+	}
+	// Trashed insights could not be restored into a deleted workspace
+	trashed, err := s.insightRepo.TrashIDs(ctx, workspace.ID, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := s.insightRepo.Purge(ctx, trashed); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, workspace.ID); err != nil {
+		return err
+	}
+	s.log.Info("Workspace deleted", zap.Uint("user_id", userID), zap.Uint("workspace_id", workspace.ID))
+	return nil
+}
+
+// --- Members ---
+
+// ListMembers returns a workspace's members.
+func (s *WorkspaceService) ListMembers(ctx context.Context, userID, workspaceID uint) ([]models.WorkspaceMemberResponse, error) {
+	if _, _, err := s.load(ctx, userID, workspaceID, models.WorkspaceRoleViewer); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(ctx, workspaceID)
+}
+
+// UpdateMemberRole changes a member's role. Owners only; the last owner cannot be demoted.
+func (s *WorkspaceService) UpdateMemberRole(ctx context.Context, userID, workspaceID, memberID uint, role models.WorkspaceRole) error {
+	workspace, _, err := s.load(ctx, userID, workspaceID, models.WorkspaceRoleOwner)
+	if err != nil {
+		return err
+	}
+	if workspace.Personal {
+		return ErrPersonalWorkspace
+	}
+
+	member, err := s.repo.GetMember(ctx, workspaceID, memberID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+	if member.Role == models.WorkspaceRoleOwner && role != models.WorkspaceRoleOwner {
+		if err := s.ensureAnotherOwner(ctx, workspaceID); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.UpdateMemberRole(ctx, workspaceID, memberID, role)
+}
+
+// RemoveMember removes a member. Owners may remove anyone; members may remove themselves.
+func (s *WorkspaceService) RemoveMember(ctx context.Context, userID, workspaceID, memberID uint) error {
+	need := models.WorkspaceRoleOwner
+	if memberID == userID {
+		need = models.WorkspaceRoleViewer
+	}
+	workspace, _, err := s.load(ctx, userID, workspaceID, need)
+	if err != nil {
+		return err
+	}
+	if workspace.Personal {
+		return ErrPersonalWorkspace
+	}
+
+	member, err := s.repo.GetMember(ctx, workspaceID, memberID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+	if member.Role == models.WorkspaceRoleOwner {
+		if err := s.ensureAnotherOwner(ctx, workspaceID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.RemoveMember(ctx, workspaceID, memberID); err != nil {
+		return err
+	}
+	s.log.Info("Workspace member removed",
+		zap.Uint("workspace_id", workspaceID),
+		zap.Uint("member_id", memberID),
+		zap.Uint("removed_by", userID),
+	)
+	return nil
+}
+
+// ensureAnotherOwner returns ErrLastOwner unless the workspace has more than one owner.
+func (s *WorkspaceService) ensureAnotherOwner(ctx context.Context, workspaceID uint) error {
+	owners, err := s.repo.CountOwners(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// --- Invitations ---
+
+// Invite creates an invitation and emails it if a mailer is configured. Owners only.
+func (s *WorkspaceService) Invite(ctx context.Context, inviter *models.User, workspaceID uint, email string, role models.WorkspaceRole) (*models.CreateInvitationResponse, error) {
+	workspace, _, err := s.load(ctx, inviter.ID, workspaceID, models.WorkspaceRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if workspace.Personal {
+		return nil, ErrPersonalWorkspace
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	invitation := &models.WorkspaceInvitation{
+		WorkspaceID: workspace.ID,
+		Email:       strings.ToLower(strings.TrimSpace(email)),
+		Role:        role,
+		TokenHash:   hashInvitationToken(token),
+		InvitedBy:   inviter.ID,
+		ExpiresAt:   time.Now().Add(invitationTTL),
+	}
+	if err := s.repo.CreateInvitation(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	acceptURL := s.baseURL + "/invitations/accept?token=" + token
+	if s.mailer != nil {
+		subject := fmt.Sprintf("%s invited you to %s", inviter.Name, workspace.Name)
+		body := fmt.Sprintf("%s invited you to join the workspace \"%s\" as %s.\n\nAccept the invitation:\n%s\n\nThis link expires in 7 days.",
+			inviter.Name, workspace.Name, role, acceptURL)
+		go func(to string) {
+			ctx, cancel := context.WithTimeout(context.Background(), securityNotifyTimeout)
+			defer cancel()
+			if err := s.mailer.Send(ctx, to, subject, body); err != nil {
+				s.log.Warn("Failed to send workspace invitation", zap.Uint("workspace_id", workspace.ID), zap.Error(err))
+			}
+		}(invitation.Email)
+	}
+
+	s.log.Info("Workspace invitation created",
+		zap.Uint("workspace_id", workspace.ID),
+		zap.Uint("invited_by", inviter.ID),
+		zap.String("role", string(role)),
+	)
+	return &models.CreateInvitationResponse{
+		Invitation: *invitation,
+		Token:      token,
+		AcceptURL:  acceptURL,
+	}, nil
+}
+
+// ListInvitations returns pending invitations. Owners only.
+func (s *WorkspaceService) ListInvitations(ctx context.Context, userID, workspaceID uint) ([]models.WorkspaceInvitation, error) {
+	if _, _, err := s.load(ctx, userID, workspaceID, models.WorkspaceRoleOwner); err != nil {
+		return nil, err
+	}
+	return s.repo.ListPendingInvitations(ctx, workspaceID)
+}
+
+// RevokeInvitation deletes a pending invitation. Owners only.
+func (s *WorkspaceService) RevokeInvitation(ctx context.Context, userID, workspaceID, invitationID uint) error {
+	if _, _, err := s.load(ctx, userID, workspaceID, models.WorkspaceRoleOwner); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteInvitation(ctx, workspaceID, invitationID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvitationNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// AcceptInvitation adds the user to the invited workspace. The invitation must have been
+// sent to the user's email address.
+func (s *WorkspaceService) AcceptInvitation(ctx context.Context, user *models.User, token string) (*models.WorkspaceResponse, error) {
+	invitation, err := s.repo.GetInvitationByTokenHash(ctx, hashInvitationToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationInvalid
+		}
+		return nil, err
+	}
+	if invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) {
+		return nil, ErrInvitationInvalid
+	}
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		return nil, ErrInvitationWrongEmail
+	}
+
+	workspace, err := s.repo.GetByID(ctx, invitation.WorkspaceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationInvalid
+		}
+		return nil, err
+	}
+	if err := s.repo.AcceptInvitation(ctx, invitation, user.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationInvalid
+		}
+		return nil, err
+	}
+
+	role, err := s.Role(ctx, workspace.ID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.log.Info("Workspace invitation accepted", zap.Uint("workspace_id", workspace.ID), zap.Uint("user_id", user.ID))
+	response := toWorkspaceResponse(workspace, role)
+	return &response, nil
+}
+
+// load returns a workspace after checking the user has at least the needed role.
+func (s *WorkspaceService) load(ctx context.Context, userID, workspaceID uint, need models.WorkspaceRole) (*models.Workspace, models.WorkspaceRole, error) {
+	role, err := s.Role(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !role.Allows(need) {
+		return nil, "", ErrWorkspaceForbidden
+	}
+	workspace, err := s.repo.GetByID(ctx, workspaceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrWorkspaceNotFound
+		}
+		return nil, "", err
+	}
+	return workspace, role, nil
+}
+
+// personalWorkspaceName returns the display name of a user's personal workspace.
+func personalWorkspaceName(user *models.User) string {
+	if user.Name != "" {
+		return user.Name + "'s Library"
+	}
+	return "Personal Library"
+}
+
+// hashInvitationToken hashes an invitation token for storage.
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// toWorkspaceResponse converts a workspace and role to its API representation.
+func toWorkspaceResponse(workspace *models.Workspace, role models.WorkspaceRole) models.WorkspaceResponse {
+	return models.WorkspaceResponse{
+		ID:        workspace.ID,
+		Name:      workspace.Name,
+		OwnerID:   workspace.OwnerID,
+		Personal:  workspace.Personal,
+		Role:      role,
+		CreatedAt: workspace.CreatedAt,
+	}
+}
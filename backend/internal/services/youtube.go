@@ -19,7 +19,9 @@ import (
 
 	"go.uber.org/zap"
 
+	"vibe-backend/internal/metrics"
 	"vibe-backend/internal/models"
+	"vibe-backend/internal/telemetry"
 )
 
 // YouTubeService handles YouTube video operations using OpenRouter and Gemini.
@@ -28,6 +30,7 @@ type YouTubeService struct {
 	youtubeAPIKey    string // YouTube Data API v3 key
 	geminiModel      string
 	httpClient       *http.Client
+	usage            *UsageService
 	log              *zap.Logger
 }
 
@@ -60,12 +63,18 @@ func NewYouTubeService(apiKey string, geminiModel string, log *zap.Logger) *YouT
 		youtubeAPIKey:    youtubeAPIKey,
 		geminiModel:      geminiModel,
 		httpClient: &http.Client{
-			Timeout: 120 * time.Second,
+			Timeout:   120 * time.Second,
+			Transport: telemetry.NewTransport(),
 		},
 		log: log,
 	}
 }
 
+// SetUsageService sets the service that meters and budgets LLM calls.
+func (s *YouTubeService) SetUsageService(svc *UsageService) {
+	s.usage = svc
+}
+
 // GetVideoMetadataFromAPI fetches video metadata using YouTube Data API v3.
 // This provides accurate video information (title, author, etc.)
 func (s *YouTubeService) GetVideoMetadataFromAPI(ctx context.Context, videoID string) (*VideoMetadata, error) {
@@ -107,6 +116,7 @@ func (s *YouTubeService) GetVideoMetadataFromAPI(ctx context.Context, videoID st
 			Snippet struct {
 				Title        string `json:"title"`
 				ChannelTitle string `json:"channelTitle"`
+				Description  string `json:"description"`
 				Thumbnails   struct {
 					MaxRes struct {
 						URL string `json:"url"`
@@ -156,6 +166,7 @@ func (s *YouTubeService) GetVideoMetadataFromAPI(ctx context.Context, videoID st
 		Author:       item.Snippet.ChannelTitle,
 		ThumbnailURL: thumbnailURL,
 		Duration:     duration,
+		Description:  item.Snippet.Description,
 	}, nil
 }
 
@@ -203,7 +214,9 @@ func (s *YouTubeService) GetVideoMetadataWithYtDlp(ctx context.Context, videoID
 		videoURL,
 	)
 
+	done := observeYtDlp(ctx, "metadata", cmd)
 	output, err := cmd.CombinedOutput()
+	done(err)
 	if err != nil {
 		s.log.Error("yt-dlp metadata extraction failed",
 			zap.String("video_id", videoID),
@@ -214,10 +227,15 @@ func (s *YouTubeService) GetVideoMetadataWithYtDlp(ctx context.Context, videoID
 	}
 
 	var metadata struct {
-		Title     string `json:"title"`
-		Uploader  string `json:"uploader"`
-		Duration  int    `json:"duration"`
-		Thumbnail string `json:"thumbnail"`
+		Title       string `json:"title"`
+		Uploader    string `json:"uploader"`
+		Duration    int    `json:"duration"`
+		Thumbnail   string `json:"thumbnail"`
+		Description string `json:"description"`
+		Chapters    []struct {
+			StartTime float64 `json:"start_time"`
+			Title     string  `json:"title"`
+		} `json:"chapters"`
 	}
 
 	if err := json.Unmarshal(output, &metadata); err != nil {
@@ -234,12 +252,23 @@ func (s *YouTubeService) GetVideoMetadataWithYtDlp(ctx context.Context, videoID
 		zap.String("uploader", metadata.Uploader),
 	)
 
+	chapters := make([]ChapterData, len(metadata.Chapters))
+	for i, chapter := range metadata.Chapters {
+		chapters[i] = ChapterData{
+			Title:     chapter.Title,
+			Timestamp: SecondsToTimestamp(int(chapter.StartTime)),
+			Seconds:   int(chapter.StartTime),
+		}
+	}
+
 	return &VideoMetadata{
 		VideoID:      videoID,
 		Title:        metadata.Title,
 		Author:       metadata.Uploader,
 		ThumbnailURL: metadata.Thumbnail,
 		Duration:     metadata.Duration,
+		Description:  metadata.Description,
+		Chapters:     chapters,
 	}, nil
 }
 
@@ -249,7 +278,9 @@ type VideoMetadata struct {
 	Title        string
 	Author       string
 	ThumbnailURL string
-	Duration     int // in seconds
+	Duration     int           // in seconds
+	Description  string        // Video description, which may list chapters
+	Chapters     []ChapterData // Chapters reported by yt-dlp, if any
 }
 
 // AnalysisResult represents the complete analysis of a video.
@@ -326,7 +357,7 @@ Please provide the response in JSON format with the following structure:
   "duration": duration_in_seconds
 }`, videoURL)
 
-	response, err := s.callGemini(ctx, prompt)
+	response, err := s.callGemini(ctx, models.LLMFeatureVideoMetadata, prompt)
 	if err != nil {
 		s.log.Error("Failed to get video metadata from Gemini", zap.Error(err))
 		// Return error instead of default values, so caller can distinguish between API failures and private videos
@@ -479,7 +510,7 @@ func (s *YouTubeService) AnalyzeVideo(ctx context.Context, videoID, targetLangua
 - 将时间戳转换为秒数
 - 字幕文本使用原始语言`, videoURL)
 
-	response, err := s.callGemini(ctx, prompt)
+	response, err := s.callGemini(ctx, models.LLMFeatureVideoAnalysis, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze video: %w", err)
 	}
@@ -1756,7 +1787,7 @@ IMPORTANT:
 - duration should be a number (in seconds), use 0 if unknown
 - For thumbnail, use the standard YouTube thumbnail URL format`, videoURL)
 
-	response, err := s.callGemini(ctx, prompt)
+	response, err := s.callGemini(ctx, models.LLMFeatureVideoMetadata, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("AI service error: %w", err)
 	}
@@ -1809,11 +1840,16 @@ type VideoMetadataWithAI struct {
 }
 
 // callGemini calls the Gemini API via OpenRouter.
-func (s *YouTubeService) callGemini(ctx context.Context, prompt string) (string, error) {
+func (s *YouTubeService) callGemini(ctx context.Context, feature models.LLMFeature, prompt string) (_ string, err error) {
 	// Check if API key is configured
 	if s.openRouterAPIKey == "" {
 		return "", fmt.Errorf("OPENROUTER_API_KEY environment variable is not set. Please configure it to use video analysis features")
 	}
+	if err := s.usage.Check(ctx, feature); err != nil {
+		return "", err
+	}
+	start := time.Now()
+	defer func() { metrics.ObserveLLMRequest(string(feature), start, err) }()
 
 	const openRouterURL = "https://openrouter.ai/api/v1/chat/completions"
 
@@ -1879,11 +1915,14 @@ func (s *YouTubeService) callGemini(ctx context.Context, prompt string) (string,
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Model string           `json:"model"`
+		Usage *openRouterUsage `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
 		return "", fmt.Errorf("failed to parse API response: %w", err)
 	}
+	s.usage.Record(ctx, feature, s.geminiModel, apiResponse.Model, apiResponse.Usage)
 
 	if len(apiResponse.Choices) == 0 {
 		return "", errors.New("no response from Gemini")
@@ -0,0 +1,12 @@
+-- Drop tables in reverse order of creation
+DROP TABLE IF EXISTS dual_subtitles;
+DROP TABLE IF EXISTS translations;
+DROP TABLE IF EXISTS chat_messages;
+DROP TABLE IF EXISTS highlights;
+DROP TABLE IF EXISTS key_points;
+DROP TABLE IF EXISTS transcriptions;
+DROP TABLE IF EXISTS chapters;
+DROP TABLE IF EXISTS insights;
+DROP TABLE IF EXISTS video_analyses;
+DROP TABLE IF EXISTS pomodoros;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,193 @@
+-- Baseline schema, identical to what GORM AutoMigrate created before versioned
+-- migrations were introduced. IF NOT EXISTS lets databases created by AutoMigrate
+-- adopt it without changes. Tables and columns added since then belong in later
+-- migrations: this one is recorded as applied on such databases without changing
+-- them.
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial,
+    email varchar(255) NOT NULL,
+    password varchar(255) NOT NULL,
+    name varchar(255),
+    api_key varchar(64) NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_api_key ON users (api_key);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS pomodoros (
+    id bigserial,
+    user_id bigint NOT NULL,
+    title varchar(255),
+    start_time timestamptz,
+    end_time timestamptz,
+    duration bigint DEFAULT 25,
+    is_completed boolean DEFAULT false,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_pomodoros_deleted_at ON pomodoros (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_pomodoros_user_id ON pomodoros (user_id);
+
+CREATE TABLE IF NOT EXISTS video_analyses (
+    id bigserial,
+    user_id bigint NOT NULL,
+    video_id varchar(255) NOT NULL,
+    title varchar(500),
+    author varchar(255),
+    thumbnail_url varchar(1000),
+    duration bigint,
+    target_language varchar(10),
+    summary text,
+    status varchar(50) DEFAULT 'pending',
+    job_id varchar(100),
+    error_message text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_video_analyses_deleted_at ON video_analyses (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_video_analyses_job_id ON video_analyses (job_id);
+CREATE INDEX IF NOT EXISTS idx_video_analyses_video_id ON video_analyses (video_id);
+CREATE INDEX IF NOT EXISTS idx_video_analyses_user_id ON video_analyses (user_id);
+
+CREATE TABLE IF NOT EXISTS insights (
+    id bigserial,
+    user_id bigint NOT NULL,
+    source_type varchar(20) NOT NULL,
+    source_url varchar(2000) NOT NULL,
+    source_id varchar(100),
+    title varchar(500),
+    author varchar(255),
+    thumbnail_url varchar(1000),
+    duration bigint,
+    published_at timestamptz,
+    summary text,
+    key_points JSONB,
+    target_lang varchar(10) DEFAULT 'zh',
+    raw_content text,
+    trans_content text,
+    transcripts JSONB,
+    status varchar(20) DEFAULT 'pending',
+    error_message text,
+    share_token varchar(64),
+    share_password varchar(255),
+    share_config JSONB,
+    is_public boolean DEFAULT false,
+    shared_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_insights_deleted_at ON insights (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_insights_share_token ON insights (share_token);
+CREATE INDEX IF NOT EXISTS idx_insights_published_at ON insights (published_at);
+CREATE INDEX IF NOT EXISTS idx_insights_source_id ON insights (source_id);
+CREATE INDEX IF NOT EXISTS idx_insights_user_id ON insights (user_id);
+
+CREATE TABLE IF NOT EXISTS chapters (
+    id bigserial,
+    analysis_id bigint NOT NULL,
+    title varchar(500),
+    "timestamp" varchar(20),
+    seconds bigint,
+    order_index bigint,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_chapters_analysis_id ON chapters (analysis_id);
+
+CREATE TABLE IF NOT EXISTS transcriptions (
+    id bigserial,
+    analysis_id bigint NOT NULL,
+    text text,
+    "timestamp" varchar(20),
+    seconds bigint,
+    order_index bigint,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_transcriptions_analysis_id ON transcriptions (analysis_id);
+
+CREATE TABLE IF NOT EXISTS key_points (
+    id bigserial,
+    analysis_id bigint NOT NULL,
+    content text,
+    order_index bigint,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_key_points_analysis_id ON key_points (analysis_id);
+
+CREATE TABLE IF NOT EXISTS highlights (
+    id bigserial,
+    insight_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    text text NOT NULL,
+    start_offset bigint NOT NULL,
+    end_offset bigint NOT NULL,
+    color varchar(20) DEFAULT 'yellow',
+    note text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_insights_highlights FOREIGN KEY (insight_id) REFERENCES insights(id)
+);
+CREATE INDEX IF NOT EXISTS idx_highlights_user_id ON highlights (user_id);
+CREATE INDEX IF NOT EXISTS idx_highlights_insight_id ON highlights (insight_id);
+
+CREATE TABLE IF NOT EXISTS chat_messages (
+    id bigserial,
+    insight_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    role varchar(20) NOT NULL,
+    content text NOT NULL,
+    highlight_id bigint,
+    created_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_insights_chat_messages FOREIGN KEY (insight_id) REFERENCES insights(id)
+);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_highlight_id ON chat_messages (highlight_id);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_user_id ON chat_messages (user_id);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_insight_id ON chat_messages (insight_id);
+
+CREATE TABLE IF NOT EXISTS translations (
+    id bigserial,
+    source_text text,
+    youtube_url varchar(500),
+    video_id varchar(50),
+    source_language varchar(10),
+    target_language varchar(10) NOT NULL,
+    translated_text text,
+    enable_dual_subs boolean DEFAULT false,
+    status varchar(50) DEFAULT 'pending',
+    error_message text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_translations_deleted_at ON translations (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_translations_video_id ON translations (video_id);
+
+CREATE TABLE IF NOT EXISTS dual_subtitles (
+    id bigserial,
+    translation_id bigint NOT NULL,
+    original text,
+    translated text,
+    start_time varchar(20),
+    end_time varchar(20),
+    order_index bigint,
+    created_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_translations_dual_subtitles FOREIGN KEY (translation_id) REFERENCES translations(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_dual_subtitles_translation_id ON dual_subtitles (translation_id);
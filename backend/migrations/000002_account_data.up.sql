@@ -0,0 +1,37 @@
+-- Self-service data exports, delivered as a ZIP kept until expires_at.
+CREATE TABLE account_exports (
+    id bigserial,
+    user_id bigint NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    archive bytea,
+    size_bytes bigint NOT NULL DEFAULT 0,
+    error_message text,
+    completed_at timestamptz,
+    expires_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_account_exports_user_id ON account_exports (user_id);
+CREATE INDEX idx_account_exports_expires_at ON account_exports (expires_at);
+
+-- Account deletion requests. Rows outlive the deleted user as the audit record of
+-- the deletion, so they hold no personal data beyond the user ID and an email hash.
+CREATE TABLE account_deletions (
+    id bigserial,
+    user_id bigint NOT NULL,
+    email_hash varchar(64) NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'scheduled',
+    requested_at timestamptz NOT NULL,
+    scheduled_for timestamptz NOT NULL,
+    cancelled_at timestamptz,
+    completed_at timestamptz,
+    ip_address varchar(64),
+    deleted_rows jsonb,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_account_deletions_user_id ON account_deletions (user_id);
+CREATE UNIQUE INDEX idx_account_deletions_scheduled_user ON account_deletions (user_id) WHERE status = 'scheduled';
+CREATE INDEX idx_account_deletions_due ON account_deletions (scheduled_for) WHERE status = 'scheduled';
@@ -1,2 +0,0 @@
--- Drop chat_messages table
-DROP TABLE IF EXISTS chat_messages;
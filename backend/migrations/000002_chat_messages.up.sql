@@ -1,22 +0,0 @@
--- Create chat_messages table
-CREATE TABLE IF NOT EXISTS chat_messages (
-    id SERIAL PRIMARY KEY,
-    analysis_id INTEGER NOT NULL,
-    role VARCHAR(20) NOT NULL,
-    content TEXT NOT NULL,
-    highlight_id INTEGER,
-    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    deleted_at TIMESTAMPTZ
-);
-
--- Create indexes
-CREATE INDEX IF NOT EXISTS idx_chat_messages_analysis_id ON chat_messages(analysis_id);
-CREATE INDEX IF NOT EXISTS idx_chat_messages_highlight_id ON chat_messages(highlight_id);
-CREATE INDEX IF NOT EXISTS idx_chat_messages_deleted_at ON chat_messages(deleted_at);
-CREATE INDEX IF NOT EXISTS idx_chat_messages_created_at ON chat_messages(created_at);
-
--- Add comments
-COMMENT ON TABLE chat_messages IS 'Chat messages for AI conversations';
-COMMENT ON COLUMN chat_messages.role IS 'Message role: user or assistant';
-COMMENT ON COLUMN chat_messages.highlight_id IS 'Optional link to a highlight';
@@ -1,8 +0,0 @@
--- Drop chat_messages table
-DROP TABLE IF EXISTS chat_messages;
-
--- Drop highlights table
-DROP TABLE IF EXISTS highlights;
-
--- Drop insights table
-DROP TABLE IF EXISTS insights;
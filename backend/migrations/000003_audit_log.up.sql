@@ -0,0 +1,36 @@
+-- Append-only log of security- and data-relevant actions. Rows are never updated or
+-- deleted; the only exception is erasing the client details of a deleted account.
+CREATE TABLE audit_logs (
+    id bigserial,
+    actor_id bigint,
+    workspace_id bigint,
+    action varchar(50) NOT NULL,
+    target_type varchar(50),
+    target_id bigint,
+    details jsonb,
+    ip_address varchar(64),
+    user_agent varchar(500),
+    request_id varchar(100),
+    created_at timestamptz NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_audit_logs_actor_created ON audit_logs (actor_id, created_at);
+CREATE INDEX idx_audit_logs_workspace_created ON audit_logs (workspace_id, created_at) WHERE workspace_id IS NOT NULL;
+CREATE INDEX idx_audit_logs_target ON audit_logs (target_type, target_id);
+
+CREATE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND NEW.ip_address IS NULL AND NEW.user_agent IS NULL
+        AND ROW(NEW.id, NEW.actor_id, NEW.workspace_id, NEW.action, NEW.target_type, NEW.target_id, NEW.details, NEW.request_id, NEW.created_at)
+            IS NOT DISTINCT FROM ROW(OLD.id, OLD.actor_id, OLD.workspace_id, OLD.action, OLD.target_type, OLD.target_id, OLD.details, OLD.request_id, OLD.created_at)
+    THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_logs_append_only
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
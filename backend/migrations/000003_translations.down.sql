@@ -1,3 +0,0 @@
--- Drop tables in reverse order
-DROP TABLE IF EXISTS dual_subtitles;
-DROP TABLE IF EXISTS translations;
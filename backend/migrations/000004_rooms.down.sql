@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS participants;
+DROP TABLE IF EXISTS rooms;
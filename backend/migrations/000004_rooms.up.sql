@@ -0,0 +1,31 @@
+-- Video rooms and their participants. They were never part of AutoMigrate, so
+-- IF NOT EXISTS lets databases where they were created by hand adopt them.
+CREATE TABLE IF NOT EXISTS rooms (
+    id bigserial,
+    room_id varchar(255) NOT NULL,
+    status varchar(50) DEFAULT 'IDLE',
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rooms_room_id ON rooms (room_id);
+CREATE INDEX IF NOT EXISTS idx_rooms_deleted_at ON rooms (deleted_at);
+
+CREATE TABLE IF NOT EXISTS participants (
+    id bigserial,
+    room_id varchar(255) NOT NULL,
+    user_id varchar(255) NOT NULL,
+    status varchar(50) DEFAULT 'ONLINE',
+    joined_at timestamptz,
+    left_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_participants_room_id ON participants (room_id);
+CREATE INDEX IF NOT EXISTS idx_participants_deleted_at ON participants (deleted_at);
+
+-- Signaling is relayed over WebSockets now instead of being stored
+DROP TABLE IF EXISTS webrtc_signals;
@@ -0,0 +1 @@
+ALTER TABLE rooms DROP COLUMN media_mode;
@@ -0,0 +1,2 @@
+-- How a room's media flows: "mesh" (peer to peer) or "sfu" (forwarded by the server)
+ALTER TABLE rooms ADD COLUMN media_mode varchar(10) NOT NULL DEFAULT 'mesh';
@@ -0,0 +1,7 @@
+ALTER TABLE rooms DROP COLUMN max_participants;
+
+DROP INDEX IF EXISTS idx_participants_online_last_seen;
+DROP INDEX IF EXISTS idx_participants_room_user;
+ALTER TABLE participants DROP COLUMN last_seen_at;
+ALTER TABLE participants DROP CONSTRAINT fk_participants_user;
+ALTER TABLE participants ALTER COLUMN user_id TYPE varchar(255) USING user_id::text;
@@ -0,0 +1,16 @@
+-- Participants are signed-in users now instead of client-generated IDs. Presence is
+-- transient, so participants recorded under the old scheme are dropped.
+DELETE FROM participants;
+ALTER TABLE participants ALTER COLUMN user_id TYPE bigint USING user_id::bigint;
+ALTER TABLE participants ADD CONSTRAINT fk_participants_user
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE participants ADD COLUMN last_seen_at timestamptz;
+-- One row per user and room, reused when they join again
+CREATE UNIQUE INDEX idx_participants_room_user ON participants (room_id, user_id);
+-- Presence sweeps only look at participants who are online
+CREATE INDEX idx_participants_online_last_seen ON participants (last_seen_at) WHERE status = 'ONLINE';
+
+-- 0 for the server default (ROOM_MAX_PARTICIPANTS)
+ALTER TABLE rooms ADD COLUMN max_participants integer NOT NULL DEFAULT 0;
+-- Statuses are driven by the server from now on; every room is empty after the reset
+UPDATE rooms SET status = CASE WHEN status = 'IDLE' THEN 'IDLE' ELSE 'DISCONNECTED' END;
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS room_invites;
+
+DROP INDEX IF EXISTS idx_participants_present_last_seen;
+CREATE INDEX idx_participants_online_last_seen ON participants (last_seen_at) WHERE status = 'ONLINE';
+ALTER TABLE participants DROP COLUMN muted;
+ALTER TABLE participants DROP COLUMN role;
+
+ALTER TABLE rooms DROP COLUMN password_hash;
+ALTER TABLE rooms DROP COLUMN lobby_enabled;
+ALTER TABLE rooms DROP COLUMN locked;
+ALTER TABLE rooms DROP COLUMN owner_id;
@@ -0,0 +1,26 @@
+-- Room ownership and settings. Rooms created before ownership go to their next joiner.
+ALTER TABLE rooms ADD COLUMN owner_id bigint REFERENCES users (id) ON DELETE SET NULL;
+ALTER TABLE rooms ADD COLUMN locked boolean NOT NULL DEFAULT false;
+ALTER TABLE rooms ADD COLUMN lobby_enabled boolean NOT NULL DEFAULT false;
+ALTER TABLE rooms ADD COLUMN password_hash varchar(255) NOT NULL DEFAULT '';
+
+-- Moderators and hard mutes
+ALTER TABLE participants ADD COLUMN role varchar(20) NOT NULL DEFAULT 'member';
+ALTER TABLE participants ADD COLUMN muted boolean NOT NULL DEFAULT false;
+DROP INDEX IF EXISTS idx_participants_online_last_seen;
+CREATE INDEX idx_participants_present_last_seen ON participants (last_seen_at) WHERE status IN ('ONLINE', 'WAITING');
+
+CREATE TABLE room_invites (
+    id bigserial,
+    room_id varchar(255) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    created_by bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    max_uses integer NOT NULL DEFAULT 0,
+    uses integer NOT NULL DEFAULT 0,
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_room_invites_room_id ON room_invites (room_id);
+CREATE UNIQUE INDEX idx_room_invites_token_hash ON room_invites (token_hash);
@@ -0,0 +1,20 @@
+-- Users of OAuth and OpenID Connect providers, and accounts without a password
+ALTER TABLE users ADD COLUMN IF NOT EXISTS passwordless boolean DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id bigserial,
+    user_id bigint NOT NULL,
+    provider varchar(50) NOT NULL,
+    subject varchar(255) NOT NULL,
+    email varchar(255),
+    email_verified boolean DEFAULT false,
+    name varchar(255),
+    avatar_url varchar(1000),
+    last_login_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_user_identities_email ON user_identities (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_identity_provider_subject ON user_identities (provider,subject);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
@@ -0,0 +1,12 @@
+-- Sign-in attempts and other security events, for lockouts and the user's history
+CREATE TABLE IF NOT EXISTS security_events (
+    id bigserial,
+    user_id bigint NOT NULL,
+    type varchar(50) NOT NULL,
+    ip_address varchar(64),
+    user_agent varchar(500),
+    details text,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_security_events_user_created ON security_events (user_id,created_at);
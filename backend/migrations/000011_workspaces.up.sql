@@ -0,0 +1,44 @@
+-- Workspaces share an insight library between their members
+CREATE TABLE IF NOT EXISTS workspaces (
+    id bigserial,
+    name varchar(255) NOT NULL,
+    owner_id bigint NOT NULL,
+    personal boolean DEFAULT false,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_workspaces_deleted_at ON workspaces (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workspaces_personal_owner ON workspaces (owner_id) WHERE personal = true;
+CREATE INDEX IF NOT EXISTS idx_workspaces_owner_id ON workspaces (owner_id);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    id bigserial,
+    workspace_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    role varchar(20) NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user_id ON workspace_members (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workspace_member ON workspace_members (workspace_id,user_id);
+
+CREATE TABLE IF NOT EXISTS workspace_invitations (
+    id bigserial,
+    workspace_id bigint NOT NULL,
+    email varchar(255) NOT NULL,
+    role varchar(20) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    invited_by bigint NOT NULL,
+    expires_at timestamptz,
+    accepted_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workspace_invitations_token_hash ON workspace_invitations (token_hash);
+CREATE INDEX IF NOT EXISTS idx_workspace_invitations_workspace_id ON workspace_invitations (workspace_id);
+
+ALTER TABLE insights ADD COLUMN IF NOT EXISTS workspace_id bigint;
+CREATE INDEX IF NOT EXISTS idx_insights_workspace_id ON insights (workspace_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_insights_collection_id;
+ALTER TABLE insights DROP COLUMN IF EXISTS collection_id;
+DROP TABLE IF EXISTS collections;
+DROP TABLE IF EXISTS insight_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,39 @@
+-- Collections (nested folders) and tags for organizing a workspace's insights
+CREATE TABLE IF NOT EXISTS tags (
+    id bigserial,
+    workspace_id bigint NOT NULL,
+    name varchar(50) NOT NULL,
+    color varchar(20),
+    created_by bigint NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_workspace_name ON tags (workspace_id,name);
+
+CREATE TABLE IF NOT EXISTS insight_tags (
+    insight_id bigint,
+    tag_id bigint,
+    created_at timestamptz,
+    PRIMARY KEY (insight_id,tag_id),
+    CONSTRAINT fk_insight_tags_tag FOREIGN KEY (tag_id) REFERENCES tags(id),
+    CONSTRAINT fk_insight_tags_insight FOREIGN KEY (insight_id) REFERENCES insights(id)
+);
+CREATE INDEX IF NOT EXISTS idx_insight_tags_tag_id ON insight_tags (tag_id);
+
+CREATE TABLE IF NOT EXISTS collections (
+    id bigserial,
+    workspace_id bigint NOT NULL,
+    parent_id bigint,
+    name varchar(255) NOT NULL,
+    "position" bigint NOT NULL DEFAULT 0,
+    created_by bigint NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_collections_parent_id ON collections (parent_id);
+CREATE INDEX IF NOT EXISTS idx_collections_workspace_id ON collections (workspace_id);
+
+ALTER TABLE insights ADD COLUMN IF NOT EXISTS collection_id bigint;
+CREATE INDEX IF NOT EXISTS idx_insights_collection_id ON insights (collection_id);
@@ -0,0 +1,17 @@
+-- Full-text search documents, one per searchable part of an insight
+CREATE TABLE IF NOT EXISTS search_entries (
+    id bigserial,
+    insight_id bigint NOT NULL,
+    kind varchar(20) NOT NULL,
+    ref_id bigint,
+    seconds bigint,
+    "timestamp" varchar(20),
+    text text NOT NULL,
+    document tsvector,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_search_entries_document ON search_entries USING gin(document);
+CREATE INDEX IF NOT EXISTS idx_search_entries_ref_id ON search_entries (ref_id);
+CREATE INDEX IF NOT EXISTS idx_search_entries_kind ON search_entries (kind);
+CREATE INDEX IF NOT EXISTS idx_search_entries_insight_id ON search_entries (insight_id);
@@ -0,0 +1,33 @@
+-- Share links, several per insight, and their views
+CREATE TABLE IF NOT EXISTS share_links (
+    id bigserial,
+    insight_id bigint NOT NULL,
+    created_by bigint NOT NULL,
+    token varchar(64) NOT NULL,
+    label varchar(100),
+    config JSONB,
+    password_hash varchar(255),
+    expires_at timestamptz,
+    max_views bigint,
+    view_count bigint NOT NULL DEFAULT 0,
+    last_viewed_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_share_links_token ON share_links (token);
+CREATE INDEX IF NOT EXISTS idx_share_links_insight_id ON share_links (insight_id);
+
+CREATE TABLE IF NOT EXISTS share_link_views (
+    id bigserial,
+    share_link_id bigint NOT NULL,
+    referer_host varchar(255),
+    referer varchar(2000),
+    visitor_hash varchar(64),
+    viewed_at timestamptz NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_share_link_views_viewed_at ON share_link_views (viewed_at);
+CREATE INDEX IF NOT EXISTS idx_share_link_views_referer_host ON share_link_views (referer_host);
+CREATE INDEX IF NOT EXISTS idx_share_link_views_share_link_id ON share_link_views (share_link_id);
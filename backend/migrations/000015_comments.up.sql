@@ -0,0 +1,23 @@
+-- Comments and annotations on insights, by members and share link visitors
+CREATE TABLE IF NOT EXISTS comments (
+    id bigserial,
+    insight_id bigint NOT NULL,
+    parent_id bigint,
+    share_link_id bigint,
+    user_id bigint,
+    author_name varchar(50) NOT NULL,
+    visitor_hash varchar(64),
+    body text NOT NULL,
+    highlight_id bigint,
+    seconds decimal,
+    hidden boolean NOT NULL DEFAULT false,
+    locked boolean NOT NULL DEFAULT false,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_comments_highlight_id ON comments (highlight_id);
+CREATE INDEX IF NOT EXISTS idx_comments_user_id ON comments (user_id);
+CREATE INDEX IF NOT EXISTS idx_comments_share_link_id ON comments (share_link_id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments (parent_id);
+CREATE INDEX IF NOT EXISTS idx_comments_insight_id ON comments (insight_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS flashcard_reviews;
+DROP TABLE IF EXISTS flashcards;
@@ -0,0 +1,40 @@
+-- Spaced-repetition flashcards and their review history
+CREATE TABLE IF NOT EXISTS flashcards (
+    id bigserial,
+    user_id bigint NOT NULL,
+    insight_id bigint NOT NULL,
+    source varchar(20) NOT NULL,
+    source_key varchar(64) NOT NULL,
+    highlight_id bigint,
+    seconds bigint,
+    front text NOT NULL,
+    back text NOT NULL,
+    suspended boolean NOT NULL DEFAULT false,
+    ease_factor decimal NOT NULL DEFAULT 2.5,
+    interval_days bigint NOT NULL DEFAULT 0,
+    repetitions bigint NOT NULL DEFAULT 0,
+    lapses bigint NOT NULL DEFAULT 0,
+    due_at timestamptz NOT NULL,
+    last_reviewed_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_flashcards_highlight_id ON flashcards (highlight_id);
+CREATE INDEX IF NOT EXISTS idx_flashcards_insight_id ON flashcards (insight_id);
+CREATE INDEX IF NOT EXISTS idx_flashcard_due ON flashcards (user_id,due_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_flashcard_source ON flashcards (user_id,insight_id,source_key);
+
+CREATE TABLE IF NOT EXISTS flashcard_reviews (
+    id bigserial,
+    flashcard_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    grade bigint NOT NULL,
+    interval_days bigint,
+    ease_factor decimal,
+    reviewed_at timestamptz NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_flashcard_reviews_reviewed_at ON flashcard_reviews (reviewed_at);
+CREATE INDEX IF NOT EXISTS idx_flashcard_reviews_user_id ON flashcard_reviews (user_id);
+CREATE INDEX IF NOT EXISTS idx_flashcard_reviews_flashcard_id ON flashcard_reviews (flashcard_id);
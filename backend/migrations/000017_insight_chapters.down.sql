@@ -0,0 +1,7 @@
+ALTER TABLE chapters DROP CONSTRAINT IF EXISTS fk_insights_chapters;
+DROP INDEX IF EXISTS idx_chapters_insight_id;
+ALTER TABLE chapters DROP COLUMN IF EXISTS source;
+ALTER TABLE chapters DROP COLUMN IF EXISTS insight_id;
+-- Insight chapters have no analysis to belong to
+DELETE FROM chapters WHERE analysis_id IS NULL;
+ALTER TABLE chapters ALTER COLUMN analysis_id SET NOT NULL;
@@ -0,0 +1,12 @@
+-- Chapters belong to a video analysis or to an insight, and record how they were found
+ALTER TABLE chapters ALTER COLUMN analysis_id DROP NOT NULL;
+ALTER TABLE chapters ADD COLUMN IF NOT EXISTS insight_id bigint;
+ALTER TABLE chapters ADD COLUMN IF NOT EXISTS source varchar(20);
+CREATE INDEX IF NOT EXISTS idx_chapters_insight_id ON chapters (insight_id);
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_insights_chapters') THEN
+        ALTER TABLE chapters ADD CONSTRAINT fk_insights_chapters FOREIGN KEY (insight_id) REFERENCES insights(id);
+    END IF;
+END;
+$$;
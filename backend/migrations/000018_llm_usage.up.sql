@@ -0,0 +1,15 @@
+-- Tokens and cost of every LLM call, by user and feature
+CREATE TABLE IF NOT EXISTS llm_usage (
+    id bigserial,
+    user_id bigint,
+    feature varchar(40) NOT NULL,
+    model varchar(200) NOT NULL,
+    prompt_tokens bigint NOT NULL DEFAULT 0,
+    completion_tokens bigint NOT NULL DEFAULT 0,
+    cost_usd numeric(14,8) NOT NULL DEFAULT 0,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_llm_usage_created_at ON llm_usage (created_at);
+CREATE INDEX IF NOT EXISTS idx_llm_usage_feature_day ON llm_usage (feature,created_at);
+CREATE INDEX IF NOT EXISTS idx_llm_usage_user_day ON llm_usage (user_id,created_at);
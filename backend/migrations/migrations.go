@@ -0,0 +1,13 @@
+// Package migrations embeds the versioned SQL migrations of the database schema.
+//
+// Each migration is a pair of files NNNNNN_description.up.sql and
+// NNNNNN_description.down.sql. Versions must be unique and are applied in order
+// by database.Migrator, each in its own transaction.
+package migrations
+
+import "embed"
+
+// FS holds the migration files.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -8,4 +8,10 @@ echo "DATABASE_URL: [configured]"
 echo "REDIS_URL: [configured]"
 echo "====================================="
 
+# Apply pending database migrations on start unless told otherwise; the server
+# refuses to start against an outdated schema. Set MIGRATE_ON_START=false to run
+# `server migrate up` as a separate release step instead.
+export MIGRATE_ON_START="${MIGRATE_ON_START:-true}"
+echo "MIGRATE_ON_START: ${MIGRATE_ON_START}"
+
 exec /server "$@"
@@ -52,6 +52,12 @@ export default function GoogleCallbackPage() {
             created_at: string;
           };
           apiKey?: string;
+          // Returned instead of user and apiKey when the account uses 2FA
+          twoFactor?: {
+            two_factor_required: boolean;
+            challenge: string;
+            expires_in: number;
+          };
         }>('/v1/auth/google/callback', {
           code,
           state: searchParams.get('state'),
@@ -71,6 +77,14 @@ export default function GoogleCallbackPage() {
         localStorage.setItem('google_refresh_token', response.refreshToken);
         localStorage.setItem('google_token_expiry', response.expiry);
 
+        // Google sign-in does not skip the second factor
+        if (response.twoFactor) {
+          setStatus('error');
+          setMessage('This account uses two-factor authentication. Sign in with your password and authenticator code.');
+          toast.error('Two-factor authentication required');
+          return;
+        }
+
         // Store system API key and user info (for backend API authentication)
         if (response.apiKey) {
           localStorage.setItem('auth_token', response.apiKey);
@@ -9,7 +9,7 @@ import MediaControls from "@/components/video/MediaControls";
 import { useMediaDevices } from "@/hooks/use-media-devices";
 import { useWebRTC } from "@/hooks/use-webrtc";
 import { roomApi } from "@/lib/api/rooms";
-import type { RoomStatus } from "@/types/room";
+import type { RoomMediaMode, RoomStatus } from "@/types/room";
 
 /**
  * Video Room Page
@@ -21,6 +21,8 @@ export default function VideoRoomPage() {
   const roomId = params.roomId as string;
 
   const [userId, setUserId] = useState<string>("");
+  const [signalToken, setSignalToken] = useState<string | null>(null);
+  const [mediaMode, setMediaMode] = useState<RoomMediaMode>("mesh");
   const [roomStatus, setRoomStatus] = useState<RoomStatus>("IDLE");
   const [joinError, setJoinError] = useState<string | null>(null);
 
@@ -46,6 +48,8 @@ export default function VideoRoomPage() {
   } = useWebRTC({
     roomId,
     userId,
+    signalToken,
+    mediaMode,
     localStream,
   });
 
@@ -60,9 +64,21 @@ export default function VideoRoomPage() {
       // Request media permissions first
       await requestPermissions();
 
-      // Join the room via API
-      const response = await roomApi.join(roomId);
-      setUserId(response.userId);
+      // Join the room via API. In rooms with a lobby, wait until a moderator
+      // admits us, then join again for a signaling token.
+      let response = await roomApi.join(roomId);
+      setUserId(response.user_id);
+      while (response.participant_status === "WAITING") {
+        await new Promise((resolve) =>
+          setTimeout(resolve, response.heartbeat_interval * 1000)
+        );
+        const heartbeat = await roomApi.heartbeat(roomId);
+        if (heartbeat.participant_status === "ONLINE") {
+          response = await roomApi.join(roomId);
+        }
+      }
+      setMediaMode(response.media_mode);
+      setSignalToken(response.signal_token ?? null);
       setRoomStatus("CONNECTED");
     } catch (err) {
       console.error("Failed to join room:", err);
@@ -80,7 +96,7 @@ export default function VideoRoomPage() {
     if (!userId) return;
 
     try {
-      await roomApi.leave(roomId, userId);
+      await roomApi.leave(roomId);
     } catch (err) {
       console.error("Failed to leave room:", err);
     } finally {
@@ -106,7 +122,7 @@ export default function VideoRoomPage() {
   useEffect(() => {
     return () => {
       if (userId) {
-        roomApi.leave(roomId, userId).catch(console.error);
+        roomApi.leave(roomId).catch(console.error);
       }
       cleanupMedia();
       cleanupWebRTC();
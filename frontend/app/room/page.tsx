@@ -5,6 +5,7 @@ import { useRouter } from "next/navigation";
 import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
 import { Video, ArrowRight } from "lucide-react";
+import { roomApi } from "@/lib/api/rooms";
 
 /**
  * Room Entry Page
@@ -13,11 +14,22 @@ import { Video, ArrowRight } from "lucide-react";
 export default function RoomPage() {
   const router = useRouter();
   const [roomId, setRoomId] = useState("");
+  const [creating, setCreating] = useState(false);
+  const [createError, setCreateError] = useState<string | null>(null);
 
-  const handleCreateRoom = () => {
-    // Generate a random room ID
-    const newRoomId = Math.random().toString(36).substring(2, 12);
-    router.push(`/room/${newRoomId}`);
+  const handleCreateRoom = async () => {
+    // The server picks the room ID and makes us its owner
+    setCreating(true);
+    setCreateError(null);
+    try {
+      const room = await roomApi.create();
+      router.push(`/room/${room.room_id}`);
+    } catch (err) {
+      setCreateError(
+        err instanceof Error ? err.message : "Failed to create room"
+      );
+      setCreating(false);
+    }
   };
 
   const handleJoinRoom = () => {
@@ -52,12 +64,16 @@ export default function RoomPage() {
             </p>
             <Button
               onClick={handleCreateRoom}
+              disabled={creating}
               size="lg"
-              className="w-full h-14 rounded-xl border-0 bg-primary hover:bg-primary/90 text-primary-foreground"
+              className="w-full h-14 rounded-xl border-0 bg-primary hover:bg-primary/90 text-primary-foreground disabled:opacity-50"
             >
               <Video className="w-5 h-5 mr-2" />
               Create Room
             </Button>
+            {createError && (
+              <p className="mt-3 text-sm text-destructive">{createError}</p>
+            )}
           </div>
 
           {/* Divider */}
@@ -60,8 +60,10 @@ export function ShareDialog({
         include_key_points: config.includeKeyPoints,
         include_highlights: config.includeHighlights,
         include_chat: config.includeChat,
-        is_public: config.isPublic,
-        password: config.password || undefined,
+        // The switch picks open vs password-protected access; is_public=false
+        // would revoke the link instead.
+        is_public: true,
+        password: config.isPublic ? undefined : config.password || undefined,
       });
 
       const fullUrl = `${window.location.origin}/share/${response.share_token}`;
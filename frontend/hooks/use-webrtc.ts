@@ -1,16 +1,31 @@
 /**
  * useWebRTC Hook
- * Manages WebRTC peer connections for video conferencing
+ * Manages WebRTC peer connections for video conferencing over the room's
+ * signaling WebSocket. In mesh rooms every pair of participants connects
+ * directly; in SFU rooms each participant publishes to and subscribes from
+ * the server.
  */
 
 import { useState, useEffect, useCallback, useRef } from "react";
 import { roomApi } from "@/lib/api/rooms";
-import type { PeerConnection, Participant } from "@/types/room";
+import { SIGNAL_SFU } from "@/types/room";
+import type {
+  PeerConnection,
+  Participant,
+  RoomMediaMode,
+  RoomModerationEvent,
+  SFUSignalPayload,
+  SFUTransport,
+  SignalMessage,
+} from "@/types/room";
 
 interface UseWebRTCOptions {
   roomId: string;
   userId: string;
+  signalToken: string | null;
+  mediaMode: RoomMediaMode;
   localStream: MediaStream | null;
+  onModeration?: (event: RoomModerationEvent) => void;
   onError?: (error: Error) => void;
 }
 
@@ -29,12 +44,26 @@ const ICE_SERVERS: RTCConfiguration = {
   ],
 };
 
-const POLL_INTERVAL = 3000; // Poll for participants every 3 seconds
+/**
+ * Apply candidates that arrived before the remote description
+ */
+async function flushCandidates(
+  pc: RTCPeerConnection,
+  pending: RTCIceCandidateInit[] | undefined
+) {
+  if (!pending) return;
+  for (const candidate of pending.splice(0)) {
+    await pc.addIceCandidate(candidate);
+  }
+}
 
 export function useWebRTC({
   roomId,
   userId,
+  signalToken,
+  mediaMode,
   localStream,
+  onModeration,
   onError,
 }: UseWebRTCOptions): UseWebRTCReturn {
   const [remoteStreams, setRemoteStreams] = useState<Map<string, MediaStream>>(
@@ -44,11 +73,66 @@ export function useWebRTC({
   const [isConnected, setIsConnected] = useState(false);
   const [error, setError] = useState<Error | null>(null);
 
+  const socketRef = useRef<WebSocket | null>(null);
+  // Mesh: one connection per remote participant
   const peerConnectionsRef = useRef<Map<string, PeerConnection>>(new Map());
-  const pollIntervalRef = useRef<NodeJS.Timeout | null>(null);
+  // SFU: publish and subscribe connections with the server
+  const sfuConnectionsRef = useRef<Map<SFUTransport, RTCPeerConnection>>(
+    new Map()
+  );
+  // Candidates received before the remote description, by peer or transport
+  const pendingCandidatesRef = useRef<Map<string, RTCIceCandidateInit[]>>(
+    new Map()
+  );
+
+  // Callbacks are read through refs so new closures do not reconnect signaling
+  const onModerationRef = useRef(onModeration);
+  const onErrorRef = useRef(onError);
+  useEffect(() => {
+    onModerationRef.current = onModeration;
+    onErrorRef.current = onError;
+  }, [onModeration, onError]);
+
+  const fail = useCallback((err: unknown) => {
+    const error = err instanceof Error ? err : new Error(String(err));
+    console.error("WebRTC error:", error);
+    setError(error);
+    onErrorRef.current?.(error);
+  }, []);
+
+  const send = useCallback((msg: SignalMessage) => {
+    const socket = socketRef.current;
+    if (socket && socket.readyState === WebSocket.OPEN) {
+      socket.send(JSON.stringify(msg));
+    }
+  }, []);
+
+  const setRemoteStream = useCallback(
+    (id: string, stream: MediaStream | null) => {
+      setRemoteStreams((prev) => {
+        const newMap = new Map(prev);
+        if (stream) {
+          newMap.set(id, stream);
+        } else {
+          newMap.delete(id);
+        }
+        return newMap;
+      });
+    },
+    []
+  );
+
+  const queueCandidate = useCallback(
+    (key: string, candidate: RTCIceCandidateInit) => {
+      const pending = pendingCandidatesRef.current.get(key) ?? [];
+      pending.push(candidate);
+      pendingCandidatesRef.current.set(key, pending);
+    },
+    []
+  );
 
   /**
-   * Create a new peer connection for a remote user
+   * Mesh: create a peer connection for a remote participant
    */
   const createPeerConnection = useCallback(
     (remoteUserId: string): RTCPeerConnection => {
@@ -65,182 +149,336 @@ export function useWebRTC({
       pc.ontrack = (event) => {
         const remoteStream = event.streams[0];
         if (remoteStream) {
-          setRemoteStreams((prev) => {
-            const newMap = new Map(prev);
-            newMap.set(remoteUserId, remoteStream);
-            return newMap;
-          });
+          setRemoteStream(remoteUserId, remoteStream);
         }
       };
 
-      // Handle ICE candidates
-      pc.onicecandidate = async (event) => {
+      // Relay ICE candidates through signaling
+      pc.onicecandidate = (event) => {
         if (event.candidate) {
-          try {
-            await roomApi.sendIceCandidate(
-              roomId,
-              userId,
-              JSON.stringify(event.candidate)
-            );
-          } catch (err) {
-            console.error("Failed to send ICE candidate:", err);
-          }
+          send({
+            type: "candidate",
+            to: remoteUserId,
+            payload: event.candidate.toJSON(),
+          });
         }
       };
 
       // Handle connection state changes
       pc.onconnectionstatechange = () => {
-        console.log(
-          `Peer connection with ${remoteUserId}: ${pc.connectionState}`
-        );
-        if (
-          pc.connectionState === "disconnected" ||
-          pc.connectionState === "failed"
-        ) {
-          // Remove disconnected peer
-          setRemoteStreams((prev) => {
-            const newMap = new Map(prev);
-            newMap.delete(remoteUserId);
-            return newMap;
-          });
+        if (pc.connectionState === "failed") {
+          setRemoteStream(remoteUserId, null);
         }
       };
 
+      peerConnectionsRef.current.set(remoteUserId, {
+        userId: remoteUserId,
+        connection: pc,
+        stream: null,
+      });
       return pc;
     },
-    [roomId, userId, localStream]
+    [localStream, send, setRemoteStream]
+  );
+
+  /**
+   * Mesh: close the connection with a participant who left
+   */
+  const closePeer = useCallback(
+    (remoteUserId: string) => {
+      peerConnectionsRef.current.get(remoteUserId)?.connection.close();
+      peerConnectionsRef.current.delete(remoteUserId);
+      pendingCandidatesRef.current.delete(remoteUserId);
+      setRemoteStream(remoteUserId, null);
+    },
+    [setRemoteStream]
   );
 
   /**
-   * Create offer for a new peer
+   * Mesh: offer a connection to a participant who was already in the room.
+   * Newcomers offer and existing participants answer, so offers never cross.
    */
   const createOffer = useCallback(
     async (remoteUserId: string) => {
-      try {
-        const pc = createPeerConnection(remoteUserId);
-        peerConnectionsRef.current.set(remoteUserId, {
-          userId: remoteUserId,
-          connection: pc,
-          stream: null,
-        });
+      const pc = createPeerConnection(remoteUserId);
+      const offer = await pc.createOffer();
+      await pc.setLocalDescription(offer);
+      send({ type: "offer", to: remoteUserId, payload: offer });
+    },
+    [createPeerConnection, send]
+  );
 
-        const offer = await pc.createOffer({
-          offerToReceiveAudio: true,
-          offerToReceiveVideo: true,
-        });
-        await pc.setLocalDescription(offer);
-
-        // Send offer to signaling server
-        const response = await roomApi.sendOffer(
-          roomId,
-          userId,
-          JSON.stringify(offer)
-        );
-
-        // Set remote description from answer
-        if (response.sdp) {
-          const answer = JSON.parse(response.sdp);
-          await pc.setRemoteDescription(new RTCSessionDescription(answer));
+  /**
+   * Mesh: handle an offer, answer or candidate from another participant
+   */
+  const handlePeerSignal = useCallback(
+    async (msg: SignalMessage) => {
+      const from = msg.from;
+      if (!from) return;
+      const existing = peerConnectionsRef.current.get(from)?.connection;
+
+      switch (msg.type) {
+        case "offer": {
+          const pc = existing ?? createPeerConnection(from);
+          await pc.setRemoteDescription(
+            msg.payload as RTCSessionDescriptionInit
+          );
+          await flushCandidates(pc, pendingCandidatesRef.current.get(from));
+          const answer = await pc.createAnswer();
+          await pc.setLocalDescription(answer);
+          send({ type: "answer", to: from, payload: answer });
+          break;
+        }
+        case "answer":
+          if (!existing) return;
+          await existing.setRemoteDescription(
+            msg.payload as RTCSessionDescriptionInit
+          );
+          await flushCandidates(existing, pendingCandidatesRef.current.get(from));
+          break;
+        case "candidate": {
+          const candidate = msg.payload as RTCIceCandidateInit;
+          if (existing?.remoteDescription) {
+            await existing.addIceCandidate(candidate);
+          } else {
+            queueCandidate(from, candidate);
+          }
+          break;
         }
-
-        setIsConnected(true);
-      } catch (err) {
-        console.error(`Failed to create offer for ${remoteUserId}:`, err);
-        const error = err instanceof Error ? err : new Error(String(err));
-        setError(error);
-        onError?.(error);
       }
     },
-    [roomId, userId, createPeerConnection, onError]
+    [createPeerConnection, queueCandidate, send]
   );
 
   /**
-   * Poll for room participants and establish connections
+   * SFU: send a message to the server on one of the two transports
    */
-  const pollParticipants = useCallback(async () => {
-    try {
-      const status = await roomApi.getStatus(roomId);
-      setParticipants(status.participants);
-
-      // Find new participants and create peer connections
-      const currentPeerIds = Array.from(peerConnectionsRef.current.keys());
-      const newParticipants = status.participants.filter(
-        (p) => p.id !== userId && !currentPeerIds.includes(p.id) && p.status === "ONLINE"
-      );
-
-      for (const participant of newParticipants) {
-        await createOffer(participant.id);
-      }
+  const sendToSFU = useCallback(
+    (type: SignalMessage["type"], payload: SFUSignalPayload) => {
+      send({ type, to: SIGNAL_SFU, payload });
+    },
+    [send]
+  );
 
-      // Remove connections for participants who left
-      const participantIds = status.participants
-        .filter((p) => p.status === "ONLINE")
-        .map((p) => p.id);
-
-      peerConnectionsRef.current.forEach((peerConn, peerId) => {
-        if (!participantIds.includes(peerId)) {
-          peerConn.connection.close();
-          peerConnectionsRef.current.delete(peerId);
-          setRemoteStreams((prev) => {
-            const newMap = new Map(prev);
-            newMap.delete(peerId);
-            return newMap;
+  /**
+   * SFU: create the connection for a transport
+   */
+  const createSFUConnection = useCallback(
+    (transport: SFUTransport): RTCPeerConnection => {
+      const pc = new RTCPeerConnection(ICE_SERVERS);
+      pc.onicecandidate = (event) => {
+        if (event.candidate) {
+          sendToSFU("candidate", {
+            transport,
+            candidate: event.candidate.toJSON(),
           });
         }
-      });
-    } catch (err) {
-      console.error("Failed to poll participants:", err);
-      const error = err instanceof Error ? err : new Error(String(err));
-      setError(error);
-    }
-  }, [roomId, userId, createOffer]);
+      };
+      sfuConnectionsRef.current.set(transport, pc);
+      return pc;
+    },
+    [sendToSFU]
+  );
 
   /**
-   * Start polling for participants
+   * SFU: publish the local tracks to the server
    */
-  useEffect(() => {
-    if (!localStream) return;
-
-    // Initial poll
-    pollParticipants();
-
-    // Start polling interval
-    pollIntervalRef.current = setInterval(pollParticipants, POLL_INTERVAL);
+  const publishToSFU = useCallback(async () => {
+    if (!localStream || sfuConnectionsRef.current.has("publish")) return;
+    const pc = createSFUConnection("publish");
+    localStream.getTracks().forEach((track) => {
+      pc.addTransceiver(track, { direction: "sendonly", streams: [localStream] });
+    });
+    const offer = await pc.createOffer();
+    await pc.setLocalDescription(offer);
+    sendToSFU("offer", { transport: "publish", sdp: offer.sdp });
+  }, [localStream, createSFUConnection, sendToSFU]);
 
-    return () => {
-      if (pollIntervalRef.current) {
-        clearInterval(pollIntervalRef.current);
-        pollIntervalRef.current = null;
+  /**
+   * SFU: handle an offer, answer or candidate from the server
+   */
+  const handleSFUSignal = useCallback(
+    async (msg: SignalMessage) => {
+      const payload = msg.payload as SFUSignalPayload;
+      const transport = payload.transport;
+      let pc = sfuConnectionsRef.current.get(transport);
+
+      switch (msg.type) {
+        case "offer": {
+          // The server offers the subscribe connection, and renegotiates it as
+          // participants come and go. Each stream carries one publisher's tracks.
+          if (transport !== "subscribe") return;
+          if (!pc) {
+            pc = createSFUConnection("subscribe");
+            pc.ontrack = (event) => {
+              const stream = event.streams[0];
+              if (!stream) return;
+              setRemoteStream(stream.id, stream);
+              stream.onremovetrack = () => {
+                if (stream.getTracks().length === 0) {
+                  setRemoteStream(stream.id, null);
+                }
+              };
+            };
+          }
+          await pc.setRemoteDescription({ type: "offer", sdp: payload.sdp });
+          await flushCandidates(pc, pendingCandidatesRef.current.get(transport));
+          const answer = await pc.createAnswer();
+          await pc.setLocalDescription(answer);
+          sendToSFU("answer", { transport, sdp: answer.sdp });
+          break;
+        }
+        case "answer":
+          if (!pc || transport !== "publish") return;
+          await pc.setRemoteDescription({ type: "answer", sdp: payload.sdp });
+          await flushCandidates(pc, pendingCandidatesRef.current.get(transport));
+          break;
+        case "candidate":
+          if (!payload.candidate) return;
+          if (pc?.remoteDescription) {
+            await pc.addIceCandidate(payload.candidate);
+          } else {
+            queueCandidate(transport, payload.candidate);
+          }
+          break;
       }
-    };
-  }, [localStream, pollParticipants]);
+    },
+    [createSFUConnection, queueCandidate, sendToSFU, setRemoteStream]
+  );
 
   /**
-   * Cleanup all peer connections
+   * Close every connection and the signaling socket
    */
   const cleanup = useCallback(() => {
-    if (pollIntervalRef.current) {
-      clearInterval(pollIntervalRef.current);
-      pollIntervalRef.current = null;
+    const socket = socketRef.current;
+    socketRef.current = null;
+    if (socket) {
+      socket.onclose = null;
+      socket.close();
     }
 
     peerConnectionsRef.current.forEach((peerConn) => {
       peerConn.connection.close();
     });
     peerConnectionsRef.current.clear();
+    sfuConnectionsRef.current.forEach((pc) => pc.close());
+    sfuConnectionsRef.current.clear();
+    pendingCandidatesRef.current.clear();
 
     setRemoteStreams(new Map());
+    setParticipants([]);
     setIsConnected(false);
   }, []);
 
   /**
-   * Cleanup on unmount
+   * Connect to signaling once joined with local media
    */
   useEffect(() => {
+    if (!signalToken || !localStream) return;
+
+    const useSFU = mediaMode === "sfu";
+    const socket = new WebSocket(roomApi.signalUrl(roomId, signalToken));
+    socketRef.current = socket;
+
+    const online = (ids: string[]): Participant[] =>
+      ids.map((id) => ({ id, status: "ONLINE" }));
+
+    const handleMessage = async (msg: SignalMessage) => {
+      switch (msg.type) {
+        case "peers":
+          setParticipants(online(msg.peers ?? []));
+          setIsConnected(true);
+          if (useSFU) {
+            await publishToSFU();
+          } else {
+            for (const peerId of msg.peers ?? []) {
+              await createOffer(peerId);
+            }
+          }
+          break;
+        case "peer-joined":
+          if (!msg.from) return;
+          setParticipants((prev) => [
+            ...prev.filter((p) => p.id !== msg.from),
+            ...online([msg.from!]),
+          ]);
+          break;
+        case "peer-left":
+          if (!msg.from) return;
+          setParticipants((prev) => prev.filter((p) => p.id !== msg.from));
+          if (useSFU) {
+            setRemoteStream(msg.from, null);
+          } else {
+            closePeer(msg.from);
+          }
+          break;
+        case "offer":
+        case "answer":
+        case "candidate":
+          if (msg.from === SIGNAL_SFU) {
+            await handleSFUSignal(msg);
+          } else if (!useSFU) {
+            await handlePeerSignal(msg);
+          }
+          break;
+        case "moderation": {
+          const event = msg.payload as RoomModerationEvent;
+          if (event.action === "end") {
+            fail(new Error("The room was closed"));
+          } else if (event.target === userId) {
+            if (event.action === "kick") {
+              fail(new Error("You were removed from the room"));
+            } else if (event.action === "mute" || event.action === "unmute") {
+              localStream.getAudioTracks().forEach((track) => {
+                track.enabled = event.action === "unmute";
+              });
+            }
+          }
+          onModerationRef.current?.(event);
+          break;
+        }
+        case "error":
+          console.warn("Signaling error:", msg.error, msg.to ?? "");
+          break;
+      }
+    };
+
+    // Messages are handled one at a time, so negotiations never interleave
+    let queue = Promise.resolve();
+    socket.onmessage = (event) => {
+      let msg: SignalMessage;
+      try {
+        msg = JSON.parse(event.data as string) as SignalMessage;
+      } catch {
+        return;
+      }
+      queue = queue.then(() => handleMessage(msg)).catch(fail);
+    };
+    socket.onclose = () => {
+      if (socketRef.current === socket) {
+        setIsConnected(false);
+        fail(new Error("Disconnected from the room"));
+      }
+    };
+
     return () => {
       cleanup();
     };
-  }, [cleanup]);
+  }, [
+    roomId,
+    userId,
+    signalToken,
+    mediaMode,
+    localStream,
+    createOffer,
+    closePeer,
+    handlePeerSignal,
+    handleSFUSignal,
+    publishToSFU,
+    setRemoteStream,
+    fail,
+    cleanup,
+  ]);
 
   return {
     remoteStreams,
@@ -4,29 +4,44 @@
  */
 
 import { apiClient } from "./client";
+import { API_BASE_PATH } from "./config";
 import type {
+  CreateRoomRequest,
+  CreateRoomResponse,
   JoinRoomRequest,
   JoinRoomResponse,
   LeaveRoomRequest,
   LeaveRoomResponse,
+  RoomHeartbeatResponse,
   RoomStatusResponse,
-  MediaOfferRequest,
-  MediaOfferResponse,
-  ICECandidateRequest,
-  ICECandidateResponse,
 } from "@/types/room";
 
 /**
  * Room API endpoints
  */
 export const roomApi = {
+  /**
+   * Create a video room owned by the caller. Rooms must be created before
+   * anyone can join them.
+   */
+  async create(options: CreateRoomRequest = {}): Promise<CreateRoomResponse> {
+    const data = await apiClient.request<CreateRoomResponse>("/room", {
+      method: "POST",
+      body: options,
+    });
+    return data;
+  },
+
   /**
    * Join a video room
    */
-  async join(roomId: string): Promise<JoinRoomResponse> {
+  async join(
+    roomId: string,
+    options: Omit<JoinRoomRequest, "roomId"> = {}
+  ): Promise<JoinRoomResponse> {
     const data = await apiClient.request<JoinRoomResponse>("/room/join", {
       method: "POST",
-      body: { roomId } as JoinRoomRequest,
+      body: { roomId, ...options } as JoinRoomRequest,
     });
     return data;
   },
@@ -34,52 +49,50 @@ export const roomApi = {
   /**
    * Leave a video room
    */
-  async leave(roomId: string, userId: string): Promise<LeaveRoomResponse> {
+  async leave(roomId: string): Promise<LeaveRoomResponse> {
     const data = await apiClient.request<LeaveRoomResponse>("/room/leave", {
       method: "POST",
-      body: { roomId, userId } as LeaveRoomRequest,
+      body: { roomId } as LeaveRoomRequest,
     });
     return data;
   },
 
   /**
-   * Get room status
+   * Keep the caller's presence alive while not connected to signaling,
+   * e.g. while waiting in the lobby
    */
-  async getStatus(roomId: string): Promise<RoomStatusResponse> {
-    const data = await apiClient.request<RoomStatusResponse>("/room/status", {
-      method: "GET",
-      params: { roomId },
-    });
+  async heartbeat(roomId: string): Promise<RoomHeartbeatResponse> {
+    const data = await apiClient.request<RoomHeartbeatResponse>(
+      "/room/heartbeat",
+      {
+        method: "POST",
+        body: { roomId },
+      }
+    );
     return data;
   },
 
   /**
-   * Send WebRTC SDP offer
+   * Get room status
    */
-  async sendOffer(
-    roomId: string,
-    userId: string,
-    sdp: string
-  ): Promise<MediaOfferResponse> {
-    const data = await apiClient.request<MediaOfferResponse>("/media/offer", {
-      method: "POST",
-      body: { roomId, userId, sdp } as MediaOfferRequest,
+  async getStatus(roomId: string): Promise<RoomStatusResponse> {
+    const data = await apiClient.request<RoomStatusResponse>("/room/status", {
+      method: "GET",
+      params: { roomId },
     });
     return data;
   },
 
   /**
-   * Send ICE candidate
+   * WebSocket URL of the room's signaling server. The token is the
+   * signal_token returned by join and is only valid for a few minutes;
+   * the room ID lets the server route the room to one instance.
    */
-  async sendIceCandidate(
-    roomId: string,
-    userId: string,
-    candidate: string
-  ): Promise<ICECandidateResponse> {
-    const data = await apiClient.request<ICECandidateResponse>("/media/iceCandidate", {
-      method: "POST",
-      body: { roomId, userId, candidate } as ICECandidateRequest,
-    });
-    return data;
+  signalUrl(roomId: string, signalToken: string): string {
+    const origin =
+      typeof window !== "undefined" ? window.location.origin : "http://localhost";
+    const base = new URL(API_BASE_PATH, origin).href.replace(/^http/, "ws");
+    const params = new URLSearchParams({ roomId, token: signalToken });
+    return `${base}/room/signal?${params.toString()}`;
   },
 };
@@ -8,16 +8,29 @@
 export type RoomStatus = 'IDLE' | 'CONNECTING' | 'CONNECTED' | 'ERROR' | 'DISCONNECTED';
 
 /**
- * Participant status
+ * Participant status. WAITING participants are in the lobby until a moderator admits them.
  */
-export type ParticipantStatus = 'ONLINE' | 'OFFLINE';
+export type ParticipantStatus = 'ONLINE' | 'OFFLINE' | 'WAITING';
 
 /**
- * Participant in a room
+ * Participant role in a room
+ */
+export type RoomRole = 'owner' | 'moderator' | 'member';
+
+/**
+ * How media flows: peer-to-peer between every pair, or through the server (SFU)
+ */
+export type RoomMediaMode = 'mesh' | 'sfu';
+
+/**
+ * Participant in a room. The ID is the one used in signaling messages.
  */
 export interface Participant {
   id: string;
+  name?: string;
   status: ParticipantStatus;
+  role?: RoomRole;
+  muted?: boolean;
 }
 
 /**
@@ -29,20 +42,53 @@ export interface Room {
   participants: Participant[];
 }
 
+/**
+ * Create room request
+ */
+export interface CreateRoomRequest {
+  maxParticipants?: number;
+}
+
+/**
+ * Create room response. room_id is generated by the server; join it to enter.
+ */
+export interface CreateRoomResponse extends RoomStatusResponse {
+  room_id: string;
+}
+
 /**
  * Join room request
  */
 export interface JoinRoomRequest {
   roomId: string;
+  password?: string;
+  invite?: string;
 }
 
 /**
- * Join room response
+ * Room status response
  */
-export interface JoinRoomResponse {
-  userId: string;
-  status: RoomStatus;
-  participants: Participant[];
+export interface RoomStatusResponse {
+  status: RoomStatus | 'CLOSED';
+  media_mode: RoomMediaMode;
+  max_participants: number;
+  locked: boolean;
+  lobby_enabled: boolean;
+  password_protected: boolean;
+  /** Online participants; omitted unless the caller is in the room */
+  participants?: Participant[];
+}
+
+/**
+ * Join room response. signal_token connects to the signaling WebSocket; it is only
+ * issued once the participant is ONLINE.
+ */
+export interface JoinRoomResponse extends RoomStatusResponse {
+  user_id: string;
+  role: RoomRole;
+  participant_status: ParticipantStatus;
+  signal_token?: string;
+  heartbeat_interval: number;
 }
 
 /**
@@ -50,7 +96,6 @@ export interface JoinRoomResponse {
  */
 export interface LeaveRoomRequest {
   roomId: string;
-  userId: string;
 }
 
 /**
@@ -61,43 +106,67 @@ export interface LeaveRoomResponse {
 }
 
 /**
- * Room status response
+ * Presence heartbeat response
  */
-export interface RoomStatusResponse {
-  status: RoomStatus;
-  participants: Participant[];
+export interface RoomHeartbeatResponse {
+  status: string;
+  participant_status: ParticipantStatus;
+  expires_at: string;
 }
 
 /**
- * WebRTC SDP offer request
+ * Signaling message type
  */
-export interface MediaOfferRequest {
-  roomId: string;
-  userId: string;
-  sdp: string;
-}
+export type SignalType =
+  | 'offer'
+  | 'answer'
+  | 'candidate'
+  | 'peers'
+  | 'peer-joined'
+  | 'peer-left'
+  | 'error'
+  | 'moderation'
+  | 'lobby'
+  | 'layer';
 
 /**
- * WebRTC SDP offer response
+ * Message on the room signaling WebSocket. "from" is set by the server.
  */
-export interface MediaOfferResponse {
-  sdp: string;
+export interface SignalMessage {
+  type: SignalType;
+  from?: string;
+  to?: string;
+  payload?: unknown;
+  peers?: string[];
+  error?: string;
 }
 
 /**
- * ICE candidate request
+ * Participant ID that messages for the server are addressed to in SFU rooms
  */
-export interface ICECandidateRequest {
-  roomId: string;
-  userId: string;
-  candidate: string;
+export const SIGNAL_SFU = 'sfu';
+
+/**
+ * One of a participant's two connections to the SFU
+ */
+export type SFUTransport = 'publish' | 'subscribe';
+
+/**
+ * Payload of offer, answer and candidate messages exchanged with the SFU
+ */
+export interface SFUSignalPayload {
+  transport: SFUTransport;
+  sdp?: string;
+  candidate?: RTCIceCandidateInit;
 }
 
 /**
- * ICE candidate response
+ * Payload of a moderation message
  */
-export interface ICECandidateResponse {
-  status: string;
+export interface RoomModerationEvent {
+  action: 'kick' | 'mute' | 'unmute' | 'admit' | 'deny' | 'promote' | 'demote' | 'end';
+  target?: string;
+  by: string;
 }
 
 /**